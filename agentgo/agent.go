@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"agentGo/pkg/agent"
+	"agentGo/pkg/budget"
+	"agentGo/pkg/checkpoint"
+	"agentGo/pkg/killswitch"
+	"agentGo/pkg/memory"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/tools"
+	"agentGo/pkg/vision"
+)
+
+// runAgent has an agent carry out a task on the desktop, with a planner
+// model deciding what to do and a grounder model finding what it
+// describes on the screen.
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	task := fs.String("task", "", "what the agent is asked to do (required)")
+	plannerModel := fs.String("planner-model", vision.DefaultModel, "Gemini model that plans the steps and calls the tools")
+	grounderModel := fs.String("grounder-model", vision.DefaultModel, "Gemini model that finds the elements the planner describes on the screen; a comma-separated list falls back to later models when one fails")
+	plannerPrice := fs.Float64("planner-price", 0, "planner price in dollars per million tokens, to estimate cost (0 to leave cost out)")
+	grounderPrice := fs.Float64("grounder-price", 0, "grounder price in dollars per million tokens, to estimate cost (0 to leave cost out)")
+	maxTurns := fs.Int("max-turns", 50, "most planner responses before giving up")
+	maxCost := fs.Float64("max-cost", 0, "stop before the planner's and grounder's calls cost more than this many dollars, at -planner-price, -grounder-price and AGENTGO_PRICES (0 for no limit)")
+	maxSteps := fs.Int("max-steps", 0, "stop before the agent makes more than this many tool calls (0 for no limit)")
+	busLog := fs.String("bus", "", "write the messages between the planner, executor and grounder to this JSONL file")
+	verbose := fs.Bool("v", false, "log the messages between the planner, executor and grounder")
+	out := fs.String("out", "", "also write the outcome and usage to this JSON file")
+	planPath := fs.String("plan", "", "first break the task into subtasks with success criteria, carried out and checked one by one and planned again when one fails; the plan is saved to this JSON file, and a plan there for the same task is resumed")
+	maxReplans := fs.Int("max-replans", 3, "with -plan, most times to plan again after a subtask fails")
+	runsDir := fs.String("runs", checkpoint.DefaultStore.Dir, "with -plan, directory the run is checkpointed in after every subtask, to be resumed with -resume (empty for none)")
+	resume := fs.String("resume", "", "resume the interrupted planned run with this ID after the last subtask it completed, with its task and plan")
+	memoryPath := fs.String("memory", "", memoryUsage+"; the facts relevant to the task are given to the agent up front (e.g. "+defaultMemory+")")
+	embedMemory := fs.Bool("embed-memory", false, embedUsage+", with the grounder's account")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo agent -task \"...\" [flags]")
+		fmt.Fprintln(os.Stderr, "       agentgo agent -resume RUN-ID [flags]")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "The planner and grounder may be different models on different accounts:")
+		fmt.Fprintln(os.Stderr, "PLANNER_API_KEY and GROUNDER_API_KEY, if set, are used instead of")
+		fmt.Fprintln(os.Stderr, "GEMINI_API_KEY, e.g. a strong reasoning model to plan and a cheap vision")
+		fmt.Fprintln(os.Stderr, "model to ground clicks, each billed separately.")
+	}
+	parseFlags(fs, args)
+	if (*task == "") == (*resume == "") || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	store := checkpoint.Store{Dir: *runsDir}
+	var run *checkpoint.Run
+	if *resume != "" {
+		var err error
+		if run, err = store.Load(*resume); err != nil {
+			log.Fatal(err)
+		}
+		if run.Kind != checkpoint.KindAgent {
+			log.Fatalf("run %s is a %s run; resume it with agentgo play", run.ID, run.Kind)
+		}
+		if err := run.Resumable(); err != nil {
+			log.Fatal(err)
+		}
+		*task, *planPath = run.Source, run.Plan
+	} else if *planPath != "" && *runsDir != "" {
+		abs, err := filepath.Abs(*planPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if run, err = store.New(checkpoint.KindAgent, *task, nil); err != nil {
+			log.Fatal(err)
+		}
+		run.Plan = abs
+		if err := run.Save(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	planner := connectRole(ctx, "PLANNER_API_KEY", *plannerModel)
+	defer planner.Close()
+	grounder := connectRole(ctx, "GROUNDER_API_KEY", *grounderModel)
+	defer grounder.Close()
+
+	drv := openDriver(*driverSpec, *display)
+	defer drv.Close()
+	armKillSwitch(drv)
+	ctx, untrack := killSwitch.Track(ctx, "agent task", run)
+	defer untrack()
+
+	// The role prices apply to the roles' models unless AGENTGO_PRICES
+	// names theirs.
+	extra := budget.Prices{}
+	for _, name := range vision.ParseModels(*grounderModel) {
+		if *grounderPrice > 0 {
+			extra[name] = *grounderPrice
+		}
+	}
+	if *plannerPrice > 0 {
+		extra[planner.ModelName()] = *plannerPrice
+	}
+	spend := newTracker(budget.Budget{MaxCost: *maxCost, MaxSteps: *maxSteps}, extra)
+	if spend != nil {
+		planner.Meter = spend
+		grounder.Meter = spend
+		defer reportSpend(spend, log.Printf)
+	}
+
+	bus := &agent.Bus{}
+	if *busLog != "" {
+		f, err := os.Create(*busLog)
+		if err != nil {
+			log.Fatalf("failed to create %s: %v", *busLog, err)
+		}
+		defer f.Close()
+		bus.Log = f
+	}
+	if *verbose {
+		bus.Listen(func(m agent.Message) {
+			log.Printf("%s -> %s %s: %s", m.From, m.To, m.Kind, m.Text)
+		})
+	}
+
+	var mem *memory.Store
+	if *memoryPath != "" {
+		var embedder *vision.Client
+		if *embedMemory {
+			embedder = grounder
+		}
+		mem = openMemory(*memoryPath, embedder)
+	}
+
+	guard := newGuard(drv, grounder, log.Printf)
+	masker := newDetector(drv)
+	newAgent := func() *agent.Agent {
+		model := planner.NewModel()
+		agent.Configure(model)
+		return &agent.Agent{
+			Chat:       model.StartChat(),
+			Dispatcher: &tools.Dispatcher{Desktop: drv, Vision: grounder, Memory: mem, Safety: guard, Secure: masker, Scrub: scrubber().String, OnCall: auditCall("task " + *task), Logf: func(string, ...any) {}},
+			Bus:        bus,
+			MaxTurns:   *maxTurns,
+			Budget:     spend,
+			Model:      planner.ModelName(),
+		}
+	}
+	prices := map[agent.Role]float64{agent.RolePlanner: *plannerPrice, agent.RoleGrounder: *grounderPrice}
+	log.Printf("Planning with %s, grounding with %s: %q", *plannerModel, grounder.ModelName(), *task)
+
+	if *planPath != "" {
+		p := &agent.Planner{
+			Model:      planner,
+			NewAgent:   newAgent,
+			Capture:    masker.Capture(drv.Capture),
+			Path:       *planPath,
+			MaxReplans: *maxReplans,
+		}
+		if run != nil {
+			log.Printf("Run %s", run.ID)
+			p.OnDone = func(s agent.Subtask) {
+				checkpointStep(run, drv, s.Name, log.Printf)
+			}
+		}
+		plan, err := p.Run(ctx, *task)
+		err = killswitch.Cause(ctx, err)
+		if run != nil {
+			if ferr := run.Finish(err); ferr != nil {
+				log.Printf("checkpoint: %v", ferr)
+			}
+		}
+		for i, s := range plan.Subtasks {
+			fmt.Printf("%2d. %-8s %s", i+1, s.Status, s.Name)
+			if s.Error != "" {
+				fmt.Printf(": %s", s.Error)
+			}
+			fmt.Println()
+		}
+		printUsage(os.Stdout, plan.Usage, prices)
+		if err != nil {
+			if run != nil {
+				log.Fatalf("%v; resume with agentgo agent -resume %s", err, run.ID)
+			}
+			log.Fatal(err)
+		}
+		return
+	}
+
+	res, runErr := newAgent().Run(ctx, *task)
+	runErr = killswitch.Cause(ctx, runErr)
+	if res != nil {
+		if res.Answer != "" {
+			fmt.Printf("done after %d turns and %d actions: %s\n", res.Turns, res.Calls, res.Answer)
+		}
+		printUsage(os.Stdout, res.Usage, prices)
+		if *out != "" {
+			data, err := json.MarshalIndent(res, "", "  ")
+			if err == nil {
+				err = os.WriteFile(*out, append(data, '\n'), 0o644)
+			}
+			if err != nil {
+				log.Fatalf("failed to write %s: %v", *out, err)
+			}
+		}
+	}
+	if runErr != nil {
+		log.Fatal(runErr)
+	}
+}
+
+// connectRole connects to model with the API key named key, or
+// GEMINI_API_KEY if it is not set.
+func connectRole(ctx context.Context, key, model string) *vision.Client {
+	apiKey := secrets.Lookup(key)
+	if apiKey == "" {
+		var err error
+		if apiKey, err = secrets.Get("GEMINI_API_KEY"); err != nil {
+			log.Fatal(err)
+		}
+	}
+	client, err := newVision(ctx, apiKey, model)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return client
+}
+
+// printUsage prints each role's model calls, with their cost at prices in
+// dollars per million tokens where one is given.
+func printUsage(w io.Writer, usage map[agent.Role]agent.Usage, prices map[agent.Role]float64) {
+	var total float64
+	for _, role := range []agent.Role{agent.RolePlanner, agent.RoleGrounder} {
+		u := usage[role]
+		line := fmt.Sprintf("%-9s %d calls, %d tokens, %d failed", role+":", u.Requests, u.Tokens, u.Errors)
+		if price := prices[role]; price > 0 {
+			line += fmt.Sprintf(", about $%.4f", u.Cost(price))
+			total += u.Cost(price)
+		}
+		fmt.Fprintln(w, line)
+	}
+	if total > 0 {
+		fmt.Fprintf(w, "total     about $%.4f\n", total)
+	}
+}
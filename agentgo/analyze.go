@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+
+	"agentGo/pkg/playback"
+	"agentGo/pkg/session"
+)
+
+// runAnalyze derives reports from recorded sessions and recordings.
+func runAnalyze(args []string) {
+	if len(args) < 1 || args[0] != "heatmap" {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo analyze heatmap [flags] session-dir|recording.csv")
+		os.Exit(2)
+	}
+	runHeatmap(args[1:])
+}
+
+// runHeatmap renders where the pointer dwelt and clicked over a screenshot.
+func runHeatmap(args []string) {
+	fs := flag.NewFlagSet("analyze heatmap", flag.ExitOnError)
+	out := fs.String("out", "heatmap.png", "PNG file to write")
+	layer := fs.String("layer", session.HeatBoth, "what to show: dwell (where the pointer rested), clicks, or both")
+	radius := fs.Float64("radius", 0.02, "how far each position spreads, as a fraction of the screen's width")
+	screenshot := fs.String("screenshot", "", "image to draw the heatmap over (default: the session's frame shown longest; required for recordings)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo analyze heatmap [flags] session-dir|recording.csv")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+
+	var events []session.Event
+	var background image.Image
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		if events, err = session.Load(path); err != nil {
+			log.Fatal(err)
+		}
+		if *screenshot == "" {
+			name := session.Representative(events)
+			if name == "" {
+				log.Fatal("the session has no frames; pass -screenshot")
+			}
+			if background, err = session.LoadFrame(path, name); err != nil {
+				log.Fatal(err)
+			}
+		}
+	} else {
+		samples, err := playback.LoadCSV(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *screenshot == "" {
+			log.Fatal("recordings have no screenshots; pass -screenshot")
+		}
+		events = recordingEvents(samples)
+	}
+	if *screenshot != "" {
+		img, err := loadImage(*screenshot)
+		if err != nil {
+			log.Fatalf("failed to load %s: %v", *screenshot, err)
+		}
+		background = img
+	}
+
+	heat, err := session.Heatmap(events, background, session.HeatmapOptions{Layer: *layer, Radius: *radius})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writePNG(*out, heat); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+	log.Printf("Wrote %s", *out)
+}
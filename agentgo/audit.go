@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"agentGo/pkg/audit"
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/safety"
+	"agentGo/pkg/script"
+)
+
+// commandName is the agentgo command running, the source of audit events.
+var commandName string
+
+var (
+	auditOnce sync.Once
+	audited   *audit.Log
+)
+
+// auditLog returns the log that AGENTGO_AUDIT, a comma-separated list of
+// destinations (see audit.Open), sends what runs do to, in
+// AGENTGO_AUDIT_FORMAT: json (the default) or cef. It is nil if
+// AGENTGO_AUDIT is not set.
+func auditLog() *audit.Log {
+	auditOnce.Do(func() {
+		dests := os.Getenv(dotenv.EnvName("audit"))
+		if dests == "" {
+			return
+		}
+		l, err := audit.Open(strings.Split(dests, ","), os.Getenv(dotenv.EnvName("audit-format")), log.Printf)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", dotenv.EnvName("audit"), err)
+		}
+		audited = l
+	})
+	return audited
+}
+
+// closeAudit sends the audit events still waiting.
+func closeAudit() {
+	if audited == nil {
+		return
+	}
+	if err := audited.Close(5 * time.Second); err != nil {
+		log.Printf("audit: %v", err)
+	}
+}
+
+// auditStep returns what records each step of the script run named run,
+// for script.Runner.OnDone, or nil if nothing is audited. Typed text is
+// counted, not recorded.
+func auditStep(run string) func(where string, step script.Step, err error) {
+	l := auditLog()
+	if l == nil {
+		return nil
+	}
+	return func(where string, step script.Step, err error) {
+		e := audit.Event{Source: commandName, Run: run, Action: step.Action, Target: step.Target, Detail: where}
+		switch step.Action {
+		case script.ActionType:
+			e.Detail += fmt.Sprintf(": %d characters", len([]rune(step.Text)))
+		case script.ActionKey:
+			e.Detail += ": " + step.Key
+		}
+		l.Record(auditOutcome(e, err))
+	}
+}
+
+// auditCall returns what records each tool call of the task named run,
+// for tools.Dispatcher.OnCall, or nil if nothing is audited.
+func auditCall(run string) func(name string, args json.RawMessage, err error) {
+	l := auditLog()
+	if l == nil {
+		return nil
+	}
+	return func(name string, args json.RawMessage, err error) {
+		e := audit.Event{Source: commandName, Run: scrubber().String(run), Action: name, Detail: scrubber().String(string(args))}
+		l.Record(auditOutcome(e, err))
+	}
+}
+
+// auditRun records the replay of a recording as a whole, since its events
+// are not steps.
+func auditRun(run string, samples int, err error) {
+	e := audit.Event{Source: commandName, Run: run, Action: "replay", Detail: fmt.Sprintf("%d samples", samples)}
+	auditLog().Record(auditOutcome(e, err))
+}
+
+// auditOutcome sets the outcome of e from err, with secrets scrubbed from
+// the error.
+func auditOutcome(e audit.Event, err error) audit.Event {
+	switch {
+	case err == nil:
+		e.Outcome = audit.OutcomeSuccess
+		return e
+	case errors.Is(err, safety.ErrRefused), errors.Is(err, desktop.ErrReadOnly):
+		e.Outcome = audit.OutcomeRefused
+	default:
+		e.Outcome = audit.OutcomeFailure
+	}
+	e.Error = scrubber().String(err.Error())
+	return e
+}
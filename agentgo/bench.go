@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/png"
+	"log"
+	"time"
+
+	"agentGo/pkg/frame"
+)
+
+// runBench times the frame path the agent, the recorder and screenshot
+// streams use, capture then PNG encoding, on the chosen driver, comparing
+// the encoder against image/png alone.
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	frames := fs.Int("frames", 20, "number of frames to capture and encode")
+	parseFlags(fs, args)
+	if *frames < 1 {
+		log.Fatal("-frames must be at least 1")
+	}
+
+	drv := openDriver(*driverSpec, *display)
+	defer drv.Close()
+
+	var captureTime, encodeTime, plainTime time.Duration
+	var size, plainSize int
+	for i := range *frames {
+		start := time.Now()
+		img, err := drv.Capture()
+		if err != nil {
+			log.Fatalf("failed to capture screen: %v", err)
+		}
+		captureTime += time.Since(start)
+
+		start = time.Now()
+		data, err := frame.EncodePNG(img)
+		if err != nil {
+			log.Fatal(err)
+		}
+		encodeTime += time.Since(start)
+		size += len(data)
+
+		var buf bytes.Buffer
+		start = time.Now()
+		if err := png.Encode(&buf, img); err != nil {
+			log.Fatalf("failed to encode image: %v", err)
+		}
+		plainTime += time.Since(start)
+		plainSize += buf.Len()
+		if i == 0 {
+			b := img.Bounds()
+			fmt.Printf("frame size: %dx%d\n", b.Dx(), b.Dy())
+		}
+		frame.Put(img)
+	}
+
+	n := time.Duration(*frames)
+	perFrame := (captureTime + encodeTime) / n
+	fmt.Printf("capture:          %v per frame\n", (captureTime / n).Round(time.Microsecond))
+	fmt.Printf("encode:           %v per frame, %d KB\n", (encodeTime / n).Round(time.Microsecond), size / *frames / 1024)
+	fmt.Printf("encode image/png: %v per frame, %d KB\n", (plainTime / n).Round(time.Microsecond), plainSize / *frames / 1024)
+	fmt.Printf("end to end:       %v per frame, %.1f fps\n", perFrame.Round(time.Microsecond), float64(time.Second)/float64(perFrame))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"agentGo/pkg/cdp"
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/script"
+	"agentGo/pkg/vision"
+)
+
+// defaultCDP is the DevTools endpoint of scripts that manage a browser
+// when AGENTGO_CDP is not set.
+const defaultCDP = "http://localhost:9222"
+
+// browserPage returns the browser page whose DOM resolves script targets:
+// the driver itself for -driver cdp://, or the page of the DevTools
+// endpoint AGENTGO_CDP names (e.g. http://localhost:9222) when the local
+// desktop shows the browser. It returns nil when there is neither. Pages
+// it attaches to are the caller's to close.
+//
+// When manage is set, because the script launches the browser or opens
+// tabs, the endpoint defaults to localhost:9222 and a browser that is not
+// running yet leaves the page unattached, for a launch_browser step to
+// attach. AGENTGO_BROWSER names the browser to launch.
+func browserPage(ctx context.Context, drv desktop.Driver, manage bool) (page *cdp.Client, attached bool, err error) {
+	if c, ok := drv.(*cdp.Client); ok {
+		c.Executable = os.Getenv(dotenv.EnvName("browser"))
+		return c, false, nil
+	}
+	endpoint := os.Getenv(dotenv.EnvName("cdp"))
+	if endpoint == "" {
+		if !manage {
+			return nil, false, nil
+		}
+		endpoint = defaultCDP
+	}
+	if _, ok := drv.(*desktop.Local); !ok {
+		return nil, false, fmt.Errorf("%s applies only to the local driver; use -driver cdp://host:port to drive a browser directly", dotenv.EnvName("cdp"))
+	}
+	if !manage {
+		c, err := cdp.Dial(ctx, endpoint, "")
+		if err != nil {
+			return nil, false, err
+		}
+		return c, true, nil
+	}
+	c := cdp.New(endpoint)
+	c.Executable = os.Getenv(dotenv.EnvName("browser"))
+	// The browser may not be running yet.
+	_ = c.Attach(ctx, "")
+	return c, true, nil
+}
+
+// browserLocator finds targets in page: by query, then by letting client
+// pick from an outline of the page, and only then by pixels.
+func browserLocator(drv desktop.Driver, page *cdp.Client, client *vision.Client, logf func(string, ...any)) script.Inspector {
+	loc := &cdp.Locator{Page: page, Model: client, Fallback: client, Logf: logf}
+	if l, ok := drv.(*desktop.Local); ok {
+		loc.Screen = l.ScreenBounds
+	}
+	return loc
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var confirmMu sync.Mutex
+
+// confirmOnTerminal asks on the terminal agentgo runs in whether to run
+// command. It asks there rather than in a dialog on the desktop, since the
+// agent drives the desktop and could answer a dialog itself. Without a
+// terminal, as when an MCP client starts the server, nothing can be
+// confirmed.
+func confirmOnTerminal(ctx context.Context, command string) (bool, error) {
+	return askOnTerminal(ctx, fmt.Sprintf("\nThe agent wants to run:\n  %s\nAllow? [y/N] ", command))
+}
+
+// askOnTerminal puts a yes-or-no question on the terminal and reports
+// whether it was answered yes.
+func askOnTerminal(ctx context.Context, question string) (bool, error) {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+	bar := liveProgress.Load()
+	bar.Pause()
+	defer bar.Resume()
+	in, out, err := openTerminal()
+	if err != nil {
+		return false, fmt.Errorf("no terminal to ask on: %w", err)
+	}
+	defer in.Close()
+	if out != in {
+		defer out.Close()
+	}
+	fmt.Fprint(out, question)
+	answer := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(in).ReadString('\n')
+		answer <- line
+	}()
+	select {
+	case line := <-answer:
+		line = strings.ToLower(strings.TrimSpace(line))
+		return line == "y" || line == "yes", nil
+	case <-ctx.Done():
+		fmt.Fprintln(out)
+		return false, ctx.Err()
+	}
+}
+
+func openTerminal() (in, out *os.File, err error) {
+	if runtime.GOOS != "windows" {
+		f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		return f, f, err
+	}
+	if in, err = os.Open("CONIN$"); err != nil {
+		return nil, nil, err
+	}
+	if out, err = os.OpenFile("CONOUT$", os.O_WRONLY, 0); err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+	return in, out, nil
+}
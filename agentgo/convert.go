@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/playback"
+	"agentGo/pkg/session"
+)
+
+// runConvert converts recordings between CSV and session events in JSONL,
+// JSON or protobuf, so that recordings and the tools that read sessions work
+// together.
+func runConvert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := fs.String("to", "", "format to write: csv, jsonl, json, pb or pb.zst (default: from the output file's extension)")
+	screen := fs.String("screen", "", "screen size, as WIDTHxHEIGHT, to normalize CSV recordings in pixels by")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo convert [flags] in.csv|in.jsonl|in.json|in.pb[.zst] out.csv|out.jsonl|out.json|out.pb[.zst]")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "CSV recordings in normalized coordinates (norm_x, norm_y) and in pixels")
+		fmt.Fprintln(os.Stderr, "(x, y; needs -screen) are both read. JSONL is the session log format, one")
+		fmt.Fprintln(os.Stderr, "event per line; JSON is the same events as an array; pb is the compact")
+		fmt.Fprintln(os.Stderr, "protobuf log format, and pb.zst the same compressed with the zstd command.")
+	}
+	parseFlags(fs, args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	in, out := fs.Arg(0), fs.Arg(1)
+	format := strings.ToLower(*to)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(out)), ".")
+		if format == "zst" {
+			format = string(session.FormatProtoZstd)
+		}
+	}
+	switch format {
+	case "csv", "json", "jsonl", string(session.FormatProto), string(session.FormatProtoZstd):
+	default:
+		log.Fatalf("invalid output format %q (want csv, jsonl, json, pb or pb.zst)", format)
+	}
+	var size image.Point
+	if *screen != "" {
+		w, h, err := desktop.ParseSize(*screen)
+		if err != nil {
+			log.Fatalf("invalid -screen: %v", err)
+		}
+		size = image.Pt(w, h)
+	}
+
+	events, err := readEvents(in, size)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch format {
+	case "csv":
+		err = playback.SaveCSV(out, recordingSamples(events))
+	case "json":
+		var data []byte
+		if data, err = json.MarshalIndent(events, "", "  "); err == nil {
+			err = os.WriteFile(out, append(data, '\n'), 0o644)
+		}
+	default:
+		var buf bytes.Buffer
+		if err = session.EncodeEvents(&buf, events, session.Format(format)); err == nil {
+			err = os.WriteFile(out, buf.Bytes(), 0o644)
+		}
+	}
+	if err != nil {
+		log.Fatalf("failed to write %s: %v", out, err)
+	}
+	log.Printf("Wrote %s", out)
+}
+
+// readEvents reads a recording or session log as session events.
+func readEvents(path string, screen image.Point) ([]session.Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		samples, _, err := playback.DecodeCSV(f, screen)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return append([]session.Event{{Kind: session.KindStart, Version: session.Version}}, recordingEvents(samples)...), nil
+	case ".json":
+		var events []session.Event
+		if err := json.NewDecoder(f).Decode(&events); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return events, nil
+	default:
+		events, _, err := session.DecodeEvents(f, session.FormatOf(strings.ToLower(path)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return events, nil
+	}
+}
+
+// recordingEvents turns a recording's samples into session events: position
+// samples into moves and click samples into clicks.
+func recordingEvents(samples []playback.Sample) []session.Event {
+	events := make([]session.Event, 0, len(samples))
+	for _, s := range samples {
+		e := session.Event{Time: s.Timestamp, Kind: session.KindMove, X: s.X, Y: s.Y, Cursor: s.Cursor}
+		if s.Button != "" {
+			e.Kind, e.Button, e.Clicks, e.Hold = session.KindClick, s.Button, s.Clicks, s.Hold
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// recordingSamples turns session events back into a recording: moves into
+// position samples and clicks into click samples. Other events have no
+// place in a recording and are dropped.
+func recordingSamples(events []session.Event) []playback.Sample {
+	var samples []playback.Sample
+	for _, e := range events {
+		switch e.Kind {
+		case session.KindMove:
+			samples = append(samples, playback.Sample{Timestamp: e.Time, X: e.X, Y: e.Y, Cursor: e.Cursor})
+		case session.KindClick:
+			samples = append(samples, playback.Sample{Timestamp: e.Time, X: e.X, Y: e.Y, Button: e.Button, Clicks: e.Clicks, Hold: e.Hold})
+		}
+	}
+	return samples
+}
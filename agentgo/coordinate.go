@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"agentGo/pkg/fleet"
+	"agentGo/pkg/script"
+	"agentGo/pkg/server"
+)
+
+func runCoordinate(args []string) {
+	fs := flag.NewFlagSet("coordinate", flag.ExitOnError)
+	hostsPath := fs.String("hosts", "", "JSON file listing worker addresses and labels (required)")
+	selector := fs.String("select", "", "only use hosts whose labels match key=value[,key=value...]")
+	outPath := fs.String("out", "", "write aggregated results as JSON to this file")
+	timeout := fs.Duration("timeout", 0, "per-task timeout enforced by the worker (0 for none)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo coordinate -hosts hosts.json [flags] task.json|recording.csv ...")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Tasks go to workers started with agentgo serve, over its HTTP/JSON API. Workers")
+		fmt.Fprintln(os.Stderr, "listen only on 127.0.0.1 unless given -listen, e.g. -listen :7070.")
+	}
+	parseFlags(fs, args)
+
+	if *hostsPath == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	hosts, err := fleet.LoadHosts(*hostsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sel, err := fleet.ParseSelector(*selector)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tasks := make([]server.TaskRequest, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		req := server.TaskRequest{Name: filepath.Base(path), Timeout: script.Duration(*timeout)}
+		if req.Script, req.Recording, err = loadTask(path); err != nil {
+			log.Fatal(err)
+		}
+		tasks = append(tasks, req)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	coordinator := &fleet.Coordinator{Hosts: hosts, Connect: newWorkerClient}
+	results, err := coordinator.Dispatch(ctx, sel, tasks)
+	if err != nil && results == nil {
+		log.Fatalf("dispatch failed: %v", err)
+	}
+
+	failed := 0
+	fmt.Printf("%-30s %-22s %-10s %10s  %s\n", "TASK", "HOST", "STATE", "DURATION", "ERROR")
+	for _, r := range results {
+		if r.State != server.StateSuccess {
+			failed++
+		}
+		fmt.Printf("%-30s %-22s %-10s %10s  %s\n", r.Task, r.Host, r.State, r.Duration.Round(time.Millisecond), r.Error)
+	}
+	fmt.Printf("\n%d tasks, %d succeeded, %d failed\n", len(results), len(results)-failed, failed)
+
+	if *outPath != "" {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		if err := os.WriteFile(*outPath, data, 0644); err != nil {
+			log.Fatalf("failed to write results: %v", err)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/filelock"
+	"agentGo/pkg/notify"
+	"agentGo/pkg/playback"
+	"agentGo/pkg/schedule"
+	"agentGo/pkg/script"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/vars"
+	"agentGo/pkg/voice"
+)
+
+func runDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	schedulePath := fs.String("schedule", "", "JSON schedule file mapping cron expressions to scripts or recordings (required)")
+	stateDir := fs.String("state-dir", ".agentgo", "directory for daemon state such as job history")
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	listen := fs.Bool("voice", false, "take spoken commands from the microphone (needs SoX and GEMINI_API_KEY): \"agent, stop\" stops every run, \"agent, run <job>\" runs a job now, and \"agent, take over and <task>\" has the agent carry out a task")
+	wake := fs.String("wake", "agent", "word spoken commands start with")
+	transcriber := fs.String("transcriber", "gemini", voice.TranscriberUsage)
+	healthAddr := fs.String("health", "", "serve /healthz and /readyz on this address, e.g. 127.0.0.1:7071, for orchestrators and monitors")
+	maxRunning := fs.Int("max-running", 0, "with -health, report not ready while more than this many runs are in progress (0 for no limit)")
+	parseFlags(fs, args)
+
+	if *schedulePath == "" {
+		log.Fatal("daemon: -schedule is required")
+	}
+	jobs, err := schedule.LoadFile(*schedulePath)
+	if err != nil {
+		log.Fatalf("failed to load schedule: %v", err)
+	}
+	history, err := schedule.NewHistory(filepath.Join(*stateDir, "history"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	artifactsDir := filepath.Join(*stateDir, "artifacts")
+	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
+		log.Fatalf("failed to create artifacts directory: %v", err)
+	}
+
+	drv := openDriver(*driverSpec, *display)
+	defer drv.Close()
+	armKillSwitch(drv)
+
+	baseDir := filepath.Dir(*schedulePath)
+	active := &runs{}
+	desk := newDesktopLock(*driverSpec)
+	scheduler := &schedule.Scheduler{
+		Jobs:    jobs,
+		History: history,
+		Run: func(ctx context.Context, job schedule.Job) error {
+			ctx, done := active.track(ctx)
+			defer done()
+			// Jobs due at the same time take turns at the desktop, as do
+			// those of other daemons driving it.
+			release, err := desk.acquire(ctx, fmt.Sprintf("job %q", job.Name))
+			if err != nil {
+				return err
+			}
+			defer release()
+			return runJob(ctx, drv, job, baseDir)
+		},
+		OnFinish: func(ctx context.Context, job schedule.Job, run *schedule.Run) {
+			finishJob(ctx, drv, job, run, artifactsDir)
+		},
+	}
+
+	if *listen {
+		client, err := connectVision(ctx)
+		if err != nil {
+			log.Fatalf("voice commands need a model to transcribe them: %v", err)
+		}
+		defer client.Close()
+		openAIKey := secrets.Lookup("OPENAI_API_KEY")
+		t, err := voice.ParseTranscriber(*transcriber, openAIKey, client)
+		if err != nil {
+			log.Fatalf("invalid -transcriber: %v", err)
+		}
+		go listenForCommands(ctx, drv, client, t, scheduler, active, desk, *wake)
+	}
+
+	if *healthAddr != "" {
+		go serveHealth(ctx, *healthAddr, drv, scheduler, active, *maxRunning)
+	}
+
+	log.Printf("Daemon started with %d scheduled jobs.", len(jobs))
+	if err := scheduler.Start(ctx); err != nil {
+		log.Fatalf("scheduler failed: %v", err)
+	}
+	log.Println("Daemon stopped.")
+}
+
+// desktopLock serializes the runs that drive one desktop, whether they
+// are in this process or in other agentgo processes of the same user. It is
+// the path of a lock file named after the desktop.
+type desktopLock string
+
+// newDesktopLock returns the lock of the desktop driverSpec names: the
+// local one is told apart by its X or Wayland display, others by their
+// spec.
+func newDesktopLock(driverSpec string) desktopLock {
+	desktop := driverSpec
+	if desktop == "" || desktop == "local" {
+		desktop = "local " + os.Getenv("DISPLAY") + " " + os.Getenv("WAYLAND_DISPLAY")
+	}
+	h := fnv.New64a()
+	h.Write([]byte(desktop))
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return desktopLock(filepath.Join(dir, "agentgo", "locks", fmt.Sprintf("desktop-%x.lock", h.Sum64())))
+}
+
+// acquire waits until no other run holds the desktop, or ctx is done, and
+// takes it for the run described by what.
+func (l desktopLock) acquire(ctx context.Context, what string) (release func(), err error) {
+	lock, err := filelock.Try(string(l))
+	if errors.Is(err, filelock.ErrLocked) {
+		log.Printf("%s: waiting for another run to finish with the desktop", what)
+		lock, err = filelock.Acquire(ctx, string(l))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take the desktop: %w", err)
+	}
+	return func() { lock.Release() }, nil
+}
+
+// runJob executes a scheduled script or recording on the driver's desktop.
+// Relative paths are resolved against baseDir.
+func runJob(ctx context.Context, drv desktop.Driver, job schedule.Job, baseDir string) error {
+	variables := vars.New()
+	for name, value := range job.Vars {
+		variables.Set(name, value)
+	}
+
+	if job.Recording != "" {
+		path, err := variables.Expand(job.Recording)
+		if err != nil {
+			return err
+		}
+		path = resolvePath(baseDir, path)
+		if err := checkSeal(path); err != nil {
+			return err
+		}
+		samples, err := playback.LoadCSV(path)
+		if err != nil {
+			return err
+		}
+		return execute(ctx, drv, nil, samples, variables, nil, nil)
+	}
+
+	path, err := variables.Expand(job.Script)
+	if err != nil {
+		return err
+	}
+	path = resolvePath(baseDir, path)
+	if err := checkSeal(path); err != nil {
+		return err
+	}
+	s, err := script.Load(path)
+	if err != nil {
+		return err
+	}
+	return execute(ctx, drv, s, nil, variables, nil, nil)
+}
+
+// finishJob writes the run report, captures a screenshot of failed runs, and
+// sends the job's notifications.
+func finishJob(ctx context.Context, drv desktop.Driver, job schedule.Job, run *schedule.Run, artifactsDir string) {
+	prefix := filepath.Join(artifactsDir, fmt.Sprintf("%s-%s", job.Name, run.Start.Format("20060102-150405")))
+
+	if run.Status == schedule.StatusFailed {
+		if img, err := drv.Capture(); err != nil {
+			log.Printf("failed to capture failure screenshot: %v", err)
+		} else if err := savePNG(prefix+".png", img); err != nil {
+			log.Printf("failed to save failure screenshot: %v", err)
+		} else {
+			run.Screenshot = prefix + ".png"
+		}
+	}
+	if run.Status != schedule.StatusSkipped {
+		report, err := json.MarshalIndent(struct {
+			Run schedule.Run `json:"run"`
+			Job schedule.Job `json:"job"`
+		}{*run, job}, "", "  ")
+		if err == nil {
+			err = os.WriteFile(prefix+".json", report, 0644)
+		}
+		if err != nil {
+			log.Printf("failed to write run report: %v", err)
+		} else {
+			run.Report = prefix + ".json"
+		}
+	}
+
+	event := notify.Event{
+		Job:            run.Job,
+		Status:         run.Status,
+		Start:          run.Start,
+		End:            run.End,
+		Duration:       run.Duration(),
+		Error:          run.Error,
+		ReportPath:     run.Report,
+		ScreenshotPath: run.Screenshot,
+	}
+	if err := notify.Send(ctx, job.Notify, event); err != nil {
+		log.Printf("job %q: notification failed: %v", job.Name, err)
+	}
+}
+
+func savePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	stateDir := fs.String("state-dir", ".agentgo", "directory for daemon state such as job history")
+	limit := fs.Int("n", 20, "number of most recent runs to show per job")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo history [flags] [job...]")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	history := &schedule.History{Dir: filepath.Join(*stateDir, "history")}
+	jobs := fs.Args()
+	if len(jobs) == 0 {
+		matches, err := filepath.Glob(filepath.Join(history.Dir, "*.jsonl"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, m := range matches {
+			jobs = append(jobs, filepath.Base(m[:len(m)-len(".jsonl")]))
+		}
+	}
+
+	for _, job := range jobs {
+		runs, err := history.Recent(job, *limit)
+		if err != nil {
+			log.Fatalf("failed to read history for %q: %v", job, err)
+		}
+		fmt.Printf("%s (%d runs)\n", job, len(runs))
+		for _, run := range runs {
+			line := fmt.Sprintf("  %s  %-8s %8s", run.Start.Format("2006-01-02 15:04:05"), run.Status, run.Duration().Round(time.Millisecond))
+			if run.Error != "" {
+				line += "  " + run.Error
+			}
+			fmt.Println(line)
+		}
+	}
+}
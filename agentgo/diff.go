@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/vision"
+)
+
+// runDiff describes the meaningful differences between two screenshots, or
+// in live mode between the screen before and after each action.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	live := fs.Bool("live", false, "compare the live screen: capture now, then describe what changed each time Enter is pressed")
+	driverSpec := fs.String("driver", "local", "with -live, "+driverUsage)
+	display := fs.Int("display", 0, "with -live, "+displayUsage)
+	modelName := fs.String("model", vision.DefaultModel, "Gemini model that describes the differences; a comma-separated list falls back to later models when one fails")
+	focus := fs.String("focus", "", "what differences matter, e.g. \"text and values, not layout\"")
+	highlight := fs.String("highlight", "", "write the after image with the changed area outlined to this PNG file")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo diff [flags] before.png after.png")
+		fmt.Fprintln(os.Stderr, "       agentgo diff -live [flags]")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	if *live != (fs.NArg() == 0) || (!*live && fs.NArg() != 2) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	apiKey, err := secrets.Get("GEMINI_API_KEY")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := newVision(ctx, apiKey, *modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	describe := func(before, after image.Image) {
+		if *highlight != "" {
+			if d, err := vision.CompareImages(before, after, 24); err == nil {
+				if err := writePNG(*highlight, vision.Highlight(after, d.Changed)); err != nil {
+					log.Printf("failed to write %s: %v", *highlight, err)
+				}
+			}
+		}
+		text, err := client.DescribeDiff(ctx, before, after, *focus)
+		if err != nil {
+			log.Printf("failed to describe differences: %v", err)
+			return
+		}
+		fmt.Println(text)
+	}
+
+	if !*live {
+		before, err := loadImage(fs.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		after, err := loadImage(fs.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		describe(before, after)
+		return
+	}
+
+	drv := openDriver(*driverSpec, *display)
+	defer drv.Close()
+	before, err := drv.Capture()
+	if err != nil {
+		log.Fatalf("failed to capture screen: %v", err)
+	}
+	log.Println("Captured the screen. Act, then press Enter to see what changed; Ctrl+D to stop.")
+	lines := bufio.NewScanner(os.Stdin)
+	for ctx.Err() == nil && lines.Scan() {
+		after, err := drv.Capture()
+		if err != nil {
+			log.Printf("failed to capture screen: %v", err)
+			continue
+		}
+		describe(before, after)
+		before = after
+	}
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
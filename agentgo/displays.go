@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"agentGo/pkg/desktop"
+)
+
+// runDisplays lists the local displays with the indexes -display accepts.
+func runDisplays(args []string) {
+	fs := flag.NewFlagSet("displays", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the displays as JSON")
+	parseFlags(fs, args)
+
+	displays := desktop.Displays()
+	if *asJSON {
+		data, err := json.MarshalIndent(displays, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to encode displays: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	if len(displays) == 0 {
+		fmt.Fprintln(os.Stderr, "no active displays")
+		os.Exit(1)
+	}
+	fmt.Printf("%-6s %-28s %-6s %s\n", "INDEX", "BOUNDS", "SCALE", "PRIMARY")
+	for _, d := range displays {
+		bounds := fmt.Sprintf("%dx%d at (%d,%d)", d.Bounds.Dx(), d.Bounds.Dy(), d.Bounds.Min.X, d.Bounds.Min.Y)
+		primary := ""
+		if d.Primary {
+			primary = "yes"
+		}
+		fmt.Printf("%-6d %-28s %-6.2f %s\n", d.Index, bounds, d.Scale, primary)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"agentGo/pkg/distill"
+	"agentGo/pkg/playback"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/vision"
+)
+
+// runDistill turns a recording into a script that finds elements by
+// description.
+func runDistill(args []string) {
+	fs := flag.NewFlagSet("distill", flag.ExitOnError)
+	framesDir := fs.String("frames", "", "directory of the recorder's screenshots for the recording (default: the recording's directory)")
+	out := fs.String("out", "", "write the script to this file instead of standard output")
+	name := fs.String("name", "", "script name (default: the recording's file name)")
+	modelName := fs.String("model", vision.DefaultModel, "Gemini model that writes the script; a comma-separated list falls back to later models when one fails")
+	dwell := fs.Duration("dwell", 2*time.Second, "how long the pointer must rest for the spot to count as an event")
+	radius := fs.Float64("radius", 0.01, "how far, as a fraction of the screen, the pointer may drift while resting")
+	maxFrames := fs.Int("max-frames", 16, "most screenshots sent to the model")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo distill [flags] recording.csv")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	samples, err := playback.LoadCSV(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	events := distill.Events(samples, *dwell, *radius)
+	log.Printf("Found %d events in %d samples.", len(events), len(samples))
+
+	dir := *framesDir
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+	frames, err := distill.LoadFrames(dir)
+	if err != nil {
+		log.Fatalf("failed to list frames: %v", err)
+	}
+	if len(frames) == 0 {
+		log.Printf("No screenshots in %s; distilling from pointer events alone.", dir)
+	}
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	apiKey, err := secrets.Get("GEMINI_API_KEY")
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+	client, err := newVision(ctx, apiKey, *modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	d := &distill.Distiller{Client: client, Frames: frames, MaxFrames: *maxFrames}
+	s, err := d.Distill(ctx, *name, events)
+	if err != nil {
+		log.Fatalf("failed to distill %s: %v", path, err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		log.Fatalf("failed to encode script: %v", err)
+	}
+	data = append(data, '\n')
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		log.Fatalf("failed to write script: %v", err)
+	}
+	log.Printf("Wrote %d steps to %s.", len(s.Steps), *out)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"agentGo/pkg/checkpoint"
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/doctor"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/frame"
+	"agentGo/pkg/preflight"
+	"agentGo/pkg/secrets"
+)
+
+// runDoctor checks that this machine can run agentGo and says how to fix
+// what it cannot.
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	asJSON := fs.Bool("json", false, "print the results as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo doctor [flags]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Checks that the display can be captured, input is permitted, display")
+		fmt.Fprintln(os.Stderr, "scaling lines up with capture, the model can be reached, there is disk")
+		fmt.Fprintln(os.Stderr, "space for artifacts, and robotgo's native libraries are installed, and")
+		fmt.Fprintln(os.Stderr, "says how to fix each problem. It exits with status 1 if a check fails.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	spec := *driverSpec
+	if *display != 0 {
+		spec = fmt.Sprintf("local:%d", *display)
+	}
+	drv, err := desktop.Open(spec)
+	if err != nil {
+		log.Fatalf("failed to open driver: %v", err)
+	}
+	defer drv.Close()
+	_, local := drv.(*desktop.Local)
+
+	checks := []doctor.Check{{Name: "display", Run: func(context.Context) doctor.Finding { return checkDisplay(drv, local) }}}
+	if local {
+		checks = append(checks,
+			doctor.Check{Name: "input", Run: func(context.Context) doctor.Finding { return checkInput() }},
+			doctor.Check{Name: "dpi", Run: func(context.Context) doctor.Finding { return checkScaling() }},
+		)
+	}
+	checks = append(checks,
+		doctor.Check{Name: "model", Run: checkModel},
+		doctor.Check{Name: "disk", Run: func(context.Context) doctor.Finding { return checkDisk() }},
+		doctor.Check{Name: "native", Run: func(context.Context) doctor.Finding { return checkNative() }},
+	)
+	results, worst := doctor.Run(context.Background(), checks, 30*time.Second)
+
+	if *asJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to encode results: %v", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, r := range results {
+			fmt.Printf("%-8s %-8s %s\n", r.Name, r.Status, r.Detail)
+			if r.Fix != "" {
+				fmt.Printf("%-17s fix: %s\n", "", r.Fix)
+			}
+		}
+	}
+	if worst == doctor.Failed {
+		os.Exit(1)
+	}
+}
+
+// checkDisplay captures the screen, looking for the black frames a missing
+// permission leaves.
+func checkDisplay(drv desktop.Driver, local bool) doctor.Finding {
+	if local {
+		if runtime.GOOS == "linux" && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return doctor.Fail("no DISPLAY or WAYLAND_DISPLAY: not in a desktop session",
+				"run from a desktop session, export DISPLAY=:0 to reach the one on this machine, or use -driver xvfb for a virtual one")
+		}
+		if err := desktop.UseCapture(os.Getenv(dotenv.EnvName("capture"))); err != nil {
+			return doctor.Fail(err.Error(), "set "+dotenv.EnvName("capture")+" to native or leave it unset")
+		}
+		if len(desktop.Displays()) == 0 {
+			return doctor.Fail("no active displays", "connect or wake a display; on a headless machine use -driver xvfb")
+		}
+	}
+	img, err := drv.Capture()
+	if err != nil {
+		fix := "check that this session owns the display it captures"
+		switch {
+		case runtime.GOOS == "darwin":
+			fix = "grant Screen Recording: agentgo preflight -open"
+		case os.Getenv("WAYLAND_DISPLAY") != "":
+			fix = "under Wayland, set " + dotenv.EnvName("capture") + "=native to capture through the desktop portal and PipeWire"
+		}
+		return doctor.Fail(fmt.Sprintf("failed to capture the screen: %v", err), fix)
+	}
+	defer frame.Put(img)
+	b := img.Bounds()
+	if b.Empty() {
+		return doctor.Fail("the screen capture is empty", "wake the display and unlock the session")
+	}
+	detail := fmt.Sprintf("%dx%d captured", b.Dx(), b.Dy())
+	if local {
+		detail = fmt.Sprintf("%d displays, %s", len(desktop.Displays()), detail)
+	}
+	if black(img) {
+		fix := "wake the display and unlock the session"
+		if runtime.GOOS == "darwin" {
+			fix = "grant Screen Recording to the app agentgo runs from: agentgo preflight -open"
+		}
+		return doctor.Warn(detail+", but entirely black", fix)
+	}
+	return doctor.Pass("%s", detail)
+}
+
+// black reports whether every sampled pixel of img is black.
+func black(img image.Image) bool {
+	b := img.Bounds()
+	stepX, stepY := max(1, b.Dx()/64), max(1, b.Dy()/64)
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			if r, g, bl, _ := img.At(x, y).RGBA(); r|g|bl != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkInput checks that input can be injected with the driver
+// AGENTGO_INPUT selects.
+func checkInput() doctor.Finding {
+	if missing := preflight.Missing(); len(missing) > 0 {
+		names := make([]string, len(missing))
+		for i, p := range missing {
+			names[i] = p.Name
+		}
+		return doctor.Fail("missing permissions: "+strings.Join(names, ", "),
+			"run agentgo preflight -open, enable agentgo's terminal or binary in each pane, then restart it")
+	}
+	name := os.Getenv(dotenv.EnvName("input"))
+	if err := desktop.UseInputDriver(name); err != nil {
+		fix := "fix the driver's setup, or unset " + dotenv.EnvName("input") + " to use robotgo"
+		if name == desktop.InputUinput {
+			fix = "add yourself to the group owning /dev/uinput (often input) or add a udev rule granting it, then log in again"
+		}
+		return doctor.Fail(fmt.Sprintf("input driver %s: %v", name, err), fix)
+	}
+	if name == "" {
+		name = desktop.InputRobotgo
+	}
+	x, y, err := desktop.Input().Position()
+	if err != nil {
+		fix := "set " + dotenv.EnvName("input") + " to another driver"
+		if name == desktop.InputRobotgo && !doctor.Cgo {
+			fix = "rebuild with CGO_ENABLED=1, or set " + dotenv.EnvName("input") + " to xdotool or uinput (Linux) or sendinput (Windows)"
+		}
+		return doctor.Fail(fmt.Sprintf("%s cannot read the pointer: %v", name, err), fix)
+	}
+	detail := fmt.Sprintf("%s, pointer at (%d, %d)", name, x, y)
+	if readOnly() {
+		return doctor.Warn(detail+", but read-only mode refuses input", "unset "+dotenv.EnvName("read-only")+" to let runs act")
+	}
+	if name == desktop.InputRobotgo && os.Getenv("WAYLAND_DISPLAY") != "" {
+		return doctor.Warn(detail+", but robotgo reaches only XWayland windows under Wayland",
+			"set "+dotenv.EnvName("input")+"=uinput to drive native Wayland windows")
+	}
+	return doctor.Pass("%s", detail)
+}
+
+// checkScaling checks that display bounds, scale and the coordinates input
+// uses line up, since a mismatch makes clicks land off target.
+func checkScaling() doctor.Finding {
+	displays := desktop.Displays()
+	if len(displays) == 0 {
+		return doctor.Warn("no displays to check", "")
+	}
+	scales := map[float64]bool{}
+	var primary desktop.Display
+	for _, d := range displays {
+		scales[d.Scale] = true
+		if d.Primary {
+			primary = d
+		}
+	}
+	if primary.Bounds.Empty() {
+		primary = displays[0]
+	}
+	w, h := desktop.NewExecutor().ScreenSize()
+	detail := fmt.Sprintf("primary display %dx%d at scale %.2f, input %dx%d", primary.Bounds.Dx(), primary.Bounds.Dy(), primary.Scale, w, h)
+	if w > 0 && primary.Scale > 0 {
+		ratio := float64(primary.Bounds.Dx()) / float64(w)
+		if math.Abs(ratio-1) > 0.02 && math.Abs(ratio-primary.Scale) > 0.02 {
+			fix := "set the display's scale to 100% or restart the session after changing it"
+			if runtime.GOOS == "windows" {
+				fix = "set the display's scale to 100%, or under Compatibility > Change high DPI settings let the application handle scaling"
+			}
+			return doctor.Fail(fmt.Sprintf("%s: input is scaled by %.2f, not the display's %.2f", detail, ratio, primary.Scale), fix)
+		}
+	}
+	if len(scales) > 1 {
+		return doctor.Warn(detail+"; displays have different scales",
+			"pick one display with -display, or give the displays the same scale")
+	}
+	return doctor.Pass("%s", detail)
+}
+
+// checkModel checks that the model answers.
+func checkModel(ctx context.Context) doctor.Finding {
+	if secrets.Lookup("GEMINI_API_KEY") == "" {
+		return doctor.Warn("GEMINI_API_KEY is not set: scripts that read the screen and the agent cannot run",
+			"set GEMINI_API_KEY, or store it in the keychain with agentgo secret set GEMINI_API_KEY")
+	}
+	name, err := pingModel()(ctx)
+	if err != nil {
+		fix := "check GEMINI_API_KEY, " + dotenv.EnvName("model") + " and that this machine can reach generativelanguage.googleapis.com (HTTPS_PROXY if behind a proxy)"
+		return doctor.Fail(err.Error(), fix)
+	}
+	return doctor.Pass("%s answers", name)
+}
+
+// Disk space below which artifacts may not fit.
+const (
+	diskLow      = 1 << 30
+	diskCritical = 100 << 20
+)
+
+// checkDisk checks that the directories runs write artifacts, checkpoints
+// and sessions to are writable and have space.
+func checkDisk() doctor.Finding {
+	dirs := []struct{ name, path, env string }{
+		{"artifacts", artifactDir(), dotenv.EnvName("artifacts")},
+		{"runs", checkpoint.DefaultStore.Dir, ""},
+	}
+	if dir := os.Getenv(dotenv.EnvName("session")); dir != "" {
+		dirs = append(dirs, struct{ name, path, env string }{"session", dir, dotenv.EnvName("session")})
+	}
+	var details []string
+	worst := doctor.Pass("")
+	for _, d := range dirs {
+		f := checkDir(d.path, d.env)
+		details = append(details, fmt.Sprintf("%s %s", d.name, f.Detail))
+		if f.Status > worst.Status {
+			worst = f
+			worst.Detail = d.name + " " + f.Detail
+		}
+	}
+	if worst.Status == doctor.OK {
+		worst.Detail = strings.Join(details, ", ")
+	}
+	return worst
+}
+
+// checkDir checks the space and permissions of dir, which need not exist
+// yet. env is the variable that moves it, if any.
+func checkDir(dir, env string) doctor.Finding {
+	elsewhere := "free up space"
+	if env != "" {
+		elsewhere += " or point " + env + " at a larger disk"
+	}
+	// The nearest directory that exists is where dir will be created.
+	existing, err := filepath.Abs(dir)
+	if err != nil {
+		return doctor.Fail(err.Error(), "")
+	}
+	for {
+		if fi, err := os.Stat(existing); err == nil && fi.IsDir() {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return doctor.Fail(dir+" has no existing parent", "")
+		}
+		existing = parent
+	}
+	probe, err := os.CreateTemp(existing, ".agentgo-doctor-*")
+	if err != nil {
+		return doctor.Fail(fmt.Sprintf("%s is not writable: %v", existing, err), "fix its permissions or run from a directory you can write to")
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	free, err := doctor.Free(existing)
+	if err != nil {
+		return doctor.Warn(fmt.Sprintf("%s: free space unknown: %v", dir, err), "")
+	}
+	detail := fmt.Sprintf("%s: %s free", dir, bytesize(free))
+	switch {
+	case free < diskCritical:
+		return doctor.Fail(detail, elsewhere)
+	case free < diskLow:
+		return doctor.Warn(detail, elsewhere)
+	}
+	return doctor.Pass("%s", detail)
+}
+
+func bytesize(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.0f MiB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%d KiB", n>>10)
+}
+
+// checkNative checks that robotgo, the default input driver, can work:
+// that agentGo was built with cgo and its shared libraries are installed.
+func checkNative() doctor.Finding {
+	if !doctor.Cgo {
+		return doctor.Warn("built without cgo: robotgo input is unavailable",
+			"rebuild with CGO_ENABLED=1 and a C compiler, or set "+dotenv.EnvName("input")+" to another driver")
+	}
+	libs, pkgs := doctor.MissingLibraries()
+	if len(libs) > 0 {
+		return doctor.Fail("missing "+strings.Join(libs, ", "),
+			"sudo apt install "+strings.Join(pkgs, " ")+" (Debian and Ubuntu; other distributions package the same libraries as libX11, libXtst, libxkbcommon and libpng)")
+	}
+	return doctor.Pass("cgo, native libraries installed")
+}
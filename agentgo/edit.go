@@ -0,0 +1,220 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"agentGo/pkg/session"
+)
+
+// editOp is one edit, applied in the order given on the command line.
+type editOp struct {
+	name  string
+	apply func(events []session.Event) ([]session.Event, error)
+}
+
+// editOps collects edits from several flags, keeping their order.
+type editOps []editOp
+
+// flag returns a flag.Value adding edits parsed by parse under name.
+func (ops *editOps) flag(name string, parse func(string) (func([]session.Event) ([]session.Event, error), error)) flag.Value {
+	return editFlag{ops: ops, name: name, parse: parse}
+}
+
+type editFlag struct {
+	ops   *editOps
+	name  string
+	parse func(string) (func([]session.Event) ([]session.Event, error), error)
+}
+
+func (f editFlag) String() string { return "" }
+
+func (f editFlag) Set(s string) error {
+	apply, err := f.parse(s)
+	if err != nil {
+		return err
+	}
+	*f.ops = append(*f.ops, editOp{name: "-" + f.name + " " + s, apply: apply})
+	return nil
+}
+
+// runEdit edits a recording or session, writing the result as a new
+// version beside it.
+func runEdit(args []string) {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	list := fs.Bool("list", false, "list the steps, numbered, with their times, and exit")
+	out := fs.String("o", "", "where to write the edited copy (default: the next free NAME.vN beside the input)")
+	var ops editOps
+	fs.Var(ops.flag("delete-time", parseDeleteTime), "delete-time", "delete what happened in a time range, FROM-TO (e.g. 1:05-1:20), closing the gap (repeatable)")
+	fs.Var(ops.flag("delete-steps", parseDeleteSteps), "delete-steps", "delete steps N or N-M, as numbered by -list (repeatable)")
+	fs.Var(ops.flag("insert-wait", parseInsertWait), "insert-wait", "wait longer at a time, TIME=DURATION (e.g. 0:45=2s) (repeatable)")
+	fs.Var(ops.flag("replace-text", parseReplaceText), "replace-text", "replace typed text, OLD=NEW (repeatable)")
+	fs.Var(ops.flag("move-click", parseMoveClick), "move-click", "set where click step N is made, N=X,Y in normalized coordinates, or move it with signed offsets, N=+0.01,-0.02 (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo edit [flags] session-dir|recording.csv")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Edits apply in the order given, each to the result of the one before, so")
+		fmt.Fprintln(os.Stderr, "step numbers and times refer to the recording as edited so far. Times are")
+		fmt.Fprintln(os.Stderr, "HH:MM:SS, MM:SS, seconds or durations such as 1m5s. The input is left")
+		fmt.Fprintln(os.Stderr, "as it was.")
+	}
+	inputs := parseInterspersed(fs, args)
+	if len(inputs) != 1 || (!*list && len(ops) == 0) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	in := inputs[0]
+	events, err := openTimeline(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *list {
+		for i, e := range session.Steps(events) {
+			if e.Frame != "" {
+				e.Frame = filepath.Base(e.Frame)
+			}
+			fmt.Printf("%5d  %s  %s\n", i+1, offsetString(e.Time), e)
+		}
+		return
+	}
+
+	for _, op := range ops {
+		if events, err = op.apply(events); err != nil {
+			log.Fatalf("%s: %v", op.name, err)
+		}
+	}
+	if *out == "" {
+		*out = nextVersion(in)
+	}
+	checkNew(*out)
+	if err := saveTimeline(*out, in, events); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+	log.Printf("Wrote %s", *out)
+}
+
+func parseDeleteTime(s string) (func([]session.Event) ([]session.Event, error), error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("%q is not a range FROM-TO", s)
+	}
+	start, err := parseOffset(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseOffset(to)
+	if err != nil {
+		return nil, err
+	}
+	return func(events []session.Event) ([]session.Event, error) {
+		return session.DeleteRange(events, start, end)
+	}, nil
+}
+
+func parseDeleteSteps(s string) (func([]session.Event) ([]session.Event, error), error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		to = from
+	}
+	first, err := strconv.Atoi(from)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a step number", from)
+	}
+	last, err := strconv.Atoi(to)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a step number", to)
+	}
+	return func(events []session.Event) ([]session.Event, error) {
+		return session.DeleteSteps(events, first, last)
+	}, nil
+}
+
+func parseInsertWait(s string) (func([]session.Event) ([]session.Event, error), error) {
+	at, wait, ok := strings.Cut(s, "=")
+	if !ok {
+		return nil, fmt.Errorf("%q is not TIME=DURATION", s)
+	}
+	t, err := parseOffset(at)
+	if err != nil {
+		return nil, err
+	}
+	d, err := parseOffset(wait)
+	if err != nil {
+		return nil, err
+	}
+	return func(events []session.Event) ([]session.Event, error) {
+		return session.InsertWait(events, t, d)
+	}, nil
+}
+
+func parseReplaceText(s string) (func([]session.Event) ([]session.Event, error), error) {
+	old, replacement, ok := strings.Cut(s, "=")
+	if !ok || old == "" {
+		return nil, fmt.Errorf("%q is not OLD=NEW", s)
+	}
+	return func(events []session.Event) ([]session.Event, error) {
+		events, n := session.ReplaceText(events, old, replacement)
+		if n == 0 {
+			return nil, fmt.Errorf("no typed text contains %q", old)
+		}
+		return events, nil
+	}, nil
+}
+
+func parseMoveClick(s string) (func([]session.Event) ([]session.Event, error), error) {
+	step, coords, ok := strings.Cut(s, "=")
+	xs, ys, ok2 := strings.Cut(coords, ",")
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("%q is not N=X,Y", s)
+	}
+	n, err := strconv.Atoi(step)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a step number", step)
+	}
+	x, errX := strconv.ParseFloat(xs, 64)
+	y, errY := strconv.ParseFloat(ys, 64)
+	if errX != nil || errY != nil {
+		return nil, fmt.Errorf("%q is not X,Y", coords)
+	}
+	// Coordinates are never negative, so a sign means an offset.
+	relative := strings.ContainsAny(xs[:1]+ys[:1], "+-")
+	return func(events []session.Event) ([]session.Event, error) {
+		return session.MoveClick(events, n, x, y, relative)
+	}, nil
+}
+
+// nextVersion returns the first free name for an edited copy of path:
+// rec.csv becomes rec.v2.csv, then rec.v3.csv, and session-dir
+// session-dir.v2. Editing rec.v2.csv gives rec.v3.csv.
+func nextVersion(path string) string {
+	path = filepath.Clean(path)
+	ext := ""
+	if !isDir(path) {
+		ext = filepath.Ext(path)
+	}
+	base := strings.TrimSuffix(path, ext)
+	n := 2
+	if i := strings.LastIndex(base, ".v"); i >= 0 {
+		if v, err := strconv.Atoi(base[i+2:]); err == nil && v > 0 {
+			base, n = base[:i], v+1
+		}
+	}
+	for ; ; n++ {
+		name := fmt.Sprintf("%s.v%d%s", base, n, ext)
+		if _, err := os.Stat(name); err != nil {
+			return name
+		}
+	}
+}
+
+// offsetString formats a time into a recording as parseOffset reads it.
+func offsetString(t time.Duration) string {
+	return fmt.Sprintf("%d:%02d:%06.3f", int(t.Hours()), int(t.Minutes())%60, (t % time.Minute).Seconds())
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"agentGo/pkg/session"
+)
+
+// runExport renders a recorded session as a video for review.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fps := fs.Int("fps", 5, "frames per second of the video")
+	scale := fs.Float64("scale", 0, "resize frames by this factor, from 0 to 1 (default: full size, or at most 960 pixels wide for GIFs)")
+	trail := fs.Duration("trail", 3*time.Second, "how much of the pointer's path to draw")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo export [flags] session-dir video.gif|video.mp4")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Sessions are recorded by agentgo play with AGENTGO_SESSION set, or by")
+		fmt.Fprintln(os.Stderr, "the recorder with -session. GIFs are encoded directly; other formats")
+		fmt.Fprintln(os.Stderr, "need ffmpeg.")
+	}
+	parseFlags(fs, args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *fps < 1 {
+		log.Fatal("-fps must be at least 1")
+	}
+
+	opts := session.Options{FPS: *fps, Scale: *scale, Trail: *trail}
+	if err := session.Export(fs.Arg(0), fs.Arg(1), opts); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote %s", fs.Arg(1))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"agentGo/pkg/formfill"
+	"agentGo/pkg/killswitch"
+	"agentGo/pkg/script"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/vars"
+	"agentGo/pkg/vision"
+)
+
+func runFill(args []string) {
+	fs := flag.NewFlagSet("fill", flag.ExitOnError)
+	recordPath := fs.String("record", "", "CSV, JSON, or JSONL file holding the data record (required)")
+	row := fs.Int("row", 1, "1-based row of the data file to use")
+	fieldList := fs.String("fields", "", "comma-separated field labels in fill order (default: file column order)")
+	retries := fs.Int("retries", 1, "extra attempts for a field that fails verification")
+	noVerify := fs.Bool("no-verify", false, "skip reading values back after typing")
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	modelName := fs.String("model", vision.DefaultModel, "Gemini model used to locate and read fields; a comma-separated list falls back to later models when one fails")
+	visionConfig := fs.String("vision-config", "", "JSON file, or inline JSON object, of model sampling and safety settings (temperature, top_k, top_p, max_output_tokens, safety)")
+	promptsFile := fs.String("prompts", "", "JSON file of per-provider prompts, such as the system instruction")
+	coords := fs.String("coords", "", "coordinate convention to ask the model for: auto, pixels, norm1000 or norm1 (default: the model's own)")
+	minConfidence := fs.Float64("min-confidence", 0, "ask the model for a confidence score and treat answers below this (0-1) as low confidence")
+	lowConfidence := fs.String("low-confidence", "unknown", "what to do with a low-confidence answer: unknown (fail the field), retry or template")
+	templateDir := fs.String("templates", "", "directory of PNG images of fields, named after their labels, for -low-confidence template")
+	examplesDir := fs.String("examples", "", "directory of example screenshots with JSON answers shown to the model before each question")
+	stream := fs.Bool("stream", false, "stream model responses and act as soon as the coordinates arrive")
+	refine := fs.Bool("refine", false, "locate each field in two passes, re-asking on a zoomed crop around the first answer")
+	valuesFile := fs.String("values", "", "file of NAME=VALUE lines (or a JSON object) used for ${VAR} substitution in the -record path")
+	var varFlags vars.Flag
+	fs.Var(&varFlags, "var", "set a substitution variable as NAME=VALUE (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo fill -record FILE [flags]")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "The record's values are typed exactly as they are, ${...} included. A vision")
+		fmt.Fprintln(os.Stderr, "flag that is not given falls back to its AGENTGO_* variable, as for every")
+		fmt.Fprintln(os.Stderr, "command: AGENTGO_VISION_CONFIG, AGENTGO_PROMPTS, AGENTGO_COORDS,")
+		fmt.Fprintln(os.Stderr, "AGENTGO_MIN_CONFIDENCE, AGENTGO_LOW_CONFIDENCE, AGENTGO_TEMPLATES,")
+		fmt.Fprintln(os.Stderr, "AGENTGO_EXAMPLES and AGENTGO_STREAM.")
+	}
+	parseFlags(fs, args)
+
+	if *recordPath == "" {
+		log.Fatal("fill: -record is required")
+	}
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	convention, err := vision.ParseConvention(*coords)
+	if err != nil {
+		log.Fatal(err)
+	}
+	policy, err := vision.ParsePolicy(*lowConfidence)
+	if err != nil {
+		log.Fatal(err)
+	}
+	variables, err := vars.Load(*valuesFile, varFlags)
+	if err != nil {
+		log.Fatalf("failed to load variables: %v", err)
+	}
+	path, err := variables.Expand(*recordPath)
+	if err != nil {
+		log.Fatalf("failed to expand record path: %v", err)
+	}
+
+	columns, rows, err := script.LoadTable(path)
+	if err != nil {
+		log.Fatalf("failed to load record: %v", err)
+	}
+	if *row < 1 || *row > len(rows) {
+		log.Fatalf("row %d out of range, %s has %d rows", *row, path, len(rows))
+	}
+	record := rows[*row-1]
+	if *fieldList != "" {
+		columns = strings.Split(*fieldList, ",")
+	}
+
+	fields := make([]formfill.Field, 0, len(columns))
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		// Values are data, typed as they are: a value that looks like
+		// ${HOME} is not a variable.
+		value, ok := record[column]
+		if !ok {
+			log.Fatalf("record has no field %q", column)
+		}
+		fields = append(fields, formfill.Field{Label: column, Value: value})
+	}
+
+	apiKey, err := secrets.Get("GEMINI_API_KEY")
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+	client, err := newVision(ctx, apiKey, *modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	// Flags override the AGENTGO_* settings newVision applied.
+	if convention != "" {
+		client.Convention = convention
+	}
+	if *promptsFile != "" {
+		prompts, err := vision.LoadPrompts(*promptsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.UsePrompts(prompts)
+	}
+	if *visionConfig != "" {
+		cfg, err := vision.LoadConfig(*visionConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := client.Configure(cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *examplesDir != "" {
+		if client.Examples, err = vision.LoadExamples(*examplesDir); err != nil {
+			log.Fatalf("failed to load examples: %v", err)
+		}
+	}
+	if given["stream"] {
+		client.Stream = *stream
+	}
+	if given["min-confidence"] {
+		client.MinConfidence = *minConfidence
+	}
+	if given["low-confidence"] {
+		client.LowConfidence = policy
+	}
+	if *templateDir != "" {
+		if client.Templates, err = vision.LoadTemplates(*templateDir); err != nil {
+			log.Fatalf("failed to load templates: %v", err)
+		}
+	}
+
+	drv := openDriver(*driverSpec, *display)
+	defer drv.Close()
+	armKillSwitch(drv)
+	ctx, untrack := killSwitch.Track(ctx, "form fill", nil)
+	defer untrack()
+
+	var locator formfill.Vision = client
+	if *refine {
+		locator = vision.NewRefiner(client)
+	}
+
+	filler := &formfill.Filler{
+		Vision:   locator,
+		Exec:     drv,
+		Capture:  newDetector(drv).Capture(drv.Capture),
+		Retries:  *retries,
+		NoVerify: *noVerify,
+	}
+
+	log.Printf("Filling %d fields from %s row %d...", len(fields), path, *row)
+	results, err := filler.Fill(ctx, fields)
+	err = killswitch.Cause(ctx, err)
+	for _, res := range results {
+		status := "ok"
+		switch {
+		case res.Err != nil:
+			status = "error: " + res.Err.Error()
+		case !res.Verified && !*noVerify:
+			status = "unverified, read " + strconv.Quote(res.Read)
+		}
+		log.Printf("  %-20s %s", res.Label, status)
+	}
+	if err != nil {
+		log.Fatalf("form fill failed: %v", err)
+	}
+	log.Println("Form filled.")
+}
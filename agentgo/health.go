@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/frame"
+	"agentGo/pkg/health"
+	"agentGo/pkg/preflight"
+	"agentGo/pkg/schedule"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/vision"
+)
+
+// serveHealth serves /healthz and /readyz on addr until ctx is cancelled.
+// The host is ready when drv's display can be captured, input is
+// permitted, the model answers if an API key is set, and no more than
+// maxRunning runs are in progress (0 for no limit).
+func serveHealth(ctx context.Context, addr string, drv desktop.Driver, scheduler *schedule.Scheduler, active *runs, maxRunning int) {
+	checker := &health.Checker{Checks: []health.Check{
+		{Name: "display", Run: func(context.Context) (string, error) {
+			img, err := drv.Capture()
+			if err != nil {
+				return "", fmt.Errorf("failed to capture the screen: %w", err)
+			}
+			defer frame.Put(img)
+			if img.Bounds().Empty() {
+				return "", errors.New("the screen capture is empty")
+			}
+			return fmt.Sprintf("%dx%d", img.Bounds().Dx(), img.Bounds().Dy()), nil
+		}},
+		{Name: "input", Run: func(context.Context) (string, error) {
+			if missing := preflight.Missing(); len(missing) > 0 {
+				names := make([]string, len(missing))
+				for i, p := range missing {
+					names[i] = p.Name
+				}
+				return "", fmt.Errorf("missing permissions: %s", strings.Join(names, ", "))
+			}
+			if readOnly() {
+				return "read-only", nil
+			}
+			return "permitted", nil
+		}},
+		{Name: "model", Run: pingModel()},
+		{Name: "queue", Run: func(context.Context) (string, error) {
+			n := active.count()
+			detail := fmt.Sprintf("%d running", n)
+			if jobs := scheduler.Running(); len(jobs) > 0 {
+				detail += ": " + strings.Join(jobs, ", ")
+			}
+			if maxRunning > 0 && n > maxRunning {
+				return detail, fmt.Errorf("more than %d runs in progress", maxRunning)
+			}
+			return detail, nil
+		}},
+	}}
+
+	srv := &http.Server{Addr: addr, Handler: checker.Handler()}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	log.Printf("Serving /healthz and /readyz on %s", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("health endpoints failed: %v", err)
+	}
+}
+
+// pingModel returns a check that the model can be reached, connecting on
+// first use. Without GEMINI_API_KEY there is nothing to check.
+func pingModel() func(ctx context.Context) (string, error) {
+	var mu sync.Mutex
+	var client *vision.Client
+	return func(ctx context.Context) (string, error) {
+		if secrets.Lookup("GEMINI_API_KEY") == "" {
+			return "not configured", nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if client == nil {
+			c, err := connectVision(ctx)
+			if err != nil {
+				return "", err
+			}
+			client = c
+		}
+		if err := client.Ping(ctx); err != nil {
+			return "", fmt.Errorf("%s is unreachable: %w", client.ModelName(), err)
+		}
+		return client.ModelName(), nil
+	}
+}
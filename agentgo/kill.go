@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/killswitch"
+)
+
+// Defaults of AGENTGO_KILL_ADDR and AGENTGO_KILL_HOTKEY.
+const (
+	defaultKillAddr   = "127.0.0.1:7079"
+	defaultKillHotkey = "ctrl+alt+k"
+)
+
+// killSwitch stops every run of this process at once. Runs register with
+// it as they start.
+var killSwitch = &killswitch.Switch{}
+
+var armOnce sync.Once
+
+// armKillSwitch makes killSwitch reachable for as long as the process runs,
+// whatever its runs are doing: on AGENTGO_KILL_ADDR, and by pressing
+// AGENTGO_KILL_HOTKEY at this machine. Either may be "off". Throwing it
+// releases any buttons and keys drv holds. A kill switch that cannot start
+// is logged rather than fatal, e.g. when another agentgo already listens.
+func armKillSwitch(drv desktop.Driver) {
+	armOnce.Do(func() {
+		killSwitch.Release = func() error { return desktop.Release(drv) }
+		ctx := context.Background()
+
+		addr := os.Getenv(dotenv.EnvName("kill-addr"))
+		if addr == "" {
+			addr = defaultKillAddr
+		}
+		if addr != "off" {
+			go func() {
+				if err := killSwitch.Serve(ctx, addr); err != nil {
+					log.Printf("%v; stop runs with the hotkey or Ctrl-C instead", err)
+				}
+			}()
+		}
+
+		hotkey := os.Getenv(dotenv.EnvName("kill-hotkey"))
+		if hotkey == "" {
+			hotkey = defaultKillHotkey
+		}
+		if hotkey != "off" {
+			pressed, err := desktop.WatchHotkey(ctx, hotkey, 50*time.Millisecond)
+			if err != nil {
+				log.Printf("kill switch hotkey %s is unavailable: %v", hotkey, err)
+				return
+			}
+			go killSwitch.Watch(pressed, hotkey+" pressed")
+		}
+	})
+}
+
+// runKill throws, resets or reports on the kill switch of the agentgo
+// running on this machine.
+func runKill(args []string) {
+	fs := flag.NewFlagSet("kill", flag.ExitOnError)
+	addr := fs.String("addr", defaultKillAddr, "address of the kill switch, as AGENTGO_KILL_ADDR gives it to the agentgo to stop")
+	reset := fs.Bool("reset", false, "let runs start again after a kill")
+	status := fs.Bool("status", false, "only report whether the switch is thrown and which runs are in progress")
+	reason := fs.String("reason", "agentgo kill", "why the runs are stopped, for the logs")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo kill [flags]")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "play, daemon, serve, agent, fill and mcp arm a kill switch while they run.")
+		fmt.Fprintln(os.Stderr, "Throwing it cancels every run, releases held mouse buttons and modifier")
+		fmt.Fprintln(os.Stderr, "keys, and marks checkpointed runs aborted; further runs fail until it is")
+		fmt.Fprintln(os.Stderr, "reset. Pressing AGENTGO_KILL_HOTKEY (default "+defaultKillHotkey+") at the machine")
+		fmt.Fprintln(os.Stderr, "throws it too. Set either AGENTGO_KILL_ADDR or AGENTGO_KILL_HOTKEY to off")
+		fmt.Fprintln(os.Stderr, "to disable it.")
+	}
+	parseFlags(fs, args)
+
+	method, path := http.MethodPost, "/kill?reason="+url.QueryEscape(*reason)
+	switch {
+	case *status:
+		method, path = http.MethodGet, "/status"
+	case *reset:
+		path = "/reset"
+	}
+	req, err := http.NewRequest(method, "http://"+*addr+path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatalf("failed to reach the kill switch: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("kill switch: %s", resp.Status)
+	}
+	io.Copy(os.Stdout, resp.Body)
+}
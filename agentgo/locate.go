@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/png"
+	"log"
+	"os"
+
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/vision"
+)
+
+// runLocate finds a target on saved screenshots, several frames per model
+// request, and prints one CSV row per file.
+func runLocate(args []string) {
+	fs := flag.NewFlagSet("locate", flag.ExitOnError)
+	target := fs.String("target", "", "description of the element to find on every image (required)")
+	batch := fs.Int("batch", 8, "frames sent per model request")
+	modelName := fs.String("model", vision.DefaultModel, "Gemini model used to locate the target; a comma-separated list falls back to later models when one fails")
+	rotation := fs.String("key-rotation", "on-429", "how to spread calls when GEMINI_API_KEY holds several comma-separated keys: on-429 or round-robin")
+	coords := fs.String("coords", "", "coordinate convention to ask the model for: auto, pixels, norm1000 or norm1 (default: the model's own)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo locate -target DESCRIPTION [flags] image.png...")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	if *target == "" || fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *batch < 1 {
+		*batch = 1
+	}
+	convention, err := vision.ParseConvention(*coords)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keyRotation, err := vision.ParseRotation(*rotation)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	apiKey, err := secrets.Get("GEMINI_API_KEY")
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+	client, err := newVision(ctx, apiKey, *modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	if convention != "" {
+		client.Convention = convention
+	}
+	client.Rotation = keyRotation
+	defer func() {
+		for _, u := range client.Usage() {
+			log.Printf("key %s: %d requests, %d rate limited, %d errors, %d tokens", u.Key, u.Requests, u.RateLimited, u.Errors, u.Tokens)
+		}
+	}()
+
+	out := csv.NewWriter(os.Stdout)
+	defer out.Flush()
+	out.Write([]string{"file", "x", "y", "norm_x", "norm_y", "model", "error"})
+
+	files := fs.Args()
+	for start := 0; start < len(files); start += *batch {
+		chunk := files[start:min(start+*batch, len(files))]
+		questions := make([]vision.Question, 0, len(chunk))
+		names := make([]string, 0, len(chunk))
+		for _, name := range chunk {
+			img, err := loadImage(name)
+			if err != nil {
+				out.Write([]string{name, "", "", "", "", "", err.Error()})
+				continue
+			}
+			questions = append(questions, vision.Question{Image: img, Target: *target})
+			names = append(names, name)
+		}
+		answers, err := client.LocateBatch(ctx, questions)
+		if err != nil {
+			log.Fatalf("failed to locate target: %v", err)
+		}
+		for i, a := range answers {
+			if a.Err != nil {
+				out.Write([]string{names[i], "", "", "", "", client.Answered(), a.Err.Error()})
+				continue
+			}
+			normX, normY := a.Point.Normalize(questions[i].Image.Bounds())
+			out.Write([]string{
+				names[i],
+				fmt.Sprintf("%.1f", a.Point.X),
+				fmt.Sprintf("%.1f", a.Point.Y),
+				fmt.Sprintf("%.6f", normX),
+				fmt.Sprintf("%.6f", normY),
+				client.Answered(),
+				"",
+			})
+		}
+		out.Flush()
+	}
+}
+
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/profile"
+)
+
+// command is an agentgo subcommand.
+type command struct {
+	summary string
+	run     func(args []string)
+}
+
+var commands = map[string]command{
+	"agent":      {summary: "carry out a task described in words, with a planner model driving the desktop and a grounder model finding elements", run: runAgent},
+	"analyze":    {summary: "analyze recorded sessions, e.g. as a heatmap of pointer dwell and clicks", run: runAnalyze},
+	"bench":      {summary: "time capturing and encoding frames of a desktop", run: runBench},
+	"convert":    {summary: "convert recordings between CSV and session events in JSONL, JSON or protobuf", run: runConvert},
+	"coordinate": {summary: "dispatch scripts and recordings across a fleet of workers", run: runCoordinate},
+	"daemon":     {summary: "run scheduled scripts and recordings unattended", run: runDaemon},
+	"diff":       {summary: "describe the meaningful differences between two screenshots, or live before and after actions", run: runDiff},
+	"displays":   {summary: "list local displays with index, bounds, scale and primary flag", run: runDisplays},
+	"distill":    {summary: "turn a recording into a script that finds elements by description", run: runDistill},
+	"doctor":     {summary: "check display access, input permissions, scaling, the model, disk space and native libraries, with fixes", run: runDoctor},
+	"edit":       {summary: "edit a recording or session: delete time or steps, insert waits, replace typed text, move clicks", run: runEdit},
+	"export":     {summary: "render a recorded session as a video with the pointer, clicks, typing and model answers drawn on it", run: runExport},
+	"fill":       {summary: "fill an on-screen form from a data record", run: runFill},
+	"history":    {summary: "show run history of scheduled jobs", run: runHistory},
+	"kill":       {summary: "stop every run of agentgo on this machine through its kill switch, or reset it", run: runKill},
+	"locate":     {summary: "find a target on saved screenshots, batching frames per model request", run: runLocate},
+	"memory":     {summary: "list, search, add to and forget the facts the agent remembers about apps", run: runMemory},
+	"migrate":    {summary: "upgrade old recordings and sessions to the current format", run: runMigrate},
+	"mcp":        {summary: "serve screen-control tools to MCP clients over standard input and output", run: runMCP},
+	"narrate":    {summary: "describe the screen and what changes on it, in text or aloud", run: runNarrate},
+	"play":       {summary: "run a script or recording locally or on a remote worker", run: runPlay},
+	"preflight":  {summary: "check screen recording and accessibility permissions", run: runPreflight},
+	"runs":       {summary: "list checkpointed runs of scripts, recordings and planned agent tasks, to resume", run: runRuns},
+	"secret":     {summary: "store or delete a credential in the OS keychain", run: runSecret},
+	"sessions":   {summary: "summarize, compare, merge, split, seal and verify recorded sessions", run: runSessions},
+	"serve":      {summary: "serve the worker API so this desktop can be driven remotely", run: runServe},
+	"simulate":   {summary: "show an agent saved screenshots and report the actions it proposes, without performing them", run: runSimulate},
+	"token":      {summary: "create an API token, or admit a client certificate, for the worker API", run: runToken},
+	"tools":      {summary: "print the desktop actions as JSON-schema tool definitions for function-calling models", run: runTools},
+}
+
+func main() {
+	args, global := globalFlags(os.Args[1:])
+	if len(args) < 1 {
+		usage()
+		os.Exit(2)
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "agentgo: unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	// A named env file is required to exist; ./.env is optional. Both
+	// only fill in variables the environment does not already set, and the
+	// profile only what neither sets.
+	if envFile := global["env-file"]; envFile != "" {
+		if err := dotenv.Load(envFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := dotenv.LoadDefault(); err != nil {
+		log.Fatal(err)
+	}
+	if err := profile.Use("", global["profile"]); err != nil {
+		log.Fatal(err)
+	}
+	log.SetOutput(scrubber().Writer(os.Stderr))
+	commandName = name
+	cmd.run(args[1:])
+	closeAudit()
+}
+
+// globalFlags strips the options that come before the command name:
+// -env-file and -profile.
+func globalFlags(args []string) ([]string, map[string]string) {
+	global := map[string]string{}
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		if name != "env-file" && name != "profile" {
+			break
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				fmt.Fprintf(os.Stderr, "agentgo: -%s needs a value\n", name)
+				os.Exit(2)
+			}
+			value, args = args[1], args[1:]
+		}
+		global[name], args = value, args[1:]
+	}
+	return args, global
+}
+
+// parseFlags parses a command's flags and fills the ones not given from
+// AGENTGO_* environment variables.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	if err := dotenv.ApplyFlags(fs); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: agentgo [-env-file FILE] [-profile NAME] <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Settings are read from ./.env and -env-file; flags not given on the")
+	fmt.Fprintln(os.Stderr, "command line default to AGENTGO_<FLAG> variables, e.g. AGENTGO_MODEL.")
+	fmt.Fprintln(os.Stderr, "-profile (or AGENTGO_PROFILE) picks a named profile from")
+	fmt.Fprintf(os.Stderr, "%s as the lowest layer of settings.\n", profile.DefaultPath())
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "AGENTGO_READ_ONLY=true only observes the desktop: screens are captured")
+	fmt.Fprintln(os.Stderr, "and analyzed, but moving the mouse, clicking, typing, pressing keys,")
+	fmt.Fprintln(os.Stderr, "writing files and running commands fail. A binary built with -tags")
+	fmt.Fprintln(os.Stderr, "readonly is always read-only.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Secrets are scrubbed from the log, sessions, assertion reports and model")
+	fmt.Fprintln(os.Stderr, "prompts: API keys and private keys in well-known formats, the credentials")
+	fmt.Fprintln(os.Stderr, "agentgo uses, those AGENTGO_SCRUB_SECRETS names (comma-separated, looked up")
+	fmt.Fprintln(os.Stderr, "like GEMINI_API_KEY) and whatever the regular expression")
+	fmt.Fprintln(os.Stderr, "AGENTGO_SCRUB_PATTERN matches.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "AGENTGO_AUDIT sends an event for every script step, replayed recording and")
+	fmt.Fprintln(os.Stderr, "agent tool call, with its outcome, as it happens: a comma-separated list of")
+	fmt.Fprintln(os.Stderr, "files, syslog://HOST[:PORT] (UDP), syslog+tcp://HOST[:PORT] and http(s)://")
+	fmt.Fprintln(os.Stderr, "webhooks. AGENTGO_AUDIT_FORMAT is json (the default) or cef for SIEMs.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].summary)
+	}
+}
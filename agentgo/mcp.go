@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/mcp"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/tools"
+	"agentGo/pkg/vision"
+)
+
+// runMCP serves the desktop as MCP tools on standard input and output.
+// Everything else, including logs, goes to standard error.
+func runMCP(args []string) {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	modelName := fs.String("model", vision.DefaultModel, "Gemini model used by find_element and clicks by description; a comma-separated list falls back to later models when one fails")
+	parseFlags(fs, args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	drv := openDriver(*driverSpec, *display)
+	defer drv.Close()
+	armKillSwitch(drv)
+	// The kill switch ends the session, so that a client cannot carry on
+	// driving the desktop.
+	ctx, untrack := killSwitch.Track(ctx, "mcp session", nil)
+	defer untrack()
+
+	srv := &mcp.Server{Desktop: drv, Version: "dev"}
+	// The vision tools are optional: without a key the others still work.
+	var client *vision.Client
+	if apiKey, err := secrets.Get("GEMINI_API_KEY"); err == nil {
+		client, err = newVision(ctx, apiKey, *modelName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer client.Close()
+		srv.Vision = client
+	} else {
+		log.Printf("find_element is unavailable: %v", err)
+	}
+	// So are the browser tools, which need the local desktop or a browser
+	// driver.
+	if page, attached, err := browserPage(ctx, drv, true); err == nil {
+		if attached {
+			defer page.Close()
+		}
+		srv.Browser = page
+	} else {
+		log.Printf("the browser tools are unavailable: %v", err)
+	}
+
+	// Clicks, typing and keys are checked for risk as AGENTGO_SAFETY says.
+	srv.Safety = newGuard(drv, client, log.Printf)
+	// Password fields are kept out of screenshots and logs.
+	srv.Secure = newDetector(drv)
+	srv.Scrub = scrubber().String
+	srv.OnCall = auditCall("mcp")
+	// The file tools reach only the directories AGENTGO_FILES lists.
+	if dirs := os.Getenv(dotenv.EnvName("files")); dirs != "" {
+		files, err := tools.NewFiles(filepath.SplitList(dirs)...)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", dotenv.EnvName("files"), err)
+		}
+		files.ReadOnly = readOnly()
+		srv.Files = files
+	}
+	// run_command runs only the programs AGENTGO_COMMANDS allows (see
+	// tools.ParseCommands), in the first of those directories, asking on
+	// the terminal before the ones that must be confirmed. Every call is
+	// audited to AGENTGO_COMMAND_LOG, or logged.
+	if spec := os.Getenv(dotenv.EnvName("commands")); spec != "" && readOnly() {
+		log.Printf("run_command is unavailable in read-only mode")
+	} else if spec != "" {
+		commands, err := tools.ParseCommands(spec)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", dotenv.EnvName("commands"), err)
+		}
+		commands.Confirm = confirmOnTerminal
+		if srv.Files != nil {
+			commands.Dir = srv.Files.Dirs[0]
+		}
+		if path := os.Getenv(dotenv.EnvName("command-log")); path != "" {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+			if err != nil {
+				log.Fatalf("failed to open the command audit log: %v", err)
+			}
+			defer f.Close()
+			commands.Audit = f
+		}
+		srv.Commands = commands
+	}
+	// remember and recall keep their facts in AGENTGO_MEMORY, embedded with
+	// Gemini if AGENTGO_EMBED_MEMORY is set.
+	if path := os.Getenv(dotenv.EnvName("memory")); path != "" {
+		var embedder *vision.Client
+		if embed, _ := strconv.ParseBool(os.Getenv(dotenv.EnvName("embed-memory"))); embed {
+			if client == nil {
+				log.Fatalf("%s needs GEMINI_API_KEY", dotenv.EnvName("embed-memory"))
+			}
+			embedder = client
+		}
+		srv.Memory = openMemory(path, embedder)
+	}
+
+	log.Printf("Serving MCP on standard input and output...")
+	if err := srv.Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		log.Fatalf("mcp server failed: %v", err)
+	}
+}
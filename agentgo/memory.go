@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"agentGo/pkg/memory"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/tools"
+	"agentGo/pkg/vision"
+)
+
+const (
+	defaultMemory = ".agentgo/memory.jsonl"
+	memoryUsage   = "file the agent's memory of facts learned about the apps is kept in, recalled and added to through the recall and remember tools"
+	embedUsage    = "find remembered facts by meaning, embedding them with Gemini, instead of by their words"
+)
+
+// openMemory opens the memory at path, embedding facts with client if it
+// is set.
+func openMemory(path string, client *vision.Client) *memory.Store {
+	var e memory.Embedder
+	if client != nil {
+		e = client
+	}
+	store, err := memory.Open(path, e)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return store
+}
+
+// runMemory lists, searches and edits the agent's memory.
+func runMemory(args []string) {
+	fs := flag.NewFlagSet("memory", flag.ExitOnError)
+	path := fs.String("memory", defaultMemory, "file the memory is kept in")
+	embed := fs.Bool("embed", false, embedUsage)
+	app := fs.String("app", "", "with list, only the facts about this application; with add, the application the fact is about")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo memory [flags] list")
+		fmt.Fprintln(os.Stderr, "       agentgo memory [flags] search QUERY")
+		fmt.Fprintln(os.Stderr, "       agentgo memory [flags] add FACT")
+		fmt.Fprintln(os.Stderr, "       agentgo memory [flags] forget ID")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	cmd, rest := fs.Arg(0), strings.Join(fs.Args()[1:], " ")
+	if (cmd == "list") != (rest == "") {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var client *vision.Client
+	if *embed && (cmd == "search" || cmd == "add") {
+		apiKey, err := secrets.Get("GEMINI_API_KEY")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if client, err = newVision(ctx, apiKey, vision.DefaultModel); err != nil {
+			log.Fatal(err)
+		}
+		defer client.Close()
+	}
+	store := openMemory(*path, client)
+
+	switch cmd {
+	case "list":
+		for _, f := range store.Facts() {
+			if *app != "" && !strings.EqualFold(f.App, *app) {
+				continue
+			}
+			line := fmt.Sprintf("%4d  %s  ", f.ID, f.Learned.Format("2006-01-02"))
+			if f.App != "" {
+				line += f.App + ": "
+			}
+			fmt.Println(line + f.Text)
+		}
+	case "search":
+		facts, err := store.Recall(ctx, rest, 10)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(facts) == 0 {
+			log.Printf("Nothing remembered about %q.", rest)
+			return
+		}
+		fmt.Println(tools.FormatFacts(facts))
+	case "add":
+		fact, added, err := store.Add(ctx, memory.Fact{Text: rest, App: *app})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !added {
+			log.Printf("Already remembered as fact %d.", fact.ID)
+			return
+		}
+		log.Printf("Remembered as fact %d.", fact.ID)
+	case "forget":
+		id, err := strconv.Atoi(rest)
+		if err != nil {
+			log.Fatalf("invalid fact ID %q", rest)
+		}
+		if err := store.Forget(id); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Forgot fact %d.", id)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
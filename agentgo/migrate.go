@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"agentGo/pkg/playback"
+	"agentGo/pkg/session"
+)
+
+// runMigrate upgrades recordings and sessions to the current format in
+// place, keeping the originals as .bak files.
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("n", false, "only report which files need upgrading")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo migrate [flags] recording.csv|session-dir...")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintf(os.Stderr, "Recordings are upgraded to format v%d and sessions to v%d; the originals\n", playback.Version, session.Version)
+		fmt.Fprintln(os.Stderr, "are kept with a .bak suffix.")
+	}
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range fs.Args() {
+		if err := migrate(path, *dryRun); err != nil {
+			log.Printf("%s: %v", path, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func migrate(path string, dryRun bool) error {
+	kind, latest := "recording", playback.Version
+	var from int
+	var upgrade func() error
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		kind, latest = "session", session.Version
+		if from, err = session.FormatVersion(path); err != nil {
+			return err
+		}
+		upgrade = func() error {
+			_, err := session.Migrate(path)
+			return err
+		}
+	} else {
+		var samples []playback.Sample
+		if samples, from, err = playback.ReadCSV(path); err != nil {
+			return err
+		}
+		upgrade = func() error {
+			if err := os.Rename(path, path+".bak"); err != nil {
+				return fmt.Errorf("failed to back up recording: %w", err)
+			}
+			return playback.SaveCSV(path, samples)
+		}
+	}
+
+	switch {
+	case from == latest:
+		fmt.Printf("%s: %s v%d, up to date\n", path, kind, from)
+	case dryRun:
+		fmt.Printf("%s: %s v%d, needs upgrading to v%d\n", path, kind, from, latest)
+	default:
+		if err := upgrade(); err != nil {
+			return err
+		}
+		fmt.Printf("%s: upgraded %s from v%d to v%d\n", path, kind, from, latest)
+	}
+	return nil
+}
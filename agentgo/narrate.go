@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"agentGo/pkg/narrate"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/speech"
+	"agentGo/pkg/vision"
+)
+
+// runNarrate describes the screen and its changes as they happen, in text
+// and optionally aloud.
+func runNarrate(args []string) {
+	fs := flag.NewFlagSet("narrate", flag.ExitOnError)
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	modelName := fs.String("model", vision.DefaultModel, "Gemini model that describes the screen; a comma-separated list falls back to later models when one fails")
+	interval := fs.Duration("interval", 5*time.Second, "how often to look at the screen; less often on battery power or when hot unless AGENTGO_THROTTLE=off")
+	threshold := fs.Float64("threshold", 0.01, "how much the screen must change, from 0 to 1, before it is described again")
+	focus := fs.String("focus", "", "what to pay particular attention to, e.g. \"the progress of the export\"")
+	speak := fs.Bool("speak", false, "read the descriptions aloud with the system speech synthesizer")
+	out := fs.String("out", "", "also append the descriptions to this file")
+	parseFlags(fs, args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	apiKey, err := secrets.Get("GEMINI_API_KEY")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := newVision(ctx, apiKey, *modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	drv := openDriver(*driverSpec, *display)
+	defer drv.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("failed to open %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = io.MultiWriter(os.Stdout, f)
+	}
+
+	n := &narrate.Narrator{
+		Client:    client,
+		Capture:   newDetector(drv).Capture(drv.Capture),
+		Interval:  *interval,
+		Threshold: *threshold,
+		Focus:     *focus,
+		Throttle:  captureThrottle(),
+	}
+	log.Printf("Narrating the screen every %v; press Ctrl+C to stop.", *interval)
+	n.Run(ctx, func(nr narrate.Narration) {
+		fmt.Fprintf(w, "%s %s\n", nr.Time.Format("15:04:05"), nr.Text)
+		if *speak {
+			if err := speech.Say(ctx, nr.Text); err != nil && ctx.Err() == nil {
+				log.Printf("failed to speak: %v", err)
+			}
+		}
+	})
+}
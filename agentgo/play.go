@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"agentGo/pkg/budget"
+	"agentGo/pkg/checkpoint"
+	"agentGo/pkg/server"
+	"agentGo/pkg/vars"
+)
+
+func runPlay(args []string) {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	target := fs.String("target", "", "run on a remote worker at host:port instead of the local desktop")
+	framesDir := fs.String("frames", "", "with -target, save streamed screenshots of the remote desktop to this directory")
+	frameInterval := fs.Duration("frame-interval", time.Second, "with -frames, interval between streamed screenshots")
+	timeout := fs.Duration("timeout", 0, "abort the run after this long (0 for none)")
+	valuesFile := fs.String("values", "", "file of NAME=VALUE lines (or a JSON object) used for ${VAR} substitution")
+	var varFlags vars.Flag
+	fs.Var(&varFlags, "var", "set a substitution variable as NAME=VALUE (repeatable)")
+	runsDir := fs.String("runs", checkpoint.DefaultStore.Dir, "directory local runs are checkpointed in after every step, to be resumed with -resume (empty for none)")
+	maxCost := fs.Float64("max-cost", 0, "stop a script before its model calls cost more than this many dollars, at the prices in AGENTGO_PRICES; overrides the script's budget (0 to keep it)")
+	maxSteps := fs.Int("max-steps", 0, "stop a script before it takes more than this many steps; overrides the script's budget (0 to keep it)")
+	showProgress := fs.String("progress", "auto", "show a progress bar, the current step and the latest checks, updated in place: auto (when stderr is a terminal), on or off")
+	resume := fs.String("resume", "", "resume the interrupted local run with this ID after the last step it completed, with the variables it was given")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo play [flags] script.json|recording.csv")
+		fmt.Fprintln(os.Stderr, "       agentgo play [flags] -resume RUN-ID")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Each local run prints its ID and is checkpointed in -runs: the last step it")
+		fmt.Fprintln(os.Stderr, "completed, its variables and the screen after that step. A recording resumes")
+		fmt.Fprintln(os.Stderr, "after its last replayed click. agentgo runs lists the runs.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "A script's \"budget\", e.g. {\"max_cost\": 0.5, \"max_steps\": 200}, stops it with")
+		fmt.Fprintln(os.Stderr, "an error giving the spend by model before it goes over. Costs need each")
+		fmt.Fprintln(os.Stderr, "model's price in dollars per million tokens in AGENTGO_PRICES, e.g.")
+		fmt.Fprintln(os.Stderr, "gemini-1.5-flash=0.15,gemini-2.0-flash=0.4.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Scripts also read AGENTGO_POPUPS, how to handle dialogs and prompts that")
+		fmt.Fprintln(os.Stderr, "appear unasked (fail, dismiss, pause, ignore, or a JSON policy), and")
+		fmt.Fprintln(os.Stderr, "AGENTGO_ARTIFACTS, where failed assertions are documented.")
+		fmt.Fprintln(os.Stderr, "AGENTGO_SESSION names a directory to record the run in, for agentgo export;")
+		fmt.Fprintln(os.Stderr, "AGENTGO_SESSION_FORMAT sets its log format: jsonl (the default), pb or")
+		fmt.Fprintln(os.Stderr, "pb.zst, which are smaller for long runs (pb.zst needs the zstd command).")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Clicks, typing and keys are checked for risk first: typing into password")
+		fmt.Fprintln(os.Stderr, "fields, Delete or Enter on confirmation dialogs, sudo and administrator")
+		fmt.Fprintln(os.Stderr, "prompts, destructive buttons and commands. A risky step runs only if")
+		fmt.Fprintln(os.Stderr, "AGENTGO_SAFETY_ALLOW grants its rule (password-field, sudo-prompt,")
+		fmt.Fprintln(os.Stderr, "confirm-dialog, destructive-click, destructive-command, model or *) or it is")
+		fmt.Fprintln(os.Stderr, "confirmed on the terminal. AGENTGO_SAFETY=model also asks the model about the")
+		fmt.Fprintln(os.Stderr, "other steps; AGENTGO_SAFETY=off checks nothing. Recordings are not checked.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Password fields, as the accessibility API marks them or as their label or")
+		fmt.Fprintln(os.Stderr, "window title names them, are blacked out of screenshots sent to the model")
+		fmt.Fprintln(os.Stderr, "or recorded, and what is typed into them is recorded and logged as")
+		fmt.Fprintln(os.Stderr, "<secret>. AGENTGO_MASK_SECRETS=false turns this off.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "With -target, the worker is signed in to with the token in AGENTGO_TOKEN")
+		fmt.Fprintln(os.Stderr, "(see agentgo token) or the client certificate AGENTGO_CLIENT_CERT and its key")
+		fmt.Fprintln(os.Stderr, "AGENTGO_CLIENT_KEY; agentgo coordinate signs in to its hosts the same way.")
+		fmt.Fprintln(os.Stderr, "Workers served over TLS are reached as https://host:port, or as host:port")
+		fmt.Fprintln(os.Stderr, "when a client certificate or AGENTGO_SERVER_CA, the CA that signed the")
+		fmt.Fprintln(os.Stderr, "worker's certificate, is set. A self-signed worker (serve -tls-self-signed)")
+		fmt.Fprintln(os.Stderr, "is trusted by the fingerprint it logs, in AGENTGO_SERVER_FINGERPRINT. Only")
+		fmt.Fprintln(os.Stderr, "variables the script declares in its \"vars\" are sent to the worker.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Scripts and recordings sealed with agentgo sessions seal are verified")
+		fmt.Fprintln(os.Stderr, "before they run. AGENTGO_VERIFY_KEY names a public key they must be sealed")
+		fmt.Fprintln(os.Stderr, "and signed with, refusing anything else.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "AGENTGO_MOTION sets how the pointer travels between positions: none")
+		fmt.Fprintln(os.Stderr, "(jump, the default), linear, ease-in-out or human, optionally with a top")
+		fmt.Fprintln(os.Stderr, "speed in screen widths per second, e.g. human:1.2.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "AGENTGO_HUMANIZE makes runs irregular like a real user, for load tests:")
+		fmt.Fprintln(os.Stderr, "on, or settings such as seed=7,jitter=0.3,overshoot=0.2,idle=0.1,idle-max=5s.")
+		fmt.Fprintln(os.Stderr, "Delays vary, moves sometimes overshoot and correct, and the run now and")
+		fmt.Fprintln(os.Stderr, "then pauses; the same seed repeats the same run.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "On the local desktop, moving the mouse or typing during a run pauses it and")
+		fmt.Fprintln(os.Stderr, "asks whether to resume or abort; without a terminal the run fails instead.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "AGENTGO_SPEAK=progress reads each step (\"Step 4 of 12: clicking the Settings")
+		fmt.Fprintln(os.Stderr, "button\") and how the run ends aloud with the system speech synthesizer;")
+		fmt.Fprintln(os.Stderr, "AGENTGO_SPEAK=outcome reads only how it ends.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "AGENTGO_CAPTURE=native captures the local desktop with the OS's streaming")
+		fmt.Fprintln(os.Stderr, "API (DXGI on Windows, CGDisplayStream on macOS, PipeWire on Wayland) for")
+		fmt.Fprintln(os.Stderr, "high frame rates, falling back to generic capture where it cannot.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "AGENTGO_INPUT=xdotool or uinput injects input into the local desktop without")
+		fmt.Fprintln(os.Stderr, "robotgo: xdotool needs an X11 session, uinput works under Wayland too but")
+		fmt.Fprintln(os.Stderr, "needs write access to /dev/uinput. On Windows, AGENTGO_INPUT=sendinput sends")
+		fmt.Fprintln(os.Stderr, "keys as scan codes for games and elevated windows, and interception goes")
+		fmt.Fprintln(os.Stderr, "through the Interception driver for applications that ignore injected input.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Every command that asks the vision model sets it up from AGENTGO_VISION_CONFIG")
+		fmt.Fprintln(os.Stderr, "(sampling and safety settings), AGENTGO_PROMPTS (system instruction),")
+		fmt.Fprintln(os.Stderr, "AGENTGO_EXAMPLES (solved questions shown first), AGENTGO_COORDS (coordinate")
+		fmt.Fprintln(os.Stderr, "convention), AGENTGO_MIN_CONFIDENCE, AGENTGO_LOW_CONFIDENCE, AGENTGO_TEMPLATES")
+		fmt.Fprintln(os.Stderr, "and AGENTGO_STREAM.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "AGENTGO_LANGUAGES lists the languages of the text on screen, e.g. de,ja or")
+		fmt.Fprintln(os.Stderr, "chi_sim, for desktops not in English: targets are found and text is read in")
+		fmt.Fprintln(os.Stderr, "them, without translating. A script's or step's \"languages\" overrides it.")
+		fmt.Fprintln(os.Stderr, "The vision model reads every language itself; there is no data to install.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "AGENTGO_CDP=http://localhost:9222 finds script targets in the DOM of a browser")
+		fmt.Fprintln(os.Stderr, "started with --remote-debugging-port before asking the vision model; targets")
+		fmt.Fprintln(os.Stderr, "may be css:SELECTOR, text:TEXT or descriptions naming the element's text.")
+		fmt.Fprintln(os.Stderr, "Descriptions matching no single element are answered by the model with an")
+		fmt.Fprintln(os.Stderr, "element from an outline of the page, and only then by pixel coordinates.")
+		fmt.Fprintln(os.Stderr, "-driver cdp://localhost:9222 drives the page itself through the protocol.")
+		fmt.Fprintln(os.Stderr, "The launch_browser, open_url, switch_tab and wait_for_page_load steps manage")
+		fmt.Fprintln(os.Stderr, "the browser at that endpoint (localhost:9222 by default); AGENTGO_BROWSER")
+		fmt.Fprintln(os.Stderr, "names the browser launch_browser starts, instead of Chrome, Chromium or Edge.")
+	}
+	parseFlags(fs, args)
+
+	if (*resume == "") != (fs.NArg() == 1) || fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	switch *showProgress {
+	case "auto", "on", "off":
+		progressMode = *showProgress
+	default:
+		log.Fatalf("invalid -progress %q: want auto, on or off", *showProgress)
+	}
+	if *resume != "" && (*target != "" || *runsDir == "") {
+		log.Fatal("-resume needs a local run and -runs")
+	}
+	store := checkpoint.Store{Dir: *runsDir}
+	var run *checkpoint.Run
+	variables := vars.New()
+	if *resume != "" {
+		var err error
+		if run, err = store.Load(*resume); err != nil {
+			log.Fatal(err)
+		}
+		if err := run.Resumable(); err != nil {
+			log.Fatal(err)
+		}
+		for name, value := range run.Vars {
+			variables.Set(name, value)
+		}
+	}
+	// Variables given now take precedence over those of a resumed run.
+	given, err := vars.Load(*valuesFile, varFlags)
+	if err != nil {
+		log.Fatalf("failed to load variables: %v", err)
+	}
+	for _, name := range given.Names() {
+		value, _ := given.Get(name)
+		variables.Set(name, value)
+	}
+	path := fs.Arg(0)
+	if run != nil {
+		path = run.Source
+	} else if path, err = variables.Expand(path); err != nil {
+		log.Fatalf("failed to expand path: %v", err)
+	}
+	s, samples, err := loadTask(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if s != nil && (*maxCost > 0 || *maxSteps > 0) {
+		b := budget.Budget{}
+		if s.Budget != nil {
+			b = *s.Budget
+		}
+		if *maxCost > 0 {
+			b.MaxCost = *maxCost
+		}
+		if *maxSteps > 0 {
+			b.MaxSteps = *maxSteps
+		}
+		s.Budget = &b
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	values := make(map[string]string)
+	for _, name := range variables.Names() {
+		values[name], _ = variables.Get(name)
+	}
+
+	if *target == "" {
+		drv := openDriver(*driverSpec, *display)
+		defer drv.Close()
+		armKillSwitch(drv)
+		if run == nil && *runsDir != "" {
+			kind := checkpoint.KindScript
+			if s == nil {
+				kind = checkpoint.KindRecording
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if run, err = store.New(kind, abs, values); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if run != nil {
+			log.Printf("Run %s", run.ID)
+		}
+		if err := execute(ctx, drv, s, samples, variables, run, log.Printf); err != nil {
+			if run != nil {
+				log.Fatalf("playback failed: %v; resume with agentgo play -resume %s", err, run.ID)
+			}
+			log.Fatalf("playback failed: %v", err)
+		}
+		log.Println("Playback finished.")
+		return
+	}
+
+	// A worker only takes the variables the script declares; the others
+	// only expanded the path here.
+	remoteVars := make(map[string]string)
+	if s != nil {
+		for name := range s.Vars {
+			if value, ok := values[name]; ok {
+				remoteVars[name] = value
+			}
+		}
+	}
+	req := server.TaskRequest{Name: filepath.Base(path), Script: s, Recording: samples, Vars: remoteVars}
+	if err := playRemote(ctx, *target, req, *framesDir, *frameInterval); err != nil {
+		log.Fatalf("remote playback failed: %v", err)
+	}
+	log.Println("Remote playback finished.")
+}
+
+// playRemote submits req to the worker at target, prints its progress as it
+// runs, and optionally saves the streamed remote screen to framesDir.
+func playRemote(ctx context.Context, target string, req server.TaskRequest, framesDir string, frameInterval time.Duration) error {
+	client := newWorkerClient(target)
+	info, err := client.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", target, err)
+	}
+	log.Printf("Connected to %s (%s)", target, info.Host)
+
+	t, err := client.Submit(ctx, req)
+	if err != nil {
+		return err
+	}
+	log.Printf("Submitted task %s", t.ID)
+
+	streamCtx, stopStream := context.WithCancel(ctx)
+	defer stopStream()
+	if framesDir != "" {
+		if err := os.MkdirAll(framesDir, 0755); err != nil {
+			return fmt.Errorf("failed to create frames directory: %w", err)
+		}
+		go func() {
+			n := 0
+			err := client.StreamScreenshots(streamCtx, frameInterval, func(png []byte) error {
+				n++
+				return os.WriteFile(filepath.Join(framesDir, fmt.Sprintf("frame-%06d.png", n)), png, 0644)
+			})
+			if err != nil && streamCtx.Err() == nil {
+				log.Printf("screenshot stream stopped: %v", err)
+			}
+		}()
+	}
+
+	printed := 0
+	t, err = client.Watch(ctx, t.ID, 500*time.Millisecond, func(t server.Task) {
+		if printed < t.LogOffset {
+			log.Printf("[remote] ... %d messages dropped ...", t.LogOffset-printed)
+			printed = t.LogOffset
+		}
+		for _, line := range t.Log[printed-t.LogOffset:] {
+			log.Printf("[remote] %s", line)
+		}
+		printed = t.LogOffset + len(t.Log)
+	})
+	if err != nil {
+		return err
+	}
+	if t.State != server.StateSuccess {
+		return fmt.Errorf("task %s %s: %s", t.ID, t.State, t.Error)
+	}
+	return nil
+}
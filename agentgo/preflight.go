@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"agentGo/pkg/preflight"
+)
+
+// runPreflight reports whether the OS permissions needed to capture the
+// screen and inject input are granted.
+func runPreflight(args []string) {
+	fs := flag.NewFlagSet("preflight", flag.ExitOnError)
+	open := fs.Bool("open", false, "open the System Settings pane for each missing permission")
+	parseFlags(fs, args)
+
+	missing := preflight.Missing()
+	isMissing := map[string]bool{}
+	for _, p := range missing {
+		isMissing[p.Name] = true
+	}
+	for _, p := range []preflight.Permission{preflight.ScreenRecording, preflight.Accessibility} {
+		status := "ok"
+		if isMissing[p.Name] {
+			status = "MISSING"
+		}
+		fmt.Printf("%-18s %s\n", p.Name, status)
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(preflight.Check())
+	if *open {
+		// Requesting first adds this app to the Screen Recording list, so
+		// there is something to switch on when the pane opens.
+		preflight.Request()
+		for _, p := range missing {
+			if err := preflight.Open(p); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"agentGo/pkg/playback"
+	"agentGo/pkg/progress"
+	"agentGo/pkg/script"
+)
+
+// progressMode is whether runs show a progress display: auto (when stderr
+// is a terminal), on or off, as play's -progress flag says. Other commands
+// leave it empty, which is off.
+var progressMode string
+
+// liveProgress is the progress display being shown, which questions asked
+// on the terminal move out of the way.
+var liveProgress atomic.Pointer[progress.Display]
+
+// newProgress returns the progress display progressMode asks for, of s, or
+// of samples when s is nil, or nil.
+func newProgress(s *script.Script, samples []playback.Sample) *progress.Display {
+	switch progressMode {
+	case "on":
+	case "auto":
+		if !progress.Terminal(os.Stderr) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	if s != nil {
+		return progress.New(os.Stderr, len(s.Steps))
+	}
+	d := progress.New(os.Stderr, len(samples))
+	// A recording takes as long as it took to record.
+	d.Remaining = func(done int) time.Duration {
+		if done >= len(samples) {
+			return 0
+		}
+		return samples[len(samples)-1].Timestamp - samples[done].Timestamp
+	}
+	return d
+}
+
+// topLevelStep returns the index of the step at where if it is at the top
+// level of the script; steps inside a foreach are part of the step that runs
+// them.
+func topLevelStep(where string) (int, bool) {
+	index, ok := strings.CutPrefix(where, "steps[")
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+	return i, err == nil
+}
+
+// showStep shows the step at where starting on d.
+func showStep(d *progress.Display, where string, step script.Step) {
+	action := fmt.Sprintf("%s: %s", where, step.Summary())
+	if i, ok := topLevelStep(where); ok {
+		d.Step(i, action)
+		return
+	}
+	d.Action(action)
+}
+
+// showDone shows the step at where finishing on d, with its result if it
+// checks something.
+func showDone(d *progress.Display, where string, step script.Step, err error) {
+	if strings.HasPrefix(step.Action, "assert_") {
+		d.Check(step.Summary(), err)
+	}
+	if i, ok := topLevelStep(where); ok && err == nil {
+		d.Done(i + 1)
+	}
+}
+
+// pauseProgress moves the progress display out of the way of h, which asks
+// on the terminal.
+func pauseProgress(d *progress.Display, h script.InterferenceHandler) script.InterferenceHandler {
+	if d == nil || h == nil {
+		return h
+	}
+	return func(ctx context.Context, what string) error {
+		d.Pause()
+		defer d.Resume()
+		return h(ctx, what)
+	}
+}
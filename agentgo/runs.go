@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"agentGo/pkg/checkpoint"
+)
+
+// runRuns lists the checkpointed runs, to find the one to resume.
+func runRuns(args []string) {
+	fs := flag.NewFlagSet("runs", flag.ExitOnError)
+	runsDir := fs.String("runs", checkpoint.DefaultStore.Dir, "directory the runs are checkpointed in")
+	limit := fs.Int("n", 20, "number of most recent runs to show (0 for all)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo runs [flags]")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	list, err := checkpoint.Store{Dir: *runsDir}.List()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *limit > 0 && len(list) > *limit {
+		list = list[:*limit]
+	}
+	for _, c := range list {
+		status := "unfinished"
+		switch {
+		case c.Finished && c.Error == "":
+			status = "finished"
+		case c.Finished:
+			status = "failed"
+		}
+		source := c.Source
+		if c.Kind != checkpoint.KindAgent {
+			source = filepath.Base(source)
+		}
+		fmt.Printf("%s  %-9s %-11s %3d steps  %s\n", c.ID, c.Kind, status, c.Steps, source)
+		if c.Step != "" && status != "finished" {
+			fmt.Printf("    last completed %s at %s\n", c.Step, c.Updated.Format("2006-01-02 15:04:05"))
+		}
+		if c.Error != "" {
+			fmt.Printf("    %s\n", c.Error)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/safety"
+	"agentGo/pkg/script"
+	"agentGo/pkg/secure"
+	"agentGo/pkg/vision"
+)
+
+// safetyMode is AGENTGO_SAFETY: rules (the default) checks clicks, typing
+// and keys against safety.DefaultRules, model also asks the model about the
+// actions no rule flags, and off checks nothing.
+func safetyMode() string {
+	mode := os.Getenv(dotenv.EnvName("safety"))
+	switch mode {
+	case "":
+		return "rules"
+	case "rules", "model", "off":
+		return mode
+	}
+	log.Fatalf("invalid %s %q (want rules, model or off)", dotenv.EnvName("safety"), mode)
+	return ""
+}
+
+// newGuard returns what checks the actions of runs on drv, as
+// AGENTGO_SAFETY says, or nil when it is off. In model mode client, if set,
+// judges the actions no rule flags. Risky actions run if
+// AGENTGO_SAFETY_ALLOW grants their rule (see safety.ParseAllow) or they
+// are confirmed on the terminal; without a terminal they are refused.
+func newGuard(drv desktop.Driver, client *vision.Client, logf func(format string, args ...any)) *safety.Guard {
+	mode := safetyMode()
+	if mode == "off" {
+		return nil
+	}
+	allow, err := safety.ParseAllow(os.Getenv(dotenv.EnvName("safety-allow")))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("safety-allow"), err)
+	}
+	p := &safety.Policy{Allow: allow, Confirm: confirmRisk, Logf: logf}
+	if mode == "model" && client != nil {
+		p.Classifier.Model = client
+		p.Classifier.Capture = newDetector(drv).Capture(drv.Capture)
+	}
+	g := &safety.Guard{Policy: p}
+	if t, ok := drv.(script.WindowTitler); ok {
+		g.Window = func() string {
+			title, _ := t.WindowTitle()
+			return title
+		}
+	}
+	return g
+}
+
+// newDetector returns what recognizes the password fields of drv, whose
+// typing is kept out of sessions and logs and whose boxes are blacked out
+// of screenshots, or nil if AGENTGO_MASK_SECRETS is false.
+func newDetector(drv desktop.Driver) *secure.Detector {
+	if v := os.Getenv(dotenv.EnvName("mask-secrets")); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", dotenv.EnvName("mask-secrets"), err)
+		}
+		if !on {
+			return nil
+		}
+	}
+	d := &secure.Detector{}
+	if p, ok := drv.(secure.Prober); ok {
+		d.Probe = p
+	}
+	if t, ok := drv.(script.WindowTitler); ok {
+		d.Window = func() string {
+			title, _ := t.WindowTitle()
+			return title
+		}
+	}
+	return d
+}
+
+// confirmRisk asks on the terminal whether to perform a risky action.
+func confirmRisk(ctx context.Context, a safety.Action, r safety.Risk) (bool, error) {
+	return askOnTerminal(ctx, fmt.Sprintf("\nAbout to %s, which %s (%s).\nAllow? [y/N] ", a, r.Reason, r.Rule))
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/scrub"
+)
+
+// scrubber removes secrets from the log, sessions, assertion reports and
+// model prompts: well-known credential formats, the credentials agentgo
+// uses, the credentials AGENTGO_SCRUB_SECRETS names (comma-separated, looked
+// up as GEMINI_API_KEY is) and whatever AGENTGO_SCRUB_PATTERN, a regular
+// expression, matches.
+var scrubber = sync.OnceValue(func() *scrub.Scrubber {
+	var names []string
+	if list := os.Getenv(dotenv.EnvName("scrub-secrets")); list != "" {
+		names = strings.Split(list, ",")
+	}
+	s, err := scrub.New(names, os.Getenv(dotenv.EnvName("scrub-pattern")))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("scrub-pattern"), err)
+	}
+	return s
+})
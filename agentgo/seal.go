@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/seal"
+)
+
+// runSessionSeal writes checksum manifests, optionally signed, for
+// sessions, recordings and scripts.
+func runSessionSeal(args []string) {
+	fs := flag.NewFlagSet("sessions seal", flag.ExitOnError)
+	keyPath := fs.String("key", "", "Ed25519 private key to sign with (see agentgo sessions keygen); unsigned if empty")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions seal [flags] session-dir|recording...")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Records the checksum of every file in seal.json in a session directory, or")
+		fmt.Fprintln(os.Stderr, "beside a recording or script as FILE.seal.json. Sealed files are verified")
+		fmt.Fprintln(os.Stderr, "before they are played, and so are the files in a sealed directory, against")
+		fmt.Fprintln(os.Stderr, "the directory's seal.")
+	}
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	var key ed25519.PrivateKey
+	if *keyPath != "" {
+		var err error
+		if key, err = seal.LoadPrivateKey(*keyPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, path := range fs.Args() {
+		m, err := seal.Seal(path, key)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		if signer := m.Signer(); signer != "" {
+			fmt.Printf("%s: sealed %d files, signed by %s\n", path, len(m.Files), signer)
+		} else {
+			fmt.Printf("%s: sealed %d files\n", path, len(m.Files))
+		}
+	}
+}
+
+// runSessionVerify checks sessions, recordings and scripts against their
+// seals, exiting non-zero if any fails.
+func runSessionVerify(args []string) {
+	fs := flag.NewFlagSet("sessions verify", flag.ExitOnError)
+	keyPath := fs.String("key", "", "Ed25519 public key the seals must be signed with (default: $AGENTGO_VERIFY_KEY; any valid signature if empty)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions verify [flags] session-dir|recording...")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	trusted := verifyKey()
+	if *keyPath != "" {
+		var err error
+		if trusted, err = seal.LoadPublicKey(*keyPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	failed := false
+	for _, path := range fs.Args() {
+		m, err := seal.Verify(path, trusted)
+		if err != nil {
+			fmt.Printf("%s: FAILED\n", path)
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Printf("  %s\n", line)
+			}
+			failed = true
+			continue
+		}
+		if signer := m.Signer(); signer != "" {
+			fmt.Printf("%s: OK, %d files, signed by %s\n", path, len(m.Files), signer)
+		} else {
+			fmt.Printf("%s: OK, %d files, unsigned\n", path, len(m.Files))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runSessionKeygen creates a signing key pair: NAME, the private key, and
+// NAME.pub.
+func runSessionKeygen(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions keygen NAME    (writes NAME and NAME.pub)")
+		os.Exit(2)
+	}
+	pub, err := seal.GenerateKey(args[0], args[0]+".pub")
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote %s (keep it private) and %s; key %s", args[0], args[0]+".pub", seal.Fingerprint(pub))
+}
+
+// verifyKey returns the public key of AGENTGO_VERIFY_KEY, which tasks'
+// seals must be signed with, or nil if it is unset.
+var verifyKey = sync.OnceValue(func() ed25519.PublicKey {
+	path := os.Getenv(dotenv.EnvName("verify-key"))
+	if path == "" {
+		return nil
+	}
+	key, err := seal.LoadPublicKey(path)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("verify-key"), err)
+	}
+	return key
+})
+
+// checkSeal verifies a script or recording before it is run: against its
+// seal if it has one, and against the seal of the nearest sealed directory
+// it is in, such as a session or a folder of scripts and the files they
+// use. If AGENTGO_VERIFY_KEY is set it must have one of them, signed with
+// that key.
+func checkSeal(path string) error {
+	trusted := verifyKey()
+	sealed := false
+	if seal.Sealed(path) {
+		if _, err := seal.Verify(path, trusted); err != nil {
+			return fmt.Errorf("%s failed verification: %w", path, err)
+		}
+		sealed = true
+	}
+	if dir := seal.SealedDir(path); dir != "" {
+		if _, err := seal.Verify(dir, trusted); err != nil {
+			return fmt.Errorf("%s failed verification of the seal of %s: %w", path, dir, err)
+		}
+		sealed = true
+	}
+	if !sealed && trusted != nil {
+		return fmt.Errorf("%s failed verification: %w", path, seal.ErrNotSealed)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"agentGo/pkg/secrets"
+)
+
+// runSecret stores and removes credentials in the OS keychain.
+func runSecret(args []string) {
+	if len(args) != 2 || (args[0] != "set" && args[0] != "delete") {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo secret set NAME    (reads the value from standard input)")
+		fmt.Fprintln(os.Stderr, "       agentgo secret delete NAME")
+		os.Exit(2)
+	}
+	keychain := secrets.Keychain{}
+	name := args[1]
+
+	if args[0] == "delete" {
+		if err := keychain.Delete(name); err != nil {
+			log.Fatalf("failed to delete %s: %v", name, err)
+		}
+		log.Printf("Deleted %s from the keychain.", name)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		log.Fatalf("failed to read value: %v", err)
+	}
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		log.Fatal("empty value, nothing stored")
+	}
+	if err := keychain.Store(name, value); err != nil {
+		log.Fatalf("failed to store %s: %v", name, err)
+	}
+	log.Printf("Stored %s in the keychain.", name)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"agentGo/pkg/server"
+	"agentGo/pkg/vars"
+)
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:7070", "address to serve the worker API on; only this machine can reach the default, use e.g. :7070 to serve other hosts")
+	driverSpec := fs.String("driver", "local", driverUsage)
+	display := fs.Int("display", 0, displayUsage)
+	labels := make(labelFlag)
+	fs.Var(labels, "label", "worker label as key=value, used by coordinators to select hosts (repeatable)")
+	tokens := fs.String("tokens", "", "identities file of the tokens and client certificates admitted, with their scopes (see agentgo token)")
+	tlsCert := fs.String("tls-cert", "", "serve over TLS with this certificate")
+	tlsKey := fs.String("tls-key", "", "private key of -tls-cert")
+	selfSigned := fs.Bool("tls-self-signed", false, "serve over TLS with a self-signed certificate kept in the config directory, for labs; clients pin its fingerprint, which is logged, with AGENTGO_SERVER_FINGERPRINT")
+	clientCA := fs.String("client-ca", "", "admit client certificates signed by this CA, for identities listed as cert in -tokens (needs TLS)")
+	parseFlags(fs, args)
+
+	authn := loadAuth(*tokens)
+	tlsConfig := serverTLS(*listen, *tlsCert, *tlsKey, *selfSigned, *clientCA)
+	if authn.Certs() && *clientCA == "" {
+		log.Fatalf("%s lists certificate identities but -client-ca is not set", *tokens)
+	}
+	if !isLoopback(*listen) {
+		if authn == nil {
+			log.Printf("Warning: serving without authentication on %s; anyone who can reach it controls this desktop. Set -tokens.", *listen)
+		}
+		if tlsConfig == nil {
+			log.Printf("Warning: serving in cleartext on %s; screenshots, scripts and tokens cross the network unencrypted. Set -tls-cert or -tls-self-signed.", *listen)
+		}
+	}
+
+	drv := openDriver(*driverSpec, *display)
+	defer drv.Close()
+	armKillSwitch(drv)
+
+	worker := &server.Worker{
+		Labels: labels,
+		Run: func(ctx context.Context, req server.TaskRequest, logf func(string, ...any)) error {
+			// A task's ${VAR}s are its own; this host's environment, which
+			// holds its secrets, is not the caller's to read.
+			variables := vars.NewIsolated()
+			for name, value := range req.Vars {
+				variables.Set(name, value)
+			}
+			return execute(ctx, drv, req.Script, req.Recording, variables, nil, logf)
+		},
+		Capture:  newDetector(drv).Capture(drv.Capture),
+		Throttle: captureThrottle(),
+		Auth:     authn,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go worker.Serve(ctx)
+
+	srv := &http.Server{Addr: *listen, Handler: worker.Handler(), TLSConfig: tlsConfig}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Worker listening on %s with labels %v", *listen, map[string]string(labels))
+	var err error
+	if tlsConfig != nil {
+		// The certificate is in tlsConfig.
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("server failed: %v", err)
+	}
+	log.Println("Worker stopped.")
+}
+
+// labelFlag collects repeated key=value flags.
+type labelFlag map[string]string
+
+func (l labelFlag) String() string {
+	return ""
+}
+
+func (l labelFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("invalid label %q, expected key=value", value)
+	}
+	l[k] = v
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"agentGo/pkg/session"
+)
+
+// runSessions reports on, compares, merges, splits, seals and verifies
+// recorded sessions.
+func runSessions(args []string) {
+	sub := map[string]func([]string){
+		"stats":  runSessionStats,
+		"diff":   runSessionDiff,
+		"merge":  runSessionMerge,
+		"split":  runSessionSplit,
+		"seal":   runSessionSeal,
+		"verify": runSessionVerify,
+		"keygen": runSessionKeygen,
+		"repair": runSessionRepair,
+	}
+	if len(args) < 1 || sub[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions stats [flags] session-dir...")
+		fmt.Fprintln(os.Stderr, "       agentgo sessions diff [flags] a b")
+		fmt.Fprintln(os.Stderr, "       agentgo sessions merge -o out session-dir|recording...")
+		fmt.Fprintln(os.Stderr, "       agentgo sessions split -at TIME[,TIME...] [-o prefix] session-dir|recording")
+		fmt.Fprintln(os.Stderr, "       agentgo sessions seal [flags] session-dir|recording...")
+		fmt.Fprintln(os.Stderr, "       agentgo sessions verify [flags] session-dir|recording...")
+		fmt.Fprintln(os.Stderr, "       agentgo sessions keygen NAME")
+		fmt.Fprintln(os.Stderr, "       agentgo sessions repair [-force] session-dir...")
+		os.Exit(2)
+	}
+	sub[args[0]](args[1:])
+}
+
+// runSessionRepair finalizes sessions whose recorder crashed, keeping what
+// was written before the crash.
+func runSessionRepair(args []string) {
+	fs := flag.NewFlagSet("sessions repair", flag.ExitOnError)
+	force := fs.Bool("force", false, "repair even if the session seems to be still recording")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions repair [-force] session-dir...")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Finalizes sessions left behind by a recorder that crashed or lost power:")
+		fmt.Fprintln(os.Stderr, "events up to the last sync (about a second before) are kept, a record")
+		fmt.Fprintln(os.Stderr, "or frame cut short is dropped, and the old log is kept with a .bak suffix.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	failed := false
+	for _, dir := range fs.Args() {
+		r, err := session.Repair(dir, *force)
+		switch {
+		case err != nil:
+			log.Printf("%s: %v", dir, err)
+			failed = true
+		case !r.Interrupted:
+			log.Printf("%s: complete, nothing to repair", dir)
+		default:
+			log.Printf("%s: repaired, %d events kept, %d dropped", dir, r.Events, r.Dropped)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runSessionStats prints statistics for each session and, for several, all
+// of them together.
+func runSessionStats(args []string) {
+	fs := flag.NewFlagSet("sessions stats", flag.ExitOnError)
+	price := fs.Float64("price", 0, "model price in dollars per million tokens, to estimate cost (0 to leave cost out)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions stats [flags] session-dir...")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var total session.Stats
+	for _, dir := range fs.Args() {
+		s, err := session.Summarize(dir)
+		if err != nil {
+			log.Fatalf("%s: %v", dir, err)
+		}
+		printStats(dir, s, *price)
+		total.Add(s)
+	}
+	if fs.NArg() > 1 {
+		printStats(fmt.Sprintf("all %d sessions", total.Sessions), total, *price)
+	}
+}
+
+func printStats(name string, s session.Stats, price float64) {
+	fmt.Println(name)
+	fmt.Printf("  duration:       %v\n", s.Duration.Round(100*time.Millisecond))
+	fmt.Printf("  events:         %s\n", counts(s.Events, ""))
+	fmt.Printf("  pointer travel: %.1f screen widths\n", s.Distance)
+	if len(s.ClicksByApp) > 0 {
+		fmt.Printf("  clicks by app:  %s\n", counts(s.ClicksByApp, "(unknown)"))
+	}
+	model := fmt.Sprintf("%d calls, %d tokens, %d failed (%.0f%%)", s.ModelRequests, s.ModelTokens, s.ModelErrors, percent(s.ModelErrors, s.ModelRequests))
+	if price > 0 {
+		model += fmt.Sprintf(", about $%.4f", float64(s.ModelTokens)/1e6*price)
+	}
+	fmt.Printf("  model:          %s\n", model)
+	fmt.Printf("  steps:          %d\n", s.Steps)
+	fmt.Printf("  failed runs:    %d of %d (%.0f%%)\n", s.Failed, s.Sessions, percent(s.Failed, s.Sessions))
+}
+
+// counts lists counts by name, largest first, naming "" as empty.
+func counts(m map[string]int, empty string) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if m[names[i]] != m[names[j]] {
+			return m[names[i]] > m[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		label := name
+		if label == "" {
+			label = empty
+		}
+		parts[i] = fmt.Sprintf("%s %d", label, m[name])
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func percent(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(of)
+}
+
+// runSessionDiff compares two recordings or sessions of the same workflow,
+// exiting 1 if they diverge, as diff does.
+func runSessionDiff(args []string) {
+	fs := flag.NewFlagSet("sessions diff", flag.ExitOnError)
+	distance := fs.Float64("distance", 0.02, "how far, in normalized coordinates, a click may move before it is reported")
+	timing := fs.Float64("timing", 0.5, "by what fraction the time between steps may change before it is reported")
+	minTiming := fs.Duration("min-timing", time.Second, "the smallest timing change reported")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions diff [flags] a b")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "a and b are session directories or recordings. Their clicks, typing, keys and")
+		fmt.Fprintln(os.Stderr, "step notes are aligned, and missing and extra steps, moved clicks and")
+		fmt.Fprintln(os.Stderr, "timing changes reported, with steps numbered as by agentgo edit -list.")
+	}
+	inputs := parseInterspersed(fs, args)
+	if len(inputs) != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	var timelines [2][]session.Event
+	for i, path := range inputs {
+		events, err := openTimeline(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		timelines[i] = events
+	}
+
+	diffs := session.Diff(timelines[0], timelines[1], session.DiffOptions{Distance: *distance, Timing: *timing, MinTiming: *minTiming})
+	if len(diffs) == 0 {
+		fmt.Printf("%s and %s follow the same steps\n", inputs[0], inputs[1])
+		return
+	}
+	byKind := map[string]int{}
+	for _, d := range diffs {
+		byKind[d.Kind]++
+	}
+	fmt.Printf("%s vs %s: %s\n", inputs[0], inputs[1], counts(byKind, ""))
+	marks := map[string]string{session.DiffMissing: "-", session.DiffExtra: "+"}
+	for _, d := range diffs {
+		fmt.Printf("%s %s\n", cmp.Or(marks[d.Kind], "~"), d)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"agentGo/pkg/fake"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/simulate"
+	"agentGo/pkg/vision"
+)
+
+// runSimulate shows an agent saved screenshots instead of the screen and
+// reports the actions it proposes, performing none of them.
+func runSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	framesDir := fs.String("frames", "", "directory of saved screenshots, in name order, such as a recorded session (required)")
+	task := fs.String("task", "", "what the agent is asked to do (required)")
+	modelName := fs.String("model", vision.DefaultModel, "Gemini model to run as the agent, also used to find elements it clicks by description")
+	maxTurns := fs.Int("max-turns", 30, "most model responses before giving up")
+	out := fs.String("out", "", "also write the proposed actions and outcome to this JSON file")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo simulate -frames dir -task \"...\" [flags]")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "The agent sees the first screenshot, and the next one after each click,")
+		fmt.Fprintln(os.Stderr, "typing or key it proposes. It runs at temperature 0, so runs over the same")
+		fmt.Fprintln(os.Stderr, "screenshots can be compared across models and prompts.")
+	}
+	parseFlags(fs, args)
+	if *framesDir == "" || *task == "" || fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	desktop, err := fake.Open(*framesDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	desktop.StepOnInput = true
+	apiKey, err := secrets.Get("GEMINI_API_KEY")
+	if err != nil {
+		log.Fatal(err)
+	}
+	client, err := newVision(ctx, apiKey, *modelName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+	model := client.NewModel()
+	simulate.Configure(model)
+
+	sim := &simulate.Simulator{Chat: model.StartChat(), Desktop: desktop, Vision: client, MaxTurns: *maxTurns}
+	log.Printf("Simulating %q on %d saved screenshots", *task, desktop.Frames())
+	res, runErr := sim.Run(ctx, *task)
+	if res != nil {
+		for _, s := range res.Steps {
+			status := "ok"
+			if s.Error {
+				status = "failed: " + s.Result
+			}
+			fmt.Printf("%3d  frame %-4d %s %s  (%s)\n", s.Turn, s.Frame, s.Tool, s.Args, status)
+		}
+		if res.Done {
+			fmt.Printf("done after %d turns: %s\n", res.Turns, res.Answer)
+		}
+		if *out != "" {
+			data, err := json.MarshalIndent(res, "", "  ")
+			if err == nil {
+				err = os.WriteFile(*out, append(data, '\n'), 0o644)
+			}
+			if err != nil {
+				log.Fatalf("failed to write %s: %v", *out, err)
+			}
+		}
+	}
+	if runErr != nil {
+		log.Fatal(runErr)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/script"
+	"agentGo/pkg/speech"
+)
+
+// announcer reads a run's progress aloud with the system speech
+// synthesizer, so that unattended runs can be followed from across the
+// room: AGENTGO_SPEAK=progress announces every step of the script and how
+// the run ends, and AGENTGO_SPEAK=outcome only how it ends. A nil
+// announcer is silent.
+type announcer struct {
+	queue *speech.Queue
+	steps bool
+	name  string
+	total int
+	// step is the number and summary of the last top-level step.
+	step    int
+	summary string
+}
+
+// speechGrace is how long a run waits for its last announcement to be
+// spoken.
+const speechGrace = 30 * time.Second
+
+// newAnnouncer returns the announcer AGENTGO_SPEAK asks for, for s, or for
+// a recording when s is nil.
+func newAnnouncer(s *script.Script, logf func(format string, args ...any)) *announcer {
+	a := &announcer{name: "the recording"}
+	switch mode := os.Getenv(dotenv.EnvName("speak")); mode {
+	case "", "off":
+		return nil
+	case "progress":
+		a.steps = true
+	case "outcome":
+	default:
+		log.Fatalf("invalid %s %q: want progress, outcome or off", dotenv.EnvName("speak"), mode)
+	}
+	if s != nil {
+		a.name, a.total = "the script", len(s.Steps)
+		if s.Name != "" {
+			a.name = s.Name
+		}
+	}
+	a.queue = speech.NewQueue(logf)
+	if a.steps {
+		a.queue.Say(fmt.Sprintf("Starting %s.", a.name))
+	}
+	return a
+}
+
+// onStep announces the steps at the top level of the script; steps inside
+// a foreach are part of the step that runs them.
+func (a *announcer) onStep(where string, step script.Step) {
+	if a == nil {
+		return
+	}
+	i, ok := topLevelStep(where)
+	if !ok {
+		return
+	}
+	a.step, a.summary = i+1, step.Summary()
+	if a.steps {
+		a.queue.Say(fmt.Sprintf("Step %d of %d: %s.", a.step, a.total, a.summary))
+	}
+}
+
+// finish announces how the run ended and waits for it to be spoken.
+func (a *announcer) finish(ctx context.Context, err error) {
+	if a == nil {
+		return
+	}
+	at := ""
+	if a.step > 0 {
+		at = fmt.Sprintf(" at step %d: %s", a.step, a.summary)
+	}
+	switch {
+	case err == nil:
+		a.queue.Say(fmt.Sprintf("Finished %s.", a.name))
+	case errors.Is(err, context.Canceled) && ctx.Err() != nil:
+		a.queue.Say(fmt.Sprintf("Stopped %s%s.", a.name, at))
+	default:
+		a.queue.Say(fmt.Sprintf("%s failed%s.", capitalize(a.name), at))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), speechGrace)
+	defer cancel()
+	a.queue.Close(ctx)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/playback"
+	"agentGo/pkg/session"
+)
+
+// runSessionMerge joins sessions or recordings end to end.
+func runSessionMerge(args []string) {
+	fs := flag.NewFlagSet("sessions merge", flag.ExitOnError)
+	out := fs.String("o", "", "session directory or recording to write (required; must not exist)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions merge -o out session-dir|recording.csv...")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Each input starts where the one before it ends. Inputs are all session")
+		fmt.Fprintln(os.Stderr, "directories or all recordings, and the output is the same kind.")
+	}
+	inputs := parseInterspersed(fs, args)
+	if len(inputs) < 2 || *out == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	checkNew(*out)
+
+	dirs := isDir(inputs[0])
+	parts := make([][]session.Event, 0, len(inputs))
+	for _, path := range inputs {
+		if isDir(path) != dirs {
+			log.Fatal("cannot merge sessions with recordings; convert the recordings first")
+		}
+		events, err := openTimeline(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		parts = append(parts, events)
+	}
+	events := session.Concat(parts...)
+	if err := saveTimeline(*out, inputs[0], events); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+	log.Printf("Wrote %s, %v long", *out, events[len(events)-1].Time.Round(time.Millisecond))
+}
+
+// runSessionSplit cuts a session or recording into parts at given times.
+func runSessionSplit(args []string) {
+	fs := flag.NewFlagSet("sessions split", flag.ExitOnError)
+	at := fs.String("at", "", "comma-separated times to cut at, as HH:MM:SS, MM:SS or a duration such as 2m30s (required)")
+	out := fs.String("o", "", "prefix of the parts to write, numbered from 1 (default: the input's name)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo sessions split -at TIME[,TIME...] [-o prefix] session-dir|recording.csv")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Each part starts at zero, with the screen and pointer position it was cut at.")
+	}
+	inputs := parseInterspersed(fs, args)
+	if len(inputs) != 1 || *at == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	var cuts []time.Duration
+	for _, s := range strings.Split(*at, ",") {
+		t, err := parseOffset(strings.TrimSpace(s))
+		if err != nil {
+			log.Fatalf("invalid -at: %v", err)
+		}
+		cuts = append(cuts, t)
+	}
+	in := inputs[0]
+	events, err := openTimeline(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	parts, err := session.Split(events, cuts...)
+	if err != nil {
+		log.Fatalf("%s: %v", in, err)
+	}
+
+	prefix, ext := *out, ""
+	if !isDir(in) {
+		ext = filepath.Ext(in)
+	}
+	if prefix == "" {
+		prefix = strings.TrimSuffix(filepath.Clean(in), ext)
+	}
+	names := make([]string, len(parts))
+	for i := range parts {
+		names[i] = fmt.Sprintf("%s-%d%s", prefix, i+1, ext)
+		checkNew(names[i])
+	}
+	for i, part := range parts {
+		if err := saveTimeline(names[i], in, part); err != nil {
+			log.Fatalf("failed to write %s: %v", names[i], err)
+		}
+		log.Printf("Wrote %s, %v long", names[i], part[len(part)-1].Time.Round(time.Millisecond))
+	}
+}
+
+// openTimeline reads a session directory, with its frames' paths, or a
+// recording as session events.
+func openTimeline(path string) ([]session.Event, error) {
+	if isDir(path) {
+		events, err := session.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return events, nil
+	}
+	return readEvents(path, image.Point{})
+}
+
+// saveTimeline writes events to out as the same kind of file as like: a
+// session directory, in like's log format, or a recording.
+func saveTimeline(out, like string, events []session.Event) error {
+	if !isDir(like) {
+		return playback.SaveCSV(out, recordingSamples(events))
+	}
+	format, err := session.LogFormat(like)
+	if err != nil {
+		return err
+	}
+	return session.Save(out, events, format)
+}
+
+// parseInterspersed parses fs's flags as parseFlags does, but also after
+// the arguments, as in "merge a b -o c", and returns the arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var rest []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			break
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if err := dotenv.ApplyFlags(fs); err != nil {
+		log.Fatal(err)
+	}
+	return rest
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// checkNew refuses to overwrite path, which could also be one of the inputs.
+func checkNew(path string) {
+	if _, err := os.Stat(path); err == nil {
+		log.Fatalf("%s already exists", path)
+	}
+}
+
+// parseOffset parses a time into a session: HH:MM:SS, MM:SS, plain
+// seconds, or a Go duration such as 2m30s. Seconds may have a fraction.
+func parseOffset(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	fields := strings.Split(s, ":")
+	if len(fields) > 3 {
+		return 0, fmt.Errorf("%q is not a time", s)
+	}
+	var seconds float64
+	for _, f := range fields {
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is not a time", s)
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
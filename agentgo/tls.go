@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net"
+	"os"
+
+	"agentGo/pkg/certs"
+	"agentGo/pkg/dotenv"
+)
+
+// serverTLS returns the TLS configuration of the worker API, or nil to
+// serve it in cleartext. The certificate is certFile and keyFile or, if
+// selfSigned, one made for the host of listen and kept in the config
+// directory. With clientCA, clients may sign in with certificates it
+// signed.
+func serverTLS(listen, certFile, keyFile string, selfSigned bool, clientCA string) *tls.Config {
+	switch {
+	case (certFile == "") != (keyFile == ""):
+		log.Fatal("-tls-cert and -tls-key go together")
+	case selfSigned && certFile != "":
+		log.Fatal("-tls-self-signed and -tls-cert exclude each other")
+	case !selfSigned && certFile == "":
+		if clientCA != "" {
+			log.Fatal("-client-ca needs -tls-cert or -tls-self-signed")
+		}
+		return nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if selfSigned {
+		host, _, _ := net.SplitHostPort(listen)
+		pair, err := certs.SelfSigned(certs.DefaultDir(), host)
+		if err != nil {
+			log.Fatalf("failed to make a self-signed certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+		log.Printf("Serving TLS with a self-signed certificate; clients trust it with %s=%s", dotenv.EnvName("server-fingerprint"), certs.Fingerprint(pair.Certificate[0]))
+	} else {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			log.Fatalf("failed to load TLS certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	if clientCA != "" {
+		cfg.ClientCAs = loadPool(clientCA)
+		// Clients without a certificate may still sign in with a token.
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg
+}
+
+// clientTLS returns the TLS configuration of clients of the worker API, or
+// nil if none is set: the client certificate AGENTGO_CLIENT_CERT with its
+// key AGENTGO_CLIENT_KEY, and the worker's certificate trusted if
+// AGENTGO_SERVER_CA signed it or, for self-signed certificates, if it has
+// the fingerprint AGENTGO_SERVER_FINGERPRINT.
+func clientTLS() *tls.Config {
+	certFile, keyFile := os.Getenv(dotenv.EnvName("client-cert")), os.Getenv(dotenv.EnvName("client-key"))
+	serverCA, fingerprint := os.Getenv(dotenv.EnvName("server-ca")), os.Getenv(dotenv.EnvName("server-fingerprint"))
+	if certFile == "" && keyFile == "" && serverCA == "" && fingerprint == "" {
+		return nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			log.Fatalf("failed to load client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	if serverCA != "" {
+		cfg.RootCAs = loadPool(serverCA)
+	}
+	if fingerprint != "" {
+		certs.Pin(cfg, fingerprint)
+	}
+	return cfg
+}
+
+// loadPool reads the PEM certificates of a CA.
+func loadPool(path string) *x509.CertPool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("failed to read CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		log.Fatalf("no certificates in %s", path)
+	}
+	return pool
+}
+
+// isLoopback reports whether addr only listens on this machine.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
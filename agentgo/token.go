@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"agentGo/pkg/auth"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/server"
+)
+
+// runToken creates an API token for the worker API and lists its hash in
+// an identities file, or lists an identity that signs in with a client
+// certificate.
+func runToken(args []string) {
+	fs := flag.NewFlagSet("token", flag.ExitOnError)
+	tokens := fs.String("tokens", "", "identities file to add to (required)")
+	cert := fs.Bool("cert", false, "let NAME sign in with a client certificate whose common name is NAME, instead of a token")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agentgo token -tokens FILE [-cert] NAME observe|act|admin")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Adds NAME to the identities file that agentgo serve -tokens reads, with")
+		fmt.Fprintln(os.Stderr, "the scope it may use: observe reads status and screenshots, act also")
+		fmt.Fprintln(os.Stderr, "runs tasks and cancels its own, admin cancels anyone's. The new token is")
+		fmt.Fprintln(os.Stderr, "printed once; the file keeps only its hash. Clients send it from")
+		fmt.Fprintln(os.Stderr, "AGENTGO_TOKEN (or the keychain, see agentgo secret).")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if *tokens == "" || fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name := fs.Arg(0)
+	scope, err := auth.ParseScope(fs.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, credential := "", "cert"
+	if !*cert {
+		if token, credential, err = auth.NewToken(); err != nil {
+			log.Fatalf("failed to create token: %v", err)
+		}
+	}
+	f, err := os.OpenFile(*tokens, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		log.Fatalf("failed to open identities file: %v", err)
+	}
+	if _, err := fmt.Fprintf(f, "%s %s %s\n", name, scope, credential); err != nil {
+		log.Fatalf("failed to add %s: %v", name, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("failed to add %s: %v", name, err)
+	}
+	log.Printf("Added %s with scope %s to %s.", name, scope, *tokens)
+	if token != "" {
+		fmt.Println(token)
+	}
+}
+
+// loadAuth returns the authenticator of the identities file path, or nil
+// if path is empty.
+func loadAuth(path string) *auth.Authenticator {
+	if path == "" {
+		return nil
+	}
+	a, err := auth.Load(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return a
+}
+
+// newWorkerClient returns a client of the worker at addr that signs in
+// with AGENTGO_TOKEN, looked up like GEMINI_API_KEY, or with the client
+// certificate AGENTGO_CLIENT_CERT and key AGENTGO_CLIENT_KEY, and trusts
+// the worker's certificate as clientTLS says. A bare host:port is reached
+// over TLS when any of that is configured.
+func newWorkerClient(addr string) *server.Client {
+	cfg := clientTLS()
+	if cfg != nil && !strings.Contains(addr, "://") {
+		addr = "https://" + addr
+	}
+	c := server.NewClient(addr)
+	c.Token = secrets.Lookup(dotenv.EnvName("token"))
+	if cfg != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = cfg
+		c.HTTPClient.Transport = transport
+	} else if host, ok := strings.CutPrefix(c.BaseURL, "http://"); ok && c.Token != "" && !isLoopback(host) {
+		log.Printf("Warning: sending %s to %s in cleartext; serve the worker over TLS", dotenv.EnvName("token"), c.BaseURL)
+	}
+	return c
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+
+	"agentGo/pkg/tools"
+)
+
+// runTools prints the desktop actions as OpenAI-style tool definitions, for
+// agent loops written against other function-calling APIs.
+func runTools(args []string) {
+	fs := flag.NewFlagSet("tools", flag.ExitOnError)
+	parseFlags(fs, args)
+
+	data, err := json.MarshalIndent(tools.Definitions(), "", "  ")
+	if err != nil {
+		log.Fatalf("failed to encode tool definitions: %v", err)
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"agentGo/pkg/agent"
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/killswitch"
+	"agentGo/pkg/schedule"
+	"agentGo/pkg/tools"
+	"agentGo/pkg/vision"
+	"agentGo/pkg/voice"
+)
+
+// runs tracks the daemon's runs, scheduled and spoken, so that a spoken
+// "stop" can cancel them all.
+type runs struct {
+	mu      sync.Mutex
+	next    int
+	cancels map[int]context.CancelFunc
+	task    bool
+}
+
+// count returns the number of runs in progress.
+func (r *runs) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.cancels)
+}
+
+// track returns a context for a run that stop cancels, and a function to
+// call when the run ends.
+func (r *runs) track(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancels == nil {
+		r.cancels = make(map[int]context.CancelFunc)
+	}
+	id := r.next
+	r.next++
+	r.cancels[id] = cancel
+	return ctx, func() {
+		cancel()
+		r.mu.Lock()
+		delete(r.cancels, id)
+		r.mu.Unlock()
+	}
+}
+
+// stop cancels every run in progress and returns how many there were.
+func (r *runs) stop() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+	return len(r.cancels)
+}
+
+// listenForCommands carries out spoken commands until ctx is done: stop
+// cancels every run, run starts a job of scheduler now, and anything else
+// is a task for an agent driving drv, one at a time and taking turns at the
+// desktop with jobs.
+func listenForCommands(ctx context.Context, drv desktop.Driver, client *vision.Client, t voice.Transcriber, scheduler *schedule.Scheduler, active *runs, desk desktopLock, wake string) {
+	names := make([]string, len(scheduler.Jobs))
+	for i, job := range scheduler.Jobs {
+		names[i] = job.Name
+	}
+	listener := &voice.Listener{Transcriber: t, Wake: wake, Jobs: names}
+	log.Printf("Listening for spoken commands starting with %q.", wake)
+	err := listener.Listen(ctx, func(cmd voice.Command) {
+		switch cmd.Kind {
+		case voice.Stop:
+			log.Printf("voice: stopping %d runs", active.stop())
+		case voice.RunJob:
+			if err := scheduler.Trigger(ctx, cmd.Job); err != nil {
+				log.Printf("voice: %v", err)
+			}
+		case voice.Task:
+			active.mu.Lock()
+			busy := active.task
+			active.task = true
+			active.mu.Unlock()
+			if busy {
+				log.Printf("voice: ignoring %q; the agent is busy with a task", cmd.Task)
+				return
+			}
+			go func() {
+				defer func() {
+					active.mu.Lock()
+					active.task = false
+					active.mu.Unlock()
+				}()
+				runTask(ctx, drv, client, active, desk, cmd.Task)
+			}()
+		}
+	})
+	if err != nil {
+		log.Printf("voice commands stopped: %v", err)
+	}
+}
+
+// runTask has an agent carry out task on drv's desktop once desk is free.
+func runTask(ctx context.Context, drv desktop.Driver, client *vision.Client, active *runs, desk desktopLock, task string) {
+	ctx, done := active.track(ctx)
+	defer done()
+	release, err := desk.acquire(ctx, fmt.Sprintf("task %q", task))
+	if err != nil {
+		log.Printf("task %q: %v", task, err)
+		return
+	}
+	defer release()
+	ctx, untrack := killSwitch.Track(ctx, "task "+task, nil)
+	defer untrack()
+	model := client.NewModel()
+	agent.Configure(model)
+	a := &agent.Agent{
+		Chat:       model.StartChat(),
+		Dispatcher: &tools.Dispatcher{Desktop: drv, Vision: client, Safety: newGuard(drv, client, log.Printf), Secure: newDetector(drv), Scrub: scrubber().String, OnCall: auditCall("task " + task)},
+	}
+	log.Printf("task %q: starting", task)
+	res, err := a.Run(ctx, task)
+	if err = killswitch.Cause(ctx, err); err != nil {
+		log.Printf("task %q: failed: %v", task, err)
+		return
+	}
+	log.Printf("task %q: done after %d actions: %s", task, res.Calls, res.Answer)
+}
@@ -0,0 +1,291 @@
+// Package adb drives Android devices and emulators through the Android Debug
+// Bridge: screencap for capture and the input command for taps, swipes, text
+// and key events.
+package adb
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Device is a connected Android device. It implements the desktop driver
+// methods (Move, Click, Type, KeyTap, Capture, Close).
+//
+// Touch screens have no pointer, so Move only records the position that the
+// next Click taps.
+type Device struct {
+	// Serial selects the device as in "adb -s"; empty uses the only one
+	// attached.
+	Serial string
+
+	mu            sync.Mutex
+	width, height int
+	x, y          int
+}
+
+// Open connects to the device with the given serial ("" for the only
+// attached device) and reads its screen size.
+func Open(serial string) (*Device, error) {
+	d := &Device{Serial: serial}
+	if _, err := d.adb("get-state"); err != nil {
+		return nil, fmt.Errorf("android device %s is not available: %w", d.describe(), err)
+	}
+	w, h, err := d.screenSize()
+	if err != nil {
+		return nil, err
+	}
+	d.width, d.height = w, h
+	return d, nil
+}
+
+// Size returns the screen size in pixels.
+func (d *Device) Size() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.width, d.height
+}
+
+// Move sets the position for the next tap in normalized (0-1) coordinates.
+func (d *Device) Move(normX, normY float64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.x = clamp(int(normX*float64(d.width)), 0, d.width-1)
+	d.y = clamp(int(normY*float64(d.height)), 0, d.height-1)
+	return nil
+}
+
+// Click taps at the current position. "right" long-presses, and the wheel
+// buttons scroll by swiping a third of the screen.
+func (d *Device) Click(button string) error {
+	d.mu.Lock()
+	x, y, h := d.x, d.y, d.height
+	d.mu.Unlock()
+
+	switch button {
+	case "", "left":
+		return d.input("tap", x, y)
+	case "right":
+		return d.Swipe(x, y, x, y, 800*time.Millisecond)
+	case "wheelUp":
+		return d.Swipe(x, y, x, clamp(y+h/3, 0, h-1), 300*time.Millisecond)
+	case "wheelDown":
+		return d.Swipe(x, y, x, clamp(y-h/3, 0, h-1), 300*time.Millisecond)
+	}
+	return fmt.Errorf("unknown mouse button %q", button)
+}
+
+// Swipe drags from (x1, y1) to (x2, y2) in pixels over the given duration.
+func (d *Device) Swipe(x1, y1, x2, y2 int, duration time.Duration) error {
+	return d.input("swipe", x1, y1, x2, y2, int(duration.Milliseconds()))
+}
+
+// Drag long-presses at one normalized position and drags to another over
+// the given duration, e.g. to move an icon. It needs Android 7 or newer.
+func (d *Device) Drag(fromX, fromY, toX, toY float64, duration time.Duration) error {
+	d.mu.Lock()
+	w, h := d.width, d.height
+	d.mu.Unlock()
+	return d.input("draganddrop",
+		clamp(int(fromX*float64(w)), 0, w-1), clamp(int(fromY*float64(h)), 0, h-1),
+		clamp(int(toX*float64(w)), 0, w-1), clamp(int(toY*float64(h)), 0, h-1),
+		int(duration.Milliseconds()))
+}
+
+// Type enters text into the focused field.
+func (d *Device) Type(text string) error {
+	// "input text" cannot send newlines, so split on them and press enter.
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			if err := d.input("keyevent", "KEYCODE_ENTER"); err != nil {
+				return err
+			}
+		}
+		if line == "" {
+			continue
+		}
+		if err := d.input("text", escapeText(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KeyTap presses a key or combination such as "enter", "back" or "ctrl+a".
+// Combinations need Android 13 or newer.
+func (d *Device) KeyTap(key string) error {
+	parts := strings.Split(key, "+")
+	codes := make([]any, len(parts))
+	for i, p := range parts {
+		code, ok := keycode(strings.TrimSpace(p))
+		if !ok {
+			return fmt.Errorf("unknown key %q", p)
+		}
+		codes[i] = code
+	}
+	if len(codes) == 1 {
+		return d.input(append([]any{"keyevent"}, codes...)...)
+	}
+	return d.input(append([]any{"keycombination"}, codes...)...)
+}
+
+// Capture takes a screenshot of the device.
+func (d *Device) Capture() (image.Image, error) {
+	out, err := d.adb("exec-out", "screencap", "-p")
+	if err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screencap: %w", err)
+	}
+	// Rotation changes the screen size, so keep it in sync with captures.
+	b := img.Bounds()
+	d.mu.Lock()
+	d.width, d.height = b.Dx(), b.Dy()
+	d.mu.Unlock()
+	return img, nil
+}
+
+// Close implements the driver interface; the adb server keeps running.
+func (d *Device) Close() error {
+	return nil
+}
+
+var sizePattern = regexp.MustCompile(`(\d+)x(\d+)`)
+
+// screenSize reads the display size from "wm size", preferring an override
+// size when one is set.
+func (d *Device) screenSize() (int, int, error) {
+	out, err := d.adb("shell", "wm", "size")
+	if err != nil {
+		return 0, 0, err
+	}
+	matches := sizePattern.FindAllStringSubmatch(string(out), -1)
+	if len(matches) == 0 {
+		return 0, 0, fmt.Errorf("failed to parse screen size %q", strings.TrimSpace(string(out)))
+	}
+	m := matches[len(matches)-1]
+	w, _ := strconv.Atoi(m[1])
+	h, _ := strconv.Atoi(m[2])
+	return w, h, nil
+}
+
+func (d *Device) input(args ...any) error {
+	shell := []string{"shell", "input"}
+	for _, a := range args {
+		shell = append(shell, fmt.Sprint(a))
+	}
+	_, err := d.adb(shell...)
+	return err
+}
+
+func (d *Device) adb(args ...string) ([]byte, error) {
+	if d.Serial != "" {
+		args = append([]string{"-s", d.Serial}, args...)
+	}
+	cmd := exec.Command("adb", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("adb %s failed: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return nil, fmt.Errorf("adb %s failed: %w", strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+func (d *Device) describe() string {
+	if d.Serial == "" {
+		return "(default)"
+	}
+	return d.Serial
+}
+
+// escapeText quotes text for "input text", which runs through the device
+// shell and treats %s as a space.
+func escapeText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == ' ':
+			b.WriteString("%s")
+		case strings.ContainsRune(`\'"()<>|;&*~$!?#[]{}%`+"`", r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// keycodes maps the key names used by scripts to Android key codes.
+var keycodes = map[string]string{
+	"enter":      "KEYCODE_ENTER",
+	"return":     "KEYCODE_ENTER",
+	"tab":        "KEYCODE_TAB",
+	"backspace":  "KEYCODE_DEL",
+	"delete":     "KEYCODE_FORWARD_DEL",
+	"escape":     "KEYCODE_ESCAPE",
+	"esc":        "KEYCODE_ESCAPE",
+	"space":      "KEYCODE_SPACE",
+	"up":         "KEYCODE_DPAD_UP",
+	"down":       "KEYCODE_DPAD_DOWN",
+	"left":       "KEYCODE_DPAD_LEFT",
+	"right":      "KEYCODE_DPAD_RIGHT",
+	"home":       "KEYCODE_MOVE_HOME",
+	"end":        "KEYCODE_MOVE_END",
+	"pageup":     "KEYCODE_PAGE_UP",
+	"pagedown":   "KEYCODE_PAGE_DOWN",
+	"shift":      "KEYCODE_SHIFT_LEFT",
+	"ctrl":       "KEYCODE_CTRL_LEFT",
+	"control":    "KEYCODE_CTRL_LEFT",
+	"alt":        "KEYCODE_ALT_LEFT",
+	"cmd":        "KEYCODE_META_LEFT",
+	"command":    "KEYCODE_META_LEFT",
+	"super":      "KEYCODE_META_LEFT",
+	"win":        "KEYCODE_META_LEFT",
+	"back":       "KEYCODE_BACK",
+	"menu":       "KEYCODE_MENU",
+	"homescreen": "KEYCODE_HOME",
+	"appswitch":  "KEYCODE_APP_SWITCH",
+	"power":      "KEYCODE_POWER",
+	"volumeup":   "KEYCODE_VOLUME_UP",
+	"volumedown": "KEYCODE_VOLUME_DOWN",
+}
+
+// keycode resolves a key name, a single letter or digit, or a raw
+// KEYCODE_* name.
+func keycode(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	if code, ok := keycodes[lower]; ok {
+		return code, true
+	}
+	if strings.HasPrefix(strings.ToUpper(name), "KEYCODE_") {
+		return strings.ToUpper(name), true
+	}
+	if len(lower) == 1 && (lower[0] >= 'a' && lower[0] <= 'z' || lower[0] >= '0' && lower[0] <= '9') {
+		return "KEYCODE_" + strings.ToUpper(lower), true
+	}
+	return "", false
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
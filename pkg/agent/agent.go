@@ -0,0 +1,227 @@
+// Package agent carries out tasks on a live desktop: a function-calling
+// model, the planner, is shown the screen and drives it with the tools of
+// package tools until it says the task is done. The elements it describes
+// are found by the dispatcher's vision model, the grounder, which may be a
+// different, cheaper model. The roles talk over a Bus, which can log the
+// conversation, and each role's model calls are counted. Package simulate
+// runs the same kind of agent against saved screenshots instead.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"agentGo/pkg/budget"
+	"agentGo/pkg/frame"
+	"agentGo/pkg/memory"
+	"agentGo/pkg/tools"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Chat is a conversation with a function-calling model, as
+// genai.ChatSession is.
+type Chat interface {
+	SendMessage(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
+}
+
+// Instruction is the system instruction that sets the model up as the
+// agent.
+const Instruction = "You operate a computer through the tools you are given, to carry out the user's task. " +
+	"Each message shows the current screen. Take one step at a time with the tools, and check the screen after each. " +
+	"When the task is done, or cannot be done, stop calling tools and say so in one sentence."
+
+// Configure sets m up as an agent: with the desktop tools and Instruction.
+func Configure(m *genai.GenerativeModel) {
+	m.Tools = []*genai.Tool{tools.GeminiTool()}
+	m.SystemInstruction = genai.NewUserContent(genai.Text(Instruction))
+}
+
+// Result is the outcome of a task.
+type Result struct {
+	Task string `json:"task"`
+	// Calls counts the tool calls the agent made, and Turns its responses.
+	Calls int `json:"calls"`
+	Turns int `json:"turns"`
+	// Answer is what the agent said when it stopped calling tools.
+	Answer string `json:"answer"`
+	// Usage counts the model calls of the planner and the grounder.
+	Usage map[Role]Usage `json:"usage"`
+}
+
+// Agent carries out tasks on the desktop of Dispatcher.
+type Agent struct {
+	// Chat is the model, set up as Configure does.
+	Chat Chat
+	// Dispatcher performs the tool calls; its Vision is the grounder.
+	Dispatcher *tools.Dispatcher
+	// Bus, if set, carries the messages between the roles.
+	Bus *Bus
+	// MaxTurns caps the model's responses. The default is 50.
+	MaxTurns int
+	// Budget, if set, meters the planner's calls and counts the tool calls
+	// as steps, stopping the agent before either goes over. The grounder
+	// is metered by its own client's Meter.
+	Budget *budget.Tracker
+	// Model names the planner's model, for the budget.
+	Model string
+	Logf  func(format string, args ...any)
+}
+
+// ErrTurnLimit is returned when the agent does not finish within MaxTurns.
+var ErrTurnLimit = errors.New("the agent did not finish within the turn limit")
+
+// Run gives the agent task, with the screen, and performs its tool calls
+// until it stops calling them, it runs out of turns, or ctx ends.
+func (a *Agent) Run(ctx context.Context, task string) (*Result, error) {
+	maxTurns := a.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = 50
+	}
+	d := *a.Dispatcher
+	g := &grounder{Locator: d.Vision, bus: a.Bus}
+	if d.Vision != nil {
+		d.Vision = g
+	}
+	var planner Usage
+	res := &Result{Task: task}
+	defer func() {
+		res.Usage = map[Role]Usage{RolePlanner: planner, RoleGrounder: g.usage}
+	}()
+
+	screen, err := a.screen()
+	if err != nil {
+		return nil, err
+	}
+	a.Bus.Send(Message{From: RoleUser, To: RolePlanner, Kind: MsgTask, Text: task})
+	parts := []genai.Part{genai.Text("Task: " + task)}
+	if d.Memory != nil {
+		parts = append(parts, genai.Text(a.remembered(ctx, d.Memory, task)))
+	}
+	parts = append(parts, screen)
+	for res.Turns < maxTurns {
+		if err := a.Budget.Allow(a.Model); err != nil {
+			return res, fmt.Errorf("turn %d: %w", res.Turns+1, err)
+		}
+		resp, err := a.Chat.SendMessage(ctx, a.scrub(parts)...)
+		planner.Requests++
+		var tokens int64
+		if err == nil && resp.UsageMetadata != nil {
+			tokens = int64(resp.UsageMetadata.TotalTokenCount)
+		}
+		a.Budget.Charge(a.Model, tokens)
+		if err != nil {
+			planner.Errors++
+			return res, fmt.Errorf("turn %d: %w", res.Turns+1, err)
+		}
+		planner.Tokens += tokens
+		res.Turns++
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			return res, fmt.Errorf("turn %d: the model gave no answer", res.Turns)
+		}
+		calls := resp.Candidates[0].FunctionCalls()
+		if len(calls) == 0 {
+			res.Answer = text(resp.Candidates[0].Content)
+			a.Bus.Send(Message{From: RolePlanner, To: RoleUser, Kind: MsgAnswer, Text: res.Answer})
+			a.logf("done after %d turns: %s", res.Turns, res.Answer)
+			return res, nil
+		}
+		parts = parts[:0]
+		for _, call := range calls {
+			if err := ctx.Err(); err != nil {
+				return res, err
+			}
+			if err := a.Budget.Step(); err != nil {
+				return res, err
+			}
+			res.Calls++
+			args, _ := json.Marshal(call.Args)
+			a.Bus.Send(Message{From: RolePlanner, To: RoleExecutor, Kind: MsgCall, Text: call.Name + " " + string(d.LogArgs(call.Name, args))})
+			out := d.DispatchGemini(ctx, call)
+			if r, ok := out[0].(genai.FunctionResponse); ok {
+				msg, failed := r.Response["error"]
+				if !failed {
+					msg = r.Response["output"]
+				}
+				a.Bus.Send(Message{From: RoleExecutor, To: RolePlanner, Kind: MsgResult, Text: fmt.Sprint(msg), Error: failed})
+			}
+			parts = append(parts, out...)
+		}
+		if screen, err = a.screen(); err != nil {
+			return res, err
+		}
+		parts = append(parts, genai.Text("The screen now:"), screen)
+	}
+	return res, ErrTurnLimit
+}
+
+// maxRemembered is the most remembered facts given with a task.
+const maxRemembered = 8
+
+// remembered tells the model what earlier runs learned that bears on task,
+// and to remember what this run learns.
+func (a *Agent) remembered(ctx context.Context, m *memory.Store, task string) string {
+	const hint = "Use the remember tool to record what you learn about the apps that would save exploring next time, never credentials."
+	facts, err := m.Recall(ctx, task, maxRemembered)
+	if err != nil {
+		a.logf("failed to recall facts: %v", err)
+	}
+	if len(facts) == 0 {
+		return hint
+	}
+	a.logf("recalled %d facts", len(facts))
+	return "Facts remembered from earlier runs:\n" + tools.FormatFacts(facts) + "\n" + hint
+}
+
+// scrub removes secrets from the text of parts with the Dispatcher's
+// Scrub, which has already scrubbed the results of tool calls.
+func (a *Agent) scrub(parts []genai.Part) []genai.Part {
+	scrub := a.Dispatcher.Scrub
+	if scrub == nil {
+		return parts
+	}
+	out := make([]genai.Part, len(parts))
+	for i, p := range parts {
+		if t, ok := p.(genai.Text); ok {
+			p = genai.Text(scrub(string(t)))
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// screen captures the desktop for the model.
+func (a *Agent) screen() (genai.Part, error) {
+	img, err := a.Dispatcher.Capture()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture screen: %w", err)
+	}
+	data, err := frame.EncodePNG(img)
+	frame.Put(img)
+	if err != nil {
+		return nil, err
+	}
+	return genai.ImageData("png", data), nil
+}
+
+func text(c *genai.Content) string {
+	var b strings.Builder
+	for _, p := range c.Parts {
+		if t, ok := p.(genai.Text); ok {
+			b.WriteString(string(t))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (a *Agent) logf(format string, args ...any) {
+	if a.Logf != nil {
+		a.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Role is a part an agent's models and desktop play.
+type Role string
+
+const (
+	// RoleUser gives the task and hears the answer.
+	RoleUser Role = "user"
+	// RolePlanner is the model that decides what to do next.
+	RolePlanner Role = "planner"
+	// RoleExecutor performs the planner's tool calls on the desktop.
+	RoleExecutor Role = "executor"
+	// RoleGrounder is the model that finds on the screen the elements the
+	// planner describes.
+	RoleGrounder Role = "grounder"
+)
+
+// Message kinds.
+const (
+	MsgTask   = "task"
+	MsgCall   = "call"
+	MsgResult = "result"
+	MsgLocate = "locate"
+	MsgPoint  = "point"
+	MsgAnswer = "answer"
+)
+
+// Message is what one role tells another.
+type Message struct {
+	Time time.Time `json:"time"`
+	From Role      `json:"from"`
+	To   Role      `json:"to"`
+	Kind string    `json:"kind"`
+	Text string    `json:"text,omitempty"`
+	// Error marks a result or point that reports a failure.
+	Error bool `json:"error,omitempty"`
+}
+
+// Bus carries the messages between the roles of a run, to anyone
+// listening and to its log. A nil Bus carries nothing.
+type Bus struct {
+	// Log, if set, gets every message as a JSON line.
+	Log io.Writer
+
+	mu        sync.Mutex
+	listeners []func(Message)
+}
+
+// Listen has fn called with every message from now on, in the order they
+// are sent.
+func (b *Bus) Listen(fn func(Message)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, fn)
+}
+
+// Send stamps m with the time, if it has none, and delivers it.
+func (b *Bus) Send(m Message) {
+	if b == nil {
+		return
+	}
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Log != nil {
+		line, _ := json.Marshal(m)
+		b.Log.Write(append(line, '\n'))
+	}
+	for _, fn := range b.listeners {
+		fn(m)
+	}
+}
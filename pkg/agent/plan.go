@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Subtask statuses.
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Subtask is a step of a Plan.
+type Subtask struct {
+	Name string `json:"name"`
+	// Goal is what the agent is asked to do.
+	Goal string `json:"goal"`
+	// Success is how the screen shows the subtask is done, which is
+	// checked after the agent says it is.
+	Success string `json:"success"`
+	Status  string `json:"status"`
+	// Answer is what the agent said, and Error why the subtask failed.
+	Answer string `json:"answer,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Plan is a task broken into subtasks that are carried out in order. It is
+// saved after every change, so a run can be followed, inspected afterwards
+// and resumed.
+type Plan struct {
+	Task     string    `json:"task"`
+	Subtasks []Subtask `json:"subtasks"`
+	// Replans counts the times the remaining subtasks were planned again
+	// after one failed.
+	Replans int `json:"replans"`
+	// Usage totals the model calls of the subtasks' agents.
+	Usage   map[Role]Usage `json:"usage,omitempty"`
+	Updated time.Time      `json:"updated"`
+}
+
+// LoadPlan reads a plan saved by Planner.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Save writes p to path, through a temporary file so that a crash never
+// leaves half a plan.
+func (p *Plan) Save(path string) error {
+	p.Updated = time.Now()
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// next returns the index of the first subtask not done, or -1.
+func (p *Plan) next() int {
+	for i, s := range p.Subtasks {
+		if s.Status != StatusDone {
+			return i
+		}
+	}
+	return -1
+}
+
+// Generator answers a prompt about a screenshot, as vision.Client does.
+type Generator interface {
+	Generate(ctx context.Context, prompt string, img image.Image) (string, error)
+}
+
+// Planner breaks a task into subtasks with success criteria, has an agent
+// carry out each in turn and checks it succeeded, and plans the rest of
+// the task again when one fails.
+type Planner struct {
+	// Model plans and checks the subtasks.
+	Model Generator
+	// NewAgent returns an agent, with a fresh conversation, for each
+	// subtask.
+	NewAgent func() *Agent
+	// Capture shows the model the screen.
+	Capture func() (image.Image, error)
+	// Path, if set, is where the plan is saved. A plan there for the same
+	// task is resumed from its first subtask not done.
+	Path string
+	// MaxReplans caps how often the plan is made again. The default is 3.
+	MaxReplans int
+	// OnDone, if set, is called after each subtask succeeds, e.g. to
+	// checkpoint the run.
+	OnDone func(s Subtask)
+	Logf   func(format string, args ...any)
+}
+
+const planPrompt = `You plan work on a computer for an agent that operates it through mouse, keyboard and window tools. The screenshot shows the screen now.
+Task: %s
+%sBreak what remains of the task into a short sequence of subtasks, each small enough to do in a few actions. Give each a short name, the goal the agent is asked to achieve, and a success criterion: what the screen shows once it is done.
+Answer with only a JSON array: [{"name": "...", "goal": "...", "success": "..."}].`
+
+const checkPrompt = `An agent operating this computer was asked to: %s
+It should now be true that: %s
+Does the screenshot show that? Answer with only a JSON object: {"met": true or false, "reason": a short explanation}.`
+
+// Run carries out task and returns the plan as it ended.
+func (p *Planner) Run(ctx context.Context, task string) (*Plan, error) {
+	maxReplans := p.MaxReplans
+	if maxReplans <= 0 {
+		maxReplans = 3
+	}
+	plan := &Plan{Task: task}
+	if p.Path != "" {
+		if saved, err := LoadPlan(p.Path); err == nil && saved.Task == task && saved.next() >= 0 {
+			p.logf("resuming the plan in %s", p.Path)
+			plan = saved
+		}
+	}
+	if len(plan.Subtasks) == 0 {
+		if err := p.plan(ctx, plan, ""); err != nil {
+			return plan, err
+		}
+	}
+	for {
+		i := plan.next()
+		if i < 0 {
+			return plan, nil
+		}
+		s := &plan.Subtasks[i]
+		p.logf("subtask %d of %d, %s: %s", i+1, len(plan.Subtasks), s.Name, s.Goal)
+		err := p.runSubtask(ctx, plan, s)
+		if err == nil {
+			s.Status, s.Error = StatusDone, ""
+			if err := p.save(plan); err != nil {
+				return plan, err
+			}
+			if p.OnDone != nil {
+				p.OnDone(*s)
+			}
+			continue
+		}
+		s.Status, s.Error = StatusFailed, err.Error()
+		p.save(plan)
+		if ctx.Err() != nil {
+			return plan, ctx.Err()
+		}
+		if plan.Replans >= maxReplans {
+			return plan, fmt.Errorf("subtask %q failed after %d replans: %w", s.Name, plan.Replans, err)
+		}
+		p.logf("subtask %s failed: %v; planning again", s.Name, err)
+		plan.Replans++
+		failed := fmt.Sprintf("The subtask %q (%s) failed: %s\n", s.Name, s.Goal, s.Error)
+		if err := p.plan(ctx, plan, failed); err != nil {
+			return plan, err
+		}
+	}
+}
+
+// plan replaces the subtasks not done with new ones, given what has been
+// done and, after a failure, what went wrong.
+func (p *Planner) plan(ctx context.Context, plan *Plan, failed string) error {
+	var done []Subtask
+	var b strings.Builder
+	for _, s := range plan.Subtasks {
+		if s.Status == StatusDone {
+			done = append(done, s)
+			if b.Len() == 0 {
+				b.WriteString("Already done:\n")
+			}
+			fmt.Fprintf(&b, "- %s: %s\n", s.Name, s.Goal)
+		}
+	}
+	b.WriteString(failed)
+	img, err := p.screen()
+	if err != nil {
+		return err
+	}
+	text, err := p.Model.Generate(ctx, fmt.Sprintf(planPrompt, plan.Task, b.String()), img)
+	if err != nil {
+		return fmt.Errorf("failed to plan: %w", err)
+	}
+	var next []Subtask
+	if err := json.Unmarshal([]byte(extract(text, "[", "]")), &next); err != nil {
+		return fmt.Errorf("failed to parse plan %q: %w", text, err)
+	}
+	if len(next) == 0 {
+		return errors.New("the plan has no subtasks")
+	}
+	for i := range next {
+		next[i].Status = StatusPending
+		if next[i].Name == "" {
+			next[i].Name = fmt.Sprintf("step %d", len(done)+i+1)
+		}
+	}
+	plan.Subtasks = append(done, next...)
+	for i, s := range next {
+		p.logf("  %d. %s: %s (done when %s)", len(done)+i+1, s.Name, s.Goal, s.Success)
+	}
+	return p.save(plan)
+}
+
+// runSubtask has an agent carry out s and checks its success criterion.
+func (p *Planner) runSubtask(ctx context.Context, plan *Plan, s *Subtask) error {
+	goal := s.Goal
+	if s.Success != "" {
+		goal = strings.TrimSuffix(goal, ".") + ". It is done when " + s.Success
+	}
+	res, err := p.NewAgent().Run(ctx, goal)
+	if res != nil {
+		s.Answer = res.Answer
+		if plan.Usage == nil {
+			plan.Usage = make(map[Role]Usage)
+		}
+		for role, u := range res.Usage {
+			total := plan.Usage[role]
+			plan.Usage[role] = Usage{Requests: total.Requests + u.Requests, Tokens: total.Tokens + u.Tokens, Errors: total.Errors + u.Errors}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if s.Success == "" {
+		return nil
+	}
+	img, err := p.screen()
+	if err != nil {
+		return err
+	}
+	text, err := p.Model.Generate(ctx, fmt.Sprintf(checkPrompt, s.Goal, s.Success), img)
+	if err != nil {
+		return fmt.Errorf("failed to check the subtask: %w", err)
+	}
+	var check struct {
+		Met    bool   `json:"met"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(extract(text, "{", "}")), &check); err != nil {
+		return fmt.Errorf("failed to parse check %q: %w", text, err)
+	}
+	if !check.Met {
+		return fmt.Errorf("not done: %s", check.Reason)
+	}
+	return nil
+}
+
+func (p *Planner) screen() (image.Image, error) {
+	img, err := p.Capture()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture screen: %w", err)
+	}
+	return img, nil
+}
+
+func (p *Planner) save(plan *Plan) error {
+	if p.Path == "" {
+		return nil
+	}
+	if err := plan.Save(p.Path); err != nil {
+		return fmt.Errorf("failed to save the plan: %w", err)
+	}
+	return nil
+}
+
+// extract cuts the JSON value between the first open and the last close
+// out of a model's answer, which may wrap it in prose or a code block.
+func extract(text, open, close string) string {
+	if start, end := strings.Index(text, open), strings.LastIndex(text, close); start >= 0 && end > start {
+		return text[start : end+1]
+	}
+	return text
+}
+
+func (p *Planner) logf(format string, args ...any) {
+	if p.Logf != nil {
+		p.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync"
+
+	"agentGo/pkg/tools"
+	"agentGo/pkg/vision"
+)
+
+// Usage counts the model calls a role made.
+type Usage struct {
+	Requests int   `json:"requests"`
+	Tokens   int64 `json:"tokens"`
+	Errors   int   `json:"errors"`
+}
+
+// Cost is what the usage costs at price dollars per million tokens.
+func (u Usage) Cost(price float64) float64 {
+	return float64(u.Tokens) / 1e6 * price
+}
+
+// keyUsage is implemented by locators that count their tokens, as
+// vision.Client does.
+type keyUsage interface {
+	Usage() []vision.KeyUsage
+}
+
+func tokens(l tools.Locator) int64 {
+	var n int64
+	if k, ok := l.(keyUsage); ok {
+		for _, u := range k.Usage() {
+			n += u.Tokens
+		}
+	}
+	return n
+}
+
+// grounder passes the executor's requests to find elements to the
+// grounding model, over the bus, and counts them.
+type grounder struct {
+	tools.Locator
+	bus *Bus
+
+	mu    sync.Mutex
+	usage Usage
+}
+
+func (g *grounder) Locate(ctx context.Context, img image.Image, target string) (vision.Point, error) {
+	g.bus.Send(Message{From: RoleExecutor, To: RoleGrounder, Kind: MsgLocate, Text: target})
+	before := tokens(g.Locator)
+	p, err := g.Locator.Locate(ctx, img, target)
+	used := tokens(g.Locator) - before
+	g.mu.Lock()
+	g.usage.Requests++
+	g.usage.Tokens += used
+	if err != nil {
+		g.usage.Errors++
+	}
+	g.mu.Unlock()
+	if err != nil {
+		g.bus.Send(Message{From: RoleGrounder, To: RoleExecutor, Kind: MsgPoint, Text: err.Error(), Error: true})
+		return p, err
+	}
+	g.bus.Send(Message{From: RoleGrounder, To: RoleExecutor, Kind: MsgPoint, Text: fmt.Sprintf("%s at (%.0f, %.0f)", target, p.X, p.Y)})
+	return p, nil
+}
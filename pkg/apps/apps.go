@@ -0,0 +1,154 @@
+// Package apps launches applications on the local desktop and finds,
+// activates and closes their windows: with wmctrl on Linux (X11, or
+// XWayland windows under Wayland), System Events on macOS, which needs the
+// Accessibility permission, and the window manager's API on Windows.
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned on platforms without window management.
+var ErrUnsupported = errors.New("window management is not supported on this platform")
+
+// Window is a top-level window of the desktop.
+type Window struct {
+	// ID identifies the window to the platform, e.g. an X11 window id.
+	ID    string
+	PID   int
+	Title string
+	// App is what the window belongs to: the path of its process's
+	// executable, or on macOS its application's bundle id.
+	App string
+	// Name is the process's name, e.g. "firefox" or "Safari".
+	Name string
+}
+
+// Query picks windows by the application they belong to, their title, or
+// both.
+type Query struct {
+	// App is an executable's path or name, or a bundle id; empty matches
+	// any application.
+	App string
+	// Title, if set, must match the window's title.
+	Title *regexp.Regexp
+}
+
+// String describes the query for messages.
+func (q Query) String() string {
+	switch {
+	case q.App != "" && q.Title != nil:
+		return fmt.Sprintf("%s window titled /%s/", q.App, q.Title)
+	case q.Title != nil:
+		return fmt.Sprintf("window titled /%s/", q.Title)
+	default:
+		return q.App + " window"
+	}
+}
+
+// Match reports whether w is one of the windows q picks. An application is
+// matched by its full path or bundle id, or by name, ignoring case and any
+// .exe or .app extension, so "notepad" matches C:\Windows\notepad.exe.
+func (q Query) Match(w Window) bool {
+	if q.Title != nil && !q.Title.MatchString(w.Title) {
+		return false
+	}
+	if q.App == "" {
+		return true
+	}
+	if strings.EqualFold(q.App, w.App) {
+		return true
+	}
+	name := appName(q.App)
+	return strings.EqualFold(name, appName(w.App)) || strings.EqualFold(name, appName(w.Name))
+}
+
+// appName is the base name of an application's path without its extension.
+func appName(app string) string {
+	app = filepath.Base(strings.ReplaceAll(app, `\`, "/"))
+	for _, ext := range []string{".exe", ".app"} {
+		if len(app) > len(ext) && strings.EqualFold(app[len(app)-len(ext):], ext) {
+			return app[:len(app)-len(ext)]
+		}
+	}
+	return app
+}
+
+// Find returns the windows q picks from windows.
+func Find(windows []Window, q Query) []Window {
+	var found []Window
+	for _, w := range windows {
+		if q.Match(w) {
+			found = append(found, w)
+		}
+	}
+	return found
+}
+
+// pollInterval is the pause between looks for a window.
+const pollInterval = 250 * time.Millisecond
+
+// Wait waits until list, such as List, returns a window q picks and
+// returns the first, or returns ctx's error.
+func Wait(ctx context.Context, list func() ([]Window, error), q Query) (Window, error) {
+	for {
+		windows, err := list()
+		if err != nil {
+			return Window{}, err
+		}
+		if found := Find(windows, q); len(found) > 0 {
+			return found[0], nil
+		}
+		select {
+		case <-ctx.Done():
+			return Window{}, fmt.Errorf("no %s appeared: %w", q, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// List returns the desktop's top-level windows, frontmost first where the
+// platform says which is in front.
+func List() ([]Window, error) {
+	return list()
+}
+
+// Activate brings w to the front and gives it the keyboard focus,
+// restoring it first if it is minimized.
+func Activate(w Window) error {
+	return activate(w)
+}
+
+// Close asks w to close, as its close button does; the application may
+// ask to save changes first.
+func Close(w Window) error {
+	return closeWindow(w)
+}
+
+// Launch starts app with args and returns without waiting for it. App is
+// an executable's path, or a name looked up in PATH; on macOS it is also
+// an application bundle's path, a bundle id such as com.apple.TextEdit, or
+// a bare application name such as TextEdit.
+func Launch(app string, args ...string) error {
+	if app == "" {
+		return errors.New("no application to launch")
+	}
+	return launch(app, args)
+}
+
+// start runs a command in the background, reaping it when it exits.
+func start(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to launch %s: %w", name, err)
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,215 @@
+package apps
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// listScript prints a line per window of the foreground applications: the
+// process id, bundle id, process name, the window's index in its process
+// and its title, separated by tabs. The frontmost application comes first.
+const listScript = `set front to ""
+set out to ""
+tell application "System Events"
+	set procs to every process whose background only is false
+	repeat with p in procs
+		set bid to ""
+		try
+			set bid to bundle identifier of p
+			if bid is missing value then set bid to ""
+		end try
+		set i to 0
+		repeat with w in (every window of p)
+			set i to i + 1
+			set t to ""
+			try
+				set t to name of w
+				if t is missing value then set t to ""
+			end try
+			set entry to ((unix id of p) as text) & tab & bid & tab & (name of p) & tab & i & tab & t
+			if frontmost of p then
+				set front to front & entry & linefeed
+			else
+				set out to out & entry & linefeed
+			end if
+		end repeat
+	end repeat
+end tell
+return front & out`
+
+func osascript(script string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("osascript", append([]string{"-e", script}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("osascript: %w: %s (does the terminal have the Accessibility permission?)", err, msg)
+		}
+		return nil, fmt.Errorf("osascript: %w", err)
+	}
+	return out, nil
+}
+
+// list has System Events list the windows. A window's ID is its process
+// id and its index among the process's windows.
+func list() ([]Window, error) {
+	out, err := osascript(listScript)
+	if err != nil {
+		return nil, err
+	}
+	var windows []Window
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		f := strings.SplitN(line, "\t", 5)
+		if len(f) < 5 {
+			continue
+		}
+		pid, err := strconv.Atoi(f[0])
+		if err != nil {
+			continue
+		}
+		windows = append(windows, Window{ID: f[0] + ":" + f[3], PID: pid, App: f[1], Name: f[2], Title: f[4]})
+	}
+	return windows, nil
+}
+
+// windowRef splits an ID from list into its process id and window index.
+func windowRef(w Window) (string, string, error) {
+	pid, index, ok := strings.Cut(w.ID, ":")
+	if !ok {
+		return "", "", fmt.Errorf("invalid window id %q", w.ID)
+	}
+	return pid, index, nil
+}
+
+const activateScript = `on run argv
+	tell application "System Events"
+		set p to first process whose unix id is (item 1 of argv as integer)
+		set w to window (item 2 of argv as integer) of p
+		try
+			if value of attribute "AXMinimized" of w then set value of attribute "AXMinimized" of w to false
+		end try
+		set frontmost of p to true
+		perform action "AXRaise" of w
+	end tell
+end run`
+
+func activate(w Window) error {
+	pid, index, err := windowRef(w)
+	if err != nil {
+		return err
+	}
+	_, err = osascript(activateScript, pid, index)
+	return err
+}
+
+const closeScript = `on run argv
+	tell application "System Events"
+		set p to first process whose unix id is (item 1 of argv as integer)
+		set w to window (item 2 of argv as integer) of p
+		click (first button of w whose subrole is "AXCloseButton")
+	end tell
+end run`
+
+func closeWindow(w Window) error {
+	pid, index, err := windowRef(w)
+	if err != nil {
+		return err
+	}
+	_, err = osascript(closeScript, pid, index)
+	return err
+}
+
+// launch opens bundle ids and application bundles with open, and runs
+// anything else, such as a command-line program, directly.
+func launch(app string, args []string) error {
+	var openArgs []string
+	switch {
+	case strings.HasSuffix(app, ".app") || !strings.Contains(app, "/") && !strings.Contains(app, "."):
+		openArgs = []string{"-a", app}
+	case !strings.Contains(app, "/"):
+		openArgs = []string{"-b", app}
+	default:
+		return start(app, args...)
+	}
+	if len(args) > 0 {
+		openArgs = append(append(openArgs, "--args"), args...)
+	}
+	out, err := exec.Command("open", openArgs...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w: %s", app, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+const placeScript = `on run argv
+	tell application "System Events"
+		set p to first process whose unix id is (item 1 of argv as integer)
+		set w to window (item 2 of argv as integer) of p
+		try
+			if value of attribute "AXMinimized" of w then set value of attribute "AXMinimized" of w to false
+		end try
+		try
+			if value of attribute "AXFullScreen" of w then set value of attribute "AXFullScreen" of w to false
+		end try
+		if item 3 of argv is "move" or item 3 of argv is "both" then
+			set position of w to {item 4 of argv as integer, item 5 of argv as integer}
+		end if
+		if item 3 of argv is "resize" or item 3 of argv is "both" then
+			set size of w to {item 6 of argv as integer, item 7 of argv as integer}
+		end if
+	end tell
+end run`
+
+func place(w Window, g Geometry) error {
+	pid, index, err := windowRef(w)
+	if err != nil {
+		return err
+	}
+	mode := "both"
+	if !g.Resize {
+		mode = "move"
+	} else if !g.Move {
+		mode = "resize"
+	}
+	_, err = osascript(placeScript, pid, index, mode,
+		strconv.Itoa(g.X), strconv.Itoa(g.Y), strconv.Itoa(g.Width), strconv.Itoa(g.Height))
+	return err
+}
+
+// maximizeScript fills the visible frame of the main screen, the area
+// between the menu bar and the dock, which AppKit measures from the
+// bottom left corner of the screen.
+const maximizeScript = `use framework "AppKit"
+use scripting additions
+on run argv
+	set screen to current application's NSScreen's mainScreen()
+	set full to screen's frame() as list
+	set vis to screen's visibleFrame() as list
+	set fullHeight to item 2 of item 2 of full
+	set vx to item 1 of item 1 of vis
+	set vy to item 2 of item 1 of vis
+	set vw to item 1 of item 2 of vis
+	set vh to item 2 of item 2 of vis
+	tell application "System Events"
+		set p to first process whose unix id is (item 1 of argv as integer)
+		set w to window (item 2 of argv as integer) of p
+		try
+			if value of attribute "AXMinimized" of w then set value of attribute "AXMinimized" of w to false
+		end try
+		set position of w to {vx as integer, (fullHeight - vy - vh) as integer}
+		set size of w to {vw as integer, vh as integer}
+	end tell
+end run`
+
+func maximize(w Window) error {
+	pid, index, err := windowRef(w)
+	if err != nil {
+		return err
+	}
+	_, err = osascript(maximizeScript, pid, index)
+	return err
+}
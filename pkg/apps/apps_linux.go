@@ -0,0 +1,94 @@
+package apps
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// wmctrlLine parses a line of wmctrl -l -p: the window id, its desktop,
+// the process id, the host and the title.
+var wmctrlLine = regexp.MustCompile(`^(0x[0-9a-fA-F]+)\s+-?\d+\s+(\d+)\s+\S+\s?(.*)$`)
+
+func wmctrl(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("wmctrl", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("wmctrl %s: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return nil, fmt.Errorf("wmctrl %s (is wmctrl installed?): %w", strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+// list asks wmctrl for the windows, which it lists oldest first; the
+// result is reversed so that recent windows come first.
+func list() ([]Window, error) {
+	out, err := wmctrl("-l", "-p")
+	if err != nil {
+		return nil, err
+	}
+	var windows []Window
+	for _, line := range strings.Split(string(out), "\n") {
+		m := wmctrlLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		w := Window{ID: m[1], Title: m[3]}
+		w.PID, _ = strconv.Atoi(m[2])
+		if w.PID > 0 {
+			w.App, _ = os.Readlink(fmt.Sprintf("/proc/%d/exe", w.PID))
+			if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", w.PID)); err == nil {
+				w.Name = strings.TrimSpace(string(comm))
+			} else if w.App != "" {
+				w.Name = filepath.Base(w.App)
+			}
+		}
+		windows = append([]Window{w}, windows...)
+	}
+	return windows, nil
+}
+
+func activate(w Window) error {
+	_, err := wmctrl("-i", "-a", w.ID)
+	return err
+}
+
+func closeWindow(w Window) error {
+	_, err := wmctrl("-i", "-c", w.ID)
+	return err
+}
+
+func launch(app string, args []string) error {
+	return start(app, args...)
+}
+
+// place clears the maximized state, which would keep the window manager
+// from moving the window, and sets the geometry, -1 keeping a value.
+func place(w Window, g Geometry) error {
+	if _, err := wmctrl("-i", "-r", w.ID, "-b", "remove,maximized_vert,maximized_horz"); err != nil {
+		return err
+	}
+	x, y, width, height := -1, -1, -1, -1
+	if g.Move {
+		x, y = g.X, g.Y
+	}
+	if g.Resize {
+		width, height = g.Width, g.Height
+	}
+	_, err := wmctrl("-i", "-r", w.ID, "-e", fmt.Sprintf("0,%d,%d,%d,%d", x, y, width, height))
+	return err
+}
+
+func maximize(w Window) error {
+	_, err := wmctrl("-i", "-r", w.ID, "-b", "add,maximized_vert,maximized_horz")
+	return err
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !windows
+
+package apps
+
+func list() ([]Window, error) { return nil, ErrUnsupported }
+
+func activate(w Window) error { return ErrUnsupported }
+
+func closeWindow(w Window) error { return ErrUnsupported }
+
+func launch(app string, args []string) error { return start(app, args...) }
+
+func place(w Window, g Geometry) error { return ErrUnsupported }
+
+func maximize(w Window) error { return ErrUnsupported }
@@ -0,0 +1,204 @@
+package apps
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32   = syscall.NewLazyDLL("user32.dll")
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	procEnumWindows              = user32.NewProc("EnumWindows")
+	procGetWindowTextW           = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW     = user32.NewProc("GetWindowTextLengthW")
+	procGetWindowThreadProcessID = user32.NewProc("GetWindowThreadProcessId")
+	procIsWindowVisible          = user32.NewProc("IsWindowVisible")
+	procGetWindow                = user32.NewProc("GetWindow")
+	procIsIconic                 = user32.NewProc("IsIconic")
+	procShowWindow               = user32.NewProc("ShowWindow")
+	procSetForegroundWindow      = user32.NewProc("SetForegroundWindow")
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
+	procBringWindowToTop         = user32.NewProc("BringWindowToTop")
+	procAttachThreadInput        = user32.NewProc("AttachThreadInput")
+	procPostMessageW             = user32.NewProc("PostMessageW")
+	procIsZoomed                 = user32.NewProc("IsZoomed")
+	procSetWindowPos             = user32.NewProc("SetWindowPos")
+
+	procGetCurrentThreadID         = kernel32.NewProc("GetCurrentThreadId")
+	procQueryFullProcessImageNameW = kernel32.NewProc("QueryFullProcessImageNameW")
+)
+
+const (
+	gwOwner                        = 4
+	swMaximize                     = 3
+	swRestore                      = 9
+	swpNoSize                      = 0x0001
+	swpNoMove                      = 0x0002
+	swpNoZOrder                    = 0x0004
+	swpNoActivate                  = 0x0010
+	wmClose                        = 0x0010
+	processQueryLimitedInformation = 0x1000
+)
+
+// The EnumWindows callback is made once, since Go can only make a limited
+// number, and collects into enumFound while enumMu is held.
+var (
+	enumMu       sync.Mutex
+	enumFound    []Window
+	enumCallback = syscall.NewCallback(enumWindow)
+)
+
+// list enumerates the visible, unowned top-level windows with a title,
+// which EnumWindows visits from the top of the z-order down.
+func list() ([]Window, error) {
+	enumMu.Lock()
+	defer enumMu.Unlock()
+	enumFound = nil
+	if ok, _, err := procEnumWindows.Call(enumCallback, 0); ok == 0 {
+		return nil, fmt.Errorf("EnumWindows: %w", err)
+	}
+	windows := enumFound
+	enumFound = nil
+	return windows, nil
+}
+
+func enumWindow(hwnd, _ uintptr) uintptr {
+	if visible, _, _ := procIsWindowVisible.Call(hwnd); visible == 0 {
+		return 1
+	}
+	if owner, _, _ := procGetWindow.Call(hwnd, gwOwner); owner != 0 {
+		return 1
+	}
+	title := windowText(hwnd)
+	if title == "" {
+		return 1
+	}
+	var pid uint32
+	procGetWindowThreadProcessID.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+	w := Window{ID: strconv.FormatUint(uint64(hwnd), 10), PID: int(pid), Title: title}
+	if w.App = imagePath(pid); w.App != "" {
+		w.Name = strings.TrimSuffix(filepath.Base(w.App), filepath.Ext(w.App))
+	}
+	enumFound = append(enumFound, w)
+	return 1
+}
+
+func windowText(hwnd uintptr) string {
+	n, _, _ := procGetWindowTextLengthW.Call(hwnd)
+	if n == 0 {
+		return ""
+	}
+	buf := make([]uint16, n+1)
+	procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return syscall.UTF16ToString(buf)
+}
+
+// imagePath returns the path of process pid's executable, or "" if the
+// process cannot be opened, as elevated processes cannot by unelevated
+// ones.
+func imagePath(pid uint32) string {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer syscall.CloseHandle(h)
+	buf := make([]uint16, syscall.MAX_PATH)
+	n := uint32(len(buf))
+	if ok, _, _ := procQueryFullProcessImageNameW.Call(uintptr(h), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&n))); ok == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf[:n])
+}
+
+func handle(w Window) (uintptr, error) {
+	h, err := strconv.ParseUint(w.ID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window id %q", w.ID)
+	}
+	return uintptr(h), nil
+}
+
+// activate restores and raises the window. Windows lets only the
+// foreground process move the focus, so the call briefly shares the
+// foreground window's input state to be allowed to.
+func activate(w Window) error {
+	hwnd, err := handle(w)
+	if err != nil {
+		return err
+	}
+	if iconic, _, _ := procIsIconic.Call(hwnd); iconic != 0 {
+		procShowWindow.Call(hwnd, swRestore)
+	}
+	if ok, _, _ := procSetForegroundWindow.Call(hwnd); ok != 0 {
+		return nil
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	fg, _, _ := procGetForegroundWindow.Call()
+	fgThread, _, _ := procGetWindowThreadProcessID.Call(fg, 0)
+	self, _, _ := procGetCurrentThreadID.Call()
+	if fgThread != 0 && fgThread != self {
+		procAttachThreadInput.Call(self, fgThread, 1)
+		defer procAttachThreadInput.Call(self, fgThread, 0)
+	}
+	procBringWindowToTop.Call(hwnd)
+	if ok, _, err := procSetForegroundWindow.Call(hwnd); ok == 0 {
+		return fmt.Errorf("failed to bring %q to the front: %w", w.Title, err)
+	}
+	return nil
+}
+
+func closeWindow(w Window) error {
+	hwnd, err := handle(w)
+	if err != nil {
+		return err
+	}
+	if ok, _, err := procPostMessageW.Call(hwnd, wmClose, 0, 0); ok == 0 {
+		return fmt.Errorf("failed to close %q: %w", w.Title, err)
+	}
+	return nil
+}
+
+func launch(app string, args []string) error {
+	return start(app, args...)
+}
+
+func place(w Window, g Geometry) error {
+	hwnd, err := handle(w)
+	if err != nil {
+		return err
+	}
+	iconic, _, _ := procIsIconic.Call(hwnd)
+	zoomed, _, _ := procIsZoomed.Call(hwnd)
+	if iconic != 0 || zoomed != 0 {
+		procShowWindow.Call(hwnd, swRestore)
+	}
+	flags := uintptr(swpNoZOrder | swpNoActivate)
+	if !g.Move {
+		flags |= swpNoMove
+	}
+	if !g.Resize {
+		flags |= swpNoSize
+	}
+	ok, _, err := procSetWindowPos.Call(hwnd, 0, uintptr(g.X), uintptr(g.Y), uintptr(g.Width), uintptr(g.Height), flags)
+	if ok == 0 {
+		return fmt.Errorf("failed to place %q: %w", w.Title, err)
+	}
+	return nil
+}
+
+func maximize(w Window) error {
+	hwnd, err := handle(w)
+	if err != nil {
+		return err
+	}
+	procShowWindow.Call(hwnd, swMaximize)
+	return nil
+}
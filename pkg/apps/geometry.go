@@ -0,0 +1,72 @@
+package apps
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Geometry is where Place puts a window and how big it makes it, in the
+// desktop's units: pixels, or points on macOS. Positions are those of the
+// window's top left corner, from the top left of the primary display.
+type Geometry struct {
+	X, Y          int
+	Width, Height int
+	// Move and Resize say which of the position and size to set.
+	Move, Resize bool
+}
+
+var geometryPattern = regexp.MustCompile(`^(?:(\d+)x(\d+))?(?:([+-]\d+)([+-]\d+))?$`)
+
+// ParseGeometry parses an X11-style geometry: "1280x800+0+0" sets size
+// and position, "1280x800" only the size and "+100+50" only the position.
+// Unlike X11, a negative offset is a position left of or above the primary
+// display, not one from its right or bottom edge.
+func ParseGeometry(s string) (Geometry, error) {
+	m := geometryPattern.FindStringSubmatch(s)
+	if m == nil || s == "" {
+		return Geometry{}, fmt.Errorf("invalid geometry %q (want WIDTHxHEIGHT+X+Y, WIDTHxHEIGHT or +X+Y)", s)
+	}
+	var g Geometry
+	if m[1] != "" {
+		g.Width, _ = strconv.Atoi(m[1])
+		g.Height, _ = strconv.Atoi(m[2])
+		if g.Width == 0 || g.Height == 0 {
+			return Geometry{}, fmt.Errorf("invalid geometry %q: the size must not be zero", s)
+		}
+		g.Resize = true
+	}
+	if m[3] != "" {
+		g.X, _ = strconv.Atoi(m[3])
+		g.Y, _ = strconv.Atoi(m[4])
+		g.Move = true
+	}
+	return g, nil
+}
+
+// String formats g as ParseGeometry reads it.
+func (g Geometry) String() string {
+	var s string
+	if g.Resize {
+		s = fmt.Sprintf("%dx%d", g.Width, g.Height)
+	}
+	if g.Move {
+		s += fmt.Sprintf("%+d%+d", g.X, g.Y)
+	}
+	return s
+}
+
+// Place moves and resizes w as g says, restoring it first if it is
+// maximized or minimized.
+func Place(w Window, g Geometry) error {
+	if !g.Move && !g.Resize {
+		return nil
+	}
+	return place(w, g)
+}
+
+// Maximize maximizes w to fill its display, less any taskbar, dock or
+// menu bar.
+func Maximize(w Window) error {
+	return maximize(w)
+}
@@ -0,0 +1,378 @@
+// Package audit exports a record of what automated runs do on a desktop,
+// one event per step or tool call, to the places security teams watch:
+// a file, syslog or a webhook, as JSON or in the Common Event Format that
+// SIEMs read. Events are sent as they happen, in the background, so that a
+// slow collector does not slow the run.
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of events.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	// OutcomeRefused is the outcome of actions the safety checks or
+	// read-only mode refused.
+	OutcomeRefused = "refused"
+)
+
+// Event is something a run did.
+type Event struct {
+	Time time.Time `json:"time"`
+	Host string    `json:"host"`
+	// User is the account agentgo runs as.
+	User string `json:"user"`
+	// Source is what drove the action: the command, e.g. play, agent or
+	// mcp.
+	Source string `json:"source"`
+	// Run names the script, recording or task.
+	Run string `json:"run,omitempty"`
+	// Action is the step's action or the tool called, e.g. click.
+	Action string `json:"action"`
+	// Target is what the action was aimed at, if it says.
+	Target string `json:"target,omitempty"`
+	// Detail describes the action further, with secrets masked.
+	Detail  string `json:"detail,omitempty"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Formats of events.
+const (
+	FormatJSON = "json"
+	FormatCEF  = "cef"
+)
+
+// Marshal returns e as a line in format, without the newline.
+func (e Event) Marshal(format string) []byte {
+	if format == FormatCEF {
+		return e.cef()
+	}
+	data, _ := json.Marshal(e)
+	return data
+}
+
+// cef formats e in ArcSight's Common Event Format.
+func (e Event) cef() []byte {
+	severity := 3
+	switch e.Outcome {
+	case OutcomeFailure:
+		severity = 5
+	case OutcomeRefused:
+		severity = 7
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|agentGo|agentgo|1.0|%s|%s|%d|", cefHeader(e.Action), cefHeader(e.Source+" "+e.Action), severity)
+	msg := e.Detail
+	if e.Error != "" {
+		msg = strings.TrimPrefix(msg+": "+e.Error, ": ")
+	}
+	// Custom strings (csN) come with a label naming them.
+	ext := []struct{ key, label, value string }{
+		{"rt", "", strconv.FormatInt(e.Time.UnixMilli(), 10)},
+		{"dhost", "", e.Host},
+		{"suser", "", e.User},
+		{"act", "", e.Action},
+		{"outcome", "", e.Outcome},
+		{"cs1", "source", e.Source},
+		{"cs2", "run", e.Run},
+		{"cs3", "target", e.Target},
+		{"msg", "", msg},
+	}
+	sep := ""
+	for _, kv := range ext {
+		if kv.value == "" {
+			continue
+		}
+		if kv.label != "" {
+			fmt.Fprintf(&b, "%s%sLabel=%s", sep, kv.key, kv.label)
+			sep = " "
+		}
+		fmt.Fprintf(&b, "%s%s=%s", sep, kv.key, cefValue(kv.value))
+		sep = " "
+	}
+	return []byte(b.String())
+}
+
+var (
+	cefHeader = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ").Replace
+	cefValue  = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`).Replace
+)
+
+// sink is where events go.
+type sink interface {
+	send(e Event, line []byte) error
+	close() error
+}
+
+// queueSize is how many events may wait to be sent before new ones are
+// dropped.
+const queueSize = 1024
+
+// Log sends events to its sinks. A nil Log records nothing, so callers need
+// not check whether auditing is on.
+type Log struct {
+	format string
+	sinks  []sink
+	host   string
+	user   string
+	logf   func(format string, args ...any)
+
+	queue   chan Event
+	done    chan struct{}
+	mu      sync.Mutex
+	closed  bool
+	dropped int
+}
+
+// Open returns a log sending events in format to each destination:
+//
+//	file:PATH or PATH         appended to a file
+//	syslog://HOST[:PORT]      RFC 5424 syslog over UDP (port 514)
+//	syslog+tcp://HOST[:PORT]  RFC 5424 syslog over TCP (port 601)
+//	http://... or https://... POSTed to a webhook, one event per request
+//
+// Errors sending events go to logf, which must not be nil.
+func Open(destinations []string, format string, logf func(format string, args ...any)) (*Log, error) {
+	switch format {
+	case "":
+		format = FormatJSON
+	case FormatJSON, FormatCEF:
+	default:
+		return nil, fmt.Errorf("unknown audit format %q (want json or cef)", format)
+	}
+	l := &Log{format: format, logf: logf, queue: make(chan Event, queueSize), done: make(chan struct{})}
+	l.host, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		l.user = u.Username
+	}
+	for _, dest := range destinations {
+		if dest = strings.TrimSpace(dest); dest == "" {
+			continue
+		}
+		s, err := openSink(dest)
+		if err != nil {
+			for _, s := range l.sinks {
+				s.close()
+			}
+			return nil, fmt.Errorf("audit destination %s: %w", dest, err)
+		}
+		l.sinks = append(l.sinks, s)
+	}
+	if len(l.sinks) == 0 {
+		return nil, errors.New("no audit destinations")
+	}
+	go l.run()
+	return l, nil
+}
+
+func openSink(dest string) (sink, error) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// A plain path, or a Windows one such as C:\audit.log.
+		return openFile(dest)
+	}
+	switch u.Scheme {
+	case "file":
+		return openFile(strings.TrimPrefix(strings.TrimPrefix(dest, "file:"), "//"))
+	case "syslog":
+		return openSyslog("udp", u.Host, "514")
+	case "syslog+tcp":
+		return openSyslog("tcp", u.Host, "601")
+	case "http", "https":
+		return &webhook{url: dest, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	}
+	return nil, fmt.Errorf("unknown scheme %q (want file, syslog, syslog+tcp, http or https)", u.Scheme)
+}
+
+// Record sends e, filling in its time, host and user. It does not wait
+// for e to be sent; if too many events are waiting, e is dropped and the
+// drop is reported.
+func (l *Log) Record(e Event) {
+	if l == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Host == "" {
+		e.Host = l.host
+	}
+	if e.User == "" {
+		e.User = l.user
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.queue <- e:
+	default:
+		l.dropped++
+		if l.dropped == 1 || l.dropped%100 == 0 {
+			l.logf("audit: %d events dropped, the destinations are too slow", l.dropped)
+		}
+	}
+}
+
+// Close sends the events still waiting, giving up after timeout, and
+// closes the destinations.
+func (l *Log) Close(timeout time.Duration) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+	select {
+	case <-l.done:
+	case <-time.After(timeout):
+		return errors.New("audit events were still being sent when the log closed")
+	}
+	var errs []error
+	for _, s := range l.sinks {
+		errs = append(errs, s.close())
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Log) run() {
+	defer close(l.done)
+	for e := range l.queue {
+		line := e.Marshal(l.format)
+		for _, s := range l.sinks {
+			if err := s.send(e, line); err != nil {
+				l.logf("audit: %v", err)
+			}
+		}
+	}
+}
+
+// file appends events to a file, a line each.
+type file struct {
+	f *os.File
+}
+
+func openFile(path string) (sink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return file{f: f}, nil
+}
+
+func (f file) send(_ Event, line []byte) error {
+	_, err := f.f.Write(append(line, '\n'))
+	return err
+}
+
+func (f file) close() error { return f.f.Close() }
+
+// syslog sends events to a syslog collector in RFC 5424 format, with the
+// facility log audit.
+type syslog struct {
+	network, addr string
+	conn          net.Conn
+}
+
+func openSyslog(network, host, port string) (sink, error) {
+	if host == "" {
+		return nil, errors.New("no syslog host")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, port)
+	}
+	return &syslog{network: network, addr: host}, nil
+}
+
+// facilityAudit is the syslog facility of audit logs.
+const facilityAudit = 13
+
+func (s *syslog) send(e Event, line []byte) error {
+	severity := 5 // notice
+	if e.Outcome != OutcomeSuccess {
+		severity = 4 // warning
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s agentgo %d - - %s", facilityAudit*8+severity, e.Time.Format(time.RFC3339Nano), nilValue(e.Host), os.Getpid(), line)
+	if s.network == "tcp" {
+		// Octet counting framing (RFC 6587), since messages may hold
+		// newlines.
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	// A connection that broke is redialled once.
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			conn, err := net.DialTimeout(s.network, s.addr, 5*time.Second)
+			if err != nil {
+				return fmt.Errorf("failed to reach syslog at %s: %w", s.addr, err)
+			}
+			s.conn = conn
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		_, err := s.conn.Write([]byte(msg))
+		if err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+		if attempt > 0 {
+			return fmt.Errorf("failed to send to syslog at %s: %w", s.addr, err)
+		}
+	}
+}
+
+func (s *syslog) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// webhook POSTs each event to a URL.
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhook) send(e Event, line []byte) error {
+	contentType := "application/json"
+	if !json.Valid(line) {
+		contentType = "text/plain; charset=utf-8"
+	}
+	resp, err := w.client.Post(w.url, contentType, bytes.NewReader(line))
+	if err != nil {
+		return fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *webhook) close() error { return nil }
@@ -0,0 +1,235 @@
+// Package auth authenticates and authorizes requests to the worker API,
+// which controls someone's desktop. Callers prove who they are with a
+// bearer token or, over TLS, a client certificate, and each identity is
+// granted a scope: observe (status and screenshots), act (running and
+// cancelling its own tasks) or admin (everything). Every request is logged
+// with the identity that made it.
+//
+// Identities are listed in a file, one per line:
+//
+//	# identity  scope    credential
+//	ci          act      sha256:9f86d081884c7d65...
+//	alice       admin    cert
+//
+// A token credential is the SHA-256 of the token, as NewToken returns it,
+// so that the file holds nothing that grants access by itself. "cert"
+// admits a client certificate, verified against the server's client CA,
+// whose common name is the identity.
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Scope is what an identity may do. Each scope includes those below it.
+type Scope int
+
+// Scopes.
+const (
+	ScopeNone Scope = iota
+	ScopeObserve
+	ScopeAct
+	ScopeAdmin
+)
+
+var scopeNames = map[Scope]string{ScopeNone: "none", ScopeObserve: "observe", ScopeAct: "act", ScopeAdmin: "admin"}
+
+func (s Scope) String() string {
+	if name, ok := scopeNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Scope(%d)", int(s))
+}
+
+// ParseScope parses observe, act or admin.
+func ParseScope(s string) (Scope, error) {
+	for scope, name := range scopeNames {
+		if scope != ScopeNone && name == s {
+			return scope, nil
+		}
+	}
+	return ScopeNone, fmt.Errorf("unknown scope %q (want observe, act or admin)", s)
+}
+
+// Identity is who made a request.
+type Identity struct {
+	Name  string
+	Scope Scope
+	// Via is how the identity was proven: token or cert.
+	Via string
+}
+
+// Anonymous is the identity of requests to a server without
+// authentication, which may do anything.
+var Anonymous = Identity{Name: "anonymous", Scope: ScopeAdmin}
+
+// Errors of Identify.
+var (
+	ErrNoCredentials = errors.New("no credentials: send Authorization: Bearer TOKEN or a client certificate")
+	ErrUnknown       = errors.New("unknown credentials")
+)
+
+// tokenPrefix marks agentGo tokens, so that they are recognizable in
+// configuration and to secret scanners.
+const tokenPrefix = "agt_"
+
+// NewToken returns a random token and the credential to list for it.
+func NewToken() (token, credential string, err error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", "", err
+	}
+	token = tokenPrefix + base64.RawURLEncoding.EncodeToString(b[:])
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the credential of token: "sha256:" and its hex digest.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Authenticator identifies the callers of an API.
+type Authenticator struct {
+	tokens map[string]Identity
+	certs  map[string]Identity
+}
+
+// Load reads an identities file.
+func Load(path string) (*Authenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identities: %w", err)
+	}
+	defer f.Close()
+	a := &Authenticator{tokens: map[string]Identity{}, certs: map[string]Identity{}}
+	lines := bufio.NewScanner(f)
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: want identity, scope and credential", path, n)
+		}
+		scope, err := ParseScope(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		id := Identity{Name: fields[0], Scope: scope}
+		switch cred := fields[2]; {
+		case cred == "cert":
+			id.Via = "cert"
+			a.certs[id.Name] = id
+		case strings.HasPrefix(cred, "sha256:") && len(cred) == len("sha256:")+64:
+			id.Via = "token"
+			a.tokens[strings.ToLower(cred)] = id
+		default:
+			return nil, fmt.Errorf("%s:%d: credential must be sha256:<hex digest of the token> or cert", path, n)
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read identities: %w", err)
+	}
+	return a, nil
+}
+
+// Certs reports whether any identity signs in with a client certificate,
+// which the server must then ask for.
+func (a *Authenticator) Certs() bool {
+	return a != nil && len(a.certs) > 0
+}
+
+// Identify returns who made r. A nil Authenticator admits everyone as
+// Anonymous.
+func (a *Authenticator) Identify(r *http.Request) (Identity, error) {
+	if a == nil {
+		return Anonymous, nil
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		want := HashToken(strings.TrimSpace(token))
+		for cred, id := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(cred), []byte(want)) == 1 {
+				return id, nil
+			}
+		}
+		return Identity{}, ErrUnknown
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if id, ok := a.certs[cn]; ok {
+			return id, nil
+		}
+		return Identity{}, ErrUnknown
+	}
+	return Identity{}, ErrNoCredentials
+}
+
+type identityKey struct{}
+
+// FromContext returns the identity of the request whose context ctx is,
+// within Handler.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// Handler serves h to callers whose scope is at least what need says the
+// request takes, answering 401 to those it cannot identify and 403 to
+// those without the scope. The identity is in the request's context (see
+// FromContext), and every request is logged with it through logf, or
+// log.Printf if logf is nil.
+func (a *Authenticator) Handler(h http.Handler, need func(r *http.Request) Scope, logf func(format string, args ...any)) http.Handler {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+		id, err := a.Identify(r)
+		who := id.Name
+		switch {
+		case err != nil:
+			who = "?"
+			rec.Header().Set("WWW-Authenticate", `Bearer realm="agentgo"`)
+			http.Error(rec, err.Error(), http.StatusUnauthorized)
+		case id.Scope < need(r):
+			http.Error(rec, fmt.Sprintf("%s may only %s, not %s", id.Name, id.Scope, need(r)), http.StatusForbidden)
+		default:
+			h.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+		}
+		logf("%s %s %s %s: %d in %v", r.RemoteAddr, who, r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// statusRecorder remembers the status of a response, for the log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets streamed responses, such as screenshot streams, through.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
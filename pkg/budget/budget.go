@@ -0,0 +1,240 @@
+// Package budget holds a task to a most it may spend on model calls and a
+// most steps it may take. A Tracker counts both as the task runs and stops
+// it, with an error giving the spend by model, before a step or a call
+// would go over.
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Budget is what a task may spend. Zero fields are not limited.
+type Budget struct {
+	// MaxCost is the most the task's model calls may cost, in dollars.
+	MaxCost float64 `json:"max_cost,omitempty"`
+	// MaxSteps is the most steps the task may take: script steps, or an
+	// agent's tool calls.
+	MaxSteps int `json:"max_steps,omitempty"`
+}
+
+// IsZero reports whether b limits nothing.
+func (b Budget) IsZero() bool {
+	return b.MaxCost <= 0 && b.MaxSteps <= 0
+}
+
+// Prices maps model names to their price in dollars per million tokens.
+type Prices map[string]float64
+
+// ParsePrices parses comma-separated MODEL=PRICE pairs, e.g.
+// "gemini-1.5-flash=0.15,gemini-2.0-flash=0.4".
+func ParsePrices(spec string) (Prices, error) {
+	prices := Prices{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, value, ok := strings.Cut(entry, "=")
+		price, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || strings.TrimSpace(model) == "" || err != nil || price < 0 {
+			return nil, fmt.Errorf("invalid price %q; want MODEL=DOLLARS_PER_MILLION_TOKENS", entry)
+		}
+		prices[strings.TrimSpace(model)] = price
+	}
+	return prices, nil
+}
+
+// Spend is what the calls to one model used.
+type Spend struct {
+	Model    string  `json:"model"`
+	Requests int     `json:"requests"`
+	Tokens   int64   `json:"tokens"`
+	Cost     float64 `json:"cost"`
+}
+
+// ErrExceeded is wrapped by the errors a Tracker stops a task with.
+var ErrExceeded = errors.New("budget exceeded")
+
+// ExceededError is returned when a step or model call would go over the
+// budget.
+type ExceededError struct {
+	// Limit says which limit, and by how much.
+	Limit string
+	Steps int
+	Spend []Spend
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%v: %s, after %d steps; spent %s", ErrExceeded, e.Limit, e.Steps, FormatSpend(e.Spend))
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrExceeded
+}
+
+// DefaultCallTokens is what a call to a model is assumed to use before
+// the model's calls have shown what they cost: a screenshot and a prompt,
+// and an answer of the most tokens models are usually allowed.
+const DefaultCallTokens = 10000
+
+// Tracker tracks a task's steps and spend against its budget. It is safe
+// for concurrent use. A nil Tracker allows everything.
+type Tracker struct {
+	Budget Budget
+	Prices Prices
+	// CallTokens is the most tokens a call is assumed to use until a
+	// model's calls have cost something; 0 means DefaultCallTokens.
+	CallTokens int64
+
+	mu     sync.Mutex
+	steps  int
+	models map[string]*Spend
+	// reserved holds the estimated cost of each call Allow let through
+	// that has not been charged yet, by model, oldest first.
+	reserved map[string][]float64
+}
+
+// NewTracker tracks spend against b at prices.
+func NewTracker(b Budget, prices Prices) *Tracker {
+	return &Tracker{Budget: b, Prices: prices, models: map[string]*Spend{}, reserved: map[string][]float64{}}
+}
+
+// Step counts a step about to be taken, or returns an *ExceededError if
+// it would be one more than MaxSteps.
+func (t *Tracker) Step() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Budget.MaxSteps > 0 && t.steps >= t.Budget.MaxSteps {
+		return t.exceeded(fmt.Sprintf("the next step would be more than the %d allowed", t.Budget.MaxSteps))
+	}
+	t.steps++
+	return nil
+}
+
+// Allow reports whether a call to model may be made: it returns an
+// *ExceededError if the call, estimated to cost what the model's calls
+// have cost on average, or CallTokens at the model's price before they
+// have cost anything, would take the spend past MaxCost. Calls allowed
+// but not yet charged count at their estimate, so that calls made at the
+// same time cannot together go over; every call Allow lets through must be
+// charged. Without a price for the model, a cost budget cannot be kept,
+// which is an error.
+func (t *Tracker) Allow(model string) error {
+	if t == nil || t.Budget.MaxCost <= 0 {
+		return nil
+	}
+	if _, ok := t.Prices[model]; !ok {
+		return fmt.Errorf("keeping to a cost budget needs the price of %s; set AGENTGO_PRICES, e.g. %s=0.15", model, model)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spent float64
+	for _, s := range t.models {
+		spent += s.Cost
+	}
+	for _, costs := range t.reserved {
+		for _, cost := range costs {
+			spent += cost
+		}
+	}
+	next := t.estimate(model)
+	if spent+next > t.Budget.MaxCost {
+		return t.exceeded(fmt.Sprintf("the next call to %s, about $%.4f, would take the cost past $%.4f", model, next, t.Budget.MaxCost))
+	}
+	t.reserved[model] = append(t.reserved[model], next)
+	return nil
+}
+
+// estimate returns what the next call to model is expected to cost.
+func (t *Tracker) estimate(model string) float64 {
+	if s := t.models[model]; s != nil && s.Requests > 0 && s.Cost > 0 {
+		return s.Cost / float64(s.Requests)
+	}
+	tokens := t.CallTokens
+	if tokens <= 0 {
+		tokens = DefaultCallTokens
+	}
+	return float64(tokens) / 1e6 * t.Prices[model]
+}
+
+// Charge records a call to model that used tokens, in place of the
+// estimate Allow reserved for it.
+func (t *Tracker) Charge(model string, tokens int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if costs := t.reserved[model]; len(costs) > 0 {
+		t.reserved[model] = costs[1:]
+	}
+	s := t.models[model]
+	if s == nil {
+		s = &Spend{Model: model}
+		t.models[model] = s
+	}
+	s.Requests++
+	s.Tokens += tokens
+	s.Cost += float64(tokens) / 1e6 * t.Prices[model]
+}
+
+// Steps returns the steps taken so far.
+func (t *Tracker) Steps() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.steps
+}
+
+// Spend returns the spend so far by model, the costliest first.
+func (t *Tracker) Spend() []Spend {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.spend()
+}
+
+func (t *Tracker) spend() []Spend {
+	list := make([]Spend, 0, len(t.models))
+	for _, s := range t.models {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Cost != list[j].Cost {
+			return list[i].Cost > list[j].Cost
+		}
+		return list[i].Model < list[j].Model
+	})
+	return list
+}
+
+func (t *Tracker) exceeded(limit string) error {
+	return &ExceededError{Limit: limit, Steps: t.steps, Spend: t.spend()}
+}
+
+// FormatSpend describes spend in one line, e.g. "$0.0123 (gemini-1.5-flash:
+// 12 calls, 82000 tokens, $0.0123)".
+func FormatSpend(spend []Spend) string {
+	if len(spend) == 0 {
+		return "nothing on models"
+	}
+	var total float64
+	parts := make([]string, len(spend))
+	for i, s := range spend {
+		total += s.Cost
+		parts[i] = fmt.Sprintf("%s: %d calls, %d tokens, $%.4f", s.Model, s.Requests, s.Tokens, s.Cost)
+	}
+	return fmt.Sprintf("$%.4f (%s)", total, strings.Join(parts, "; "))
+}
@@ -0,0 +1,50 @@
+// Package capture defines the interface screen capture backends implement,
+// so that the local screenshot library, remote screens (VNC, ADB), capture
+// hardware and test fakes can be swapped for one another.
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// ErrUnsupported is returned by backends that cannot perform a kind of
+// capture, e.g. window capture on a remote framebuffer.
+var ErrUnsupported = errors.New("not supported by this capture backend")
+
+// Display describes one monitor.
+type Display struct {
+	// Index is the display number used for capture.
+	Index int `json:"index"`
+	// Bounds is the display's area in physical pixels on the virtual
+	// desktop.
+	Bounds image.Rectangle `json:"bounds"`
+	// Scale is the display's UI scale factor, e.g. 1.5 for 144 DPI.
+	Scale float64 `json:"scale"`
+	// Primary marks the main display.
+	Primary bool `json:"primary"`
+}
+
+// Backend captures the screen.
+type Backend interface {
+	// Displays lists the active displays in capture order.
+	Displays() []Display
+	// Capture returns the pixels of rect, in physical virtual-desktop
+	// coordinates. HiDPI backends may return more pixels than rect covers.
+	// The image may be recycled by the caller with frame.Put.
+	Capture(rect image.Rectangle) (*image.RGBA, error)
+	// CaptureWindow returns the pixels of the window with the given ID, in
+	// the backend's own window numbering (a process ID for the local
+	// desktop). Backends without windows return ErrUnsupported.
+	CaptureWindow(id int) (*image.RGBA, error)
+}
+
+// CaptureDisplay captures display i of b.
+func CaptureDisplay(b Backend, i int) (*image.RGBA, error) {
+	list := b.Displays()
+	if i < 0 || i >= len(list) {
+		return nil, fmt.Errorf("display %d does not exist (%d active displays)", i, len(list))
+	}
+	return b.Capture(list[i].Bounds)
+}
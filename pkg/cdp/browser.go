@@ -0,0 +1,254 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// navigationGrace is how long WaitLoad waits for a navigation to begin,
+// such as one a click has just set off, before taking the page as it is.
+const navigationGrace = 500 * time.Millisecond
+
+// Launch starts the browser with remote debugging on the client's endpoint
+// and the user data directory profile, opens url in it, if not empty, and
+// attaches to it. An empty profile uses a directory of its own under the
+// system's temporary directory, since browsers refuse remote debugging of
+// the everyday profile. When a browser already answers on the endpoint
+// Launch attaches to it instead of starting another. The browser keeps
+// running after the client closes.
+func (c *Client) Launch(ctx context.Context, profile, url string) error {
+	if c.endpointJSON(ctx, http.MethodGet, "/json/version", nil) != nil {
+		if err := c.start(ctx, profile); err != nil {
+			return err
+		}
+	}
+	if err := c.Attach(ctx, ""); err != nil {
+		return err
+	}
+	if url == "" {
+		return nil
+	}
+	return c.Navigate(ctx, url)
+}
+
+// start runs the browser and waits for its endpoint to answer.
+func (c *Client) start(ctx context.Context, profile string) error {
+	u, err := url.Parse(c.endpoint)
+	if err != nil || u.Scheme != "http" || u.Port() == "" {
+		return fmt.Errorf("launching a browser needs an http://host:port endpoint, not %q", c.endpoint)
+	}
+	if h := u.Hostname(); h != "localhost" && h != "127.0.0.1" && h != "::1" {
+		return fmt.Errorf("cannot launch a browser on %s; start it there with --remote-debugging-port", h)
+	}
+	exe := c.Executable
+	if exe == "" {
+		if exe = findBrowser(); exe == "" {
+			return errors.New("no Chrome, Chromium or Edge found; set the browser's path")
+		}
+	}
+	if profile == "" {
+		profile = filepath.Join(os.TempDir(), "agentgo-browser")
+	}
+	cmd := exec.Command(exe,
+		"--remote-debugging-port="+u.Port(),
+		"--user-data-dir="+profile,
+		"--no-first-run",
+		"--no-default-browser-check",
+		"about:blank",
+	)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", exe, err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	tick := time.NewTicker(200 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		err := c.endpointJSON(ctx, http.MethodGet, "/json/version", nil)
+		if err == nil {
+			return nil
+		}
+		select {
+		case werr := <-exited:
+			// A browser already running with the profile takes over the
+			// request and leaves the new process to exit.
+			return fmt.Errorf("%s exited before opening its DevTools endpoint (is it already running with this profile?): %v", filepath.Base(exe), werr)
+		case <-ctx.Done():
+			return fmt.Errorf("browser did not open its DevTools endpoint: %w", err)
+		case <-tick.C:
+		}
+	}
+}
+
+// findBrowser returns the path of an installed Chrome, Chromium or Edge, or
+// "" if there is none.
+func findBrowser() string {
+	var paths []string
+	switch runtime.GOOS {
+	case "darwin":
+		paths = []string{
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+		}
+	case "windows":
+		for _, dir := range []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)"), os.Getenv("LocalAppData")} {
+			if dir != "" {
+				paths = append(paths,
+					filepath.Join(dir, `Google\Chrome\Application\chrome.exe`),
+					filepath.Join(dir, `Microsoft\Edge\Application\msedge.exe`),
+				)
+			}
+		}
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "microsoft-edge", "chrome", "msedge"} {
+		if p, err := exec.LookPath(name); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// Navigate loads url in the page and waits for it to finish loading.
+func (c *Client) Navigate(ctx context.Context, url string) error {
+	c.setLoading(true)
+	var r struct {
+		LoaderID  string `json:"loaderId"`
+		ErrorText string `json:"errorText"`
+	}
+	if err := c.Call(ctx, "Page.navigate", map[string]any{"url": url}, &r); err != nil {
+		c.setLoading(false)
+		return err
+	}
+	if r.ErrorText != "" {
+		c.setLoading(false)
+		return fmt.Errorf("failed to open %s: %s", url, r.ErrorText)
+	}
+	if r.LoaderID == "" {
+		// Navigating within the document, e.g. to an anchor, loads nothing.
+		c.setLoading(false)
+	}
+	c.mu.Lock()
+	c.URL = url
+	c.mu.Unlock()
+	return c.WaitLoad(ctx)
+}
+
+// NewTab opens a tab, attaches to it and loads url in it, if not empty.
+func (c *Client) NewTab(ctx context.Context, url string) error {
+	var t target
+	if err := c.endpointJSON(ctx, http.MethodPut, "/json/new", &t); err != nil {
+		return fmt.Errorf("failed to open a tab: %w", err)
+	}
+	if err := c.attach(ctx, t); err != nil {
+		return err
+	}
+	if url == "" {
+		return nil
+	}
+	return c.Navigate(ctx, url)
+}
+
+// SwitchTab brings to the front, and attaches to, the first tab whose title
+// or URL contains match, ignoring case.
+func (c *Client) SwitchTab(ctx context.Context, match string) error {
+	m := strings.ToLower(match)
+	t, err := c.findPage(ctx, func(t target) bool {
+		return strings.Contains(strings.ToLower(t.Title), m) || strings.Contains(strings.ToLower(t.URL), m)
+	})
+	if err != nil {
+		if errors.Is(err, errNoPage) {
+			return fmt.Errorf("no tab's title or URL contains %q", match)
+		}
+		return err
+	}
+	if err := c.endpointJSON(ctx, http.MethodGet, "/json/activate/"+t.ID, nil); err != nil {
+		return fmt.Errorf("failed to switch to tab %q: %w", t.Title, err)
+	}
+	return c.attach(ctx, t)
+}
+
+// WaitLoad waits until the page has finished loading its document,
+// following the browser's page events. A navigation that has not begun
+// within half a second counts as none, so waiting on a page that is not
+// going anywhere returns once it is loaded.
+func (c *Client) WaitLoad(ctx context.Context) error {
+	grace := time.NewTimer(navigationGrace)
+	defer grace.Stop()
+	poll := time.NewTicker(250 * time.Millisecond)
+	defer poll.Stop()
+	started, waited := false, false
+	for {
+		c.mu.Lock()
+		loading, changed := c.loading, c.changed
+		c.mu.Unlock()
+		started = started || loading
+		if !loading && (started || waited) {
+			var state string
+			if err := c.Evaluate(ctx, "document.readyState", &state); err != nil {
+				return err
+			}
+			if state == "complete" {
+				return nil
+			}
+		}
+		select {
+		case <-changed:
+		case <-grace.C:
+			waited = true
+		case <-poll.C:
+		case <-ctx.Done():
+			return fmt.Errorf("page did not finish loading: %w", ctx.Err())
+		}
+	}
+}
+
+// event follows the main frame's loading from the page's events.
+func (c *Client) event(method string, params json.RawMessage) {
+	var p struct {
+		FrameID string `json:"frameId"`
+	}
+	switch method {
+	case "Page.frameStartedLoading", "Page.frameStoppedLoading":
+		if json.Unmarshal(params, &p) != nil {
+			return
+		}
+		c.mu.Lock()
+		main := p.FrameID == c.frameID
+		c.mu.Unlock()
+		if main {
+			c.setLoading(method == "Page.frameStartedLoading")
+		}
+	case "Page.loadEventFired":
+		c.setLoading(false)
+	}
+}
+
+func (c *Client) setLoading(loading bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loading == loading {
+		return
+	}
+	c.loading = loading
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
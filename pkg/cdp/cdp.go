@@ -0,0 +1,367 @@
+// Package cdp attaches to a Chromium-based browser over the Chrome DevTools
+// Protocol, for browser tasks where reading the DOM beats reading pixels.
+// Start the browser with remote debugging enabled:
+//
+//	chrome --remote-debugging-port=9222
+//
+// or let Client.Launch start it.
+//
+// A Client can then serve as a desktop driver whose screen is the page
+// (agentgo play -driver cdp://localhost:9222), dispatching input through
+// the protocol, or a Locator can resolve script targets to elements of the
+// page while another driver performs the input. Either way targets the DOM
+// cannot resolve, such as canvas or iframe content, fall back to the vision
+// model.
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"agentGo/pkg/motion"
+)
+
+// Client is a DevTools connection to one page of a browser. It implements
+// the desktop driver methods (Move, Click, Type, KeyTap, Capture, Close),
+// with normalized coordinates covering the page's viewport.
+type Client struct {
+	// Title and URL describe the page attached to.
+	Title, URL string
+	// Executable is the browser Launch starts; empty looks for Chrome,
+	// Chromium or Edge in the usual places.
+	Executable string
+
+	endpoint string
+
+	mu      sync.Mutex
+	ws      *wsConn
+	nextID  int64
+	pending map[int64]chan response
+	err     error
+
+	// frameID is the page's main frame, and loading whether it is loading
+	// a document; changed is closed and replaced whenever loading changes.
+	frameID string
+	loading bool
+	changed chan struct{}
+
+	pointerMu sync.Mutex
+	x, y      float64
+	buttons   int
+	pointer   motion.Pointer
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// errNotAttached is returned by calls made before the client attaches to
+// a page.
+var errNotAttached = errors.New("not attached to a browser page; launch the browser or open a tab first")
+
+// target is an entry of the browser's /json/list.
+type target struct {
+	ID                   string `json:"id"`
+	Type                 string `json:"type"`
+	Title                string `json:"title"`
+	URL                  string `json:"url"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// New returns a client for a browser's remote debugging endpoint, e.g.
+// "http://localhost:9222", that is not yet attached to a page: Launch,
+// Attach, NewTab or SwitchTab attach it. A ws:// URL names a page's own
+// endpoint, which Attach attaches to directly.
+func New(endpoint string) *Client {
+	return &Client{
+		URL:      endpoint,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		pending:  map[int64]chan response{},
+		changed:  make(chan struct{}),
+	}
+}
+
+// Dial attaches to a browser's remote debugging endpoint, picking the first
+// page whose URL contains match (any page if match is empty). A ws:// URL
+// attaches to that target directly.
+func Dial(ctx context.Context, endpoint, match string) (*Client, error) {
+	c := New(endpoint)
+	if err := c.Attach(ctx, match); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Attach attaches to the first page whose URL contains match, detaching
+// from the page attached to before, if any.
+func (c *Client) Attach(ctx context.Context, match string) error {
+	if strings.HasPrefix(c.endpoint, "ws://") {
+		return c.attach(ctx, target{URL: c.URL, WebSocketDebuggerURL: c.endpoint})
+	}
+	t, err := c.findPage(ctx, func(t target) bool { return strings.Contains(t.URL, match) })
+	if err != nil {
+		if match != "" && errors.Is(err, errNoPage) {
+			return fmt.Errorf("no browser page at %s matches %q", c.endpoint, match)
+		}
+		return err
+	}
+	return c.attach(ctx, t)
+}
+
+// attach connects to t, failing the calls still waiting on the old page,
+// and enables the page events WaitLoad follows.
+func (c *Client) attach(ctx context.Context, t target) error {
+	ws, err := dialWebSocket(ctx, t.WebSocketDebuggerURL)
+	if err != nil {
+		return fmt.Errorf("failed to attach to browser page: %w", err)
+	}
+	c.mu.Lock()
+	old := c.ws
+	c.ws, c.err = ws, nil
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.Title, c.URL = t.Title, t.URL
+	c.frameID, c.loading = "", false
+	c.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	go c.readLoop(ws)
+
+	var tree struct {
+		FrameTree struct {
+			Frame struct {
+				ID string `json:"id"`
+			} `json:"frame"`
+		} `json:"frameTree"`
+	}
+	if err := c.Call(ctx, "Page.enable", nil, nil); err != nil {
+		return err
+	}
+	if err := c.Call(ctx, "Page.getFrameTree", nil, &tree); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.frameID = tree.FrameTree.Frame.ID
+	c.mu.Unlock()
+	return nil
+}
+
+// errNoPage is returned by findPage when no page is open or none matches.
+var errNoPage = errors.New("no browser page")
+
+// findPage returns the first page the browser lists, most recently used
+// first, that match accepts.
+func (c *Client) findPage(ctx context.Context, match func(target) bool) (target, error) {
+	var targets []target
+	if err := c.endpointJSON(ctx, http.MethodGet, "/json/list", &targets); err != nil {
+		return target{}, err
+	}
+	open := false
+	for _, t := range targets {
+		if t.Type != "page" || t.WebSocketDebuggerURL == "" {
+			continue
+		}
+		open = true
+		if match(t) {
+			return t, nil
+		}
+	}
+	if !open {
+		return target{}, fmt.Errorf("%w is open at %s", errNoPage, c.endpoint)
+	}
+	return target{}, fmt.Errorf("%w at %s matches", errNoPage, c.endpoint)
+}
+
+// endpointJSON makes a request of the browser's HTTP endpoint and decodes
+// the JSON answer into result, which may be nil.
+func (c *Client) endpointJSON(ctx context.Context, method, path string, result any) error {
+	base := c.endpoint
+	if strings.HasPrefix(base, "ws://") {
+		u, err := url.Parse(base)
+		if err != nil {
+			return err
+		}
+		base = "http://" + u.Host
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the browser's DevTools endpoint (was it started with --remote-debugging-port?): %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// readLoop hands each response read from ws to the call waiting for it,
+// and each event to event, until ws fails or is replaced.
+func (c *Client) readLoop(ws *wsConn) {
+	for {
+		msg, err := ws.read()
+		if err != nil {
+			c.mu.Lock()
+			if c.ws == ws {
+				c.err = err
+				for id, ch := range c.pending {
+					close(ch)
+					delete(c.pending, id)
+				}
+			}
+			c.mu.Unlock()
+			return
+		}
+		var m struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			response
+		}
+		if json.Unmarshal(msg, &m) != nil {
+			continue
+		}
+		if m.ID == 0 {
+			if m.Method != "" {
+				c.event(m.Method, m.Params)
+			}
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[m.ID]
+		delete(c.pending, m.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- m.response
+		}
+	}
+}
+
+// Call invokes a protocol method and decodes its result into result, which
+// may be nil.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	ws := c.ws
+	if ws == nil {
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %w", method, errNotAttached)
+	}
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan response, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	msg, err := json.Marshal(struct {
+		ID     int64  `json:"id"`
+		Method string `json:"method"`
+		Params any    `json:"params,omitempty"`
+	}{id, method, params})
+	if err == nil {
+		err = ws.write(opText, msg)
+	}
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			c.mu.Lock()
+			err := c.err
+			c.mu.Unlock()
+			if err == nil {
+				err = errors.New("detached from the page")
+			}
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s", method, resp.Error.Message)
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Evaluate runs a JavaScript expression in the page and decodes its value,
+// which must be JSON-serializable, into result.
+func (c *Client) Evaluate(ctx context.Context, expr string, result any) error {
+	var r struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	err := c.Call(ctx, "Runtime.evaluate", map[string]any{"expression": expr, "returnByValue": true, "awaitPromise": true}, &r)
+	if err != nil {
+		return err
+	}
+	if e := r.ExceptionDetails; e != nil {
+		msg := e.Exception.Description
+		if msg == "" {
+			msg = e.Text
+		}
+		return errors.New("script error in page: " + msg)
+	}
+	if result == nil || len(r.Result.Value) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Result.Value, result)
+}
+
+// Close detaches from the page; the browser keeps running.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	ws := c.ws
+	c.mu.Unlock()
+	if ws == nil {
+		return nil
+	}
+	return ws.Close()
+}
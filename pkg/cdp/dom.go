@@ -0,0 +1,228 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Element is an element of the page found by Find.
+type Element struct {
+	Tag  string `json:"tag"`
+	Text string `json:"text"`
+	// X, Y, Width and Height are its box in CSS pixels of the page's layout
+	// viewport, which boxes inside iframes are mapped into.
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Center returns the middle of the element's box.
+func (e Element) Center() (float64, float64) {
+	return e.X + e.Width/2, e.Y + e.Height/2
+}
+
+// Window describes the browser window, for mapping the viewport to the
+// screen. The window's position and outer size are in device-independent
+// pixels, which page zoom leaves alone; the inner size is in CSS pixels,
+// which it scales. The device pixel ratio is the system's display scale
+// times the page zoom.
+type Window struct {
+	DevicePixelRatio float64 `json:"dpr"`
+	ScreenX          float64 `json:"screenX"`
+	ScreenY          float64 `json:"screenY"`
+	OuterWidth       float64 `json:"outerWidth"`
+	OuterHeight      float64 `json:"outerHeight"`
+	InnerWidth       float64 `json:"innerWidth"`
+	InnerHeight      float64 `json:"innerHeight"`
+	// PinchX, PinchY and PinchScale place the visual viewport, the part of
+	// the page a pinch zoom shows, in the layout viewport.
+	PinchX     float64 `json:"pinchX"`
+	PinchY     float64 `json:"pinchY"`
+	PinchScale float64 `json:"pinchScale"`
+}
+
+// zoomLevels are the page zooms browsers offer.
+var zoomLevels = []float64{0.25, 1.0 / 3, 0.5, 2.0 / 3, 0.75, 0.8, 0.9, 1, 1.1, 1.25, 1.5, 1.75, 2, 2.5, 3, 4, 5}
+
+// Zoom returns the page zoom, worked out from how much wider the window is
+// than its viewport in CSS pixels and snapped to the browser's zoom levels.
+// A viewport narrowed by more than a window frame, e.g. by docked DevTools
+// or a side panel, gives no level to snap to and counts as unzoomed.
+func (w Window) Zoom() float64 {
+	if w.InnerWidth <= 0 || w.OuterWidth <= 0 {
+		return 1
+	}
+	ratio := w.OuterWidth / w.InnerWidth
+	zoom, best := 1.0, math.Inf(1)
+	for _, z := range zoomLevels {
+		// The frame only ever adds to the outer width.
+		if d := (ratio - z) / z; d > -0.01 && d < best {
+			zoom, best = z, d
+		}
+	}
+	if best > 0.05 {
+		return 1
+	}
+	return zoom
+}
+
+// Visual maps a point of the layout viewport to the visual viewport, in
+// CSS pixels at the layout viewport's scale, undoing any pinch zoom.
+func (w Window) Visual(x, y float64) (float64, float64) {
+	if w.PinchScale <= 0 {
+		return x, y
+	}
+	return (x - w.PinchX) * w.PinchScale, (y - w.PinchY) * w.PinchScale
+}
+
+// Screen maps a point of the layout viewport to physical screen pixels,
+// assuming the browser's toolbars are at the top of the window and its
+// frame is as wide at the sides as at the bottom.
+func (w Window) Screen(x, y float64) (float64, float64) {
+	x, y = w.Visual(x, y)
+	zoom := w.Zoom()
+	scale := w.DevicePixelRatio / zoom
+	if scale <= 0 {
+		scale = 1
+	}
+	border := (w.OuterWidth - w.InnerWidth*zoom) / 2
+	top := w.OuterHeight - w.InnerHeight*zoom - border
+	return (w.ScreenX + border + x*zoom) * scale, (w.ScreenY + top + y*zoom) * scale
+}
+
+// Query selects elements: by CSS selector, or else by the text a user
+// sees on or for them (content, label, value, placeholder, title or alt
+// text), compared case-insensitively.
+type Query struct {
+	Selector string `json:"selector,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// ParseTarget turns a script target into a query. "css:SELECTOR" and
+// "text:TEXT" are explicit; otherwise quoted text in the description is
+// looked for, or the description without its article and a trailing role
+// word, so that `the "Save" button` and "the Save button" both look for
+// an element reading Save.
+func ParseTarget(target string) Query {
+	target = strings.TrimSpace(target)
+	if s, ok := strings.CutPrefix(target, "css:"); ok {
+		return Query{Selector: strings.TrimSpace(s)}
+	}
+	if s, ok := strings.CutPrefix(target, "text:"); ok {
+		return Query{Text: strings.TrimSpace(s)}
+	}
+	if m := quoted.FindStringSubmatch(target); m != nil {
+		return Query{Text: m[1] + m[2]}
+	}
+	text := strings.ToLower(target)
+	for _, article := range []string{"the ", "a ", "an "} {
+		text = strings.TrimPrefix(text, article)
+	}
+	if i := strings.LastIndex(text, " "); i > 0 && roleWords[text[i+1:]] {
+		text = text[:i]
+	}
+	return Query{Text: strings.TrimSpace(text)}
+}
+
+var quoted = regexp.MustCompile(`"([^"]+)"|'([^']+)'`)
+
+var roleWords = map[string]bool{
+	"button": true, "link": true, "field": true, "box": true, "input": true,
+	"tab": true, "menu": true, "checkbox": true, "icon": true, "option": true,
+	"heading": true, "label": true, "item": true,
+}
+
+// frameScript defines the helpers the page scripts share: docs lists the
+// page's document and those of the iframes in it that the page can reach,
+// which excludes other origins' frames; box returns an element's box in
+// the top document's layout viewport, offset and scaled through the
+// iframes it is in; styleOf reads an element's style in its own frame; and
+// geometry describes the window for Window.
+const frameScript = `
+  const docs = () => {
+    const all = [document];
+    for (let i = 0; i < all.length; i++) {
+      for (const f of all[i].querySelectorAll('iframe,frame')) {
+        try {
+          if (f.contentDocument && f.contentDocument.documentElement) all.push(f.contentDocument);
+        } catch (err) {}
+      }
+    }
+    return all;
+  };
+  const styleOf = e => e.ownerDocument.defaultView.getComputedStyle(e);
+  const box = e => {
+    const r = e.getBoundingClientRect();
+    let x = r.x, y = r.y, w = r.width, h = r.height;
+    for (let win = e.ownerDocument.defaultView; win !== window && win.frameElement; win = win.parent) {
+      const f = win.frameElement, fr = f.getBoundingClientRect(), s = styleOf(f);
+      const scale = f.offsetWidth ? fr.width / f.offsetWidth : 1;
+      x = fr.x + (f.clientLeft + parseFloat(s.paddingLeft)) * scale + x * scale;
+      y = fr.y + (f.clientTop + parseFloat(s.paddingTop)) * scale + y * scale;
+      w *= scale;
+      h *= scale;
+    }
+    return {x, y, width: w, height: h};
+  };
+  const inView = e => {
+    const r = e.getBoundingClientRect(), win = e.ownerDocument.defaultView, b = box(e);
+    return r.bottom > 0 && r.right > 0 && r.top < win.innerHeight && r.left < win.innerWidth &&
+      b.y + b.height > 0 && b.x + b.width > 0 && b.y < innerHeight && b.x < innerWidth;
+  };
+  const geometry = () => ({dpr: devicePixelRatio, screenX, screenY, outerWidth, outerHeight, innerWidth, innerHeight,
+    pinchX: visualViewport ? visualViewport.offsetLeft : 0, pinchY: visualViewport ? visualViewport.offsetTop : 0,
+    pinchScale: visualViewport ? visualViewport.scale : 1});
+`
+
+// findScript finds the visible elements matching a query, in the page and
+// the iframes it can reach, innermost first, and scrolls the first into
+// view. Text matches keep only the innermost elements, since an element's
+// ancestors often read the same.
+const findScript = `(q => {` + frameScript + `
+  const visible = e => {
+    const r = e.getBoundingClientRect(), s = styleOf(e);
+    return r.width > 0 && r.height > 0 && s.visibility !== 'hidden' && s.display !== 'none';
+  };
+  const label = e => ((e.getAttribute('aria-label') ||
+    ((e.tagName === 'INPUT' || e.tagName === 'TEXTAREA') ? (e.value || e.placeholder) : '') ||
+    e.innerText || e.getAttribute('title') || e.getAttribute('alt') || '') + '').replace(/\s+/g, ' ').trim();
+  let found;
+  if (q.selector) {
+    found = docs().flatMap(d => [...d.querySelectorAll(q.selector)]).filter(visible);
+  } else {
+    const want = q.text.toLowerCase();
+    found = docs().flatMap(d => [...d.querySelectorAll('a,button,input,select,textarea,label,summary,option,[role],[aria-label],[title],[placeholder],[alt],h1,h2,h3,h4,h5,h6,li,td,th,span,p,div')])
+      .filter(e => visible(e) && label(e).toLowerCase() === want);
+    found = found.filter(e => !found.some(o => o !== e && e.contains(o)));
+  }
+  if (found.length > 0 && !inView(found[0])) {
+    found[0].scrollIntoView({block: 'center', inline: 'center'});
+  }
+  return {
+    elements: found.slice(0, 10).map(e => ({tag: e.tagName.toLowerCase(), text: label(e), ...box(e)})),
+    window: geometry(),
+  };
+})(%s)`
+
+// Find returns the visible elements matching q (at most ten) and the
+// browser window's geometry. The first element is scrolled into view
+// first if it is outside the viewport.
+func (c *Client) Find(ctx context.Context, q Query) ([]Element, Window, error) {
+	arg, err := json.Marshal(q)
+	if err != nil {
+		return nil, Window{}, err
+	}
+	var r struct {
+		Elements []Element `json:"elements"`
+		Window   Window    `json:"window"`
+	}
+	if err := c.Evaluate(ctx, fmt.Sprintf(findScript, arg), &r); err != nil {
+		return nil, Window{}, err
+	}
+	return r.Elements, r.Window, nil
+}
@@ -0,0 +1,295 @@
+package cdp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"time"
+
+	"agentGo/pkg/input"
+	"agentGo/pkg/motion"
+	"agentGo/pkg/secure"
+)
+
+// callTimeout bounds each protocol call made by the driver methods, which
+// take no context.
+const callTimeout = 30 * time.Second
+
+func (c *Client) call(method string, params, result any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	return c.Call(ctx, method, params, result)
+}
+
+// Viewport returns the size of the page's viewport in CSS pixels.
+func (c *Client) Viewport() (float64, float64, error) {
+	var m struct {
+		CSSVisualViewport struct {
+			ClientWidth  float64 `json:"clientWidth"`
+			ClientHeight float64 `json:"clientHeight"`
+		} `json:"cssVisualViewport"`
+	}
+	if err := c.call("Page.getLayoutMetrics", nil, &m); err != nil {
+		return 0, 0, err
+	}
+	v := m.CSSVisualViewport
+	if v.ClientWidth <= 0 || v.ClientHeight <= 0 {
+		return 0, 0, fmt.Errorf("page reports an empty viewport")
+	}
+	return v.ClientWidth, v.ClientHeight, nil
+}
+
+// Capture returns a screenshot of the viewport in device pixels.
+func (c *Client) Capture() (image.Image, error) {
+	var r struct {
+		Data string `json:"data"`
+	}
+	if err := c.call("Page.captureScreenshot", map[string]any{"format": "png"}, &r); err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(r.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	return png.Decode(bytes.NewReader(b))
+}
+
+// WindowTitle returns the page's title.
+func (c *Client) WindowTitle() (string, error) {
+	var title string
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	err := c.Evaluate(ctx, "document.title", &title)
+	return title, err
+}
+
+// focusedFieldJS finds the focused element, inside shadow roots and
+// same-origin frames, and reports whether it is a password field and its
+// box in device pixels of the viewport.
+const focusedFieldJS = `(() => {
+	let el = document.activeElement, dx = 0, dy = 0;
+	for (;;) {
+		if (el && el.shadowRoot && el.shadowRoot.activeElement) {
+			el = el.shadowRoot.activeElement;
+		} else if (el && (el.tagName === "IFRAME" || el.tagName === "FRAME") && el.contentDocument) {
+			const r = el.getBoundingClientRect();
+			dx += r.left; dy += r.top;
+			el = el.contentDocument.activeElement;
+		} else {
+			break;
+		}
+	}
+	if (!el || el === document.body) return {secure: false};
+	const ac = (el.getAttribute("autocomplete") || "").toLowerCase();
+	const style = getComputedStyle(el);
+	const secure = (el.tagName === "INPUT" && el.type === "password") ||
+		ac.includes("password") || ac.startsWith("cc-") ||
+		(style.webkitTextSecurity || "none") !== "none";
+	const r = el.getBoundingClientRect(), s = window.devicePixelRatio || 1;
+	return {secure, x: (r.left + dx) * s, y: (r.top + dy) * s, w: r.width * s, h: r.height * s};
+})()`
+
+// FocusedField implements secure.Prober: password inputs, fields that
+// autocomplete passwords or card details, and text masked with
+// -webkit-text-security are secure.
+func (c *Client) FocusedField() (secure.Field, error) {
+	var r struct {
+		Secure     bool
+		X, Y, W, H float64
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	if err := c.Evaluate(ctx, focusedFieldJS, &r); err != nil {
+		return secure.Field{}, err
+	}
+	f := secure.Field{Secure: r.Secure}
+	if r.Secure {
+		f.Bounds = image.Rect(int(r.X), int(r.Y), int(r.X+r.W+0.5), int(r.Y+r.H+0.5))
+	}
+	return f, nil
+}
+
+// Move moves the pointer to normalized (0-1) viewport coordinates, gliding
+// there if a motion profile is set.
+func (c *Client) Move(normX, normY float64) error {
+	w, h, err := c.Viewport()
+	if err != nil {
+		return err
+	}
+	return c.pointer.Move(normX, normY, func(x, y float64) error {
+		c.pointerMu.Lock()
+		c.x, c.y = x*w, y*h
+		buttons := c.buttons
+		c.pointerMu.Unlock()
+		return c.mouse("mouseMoved", "none", buttons, 0)
+	})
+}
+
+// SetMotion sets how the pointer travels between positions.
+func (c *Client) SetMotion(p motion.Profile) {
+	c.pointer.SetProfile(p)
+}
+
+// Click clicks a mouse button, or turns the wheel for wheelUp and the like,
+// at the current position.
+func (c *Client) Click(button string) error {
+	if dx, dy, ok := input.Wheel(button); ok {
+		c.pointerMu.Lock()
+		x, y := c.x, c.y
+		c.pointerMu.Unlock()
+		return c.call("Input.dispatchMouseEvent", map[string]any{
+			"type": "mouseWheel", "x": x, "y": y, "deltaX": dx * 100, "deltaY": dy * 100,
+		}, nil)
+	}
+	if err := c.MouseDown(button); err != nil {
+		return err
+	}
+	return c.MouseUp(button)
+}
+
+// MouseDown presses and holds a mouse button at the current position.
+func (c *Client) MouseDown(button string) error {
+	name, bit, err := mouseButton(button)
+	if err != nil {
+		return err
+	}
+	c.pointerMu.Lock()
+	c.buttons |= bit
+	buttons := c.buttons
+	c.pointerMu.Unlock()
+	return c.mouse("mousePressed", name, buttons, 1)
+}
+
+// MouseUp releases a mouse button held by MouseDown.
+func (c *Client) MouseUp(button string) error {
+	name, bit, err := mouseButton(button)
+	if err != nil {
+		return err
+	}
+	c.pointerMu.Lock()
+	c.buttons &^= bit
+	buttons := c.buttons
+	c.pointerMu.Unlock()
+	return c.mouse("mouseReleased", name, buttons, 1)
+}
+
+func (c *Client) mouse(typ, button string, buttons, clicks int) error {
+	c.pointerMu.Lock()
+	x, y := c.x, c.y
+	c.pointerMu.Unlock()
+	return c.call("Input.dispatchMouseEvent", map[string]any{
+		"type": typ, "x": x, "y": y, "button": button, "buttons": buttons, "clickCount": clicks,
+	}, nil)
+}
+
+// mouseButton returns the protocol's name and bit for a mouse button.
+func mouseButton(button string) (string, int, error) {
+	switch button {
+	case "", "left":
+		return "left", 1, nil
+	case "right":
+		return "right", 2, nil
+	case "center", "middle":
+		return "middle", 4, nil
+	}
+	return "", 0, fmt.Errorf("unknown mouse button %q", button)
+}
+
+// Type inserts text at the focus, as an input method would.
+func (c *Client) Type(text string) error {
+	return c.call("Input.insertText", map[string]any{"text": text}, nil)
+}
+
+// Modifier bits of key and mouse events.
+var modifierBits = map[string]int{
+	"alt": 1, "lalt": 1, "ralt": 1,
+	"ctrl": 2, "control": 2, "lctrl": 2, "rctrl": 2,
+	"cmd": 4, "command": 4, "super": 4, "win": 4,
+	"shift": 8, "lshift": 8, "rshift": 8,
+}
+
+// keyDef is how the protocol names a key: its DOM key and code values and
+// Windows virtual-key code.
+type keyDef struct {
+	key, code string
+	vk        int
+	text      string
+}
+
+// keys maps the key names used by scripts (robotgo's names) to keys.
+var keys = map[string]keyDef{
+	"enter":     {"Enter", "Enter", 13, "\r"},
+	"return":    {"Enter", "Enter", 13, "\r"},
+	"tab":       {"Tab", "Tab", 9, ""},
+	"escape":    {"Escape", "Escape", 27, ""},
+	"esc":       {"Escape", "Escape", 27, ""},
+	"backspace": {"Backspace", "Backspace", 8, ""},
+	"delete":    {"Delete", "Delete", 46, ""},
+	"insert":    {"Insert", "Insert", 45, ""},
+	"space":     {" ", "Space", 32, " "},
+	"left":      {"ArrowLeft", "ArrowLeft", 37, ""},
+	"up":        {"ArrowUp", "ArrowUp", 38, ""},
+	"right":     {"ArrowRight", "ArrowRight", 39, ""},
+	"down":      {"ArrowDown", "ArrowDown", 40, ""},
+	"home":      {"Home", "Home", 36, ""},
+	"end":       {"End", "End", 35, ""},
+	"pageup":    {"PageUp", "PageUp", 33, ""},
+	"pagedown":  {"PageDown", "PageDown", 34, ""},
+}
+
+func init() {
+	for i := 1; i <= 12; i++ {
+		name := fmt.Sprintf("F%d", i)
+		keys[strings.ToLower(name)] = keyDef{name, name, 111 + i, ""}
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		keys[string(c)] = keyDef{string(c), "Key" + strings.ToUpper(string(c)), int(c - 'a' + 'A'), string(c)}
+	}
+	for c := '0'; c <= '9'; c++ {
+		keys[string(c)] = keyDef{string(c), "Digit" + string(c), int(c), string(c)}
+	}
+}
+
+// KeyTap presses a key or combination such as "enter" or "ctrl+a".
+func (c *Client) KeyTap(key string) error {
+	parts := strings.Split(key, "+")
+	modifiers := 0
+	for _, m := range parts[:len(parts)-1] {
+		bit, ok := modifierBits[strings.ToLower(strings.TrimSpace(m))]
+		if !ok {
+			return fmt.Errorf("unknown modifier %q in %q", m, key)
+		}
+		modifiers |= bit
+	}
+	name := strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))
+	k, ok := keys[name]
+	if !ok {
+		return fmt.Errorf("unknown key %q", name)
+	}
+	if modifiers&8 != 0 && len(k.key) == 1 {
+		k.key = strings.ToUpper(k.key)
+	}
+	down := map[string]any{
+		"type": "keyDown", "key": k.key, "code": k.code,
+		"windowsVirtualKeyCode": k.vk, "modifiers": modifiers,
+	}
+	// Text makes the key type a character; with a shortcut modifier held it
+	// would type instead of triggering the shortcut.
+	if modifiers&^8 == 0 && k.text != "" {
+		down["text"] = k.text
+		if modifiers&8 != 0 {
+			down["text"] = strings.ToUpper(k.text)
+		}
+	}
+	if err := c.call("Input.dispatchKeyEvent", down, nil); err != nil {
+		return err
+	}
+	return c.call("Input.dispatchKeyEvent", map[string]any{
+		"type": "keyUp", "key": k.key, "code": k.code,
+		"windowsVirtualKeyCode": k.vk, "modifiers": modifiers,
+	}, nil)
+}
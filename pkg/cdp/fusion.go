@@ -0,0 +1,54 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Generator answers a prompt about a screenshot, as vision.Client does.
+type Generator interface {
+	Generate(ctx context.Context, prompt string, img image.Image) (string, error)
+}
+
+var indexPattern = regexp.MustCompile(`\[?\b(\d+)\b\]?`)
+
+// pick asks the model which node of the page's outline target is, given
+// the screenshot, and returns it. The answer is an index into the outline
+// rather than coordinates, so the element's box comes from the DOM.
+// errNoElement means the model named none, e.g. for content drawn on a
+// canvas.
+func (l *Locator) pick(ctx context.Context, img image.Image, target string) (Element, Window, error) {
+	outline, win, err := l.Page.Outline(ctx)
+	if err != nil {
+		return Element{}, win, err
+	}
+	if len(outline) == 0 {
+		return Element{}, win, fmt.Errorf("%w: the page lists no elements", errNoElement)
+	}
+	prompt := fmt.Sprintf("The screenshot shows a web page. These are its visible elements, each with an index in brackets, in document order:\n\n%s\n"+
+		"Which element is %s? Use the screenshot to tell apart elements with similar names. Answer with the index alone, e.g. [12]. "+
+		"If the target is not one of the listed elements, for example because it is drawn on a canvas or inside an iframe, answer none.",
+		outline, target)
+	text, err := l.Model.Generate(ctx, prompt, img)
+	if err != nil {
+		return Element{}, win, err
+	}
+	text = strings.TrimSpace(text)
+	m := indexPattern.FindStringSubmatch(text)
+	if m == nil || strings.HasPrefix(strings.ToLower(text), "none") {
+		return Element{}, win, fmt.Errorf("%w: the model found it in no element", errNoElement)
+	}
+	i, _ := strconv.Atoi(m[1])
+	n, ok := outline.Lookup(i)
+	if !ok {
+		return Element{}, win, fmt.Errorf("%w: the model answered [%d] of %d elements", errNoElement, i, len(outline))
+	}
+	if n.Tag == "canvas" || n.Tag == "iframe" {
+		return Element{}, win, fmt.Errorf("%w: the content is in a %s", errNoElement, n.Tag)
+	}
+	return n.Element(), win, nil
+}
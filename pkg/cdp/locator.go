@@ -0,0 +1,166 @@
+package cdp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+
+	"agentGo/pkg/vision"
+)
+
+// Inspector finds elements on and reads text off screenshots, as
+// vision.Client does.
+type Inspector interface {
+	Locate(ctx context.Context, img image.Image, target string) (vision.Point, error)
+	ReadText(ctx context.Context, img image.Image, target string) (string, error)
+	Visible(ctx context.Context, img image.Image, target string) (bool, error)
+}
+
+// Locator resolves script targets to elements of a browser page through the
+// DOM. Targets a query cannot resolve, descriptions that match no element
+// or several, go to Model with the screenshot and an outline of the page,
+// to be answered with an element. Those neither resolves, such as content
+// drawn on a canvas or inside an iframe, go to Fallback to be found by
+// their pixels. It implements the runner's Inspector.
+type Locator struct {
+	Page *Client
+	// Model, if set, picks elements from the page's outline.
+	Model    Generator
+	Fallback Inspector
+	// Screen returns the area of the virtual desktop, in physical pixels,
+	// that the screenshots passed in cover, when the page is driven through
+	// the desktop rather than with Page as the driver. Nil means the
+	// screenshots are of the page's viewport, as Page.Capture takes them.
+	//
+	// The page's position on the screen is worked out from the window
+	// geometry the browser reports, allowing for page and pinch zoom and
+	// assuming its toolbars are at the top (see Window.Screen).
+	Screen func() image.Rectangle
+	// Logf receives a message for each target handed to Fallback. It
+	// defaults to log.Printf.
+	Logf func(format string, args ...any)
+}
+
+// errNoElement is why a target is handed to the fallback.
+var errNoElement = errors.New("no unique element")
+
+// find returns the element target names and the window it is in: the one
+// its query selects, or else the one Model picks.
+func (l *Locator) find(ctx context.Context, img image.Image, target string) (Element, Window, error) {
+	el, win, err := l.query(ctx, target)
+	if err == nil || !errors.Is(err, errNoElement) || l.Model == nil || ParseTarget(target).Selector != "" {
+		return el, win, err
+	}
+	return l.pick(ctx, img, target)
+}
+
+// query returns the element target's query selects.
+func (l *Locator) query(ctx context.Context, target string) (Element, Window, error) {
+	q := ParseTarget(target)
+	if q.Selector == "" && q.Text == "" {
+		return Element{}, Window{}, errNoElement
+	}
+	els, win, err := l.Page.Find(ctx, q)
+	if err != nil {
+		return Element{}, win, err
+	}
+	// A selector picks its first match; text must be unambiguous.
+	if len(els) == 0 || (q.Selector == "" && len(els) > 1) {
+		return Element{}, win, errNoElement
+	}
+	// A title or label naming a canvas or iframe says nothing about where in
+	// it the target is.
+	if q.Selector == "" && (els[0].Tag == "canvas" || els[0].Tag == "iframe") {
+		return Element{}, win, fmt.Errorf("%w: the content is in a %s", errNoElement, els[0].Tag)
+	}
+	return els[0], win, nil
+}
+
+// toImage maps a viewport point to a pixel of img.
+func (l *Locator) toImage(img image.Image, win Window, x, y float64) (vision.Point, bool) {
+	b := img.Bounds()
+	var p vision.Point
+	if l.Screen == nil {
+		if win.InnerWidth <= 0 {
+			return p, false
+		}
+		x, y = win.Visual(x, y)
+		scale := float64(b.Dx()) / win.InnerWidth
+		p = vision.Point{X: float64(b.Min.X) + x*scale, Y: float64(b.Min.Y) + y*scale}
+	} else {
+		screen := l.Screen()
+		if screen.Empty() {
+			return p, false
+		}
+		sx, sy := win.Screen(x, y)
+		sx -= float64(screen.Min.X)
+		sy -= float64(screen.Min.Y)
+		p = vision.Point{
+			X: float64(b.Min.X) + sx*float64(b.Dx())/float64(screen.Dx()),
+			Y: float64(b.Min.Y) + sy*float64(b.Dy())/float64(screen.Dy()),
+		}
+	}
+	return p, p.X >= float64(b.Min.X) && p.X < float64(b.Max.X) && p.Y >= float64(b.Min.Y) && p.Y < float64(b.Max.Y)
+}
+
+func (l *Locator) fallBack(target string, why error) error {
+	if l.Fallback == nil {
+		return fmt.Errorf("%s is not in the page (%v) and there is no vision model to look for it", target, why)
+	}
+	logf := l.Logf
+	if logf == nil {
+		logf = log.Printf
+	}
+	logf("cdp: looking for %s with vision: %v", target, why)
+	return nil
+}
+
+// Locate implements Inspector.
+func (l *Locator) Locate(ctx context.Context, img image.Image, target string) (vision.Point, error) {
+	el, win, err := l.find(ctx, img, target)
+	if err == nil {
+		x, y := el.Center()
+		if p, ok := l.toImage(img, win, x, y); ok {
+			return p, nil
+		}
+		err = fmt.Errorf("%w: the element is outside the screenshot", errNoElement)
+	}
+	if err := l.fallBack(target, err); err != nil {
+		return vision.Point{}, err
+	}
+	return l.Fallback.Locate(ctx, img, target)
+}
+
+// ReadText implements Inspector, reading an element's text from the DOM.
+func (l *Locator) ReadText(ctx context.Context, img image.Image, target string) (string, error) {
+	el, _, err := l.find(ctx, img, target)
+	if err == nil {
+		return el.Text, nil
+	}
+	if err := l.fallBack(target, err); err != nil {
+		return "", err
+	}
+	return l.Fallback.ReadText(ctx, img, target)
+}
+
+// Visible implements Inspector. A CSS selector that matches nothing is
+// definitely not visible; other targets not in the DOM are looked for with
+// Fallback.
+func (l *Locator) Visible(ctx context.Context, img image.Image, target string) (bool, error) {
+	el, win, err := l.find(ctx, img, target)
+	if err == nil {
+		x, y := el.Center()
+		if _, ok := l.toImage(img, win, x, y); ok {
+			return true, nil
+		}
+		err = fmt.Errorf("%w: the element is outside the screenshot", errNoElement)
+	} else if errors.Is(err, errNoElement) && ParseTarget(target).Selector != "" {
+		return false, nil
+	}
+	if err := l.fallBack(target, err); err != nil {
+		return false, err
+	}
+	return l.Fallback.Visible(ctx, img, target)
+}
@@ -0,0 +1,135 @@
+package cdp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Node is an element listed in an outline of the page.
+type Node struct {
+	// Index numbers the node in its outline, from 1.
+	Index int    `json:"-"`
+	Tag   string `json:"tag"`
+	// Role is the ARIA role, or one implied by the tag, e.g. textbox for a
+	// text input.
+	Role string `json:"role"`
+	// Name is what a user would call it: its label, text, placeholder or
+	// alt text.
+	Name string `json:"name"`
+	// Value is the current value of form fields.
+	Value  string  `json:"value,omitempty"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Element returns the node as an Element, whose text is the value of form
+// fields.
+func (n Node) Element() Element {
+	text := n.Name
+	if n.Value != "" {
+		text = n.Value
+	}
+	return Element{Tag: n.Tag, Text: text, X: n.X, Y: n.Y, Width: n.Width, Height: n.Height}
+}
+
+// Outline is a condensed list of the visible elements of a page that a
+// user might act on or refer to, in document order.
+type Outline []Node
+
+// MaxOutline is the most nodes Outline lists, to bound the prompt.
+const MaxOutline = 300
+
+// outlineScript lists the visible elements inside the viewport, including
+// those of the iframes the page can reach. Text is collapsed and cut to 80
+// characters.
+const outlineScript = `(max => {` + frameScript + `
+  const clip = s => { s = (s || '').replace(/\s+/g, ' ').trim(); return s.length > 80 ? s.slice(0, 79) + '…' : s; };
+  const roleOf = e => {
+    const r = e.getAttribute('role');
+    if (r) return r;
+    const t = e.tagName.toLowerCase();
+    if (t === 'a') return 'link';
+    if (t === 'input') {
+      const type = (e.type || 'text').toLowerCase();
+      return {checkbox: 'checkbox', radio: 'radio', button: 'button', submit: 'button', reset: 'button', range: 'slider', search: 'searchbox'}[type] || 'textbox';
+    }
+    if (t === 'textarea') return 'textbox';
+    if (t === 'select') return 'combobox';
+    if (/^h[1-6]$/.test(t)) return 'heading';
+    if (t === 'img') return 'image';
+    if (t === 'li') return 'listitem';
+    if (t === 'td' || t === 'th') return 'cell';
+    return t;
+  };
+  const nameOf = e => {
+    const aria = e.getAttribute('aria-label');
+    if (aria) return clip(aria);
+    const by = e.getAttribute('aria-labelledby');
+    if (by) { const l = e.ownerDocument.getElementById(by); if (l) return clip(l.innerText); }
+    if (e.labels && e.labels.length) return clip(e.labels[0].innerText);
+    if (e.tagName === 'INPUT' || e.tagName === 'TEXTAREA') return clip(e.placeholder || e.name || e.title);
+    if (e.tagName === 'IMG') return clip(e.alt || e.title);
+    return clip(e.innerText || e.title || e.getAttribute('alt'));
+  };
+  const reachable = f => { try { return !!(f.contentDocument && f.contentDocument.documentElement); } catch (err) { return false; } };
+  const nodes = [];
+  for (const e of docs().flatMap(d => [...d.querySelectorAll('a,button,input,select,textarea,summary,label,option,[role],[onclick],[tabindex],[contenteditable=true],h1,h2,h3,h4,h5,h6,img,canvas,iframe,li,td,th')])) {
+    if (nodes.length >= max) break;
+    const r = e.getBoundingClientRect(), s = styleOf(e);
+    if (r.width <= 0 || r.height <= 0 || s.visibility === 'hidden' || s.display === 'none') continue;
+    if (!inView(e)) continue;
+    if (e.type === 'hidden') continue;
+    // The elements of a reachable iframe are listed in its place.
+    if (e.tagName === 'IFRAME' && reachable(e)) continue;
+    const name = nameOf(e), role = roleOf(e);
+    if (!name && !['textbox', 'searchbox', 'combobox', 'checkbox', 'radio', 'slider', 'button', 'canvas', 'iframe'].includes(role)) continue;
+    const node = {tag: e.tagName.toLowerCase(), role, name, ...box(e)};
+    if ('value' in e && role !== 'button' && typeof e.value === 'string') node.value = clip(e.type === 'password' ? '' : e.value);
+    if (e.type === 'checkbox' || e.type === 'radio') node.value = e.checked ? 'checked' : 'unchecked';
+    nodes.push(node);
+  }
+  return {nodes, window: geometry()};
+})(%d)`
+
+// Outline lists the visible elements inside the viewport, at most
+// MaxOutline of them, and the browser window's geometry.
+func (c *Client) Outline(ctx context.Context) (Outline, Window, error) {
+	var r struct {
+		Nodes  []Node `json:"nodes"`
+		Window Window `json:"window"`
+	}
+	if err := c.Evaluate(ctx, fmt.Sprintf(outlineScript, MaxOutline), &r); err != nil {
+		return nil, Window{}, err
+	}
+	for i := range r.Nodes {
+		r.Nodes[i].Index = i + 1
+	}
+	return r.Nodes, r.Window, nil
+}
+
+// String renders the outline one node per line, as `[3] button "Save"`.
+func (o Outline) String() string {
+	var b strings.Builder
+	for _, n := range o {
+		fmt.Fprintf(&b, "[%d] %s", n.Index, n.Role)
+		if n.Name != "" {
+			fmt.Fprintf(&b, " %q", n.Name)
+		}
+		if n.Value != "" {
+			fmt.Fprintf(&b, " value=%q", n.Value)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Lookup returns the node with the given index.
+func (o Outline) Lookup(index int) (Node, bool) {
+	if index < 1 || index > len(o) {
+		return Node{}, false
+	}
+	return o[index-1], true
+}
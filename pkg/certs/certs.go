@@ -0,0 +1,122 @@
+// Package certs provides TLS for the worker API without a certificate
+// authority, for labs: a self-signed certificate kept in the user's config
+// directory, and clients that trust it by its fingerprint.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// validity is how long a self-signed certificate lasts. It is renewed
+// when less than a tenth of that is left.
+const validity = 365 * 24 * time.Hour
+
+// DefaultDir is where the self-signed certificate is kept: the user's
+// agentgo config directory.
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "agentgo")
+}
+
+// SelfSigned returns the self-signed certificate kept in dir as
+// worker-cert.pem and worker-key.pem, creating or renewing it if need be,
+// so that its fingerprint stays the same from run to run. The certificate
+// names localhost, this machine's host name and hosts.
+func SelfSigned(dir string, hosts ...string) (tls.Certificate, error) {
+	certFile, keyFile := filepath.Join(dir, "worker-cert.pem"), filepath.Join(dir, "worker-key.pem")
+	if pair, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil && time.Until(pair.Leaf.NotAfter) > validity/10 {
+		return pair, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	name, _ := os.Hostname()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "agentgo worker " + name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range append([]string{"localhost", "127.0.0.1", "::1", name}, hosts...) {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to save certificate: %w", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to save certificate: %w", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to save certificate: %w", err)
+	}
+	return tls.LoadX509KeyPair(certFile, keyFile)
+}
+
+// Fingerprint returns "sha256:" and the hex digest of a DER certificate.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ErrMismatch is returned when a server's certificate is not the pinned
+// one.
+var ErrMismatch = errors.New("server certificate does not match the pinned fingerprint")
+
+// Pin makes cfg trust the server whose certificate has fingerprint, as
+// Fingerprint formats it, instead of verifying it against certificate
+// authorities: the way to trust a self-signed certificate.
+func Pin(cfg *tls.Config, fingerprint string) {
+	want := strings.ToLower(strings.TrimSpace(fingerprint))
+	if !strings.HasPrefix(want, "sha256:") {
+		want = "sha256:" + want
+	}
+	// Verification is done below instead, against the pin.
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+		if len(raw) == 0 || Fingerprint(raw[0]) != want {
+			return ErrMismatch
+		}
+		return nil
+	}
+}
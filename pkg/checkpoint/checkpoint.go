@@ -0,0 +1,231 @@
+// Package checkpoint saves the state of a run after every step it
+// completes: which step that was, the variables it ran with and the screen
+// as the step left it. A run that crashes or is interrupted can then be
+// resumed from where it stopped instead of starting the whole workflow
+// over.
+package checkpoint
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"agentGo/pkg/frame"
+)
+
+// Run kinds.
+const (
+	KindScript    = "script"
+	KindRecording = "recording"
+	KindAgent     = "agent"
+)
+
+// Checkpoint is a run as of its last completed step.
+type Checkpoint struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Source is the script or recording run, or the agent's task.
+	Source string `json:"source"`
+	// Digest is the SHA-256 of the script or recording, so that a run is
+	// not resumed against a file that has changed since.
+	Digest string `json:"digest,omitempty"`
+	// Vars are the variables the run was given.
+	Vars map[string]string `json:"vars,omitempty"`
+	// Plan is the file an agent's plan is saved in, which it resumes from.
+	Plan string `json:"plan,omitempty"`
+	// Step names the last step completed: where it is in a script, e.g.
+	// "steps[2].row4[1]", the index of a recording's sample, or an agent's
+	// subtask. Steps counts the steps completed.
+	Step  string `json:"step,omitempty"`
+	Steps int    `json:"steps"`
+	// Screenshot is the file in the run's directory showing the screen
+	// after the last completed step.
+	Screenshot string    `json:"screenshot,omitempty"`
+	Started    time.Time `json:"started"`
+	Updated    time.Time `json:"updated"`
+	Finished   bool      `json:"finished"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Store keeps a directory per run under Dir.
+type Store struct {
+	Dir string
+}
+
+// DefaultStore keeps runs in .agentgo/runs.
+var DefaultStore = Store{Dir: filepath.Join(".agentgo", "runs")}
+
+// Run is a run being checkpointed.
+type Run struct {
+	Checkpoint
+	dir string
+	mu  sync.Mutex
+}
+
+// New starts a run of source, of the given kind, with an ID made from the
+// time and a random suffix. If source is a file, its digest is taken.
+func (s Store) New(kind, source string, vars map[string]string) (*Run, error) {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	now := time.Now()
+	r := &Run{Checkpoint: Checkpoint{
+		ID:      now.Format("20060102-150405-") + hex.EncodeToString(suffix),
+		Kind:    kind,
+		Source:  source,
+		Vars:    vars,
+		Started: now,
+	}}
+	if kind != KindAgent {
+		digest, err := Digest(source)
+		if err != nil {
+			return nil, err
+		}
+		r.Digest = digest
+	}
+	r.dir = filepath.Join(s.Dir, r.ID)
+	if err := os.MkdirAll(r.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create run directory: %w", err)
+	}
+	return r, r.Save()
+}
+
+// Load returns the run with the given ID.
+func (s Store) Load(id string) (*Run, error) {
+	if id == "" || filepath.Base(id) != id {
+		return nil, fmt.Errorf("invalid run ID %q", id)
+	}
+	dir := filepath.Join(s.Dir, id)
+	data, err := os.ReadFile(filepath.Join(dir, "checkpoint.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no run %s in %s", id, s.Dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &Run{dir: dir}
+	if err := json.Unmarshal(data, &r.Checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint of run %s: %w", id, err)
+	}
+	return r, nil
+}
+
+// List returns the checkpoints of every run, most recent first.
+func (s Store) List() ([]Checkpoint, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Checkpoint
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if r, err := s.Load(e.Name()); err == nil {
+			list = append(list, r.Checkpoint)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Started.After(list[j].Started) })
+	return list, nil
+}
+
+// Dir returns the run's directory, where anything else it saves, such as
+// an agent's plan, belongs.
+func (r *Run) Dir() string {
+	return r.dir
+}
+
+// Resumable reports why the run cannot be resumed, or nil: because it
+// finished, or its script or recording has changed since.
+func (r *Run) Resumable() error {
+	if r.Finished && r.Error == "" {
+		return fmt.Errorf("run %s already finished", r.ID)
+	}
+	if r.Digest == "" {
+		return nil
+	}
+	digest, err := Digest(r.Source)
+	if err != nil {
+		return err
+	}
+	if digest != r.Digest {
+		return fmt.Errorf("%s has changed since run %s started", r.Source, r.ID)
+	}
+	return nil
+}
+
+// Done records that the step named step completed, leaving the screen as
+// img, which may be nil if it could not be captured.
+func (r *Run) Done(step string, img image.Image) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Step, r.Steps = step, r.Steps+1
+	r.Finished, r.Error = false, ""
+	if img != nil {
+		data, err := frame.EncodePNG(img)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(r.dir, "screen.png"), data); err != nil {
+			return fmt.Errorf("failed to save the screenshot: %w", err)
+		}
+		r.Screenshot = "screen.png"
+	}
+	return r.Save()
+}
+
+// Finish records how the run ended: err nil if it succeeded.
+func (r *Run) Finish(err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Finished, r.Error = true, ""
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r.Save()
+}
+
+// Save writes the checkpoint, after its fields are changed directly.
+func (r *Run) Save() error {
+	r.Updated = time.Now()
+	data, err := json.MarshalIndent(r.Checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(r.dir, "checkpoint.json"), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to save the checkpoint: %w", err)
+	}
+	return nil
+}
+
+// writeFile writes data through a temporary file, so that a crash never
+// leaves half of it. Checkpoints hold variables, which may be private, so
+// only the user can read them.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Digest returns the SHA-256 of the file at path, in hex.
+func Digest(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,62 @@
+package desktop
+
+import (
+	"fmt"
+	"image"
+	"sync"
+
+	"agentGo/pkg/capture"
+	"github.com/kbinani/screenshot"
+)
+
+// Screenshot is the default capture backend: it reads the local screen with
+// kbinani/screenshot.
+type Screenshot struct{}
+
+// Displays implements capture.Backend.
+func (Screenshot) Displays() []Display {
+	return displays()
+}
+
+// Capture implements capture.Backend.
+func (Screenshot) Capture(rect image.Rectangle) (*image.RGBA, error) {
+	return screenshot.CaptureRect(rect)
+}
+
+// CaptureWindow implements capture.Backend. The ID is the process ID of the
+// window's owner, as robotgo numbers windows.
+func (Screenshot) CaptureWindow(id int) (*image.RGBA, error) {
+	r, err := windowBounds(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Empty() {
+		return nil, fmt.Errorf("no window for process %d", id)
+	}
+	return screenshot.CaptureRect(r)
+}
+
+var (
+	backendMu sync.Mutex
+	backend   capture.Backend = Screenshot{}
+)
+
+// UseBackend replaces the capture backend of the local desktop, e.g. with a
+// remote screen or a test fake. A nil backend restores Screenshot. The
+// native and Wayland capture paths chosen with UseCapture only apply to
+// Screenshot.
+func UseBackend(b capture.Backend) {
+	if b == nil {
+		b = Screenshot{}
+	}
+	backendMu.Lock()
+	backend = b
+	backendMu.Unlock()
+}
+
+// Backend returns the capture backend in use.
+func Backend() capture.Backend {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	return backend
+}
@@ -0,0 +1,146 @@
+package desktop
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Buttons is a set of mouse buttons held down.
+type Buttons uint8
+
+// Mouse buttons.
+const (
+	ButtonLeft Buttons = 1 << iota
+	ButtonRight
+	ButtonCenter
+)
+
+// Name returns the button's name as Executor.Click takes it.
+func (b Buttons) Name() string {
+	switch b {
+	case ButtonLeft:
+		return "left"
+	case ButtonRight:
+		return "right"
+	case ButtonCenter:
+		return "center"
+	}
+	return ""
+}
+
+var errButtonsUnsupported = errors.New("reading the mouse buttons is not supported on this platform")
+
+// ButtonState returns the mouse buttons held down right now.
+func ButtonState() (Buttons, error) {
+	return buttonState()
+}
+
+// Click is a click seen by WatchClicks: one, two or three presses of a
+// button in quick succession, or a press held down.
+type Click struct {
+	// Time is when the first press began.
+	Time time.Time
+	// X and Y are where it was pressed, in CursorPos coordinates.
+	X, Y   int
+	Button string
+	// Count is 2 for a double click and 3 for a triple click.
+	Count int
+	// Hold is how long the button was held on the last press.
+	Hold time.Duration
+}
+
+// DoubleClickTime is the longest gap between presses that still counts as
+// part of a double or triple click.
+const DoubleClickTime = 500 * time.Millisecond
+
+// LongPress is how long a press is held before it counts as a press and
+// hold rather than a click.
+const LongPress = 500 * time.Millisecond
+
+// WatchClicks polls the mouse buttons every poll interval and sends each
+// click once it is complete: after the double-click time has passed
+// without another press, or at once for a triple click or a long press.
+// The channel is closed when ctx is done.
+func WatchClicks(ctx context.Context, poll time.Duration) (<-chan Click, error) {
+	if _, err := ButtonState(); err != nil {
+		return nil, err
+	}
+	out := make(chan Click, 16)
+	go func() {
+		defer close(out)
+		var (
+			held     Buttons
+			pending  *Click
+			pressed  time.Time
+			released time.Time
+		)
+		flush := func() {
+			if pending != nil {
+				select {
+				case out <- *pending:
+				case <-ctx.Done():
+				}
+				pending = nil
+			}
+		}
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				if pending != nil {
+					select {
+					case out <- *pending:
+					default:
+					}
+				}
+				return
+			case now := <-ticker.C:
+				state, err := ButtonState()
+				if err != nil {
+					continue
+				}
+				for _, b := range []Buttons{ButtonLeft, ButtonRight, ButtonCenter} {
+					switch {
+					case state&b != 0 && held&b == 0:
+						x, y := CursorPos()
+						if pending != nil && (pending.Button != b.Name() || now.Sub(released) > DoubleClickTime || !near(pending.X, pending.Y, x, y)) {
+							flush()
+						}
+						if pending == nil {
+							pending = &Click{Time: now, X: x, Y: y, Button: b.Name()}
+						}
+						pending.Count++
+						pressed = now
+					case state&b == 0 && held&b != 0 && pending != nil && pending.Button == b.Name():
+						pending.Hold = now.Sub(pressed)
+						released = now
+						if pending.Count >= 3 || pending.Hold >= LongPress {
+							flush()
+						}
+					}
+				}
+				held = state
+				if pending != nil && held == 0 && now.Sub(released) > DoubleClickTime {
+					flush()
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// near reports whether two presses were close enough to make up a double
+// click.
+func near(x1, y1, x2, y2 int) bool {
+	const slop = 4
+	return abs(x1-x2) <= slop && abs(y1-y2) <= slop
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+//go:build darwin && cgo
+
+package desktop
+
+/*
+#cgo LDFLAGS: -framework CoreGraphics
+#include <CoreGraphics/CoreGraphics.h>
+
+static int get_buttons(void) {
+	int mask = 0;
+	if (CGEventSourceButtonState(kCGEventSourceStateCombinedSessionState, kCGMouseButtonLeft)) {
+		mask |= 1;
+	}
+	if (CGEventSourceButtonState(kCGEventSourceStateCombinedSessionState, kCGMouseButtonRight)) {
+		mask |= 2;
+	}
+	if (CGEventSourceButtonState(kCGEventSourceStateCombinedSessionState, kCGMouseButtonCenter)) {
+		mask |= 4;
+	}
+	return mask;
+}
+
+// any_key_down checks the virtual key codes, which all lie below 128.
+static int any_key_down(void) {
+	for (CGKeyCode k = 0; k < 128; k++) {
+		if (CGEventSourceKeyState(kCGEventSourceStateCombinedSessionState, k)) {
+			return 1;
+		}
+	}
+	return 0;
+}
+
+static int key_down(int code) {
+	return CGEventSourceKeyState(kCGEventSourceStateCombinedSessionState, (CGKeyCode)code);
+}
+*/
+import "C"
+
+import "fmt"
+
+func buttonState() (Buttons, error) {
+	return Buttons(C.get_buttons()), nil
+}
+
+func keyHeld() (bool, error) {
+	return C.any_key_down() != 0, nil
+}
+
+// keyCodes maps the key names hotkeys use to macOS virtual key codes, both
+// sides of the modifiers.
+var keyCodes = map[string][]int{
+	"a": {0x00}, "s": {0x01}, "d": {0x02}, "f": {0x03}, "h": {0x04},
+	"g": {0x05}, "z": {0x06}, "x": {0x07}, "c": {0x08}, "v": {0x09},
+	"b": {0x0b}, "q": {0x0c}, "w": {0x0d}, "e": {0x0e}, "r": {0x0f},
+	"y": {0x10}, "t": {0x11}, "1": {0x12}, "2": {0x13}, "3": {0x14},
+	"4": {0x15}, "6": {0x16}, "5": {0x17}, "9": {0x19}, "7": {0x1a},
+	"8": {0x1c}, "0": {0x1d}, "o": {0x1f}, "u": {0x20}, "i": {0x22},
+	"p": {0x23}, "l": {0x25}, "j": {0x26}, "k": {0x28}, "n": {0x2d},
+	"m": {0x2e}, "enter": {0x24}, "tab": {0x30}, "space": {0x31},
+	"backspace": {0x33}, "escape": {0x35}, "esc": {0x35}, "delete": {0x75},
+	"home": {0x73}, "end": {0x77},
+	"cmd": {0x37, 0x36}, "shift": {0x38, 0x3c}, "alt": {0x3a, 0x3d},
+	"ctrl": {0x3b, 0x3e}, "control": {0x3b, 0x3e},
+	"f1": {0x7a}, "f2": {0x78}, "f3": {0x63}, "f4": {0x76}, "f5": {0x60},
+	"f6": {0x61}, "f7": {0x62}, "f8": {0x64}, "f9": {0x65}, "f10": {0x6d},
+	"f11": {0x67}, "f12": {0x6f},
+}
+
+func keyDown(name string) (bool, error) {
+	codes, ok := keyCodes[name]
+	if !ok {
+		return false, fmt.Errorf("unknown key %q", name)
+	}
+	for _, code := range codes {
+		if C.key_down(C.int(code)) != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
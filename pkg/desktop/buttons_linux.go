@@ -0,0 +1,159 @@
+//go:build linux && cgo
+
+package desktop
+
+/*
+#cgo LDFLAGS: -lX11
+#include <stdlib.h>
+#include <X11/Xlib.h>
+
+static Display *buttons_dpy;
+
+// get_buttons returns the X button mask of the pointer, or -1 if there is
+// no X display. The display stays open because it is polled often.
+static int get_buttons(void) {
+	if (buttons_dpy == NULL) {
+		buttons_dpy = XOpenDisplay(NULL);
+		if (buttons_dpy == NULL) {
+			return -1;
+		}
+	}
+	Window root, child;
+	int rx, ry, wx, wy;
+	unsigned int mask;
+	XQueryPointer(buttons_dpy, DefaultRootWindow(buttons_dpy), &root, &child, &rx, &ry, &wx, &wy, &mask);
+	return (int)(mask & (Button1Mask | Button2Mask | Button3Mask));
+}
+
+// get_keys returns 1 if any key is down, 0 if none, or -1 if there is no X
+// display.
+static int get_keys(void) {
+	if (buttons_dpy == NULL) {
+		buttons_dpy = XOpenDisplay(NULL);
+		if (buttons_dpy == NULL) {
+			return -1;
+		}
+	}
+	char keys[32];
+	XQueryKeymap(buttons_dpy, keys);
+	for (int i = 0; i < 32; i++) {
+		if (keys[i]) {
+			return 1;
+		}
+	}
+	return 0;
+}
+
+// get_key returns 1 if the key with the named keysym is down, 0 if it is
+// up, -1 if there is no X display, or -2 if no key has the keysym.
+static int get_key(const char *name) {
+	if (buttons_dpy == NULL) {
+		buttons_dpy = XOpenDisplay(NULL);
+		if (buttons_dpy == NULL) {
+			return -1;
+		}
+	}
+	KeySym sym = XStringToKeysym(name);
+	if (sym == NoSymbol) {
+		return -2;
+	}
+	KeyCode code = XKeysymToKeycode(buttons_dpy, sym);
+	if (code == 0) {
+		return -2;
+	}
+	char keys[32];
+	XQueryKeymap(buttons_dpy, keys);
+	return (keys[code / 8] >> (code % 8)) & 1;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// Xlib calls on one display must not overlap.
+var buttonsMu sync.Mutex
+
+func buttonState() (Buttons, error) {
+	buttonsMu.Lock()
+	mask := C.get_buttons()
+	buttonsMu.Unlock()
+	if mask < 0 {
+		return 0, errors.New("failed to open the X display to read the mouse buttons")
+	}
+	var b Buttons
+	if mask&C.Button1Mask != 0 {
+		b |= ButtonLeft
+	}
+	if mask&C.Button2Mask != 0 {
+		b |= ButtonCenter
+	}
+	if mask&C.Button3Mask != 0 {
+		b |= ButtonRight
+	}
+	return b, nil
+}
+
+func keyHeld() (bool, error) {
+	buttonsMu.Lock()
+	held := C.get_keys()
+	buttonsMu.Unlock()
+	if held < 0 {
+		return false, errors.New("failed to open the X display to read the keyboard")
+	}
+	return held == 1, nil
+}
+
+// keysyms maps the key names hotkeys use to X keysym names, both sides of
+// the modifiers. Letters, digits and other keysym names pass through.
+var keysyms = map[string][]string{
+	"ctrl":      {"Control_L", "Control_R"},
+	"control":   {"Control_L", "Control_R"},
+	"shift":     {"Shift_L", "Shift_R"},
+	"alt":       {"Alt_L", "Alt_R"},
+	"cmd":       {"Super_L", "Super_R"},
+	"super":     {"Super_L", "Super_R"},
+	"win":       {"Super_L", "Super_R"},
+	"escape":    {"Escape"},
+	"esc":       {"Escape"},
+	"enter":     {"Return"},
+	"tab":       {"Tab"},
+	"space":     {"space"},
+	"pause":     {"Pause"},
+	"delete":    {"Delete"},
+	"backspace": {"BackSpace"},
+	"insert":    {"Insert"},
+	"home":      {"Home"},
+	"end":       {"End"},
+}
+
+func keyDown(name string) (bool, error) {
+	syms, ok := keysyms[name]
+	if !ok {
+		syms = []string{name}
+		if len(name) > 1 && name[0] == 'f' {
+			syms = []string{strings.ToUpper(name)}
+		}
+	}
+	for _, sym := range syms {
+		cs := C.CString(sym)
+		buttonsMu.Lock()
+		down := C.get_key(cs)
+		buttonsMu.Unlock()
+		C.free(unsafe.Pointer(cs))
+		switch down {
+		case -1:
+			return false, errors.New("failed to open the X display to read the keyboard")
+		case -2:
+			return false, fmt.Errorf("unknown key %q", name)
+		case 1:
+			return true, nil
+		}
+	}
+	return false, nil
+}
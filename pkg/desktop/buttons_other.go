@@ -0,0 +1,15 @@
+//go:build !windows && !((linux || darwin) && cgo)
+
+package desktop
+
+func buttonState() (Buttons, error) {
+	return 0, errButtonsUnsupported
+}
+
+func keyHeld() (bool, error) {
+	return false, errKeysUnsupported
+}
+
+func keyDown(name string) (bool, error) {
+	return false, errKeysUnsupported
+}
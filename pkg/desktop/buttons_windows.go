@@ -0,0 +1,89 @@
+package desktop
+
+import "fmt"
+
+var (
+	procGetAsyncKeyState = user32.NewProc("GetAsyncKeyState")
+	procGetSystemMetrics = user32.NewProc("GetSystemMetrics")
+)
+
+const (
+	vkLButton    = 0x01
+	vkRButton    = 0x02
+	vkMButton    = 0x04
+	vkXButton2   = 0x06
+	vkLastKey    = 0xfe
+	smSwapButton = 23
+)
+
+func buttonState() (Buttons, error) {
+	down := func(vk uintptr) bool {
+		r, _, _ := procGetAsyncKeyState.Call(vk)
+		return r&0x8000 != 0
+	}
+	// GetAsyncKeyState reports physical buttons, so undo a left-handed
+	// swap to get the logical ones.
+	left, right := uintptr(vkLButton), uintptr(vkRButton)
+	if swapped, _, _ := procGetSystemMetrics.Call(smSwapButton); swapped != 0 {
+		left, right = right, left
+	}
+	var b Buttons
+	if down(left) {
+		b |= ButtonLeft
+	}
+	if down(right) {
+		b |= ButtonRight
+	}
+	if down(vkMButton) {
+		b |= ButtonCenter
+	}
+	return b, nil
+}
+
+func keyHeld() (bool, error) {
+	// Virtual keys up to the X buttons are mouse buttons.
+	for vk := uintptr(vkXButton2 + 1); vk <= vkLastKey; vk++ {
+		if r, _, _ := procGetAsyncKeyState.Call(vk); r&0x8000 != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// virtualKeys maps the key names hotkeys use, other than letters and digits,
+// to Windows virtual keys. The modifier keys cover both sides.
+var virtualKeys = map[string]uintptr{
+	"ctrl": 0x11, "control": 0x11, "shift": 0x10, "alt": 0x12,
+	"cmd": 0x5b, "win": 0x5b, "super": 0x5b,
+	"backspace": 0x08, "tab": 0x09, "enter": 0x0d, "pause": 0x13,
+	"escape": 0x1b, "esc": 0x1b, "space": 0x20, "end": 0x23, "home": 0x24,
+	"insert": 0x2d, "delete": 0x2e,
+	"f1": 0x70, "f2": 0x71, "f3": 0x72, "f4": 0x73, "f5": 0x74, "f6": 0x75,
+	"f7": 0x76, "f8": 0x77, "f9": 0x78, "f10": 0x79, "f11": 0x7a, "f12": 0x7b,
+}
+
+func keyDown(name string) (bool, error) {
+	vk, ok := virtualKeys[name]
+	if !ok && len(name) == 1 {
+		// Letter and digit keys are their upper-case ASCII codes.
+		switch c := name[0]; {
+		case c >= 'a' && c <= 'z':
+			vk, ok = uintptr(c-'a'+'A'), true
+		case c >= '0' && c <= '9':
+			vk, ok = uintptr(c), true
+		}
+	}
+	if !ok {
+		return false, fmt.Errorf("unknown key %q", name)
+	}
+	keys := []uintptr{vk}
+	if vk == 0x5b {
+		keys = append(keys, 0x5c)
+	}
+	for _, k := range keys {
+		if r, _, _ := procGetAsyncKeyState.Call(k); r&0x8000 != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,88 @@
+package desktop
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"sync"
+)
+
+// Capture backends.
+const (
+	// CaptureGeneric captures with kbinani/screenshot, or through the portal
+	// on Wayland. It works everywhere but copies the whole screen through
+	// the OS on every call, which tops out at a few frames per second.
+	CaptureGeneric = "generic"
+	// CaptureNative keeps a stream of the display open with the OS's own
+	// capture API (DXGI desktop duplication on Windows, CGDisplayStream on
+	// macOS, a PipeWire stream on Wayland) and reads its latest frame, for
+	// 30 fps and more. Displays it cannot stream fall back to generic.
+	CaptureNative = "native"
+)
+
+// stream is a native capture of one display.
+type stream interface {
+	// Capture returns the display's latest frame.
+	Capture() (*image.RGBA, error)
+	Close() error
+}
+
+var (
+	captureMu sync.Mutex
+	native    bool
+	streams   = map[int]stream{}
+	// fallen records the displays native capture failed on, so that each
+	// failure is logged and retried once rather than every frame.
+	fallen = map[int]bool{}
+)
+
+// UseCapture selects the capture backend for the local desktop: generic
+// (the default, also for "") or native.
+func UseCapture(backend string) error {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	switch backend {
+	case "", CaptureGeneric:
+		native = false
+		for i, s := range streams {
+			s.Close()
+			delete(streams, i)
+		}
+	case CaptureNative:
+		native = true
+		clear(fallen)
+	default:
+		return fmt.Errorf("unknown capture backend %q (want generic or native)", backend)
+	}
+	return nil
+}
+
+// captureNative captures display i from its native stream, opening it on
+// first use. ok is false if native capture is off or does not work for the
+// display, and the generic backend should be used.
+func captureNative(i int) (img *image.RGBA, ok bool) {
+	captureMu.Lock()
+	defer captureMu.Unlock()
+	if !native || fallen[i] {
+		return nil, false
+	}
+	s := streams[i]
+	if s == nil {
+		var err error
+		if s, err = openStream(i); err != nil {
+			log.Printf("native capture of display %d is unavailable, using generic capture: %v", i, err)
+			fallen[i] = true
+			return nil, false
+		}
+		streams[i] = s
+	}
+	img, err := s.Capture()
+	if err != nil {
+		log.Printf("native capture of display %d failed, using generic capture: %v", i, err)
+		s.Close()
+		delete(streams, i)
+		fallen[i] = true
+		return nil, false
+	}
+	return img, true
+}
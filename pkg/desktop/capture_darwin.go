@@ -0,0 +1,170 @@
+//go:build darwin && cgo
+
+package desktop
+
+/*
+#cgo CFLAGS: -Wno-deprecated-declarations
+#cgo LDFLAGS: -framework CoreGraphics -framework IOSurface -framework CoreFoundation
+#include <CoreGraphics/CoreGraphics.h>
+#include <IOSurface/IOSurface.h>
+#include <dispatch/dispatch.h>
+#include <pthread.h>
+#include <stdlib.h>
+#include <string.h>
+
+// display_stream keeps the latest frame of a CGDisplayStream. The stream
+// delivers frames on its own queue; copy_frame reads whichever is newest.
+typedef struct {
+	CGDisplayStreamRef stream;
+	dispatch_queue_t queue;
+	pthread_mutex_t mu;
+	IOSurfaceRef latest;
+} display_stream;
+
+static CGDirectDisplayID display_id(int i) {
+	CGDirectDisplayID ids[32];
+	uint32_t n = 0;
+	if (CGGetActiveDisplayList(32, ids, &n) != kCGErrorSuccess || i < 0 || (uint32_t)i >= n) {
+		return kCGNullDirectDisplay;
+	}
+	return ids[i];
+}
+
+// start_stream streams display i at its physical resolution, or returns
+// NULL if it cannot.
+static display_stream *start_stream(int i, size_t *width, size_t *height) {
+	CGDirectDisplayID id = display_id(i);
+	if (id == kCGNullDirectDisplay) {
+		return NULL;
+	}
+	CGDisplayModeRef mode = CGDisplayCopyDisplayMode(id);
+	if (mode == NULL) {
+		return NULL;
+	}
+	*width = CGDisplayModeGetPixelWidth(mode);
+	*height = CGDisplayModeGetPixelHeight(mode);
+	CGDisplayModeRelease(mode);
+
+	display_stream *s = calloc(1, sizeof *s);
+	pthread_mutex_init(&s->mu, NULL);
+	s->queue = dispatch_queue_create("agentgo.capture", DISPATCH_QUEUE_SERIAL);
+	s->stream = CGDisplayStreamCreateWithDispatchQueue(id, *width, *height, 'BGRA', NULL, s->queue,
+		^(CGDisplayStreamFrameStatus status, uint64_t time, IOSurfaceRef surface, CGDisplayStreamUpdateRef update) {
+			if (status != kCGDisplayStreamFrameStatusFrameComplete || surface == NULL) {
+				return;
+			}
+			CFRetain(surface);
+			IOSurfaceIncrementUseCount(surface);
+			pthread_mutex_lock(&s->mu);
+			IOSurfaceRef old = s->latest;
+			s->latest = surface;
+			pthread_mutex_unlock(&s->mu);
+			if (old != NULL) {
+				IOSurfaceDecrementUseCount(old);
+				CFRelease(old);
+			}
+		});
+	if (s->stream == NULL || CGDisplayStreamStart(s->stream) != kCGErrorSuccess) {
+		if (s->stream != NULL) {
+			CFRelease(s->stream);
+		}
+		dispatch_release(s->queue);
+		pthread_mutex_destroy(&s->mu);
+		free(s);
+		return NULL;
+	}
+	return s;
+}
+
+// copy_frame copies the latest frame, as BGRA rows, into dst. It returns 0
+// if no frame has arrived yet.
+static int copy_frame(display_stream *s, uint8_t *dst, size_t width, size_t height, size_t stride) {
+	pthread_mutex_lock(&s->mu);
+	IOSurfaceRef surface = s->latest;
+	if (surface == NULL) {
+		pthread_mutex_unlock(&s->mu);
+		return 0;
+	}
+	IOSurfaceLock(surface, kIOSurfaceLockReadOnly, NULL);
+	const uint8_t *base = IOSurfaceGetBaseAddress(surface);
+	size_t pitch = IOSurfaceGetBytesPerRow(surface);
+	size_t w = IOSurfaceGetWidth(surface), h = IOSurfaceGetHeight(surface);
+	if (w > width) {
+		w = width;
+	}
+	if (h > height) {
+		h = height;
+	}
+	for (size_t y = 0; y < h; y++) {
+		memcpy(dst + y * stride, base + y * pitch, w * 4);
+	}
+	IOSurfaceUnlock(surface, kIOSurfaceLockReadOnly, NULL);
+	pthread_mutex_unlock(&s->mu);
+	return 1;
+}
+
+static void stop_stream(display_stream *s) {
+	CGDisplayStreamStop(s->stream);
+	CFRelease(s->stream);
+	// Let any frame handler still running finish before freeing.
+	dispatch_sync(s->queue, ^{});
+	dispatch_release(s->queue);
+	if (s->latest != NULL) {
+		IOSurfaceDecrementUseCount(s->latest);
+		CFRelease(s->latest);
+	}
+	pthread_mutex_destroy(&s->mu);
+	free(s);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"time"
+	"unsafe"
+
+	"agentGo/pkg/frame"
+)
+
+// displayStream streams one display through CGDisplayStream, which needs
+// the same Screen Recording permission as any other capture.
+type displayStream struct {
+	s             *C.display_stream
+	width, height int
+}
+
+func openStream(i int) (stream, error) {
+	var w, h C.size_t
+	s := C.start_stream(C.int(i), &w, &h)
+	if s == nil {
+		return nil, fmt.Errorf("failed to start a display stream for display %d", i)
+	}
+	return &displayStream{s: s, width: int(w), height: int(h)}, nil
+}
+
+// Capture implements stream.
+func (d *displayStream) Capture() (*image.RGBA, error) {
+	img := frame.Get(image.Rect(0, 0, d.width, d.height))
+	// The stream delivers its first frame shortly after starting.
+	deadline := time.Now().Add(2 * time.Second)
+	for C.copy_frame(d.s, (*C.uint8_t)(unsafe.Pointer(&img.Pix[0])), C.size_t(d.width), C.size_t(d.height), C.size_t(img.Stride)) == 0 {
+		if time.Now().After(deadline) {
+			frame.Put(img)
+			return nil, errors.New("the display stream delivered no frames")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for p := 0; p < len(img.Pix); p += 4 {
+		img.Pix[p], img.Pix[p+2], img.Pix[p+3] = img.Pix[p+2], img.Pix[p], 255
+	}
+	return img, nil
+}
+
+// Close implements stream.
+func (d *displayStream) Close() error {
+	C.stop_stream(d.s)
+	return nil
+}
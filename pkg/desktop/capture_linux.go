@@ -0,0 +1,26 @@
+package desktop
+
+import (
+	"errors"
+
+	"agentGo/pkg/wayland"
+)
+
+// openStream streams display i from the Wayland screen cast, sharing the
+// session generic capture uses so the user approves it only once. On X11
+// the generic backend already reads the screen through shared memory.
+func openStream(i int) (stream, error) {
+	if !wayland.Session() {
+		return nil, errors.New("native capture on Linux streams from PipeWire, which needs a Wayland session")
+	}
+	screenCastMu.Lock()
+	defer screenCastMu.Unlock()
+	if screenCast == nil {
+		sc, err := wayland.Start()
+		if err != nil {
+			return nil, err
+		}
+		screenCast = sc
+	}
+	return screenCast.Stream()
+}
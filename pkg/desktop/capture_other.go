@@ -0,0 +1,9 @@
+//go:build !windows && !linux && !(darwin && cgo)
+
+package desktop
+
+import "errors"
+
+func openStream(i int) (stream, error) {
+	return nil, errors.New("native capture is not supported on this platform")
+}
@@ -0,0 +1,318 @@
+package desktop
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"syscall"
+	"unsafe"
+
+	"agentGo/pkg/frame"
+)
+
+// Native capture on Windows uses DXGI desktop duplication: the compositor
+// hands over each new desktop image as a GPU texture, which is copied to a
+// CPU-readable staging texture only when a frame is asked for.
+
+var (
+	d3d11                 = syscall.NewLazyDLL("d3d11.dll")
+	procD3D11CreateDevice = d3d11.NewProc("D3D11CreateDevice")
+)
+
+var (
+	iidIDXGIDevice     = syscall.GUID{Data1: 0x54ec77fa, Data2: 0x1377, Data3: 0x44e6, Data4: [8]byte{0x8c, 0x32, 0x88, 0xfd, 0x5f, 0x44, 0xc8, 0x4c}}
+	iidIDXGIOutput1    = syscall.GUID{Data1: 0x00cddea8, Data2: 0x939b, Data3: 0x4b83, Data4: [8]byte{0xa3, 0x40, 0xa6, 0x85, 0x22, 0x66, 0x66, 0xcc}}
+	iidID3D11Texture2D = syscall.GUID{Data1: 0x6f15aaf2, Data2: 0xd208, Data3: 0x4e89, Data4: [8]byte{0x9a, 0xb4, 0x48, 0x95, 0x35, 0xd3, 0x4f, 0x9c}}
+)
+
+const (
+	d3dDriverTypeHardware = 1
+	d3d11SDKVersion       = 7
+	dxgiFormatB8G8R8A8    = 87
+	d3d11UsageStaging     = 3
+	d3d11CPUAccessRead    = 0x20000
+	d3d11MapRead          = 1
+	dxgiModeRotationIdent = 1
+
+	dxgiErrorNotFound    = 0x887a0002
+	dxgiErrorAccessLost  = 0x887a0026
+	dxgiErrorWaitTimeout = 0x887a0027
+)
+
+// Vtable indexes of the COM methods used.
+const (
+	methodQueryInterface = 0
+	methodRelease        = 2
+
+	dxgiDeviceGetAdapter  = 7
+	dxgiAdapterEnumOutput = 7
+	dxgiOutputGetDesc     = 7
+	dxgiOutputDuplicate   = 22
+
+	duplicationGetDesc      = 7
+	duplicationAcquireFrame = 8
+	duplicationReleaseFrame = 14
+
+	deviceCreateTexture2D = 5
+
+	contextMap          = 14
+	contextUnmap        = 15
+	contextCopyResource = 47
+)
+
+// comObject is a COM interface pointer: a pointer to its vtable.
+type comObject struct {
+	vtbl *[64]uintptr
+}
+
+func (o *comObject) call(method int, args ...uintptr) uint32 {
+	r, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	return uint32(r)
+}
+
+func (o *comObject) queryInterface(iid *syscall.GUID) (*comObject, error) {
+	var out *comObject
+	if hr := o.call(methodQueryInterface, uintptr(unsafe.Pointer(iid)), uintptr(unsafe.Pointer(&out))); failed(hr) {
+		return nil, hresultError("QueryInterface", hr)
+	}
+	return out, nil
+}
+
+func (o *comObject) release() {
+	if o != nil {
+		o.call(methodRelease)
+	}
+}
+
+func failed(hr uint32) bool {
+	return int32(hr) < 0
+}
+
+func hresultError(op string, hr uint32) error {
+	return fmt.Errorf("%s failed with HRESULT %#08x", op, hr)
+}
+
+type outputDesc struct {
+	DeviceName [32]uint16
+	Desktop    rect
+	Attached   int32
+	Rotation   uint32
+	Monitor    uintptr
+}
+
+type duplicationDesc struct {
+	Width, Height    uint32
+	RefreshRate      [2]uint32
+	Format           uint32
+	ScanlineOrdering uint32
+	Scaling          uint32
+	Rotation         uint32
+	InSystemMemory   int32
+}
+
+type frameInfo struct {
+	LastPresentTime     int64
+	LastMouseUpdateTime int64
+	AccumulatedFrames   uint32
+	RectsCoalesced      int32
+	ProtectedMasked     int32
+	PointerX, PointerY  int32
+	PointerVisible      int32
+	MetadataSize        uint32
+	PointerShapeSize    uint32
+}
+
+type texture2DDesc struct {
+	Width, Height  uint32
+	MipLevels      uint32
+	ArraySize      uint32
+	Format         uint32
+	SampleCount    uint32
+	SampleQuality  uint32
+	Usage          uint32
+	BindFlags      uint32
+	CPUAccessFlags uint32
+	MiscFlags      uint32
+}
+
+type mappedSubresource struct {
+	Data       *byte
+	RowPitch   uint32
+	DepthPitch uint32
+}
+
+// duplication streams one display through DXGI desktop duplication.
+type duplication struct {
+	bounds  image.Rectangle
+	device  *comObject
+	context *comObject
+	output  *comObject
+	dup     *comObject
+	staging *comObject
+	width   int
+	height  int
+	// last is the previous frame, returned again when the desktop has not
+	// changed since.
+	last *image.RGBA
+}
+
+func openStream(i int) (stream, error) {
+	d, err := LookupDisplay(i)
+	if err != nil {
+		return nil, err
+	}
+	s := &duplication{bounds: d.Bounds}
+	if err := s.open(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// open creates the device and duplicates the output showing s.bounds. Only
+// outputs on the default adapter can be duplicated.
+func (s *duplication) open() error {
+	if err := procD3D11CreateDevice.Find(); err != nil {
+		return err
+	}
+	hr, _, _ := procD3D11CreateDevice.Call(0, d3dDriverTypeHardware, 0, 0, 0, 0, d3d11SDKVersion,
+		uintptr(unsafe.Pointer(&s.device)), 0, uintptr(unsafe.Pointer(&s.context)))
+	if failed(uint32(hr)) {
+		return hresultError("D3D11CreateDevice", uint32(hr))
+	}
+	dxgiDevice, err := s.device.queryInterface(&iidIDXGIDevice)
+	if err != nil {
+		return err
+	}
+	defer dxgiDevice.release()
+	var adapter *comObject
+	if hr := dxgiDevice.call(dxgiDeviceGetAdapter, uintptr(unsafe.Pointer(&adapter))); failed(hr) {
+		return hresultError("IDXGIDevice::GetAdapter", hr)
+	}
+	defer adapter.release()
+
+	for n := 0; ; n++ {
+		var output *comObject
+		hr := adapter.call(dxgiAdapterEnumOutput, uintptr(n), uintptr(unsafe.Pointer(&output)))
+		if hr == dxgiErrorNotFound {
+			return errors.New("the display is not attached to the default graphics adapter")
+		}
+		if failed(hr) {
+			return hresultError("IDXGIAdapter::EnumOutputs", hr)
+		}
+		var desc outputDesc
+		output.call(dxgiOutputGetDesc, uintptr(unsafe.Pointer(&desc)))
+		r := image.Rect(int(desc.Desktop.Left), int(desc.Desktop.Top), int(desc.Desktop.Right), int(desc.Desktop.Bottom))
+		if r != s.bounds {
+			output.release()
+			continue
+		}
+		s.output, err = output.queryInterface(&iidIDXGIOutput1)
+		output.release()
+		if err != nil {
+			return fmt.Errorf("desktop duplication needs Windows 8 or later: %w", err)
+		}
+		return s.duplicate()
+	}
+}
+
+// duplicate starts duplicating the output and creates a staging texture of
+// its size. It is called again when access to the desktop is lost, as when
+// a UAC prompt or the lock screen switches desktops.
+func (s *duplication) duplicate() error {
+	s.dup.release()
+	s.staging.release()
+	s.dup, s.staging = nil, nil
+	if hr := s.output.call(dxgiOutputDuplicate, uintptr(unsafe.Pointer(s.device)), uintptr(unsafe.Pointer(&s.dup))); failed(hr) {
+		return hresultError("IDXGIOutput1::DuplicateOutput", hr)
+	}
+	var desc duplicationDesc
+	s.dup.call(duplicationGetDesc, uintptr(unsafe.Pointer(&desc)))
+	if desc.Rotation > dxgiModeRotationIdent {
+		return errors.New("rotated displays are not supported")
+	}
+	s.width, s.height = int(desc.Width), int(desc.Height)
+	tex := texture2DDesc{
+		Width:          desc.Width,
+		Height:         desc.Height,
+		MipLevels:      1,
+		ArraySize:      1,
+		Format:         dxgiFormatB8G8R8A8,
+		SampleCount:    1,
+		Usage:          d3d11UsageStaging,
+		CPUAccessFlags: d3d11CPUAccessRead,
+	}
+	if hr := s.device.call(deviceCreateTexture2D, uintptr(unsafe.Pointer(&tex)), 0, uintptr(unsafe.Pointer(&s.staging))); failed(hr) {
+		return hresultError("ID3D11Device::CreateTexture2D", hr)
+	}
+	return nil
+}
+
+// Capture implements stream.
+func (s *duplication) Capture() (*image.RGBA, error) {
+	var info frameInfo
+	var resource *comObject
+	// The first frame can take a moment to arrive; after that, no new
+	// frame means the desktop has not changed.
+	timeout := uintptr(0)
+	if s.last == nil {
+		timeout = 500
+	}
+	hr := s.dup.call(duplicationAcquireFrame, timeout, uintptr(unsafe.Pointer(&info)), uintptr(unsafe.Pointer(&resource)))
+	switch {
+	case hr == dxgiErrorWaitTimeout && s.last != nil:
+		return s.copyLast(), nil
+	case hr == dxgiErrorAccessLost:
+		if err := s.duplicate(); err != nil {
+			return nil, err
+		}
+		s.last = nil
+		return s.Capture()
+	case failed(hr):
+		return nil, hresultError("IDXGIOutputDuplication::AcquireNextFrame", hr)
+	}
+	defer s.dup.call(duplicationReleaseFrame)
+	defer resource.release()
+	tex, err := resource.queryInterface(&iidID3D11Texture2D)
+	if err != nil {
+		return nil, err
+	}
+	defer tex.release()
+	s.context.call(contextCopyResource, uintptr(unsafe.Pointer(s.staging)), uintptr(unsafe.Pointer(tex)))
+
+	var mapped mappedSubresource
+	if hr := s.context.call(contextMap, uintptr(unsafe.Pointer(s.staging)), 0, d3d11MapRead, 0, uintptr(unsafe.Pointer(&mapped))); failed(hr) {
+		return nil, hresultError("ID3D11DeviceContext::Map", hr)
+	}
+	defer s.context.call(contextUnmap, uintptr(unsafe.Pointer(s.staging)), 0)
+	pitch := int(mapped.RowPitch)
+	src := unsafe.Slice(mapped.Data, pitch*s.height)
+	if s.last == nil || s.last.Rect.Dx() != s.width || s.last.Rect.Dy() != s.height {
+		s.last = image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+	}
+	for y := 0; y < s.height; y++ {
+		row := src[y*pitch : y*pitch+4*s.width]
+		dst := s.last.Pix[y*s.last.Stride:]
+		for x := 0; x < 4*s.width; x += 4 {
+			dst[x], dst[x+1], dst[x+2], dst[x+3] = row[x+2], row[x+1], row[x], 255
+		}
+	}
+	return s.copyLast(), nil
+}
+
+// copyLast returns a copy of the last frame for the caller to keep.
+func (s *duplication) copyLast() *image.RGBA {
+	img := frame.Get(s.last.Rect)
+	copy(img.Pix, s.last.Pix)
+	return img
+}
+
+// Close implements stream.
+func (s *duplication) Close() error {
+	s.staging.release()
+	s.dup.release()
+	s.output.release()
+	s.context.release()
+	s.device.release()
+	return nil
+}
@@ -0,0 +1,72 @@
+package desktop
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// CursorImage is the mouse cursor as currently shown on screen.
+type CursorImage struct {
+	// Image is the cursor bitmap with premultiplied alpha.
+	Image *image.RGBA
+	// Hotspot is the pixel of Image that marks the pointer position.
+	Hotspot image.Point
+	// Position is the pointer position in virtual-desktop coordinates.
+	Position image.Point
+	// Scale is the number of Image pixels per desktop unit, e.g. 2 for a
+	// Retina cursor.
+	Scale float64
+}
+
+var errCursorUnsupported = errors.New("capturing the cursor image is not supported on this platform")
+
+// Cursor returns the current cursor image and position. It returns nil
+// without an error when the cursor is hidden.
+func Cursor() (*CursorImage, error) {
+	return cursor()
+}
+
+// DrawCursor composites c onto dst, an image of the desktop area starting at
+// origin with scale image pixels per desktop unit.
+func DrawCursor(dst *image.RGBA, c *CursorImage, origin image.Point, scale float64) {
+	if c == nil || c.Image == nil {
+		return
+	}
+	if c.Scale == 0 {
+		c.Scale = 1
+	}
+	f := scale / c.Scale
+	at := image.Pt(
+		int(float64(c.Position.X-origin.X)*scale-float64(c.Hotspot.X)*f),
+		int(float64(c.Position.Y-origin.Y)*scale-float64(c.Hotspot.Y)*f),
+	)
+	src := c.Image
+	if f != 1 {
+		b := src.Bounds()
+		scaled := image.NewRGBA(image.Rect(0, 0, int(float64(b.Dx())*f+0.5), int(float64(b.Dy())*f+0.5)))
+		scaleInto(scaled, scaled.Bounds(), src)
+		src = scaled
+	}
+	r := image.Rectangle{Min: at, Max: at.Add(src.Bounds().Size())}
+	draw.Draw(dst, r, src, src.Bounds().Min, draw.Over)
+}
+
+// CaptureDisplayWithCursor captures display i with the real cursor drawn in,
+// since most capture APIs leave it out.
+func CaptureDisplayWithCursor(i int) (*image.RGBA, error) {
+	d, err := LookupDisplay(i)
+	if err != nil {
+		return nil, err
+	}
+	img, err := CaptureDisplay(i)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Cursor()
+	if err != nil {
+		return nil, err
+	}
+	DrawCursor(img, c, d.Bounds.Min, float64(img.Bounds().Dx())/float64(d.Bounds.Dx()))
+	return img, nil
+}
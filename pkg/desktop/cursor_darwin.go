@@ -0,0 +1,114 @@
+//go:build darwin && cgo
+
+package desktop
+
+/*
+#cgo CFLAGS: -x objective-c
+#cgo LDFLAGS: -framework AppKit
+#include <stdlib.h>
+#import <AppKit/AppKit.h>
+
+typedef struct {
+	double x, y, xhot, yhot, scale;
+	int width, height;
+	unsigned char *pixels;
+} cursor_image;
+
+// get_cursor renders the current system cursor into an RGBA buffer with
+// premultiplied alpha. Returns 0 on success.
+static int get_cursor(cursor_image *c) {
+	@autoreleasepool {
+		NSCursor *cur = [NSCursor currentSystemCursor];
+		if (cur == nil) {
+			return 1;
+		}
+		NSImage *img = [cur image];
+		CGImageRef cg = [img CGImageForProposedRect:NULL context:nil hints:nil];
+		if (cg == NULL) {
+			return 1;
+		}
+		size_t w = CGImageGetWidth(cg), h = CGImageGetHeight(cg);
+		c->pixels = calloc(w * h, 4);
+		CGColorSpaceRef cs = CGColorSpaceCreateDeviceRGB();
+		CGContextRef ctx = CGBitmapContextCreate(c->pixels, w, h, 8, w * 4, cs,
+			kCGImageAlphaPremultipliedLast | kCGBitmapByteOrder32Big);
+		CGContextDrawImage(ctx, CGRectMake(0, 0, w, h), cg);
+		CGContextRelease(ctx);
+		CGColorSpaceRelease(cs);
+
+		c->width = (int)w;
+		c->height = (int)h;
+		c->scale = [img size].width > 0 ? w / [img size].width : 1;
+		NSPoint hot = [cur hotSpot];
+		c->xhot = hot.x * c->scale;
+		c->yhot = hot.y * c->scale;
+
+		// mouseLocation has its origin at the bottom left of the main screen.
+		NSPoint loc = [NSEvent mouseLocation];
+		CGFloat top = NSMaxY([[[NSScreen screens] objectAtIndex:0] frame]);
+		c->x = loc.x;
+		c->y = top - loc.y;
+	}
+	return 0;
+}
+// cursor_shape compares the current system cursor with the standard
+// cursors by image, since currentSystemCursor returns a fresh object.
+// Returns 0 unknown, 1 arrow, 2 ibeam, 3 hand, 4 crosshair, 5 resize,
+// 6 not allowed, 7 move.
+static int cursor_shape(void) {
+	@autoreleasepool {
+		NSCursor *cur = [NSCursor currentSystemCursor];
+		if (cur == nil) {
+			return 0;
+		}
+		NSData *data = [[cur image] TIFFRepresentation];
+		NSArray *standard = @[
+			[NSCursor arrowCursor], [NSCursor IBeamCursor], [NSCursor pointingHandCursor],
+			[NSCursor crosshairCursor], [NSCursor resizeLeftRightCursor], [NSCursor resizeUpDownCursor],
+			[NSCursor operationNotAllowedCursor], [NSCursor openHandCursor], [NSCursor closedHandCursor],
+		];
+		int shapes[] = {1, 2, 3, 4, 5, 5, 6, 7, 7};
+		for (NSUInteger i = 0; i < [standard count]; i++) {
+			NSCursor *c = [standard objectAtIndex:i];
+			if (NSEqualPoints([c hotSpot], [cur hotSpot]) && [[[c image] TIFFRepresentation] isEqualToData:data]) {
+				return shapes[i];
+			}
+		}
+	}
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"unsafe"
+)
+
+func cursor() (*CursorImage, error) {
+	var c C.cursor_image
+	if C.get_cursor(&c) != 0 {
+		return nil, errors.New("failed to read the system cursor")
+	}
+	defer C.free(unsafe.Pointer(c.pixels))
+
+	w, h := int(c.width), int(c.height)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	copy(img.Pix, unsafe.Slice((*byte)(unsafe.Pointer(c.pixels)), w*h*4))
+	return &CursorImage{
+		Image:    img,
+		Hotspot:  image.Pt(int(c.xhot), int(c.yhot)),
+		Position: image.Pt(int(c.x), int(c.y)),
+		Scale:    float64(c.scale),
+	}, nil
+}
+
+var darwinShapes = []CursorShape{ShapeUnknown, ShapeArrow, ShapeIBeam, ShapeHand, ShapeCrosshair, ShapeResize, ShapeNotAllowed, ShapeMove}
+
+// cursorShape matches the system cursor against AppKit's standard cursors.
+// The spinning wait cursor is drawn by the window server and reads as
+// unknown.
+func cursorShape() (CursorShape, error) {
+	return darwinShapes[C.cursor_shape()], nil
+}
@@ -0,0 +1,115 @@
+//go:build linux && cgo
+
+package desktop
+
+/*
+#cgo LDFLAGS: -lX11 -lXfixes
+#include <stdlib.h>
+#include <string.h>
+#include <X11/Xlib.h>
+#include <X11/extensions/Xfixes.h>
+
+typedef struct {
+	int x, y, width, height, xhot, yhot;
+	unsigned int *pixels;
+} cursor_image;
+
+// get_cursor copies the current cursor image from the XFixes extension.
+// Returns 0 on success, 1 if there is no X display, 2 without XFixes.
+static int get_cursor(cursor_image *c) {
+	Display *dpy = XOpenDisplay(NULL);
+	if (dpy == NULL) {
+		return 1;
+	}
+	int event_base, error_base;
+	if (!XFixesQueryExtension(dpy, &event_base, &error_base)) {
+		XCloseDisplay(dpy);
+		return 2;
+	}
+	XFixesCursorImage *img = XFixesGetCursorImage(dpy);
+	if (img == NULL) {
+		XCloseDisplay(dpy);
+		return 2;
+	}
+	c->x = img->x;
+	c->y = img->y;
+	c->width = img->width;
+	c->height = img->height;
+	c->xhot = img->xhot;
+	c->yhot = img->yhot;
+	c->pixels = malloc(sizeof(unsigned int) * img->width * img->height);
+	// XFixes pixels are unsigned long (64-bit on LP64) premultiplied ARGB.
+	for (int i = 0; i < img->width * img->height; i++) {
+		c->pixels[i] = (unsigned int)img->pixels[i];
+	}
+	XFree(img);
+	XCloseDisplay(dpy);
+	return 0;
+}
+// get_cursor_name returns the theme name of the current cursor, or NULL if
+// it has none or XFixes is unavailable. The caller frees the result.
+static char *get_cursor_name(void) {
+	Display *dpy = XOpenDisplay(NULL);
+	if (dpy == NULL) {
+		return NULL;
+	}
+	int event_base, error_base;
+	char *name = NULL;
+	if (XFixesQueryExtension(dpy, &event_base, &error_base)) {
+		XFixesCursorImage *img = XFixesGetCursorImage(dpy);
+		if (img != NULL) {
+			if (img->name != NULL && img->name[0] != '\0') {
+				name = strdup(img->name);
+			}
+			XFree(img);
+		}
+	}
+	XCloseDisplay(dpy);
+	return name;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"unsafe"
+)
+
+func cursor() (*CursorImage, error) {
+	var c C.cursor_image
+	switch C.get_cursor(&c) {
+	case 1:
+		return nil, errors.New("failed to open the X display to read the cursor")
+	case 2:
+		return nil, errors.New("the X server does not support XFixes cursor images")
+	}
+	defer C.free(unsafe.Pointer(c.pixels))
+
+	w, h := int(c.width), int(c.height)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	pixels := unsafe.Slice((*uint32)(unsafe.Pointer(c.pixels)), w*h)
+	for i, p := range pixels {
+		img.Pix[4*i] = uint8(p >> 16)
+		img.Pix[4*i+1] = uint8(p >> 8)
+		img.Pix[4*i+2] = uint8(p)
+		img.Pix[4*i+3] = uint8(p >> 24)
+	}
+	return &CursorImage{
+		Image:    img,
+		Hotspot:  image.Pt(int(c.xhot), int(c.yhot)),
+		Position: image.Pt(int(c.x), int(c.y)),
+		Scale:    1,
+	}, nil
+}
+
+// cursorShape uses the cursor's theme name, which toolkits set when they
+// pick a named cursor such as "text" or "pointer".
+func cursorShape() (CursorShape, error) {
+	name := C.get_cursor_name()
+	if name == nil {
+		return ShapeUnknown, nil
+	}
+	defer C.free(unsafe.Pointer(name))
+	return shapeFromName(C.GoString(name)), nil
+}
@@ -0,0 +1,7 @@
+//go:build !windows && !((linux || darwin) && cgo)
+
+package desktop
+
+func cursor() (*CursorImage, error) {
+	return nil, errCursorUnsupported
+}
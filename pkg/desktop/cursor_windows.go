@@ -0,0 +1,171 @@
+package desktop
+
+import (
+	"errors"
+	"image"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	gdi32 = syscall.NewLazyDLL("gdi32.dll")
+
+	procGetCursorInfo      = user32.NewProc("GetCursorInfo")
+	procGetIconInfo        = user32.NewProc("GetIconInfo")
+	procDrawIconEx         = user32.NewProc("DrawIconEx")
+	procGetDC              = user32.NewProc("GetDC")
+	procReleaseDC          = user32.NewProc("ReleaseDC")
+	procCreateCompatibleDC = gdi32.NewProc("CreateCompatibleDC")
+	procCreateDIBSection   = gdi32.NewProc("CreateDIBSection")
+	procSelectObject       = gdi32.NewProc("SelectObject")
+	procDeleteObject       = gdi32.NewProc("DeleteObject")
+	procDeleteDC           = gdi32.NewProc("DeleteDC")
+	procGetObjectW         = gdi32.NewProc("GetObjectW")
+)
+
+const (
+	cursorShowing = 1
+	diNormal      = 3
+)
+
+type cursorInfo struct {
+	Size   uint32
+	Flags  uint32
+	Cursor uintptr
+	Pos    point
+}
+
+type iconInfo struct {
+	Icon     int32
+	XHotspot uint32
+	YHotspot uint32
+	Mask     uintptr
+	Color    uintptr
+}
+
+type bitmap struct {
+	Type       int32
+	Width      int32
+	Height     int32
+	WidthBytes int32
+	Planes     uint16
+	BitsPixel  uint16
+	Bits       uintptr
+}
+
+type bitmapInfoHeader struct {
+	Size          uint32
+	Width         int32
+	Height        int32
+	Planes        uint16
+	BitCount      uint16
+	Compression   uint32
+	SizeImage     uint32
+	XPelsPerMeter int32
+	YPelsPerMeter int32
+	ClrUsed       uint32
+	ClrImportant  uint32
+}
+
+// cursor renders the current cursor twice, over black and over white, and
+// recovers its alpha from the difference. This also handles monochrome and
+// colour cursors that have no alpha channel of their own.
+func cursor() (*CursorImage, error) {
+	info := cursorInfo{Size: uint32(unsafe.Sizeof(cursorInfo{}))}
+	if ok, _, err := procGetCursorInfo.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return nil, err
+	}
+	if info.Flags&cursorShowing == 0 || info.Cursor == 0 {
+		return nil, nil
+	}
+
+	var icon iconInfo
+	if ok, _, err := procGetIconInfo.Call(info.Cursor, uintptr(unsafe.Pointer(&icon))); ok == 0 {
+		return nil, err
+	}
+	defer func() {
+		if icon.Mask != 0 {
+			procDeleteObject.Call(icon.Mask)
+		}
+		if icon.Color != 0 {
+			procDeleteObject.Call(icon.Color)
+		}
+	}()
+	var bm bitmap
+	w, h := 32, 32
+	if icon.Color != 0 {
+		procGetObjectW.Call(icon.Color, unsafe.Sizeof(bm), uintptr(unsafe.Pointer(&bm)))
+		w, h = int(bm.Width), int(bm.Height)
+	} else if icon.Mask != 0 {
+		// A monochrome cursor's mask holds the AND and XOR masks stacked.
+		procGetObjectW.Call(icon.Mask, unsafe.Sizeof(bm), uintptr(unsafe.Pointer(&bm)))
+		w, h = int(bm.Width), int(bm.Height)/2
+	}
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("cursor has no size")
+	}
+
+	screen, _, _ := procGetDC.Call(0)
+	defer procReleaseDC.Call(0, screen)
+	dc, _, _ := procCreateCompatibleDC.Call(screen)
+	if dc == 0 {
+		return nil, errors.New("failed to create a device context for the cursor")
+	}
+	defer procDeleteDC.Call(dc)
+
+	header := bitmapInfoHeader{
+		Size:     uint32(unsafe.Sizeof(bitmapInfoHeader{})),
+		Width:    int32(w),
+		Height:   -int32(h), // top-down rows
+		Planes:   1,
+		BitCount: 32,
+	}
+	var bits unsafe.Pointer
+	dib, _, _ := procCreateDIBSection.Call(dc, uintptr(unsafe.Pointer(&header)), 0, uintptr(unsafe.Pointer(&bits)), 0, 0)
+	if dib == 0 || bits == nil {
+		return nil, errors.New("failed to create a bitmap for the cursor")
+	}
+	defer procDeleteObject.Call(dib)
+	old, _, _ := procSelectObject.Call(dc, dib)
+	defer procSelectObject.Call(dc, old)
+
+	pixels := unsafe.Slice((*byte)(bits), w*h*4)
+	render := func(background byte) []byte {
+		for i := range pixels {
+			pixels[i] = background
+		}
+		procDrawIconEx.Call(dc, 0, 0, info.Cursor, uintptr(w), uintptr(h), 0, 0, diNormal)
+		return append([]byte(nil), pixels...)
+	}
+	onBlack := render(0)
+	onWhite := render(255)
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w*h; i++ {
+		b, wh := onBlack[4*i:], onWhite[4*i:]
+		// Over black the result is the premultiplied colour; over white it
+		// is that plus 255 * (1 - alpha).
+		alpha := 255 - (int(wh[1]) - int(b[1]))
+		alpha = clamp(alpha, 0, 255)
+		img.Pix[4*i] = min(b[2], uint8(alpha))
+		img.Pix[4*i+1] = min(b[1], uint8(alpha))
+		img.Pix[4*i+2] = min(b[0], uint8(alpha))
+		img.Pix[4*i+3] = uint8(alpha)
+	}
+	return &CursorImage{
+		Image:    img,
+		Hotspot:  image.Pt(int(icon.XHotspot), int(icon.YHotspot)),
+		Position: image.Pt(int(info.Pos.X), int(info.Pos.Y)),
+		Scale:    1,
+	}, nil
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
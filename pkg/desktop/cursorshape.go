@@ -0,0 +1,89 @@
+package desktop
+
+import "strings"
+
+// CursorShape names the kind of pointer on screen. It hints at what is
+// under the cursor: a link or button (hand), editable text (ibeam), or an
+// application that is loading (busy, progress).
+type CursorShape string
+
+// Cursor shapes.
+const (
+	ShapeUnknown    CursorShape = "unknown"
+	ShapeHidden     CursorShape = "hidden"
+	ShapeArrow      CursorShape = "arrow"
+	ShapeIBeam      CursorShape = "ibeam"
+	ShapeHand       CursorShape = "hand"
+	ShapeBusy       CursorShape = "busy"
+	ShapeProgress   CursorShape = "progress"
+	ShapeCrosshair  CursorShape = "crosshair"
+	ShapeMove       CursorShape = "move"
+	ShapeResize     CursorShape = "resize"
+	ShapeNotAllowed CursorShape = "not-allowed"
+)
+
+// CurrentCursorShape returns the shape of the cursor being shown.
+func CurrentCursorShape() (CursorShape, error) {
+	return cursorShape()
+}
+
+// cursorNames maps X cursor font and CSS cursor names, as used by cursor
+// themes, to shapes.
+var cursorNames = map[string]CursorShape{
+	"left_ptr":            ShapeArrow,
+	"default":             ShapeArrow,
+	"arrow":               ShapeArrow,
+	"top_left_arrow":      ShapeArrow,
+	"xterm":               ShapeIBeam,
+	"text":                ShapeIBeam,
+	"ibeam":               ShapeIBeam,
+	"vertical-text":       ShapeIBeam,
+	"hand1":               ShapeHand,
+	"hand2":               ShapeHand,
+	"pointer":             ShapeHand,
+	"pointing_hand":       ShapeHand,
+	"watch":               ShapeBusy,
+	"wait":                ShapeBusy,
+	"left_ptr_watch":      ShapeProgress,
+	"progress":            ShapeProgress,
+	"crosshair":           ShapeCrosshair,
+	"cross":               ShapeCrosshair,
+	"tcross":              ShapeCrosshair,
+	"fleur":               ShapeMove,
+	"move":                ShapeMove,
+	"all-scroll":          ShapeMove,
+	"grab":                ShapeMove,
+	"grabbing":            ShapeMove,
+	"not-allowed":         ShapeNotAllowed,
+	"no-drop":             ShapeNotAllowed,
+	"crossed_circle":      ShapeNotAllowed,
+	"circle":              ShapeNotAllowed,
+	"sb_h_double_arrow":   ShapeResize,
+	"sb_v_double_arrow":   ShapeResize,
+	"col-resize":          ShapeResize,
+	"row-resize":          ShapeResize,
+	"ew-resize":           ShapeResize,
+	"ns-resize":           ShapeResize,
+	"nesw-resize":         ShapeResize,
+	"nwse-resize":         ShapeResize,
+	"size_hor":            ShapeResize,
+	"size_ver":            ShapeResize,
+	"size_bdiag":          ShapeResize,
+	"size_fdiag":          ShapeResize,
+	"bottom_right_corner": ShapeResize,
+	"bottom_left_corner":  ShapeResize,
+	"top_right_corner":    ShapeResize,
+	"top_left_corner":     ShapeResize,
+	"left_side":           ShapeResize,
+	"right_side":          ShapeResize,
+	"top_side":            ShapeResize,
+	"bottom_side":         ShapeResize,
+}
+
+// shapeFromName maps a cursor name to its shape.
+func shapeFromName(name string) CursorShape {
+	if shape, ok := cursorNames[strings.ToLower(name)]; ok {
+		return shape
+	}
+	return ShapeUnknown
+}
@@ -0,0 +1,7 @@
+//go:build !windows && !((linux || darwin) && cgo)
+
+package desktop
+
+func cursorShape() (CursorShape, error) {
+	return ShapeUnknown, errCursorUnsupported
+}
@@ -0,0 +1,54 @@
+package desktop
+
+import (
+	"sync"
+	"unsafe"
+)
+
+var procLoadCursorW = user32.NewProc("LoadCursorW")
+
+// Standard cursor resource IDs (IDC_*).
+var systemCursors = map[uintptr]CursorShape{
+	32512: ShapeArrow,      // IDC_ARROW
+	32513: ShapeIBeam,      // IDC_IBEAM
+	32514: ShapeBusy,       // IDC_WAIT
+	32515: ShapeCrosshair,  // IDC_CROSS
+	32642: ShapeResize,     // IDC_SIZENWSE
+	32643: ShapeResize,     // IDC_SIZENESW
+	32644: ShapeResize,     // IDC_SIZEWE
+	32645: ShapeResize,     // IDC_SIZENS
+	32646: ShapeMove,       // IDC_SIZEALL
+	32648: ShapeNotAllowed, // IDC_NO
+	32649: ShapeHand,       // IDC_HAND
+	32650: ShapeProgress,   // IDC_APPSTARTING
+}
+
+var (
+	cursorHandlesOnce sync.Once
+	cursorHandles     map[uintptr]CursorShape
+)
+
+// cursorShape compares the current cursor handle with the shared handles
+// of the standard system cursors.
+func cursorShape() (CursorShape, error) {
+	cursorHandlesOnce.Do(func() {
+		cursorHandles = map[uintptr]CursorShape{}
+		for id, shape := range systemCursors {
+			if h, _, _ := procLoadCursorW.Call(0, id); h != 0 {
+				cursorHandles[h] = shape
+			}
+		}
+	})
+
+	info := cursorInfo{Size: uint32(unsafe.Sizeof(cursorInfo{}))}
+	if ok, _, err := procGetCursorInfo.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return ShapeUnknown, err
+	}
+	if info.Flags&cursorShowing == 0 {
+		return ShapeHidden, nil
+	}
+	if shape, ok := cursorHandles[info.Cursor]; ok {
+		return shape, nil
+	}
+	return ShapeUnknown, nil
+}
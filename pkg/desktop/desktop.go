@@ -0,0 +1,208 @@
+// Package desktop drives the local desktop: it captures the screen with
+// kbinani/screenshot and injects input with robotgo, or through the
+// backends installed with UseBackend and UseInput.
+package desktop
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+	"sync"
+
+	"agentGo/pkg/apps"
+	"agentGo/pkg/capture"
+	"agentGo/pkg/frame"
+	"agentGo/pkg/input"
+	"agentGo/pkg/motion"
+	"agentGo/pkg/wayland"
+
+	"github.com/kbinani/screenshot"
+)
+
+// Executor performs script actions on the local desktop. It implements
+// script.Executor.
+type Executor struct {
+	origin                      image.Point
+	logicalWidth, logicalHeight int
+	pointer                     motion.Pointer
+
+	mu     sync.Mutex
+	placed image.Point
+	known  bool
+	held   Buttons
+	// keyDown is whether a key was held at the last Interference call, and
+	// watched whether there has been one.
+	keyDown, watched bool
+}
+
+// NewExecutor returns an executor sized to the current logical screen.
+func NewExecutor() *Executor {
+	w, h := screenSize()
+	return &Executor{logicalWidth: w, logicalHeight: h}
+}
+
+// NewDisplayExecutor returns an executor whose normalized coordinates cover
+// display i.
+func NewDisplayExecutor(i int) (*Executor, error) {
+	d, err := LookupDisplay(i)
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{origin: d.Bounds.Min, logicalWidth: d.Bounds.Dx(), logicalHeight: d.Bounds.Dy()}, nil
+}
+
+// ScreenSize returns the logical screen size used to scale coordinates.
+func (e *Executor) ScreenSize() (int, int) {
+	return e.logicalWidth, e.logicalHeight
+}
+
+// Move moves the mouse to normalized screen coordinates, gliding there if a
+// motion profile is set.
+func (e *Executor) Move(normX, normY float64) error {
+	return e.pointer.Move(normX, normY, func(x, y float64) error {
+		return e.moveTo(e.origin.X+int(x*float64(e.logicalWidth)), e.origin.Y+int(y*float64(e.logicalHeight)))
+	})
+}
+
+// SetMotion sets how the mouse travels between positions.
+func (e *Executor) SetMotion(p motion.Profile) {
+	e.pointer.SetProfile(p)
+}
+
+// Click clicks the given mouse button at the current position.
+func (e *Executor) Click(button string) error {
+	if dx, dy, ok := input.Wheel(button); ok {
+		return Input().Scroll(dx, dy)
+	}
+	return Input().Click(button)
+}
+
+// MouseDown presses and holds a mouse button at the current position.
+func (e *Executor) MouseDown(button string) error {
+	e.hold(button, true)
+	return Input().Toggle(button, true)
+}
+
+// MouseUp releases a mouse button held by MouseDown.
+func (e *Executor) MouseUp(button string) error {
+	defer e.hold(button, false)
+	return Input().Toggle(button, false)
+}
+
+// Type types text at the current focus.
+func (e *Executor) Type(text string) error {
+	return Input().Type(text)
+}
+
+// KeyTap presses a key or key combination such as "enter" or "ctrl+a".
+func (e *Executor) KeyTap(key string) error {
+	parts := strings.Split(key, "+")
+	if len(parts) == 1 {
+		return Input().KeyTap(key)
+	}
+	modifiers := make([]string, 0, len(parts)-1)
+	for _, m := range parts[:len(parts)-1] {
+		modifiers = append(modifiers, strings.TrimSpace(m))
+	}
+	return Input().KeyTap(strings.TrimSpace(parts[len(parts)-1]), modifiers...)
+}
+
+// WindowTitle returns the title of the focused window.
+func (e *Executor) WindowTitle() (string, error) {
+	return windowTitle()
+}
+
+// LaunchApp starts an application (see apps.Launch).
+func (e *Executor) LaunchApp(app string, args []string) error {
+	return apps.Launch(app, args...)
+}
+
+// Windows returns the desktop's top-level windows, frontmost first.
+func (e *Executor) Windows() ([]apps.Window, error) {
+	return apps.List()
+}
+
+// ActivateWindow brings w to the front and focuses it.
+func (e *Executor) ActivateWindow(w apps.Window) error {
+	return apps.Activate(w)
+}
+
+// CloseWindow asks w to close.
+func (e *Executor) CloseWindow(w apps.Window) error {
+	return apps.Close(w)
+}
+
+// PlaceWindow moves and resizes w.
+func (e *Executor) PlaceWindow(w apps.Window, g apps.Geometry) error {
+	return apps.Place(w, g)
+}
+
+// MaximizeWindow maximizes w.
+func (e *Executor) MaximizeWindow(w apps.Window) error {
+	return apps.Maximize(w)
+}
+
+// CursorShape returns the shape of the mouse pointer, e.g. "busy" while an
+// application is loading.
+func (e *Executor) CursorShape() (string, error) {
+	shape, err := CurrentCursorShape()
+	return string(shape), err
+}
+
+// Clipboard returns the text on the clipboard.
+func (e *Executor) Clipboard() (string, error) {
+	return clipboard()
+}
+
+// Capture returns a screenshot of display 0 at physical resolution. On
+// Wayland sessions it reads from a portal screen cast, which asks the user
+// for permission the first time.
+func Capture() (*image.RGBA, error) {
+	return CaptureDisplay(0)
+}
+
+// CaptureDisplay returns a screenshot of display i, through the backend
+// chosen with UseCapture, or the one installed with UseBackend. On Wayland
+// the portal lets the user pick the monitor instead.
+func CaptureDisplay(i int) (*image.RGBA, error) {
+	b := Backend()
+	if _, ok := b.(Screenshot); !ok {
+		return capture.CaptureDisplay(b, i)
+	}
+	if img, ok := captureNative(i); ok {
+		return img, nil
+	}
+	if wayland.Session() {
+		return captureWayland()
+	}
+	return screenshot.CaptureRect(screenshot.GetDisplayBounds(i))
+}
+
+var (
+	screenCastMu sync.Mutex
+	screenCast   *wayland.ScreenCast
+)
+
+// captureWayland captures through a screen cast shared by the whole process,
+// so the permission prompt appears at most once per run.
+func captureWayland() (*image.RGBA, error) {
+	screenCastMu.Lock()
+	defer screenCastMu.Unlock()
+	if screenCast == nil {
+		sc, err := wayland.Start()
+		if err != nil {
+			return nil, err
+		}
+		screenCast = sc
+	}
+	img, err := screenCast.Capture()
+	if err != nil {
+		return nil, err
+	}
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba, nil
+	}
+	rgba := frame.Get(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba, nil
+}
@@ -0,0 +1,45 @@
+package desktop
+
+import (
+	"fmt"
+	"image"
+
+	"agentGo/pkg/capture"
+)
+
+// Display describes one monitor.
+type Display = capture.Display
+
+// Displays lists the active displays of the capture backend, in capture
+// order.
+func Displays() []Display {
+	return Backend().Displays()
+}
+
+// LookupDisplay returns display i.
+func LookupDisplay(i int) (Display, error) {
+	list := Displays()
+	if i < 0 || i >= len(list) {
+		return Display{}, fmt.Errorf("display %d does not exist (%d active displays)", i, len(list))
+	}
+	return list[i], nil
+}
+
+// DisplayAt returns the display containing the point (x, y) in physical
+// virtual-desktop coordinates.
+func DisplayAt(x, y int) (Display, bool) {
+	p := image.Pt(x, y)
+	for _, d := range Displays() {
+		if p.In(d.Bounds) {
+			return d, true
+		}
+	}
+	return Display{}, false
+}
+
+// CursorPos returns the mouse position in the coordinates Executor.Move
+// uses.
+func CursorPos() (int, int) {
+	x, y, _ := Input().Position()
+	return x, y
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package desktop
+
+import "github.com/kbinani/screenshot"
+
+func displays() []Display {
+	n := screenshot.NumActiveDisplays()
+	main := mainDisplay()
+	list := make([]Display, n)
+	for i := range list {
+		list[i] = Display{
+			Index:   i,
+			Bounds:  screenshot.GetDisplayBounds(i),
+			Scale:   displayScale(i),
+			Primary: i == main,
+		}
+	}
+	return list
+}
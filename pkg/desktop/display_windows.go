@@ -0,0 +1,115 @@
+package desktop
+
+import (
+	"image"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+	shcore = syscall.NewLazyDLL("shcore.dll")
+
+	procSetProcessDpiAwarenessContext = user32.NewProc("SetProcessDpiAwarenessContext")
+	procSetProcessDPIAware            = user32.NewProc("SetProcessDPIAware")
+	procEnumDisplayMonitors           = user32.NewProc("EnumDisplayMonitors")
+	procGetMonitorInfoW               = user32.NewProc("GetMonitorInfoW")
+	procSetCursorPos                  = user32.NewProc("SetCursorPos")
+	procGetCursorPos                  = user32.NewProc("GetCursorPos")
+	procSetProcessDpiAwareness        = shcore.NewProc("SetProcessDpiAwareness")
+	procGetDpiForMonitor              = shcore.NewProc("GetDpiForMonitor")
+)
+
+const (
+	// DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2 is the handle value -4.
+	dpiAwarenessContextPerMonitorV2 = ^uintptr(3)
+	processPerMonitorDPIAware       = 2
+	mdtEffectiveDPI                 = 0
+	monitorInfoPrimary              = 1
+)
+
+type rect struct {
+	Left, Top, Right, Bottom int32
+}
+
+type monitorInfo struct {
+	Size    uint32
+	Monitor rect
+	Work    rect
+	Flags   uint32
+}
+
+type point struct {
+	X, Y int32
+}
+
+// Declaring per-monitor DPI awareness makes Windows report physical pixels
+// for every monitor, instead of scaling all coordinates by the primary
+// monitor's factor, which is wrong on mixed-DPI setups. It must happen
+// before any window or coordinate API is used.
+func init() {
+	if procSetProcessDpiAwarenessContext.Find() == nil {
+		if ok, _, _ := procSetProcessDpiAwarenessContext.Call(dpiAwarenessContextPerMonitorV2); ok != 0 {
+			return
+		}
+	}
+	if procSetProcessDpiAwareness.Find() == nil {
+		if hr, _, _ := procSetProcessDpiAwareness.Call(processPerMonitorDPIAware); hr == 0 {
+			return
+		}
+	}
+	procSetProcessDPIAware.Call()
+}
+
+var (
+	enumMu   sync.Mutex
+	enumList []Display
+	// enumCallback is created once: Windows callbacks are never freed.
+	enumCallback = syscall.NewCallback(func(monitor, hdc uintptr, r *rect, data uintptr) uintptr {
+		info := monitorInfo{Size: uint32(unsafe.Sizeof(monitorInfo{}))}
+		procGetMonitorInfoW.Call(monitor, uintptr(unsafe.Pointer(&info)))
+		enumList = append(enumList, Display{
+			Index:   len(enumList),
+			Bounds:  image.Rect(int(info.Monitor.Left), int(info.Monitor.Top), int(info.Monitor.Right), int(info.Monitor.Bottom)),
+			Scale:   monitorScale(monitor),
+			Primary: info.Flags&monitorInfoPrimary != 0,
+		})
+		return 1
+	})
+)
+
+func displays() []Display {
+	enumMu.Lock()
+	defer enumMu.Unlock()
+	enumList = nil
+	procEnumDisplayMonitors.Call(0, 0, enumCallback, 0)
+	return enumList
+}
+
+// monitorScale returns the effective DPI scale of one monitor.
+func monitorScale(monitor uintptr) float64 {
+	if procGetDpiForMonitor.Find() != nil {
+		return 1
+	}
+	var dpiX, dpiY uint32
+	hr, _, _ := procGetDpiForMonitor.Call(monitor, mdtEffectiveDPI, uintptr(unsafe.Pointer(&dpiX)), uintptr(unsafe.Pointer(&dpiY)))
+	if hr != 0 || dpiX == 0 {
+		return 1
+	}
+	return float64(dpiX) / 96
+}
+
+// moveTo places the cursor at physical coordinates. robotgo.Move divides by
+// the primary monitor's scale, which is only right on single-DPI setups, so
+// SetCursorPos is called directly.
+func moveTo(x, y int) {
+	procSetCursorPos.Call(uintptr(int32(x)), uintptr(int32(y)))
+}
+
+// cursorPos returns the cursor position in physical coordinates.
+func cursorPos() (int, int) {
+	var p point
+	procGetCursorPos.Call(uintptr(unsafe.Pointer(&p)))
+	return int(p.X), int(p.Y)
+}
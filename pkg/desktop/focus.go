@@ -0,0 +1,18 @@
+package desktop
+
+import "agentGo/pkg/secure"
+
+// FocusedField implements secure.Prober: it asks the accessibility API on
+// macOS, and the focused edit control on Windows, whether the focus is in
+// a password field. Elsewhere it returns secure.ErrUnsupported.
+func (l *Local) FocusedField() (secure.Field, error) {
+	isSecure, box, err := focusedField()
+	if err != nil {
+		return secure.Field{}, err
+	}
+	f := secure.Field{Secure: isSecure}
+	if !box.Empty() {
+		f.Bounds = box.Sub(l.ScreenBounds().Min)
+	}
+	return f, nil
+}
@@ -0,0 +1,76 @@
+//go:build darwin && cgo
+
+package desktop
+
+/*
+#cgo LDFLAGS: -framework ApplicationServices -framework CoreFoundation
+#include <ApplicationServices/ApplicationServices.h>
+
+// focused_field reports whether the focused element is a secure text
+// field, and its frame in points. It returns -1 if there is no focused
+// element or the process may not use the accessibility API.
+static int focused_field(double *x, double *y, double *w, double *h) {
+	AXUIElementRef sys = AXUIElementCreateSystemWide();
+	CFTypeRef focused = NULL;
+	AXError err = AXUIElementCopyAttributeValue(sys, kAXFocusedUIElementAttribute, &focused);
+	CFRelease(sys);
+	if (err != kAXErrorSuccess || focused == NULL) {
+		return -1;
+	}
+	AXUIElementRef el = (AXUIElementRef)focused;
+	int secure = 0;
+	CFTypeRef subrole = NULL;
+	if (AXUIElementCopyAttributeValue(el, kAXSubroleAttribute, &subrole) == kAXErrorSuccess && subrole != NULL) {
+		secure = CFGetTypeID(subrole) == CFStringGetTypeID() &&
+			CFStringCompare((CFStringRef)subrole, kAXSecureTextFieldSubrole, 0) == kCFCompareEqualTo;
+		CFRelease(subrole);
+	}
+	CGPoint pos = CGPointZero;
+	CGSize size = CGSizeZero;
+	CFTypeRef value = NULL;
+	if (AXUIElementCopyAttributeValue(el, kAXPositionAttribute, &value) == kAXErrorSuccess && value != NULL) {
+		AXValueGetValue((AXValueRef)value, kAXValueCGPointType, &pos);
+		CFRelease(value);
+	}
+	value = NULL;
+	if (AXUIElementCopyAttributeValue(el, kAXSizeAttribute, &value) == kAXErrorSuccess && value != NULL) {
+		AXValueGetValue((AXValueRef)value, kAXValueCGSizeType, &size);
+		CFRelease(value);
+	}
+	CFRelease(focused);
+	*x = pos.x;
+	*y = pos.y;
+	*w = size.width;
+	*h = size.height;
+	return secure;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+)
+
+// focusedField asks the accessibility API, which gives the frame in
+// points; the primary display's scale turns them into physical pixels.
+func focusedField() (bool, image.Rectangle, error) {
+	var x, y, w, h C.double
+	switch C.focused_field(&x, &y, &w, &h) {
+	case -1:
+		return false, image.Rectangle{}, errors.New("no focused element, or agentgo is not allowed to use the accessibility API")
+	case 0:
+		return false, image.Rectangle{}, nil
+	}
+	scale := 1.0
+	for _, d := range Displays() {
+		if d.Primary && d.Scale > 0 {
+			scale = d.Scale
+		}
+	}
+	box := image.Rect(
+		int(float64(x)*scale), int(float64(y)*scale),
+		int(float64(x+w)*scale), int(float64(y+h)*scale),
+	)
+	return true, box, nil
+}
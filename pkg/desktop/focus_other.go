@@ -0,0 +1,13 @@
+//go:build !windows && !(darwin && cgo)
+
+package desktop
+
+import (
+	"image"
+
+	"agentGo/pkg/secure"
+)
+
+func focusedField() (bool, image.Rectangle, error) {
+	return false, image.Rectangle{}, secure.ErrUnsupported
+}
@@ -0,0 +1,61 @@
+package desktop
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procGetGUIThreadInfo = user32.NewProc("GetGUIThreadInfo")
+	procGetClassNameW    = user32.NewProc("GetClassNameW")
+	procGetWindowLongW   = user32.NewProc("GetWindowLongW")
+	procGetWindowRect    = user32.NewProc("GetWindowRect")
+)
+
+const (
+	// GWL_STYLE is -16.
+	gwlStyle   = ^uintptr(15)
+	esPassword = 0x20
+)
+
+type guiThreadInfo struct {
+	Size      uint32
+	Flags     uint32
+	Active    uintptr
+	Focus     uintptr
+	Capture   uintptr
+	MenuOwner uintptr
+	MoveSize  uintptr
+	Caret     uintptr
+	CaretRect rect
+}
+
+// focusedField looks at the control with the keyboard focus in the
+// foreground window: edit controls with the ES_PASSWORD style are secure.
+// Controls that draw their own fields, as WPF and browsers do, are not
+// recognized here.
+func focusedField() (bool, image.Rectangle, error) {
+	info := guiThreadInfo{}
+	info.Size = uint32(unsafe.Sizeof(info))
+	if r, _, err := procGetGUIThreadInfo.Call(0, uintptr(unsafe.Pointer(&info))); r == 0 {
+		return false, image.Rectangle{}, fmt.Errorf("GetGUIThreadInfo: %v", err)
+	}
+	if info.Focus == 0 {
+		return false, image.Rectangle{}, nil
+	}
+	var class [64]uint16
+	n, _, _ := procGetClassNameW.Call(info.Focus, uintptr(unsafe.Pointer(&class[0])), uintptr(len(class)))
+	name := strings.ToLower(syscall.UTF16ToString(class[:n]))
+	style, _, _ := procGetWindowLongW.Call(info.Focus, gwlStyle)
+	if !strings.Contains(name, "edit") || style&esPassword == 0 {
+		return false, image.Rectangle{}, nil
+	}
+	var r rect
+	if ok, _, _ := procGetWindowRect.Call(info.Focus, uintptr(unsafe.Pointer(&r))); ok == 0 {
+		return true, image.Rectangle{}, nil
+	}
+	return true, image.Rect(int(r.Left), int(r.Top), int(r.Right), int(r.Bottom)), nil
+}
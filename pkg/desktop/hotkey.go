@@ -0,0 +1,108 @@
+package desktop
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"agentGo/pkg/input"
+	"agentGo/pkg/script"
+)
+
+// HotkeyHeld reports whether every key of combo, such as "ctrl+alt+k", is
+// held down right now. Keys are named as in KeyTap: ctrl, shift, alt and
+// cmd (on either side), letters, digits, f1 to f12, escape, pause and the
+// like.
+func HotkeyHeld(combo string) (bool, error) {
+	keys := strings.Split(combo, "+")
+	for _, k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			return false, errors.New("empty key in hotkey " + combo)
+		}
+		down, err := keyDown(k)
+		if err != nil || !down {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// WatchHotkey polls the keyboard every poll interval and sends the time
+// combo is pressed, once per press however long it is held. The channel is
+// closed when ctx is done. It fails at once if the keyboard cannot be read
+// or combo names an unknown key.
+func WatchHotkey(ctx context.Context, combo string, poll time.Duration) (<-chan time.Time, error) {
+	held, err := HotkeyHeld(combo)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan time.Time, 1)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				down, err := HotkeyHeld(combo)
+				if err != nil {
+					continue
+				}
+				if down && !held {
+					select {
+					case out <- now:
+					default:
+					}
+				}
+				held = down
+			}
+		}
+	}()
+	return out, nil
+}
+
+// heldModifiers are the keys released by Release, since KeyTap holds them
+// while it presses a key.
+var heldModifiers = []string{"ctrl", "shift", "alt", "cmd"}
+
+// Release lets go of any mouse button the executor pressed or that is held
+// down, and of the modifier keys if the input backend can release them.
+func (e *Executor) Release() error {
+	state, _ := ButtonState()
+	e.mu.Lock()
+	state |= e.held
+	e.held = 0
+	e.mu.Unlock()
+	var errs []error
+	for _, b := range []Buttons{ButtonLeft, ButtonRight, ButtonCenter} {
+		if state&b != 0 {
+			errs = append(errs, Input().Toggle(b.Name(), false))
+		}
+	}
+	if r, ok := Input().(input.KeyReleaser); ok {
+		errs = append(errs, r.ReleaseKeys(heldModifiers...))
+	}
+	return errors.Join(errs...)
+}
+
+// Release lets go of whatever drv may be holding: every mouse button,
+// and on the local desktop the modifier keys. Drivers that cannot hold a
+// button down have nothing to release.
+func Release(drv Driver) error {
+	if r, ok := drv.(interface{ Release() error }); ok {
+		return r.Release()
+	}
+	p, ok := drv.(script.ButtonPresser)
+	if !ok {
+		return nil
+	}
+	var errs []error
+	for _, b := range []Buttons{ButtonLeft, ButtonRight, ButtonCenter} {
+		errs = append(errs, p.MouseUp(b.Name()))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,166 @@
+package desktop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"agentGo/pkg/input"
+	"agentGo/pkg/sendinput"
+	"agentGo/pkg/uinput"
+	"agentGo/pkg/x11"
+)
+
+// Input drivers.
+const (
+	// InputRobotgo injects input with robotgo. It is the default, but needs
+	// a build with cgo.
+	InputRobotgo = "robotgo"
+	// InputXdotool runs xdotool for each action. It needs no cgo, only an
+	// X11 session (or XWayland windows) and xdotool installed.
+	InputXdotool = "xdotool"
+	// InputUinput writes to a virtual device created with Linux's uinput,
+	// which works under X11 and Wayland alike but needs write access to
+	// /dev/uinput.
+	InputUinput = "uinput"
+	// InputSendInput injects input with Windows' SendInput as hardware scan
+	// codes, which games and elevated windows accept more readily.
+	InputSendInput = "sendinput"
+	// InputInterception sends the same strokes through the Interception
+	// kernel driver, for applications that ignore injected input.
+	InputInterception = "interception"
+)
+
+var (
+	inputMu      sync.Mutex
+	inputBackend input.Backend = defaultInput()
+)
+
+// UseInput replaces the input backend of the local desktop, e.g. with
+// xdotool or a remote protocol. A nil backend restores the default:
+// robotgo, or on builds without cgo, a backend that reports every action
+// as unavailable.
+func UseInput(b input.Backend) {
+	if b == nil {
+		b = defaultInput()
+	}
+	inputMu.Lock()
+	inputBackend = b
+	inputMu.Unlock()
+}
+
+// UseInputDriver selects the input driver for the local desktop: robotgo
+// (the default, also for ""), xdotool, uinput, sendinput or interception.
+func UseInputDriver(name string) error {
+	var b input.Backend
+	switch name {
+	case "", InputRobotgo:
+	case InputXdotool:
+		b = x11.NewDisplay(os.Getenv("DISPLAY")).Input()
+	case InputUinput:
+		dev, err := uinput.Open(desktopBounds(Displays()))
+		if err != nil {
+			return err
+		}
+		b = dev
+	case InputSendInput:
+		drv, err := sendinput.New()
+		if err != nil {
+			return err
+		}
+		b = drv
+	case InputInterception:
+		drv, err := sendinput.NewInterception()
+		if err != nil {
+			return err
+		}
+		b = drv
+	default:
+		return fmt.Errorf("unknown input driver %q (want robotgo, xdotool, uinput, sendinput or interception)", name)
+	}
+	if c, ok := Input().(interface{ Close() error }); ok {
+		c.Close()
+	}
+	UseInput(b)
+	return nil
+}
+
+// Input returns the input backend in use, which in read-only builds
+// refuses every action.
+func Input() input.Backend {
+	if ReadOnlyBuild {
+		return readOnlyInput{}
+	}
+	inputMu.Lock()
+	defer inputMu.Unlock()
+	return inputBackend
+}
+
+// pollHook reports input by polling the pointer, the mouse buttons and the
+// keyboard every poll interval, for backends with no event hook of their
+// own. Key events carry no key name, since only whether some key is held
+// can be read.
+func pollHook(ctx context.Context, poll time.Duration) (<-chan input.Event, error) {
+	if _, err := ButtonState(); err != nil {
+		return nil, err
+	}
+	out := make(chan input.Event, 64)
+	go func() {
+		defer close(out)
+		x, y := CursorPos()
+		var held Buttons
+		keyDown, _ := KeyHeld()
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		send := func(ev input.Event) bool {
+			select {
+			case out <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				cx, cy := CursorPos()
+				if cx != x || cy != y {
+					x, y = cx, cy
+					if !send(input.Event{Time: now, Kind: input.EventMove, X: x, Y: y}) {
+						return
+					}
+				}
+				if state, err := ButtonState(); err == nil {
+					for _, b := range []Buttons{ButtonLeft, ButtonRight, ButtonCenter} {
+						kind := ""
+						switch {
+						case state&b != 0 && held&b == 0:
+							kind = input.EventDown
+						case state&b == 0 && held&b != 0:
+							kind = input.EventUp
+						}
+						if kind != "" && !send(input.Event{Time: now, Kind: kind, X: x, Y: y, Button: b.Name()}) {
+							return
+						}
+					}
+					held = state
+				}
+				if down, err := KeyHeld(); err == nil && down != keyDown {
+					keyDown = down
+					kind := input.EventKeyUp
+					if down {
+						kind = input.EventKeyDown
+					}
+					if !send(input.Event{Time: now, Kind: kind, X: x, Y: y}) {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,85 @@
+package desktop
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+var errKeysUnsupported = errors.New("reading the keyboard is not supported on this platform")
+
+// KeyHeld reports whether any key is held down right now.
+func KeyHeld() (bool, error) {
+	return keyHeld()
+}
+
+// moveTo moves the mouse to (x, y) and remembers it as where the executor
+// left the pointer.
+func (e *Executor) moveTo(x, y int) error {
+	if err := Input().Move(x, y); err != nil {
+		return err
+	}
+	// Read the position back, since the OS may clamp or round it.
+	cx, cy := CursorPos()
+	e.mu.Lock()
+	e.placed, e.known = image.Pt(cx, cy), true
+	e.mu.Unlock()
+	return nil
+}
+
+// hold records a button the executor pressed or released, so that it is not
+// mistaken for someone else's.
+func (e *Executor) hold(button string, down bool) {
+	var b Buttons
+	switch button {
+	case "left", "":
+		b = ButtonLeft
+	case "right":
+		b = ButtonRight
+	case "center", "middle":
+		b = ButtonCenter
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if down {
+		e.held |= b
+	} else {
+		e.held &^= b
+	}
+}
+
+// Interference reports input from a person at this machine: the pointer is
+// no longer where the executor left it, a mouse button the executor did not
+// press is held down, or a key is down that was up at the last call. Keys
+// already down at the first call, such as the Enter that started the run,
+// do not count. It describes what it saw, or returns "" if nothing. Having
+// reported the pointer, it takes the pointer's new position as where it was
+// left, so each move is reported once.
+//
+// It must not be called while an action is under way, since the executor's
+// own keystrokes and clicks would count.
+func (e *Executor) Interference() string {
+	x, y := CursorPos()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.known && !near(e.placed.X, e.placed.Y, x, y) {
+		from := e.placed
+		e.placed = image.Pt(x, y)
+		return fmt.Sprintf("the mouse moved from (%d, %d) to (%d, %d)", from.X, from.Y, x, y)
+	}
+	if state, err := ButtonState(); err == nil {
+		for _, b := range []Buttons{ButtonLeft, ButtonRight, ButtonCenter} {
+			if state&b != 0 && e.held&b == 0 {
+				return fmt.Sprintf("the %s mouse button was pressed", b.Name())
+			}
+		}
+	}
+	if held, err := KeyHeld(); err == nil {
+		pressed := held && !e.keyDown && e.watched
+		e.keyDown, e.watched = held, true
+		if pressed {
+			return "a key was pressed"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+//go:build !cgo
+
+package desktop
+
+import (
+	"context"
+	"errors"
+	"image"
+
+	"agentGo/pkg/input"
+)
+
+// errNoCgo is returned by the default input backend of builds without cgo,
+// which cannot link robotgo. Install another backend with UseInput.
+var errNoCgo = errors.New("local input with robotgo needs a build with cgo; use the xdotool, uinput or sendinput input driver")
+
+type noInput struct{}
+
+func defaultInput() input.Backend {
+	return noInput{}
+}
+
+func (noInput) Move(x, y int) error                          { return errNoCgo }
+func (noInput) Position() (int, int, error)                  { return 0, 0, errNoCgo }
+func (noInput) Click(button string) error                    { return errNoCgo }
+func (noInput) Toggle(button string, down bool) error        { return errNoCgo }
+func (noInput) Type(text string) error                       { return errNoCgo }
+func (noInput) KeyTap(key string, modifiers ...string) error { return errNoCgo }
+func (noInput) Scroll(dx, dy int) error                      { return errNoCgo }
+func (noInput) Hook(ctx context.Context) (<-chan input.Event, error) {
+	return nil, errNoCgo
+}
+
+// screenSize returns the size of the primary display, which is the logical
+// size unless the display is scaled.
+func screenSize() (int, int) {
+	for _, d := range Displays() {
+		if d.Primary {
+			return d.Bounds.Dx(), d.Bounds.Dy()
+		}
+	}
+	return 0, 0
+}
+
+func windowTitle() (string, error) {
+	return "", errNoCgo
+}
+
+func clipboard() (string, error) {
+	return "", errNoCgo
+}
+
+func windowBounds(pid int) (image.Rectangle, error) {
+	return image.Rectangle{}, errNoCgo
+}
+
+func mainDisplay() int {
+	return 0
+}
+
+func displayScale(i int) float64 {
+	return 1
+}
@@ -0,0 +1,103 @@
+package desktop
+
+import (
+	"context"
+	"errors"
+	"image"
+
+	"agentGo/pkg/input"
+	"agentGo/pkg/script"
+	"agentGo/pkg/secure"
+)
+
+// ErrReadOnly is returned for every attempt to act on a read-only desktop.
+var ErrReadOnly = errors.New("read-only mode: moving the mouse and typing are disabled")
+
+// ReadOnly wraps a driver so that it can only observe: it captures the
+// screen and reads window titles, the clipboard and the cursor, but every
+// move, click, key and typed text fails with ErrReadOnly. It implements
+// none of the optional interfaces that act, such as script.ButtonPresser or
+// script.WindowManager, so steps and tools that need them report that the
+// driver cannot.
+type ReadOnly struct {
+	Driver Driver
+}
+
+// NewReadOnly returns drv made read-only, or drv itself if it already is.
+func NewReadOnly(drv Driver) Driver {
+	if _, ok := drv.(*ReadOnly); ok {
+		return drv
+	}
+	return &ReadOnly{Driver: drv}
+}
+
+// Move implements script.Executor by refusing.
+func (*ReadOnly) Move(normX, normY float64) error { return ErrReadOnly }
+
+// Click implements script.Executor by refusing.
+func (*ReadOnly) Click(button string) error { return ErrReadOnly }
+
+// Type implements script.Executor by refusing.
+func (*ReadOnly) Type(text string) error { return ErrReadOnly }
+
+// KeyTap implements script.Executor by refusing.
+func (*ReadOnly) KeyTap(key string) error { return ErrReadOnly }
+
+// Capture implements Driver.
+func (r *ReadOnly) Capture() (image.Image, error) { return r.Driver.Capture() }
+
+// Close implements Driver.
+func (r *ReadOnly) Close() error { return r.Driver.Close() }
+
+// WindowTitle implements script.WindowTitler if the wrapped driver can.
+func (r *ReadOnly) WindowTitle() (string, error) {
+	if t, ok := r.Driver.(script.WindowTitler); ok {
+		return t.WindowTitle()
+	}
+	return "", errors.New("this driver cannot read window titles")
+}
+
+// Clipboard implements script.ClipboardReader if the wrapped driver can.
+func (r *ReadOnly) Clipboard() (string, error) {
+	if c, ok := r.Driver.(script.ClipboardReader); ok {
+		return c.Clipboard()
+	}
+	return "", errors.New("this driver cannot read the clipboard")
+}
+
+// CursorShape implements script.CursorShaper if the wrapped driver can.
+func (r *ReadOnly) CursorShape() (string, error) {
+	if c, ok := r.Driver.(script.CursorShaper); ok {
+		return c.CursorShape()
+	}
+	return "", errors.New("this driver cannot read the cursor shape")
+}
+
+// FocusedField implements secure.Prober if the wrapped driver can.
+func (r *ReadOnly) FocusedField() (secure.Field, error) {
+	if p, ok := r.Driver.(secure.Prober); ok {
+		return p.FocusedField()
+	}
+	return secure.Field{}, secure.ErrUnsupported
+}
+
+// readOnlyInput is the input backend of builds with the readonly tag: it
+// reads the pointer but refuses to inject anything.
+type readOnlyInput struct{}
+
+func (readOnlyInput) Move(x, y int) error { return ErrReadOnly }
+
+func (readOnlyInput) Position() (int, int, error) {
+	x, y := CursorPos()
+	return x, y, nil
+}
+
+func (readOnlyInput) Click(button string) error                    { return ErrReadOnly }
+func (readOnlyInput) Toggle(button string, down bool) error        { return ErrReadOnly }
+func (readOnlyInput) Type(text string) error                       { return ErrReadOnly }
+func (readOnlyInput) KeyTap(key string, modifiers ...string) error { return ErrReadOnly }
+func (readOnlyInput) Scroll(dx, dy int) error                      { return ErrReadOnly }
+
+func (readOnlyInput) Hook(context.Context) (<-chan input.Event, error) {
+	return nil, input.ErrUnsupported
+}
@@ -0,0 +1,7 @@
+//go:build !readonly
+
+package desktop
+
+// ReadOnlyBuild reports whether acting is compiled out (see the readonly
+// build tag).
+const ReadOnlyBuild = false
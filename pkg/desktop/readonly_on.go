@@ -0,0 +1,8 @@
+//go:build readonly
+
+package desktop
+
+// ReadOnlyBuild reports whether acting is compiled out: built with the
+// readonly tag, every driver Open returns is read-only and the local input
+// backend refuses to inject input, whatever the settings say.
+const ReadOnlyBuild = true
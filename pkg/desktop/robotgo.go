@@ -0,0 +1,124 @@
+//go:build cgo
+
+package desktop
+
+import (
+	"context"
+	"image"
+	"time"
+
+	"agentGo/pkg/input"
+
+	"github.com/go-vgo/robotgo"
+)
+
+// Robotgo is the default input backend: it injects input with robotgo,
+// which needs cgo. Its Hook polls the pointer and buttons, like
+// WatchClicks.
+type Robotgo struct{}
+
+func defaultInput() input.Backend {
+	return Robotgo{}
+}
+
+// Move implements input.Backend.
+func (Robotgo) Move(x, y int) error {
+	moveTo(x, y)
+	return nil
+}
+
+// Position implements input.Backend.
+func (Robotgo) Position() (int, int, error) {
+	x, y := cursorPos()
+	return x, y, nil
+}
+
+// Click implements input.Backend.
+func (Robotgo) Click(button string) error {
+	robotgo.Click(button)
+	return nil
+}
+
+// Toggle implements input.Backend.
+func (Robotgo) Toggle(button string, down bool) error {
+	if down {
+		return robotgo.Toggle(button, "down")
+	}
+	return robotgo.Toggle(button, "up")
+}
+
+// Type implements input.Backend.
+func (Robotgo) Type(text string) error {
+	robotgo.TypeStr(text)
+	return nil
+}
+
+// KeyTap implements input.Backend.
+func (Robotgo) KeyTap(key string, modifiers ...string) error {
+	args := make([]interface{}, len(modifiers))
+	for i, m := range modifiers {
+		args[i] = m
+	}
+	return robotgo.KeyTap(key, args...)
+}
+
+// ReleaseKeys implements input.KeyReleaser.
+func (Robotgo) ReleaseKeys(keys ...string) error {
+	var first error
+	for _, k := range keys {
+		if err := robotgo.KeyToggle(k, "up"); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Scroll implements input.Backend, one wheel click per step.
+func (Robotgo) Scroll(dx, dy int) error {
+	for ; dy < 0; dy++ {
+		robotgo.Click("wheelUp")
+	}
+	for ; dy > 0; dy-- {
+		robotgo.Click("wheelDown")
+	}
+	for ; dx < 0; dx++ {
+		robotgo.Click("wheelLeft")
+	}
+	for ; dx > 0; dx-- {
+		robotgo.Click("wheelRight")
+	}
+	return nil
+}
+
+// Hook implements input.Backend by polling every 10ms. Wheel turns are not
+// seen.
+func (Robotgo) Hook(ctx context.Context) (<-chan input.Event, error) {
+	return pollHook(ctx, 10*time.Millisecond)
+}
+
+func screenSize() (int, int) {
+	return robotgo.GetScreenSize()
+}
+
+func windowTitle() (string, error) {
+	return robotgo.GetTitle(), nil
+}
+
+func clipboard() (string, error) {
+	return robotgo.ReadAll()
+}
+
+// windowBounds returns the bounds of the window of process pid, as robotgo
+// numbers windows.
+func windowBounds(pid int) (image.Rectangle, error) {
+	x, y, w, h := robotgo.GetBounds(pid)
+	return image.Rect(x, y, x+w, y+h), nil
+}
+
+func mainDisplay() int {
+	return robotgo.GetMainId()
+}
+
+func displayScale(i int) float64 {
+	return robotgo.ScaleF(i)
+}
@@ -0,0 +1,13 @@
+//go:build cgo && !windows
+
+package desktop
+
+import "github.com/go-vgo/robotgo"
+
+func moveTo(x, y int) {
+	robotgo.Move(x, y)
+}
+
+func cursorPos() (int, int) {
+	return robotgo.Location()
+}
@@ -0,0 +1,87 @@
+package desktop
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"agentGo/pkg/frame"
+)
+
+// Layout records where each display sits in a stitched virtual-desktop
+// capture, so points found in the image can be mapped back to a display.
+type Layout struct {
+	// Bounds is the virtual desktop area the image covers; image pixel
+	// (0, 0) is Bounds.Min.
+	Bounds   image.Rectangle `json:"bounds"`
+	Displays []Display       `json:"displays"`
+}
+
+// Offset returns the position of a display's top-left corner in the image.
+func (l Layout) Offset(d Display) image.Point {
+	return d.Bounds.Min.Sub(l.Bounds.Min)
+}
+
+// Locate maps a pixel of the stitched image to the display it falls on and
+// the point on the virtual desktop.
+func (l Layout) Locate(p image.Point) (Display, image.Point, bool) {
+	q := p.Add(l.Bounds.Min)
+	for _, d := range l.Displays {
+		if q.In(d.Bounds) {
+			return d, q, true
+		}
+	}
+	return Display{}, q, false
+}
+
+// CaptureDesktop captures every display and composes them into one image of
+// the whole virtual desktop. Areas not covered by any display are black.
+func CaptureDesktop() (*image.RGBA, Layout, error) {
+	b := Backend()
+	layout := Layout{Displays: b.Displays()}
+	if len(layout.Displays) == 0 {
+		return nil, layout, errors.New("no active displays")
+	}
+	layout.Bounds = desktopBounds(layout.Displays)
+
+	canvas := frame.Get(image.Rect(0, 0, layout.Bounds.Dx(), layout.Bounds.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), image.Black, image.Point{}, draw.Src)
+	for _, d := range layout.Displays {
+		img, err := b.Capture(d.Bounds)
+		if err != nil {
+			frame.Put(canvas)
+			return nil, layout, fmt.Errorf("failed to capture display %d: %w", d.Index, err)
+		}
+		dst := image.Rectangle{Min: layout.Offset(d), Max: layout.Offset(d).Add(d.Bounds.Size())}
+		if img.Bounds().Size() == d.Bounds.Size() {
+			draw.Draw(canvas, dst, img, img.Bounds().Min, draw.Src)
+		} else {
+			// HiDPI displays may capture at a multiple of their bounds.
+			scaleInto(canvas, dst, img)
+		}
+		frame.Put(img)
+	}
+	return canvas, layout, nil
+}
+
+// desktopBounds returns the smallest rectangle covering all displays.
+func desktopBounds(displays []Display) image.Rectangle {
+	var b image.Rectangle
+	for _, d := range displays {
+		b = b.Union(d.Bounds)
+	}
+	return b
+}
+
+// scaleInto draws src into dst with nearest-neighbour sampling.
+func scaleInto(dst *image.RGBA, r image.Rectangle, src *image.RGBA) {
+	sb := src.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		sy := sb.Min.Y + (y-r.Min.Y)*sb.Dy()/r.Dy()
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sx := sb.Min.X + (x-r.Min.X)*sb.Dx()/r.Dx()
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+}
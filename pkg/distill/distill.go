@@ -0,0 +1,275 @@
+// Package distill turns a raw recording into a semantic task script. The
+// recording's cursor samples are reduced to events, points where the
+// pointer rested or changed shape, and the model is shown the screen at
+// each event and asked for steps such as "click the Login button" or "type
+// ${username}" that replay by finding elements rather than by coordinates.
+package distill
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/png"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agentGo/pkg/playback"
+	"agentGo/pkg/script"
+	"agentGo/pkg/vision"
+)
+
+// Event is a moment of a recording worth describing.
+type Event struct {
+	// Start and End bound the samples the event covers.
+	Start, End time.Duration
+	// X and Y are where the pointer was, as 0-1 fractions of the screen.
+	X, Y float64
+	// Cursor is the pointer shape, e.g. "ibeam" over a text field.
+	Cursor string
+	// Sample is the index of the event's first position sample, which is
+	// also the index of its screenshot.
+	Sample int
+	// Button is set on click events: the button clicked, how many times,
+	// and how long it was held on the last press.
+	Button string
+	Clicks int
+	Hold   time.Duration
+}
+
+// Describe returns a line describing the event for the model.
+func (e Event) Describe() string {
+	if e.Button != "" {
+		at := fmt.Sprintf("at %.1fs the user", e.Start.Seconds())
+		where := fmt.Sprintf("at x=%.3f y=%.3f", e.X, e.Y)
+		switch {
+		case e.Hold >= playback.LongPress:
+			return fmt.Sprintf("%s pressed and held the %s button %s for %.1fs", at, e.Button, where, e.Hold.Seconds())
+		case e.Clicks == 2:
+			return fmt.Sprintf("%s double-clicked the %s button %s", at, e.Button, where)
+		case e.Clicks >= 3:
+			return fmt.Sprintf("%s triple-clicked the %s button %s", at, e.Button, where)
+		}
+		return fmt.Sprintf("%s clicked the %s button %s", at, e.Button, where)
+	}
+	d := fmt.Sprintf("at %.1fs the pointer was at x=%.3f y=%.3f", e.Start.Seconds(), e.X, e.Y)
+	if e.End > e.Start {
+		d += fmt.Sprintf(" and rested there for %.1fs", (e.End - e.Start).Seconds())
+	}
+	if e.Cursor != "" && e.Cursor != "unknown" {
+		d += fmt.Sprintf(" (cursor: %s)", e.Cursor)
+	}
+	return d
+}
+
+// Events finds the places the pointer rested: runs of samples that stay
+// within radius of each other (as a fraction of the screen) for at least
+// dwell, or where the cursor shape changes to something other than the
+// arrow, which usually means a link, button or text field. Every recorded
+// click is an event of its own.
+func Events(all []playback.Sample, dwell time.Duration, radius float64) []Event {
+	var events []Event
+	// Clicks are set apart so that sample indexes count only positions,
+	// which have screenshots.
+	samples := make([]playback.Sample, 0, len(all))
+	for _, s := range all {
+		if s.Button == "" {
+			samples = append(samples, s)
+			continue
+		}
+		events = append(events, Event{
+			Start:  s.Timestamp,
+			End:    s.Timestamp,
+			X:      s.X,
+			Y:      s.Y,
+			Sample: max(len(samples)-1, 0),
+			Button: s.Button,
+			Clicks: s.Clicks,
+			Hold:   s.Hold,
+		})
+	}
+	for i := 0; i < len(samples); {
+		j := i + 1
+		for j < len(samples) && math.Hypot(samples[j].X-samples[i].X, samples[j].Y-samples[i].Y) <= radius {
+			j++
+		}
+		run := samples[i:j]
+		last := run[len(run)-1]
+		shapeChanged := i > 0 && run[0].Cursor != samples[i-1].Cursor && run[0].Cursor != "" && run[0].Cursor != "arrow"
+		if last.Timestamp-run[0].Timestamp >= dwell || shapeChanged {
+			events = append(events, Event{
+				Start:  run[0].Timestamp,
+				End:    last.Timestamp,
+				X:      run[0].X,
+				Y:      run[0].Y,
+				Cursor: run[0].Cursor,
+				Sample: i,
+			})
+		}
+		i = j
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start < events[j].Start })
+	return events
+}
+
+var frameTime = regexp.MustCompile(`_t(\d+)\.png$`)
+
+// LoadFrames reads the recorder's screenshots from dir in the order they
+// were taken, one per sample. Screenshots are named debug_x*_y*_t<unix>.png;
+// other PNGs are ordered by name.
+func LoadFrames(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		return nil, err
+	}
+	key := func(p string) int64 {
+		if m := frameTime.FindStringSubmatch(p); m != nil {
+			n, _ := strconv.ParseInt(m[1], 10, 64)
+			return n
+		}
+		return 0
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		if ki, kj := key(paths[i]), key(paths[j]); ki != kj {
+			return ki < kj
+		}
+		return paths[i] < paths[j]
+	})
+	return paths, nil
+}
+
+// Distiller asks a model to write a script from events.
+type Distiller struct {
+	Client *vision.Client
+	// Frames are screenshot paths, one per sample, as from LoadFrames.
+	Frames []string
+	// MaxFrames caps the screenshots sent with a request; 0 means 16.
+	MaxFrames int
+	Logf      func(format string, args ...any)
+}
+
+const instructions = `You are given the events of a recording of someone using a desktop application, each with a screenshot taken at the time. Write a task script that repeats what they did so that it keeps working when the layout changes: refer to elements by visible description rather than by coordinates. A red crosshair on a screenshot marks where the pointer was.
+
+Answer with only a JSON object of this form:
+{"name": "...", "vars": {"NAME": "recorded value"}, "steps": [...]}
+
+Steps may be:
+{"action": "click", "target": "the Login button"}
+{"action": "click", "target": "the report.pdf file", "clicks": 2}
+{"action": "click", "target": "the record button", "hold": "1.5s"}
+{"action": "context_menu", "target": "the report.pdf file", "item": "Open With > Preview"}
+{"action": "move", "target": "the File menu"}
+{"action": "click", "target": "the row for invoice 1042", "scroll": 10}
+{"action": "type", "text": "${username}"}
+{"action": "key", "key": "enter"}
+{"action": "wait_until_idle", "timeout": "30s"}
+{"action": "wait", "duration": "2s"}
+{"action": "assert_text", "target": "the status bar", "expect": "Saved", "match": "contains"}
+
+Put anything typed that could change between runs, such as user names, search terms or amounts, in vars and refer to it as ${NAME}. Targets must describe one element unambiguously, e.g. "the Save button in the toolbar". Give a click a scroll budget when the user scrolled a list or page to reach its target, so it is found wherever it ends up. Where the application had to load or respond, wait with wait_until_idle rather than a fixed wait. Add an assert_text step at the end if the last screenshot shows an outcome worth checking.`
+
+// Distill returns a script named name for events.
+func (d *Distiller) Distill(ctx context.Context, name string, events []Event) (*script.Script, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no events found in the recording")
+	}
+	prompt := instructions + fmt.Sprintf("\n\nThe recording is called %q. Its events:\n", name)
+	for i, e := range events {
+		prompt += fmt.Sprintf("%d. %s\n", i+1, e.Describe())
+	}
+
+	var imgs []image.Image
+	var captions []string
+	for _, i := range d.pickFrames(events) {
+		e := events[i]
+		if e.Sample >= len(d.Frames) {
+			continue
+		}
+		img, err := loadPNG(d.Frames[e.Sample])
+		if err != nil {
+			d.logf("skipping frame %s: %v", d.Frames[e.Sample], err)
+			continue
+		}
+		imgs = append(imgs, img)
+		captions = append(captions, fmt.Sprintf("Screenshot at event %d:", i+1))
+	}
+
+	text, err := d.Client.GenerateImages(ctx, prompt, imgs, captions)
+	if err != nil {
+		return nil, err
+	}
+	s, err := script.Parse([]byte(stripFence(text)))
+	if err != nil {
+		// One chance to fix an invalid script, with the reason.
+		d.logf("model returned an invalid script (%v); asking again", err)
+		retry := prompt + fmt.Sprintf("\nA previous answer was rejected: %v. Answer with a valid JSON script only.", err)
+		if text, err = d.Client.GenerateImages(ctx, retry, imgs, captions); err != nil {
+			return nil, err
+		}
+		if s, err = script.Parse([]byte(stripFence(text))); err != nil {
+			return nil, fmt.Errorf("model returned an invalid script: %w", err)
+		}
+	}
+	if s.Name == "" {
+		s.Name = name
+	}
+	return s, nil
+}
+
+// pickFrames returns the indexes of the events to send screenshots for,
+// spread evenly and always including the last.
+func (d *Distiller) pickFrames(events []Event) []int {
+	limit := d.MaxFrames
+	if limit <= 0 {
+		limit = 16
+	}
+	if len(events) <= limit {
+		picked := make([]int, len(events))
+		for i := range picked {
+			picked[i] = i
+		}
+		return picked
+	}
+	if limit == 1 {
+		return []int{len(events) - 1}
+	}
+	picked := make([]int, 0, limit)
+	for k := 0; k < limit; k++ {
+		picked = append(picked, k*(len(events)-1)/(limit-1))
+	}
+	return picked
+}
+
+func (d *Distiller) logf(format string, args ...any) {
+	if d.Logf != nil {
+		d.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// stripFence removes a Markdown code fence around the answer.
+func stripFence(text string) string {
+	text = strings.TrimSpace(text)
+	if rest, ok := strings.CutPrefix(text, "```"); ok {
+		rest = strings.TrimPrefix(rest, "json")
+		text = strings.TrimSuffix(strings.TrimSpace(rest), "```")
+	}
+	return strings.TrimSpace(text)
+}
+
+func loadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	return img, err
+}
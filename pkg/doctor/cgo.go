@@ -0,0 +1,7 @@
+//go:build cgo
+
+package doctor
+
+// Cgo reports whether agentGo was built with cgo, which robotgo, the
+// default input driver, needs.
+const Cgo = true
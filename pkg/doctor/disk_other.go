@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package doctor
+
+import "errors"
+
+// Free cannot tell the space available on this platform.
+func Free(path string) (uint64, error) {
+	return 0, errors.New("free space cannot be read on this platform")
+}
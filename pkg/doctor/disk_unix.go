@@ -0,0 +1,15 @@
+//go:build unix
+
+package doctor
+
+import "syscall"
+
+// Free returns the bytes available to this user on the file system of
+// path.
+func Free(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
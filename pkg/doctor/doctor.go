@@ -0,0 +1,89 @@
+// Package doctor diagnoses a machine agentGo is to run on: each check
+// reports whether what it looks at works and, when it does not, what to
+// do about it, so that a broken setup is fixed before a run fails halfway.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Status is the outcome of a check.
+type Status int
+
+// Statuses, from best to worst.
+const (
+	OK Status = iota
+	// Warning marks something that works but may cause trouble.
+	Warning
+	Failed
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Warning:
+		return "warning"
+	case Failed:
+		return "FAILED"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Finding is what a check found.
+type Finding struct {
+	Status Status `json:"status"`
+	// Detail says what was found, e.g. "2 displays, 3840x2160".
+	Detail string `json:"detail,omitempty"`
+	// Fix says what to do about a warning or failure.
+	Fix string `json:"fix,omitempty"`
+}
+
+// Pass returns an OK finding.
+func Pass(format string, args ...any) Finding {
+	return Finding{Status: OK, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Warn returns a warning with its fix.
+func Warn(detail, fix string) Finding {
+	return Finding{Status: Warning, Detail: detail, Fix: fix}
+}
+
+// Fail returns a failure with its fix.
+func Fail(detail, fix string) Finding {
+	return Finding{Status: Failed, Detail: detail, Fix: fix}
+}
+
+// Check is one diagnosis.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Finding
+}
+
+// Result is the finding of a check.
+type Result struct {
+	Name string `json:"name"`
+	Finding
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Run runs checks one after another, each bounded by timeout, and returns
+// their results and the worst status among them.
+func Run(ctx context.Context, checks []Check, timeout time.Duration) ([]Result, Status) {
+	results := make([]Result, 0, len(checks))
+	worst := OK
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		f := c.Run(checkCtx)
+		if checkCtx.Err() != nil && f.Status == OK {
+			f = Fail(fmt.Sprintf("did not finish within %v", timeout), "")
+		}
+		cancel()
+		results = append(results, Result{Name: c.Name, Finding: f, Duration: time.Since(start)})
+		worst = max(worst, f.Status)
+	}
+	return results, worst
+}
@@ -0,0 +1,43 @@
+package doctor
+
+import (
+	"path/filepath"
+	"runtime"
+)
+
+// nativeLibraries are the shared libraries robotgo links on Linux, with the
+// package that provides them on Debian and Ubuntu.
+var nativeLibraries = []struct{ name, pkg string }{
+	{"libX11.so.6", "libx11-dev"},
+	{"libXtst.so.6", "libxtst-dev"},
+	{"libX11-xcb.so.1", "libx11-xcb-dev"},
+	{"libxcb.so.1", "libxcb1-dev"},
+	{"libxkbcommon.so.0", "libxkbcommon-dev"},
+	{"libxkbcommon-x11.so.0", "libxkbcommon-x11-dev"},
+	{"libpng16.so.16", "libpng-dev"},
+}
+
+// libraryDirs are where Linux distributions install shared libraries.
+var libraryDirs = []string{"/lib", "/lib64", "/usr/lib", "/usr/lib64", "/usr/local/lib", "/lib/*-linux-gnu", "/usr/lib/*-linux-gnu"}
+
+// MissingLibraries returns the shared libraries robotgo needs that are
+// not installed, and the Debian packages providing them. Only Linux needs
+// any beyond the system's own.
+func MissingLibraries() (libs, pkgs []string) {
+	if runtime.GOOS != "linux" {
+		return nil, nil
+	}
+	for _, lib := range nativeLibraries {
+		found := false
+		for _, dir := range libraryDirs {
+			if matches, _ := filepath.Glob(filepath.Join(dir, lib.name)); len(matches) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			libs, pkgs = append(libs, lib.name), append(pkgs, lib.pkg)
+		}
+	}
+	return libs, pkgs
+}
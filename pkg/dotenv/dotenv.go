@@ -0,0 +1,95 @@
+// Package dotenv layers configuration from .env files, the environment and
+// command-line flags. Precedence, highest first: flags given on the command
+// line, the process environment, the .env file, and flag defaults. A
+// project-local .env can therefore hold its own GEMINI_API_KEY or
+// AGENTGO_MODEL without touching the shell's global environment, and any
+// flag can be given a per-project default as AGENTGO_<FLAG>, with dashes
+// written as underscores (e.g. AGENTGO_VISION_CONFIG for -vision-config).
+package dotenv
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"agentGo/pkg/vars"
+)
+
+// DefaultFile is the file LoadDefault reads from the working directory.
+const DefaultFile = ".env"
+
+// Prefix starts the environment variable names that supply flag values.
+const Prefix = "AGENTGO_"
+
+// Load reads NAME=VALUE lines from path into the process environment.
+// Variables that are already set keep their values. Blank lines, lines
+// starting with # and a leading "export " are ignored.
+func Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open env file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, err := vars.ParseAssignment(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		os.Setenv(name, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+	return nil
+}
+
+// LoadDefault loads .env from the working directory if there is one.
+func LoadDefault() error {
+	err := Load(DefaultFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// ApplyFlags sets every flag of set that was not given on the command line
+// from its AGENTGO_<FLAG> environment variable, if present. Call it after
+// set.Parse.
+func ApplyFlags(set *flag.FlagSet) error {
+	given := map[string]bool{}
+	set.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	var err error
+	set.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || err != nil {
+			return
+		}
+		name := EnvName(f.Name)
+		if value, ok := os.LookupEnv(name); ok {
+			if serr := set.Set(f.Name, value); serr != nil {
+				err = fmt.Errorf("invalid %s: %w", name, serr)
+			}
+		}
+	})
+	return err
+}
+
+// EnvName returns the environment variable that supplies flag name.
+func EnvName(name string) string {
+	return Prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
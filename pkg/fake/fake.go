@@ -0,0 +1,263 @@
+// Package fake is a desktop for tests and CI: its screen is served from
+// saved images and the input sent to it is recorded in memory instead of
+// performed. It needs no display server and, unlike the local driver, no
+// cgo, so record, analyze and play pipelines can run anywhere.
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"agentGo/pkg/frame"
+	"agentGo/pkg/script"
+)
+
+// Kinds of action.
+const (
+	ActionMove  = "move"
+	ActionClick = "click"
+	ActionType  = "type"
+	ActionKey   = "key"
+	ActionDown  = "down"
+	ActionUp    = "up"
+)
+
+// Action is input sent to the desktop.
+type Action struct {
+	// Time is the offset from the first action.
+	Time time.Duration `json:"t"`
+	Kind string        `json:"kind"`
+	// X and Y are the pointer's normalized position.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// Button is set on clicks, downs and ups, and Text on typing and keys.
+	Button string `json:"button,omitempty"`
+	Text   string `json:"text,omitempty"`
+}
+
+// Desktop is a fake desktop. It implements the desktop driver methods
+// (Move, Click, Type, KeyTap, Capture, Close) and script.ButtonPresser.
+//
+// Each capture returns the next frame, holding the last once they run out,
+// or starting over if Loop is set.
+type Desktop struct {
+	// Loop makes captures start over from the first frame after the last.
+	Loop bool
+	// StepOnInput moves on to the next frame after each click, typing, key
+	// or button release instead of after each capture, so that saved frames
+	// play the screen's successive states as the input changes it.
+	StepOnInput bool
+	// Clock times actions. It defaults to the system clock; tests can share
+	// a script.VirtualClock with the runner or player.
+	Clock script.Clock
+	// LogPath, if set, is where Close writes the actions, one JSON object
+	// per line.
+	LogPath string
+
+	mu      sync.Mutex
+	frames  []image.Image
+	next    int
+	shown   int
+	x, y    float64
+	start   time.Time
+	actions []Action
+}
+
+// New returns a desktop showing frames in turn. It needs at least one.
+func New(frames ...image.Image) (*Desktop, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("a fake desktop needs at least one frame")
+	}
+	return &Desktop{frames: frames, shown: -1}, nil
+}
+
+// Open returns a desktop showing the PNG and JPEG images in dir in name
+// order, or the single image file dir names.
+func Open(dir string) (*Desktop, error) {
+	paths := []string{dir}
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if fi.IsDir() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = paths[:0]
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".png", ".jpg", ".jpeg":
+				paths = append(paths, filepath.Join(dir, e.Name()))
+			}
+		}
+		sort.Strings(paths)
+	}
+	frames := make([]image.Image, 0, len(paths))
+	for _, path := range paths {
+		img, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, img)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no PNG or JPEG frames in %s", dir)
+	}
+	return New(frames...)
+}
+
+func load(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Capture returns a copy of the current frame and moves on to the next.
+// Copies are made because callers may recycle captures (see frame.Put).
+func (d *Desktop) Capture() (image.Image, error) {
+	d.mu.Lock()
+	src := d.frames[d.next]
+	d.shown = d.next
+	if !d.StepOnInput {
+		d.advance()
+	}
+	d.mu.Unlock()
+	img := frame.Get(src.Bounds())
+	draw.Draw(img, img.Rect, src, src.Bounds().Min, draw.Src)
+	return img, nil
+}
+
+// Move implements the driver method, recording the move.
+func (d *Desktop) Move(normX, normY float64) error {
+	if normX < 0 || normX > 1 || normY < 0 || normY > 1 {
+		return fmt.Errorf("position (%.4f, %.4f) is off the screen", normX, normY)
+	}
+	d.mu.Lock()
+	d.x, d.y = normX, normY
+	d.mu.Unlock()
+	d.record(Action{Kind: ActionMove})
+	return nil
+}
+
+// Click implements the driver method, recording the click.
+func (d *Desktop) Click(button string) error {
+	d.record(Action{Kind: ActionClick, Button: button})
+	return nil
+}
+
+// Type implements the driver method, recording the text.
+func (d *Desktop) Type(text string) error {
+	d.record(Action{Kind: ActionType, Text: text})
+	return nil
+}
+
+// KeyTap implements the driver method, recording the key.
+func (d *Desktop) KeyTap(key string) error {
+	d.record(Action{Kind: ActionKey, Text: key})
+	return nil
+}
+
+// MouseDown implements script.ButtonPresser.
+func (d *Desktop) MouseDown(button string) error {
+	d.record(Action{Kind: ActionDown, Button: button})
+	return nil
+}
+
+// MouseUp implements script.ButtonPresser.
+func (d *Desktop) MouseUp(button string) error {
+	d.record(Action{Kind: ActionUp, Button: button})
+	return nil
+}
+
+func (d *Desktop) record(a Action) {
+	clock := d.Clock
+	if clock == nil {
+		clock = script.SystemClock{}
+	}
+	now := clock.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.start.IsZero() {
+		d.start = now
+	}
+	a.Time = now.Sub(d.start)
+	a.X, a.Y = d.x, d.y
+	d.actions = append(d.actions, a)
+	if d.StepOnInput && a.Kind != ActionMove && a.Kind != ActionDown {
+		d.advance()
+	}
+}
+
+// advance moves on to the next frame. d.mu must be held.
+func (d *Desktop) advance() {
+	switch {
+	case d.next < len(d.frames)-1:
+		d.next++
+	case d.Loop:
+		d.next = 0
+	}
+}
+
+// Shown returns the index of the frame the last capture returned, or -1
+// before the first.
+func (d *Desktop) Shown() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.shown
+}
+
+// Frames returns the number of frames.
+func (d *Desktop) Frames() int {
+	return len(d.frames)
+}
+
+// Actions returns the input sent so far, oldest first.
+func (d *Desktop) Actions() []Action {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Action(nil), d.actions...)
+}
+
+// Position returns the pointer's normalized position.
+func (d *Desktop) Position() (float64, float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.x, d.y
+}
+
+// Close implements the driver method, writing the actions to LogPath if it
+// is set.
+func (d *Desktop) Close() error {
+	if d.LogPath == "" {
+		return nil
+	}
+	f, err := os.Create(d.LogPath)
+	if err != nil {
+		return fmt.Errorf("failed to write fake desktop log: %w", err)
+	}
+	enc := json.NewEncoder(f)
+	for _, a := range d.Actions() {
+		if err := enc.Encode(a); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write fake desktop log: %w", err)
+		}
+	}
+	return f.Close()
+}
@@ -0,0 +1,259 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agentGo/pkg/script"
+	"agentGo/pkg/vision"
+)
+
+var (
+	white = color.RGBA{255, 255, 255, 255}
+	red   = color.RGBA{255, 0, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+)
+
+// button is a colored rectangle on a frame.
+type button struct {
+	c    color.RGBA
+	rect image.Rectangle
+}
+
+// writeFrames saves a 200x100 white frame for each entry of frames, with
+// its buttons drawn on it, as 0.png, 1.png and so on, which is what a
+// fake://dir driver serves.
+func writeFrames(t *testing.T, frames ...[]button) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, buttons := range frames {
+		img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+		draw.Draw(img, img.Rect, image.NewUniform(white), image.Point{}, draw.Src)
+		for _, b := range buttons {
+			draw.Draw(img, b.rect, image.NewUniform(b.c), image.Point{}, draw.Src)
+		}
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%d.png", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// colorVision finds "the red button" and "the blue button" by their color,
+// as a vision model would find them by description.
+type colorVision struct{}
+
+func (colorVision) find(img image.Image, target string) (image.Rectangle, bool) {
+	want := map[string]color.RGBA{"the red button": red, "the blue button": blue}[target]
+	var found image.Rectangle
+	r := img.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if color.RGBAModel.Convert(img.At(x, y)) == want {
+				found = found.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return found, !found.Empty()
+}
+
+func (v colorVision) Locate(ctx context.Context, img image.Image, target string) (vision.Point, error) {
+	found, ok := v.find(img, target)
+	if !ok {
+		return vision.Point{}, fmt.Errorf("%s is not on the screen", target)
+	}
+	return vision.Point{X: float64(found.Min.X+found.Max.X) / 2, Y: float64(found.Min.Y+found.Max.Y) / 2}, nil
+}
+
+func (colorVision) ReadText(ctx context.Context, img image.Image, target string) (string, error) {
+	return "", errors.New("no text")
+}
+
+func (v colorVision) Visible(ctx context.Context, img image.Image, target string) (bool, error) {
+	_, ok := v.find(img, target)
+	return ok, nil
+}
+
+func run(t *testing.T, d *Desktop, clock script.Clock, src string) error {
+	t.Helper()
+	s, err := script.Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &script.Runner{Exec: d, Vision: colorVision{}, Clock: clock, Logf: t.Logf}
+	return r.Run(context.Background(), s)
+}
+
+func TestScriptClicksTargets(t *testing.T) {
+	// The red button opens a screen with a blue one where it was not.
+	dir := writeFrames(t,
+		[]button{{red, image.Rect(20, 20, 60, 40)}},
+		[]button{{blue, image.Rect(140, 60, 180, 80)}},
+	)
+	d, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.StepOnInput = true
+	clock := script.NewVirtualClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	d.Clock = clock
+	err = run(t, d, clock, `{"steps": [
+		{"action": "click", "target": "the red button"},
+		{"action": "wait", "duration": "1s"},
+		{"action": "click", "target": "the blue button", "button": "right"},
+		{"action": "type", "text": "done"}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Action{
+		{Time: 0, Kind: ActionMove, X: 0.2, Y: 0.3},
+		{Time: 0, Kind: ActionClick, X: 0.2, Y: 0.3, Button: "left"},
+		{Time: time.Second, Kind: ActionMove, X: 0.8, Y: 0.7},
+		{Time: time.Second, Kind: ActionClick, X: 0.8, Y: 0.7, Button: "right"},
+		{Time: time.Second, Kind: ActionType, X: 0.8, Y: 0.7, Text: "done"},
+	}
+	got := d.Actions()
+	if len(got) != len(want) {
+		t.Fatalf("got %d actions, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("action %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestScriptMissingTarget(t *testing.T) {
+	d, err := Open(writeFrames(t, []button{{red, image.Rect(20, 20, 60, 40)}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(t, d, script.NewVirtualClock(time.Time{}), `{"steps": [{"action": "click", "target": "the blue button"}]}`)
+	if err == nil || !strings.Contains(err.Error(), "failed to find the blue button") {
+		t.Errorf("Run returned %v, want an error finding the blue button", err)
+	}
+	if actions := d.Actions(); len(actions) != 0 {
+		t.Errorf("a failed click sent input: %+v", actions)
+	}
+}
+
+func TestScriptWaitTimeouts(t *testing.T) {
+	still := writeFrames(t, []button{{red, image.Rect(20, 20, 60, 40)}})
+	// A button that blinks: two frames shown in turn.
+	blinking := writeFrames(t, []button{{red, image.Rect(20, 20, 60, 40)}}, nil)
+	tests := []struct {
+		name    string
+		dir     string
+		loop    bool
+		step    string
+		elapsed time.Duration
+		err     string
+	}{
+		{
+			name:    "still screen settles",
+			dir:     still,
+			step:    `{"action": "wait_until_idle", "timeout": "5s", "settle": "2s"}`,
+			elapsed: 2 * time.Second,
+		},
+		{
+			name:    "changing screen times out",
+			dir:     blinking,
+			loop:    true,
+			step:    `{"action": "wait_until_idle", "timeout": "3s", "settle": "1s"}`,
+			elapsed: 3 * time.Second,
+			err:     "not idle after 3s",
+		},
+		{
+			name: "screen that stops changing settles",
+			dir:  blinking,
+			// Without Loop the last frame is held once the first has
+			// been shown.
+			step:    `{"action": "wait_until_idle", "timeout": "3s", "settle": "1s"}`,
+			elapsed: 1250 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Open(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.Loop = tt.loop
+			clock := script.NewVirtualClock(time.Time{})
+			err = run(t, d, clock, `{"steps": [`+tt.step+`]}`)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("Run: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("Run returned %v, want an error containing %q", err, tt.err)
+			}
+			if got := clock.Elapsed(); got != tt.elapsed {
+				t.Errorf("waited %v of clock time, want %v", got, tt.elapsed)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := writeFrames(t, nil, nil, nil)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a frame"), 0o644)
+	d, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Frames() != 3 {
+		t.Errorf("opened %d frames, want 3", d.Frames())
+	}
+	d, err = Open(filepath.Join(dir, "1.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Frames() != 1 {
+		t.Errorf("opened %d frames from one file, want 1", d.Frames())
+	}
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("Open of a directory without frames succeeded")
+	}
+}
+
+func TestLog(t *testing.T) {
+	d, err := Open(writeFrames(t, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.LogPath = filepath.Join(t.TempDir(), "actions.jsonl")
+	d.Clock = script.NewVirtualClock(time.Time{})
+	d.Move(0.5, 0.25)
+	d.KeyTap("enter")
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(d.LogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"t":0,"kind":"move","x":0.5,"y":0.25}
+{"t":0,"kind":"key","x":0.5,"y":0.25,"text":"enter"}
+`
+	if string(data) != want {
+		t.Errorf("log is\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,49 @@
+package fake
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"agentGo/pkg/capture"
+	"agentGo/pkg/frame"
+)
+
+// Screen returns d as a capture backend with a single display the size of
+// its frames, so code that captures through capture.Backend (for example
+// desktop.UseBackend) can be fed saved images.
+func (d *Desktop) Screen() capture.Backend {
+	return screen{d}
+}
+
+type screen struct{ d *Desktop }
+
+func (s screen) Displays() []capture.Display {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return []capture.Display{{Bounds: s.d.frames[s.d.next].Bounds(), Scale: 1, Primary: true}}
+}
+
+// Capture returns rect of the current frame, moving on to the next as
+// Desktop.Capture does.
+func (s screen) Capture(rect image.Rectangle) (*image.RGBA, error) {
+	img, err := s.d.Capture()
+	if err != nil {
+		return nil, err
+	}
+	full := img.(*image.RGBA)
+	if rect == full.Rect {
+		return full, nil
+	}
+	defer frame.Put(full)
+	if !rect.In(full.Rect) {
+		return nil, fmt.Errorf("%v is outside the screen %v", rect, full.Rect)
+	}
+	out := frame.Get(rect)
+	draw.Draw(out, rect, full, rect.Min, draw.Src)
+	return out, nil
+}
+
+func (screen) CaptureWindow(int) (*image.RGBA, error) {
+	return nil, capture.ErrUnsupported
+}
@@ -0,0 +1,66 @@
+// Package filelock takes locks on files that other processes, and other
+// opens of the same file in this one, respect, so that agentgo commands
+// sharing a file or the desktop take turns. A lock is released when it is
+// released, or when its process exits.
+package filelock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned by Try when someone else holds the lock.
+var ErrLocked = errors.New("locked by another process")
+
+// pollInterval is how often Acquire tries a held lock again.
+const pollInterval = 100 * time.Millisecond
+
+// Lock is a held lock.
+type Lock struct {
+	f *os.File
+}
+
+// Try takes the lock on path, creating the file and its directory if
+// needed, or returns ErrLocked at once if it is held.
+func Try(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock: %w", err)
+	}
+	if err := lock(f); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Acquire takes the lock on path, waiting for whoever holds it to release
+// it until ctx is done.
+func Acquire(ctx context.Context, path string) (*Lock, error) {
+	for {
+		l, err := Try(path)
+		if !errors.Is(err, ErrLocked) {
+			return l, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	return l.f.Close()
+}
@@ -0,0 +1,32 @@
+package filelock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "test.lock")
+	l, err := Try(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Try(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second Try returned %v, want ErrLocked", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*pollInterval)
+	defer cancel()
+	if _, err := Acquire(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire of a held lock returned %v, want the context's error", err)
+	}
+
+	time.AfterFunc(2*pollInterval, func() { l.Release() })
+	l2, err := Acquire(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	l2.Release()
+}
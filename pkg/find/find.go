@@ -0,0 +1,155 @@
+// Package find brings off-screen elements into view: when the target is
+// not visible, it scrolls the container it should be in a few wheel steps
+// at a time, looking again after each scroll, until the target appears or
+// the scroll budget runs out. Long pages and lists need this before
+// anything on them can be clicked by description.
+package find
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+
+	"agentGo/pkg/vision"
+)
+
+// ErrNotFound is returned when the target did not appear within the scroll
+// budget.
+var ErrNotFound = errors.New("target not found")
+
+// Vision answers whether and where elements are shown, as vision.Client
+// does.
+type Vision interface {
+	Visible(ctx context.Context, img image.Image, target string) (bool, error)
+	Locate(ctx context.Context, img image.Image, target string) (vision.Point, error)
+}
+
+// Scroller moves the pointer and turns the mouse wheel.
+type Scroller interface {
+	Move(normX, normY float64) error
+	Click(button string) error
+}
+
+// Finder scrolls until targets come into view.
+type Finder struct {
+	Vision   Vision
+	Scroller Scroller
+	Capture  func() (image.Image, error)
+	// Budget is the most scrolls made looking for a target; 0 means 10.
+	Budget int
+	// Steps is the number of wheel clicks per scroll; 0 means 3.
+	Steps int
+	// Container describes the scrollable area to search, e.g. "the
+	// results list". If empty, the model picks the one the target
+	// belongs in.
+	Container string
+	Logf      func(format string, args ...any)
+}
+
+// Find returns where target is, as 0-1 fractions of the screen, scrolling
+// it into view first if needed.
+func (f *Finder) Find(ctx context.Context, target string) (float64, float64, error) {
+	budget := f.Budget
+	if budget <= 0 {
+		budget = 10
+	}
+	img, err := f.Capture()
+	if err != nil {
+		return 0, 0, err
+	}
+	if x, y, ok, err := f.look(ctx, img, target); err != nil || ok {
+		return x, y, err
+	}
+
+	// Point at the container so the wheel scrolls it rather than whatever
+	// is under the pointer.
+	container := f.Container
+	if container == "" {
+		container = fmt.Sprintf("the scrollable list, table or page area where %s would most likely be", target)
+	}
+	p, err := f.Vision.Locate(ctx, img, container)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find %s: %w", container, err)
+	}
+	cx, cy := p.Normalize(img.Bounds())
+	if err := f.Scroller.Move(cx, cy); err != nil {
+		return 0, 0, err
+	}
+
+	wheel := "wheelDown"
+	for scroll := 1; scroll <= budget; scroll++ {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+		if err := f.turn(wheel); err != nil {
+			return 0, 0, err
+		}
+		next, err := f.Capture()
+		if err != nil {
+			return 0, 0, err
+		}
+		if !moved(img, next) {
+			if wheel == "wheelUp" {
+				// Both ends reached.
+				break
+			}
+			// The end of the container: search back the other way.
+			f.logf("reached the end of %s; scrolling up", container)
+			wheel = "wheelUp"
+			img = next
+			continue
+		}
+		img = next
+		f.logf("scrolled %s looking for %s (%d/%d)", wheel, target, scroll, budget)
+		if x, y, ok, err := f.look(ctx, img, target); err != nil || ok {
+			return x, y, err
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: %s, after scrolling %s", ErrNotFound, target, container)
+}
+
+// look reports whether target is on img and where.
+func (f *Finder) look(ctx context.Context, img image.Image, target string) (float64, float64, bool, error) {
+	visible, err := f.Vision.Visible(ctx, img, target)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if !visible {
+		return 0, 0, false, nil
+	}
+	p, err := f.Vision.Locate(ctx, img, target)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	x, y := p.Normalize(img.Bounds())
+	return x, y, true, nil
+}
+
+func (f *Finder) turn(wheel string) error {
+	steps := f.Steps
+	if steps <= 0 {
+		steps = 3
+	}
+	for i := 0; i < steps; i++ {
+		if err := f.Scroller.Click(wheel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moved reports whether scrolling changed the screen.
+func moved(a, b image.Image) bool {
+	d, err := vision.CompareImages(a, b, 24)
+	return err != nil || d.Fraction > 0.001
+}
+
+func (f *Finder) logf(format string, args ...any) {
+	if f.Logf != nil {
+		f.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,212 @@
+// Package fleet dispatches tasks across a set of agentGo workers, selecting
+// hosts by label and collecting the results. Workers are reached over the
+// HTTP/JSON worker API of package server, which explains why it is not
+// gRPC.
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"agentGo/pkg/server"
+)
+
+// Host is a worker in the fleet.
+type Host struct {
+	Addr   string            `json:"addr"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// LoadHosts reads a hosts file of the form {"hosts": [{"addr": ..., "labels": {...}}]}.
+func LoadHosts(path string) ([]Host, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+	var f struct {
+		Hosts []Host `json:"hosts"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse hosts file %s: %w", path, err)
+	}
+	return f.Hosts, nil
+}
+
+// Selector matches hosts whose labels contain every key=value pair.
+type Selector map[string]string
+
+// ParseSelector parses "key=value,key2=value2". An empty string matches
+// every host.
+func ParseSelector(s string) (Selector, error) {
+	sel := make(Selector)
+	if strings.TrimSpace(s) == "" {
+		return sel, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid selector %q, expected key=value", pair)
+		}
+		sel[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels satisfy the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for k, v := range s {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Result is the outcome of one task.
+type Result struct {
+	Task     string        `json:"task"`
+	Host     string        `json:"host"`
+	TaskID   string        `json:"task_id,omitempty"`
+	State    string        `json:"state"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Coordinator runs tasks on a fleet of workers.
+type Coordinator struct {
+	Hosts []Host
+	// PollInterval is how often task status is polled. It defaults to 1s.
+	PollInterval time.Duration
+	// Connect, if set, returns the client of the worker at addr, e.g. with
+	// a token; it defaults to server.NewClient.
+	Connect func(addr string) *server.Client
+	Logf    func(format string, args ...any)
+}
+
+// Dispatch runs every task on a host matching sel. Each host runs one task
+// at a time; tasks are handed to whichever matching host is free next.
+// Results are returned in task order.
+func (c *Coordinator) Dispatch(ctx context.Context, sel Selector, tasks []server.TaskRequest) ([]Result, error) {
+	hosts := c.matchingHosts(ctx, sel)
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no reachable hosts match selector %v", sel)
+	}
+	c.logf("dispatching %d tasks across %d hosts", len(tasks), len(hosts))
+
+	results := make([]Result, len(tasks))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for _, h := range hosts {
+		wg.Add(1)
+		go func(h Host) {
+			defer wg.Done()
+			client := c.connect(h.Addr)
+			for i := range next {
+				results[i] = c.runOne(ctx, client, h, tasks[i])
+			}
+		}(h)
+	}
+	for i := range tasks {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			for j := i; j < len(tasks); j++ {
+				results[j] = Result{Task: tasks[j].Name, State: server.StateCancelled, Error: ctx.Err().Error()}
+			}
+			close(next)
+			wg.Wait()
+			return results, ctx.Err()
+		}
+	}
+	close(next)
+	wg.Wait()
+	return results, nil
+}
+
+func (c *Coordinator) runOne(ctx context.Context, client *server.Client, h Host, req server.TaskRequest) Result {
+	res := Result{Task: req.Name, Host: h.Addr}
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	t, err := client.Submit(ctx, req)
+	if err != nil {
+		res.State = server.StateFailed
+		res.Error = err.Error()
+		return res
+	}
+	res.TaskID = t.ID
+	c.logf("task %q: submitted to %s as %s", req.Name, h.Addr, t.ID)
+
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t, err = client.Wait(ctx, t.ID, interval)
+	res.State = t.State
+	res.Error = t.Error
+	if err != nil {
+		res.State = server.StateFailed
+		res.Error = err.Error()
+	}
+	c.logf("task %q on %s: %s", req.Name, h.Addr, res.State)
+	return res
+}
+
+// matchingHosts returns reachable hosts whose labels match sel. Labels
+// reported by a worker are merged over those in the hosts file.
+func (c *Coordinator) matchingHosts(ctx context.Context, sel Selector) []Host {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var hosts []Host
+	for _, h := range c.Hosts {
+		wg.Add(1)
+		go func(h Host) {
+			defer wg.Done()
+			infoCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			info, err := c.connect(h.Addr).Info(infoCtx)
+			if err != nil {
+				c.logf("host %s unreachable: %v", h.Addr, err)
+				return
+			}
+			labels := make(map[string]string)
+			for k, v := range h.Labels {
+				labels[k] = v
+			}
+			for k, v := range info.Labels {
+				labels[k] = v
+			}
+			if !sel.Matches(labels) {
+				return
+			}
+			mu.Lock()
+			hosts = append(hosts, Host{Addr: h.Addr, Labels: labels})
+			mu.Unlock()
+		}(h)
+	}
+	wg.Wait()
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Addr < hosts[j].Addr })
+	return hosts
+}
+
+func (c *Coordinator) connect(addr string) *server.Client {
+	if c.Connect != nil {
+		return c.Connect(addr)
+	}
+	return server.NewClient(addr)
+}
+
+func (c *Coordinator) logf(format string, args ...any) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,155 @@
+// Package formfill fills an on-screen form from a data record. Instead of
+// replaying pixel-exact recordings, it asks the vision model where each
+// labeled field is, types the value, and reads it back to verify.
+package formfill
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"runtime"
+	"strings"
+
+	"agentGo/pkg/script"
+	"agentGo/pkg/vision"
+)
+
+// Field is one labeled form field and the value to enter into it.
+type Field struct {
+	Label string
+	Value string
+}
+
+// Vision locates fields and reads their contents on a screenshot.
+type Vision interface {
+	Locate(ctx context.Context, img image.Image, target string) (vision.Point, error)
+	ReadText(ctx context.Context, img image.Image, target string) (string, error)
+}
+
+// Result reports how a single field was filled.
+type Result struct {
+	Field
+	Verified bool
+	// Read is the text the model read back from the field after typing.
+	Read     string
+	Attempts int
+	Err      error
+}
+
+// Filler fills forms on screen.
+type Filler struct {
+	Vision  Vision
+	Exec    script.Executor
+	Capture func() (image.Image, error)
+	// Retries is how many extra attempts are made for a field whose value
+	// does not verify.
+	Retries int
+	// NoVerify skips reading values back, e.g. for password fields.
+	NoVerify bool
+	Logf     func(format string, args ...any)
+}
+
+// Fill fills each field in order. It returns one result per field and an
+// error if any field could not be filled and verified.
+func (f *Filler) Fill(ctx context.Context, fields []Field) ([]Result, error) {
+	results := make([]Result, 0, len(fields))
+	failed := 0
+	for _, field := range fields {
+		res := f.fillField(ctx, field)
+		if res.Err != nil || (!f.NoVerify && !res.Verified) {
+			failed++
+		}
+		results = append(results, res)
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d fields could not be filled and verified", failed, len(fields))
+	}
+	return results, nil
+}
+
+func (f *Filler) fillField(ctx context.Context, field Field) Result {
+	res := Result{Field: field}
+	target := fmt.Sprintf("the input field labeled %q (the editable box, not the label text)", field.Label)
+
+	for res.Attempts <= f.Retries {
+		res.Attempts++
+
+		img, err := f.Capture()
+		if err != nil {
+			res.Err = fmt.Errorf("failed to capture screen: %w", err)
+			return res
+		}
+		p, err := f.Vision.Locate(ctx, img, target)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to locate field %q: %w", field.Label, err)
+			f.logf("field %q: %v", field.Label, res.Err)
+			continue
+		}
+		res.Err = nil
+
+		normX, normY := p.Normalize(img.Bounds())
+		if err := f.Exec.Move(normX, normY); err != nil {
+			res.Err = err
+			return res
+		}
+		if err := f.Exec.Click("left"); err != nil {
+			res.Err = err
+			return res
+		}
+		// Replace any existing content rather than appending to it.
+		if err := f.Exec.KeyTap(selectAllKey()); err != nil {
+			res.Err = err
+			return res
+		}
+		if err := f.Exec.Type(field.Value); err != nil {
+			res.Err = err
+			return res
+		}
+
+		if f.NoVerify {
+			f.logf("field %q: filled (unverified)", field.Label)
+			return res
+		}
+
+		img, err = f.Capture()
+		if err != nil {
+			res.Err = fmt.Errorf("failed to capture screen: %w", err)
+			return res
+		}
+		res.Read, err = f.Vision.ReadText(ctx, img, target)
+		if err != nil {
+			res.Err = fmt.Errorf("failed to read back field %q: %w", field.Label, err)
+			continue
+		}
+		if normalize(res.Read) == normalize(field.Value) {
+			res.Verified = true
+			f.logf("field %q: filled and verified", field.Label)
+			return res
+		}
+		f.logf("field %q: expected %q but read %q (attempt %d)", field.Label, field.Value, res.Read, res.Attempts)
+	}
+	return res
+}
+
+func (f *Filler) logf(format string, args ...any) {
+	if f.Logf != nil {
+		f.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func selectAllKey() string {
+	if runtime.GOOS == "darwin" {
+		return "cmd+a"
+	}
+	return "ctrl+a"
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,77 @@
+// Package frame recycles the memory screen captures go through. Capturing
+// and encoding a frame allocates a screen-sized image and a buffer for its
+// PNG, which at streaming and polling rates keeps the garbage collector
+// busy; pooling them lets each frame reuse the last one's memory.
+//
+// A frame handed to Put must no longer be used by anyone, so only code that
+// owns a frame from capture to disposal, such as a polling loop, returns it.
+package frame
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"sync"
+)
+
+var images sync.Pool
+
+// Get returns an RGBA image with bounds r, reusing a pooled image's pixels
+// if one is large enough. The pixels are not cleared: callers draw over the
+// whole image.
+func Get(r image.Rectangle) *image.RGBA {
+	n := 4 * r.Dx() * r.Dy()
+	if img, ok := images.Get().(*image.RGBA); ok && cap(img.Pix) >= n {
+		img.Pix, img.Stride, img.Rect = img.Pix[:n], 4*r.Dx(), r
+		return img
+	}
+	return image.NewRGBA(r)
+}
+
+// Put returns a frame for reuse by Get. Images other than *image.RGBA are
+// left to the garbage collector.
+func Put(img image.Image) {
+	if rgba, ok := img.(*image.RGBA); ok && rgba != nil {
+		images.Put(rgba)
+	}
+}
+
+// encoderBuffers lets the PNG encoder reuse its compression state, which
+// is several hundred kilobytes, across frames.
+type encoderBuffers struct{ pool sync.Pool }
+
+func (b *encoderBuffers) Get() *png.EncoderBuffer {
+	buf, _ := b.pool.Get().(*png.EncoderBuffer)
+	return buf
+}
+
+func (b *encoderBuffers) Put(buf *png.EncoderBuffer) {
+	b.pool.Put(buf)
+}
+
+var encoder = png.Encoder{BufferPool: &encoderBuffers{}}
+
+var buffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// EncodePNG encodes img as PNG. The output grows in a pooled buffer and is
+// copied out once at its final size.
+func EncodePNG(img image.Image) ([]byte, error) {
+	buf := buffers.Get().(*bytes.Buffer)
+	defer buffers.Put(buf)
+	buf.Reset()
+	if err := encode(buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// AppendPNG appends the PNG encoding of img to dst, so that a caller sending
+// frame after frame can reuse one slice.
+func AppendPNG(dst []byte, img image.Image) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	if err := encode(buf, img); err != nil {
+		return dst, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,118 @@
+package frame
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+)
+
+// screenshot returns an opaque image that compresses about as well as a
+// desktop does: flat panels with lines of text-like speckle.
+func screenshot(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	rng := rand.New(rand.NewPCG(1, 2))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{240, 240, 240, 255}
+			if (x/200+y/150)%3 == 0 {
+				c = color.RGBA{40, 60, 90, 255}
+			}
+			if y%20 < 12 && x%300 < 220 && rng.IntN(3) == 0 {
+				c = color.RGBA{20, 20, 20, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestEncodePNG(t *testing.T) {
+	for _, size := range []image.Point{{64, 48}, {1920, 1080}} {
+		img := screenshot(size.X, size.Y)
+		data, err := EncodePNG(img)
+		if err != nil {
+			t.Fatalf("%v: %v", size, err)
+		}
+		got, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%v: decoding: %v", size, err)
+		}
+		if !sameImage(got, img) {
+			t.Errorf("%v: decoded image differs from the original", size)
+		}
+	}
+}
+
+func TestGetReusesPut(t *testing.T) {
+	r := image.Rect(0, 0, 320, 200)
+	img := Get(r)
+	Put(img)
+	// The pool may drop entries, so only what Get returns is checked.
+	again := Get(image.Rect(10, 10, 110, 60))
+	if again.Rect != image.Rect(10, 10, 110, 60) || again.Stride != 400 || len(again.Pix) != 4*100*50 {
+		t.Errorf("Get returned rect %v, stride %d, %d bytes", again.Rect, again.Stride, len(again.Pix))
+	}
+}
+
+// sameImage reports whether a and b have the same size and pixels. PNG
+// does not keep an image's origin, so a decoded image starts at 0,0.
+func sameImage(a, b image.Image) bool {
+	ra, rb := a.Bounds(), b.Bounds()
+	if ra.Size() != rb.Size() {
+		return false
+	}
+	for y := 0; y < ra.Dy(); y++ {
+		for x := 0; x < ra.Dx(); x++ {
+			ca := color.RGBAModel.Convert(a.At(ra.Min.X+x, ra.Min.Y+y))
+			cb := color.RGBAModel.Convert(b.At(rb.Min.X+x, rb.Min.Y+y))
+			if ca != cb {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func BenchmarkEncodePNG(b *testing.B) {
+	img := screenshot(1920, 1080)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := EncodePNG(img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCapture compares a frame's trip from the screen to PNG bytes
+// with pooled images and buffers against allocating them for every frame,
+// with the same encoder.
+func BenchmarkCapture(b *testing.B) {
+	screen := screenshot(1920, 1080)
+	r := screen.Bounds()
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			img := Get(r)
+			draw.Draw(img, r, screen, r.Min, draw.Src)
+			if _, err := EncodePNG(img); err != nil {
+				b.Fatal(err)
+			}
+			Put(img)
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			img := image.NewRGBA(r)
+			draw.Draw(img, r, screen, r.Min, draw.Src)
+			var buf bytes.Buffer
+			if err := encode(&buf, img); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
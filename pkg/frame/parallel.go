@@ -0,0 +1,263 @@
+package frame
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"hash/adler32"
+	"hash/crc32"
+	"image"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// Large frames are encoded in parallel: the rows are split into strips that
+// are filtered and compressed on separate cores, the way pigz does, and the
+// strips' deflate streams joined into one PNG. A strip encodes about as fast
+// as image/png encodes the same rows, so a frame encodes about as many times
+// faster as there are cores, for output about 1% larger.
+
+// parallelMin is the smallest frame, in pixels, worth splitting up.
+const parallelMin = 1 << 20
+
+// minStripRows keeps strips big enough to compress well.
+const minStripRows = 64
+
+// encode writes img to w as PNG: in strips if it is a large opaque RGBA
+// image, as screenshots are, and with image/png otherwise.
+func encode(w io.Writer, img image.Image) error {
+	rgba, ok := img.(*image.RGBA)
+	if !ok || rgba.Rect.Dx()*rgba.Rect.Dy() < parallelMin || !rgba.Opaque() {
+		return encoder.Encode(w, img)
+	}
+	return encodeParallel(w, rgba, runtime.GOMAXPROCS(0))
+}
+
+var compressors = sync.Pool{New: func() any {
+	// BestSpeed costs a few percent in size over the default level and
+	// halves the time.
+	w, _ := flate.NewWriter(nil, flate.BestSpeed)
+	return w
+}}
+
+// strip is one compressed run of rows.
+type strip struct {
+	data  bytes.Buffer
+	sum   uint32 // adler-32 of the filtered rows
+	n     int    // length of the filtered rows
+	first bool
+	last  bool
+}
+
+func encodeParallel(w io.Writer, img *image.RGBA, procs int) error {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	rows := max((height+procs-1)/procs, minStripRows)
+	strips := make([]strip, (height+rows-1)/rows)
+
+	var wg sync.WaitGroup
+	for i := range strips {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := &strips[i]
+			s.first, s.last = i == 0, i == len(strips)-1
+			compressStrip(s, img, i*rows, min((i+1)*rows, height))
+		}(i)
+	}
+	wg.Wait()
+
+	sum := strips[0].sum
+	for _, s := range strips[1:] {
+		sum = adler32Combine(sum, s.sum, s.n)
+	}
+
+	var header [13]byte
+	binary.BigEndian.PutUint32(header[0:], uint32(width))
+	binary.BigEndian.PutUint32(header[4:], uint32(height))
+	header[8] = 8 // bits per channel
+	header[9] = 2 // truecolour
+	if _, err := io.WriteString(w, "\x89PNG\r\n\x1a\n"); err != nil {
+		return err
+	}
+	if err := writeChunk(w, "IHDR", header[:]); err != nil {
+		return err
+	}
+	for _, s := range strips {
+		data := s.data.Bytes()
+		if s.last {
+			data = binary.BigEndian.AppendUint32(data, sum)
+		}
+		if err := writeChunk(w, "IDAT", data); err != nil {
+			return err
+		}
+	}
+	return writeChunk(w, "IEND", nil)
+}
+
+// compressStrip filters and deflates rows y0 to y1 of img. Every strip but
+// the last ends on a sync flush rather than a final block, so the strips'
+// streams join into one.
+func compressStrip(s *strip, img *image.RGBA, y0, y1 int) {
+	if s.first {
+		// zlib header: deflate with a 32K window, fastest compression.
+		s.data.Write([]byte{0x78, 0x01})
+	}
+	zw := compressors.Get().(*flate.Writer)
+	defer compressors.Put(zw)
+	zw.Reset(&s.data)
+
+	const bpp = 3
+	n := 1 + bpp*img.Rect.Dx()
+	cur, prev := make([]byte, n), make([]byte, n)
+	filtered := make([][]byte, 5)
+	for f := range filtered {
+		filtered[f] = make([]byte, n)
+	}
+	if y0 > 0 {
+		pack(prev, img, y0-1)
+	}
+	hash := adler32.New()
+	for y := y0; y < y1; y++ {
+		pack(cur, img, y)
+		row := filter(filtered, cur, prev, bpp)
+		zw.Write(row)
+		hash.Write(row)
+		cur, prev = prev, cur
+	}
+	if s.last {
+		zw.Close()
+	} else {
+		zw.Flush()
+	}
+	s.sum, s.n = hash.Sum32(), (y1-y0)*n
+}
+
+// pack copies row y of img into row[1:] as RGB bytes.
+func pack(row []byte, img *image.RGBA, y int) {
+	src := img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y):]
+	for i, j := 1, 0; i < len(row); i, j = i+3, j+4 {
+		row[i], row[i+1], row[i+2] = src[j], src[j+1], src[j+2]
+	}
+}
+
+// filter applies each PNG filter to cur and returns the result that looks
+// most compressible, as image/png does: the one whose bytes, taken as
+// signed, sum to the least.
+func filter(out [][]byte, cur, prev []byte, bpp int) []byte {
+	n := len(cur)
+	sums := [5]int{}
+	for f := range out {
+		out[f][0] = byte(f)
+	}
+
+	none := out[0]
+	copy(none[1:], cur[1:])
+	for i := 1; i < n; i++ {
+		sums[0] += abs(int(int8(cur[i])))
+	}
+
+	sub := out[1]
+	for i := 1; i <= bpp; i++ {
+		sub[i] = cur[i]
+		sums[1] += abs(int(int8(sub[i])))
+	}
+	for i := bpp + 1; i < n; i++ {
+		sub[i] = cur[i] - cur[i-bpp]
+		sums[1] += abs(int(int8(sub[i])))
+	}
+
+	up := out[2]
+	for i := 1; i < n; i++ {
+		up[i] = cur[i] - prev[i]
+		sums[2] += abs(int(int8(up[i])))
+	}
+
+	avg := out[3]
+	for i := 1; i <= bpp; i++ {
+		avg[i] = cur[i] - prev[i]/2
+		sums[3] += abs(int(int8(avg[i])))
+	}
+	for i := bpp + 1; i < n; i++ {
+		avg[i] = cur[i] - uint8((int(cur[i-bpp])+int(prev[i]))/2)
+		sums[3] += abs(int(int8(avg[i])))
+	}
+
+	pth := out[4]
+	for i := 1; i <= bpp; i++ {
+		pth[i] = cur[i] - prev[i]
+		sums[4] += abs(int(int8(pth[i])))
+	}
+	for i := bpp + 1; i < n; i++ {
+		pth[i] = cur[i] - paeth(cur[i-bpp], prev[i], prev[i-bpp])
+		sums[4] += abs(int(int8(pth[i])))
+	}
+
+	best := 0
+	for f := 1; f < len(sums); f++ {
+		if sums[f] < sums[best] {
+			best = f
+		}
+	}
+	return out[best]
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// adler32Combine returns the adler-32 of two byte strings joined, from
+// their own checksums and the second's length, as zlib's adler32_combine.
+func adler32Combine(sum1, sum2 uint32, len2 int) uint32 {
+	const mod = 65521
+	rem := uint32(len2 % mod)
+	s1 := sum1 & 0xffff
+	s2 := rem * s1 % mod
+	s1 += sum2&0xffff + mod - 1
+	s2 += sum1>>16 + sum2>>16 + mod - rem
+	if s1 >= mod {
+		s1 -= mod
+	}
+	if s1 >= mod {
+		s1 -= mod
+	}
+	if s2 >= mod<<1 {
+		s2 -= mod << 1
+	}
+	if s2 >= mod {
+		s2 -= mod
+	}
+	return s2<<16 | s1
+}
+
+func writeChunk(w io.Writer, kind string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], kind)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err := w.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+	return err
+}
@@ -0,0 +1,87 @@
+package frame
+
+import (
+	"bytes"
+	"hash/adler32"
+	"image"
+	"image/draw"
+	"image/png"
+	"runtime"
+	"testing"
+)
+
+func TestEncodeParallel(t *testing.T) {
+	// Heights that do and do not divide into strips evenly, and a frame
+	// offset from the origin as captures of a second display are.
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 300, 64),
+		image.Rect(0, 0, 301, 257),
+		image.Rect(1920, 0, 2560, 333),
+	} {
+		img := screenshot(r.Max.X, r.Max.Y).SubImage(r).(*image.RGBA)
+		for _, procs := range []int{1, 3, 8} {
+			var buf bytes.Buffer
+			if err := encodeParallel(&buf, img, procs); err != nil {
+				t.Fatalf("%v with %d procs: %v", r, procs, err)
+			}
+			got, err := png.Decode(&buf)
+			if err != nil {
+				t.Fatalf("%v with %d procs: decoding: %v", r, procs, err)
+			}
+			if !sameImage(got, img) {
+				t.Errorf("%v with %d procs: decoded image differs from the original", r, procs)
+			}
+		}
+	}
+}
+
+func TestAdler32Combine(t *testing.T) {
+	a := bytes.Repeat([]byte("agentgo strip "), 5000)
+	b := bytes.Repeat([]byte{0xff, 0x00, 0x7f}, 30000)
+	want := adler32.Checksum(append(append([]byte{}, a...), b...))
+	if got := adler32Combine(adler32.Checksum(a), adler32.Checksum(b), len(b)); got != want {
+		t.Errorf("adler32Combine = %#x, want %#x", got, want)
+	}
+}
+
+// BenchmarkEncode4K compares image/png with the parallel encoder on a 4K
+// frame.
+func BenchmarkEncode4K(b *testing.B) {
+	img := screenshot(3840, 2160)
+	b.Run("image-png", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var buf bytes.Buffer
+			if err := encoder.Encode(&buf, img); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var buf bytes.Buffer
+			if err := encodeParallel(&buf, img, runtime.GOMAXPROCS(0)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkFrame4K measures a 4K frame end to end, from the captured
+// pixels to PNG bytes, as the capture path does it.
+func BenchmarkFrame4K(b *testing.B) {
+	screen := screenshot(3840, 2160)
+	r := screen.Bounds()
+	b.ReportAllocs()
+	for b.Loop() {
+		img := Get(r)
+		draw.Draw(img, r, screen, r.Min, draw.Src)
+		data, err := EncodePNG(img)
+		if err != nil {
+			b.Fatal(err)
+		}
+		Put(img)
+		b.SetBytes(int64(len(data)))
+	}
+}
@@ -0,0 +1,109 @@
+// Package health serves the liveness and readiness endpoints that
+// orchestrators and monitors probe: /healthz answers as long as the
+// process does, and /readyz runs checks, such as whether the display can
+// be captured and the model reached, so that a broken automation host is
+// noticed before jobs are queued onto it.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check is one readiness check.
+type Check struct {
+	Name string
+	// Run returns a short description of what it found, and an error if
+	// the host is not ready.
+	Run func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	Name     string        `json:"name"`
+	OK       bool          `json:"ok"`
+	Detail   string        `json:"detail,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report is the answer of /readyz.
+type Report struct {
+	Ready   bool      `json:"ready"`
+	Checked time.Time `json:"checked"`
+	Checks  []Result  `json:"checks"`
+}
+
+// Checker runs readiness checks.
+type Checker struct {
+	Checks []Check
+	// Timeout bounds each check. It defaults to 10s.
+	Timeout time.Duration
+	// MaxAge is how long a report is reused before the checks run again,
+	// so that frequent probes do not capture the screen or call the model
+	// every time. It defaults to 5s.
+	MaxAge time.Duration
+
+	mu   sync.Mutex
+	last *Report
+}
+
+// Ready runs the checks, or returns the last report if it is recent
+// enough. Checks run one after another, since several may use the
+// desktop.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maxAge := c.MaxAge
+	if maxAge <= 0 {
+		maxAge = 5 * time.Second
+	}
+	if c.last != nil && time.Since(c.last.Checked) < maxAge {
+		return *c.last
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	r := Report{Ready: true, Checked: time.Now()}
+	for _, check := range c.Checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		detail, err := check.Run(checkCtx)
+		cancel()
+		res := Result{Name: check.Name, OK: err == nil, Detail: detail, Duration: time.Since(start)}
+		if err != nil {
+			res.Error = err.Error()
+			r.Ready = false
+		}
+		r.Checks = append(r.Checks, res)
+	}
+	c.last = &r
+	return r
+}
+
+// Handler serves
+//
+//	GET /healthz  200 while the process runs
+//	GET /readyz   200 if every check passes, 503 if not, with the Report
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rw.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /readyz", func(rw http.ResponseWriter, r *http.Request) {
+		report := c.Ready(r.Context())
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		json.NewEncoder(rw).Encode(report)
+	})
+	return mux
+}
@@ -0,0 +1,153 @@
+// Package humanize adds the irregularity of a person at the controls to
+// scripted runs, for load tests that should look like real users: delays
+// vary, moves sometimes overshoot and correct, and now and then the "user"
+// stops for a moment. Every choice comes from a seeded random source, so
+// two runs with the same seed behave the same and can be compared.
+//
+// Behaviors are written as "on" for the defaults or as comma-separated
+// settings, e.g. "seed=7,jitter=0.3,overshoot=0.2,idle=0.1,idle-max=5s".
+package humanize
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config sets how much randomness a Behavior adds.
+type Config struct {
+	// Seed seeds the random source; runs with the same seed match.
+	Seed int64
+	// Jitter is the most a delay is lengthened or shortened, as a fraction
+	// of it: 0.25 varies a 2s wait between 1.5s and 2.5s.
+	Jitter float64
+	// Overshoot is the chance, from 0 to 1, that a move goes past its
+	// destination and comes back.
+	Overshoot float64
+	// Idle is the chance, from 0 to 1, of pausing before an action, for up
+	// to IdleMax.
+	Idle    float64
+	IdleMax time.Duration
+}
+
+// Default is the configuration "on" stands for.
+var Default = Config{Seed: 1, Jitter: 0.25, Overshoot: 0.3, Idle: 0.05, IdleMax: 3 * time.Second}
+
+// Parse reads a configuration. An empty string or "off" returns nil.
+func Parse(s string) (*Config, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "", "off":
+		return nil, nil
+	case "on":
+		c := Default
+		return &c, nil
+	}
+	c := Default
+	for _, field := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid humanize setting %q: want NAME=VALUE", field)
+		}
+		var err error
+		switch name {
+		case "seed":
+			c.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "jitter":
+			c.Jitter, err = parseFraction(value)
+		case "overshoot":
+			c.Overshoot, err = parseFraction(value)
+		case "idle":
+			c.Idle, err = parseFraction(value)
+		case "idle-max":
+			c.IdleMax, err = time.ParseDuration(value)
+		default:
+			return nil, fmt.Errorf("unknown humanize setting %q (want seed, jitter, overshoot, idle or idle-max)", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid humanize %s %q: %w", name, value, err)
+		}
+	}
+	return &c, nil
+}
+
+func parseFraction(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || v > 1 {
+		return 0, errors.New("must be between 0 and 1")
+	}
+	return v, nil
+}
+
+// Behavior makes the random choices of one run. A nil Behavior makes none:
+// delays are kept, moves go straight and there are no pauses.
+type Behavior struct {
+	Config
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// New returns a behavior for c, or nil if c is nil.
+func New(c *Config) *Behavior {
+	if c == nil {
+		return nil
+	}
+	return &Behavior{Config: *c, rnd: rand.New(rand.NewSource(c.Seed))}
+}
+
+func (b *Behavior) float() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rnd.Float64()
+}
+
+// Delay returns d lengthened or shortened by up to the jitter.
+func (b *Behavior) Delay(d time.Duration) time.Duration {
+	if b == nil || b.Jitter == 0 || d <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + b.Jitter*(2*b.float()-1)))
+}
+
+// Pause returns how long to stay idle before the next action, usually 0.
+func (b *Behavior) Pause() time.Duration {
+	if b == nil || b.Idle == 0 || b.IdleMax <= 0 {
+		return 0
+	}
+	if b.float() >= b.Idle {
+		return 0
+	}
+	// Short pauses are likelier than long ones.
+	r := b.float()
+	return time.Duration(r * r * float64(b.IdleMax))
+}
+
+// OvershootTo decides whether a move from one position to another, in 0-1
+// coordinates, overshoots, and returns the point it goes to first: a little
+// beyond the destination and off the straight line, as a hand does. It
+// suits motion.Profile.Overshoot.
+func (b *Behavior) OvershootTo(fromX, fromY, toX, toY float64) (float64, float64, bool) {
+	if b == nil || b.Overshoot == 0 {
+		return 0, 0, false
+	}
+	dx, dy := toX-fromX, toY-fromY
+	dist := math.Hypot(dx, dy)
+	// Short hops land where they aim.
+	if dist < 0.05 || b.float() >= b.Overshoot {
+		return 0, 0, false
+	}
+	past := 0.03 + 0.07*b.float()
+	side := 0.04 * (2*b.float() - 1)
+	x := toX + dx*past - dy*side
+	y := toY + dy*past + dx*side
+	return min(max(x, 0), 1), min(max(y, 0), 1), true
+}
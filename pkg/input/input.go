@@ -0,0 +1,90 @@
+// Package input defines the interface input backends implement, so that
+// robotgo, OS APIs such as SendInput, command-line tools such as xdotool,
+// uinput and remote protocols can be swapped for one another.
+package input
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUnsupported is returned by backends that cannot perform an operation,
+// e.g. hooking input events.
+var ErrUnsupported = errors.New("not supported by this input backend")
+
+// Kinds of event.
+const (
+	EventMove    = "move"
+	EventDown    = "down"
+	EventUp      = "up"
+	EventKeyDown = "keydown"
+	EventKeyUp   = "keyup"
+	EventScroll  = "scroll"
+)
+
+// Event is input seen by Backend.Hook.
+type Event struct {
+	Time time.Time
+	Kind string
+	// X and Y are the pointer position in physical virtual-desktop pixels.
+	X, Y int
+	// Button is the mouse button of down and up events: left, right or
+	// center.
+	Button string
+	// Key is the key of key events, in robotgo's names. Backends that can
+	// only tell that some key is down leave it empty.
+	Key string
+	// DX and DY are the wheel steps of scroll events; positive is right
+	// and down.
+	DX, DY int
+}
+
+// Backend injects mouse and keyboard input.
+type Backend interface {
+	// Move places the pointer at (x, y) in physical virtual-desktop pixels.
+	Move(x, y int) error
+	// Position returns where the pointer is.
+	Position() (x, y int, err error)
+	// Click clicks a mouse button (left, right or center) where the
+	// pointer is.
+	Click(button string) error
+	// Toggle presses (down) or releases a mouse button.
+	Toggle(button string, down bool) error
+	// Type types text at the current focus.
+	Type(text string) error
+	// KeyTap presses and releases a key, in robotgo's names (enter, tab,
+	// a, f5, ...), while holding the modifiers (ctrl, shift, alt, cmd).
+	KeyTap(key string, modifiers ...string) error
+	// Scroll turns the mouse wheel dx steps right and dy steps down;
+	// negative values scroll left and up.
+	Scroll(dx, dy int) error
+	// Hook sends the input events the backend sees until ctx is done, and
+	// then closes the channel.
+	Hook(ctx context.Context) (<-chan Event, error)
+}
+
+// KeyReleaser is implemented by backends that can release keys, so that
+// none is left held when a run is stopped mid-action.
+type KeyReleaser interface {
+	// ReleaseKeys releases keys, in robotgo's names, whether or not they
+	// are down.
+	ReleaseKeys(keys ...string) error
+}
+
+// Wheel returns the Scroll steps for one turn of a wheel button name as
+// scripts use them (wheelUp, wheelDown, wheelLeft, wheelRight), and whether
+// it is one.
+func Wheel(button string) (dx, dy int, ok bool) {
+	switch button {
+	case "wheelUp":
+		return 0, -1, true
+	case "wheelDown":
+		return 0, 1, true
+	case "wheelLeft":
+		return -1, 0, true
+	case "wheelRight":
+		return 1, 0, true
+	}
+	return 0, 0, false
+}
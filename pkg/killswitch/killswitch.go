@@ -0,0 +1,215 @@
+// Package killswitch stops every automated run at once, from outside the
+// runs: over a small local HTTP endpoint, or with a hotkey pressed at the
+// machine. A run that is stuck in an action, or a loop that ignores its
+// own stop conditions, still stops, since its context is cancelled from
+// here and the input it may be holding is released.
+package killswitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"agentGo/pkg/checkpoint"
+)
+
+// ErrKilled is the cause of the contexts the switch cancels.
+var ErrKilled = errors.New("aborted by the kill switch")
+
+// Switch tracks runs and stops them all when it is thrown. Once thrown it
+// stays thrown, cancelling runs as they start, until it is reset.
+type Switch struct {
+	// Release, if set, lets go of any mouse buttons and keys the runs may
+	// be holding down.
+	Release func() error
+	// Logf receives messages about kills. It defaults to log.Printf.
+	Logf func(format string, args ...any)
+
+	mu     sync.Mutex
+	next   int
+	runs   map[int]*tracked
+	killed *Kill
+}
+
+type tracked struct {
+	name    string
+	started time.Time
+	cancel  context.CancelCauseFunc
+	run     *checkpoint.Run
+}
+
+// Kill describes when and why the switch was thrown.
+type Kill struct {
+	Reason  string    `json:"reason"`
+	At      time.Time `json:"at"`
+	Stopped int       `json:"stopped"`
+}
+
+// Run is a run in progress, as Status reports it.
+type Run struct {
+	Name    string    `json:"name"`
+	Started time.Time `json:"started"`
+}
+
+// Status is the state of the switch.
+type Status struct {
+	Killed *Kill `json:"killed,omitempty"`
+	Runs   []Run `json:"runs"`
+}
+
+// Track returns a context for a run that Kill cancels with ErrKilled, and a
+// function to call when the run ends. name describes the run in Status. If
+// run is set, Kill marks the checkpointed run aborted at once, rather than
+// relying on the run to record how it ended. On a thrown switch the
+// context is already cancelled.
+func (s *Switch) Track(ctx context.Context, name string, run *checkpoint.Run) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.killed != nil {
+		cancel(ErrKilled)
+		return ctx, func() {}
+	}
+	if s.runs == nil {
+		s.runs = make(map[int]*tracked)
+	}
+	id := s.next
+	s.next++
+	s.runs[id] = &tracked{name: name, started: time.Now(), cancel: cancel, run: run}
+	return ctx, func() {
+		cancel(nil)
+		s.mu.Lock()
+		delete(s.runs, id)
+		s.mu.Unlock()
+	}
+}
+
+// Kill throws the switch: it cancels every run in progress, releases held
+// input and marks checkpointed runs aborted. It returns how many runs it
+// stopped.
+func (s *Switch) Kill(reason string) int {
+	s.mu.Lock()
+	runs := s.runs
+	s.runs = nil
+	s.killed = &Kill{Reason: reason, At: time.Now(), Stopped: len(runs)}
+	s.mu.Unlock()
+
+	for _, t := range runs {
+		t.cancel(ErrKilled)
+	}
+	if s.Release != nil {
+		if err := s.Release(); err != nil {
+			s.logf("kill switch: failed to release input: %v", err)
+		}
+	}
+	for _, t := range runs {
+		if t.run != nil {
+			if err := t.run.Finish(ErrKilled); err != nil {
+				s.logf("kill switch: %v", err)
+			}
+		}
+	}
+	s.logf("kill switch thrown (%s): stopped %d runs", reason, len(runs))
+	return len(runs)
+}
+
+// Reset lets runs start again after Kill.
+func (s *Switch) Reset() {
+	s.mu.Lock()
+	s.killed = nil
+	s.mu.Unlock()
+	s.logf("kill switch reset")
+}
+
+// Status returns whether the switch is thrown and the runs in progress,
+// oldest first.
+func (s *Switch) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Status{Runs: []Run{}}
+	if s.killed != nil {
+		k := *s.killed
+		st.Killed = &k
+	}
+	for _, t := range s.runs {
+		st.Runs = append(st.Runs, Run{Name: t.name, Started: t.started})
+	}
+	sort.Slice(st.Runs, func(i, j int) bool { return st.Runs[i].Started.Before(st.Runs[j].Started) })
+	return st
+}
+
+// Handler returns the switch's HTTP API:
+//
+//	GET  /status  whether the switch is thrown, and the runs in progress
+//	POST /kill    throw the switch; ?reason= says why
+//	POST /reset   let runs start again
+func (s *Switch) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, s.Status())
+	})
+	mux.HandleFunc("POST /kill", func(rw http.ResponseWriter, r *http.Request) {
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			reason = "requested from " + r.RemoteAddr
+		}
+		s.Kill(reason)
+		writeJSON(rw, s.Status())
+	})
+	mux.HandleFunc("POST /reset", func(rw http.ResponseWriter, r *http.Request) {
+		s.Reset()
+		writeJSON(rw, s.Status())
+	})
+	return mux
+}
+
+// Serve serves Handler on addr until ctx is done. The endpoint can stop
+// every run on the machine, so addr should be a loopback address.
+func (s *Switch) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("kill switch: %w", err)
+	}
+	return nil
+}
+
+// Watch throws the switch each time a signal arrives on pressed, e.g. from
+// desktop.WatchHotkey, until it is closed.
+func (s *Switch) Watch(pressed <-chan time.Time, reason string) {
+	for range pressed {
+		s.Kill(reason)
+	}
+}
+
+// Cause returns ErrKilled in place of err if err ended a run because ctx
+// was cancelled by the switch, so that the run is reported as aborted
+// rather than as "context canceled".
+func Cause(ctx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(ctx), ErrKilled) {
+		return ErrKilled
+	}
+	return err
+}
+
+func (s *Switch) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func writeJSON(rw http.ResponseWriter, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(v)
+}
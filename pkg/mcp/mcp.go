@@ -0,0 +1,200 @@
+// Package mcp serves the desktop as Model Context Protocol tools, so MCP
+// clients such as Claude Desktop can look at and drive it. The server
+// speaks JSON-RPC 2.0 over newline-delimited standard input and output;
+// to use it from Claude Desktop, add to claude_desktop_config.json:
+//
+//	{"mcpServers": {"agentgo": {"command": "agentgo", "args": ["mcp"]}}}
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"agentGo/pkg/memory"
+	"agentGo/pkg/safety"
+	"agentGo/pkg/secure"
+	"agentGo/pkg/tools"
+)
+
+// ProtocolVersion is the MCP revision the server implements.
+const ProtocolVersion = "2024-11-05"
+
+// Server answers MCP requests with the tools of package tools.
+type Server struct {
+	Desktop tools.Desktop
+	// Vision is optional; without it the tools that find elements by
+	// description report an error.
+	Vision tools.Locator
+	// Browser is optional; without it the browser tools report an error.
+	Browser tools.Browser
+	// Files is optional; without it the file tools report an error.
+	Files *tools.Files
+	// Commands is optional; without it run_command reports an error.
+	Commands *tools.Commands
+	// Memory is optional; without it remember and recall report an error.
+	Memory *memory.Store
+	// Safety, if set, checks clicks, typing and keys before they are
+	// performed.
+	Safety *safety.Guard
+	// Secure, if set, keeps password fields out of screenshots and logs.
+	Secure *secure.Detector
+	// Scrub, if set, removes secrets from tool results and the log.
+	Scrub func(string) string
+	// OnCall, if set, is called after every tool call, e.g. to audit it
+	// (see tools.Dispatcher.OnCall).
+	OnCall  func(name string, args json.RawMessage, err error)
+	Name    string
+	Version string
+	Logf    func(format string, args ...any)
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes.
+const (
+	codeParse          = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Serve reads requests from r and writes responses to w until r is
+// exhausted or ctx is done. Requests are handled one at a time, since they
+// all act on the same desktop.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = w
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.reply(response{ID: json.RawMessage("null"), Error: &rpcError{codeParse, err.Error()}})
+			continue
+		}
+		result, rerr := s.handle(ctx, req)
+		if len(req.ID) == 0 {
+			// A notification: no response.
+			continue
+		}
+		s.reply(response{ID: req.ID, Result: result, Error: rerr})
+	}
+	return scanner.Err()
+}
+
+func (s *Server) reply(res response) {
+	res.JSONRPC = "2.0"
+	data, err := json.Marshal(res)
+	if err != nil {
+		s.logf("failed to encode response: %v", err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(append(data, '\n'))
+}
+
+func (s *Server) handle(ctx context.Context, req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" {
+			version = ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.name(), "version": s.Version},
+		}, nil
+	case "ping", "notifications/initialized", "notifications/cancelled":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": toolList()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+		d := &tools.Dispatcher{Desktop: s.Desktop, Vision: s.Vision, Browser: s.Browser, Files: s.Files, Commands: s.Commands, Memory: s.Memory, Safety: s.Safety, Secure: s.Secure, Scrub: s.Scrub, OnCall: s.OnCall, Logf: s.logf}
+		result, err := d.Call(ctx, params.Name, params.Arguments)
+		if errors.Is(err, tools.ErrUnknownTool) {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+		// Tool failures are results the model can read and react to, not
+		// protocol errors.
+		res := map[string]any{"content": result.Content}
+		if result.IsError {
+			res["isError"] = true
+		}
+		return res, nil
+	}
+	return nil, &rpcError{codeMethodNotFound, fmt.Sprintf("unknown method %q", req.Method)}
+}
+
+// tool is an MCP tool definition.
+type tool struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	InputSchema *tools.Schema `json:"inputSchema"`
+}
+
+func toolList() []tool {
+	var list []tool
+	for _, t := range tools.All() {
+		list = append(list, tool{Name: t.Name, Description: t.Description, InputSchema: t.Parameters})
+	}
+	return list
+}
+
+func (s *Server) name() string {
+	if s.Name == "" {
+		return "agentgo"
+	}
+	return s.Name
+}
+
+func (s *Server) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,361 @@
+// Package memory keeps the facts an agent learns about the apps it
+// operates, such as "the export button is under File → Export", so that
+// later runs can recall them instead of exploring again. Facts are kept as
+// JSON lines in a file, with an embedding each when an Embedder is set, and
+// nothing about credentials is ever stored.
+//
+// A store holds hundreds of facts, not millions, and Recall compares the
+// query with every one of them, by embedding or by words, which no index
+// in a database would spare. So the store is a plain file, which people
+// can read, edit and keep in version control, and which needs no cgo or
+// database driver. Agents sharing a store take turns writing it under a
+// lock file next to it, and read what the others added before they write.
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"agentGo/pkg/filelock"
+)
+
+// Fact is something learned during a run.
+type Fact struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+	// App is the application the fact is about, if known.
+	App     string    `json:"app,omitempty"`
+	Learned time.Time `json:"learned"`
+	// Embedding is the fact's text embedded by the store's Embedder.
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Embedder turns text into a vector whose direction reflects its meaning,
+// as vision.Client does.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// ErrSensitive is returned for facts about credentials, which are never
+// stored.
+var ErrSensitive = errors.New("facts about credentials are not stored")
+
+// Store is the facts in a JSONL file. Stores of the same file, in this
+// process or others, may be used at the same time.
+type Store struct {
+	Path string
+	// Embedder, if set, embeds facts as they are added so that Recall
+	// finds them by meaning; otherwise by the words they share with the
+	// query.
+	Embedder Embedder
+
+	mu    sync.Mutex
+	facts []Fact
+}
+
+// Open reads the store at path, creating its directory if needed. A store
+// that does not exist yet is empty.
+func Open(path string, e Embedder) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	s := &Store{Path: path, Embedder: e}
+	facts, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	s.facts = facts
+	return s, nil
+}
+
+// load reads the facts in the file at path. A line that does not parse,
+// such as one a crashed writer left unfinished, is skipped.
+func load(path string) ([]Fact, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory: %w", err)
+	}
+	defer f.Close()
+	var facts []Fact
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var fact Fact
+		if err := json.Unmarshal(scanner.Bytes(), &fact); err != nil {
+			continue
+		}
+		facts = append(facts, fact)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read memory: %w", err)
+	}
+	return facts, nil
+}
+
+// lockTimeout is how long a write waits for another writer of the store.
+const lockTimeout = 10 * time.Second
+
+// update runs change with the store's file locked and its facts read
+// afresh, so that facts other stores of the file added are kept and
+// numbered around. s.mu must be held.
+func (s *Store) update(change func() error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+	lock, err := filelock.Acquire(ctx, s.Path+".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock memory: %w", err)
+	}
+	defer lock.Release()
+	facts, err := load(s.Path)
+	if err != nil {
+		return err
+	}
+	s.facts = facts
+	return change()
+}
+
+// Facts returns every fact, oldest first.
+func (s *Store) Facts() []Fact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Fact(nil), s.facts...)
+}
+
+// Add stores fact, numbering it and stamping when it was learned, and
+// returns it. A fact already known, in other case or spacing, is returned
+// as it was stored, with added false. A fact whose embedding fails is
+// stored without one, to be found by its words.
+func (s *Store) Add(ctx context.Context, fact Fact) (stored Fact, added bool, err error) {
+	fact.Text = strings.TrimSpace(fact.Text)
+	if fact.Text == "" {
+		return Fact{}, false, errors.New("the fact is empty")
+	}
+	if Sensitive(fact.Text) {
+		return Fact{}, false, ErrSensitive
+	}
+	key := strings.Join(words(fact.Text), " ")
+
+	s.mu.Lock()
+	for _, f := range s.facts {
+		if strings.Join(words(f.Text), " ") == key {
+			s.mu.Unlock()
+			return f, false, nil
+		}
+	}
+	s.mu.Unlock()
+
+	if s.Embedder != nil {
+		fact.Embedding, _ = s.Embedder.Embed(ctx, fact.Text)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.update(func() error {
+		// Another store may have added the fact while it was embedded.
+		for _, f := range s.facts {
+			if strings.Join(words(f.Text), " ") == key {
+				stored = f
+				return nil
+			}
+		}
+		fact.ID = 1
+		for _, f := range s.facts {
+			fact.ID = max(fact.ID, f.ID+1)
+		}
+		if fact.Learned.IsZero() {
+			fact.Learned = time.Now()
+		}
+		line, err := json.Marshal(fact)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open memory: %w", err)
+		}
+		defer f.Close()
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write memory: %w", err)
+		}
+		s.facts = append(s.facts, fact)
+		stored, added = fact, true
+		return nil
+	})
+	if err != nil {
+		return Fact{}, false, err
+	}
+	return stored, added, nil
+}
+
+// Forget removes the fact numbered id.
+func (s *Store) Forget(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(func() error { return s.forget(id) })
+}
+
+func (s *Store) forget(id int) error {
+	kept := make([]Fact, 0, len(s.facts))
+	for _, f := range s.facts {
+		if f.ID != id {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == len(s.facts) {
+		return fmt.Errorf("no fact %d", id)
+	}
+	var b strings.Builder
+	for _, f := range kept {
+		line, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write memory: %w", err)
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return fmt.Errorf("failed to write memory: %w", err)
+	}
+	s.facts = kept
+	return nil
+}
+
+// minSimilarity is how close in meaning a fact must be to the query for
+// Recall to return it.
+const minSimilarity = 0.55
+
+// Recall returns up to n facts relevant to query, most relevant first. With
+// an Embedder, facts are matched by the similarity of their embeddings, and
+// those without one by their words; if embedding the query fails, all are
+// matched by their words.
+func (s *Store) Recall(ctx context.Context, query string, n int) ([]Fact, error) {
+	facts := s.Facts()
+	if len(facts) == 0 {
+		return nil, nil
+	}
+	var q []float32
+	if s.Embedder != nil {
+		q, _ = s.Embedder.Embed(ctx, query)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	queryWords := words(query)
+	type scored struct {
+		fact  Fact
+		score float64
+	}
+	var found []scored
+	for _, f := range facts {
+		var score float64
+		if len(q) > 0 && len(f.Embedding) == len(q) {
+			if score = cosine(q, f.Embedding); score < minSimilarity {
+				continue
+			}
+		} else if score = overlap(queryWords, words(f.Text+" "+f.App)); score == 0 {
+			continue
+		}
+		found = append(found, scored{f, score})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+	if n > 0 && len(found) > n {
+		found = found[:n]
+	}
+	out := make([]Fact, len(found))
+	for i, f := range found {
+		out[i] = f.fact
+	}
+	return out, nil
+}
+
+// sensitiveWords mark facts about credentials: what they are, or where
+// they are kept.
+var sensitiveWords = []string{
+	"password", "passwords", "passcode", "passphrase", "credential", "credentials",
+	"secret", "secrets", "token", "tokens", "apikey", "otp", "2fa", "mfa",
+	"keychain", "keyring", "vault", "ssn",
+}
+
+// Sensitive reports whether text is about credentials, such as a password
+// or where the API keys are kept, which must not be remembered.
+func Sensitive(text string) bool {
+	w := words(text)
+	for i, word := range w {
+		if contains(sensitiveWords, word) {
+			return true
+		}
+		if i > 0 && (word == "key" || word == "keys") && (w[i-1] == "api" || w[i-1] == "private" || w[i-1] == "access" || w[i-1] == "ssh") {
+			return true
+		}
+	}
+	return false
+}
+
+// words splits text into lowercase words, dropping punctuation.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// stopWords are too common to make a fact relevant.
+var stopWords = []string{"a", "an", "and", "the", "to", "of", "in", "on", "is", "it", "for", "with", "at", "by", "or", "be", "this", "that", "from", "as", "under"}
+
+// overlap scores how many of the query's words, other than stop words,
+// are among words.
+func overlap(query, words []string) float64 {
+	var total, shared int
+	for _, q := range query {
+		if contains(stopWords, q) {
+			continue
+		}
+		total++
+		if contains(words, q) {
+			shared++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(shared) / float64(total)
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+// Package motion shapes synthetic pointer movement. By default drivers jump
+// straight to each position; with a profile they glide there along an
+// easing curve, no faster than a top speed, which some applications need
+// to register hovers and drags and which looks like a person at the mouse.
+//
+// Profiles are written EASING[:MAXSPEED], e.g. "human" or "ease-in-out:2",
+// where MAXSPEED is in screen widths per second.
+package motion
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Easings.
+const (
+	// None jumps straight to the destination.
+	None = "none"
+	// Linear moves at a constant speed.
+	Linear = "linear"
+	// EaseInOut speeds up from rest and slows down into the destination.
+	EaseInOut = "ease-in-out"
+	// Human follows the minimum-jerk profile fitted to measured human
+	// reaching movements: a bell-shaped velocity that peaks mid-way.
+	Human = "human"
+)
+
+// easings map progress in time to progress along the path, with the peak
+// velocity of each relative to a constant-speed move.
+var easings = map[string]struct {
+	f    func(t float64) float64
+	peak float64
+}{
+	Linear:    {func(t float64) float64 { return t }, 1},
+	EaseInOut: {func(t float64) float64 { return t * t * (3 - 2*t) }, 1.5},
+	Human: {func(t float64) float64 {
+		return t * t * t * (10 + t*(-15+6*t))
+	}, 1.875},
+}
+
+// DefaultMaxSpeed is the top speed, in screen widths per second, of a
+// profile that does not give one.
+const DefaultMaxSpeed = 1.5
+
+// frame is the pause between pointer updates while gliding.
+const frame = 16 * time.Millisecond
+
+// Profile says how the pointer travels between positions.
+type Profile struct {
+	// Easing is none, linear, ease-in-out or human; empty means none.
+	Easing string
+	// MaxSpeed caps the pointer's speed in screen widths per second; 0
+	// means DefaultMaxSpeed.
+	MaxSpeed float64
+	// Overshoot, if set, decides whether a move first goes to another
+	// point, past the destination, before correcting back to it.
+	Overshoot func(fromX, fromY, toX, toY float64) (x, y float64, ok bool)
+}
+
+// Parse reads a profile written EASING[:MAXSPEED]. An empty string is the
+// zero profile, which jumps.
+func Parse(s string) (Profile, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Profile{}, nil
+	}
+	name, speed, hasSpeed := strings.Cut(s, ":")
+	p := Profile{Easing: strings.ToLower(strings.TrimSpace(name))}
+	if _, ok := easings[p.Easing]; !ok && p.Easing != None {
+		return Profile{}, fmt.Errorf("unknown easing %q (want none, linear, ease-in-out or human)", name)
+	}
+	if hasSpeed {
+		v, err := strconv.ParseFloat(strings.TrimSpace(speed), 64)
+		if err != nil || v <= 0 {
+			return Profile{}, fmt.Errorf("invalid max speed %q: want a positive number of screen widths per second", speed)
+		}
+		p.MaxSpeed = v
+	}
+	return p, nil
+}
+
+// String returns the profile as Parse reads it.
+func (p Profile) String() string {
+	if p.Easing == "" {
+		return None
+	}
+	if p.MaxSpeed == 0 {
+		return p.Easing
+	}
+	return p.Easing + ":" + strconv.FormatFloat(p.MaxSpeed, 'g', -1, 64)
+}
+
+// Duration returns how long a move of the given distance, in screen widths,
+// takes so that its peak speed stays under MaxSpeed.
+func (p Profile) Duration(distance float64) time.Duration {
+	e, ok := easings[p.Easing]
+	if !ok {
+		return 0
+	}
+	speed := p.MaxSpeed
+	if speed <= 0 {
+		speed = DefaultMaxSpeed
+	}
+	return time.Duration(distance * e.peak / speed * float64(time.Second))
+}
+
+// Glide moves from one position to another along the profile's curve by
+// calling move for each frame, ending exactly on the destination.
+// Coordinates are normalized to 0-1.
+func (p Profile) Glide(fromX, fromY, toX, toY float64, move func(x, y float64) error) error {
+	e, ok := easings[p.Easing]
+	d := p.Duration(math.Hypot(toX-fromX, toY-fromY))
+	if !ok || d < frame {
+		return move(toX, toY)
+	}
+	start := time.Now()
+	for {
+		elapsed := time.Since(start)
+		if elapsed >= d {
+			return move(toX, toY)
+		}
+		s := e.f(float64(elapsed) / float64(d))
+		if err := move(fromX+(toX-fromX)*s, fromY+(toY-fromY)*s); err != nil {
+			return err
+		}
+		time.Sleep(frame)
+	}
+}
+
+// Pointer glides a driver's pointer along a profile, remembering where it
+// left it. The first move jumps, since where the pointer starts is unknown.
+type Pointer struct {
+	mu      sync.Mutex
+	profile Profile
+	x, y    float64
+	known   bool
+}
+
+// SetProfile sets how later moves travel.
+func (p *Pointer) SetProfile(profile Profile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.profile = profile
+}
+
+// Move takes the pointer to x, y by calling move, the driver's jump, once
+// or once per frame.
+func (p *Pointer) Move(x, y float64, move func(x, y float64) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	switch {
+	case !p.known:
+		err = move(x, y)
+	case p.profile.Overshoot != nil:
+		if ox, oy, ok := p.profile.Overshoot(p.x, p.y, x, y); ok {
+			if err = p.profile.Glide(p.x, p.y, ox, oy, move); err == nil {
+				err = p.profile.Glide(ox, oy, x, y, move)
+			}
+			break
+		}
+		fallthrough
+	default:
+		err = p.profile.Glide(p.x, p.y, x, y, move)
+	}
+	if err != nil {
+		p.known = false
+		return err
+	}
+	p.x, p.y, p.known = x, y, true
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package narrate periodically describes the screen in words: what is on it
+// at first, then what changed. It serves as an assistive tool and as a way
+// to follow long unattended runs without watching them.
+package narrate
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"strings"
+	"time"
+
+	"agentGo/pkg/throttle"
+	"agentGo/pkg/vision"
+)
+
+// Narration is one description of the screen.
+type Narration struct {
+	Time time.Time
+	Text string
+	// First is set for the initial description of the whole screen; later
+	// narrations describe changes.
+	First bool
+}
+
+// Narrator watches the screen and describes it.
+type Narrator struct {
+	Client  *vision.Client
+	Capture func() (image.Image, error)
+	// Interval is how often the screen is looked at; 0 means 5 seconds.
+	Interval time.Duration
+	// Threshold is how different, from 0 to 1, the screen must look
+	// before the model is asked what changed; 0 means 0.01. It saves model
+	// calls while nothing happens.
+	Threshold float64
+	// Focus, if set, asks the model to concentrate on something, e.g. "the
+	// progress of the export dialog".
+	Focus string
+	// Throttle, if set, looks less often and at smaller frames on battery
+	// power or when the machine runs hot.
+	Throttle *throttle.Governor
+	Logf     func(format string, args ...any)
+}
+
+// NoChange is the answer the model gives when nothing worth mentioning
+// changed; such narrations are not reported.
+const NoChange = "No change."
+
+// Run narrates until ctx is done, passing each narration to report.
+func (n *Narrator) Run(ctx context.Context, report func(Narration)) error {
+	interval := n.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	threshold := n.Threshold
+	if threshold <= 0 {
+		threshold = 0.01
+	}
+
+	var last []uint8
+	var previous string
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		img, err := n.Capture()
+		if err == nil {
+			img = n.Throttle.Shrink(img)
+		}
+		if err != nil {
+			n.logf("failed to capture screen: %v", err)
+		} else if thumb := thumbnail(img); last == nil || difference(last, thumb) >= threshold {
+			text, err := n.describe(ctx, img, previous)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				n.logf("failed to describe screen: %v", err)
+			} else {
+				first := last == nil
+				last = thumb
+				if !strings.EqualFold(strings.TrimSpace(text), NoChange) {
+					report(Narration{Time: time.Now(), Text: text, First: first})
+					previous = text
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			ticker.Reset(n.Throttle.Interval(interval))
+		}
+	}
+}
+
+func (n *Narrator) describe(ctx context.Context, img image.Image, previous string) (string, error) {
+	var prompt string
+	if previous == "" {
+		prompt = "Describe this screen for someone who cannot see it: which application and window are in front, what it shows and anything that asks for attention, such as dialogs, errors or progress. Use two or three plain sentences."
+	} else {
+		prompt = fmt.Sprintf("This is the screen a moment later. The last description was: %q. Say in one or two plain sentences what changed that matters, such as a new window, dialog, message or progress. If nothing meaningful changed, answer exactly %q.", previous, NoChange)
+	}
+	if n.Focus != "" {
+		prompt += " Pay particular attention to " + n.Focus + "."
+	}
+	text, err := n.Client.Generate(ctx, prompt, img)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+func (n *Narrator) logf(format string, args ...any) {
+	if n.Logf != nil {
+		n.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+const thumbWidth, thumbHeight = 64, 36
+
+// thumbnail averages img down to a small grayscale grid for cheap change
+// detection.
+func thumbnail(img image.Image) []uint8 {
+	b := img.Bounds()
+	thumb := make([]uint8, thumbWidth*thumbHeight)
+	for ty := 0; ty < thumbHeight; ty++ {
+		y0, y1 := b.Min.Y+ty*b.Dy()/thumbHeight, b.Min.Y+(ty+1)*b.Dy()/thumbHeight
+		for tx := 0; tx < thumbWidth; tx++ {
+			x0, x1 := b.Min.X+tx*b.Dx()/thumbWidth, b.Min.X+(tx+1)*b.Dx()/thumbWidth
+			var sum, count uint64
+			// Sample a sparse grid of the cell; exact averages do not
+			// matter for spotting change.
+			for y := y0; y < y1; y += 4 {
+				for x := x0; x < x1; x += 4 {
+					r, g, bl, _ := img.At(x, y).RGBA()
+					sum += uint64(299*r+587*g+114*bl) / 1000 >> 8
+					count++
+				}
+			}
+			if count > 0 {
+				thumb[ty*thumbWidth+tx] = uint8(sum / count)
+			}
+		}
+	}
+	return thumb
+}
+
+// difference is the mean absolute difference of two thumbnails, from 0 to 1.
+func difference(a, b []uint8) float64 {
+	var sum int
+	for i := range a {
+		d := int(a[i]) - int(b[i])
+		if d < 0 {
+			d = -d
+		}
+		sum += d
+	}
+	return float64(sum) / float64(len(a)) / 255
+}
@@ -0,0 +1,133 @@
+// Package notify sends completion and failure notifications for automated
+// runs via webhooks, Slack, and email.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// When a notification is sent.
+const (
+	OnFailure = "failure"
+	OnSuccess = "success"
+	OnAlways  = "always"
+)
+
+// Config selects notification channels. The zero value sends nothing.
+type Config struct {
+	// On is "failure" (default), "success", or "always".
+	On      string         `json:"on,omitempty"`
+	Webhook *WebhookConfig `json:"webhook,omitempty"`
+	Slack   *SlackConfig   `json:"slack,omitempty"`
+	Email   *EmailConfig   `json:"email,omitempty"`
+	// ArtifactBaseURL, if set, is prefixed to artifact file names to build
+	// links, e.g. when the artifacts directory is served over HTTP.
+	ArtifactBaseURL string `json:"artifact_base_url,omitempty"`
+}
+
+// Event describes a finished run.
+type Event struct {
+	Job      string        `json:"job"`
+	Status   string        `json:"status"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+	Host     string        `json:"host"`
+	// ReportPath and ScreenshotPath are local artifact files, if any.
+	ReportPath     string `json:"report_path,omitempty"`
+	ScreenshotPath string `json:"screenshot_path,omitempty"`
+	// ReportURL and ScreenshotURL are links built from ArtifactBaseURL.
+	ReportURL     string `json:"report_url,omitempty"`
+	ScreenshotURL string `json:"screenshot_url,omitempty"`
+}
+
+// Failed reports whether the event is for an unsuccessful run.
+func (e Event) Failed() bool {
+	return e.Status != "success"
+}
+
+// Summary is a one-line human-readable description of the event.
+func (e Event) Summary() string {
+	s := fmt.Sprintf("agentGo job %q %s on %s after %s", e.Job, e.Status, e.Host, e.Duration.Round(time.Millisecond))
+	if e.Error != "" {
+		s += ": " + e.Error
+	}
+	return s
+}
+
+// Notifier delivers an event over one channel.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// ShouldSend reports whether c wants a notification for e.
+func (c *Config) ShouldSend(e Event) bool {
+	if c == nil {
+		return false
+	}
+	switch c.On {
+	case OnAlways:
+		return true
+	case OnSuccess:
+		return !e.Failed()
+	default:
+		return e.Failed()
+	}
+}
+
+// Notifiers returns the configured channels.
+func (c *Config) Notifiers() []Notifier {
+	if c == nil {
+		return nil
+	}
+	var ns []Notifier
+	if c.Webhook != nil {
+		ns = append(ns, c.Webhook)
+	}
+	if c.Slack != nil {
+		ns = append(ns, c.Slack)
+	}
+	if c.Email != nil {
+		ns = append(ns, c.Email)
+	}
+	return ns
+}
+
+// Send delivers e on every configured channel if c's policy calls for it.
+// Errors from individual channels are joined; one failing channel does not
+// stop the others.
+func Send(ctx context.Context, c *Config, e Event) error {
+	if !c.ShouldSend(e) {
+		return nil
+	}
+	if e.Host == "" {
+		e.Host, _ = os.Hostname()
+	}
+	if c.ArtifactBaseURL != "" {
+		e.ReportURL = artifactURL(c.ArtifactBaseURL, e.ReportPath)
+		e.ScreenshotURL = artifactURL(c.ArtifactBaseURL, e.ScreenshotPath)
+	}
+
+	var errs []error
+	for _, n := range c.Notifiers() {
+		if err := n.Notify(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func artifactURL(base, path string) string {
+	if path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(base, "/") + "/" + url.PathEscape(filepath.Base(path))
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// WebhookConfig posts the event as JSON to an arbitrary URL.
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// IncludeScreenshot embeds the failure screenshot as base64 PNG in the
+	// "screenshot_png" field.
+	IncludeScreenshot bool `json:"include_screenshot,omitempty"`
+}
+
+// Notify implements Notifier.
+func (w *WebhookConfig) Notify(ctx context.Context, e Event) error {
+	payload := struct {
+		Event
+		Summary       string `json:"summary"`
+		ScreenshotPNG string `json:"screenshot_png,omitempty"`
+	}{Event: e, Summary: e.Summary()}
+	if w.IncludeScreenshot && e.ScreenshotPath != "" {
+		if data, err := os.ReadFile(e.ScreenshotPath); err == nil {
+			payload.ScreenshotPNG = base64.StdEncoding.EncodeToString(data)
+		}
+	}
+	if err := postJSON(ctx, w.URL, w.Headers, payload); err != nil {
+		return fmt.Errorf("webhook notification failed: %w", err)
+	}
+	return nil
+}
+
+// SlackConfig posts a message to a Slack incoming webhook.
+type SlackConfig struct {
+	WebhookURL string `json:"webhook_url"`
+	Channel    string `json:"channel,omitempty"`
+}
+
+// Notify implements Notifier. Incoming webhooks cannot upload files, so
+// artifacts are referenced by link (or local path when no base URL is set).
+func (s *SlackConfig) Notify(ctx context.Context, e Event) error {
+	icon := ":white_check_mark:"
+	if e.Failed() {
+		icon = ":x:"
+	}
+	text := fmt.Sprintf("%s %s", icon, e.Summary())
+	if link := firstNonEmpty(e.ReportURL, e.ReportPath); link != "" {
+		text += "\nReport: " + link
+	}
+	if link := firstNonEmpty(e.ScreenshotURL, e.ScreenshotPath); link != "" {
+		text += "\nScreenshot: " + link
+	}
+
+	msg := map[string]string{"text": text}
+	if s.Channel != "" {
+		msg["channel"] = s.Channel
+	}
+	if err := postJSON(ctx, s.WebhookURL, nil, msg); err != nil {
+		return fmt.Errorf("slack notification failed: %w", err)
+	}
+	return nil
+}
+
+func postJSON(ctx context.Context, url string, headers map[string]string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package playback
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Version is the recording format this package writes. Version 1 starts
+// with a version line, then the header row, and every row has all seven
+// columns. Recordings from before versions were stamped are version 0.
+const Version = 1
+
+// versionPrefix starts the line that stamps a recording's version, e.g.
+// "# agentgo recording v1". CSV tools that skip # comments skip it.
+const versionPrefix = "# agentgo recording v"
+
+// Header is the header row of a recording.
+var Header = []string{"timestamp", "norm_x", "norm_y", "cursor", "button", "clicks", "hold_ms"}
+
+// WriteHeader starts a recording in the current format: its version line
+// and header row.
+func WriteHeader(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%s%d\n", versionPrefix, Version); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(Header)
+	cw.Flush()
+	return cw.Error()
+}
+
+// Record returns the row recording s.
+func Record(s Sample) []string {
+	record := []string{
+		strconv.FormatInt(s.Timestamp.Milliseconds(), 10),
+		strconv.FormatFloat(s.X, 'f', 8, 64),
+		strconv.FormatFloat(s.Y, 'f', 8, 64),
+		s.Cursor,
+		"", "", "",
+	}
+	if s.Button != "" {
+		record[4] = s.Button
+		record[5] = strconv.Itoa(max(s.Clicks, 1))
+		record[6] = strconv.FormatInt(s.Hold.Milliseconds(), 10)
+	}
+	return record
+}
+
+// SaveCSV writes samples to path as a recording in the current format.
+func SaveCSV(path string, samples []Sample) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create csv file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	if err := WriteHeader(w); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	cw := csv.NewWriter(w)
+	for _, s := range samples {
+		cw.Write(Record(s))
+	}
+	cw.Flush()
+	if err := errors.Join(cw.Error(), w.Flush()); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// readVersion consumes the version line at the start of in, if there is
+// one, and returns the version: 0 if there is none.
+func readVersion(in *bufio.Reader) (int, error) {
+	start, err := in.Peek(len(versionPrefix))
+	if err != nil || string(start) != versionPrefix {
+		return 0, nil
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, versionPrefix)))
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid recording version line %q", strings.TrimSpace(line))
+	}
+	if v > Version {
+		return 0, fmt.Errorf("recording format v%d is newer than this version of agentgo reads (v%d); upgrade agentgo", v, Version)
+	}
+	return v, nil
+}
@@ -0,0 +1,294 @@
+// Package playback replays recorded mouse movements and clicks.
+package playback
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"agentGo/pkg/humanize"
+	"agentGo/pkg/script"
+)
+
+// Sample is one recorded cursor position.
+type Sample struct {
+	// Timestamp is the offset from the start of the recording.
+	Timestamp time.Duration `json:"timestamp"`
+	// X and Y are normalized (0-1) screen coordinates.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// Cursor is the cursor shape at the time, e.g. "arrow", "ibeam",
+	// "hand" or "busy", if it was recorded.
+	Cursor string `json:"cursor,omitempty"`
+	// Button is set on samples that record a click rather than a position:
+	// left, right or center.
+	Button string `json:"button,omitempty"`
+	// Clicks is 2 for a double click and 3 for a triple click.
+	Clicks int `json:"clicks,omitempty"`
+	// Hold is how long the button was held on the last press.
+	Hold time.Duration `json:"hold,omitempty"`
+}
+
+// LongPress is the shortest recorded hold replayed as a press and hold
+// rather than an ordinary click.
+const LongPress = 500 * time.Millisecond
+
+// Mover moves the cursor to normalized screen coordinates.
+type Mover interface {
+	Move(normX, normY float64) error
+}
+
+// LoadCSV reads a recording written by the recorder: timestamp, norm_x,
+// norm_y and, in newer recordings, cursor, then button, clicks and hold_ms,
+// which are set on rows recording a click. Malformed rows are logged and
+// skipped. Samples are returned in timestamp order.
+func LoadCSV(path string) ([]Sample, error) {
+	samples, _, err := ReadCSV(path)
+	return samples, err
+}
+
+// ReadCSV is LoadCSV that also returns the recording's format version: 0
+// for recordings made before versions were stamped, which may lack the
+// header row and have 3, 4 or 7 columns.
+func ReadCSV(path string) ([]Sample, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open csv file: %w", err)
+	}
+	defer file.Close()
+	return DecodeCSV(file, image.Point{})
+}
+
+// ErrPixelCoordinates is returned for recordings in pixels rather than
+// normalized coordinates when the screen size to normalize them by is not
+// known.
+var ErrPixelCoordinates = errors.New("the recording has pixel coordinates; convert it with agentgo convert -screen WIDTHxHEIGHT")
+
+// DecodeCSV reads a recording from r, as ReadCSV does. Recordings whose
+// header names pixel columns, such as x and y rather than norm_x and norm_y,
+// or whose coordinates go past 1, are in pixels: they are normalized by
+// screen, and fail with ErrPixelCoordinates if screen is zero.
+func DecodeCSV(r io.Reader, screen image.Point) ([]Sample, int, error) {
+	in := bufio.NewReader(r)
+	version, err := readVersion(in)
+	if err != nil {
+		return nil, 0, err
+	}
+	reader := csv.NewReader(in)
+	// Older recordings mix position rows with longer click rows.
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read csv records: %w", err)
+	}
+
+	// Skip the header row, which some early recordings lack.
+	pixels := false
+	if len(records) > 0 && len(records[0]) > 0 {
+		if _, err := strconv.ParseInt(records[0][0], 10, 64); err != nil {
+			header := records[0]
+			pixels = len(header) >= 3 && !strings.HasPrefix(header[1], "norm")
+			records = records[1:]
+		}
+	}
+
+	samples := make([]Sample, 0, len(records))
+	for _, record := range records {
+		if len(record) != len(Header) && (version > 0 || len(record) != 3 && len(record) != 4) {
+			log.Printf("skipping malformed record: %v", record)
+			continue
+		}
+		timestamp, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil {
+			log.Printf("failed to parse timestamp: %v", err)
+			continue
+		}
+		normX, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			log.Printf("failed to parse normalized x coordinate: %v", err)
+			continue
+		}
+		normY, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			log.Printf("failed to parse normalized y coordinate: %v", err)
+			continue
+		}
+		sample := Sample{
+			Timestamp: time.Duration(timestamp) * time.Millisecond,
+			X:         normX,
+			Y:         normY,
+		}
+		if len(record) >= 4 {
+			sample.Cursor = record[3]
+		}
+		if len(record) == 7 && record[4] != "" {
+			sample.Button = record[4]
+			if sample.Clicks, err = strconv.Atoi(record[5]); err != nil {
+				log.Printf("failed to parse click count: %v", err)
+				continue
+			}
+			hold, err := strconv.ParseInt(record[6], 10, 64)
+			if err != nil {
+				log.Printf("failed to parse hold time: %v", err)
+				continue
+			}
+			sample.Hold = time.Duration(hold) * time.Millisecond
+		}
+		samples = append(samples, sample)
+	}
+	for _, s := range samples {
+		pixels = pixels || s.X > 1 || s.Y > 1
+	}
+	if pixels {
+		if screen.X <= 0 || screen.Y <= 0 {
+			return nil, version, ErrPixelCoordinates
+		}
+		for i := range samples {
+			samples[i].X /= float64(screen.X)
+			samples[i].Y /= float64(screen.Y)
+		}
+	}
+	// Clicks are written when they complete, which can be after later
+	// position samples.
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+	return samples, version, nil
+}
+
+// Player replays samples with their original timing.
+type Player struct {
+	// Mover moves the cursor. To replay clicks it must also be a
+	// script.Executor, and to replay long presses a script.ButtonPresser.
+	Mover Mover
+	// Logf receives one message per move. It defaults to log.Printf.
+	Logf func(format string, args ...any)
+	// Human, if set, varies the recorded intervals and sometimes pauses
+	// before a click, as a person would.
+	Human *humanize.Behavior
+	// OnInterference, if set, pauses playback when someone else uses the
+	// mouse or keyboard, as script.Runner's does. Without it such input
+	// stops playback with script.ErrInterference.
+	OnInterference script.InterferenceHandler
+	// Clock, if set, times playback instead of the system clock. With a
+	// script.VirtualClock and a script.NopExecutor as Mover, a recording
+	// replays instantly and deterministically, for tests.
+	Clock script.Clock
+	// Start, if above zero, skips the samples before it, to resume
+	// playback where an earlier run stopped.
+	Start int
+	// OnClick, if set, is called after the click at samples[i] is
+	// replayed, e.g. to checkpoint the run.
+	OnClick func(i int)
+	// OnSample, if set, is called before samples[i] is replayed, e.g. to
+	// show progress.
+	OnSample func(i int, s Sample)
+}
+
+// Play moves the cursor through samples, waiting between them for the
+// recorded interval. It returns early if ctx is cancelled.
+func (p *Player) Play(ctx context.Context, samples []Sample) error {
+	ctx = script.WithClock(ctx, p.Clock)
+	clock := script.ClockFrom(ctx)
+	// Drivers that can tell manual input from their own are watched for it.
+	exec, _ := p.Mover.(script.Executor)
+	paused := false
+	handle := func(ctx context.Context, what string) error {
+		if p.OnInterference == nil {
+			return fmt.Errorf("%w: %s", script.ErrInterference, what)
+		}
+		p.logf("Manual input: %s; pausing playback", what)
+		paused = true
+		return p.OnInterference(ctx, what)
+	}
+	due := clock.Now()
+	for i, s := range samples {
+		if i < p.Start {
+			continue
+		}
+		// Wait for the sample's time, kept on a schedule so that moves that
+		// glide rather than jump do not make playback drift.
+		if i > p.Start {
+			due = due.Add(p.Human.Delay(s.Timestamp - samples[i-1].Timestamp))
+			if s.Button != "" {
+				due = due.Add(p.Human.Pause())
+			}
+			if err := script.WatchedSleep(ctx, due.Sub(clock.Now()), exec, handle); err != nil {
+				return err
+			}
+			if paused {
+				// Carry on from here rather than rushing to catch up.
+				due, paused = clock.Now(), false
+			}
+		} else if err := script.CheckInterference(ctx, exec, handle); err != nil {
+			return err
+		}
+
+		if p.OnSample != nil {
+			p.OnSample(i, s)
+		}
+		if s.Button != "" {
+			if err := p.click(ctx, s); err != nil {
+				return err
+			}
+			if p.OnClick != nil {
+				p.OnClick(i)
+			}
+			continue
+		}
+		if s.Cursor != "" {
+			p.logf("Moving mouse to normalized (%.4f, %.4f) [recorded cursor: %s]", s.X, s.Y, s.Cursor)
+		} else {
+			p.logf("Moving mouse to normalized (%.4f, %.4f)", s.X, s.Y)
+		}
+		if err := p.Mover.Move(s.X, s.Y); err != nil {
+			return fmt.Errorf("failed to move mouse: %w", err)
+		}
+	}
+	return nil
+}
+
+// click moves to a recorded click and repeats it.
+func (p *Player) click(ctx context.Context, s Sample) error {
+	exec, ok := p.Mover.(script.Executor)
+	if !ok {
+		return errors.New("this driver cannot click")
+	}
+	var hold time.Duration
+	if s.Hold >= LongPress {
+		hold = s.Hold
+	}
+	p.logf("Clicking %s x%d at normalized (%.4f, %.4f), held %v", s.Button, max(s.Clicks, 1), s.X, s.Y, s.Hold)
+	if err := p.Mover.Move(s.X, s.Y); err != nil {
+		return fmt.Errorf("failed to move mouse: %w", err)
+	}
+	if err := script.Press(ctx, exec, s.Button, s.Clicks, hold); err != nil {
+		return fmt.Errorf("failed to click: %w", err)
+	}
+	return nil
+}
+
+// PlayFile loads a CSV recording and plays it.
+func (p *Player) PlayFile(ctx context.Context, path string) error {
+	samples, err := LoadCSV(path)
+	if err != nil {
+		return err
+	}
+	return p.Play(ctx, samples)
+}
+
+func (p *Player) logf(format string, args ...any) {
+	if p.Logf != nil {
+		p.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
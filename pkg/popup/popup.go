@@ -0,0 +1,387 @@
+// Package popup watches for windows that appear unasked during playback,
+// such as modal dialogs, permission prompts and update nags, and handles
+// them by policy: dismiss them, pause until someone deals with them, or
+// fail the step. A script names its policies under "popups":
+//
+//	"popups": {
+//	  "policy": "fail",
+//	  "rules": [
+//	    {"kind": "update", "policy": "dismiss", "button": "the Remind Me Later button"},
+//	    {"title": "(?i)save changes", "policy": "pause"}
+//	  ]
+//	}
+package popup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"agentGo/pkg/vision"
+)
+
+// Kinds of popup.
+const (
+	KindDialog     = "dialog"
+	KindPermission = "permission"
+	KindUpdate     = "update"
+	KindOther      = "other"
+)
+
+// Policy says what to do about a popup.
+type Policy string
+
+const (
+	// PolicyFail stops the run with ErrPopup.
+	PolicyFail Policy = "fail"
+	// PolicyDismiss presses the popup's dismiss button, or escape.
+	PolicyDismiss Policy = "dismiss"
+	// PolicyPause waits for someone to close the popup.
+	PolicyPause Policy = "pause"
+	// PolicyIgnore carries on as if the popup were not there.
+	PolicyIgnore Policy = "ignore"
+)
+
+// ErrPopup is returned when a popup stops the run.
+var ErrPopup = errors.New("unexpected popup")
+
+// Rule picks the policy for popups of a kind or with a matching title.
+type Rule struct {
+	// Kind is dialog, permission, update or other; empty matches any.
+	Kind string `json:"kind,omitempty"`
+	// Title is a regular expression matched against the popup's title;
+	// empty matches any.
+	Title  string `json:"title,omitempty"`
+	Policy Policy `json:"policy"`
+	// Button describes the control that dismisses the popup, e.g. "the
+	// Not Now button", overriding the model's choice.
+	Button string `json:"button,omitempty"`
+
+	title *regexp.Regexp
+}
+
+// Config is a set of popup policies.
+type Config struct {
+	// Policy applies to popups no rule matches; empty means fail.
+	Policy Policy `json:"policy,omitempty"`
+	Rules  []Rule `json:"rules,omitempty"`
+	// PauseTimeout bounds how long the pause policy waits, as a Go
+	// duration; empty means 10 minutes.
+	PauseTimeout string `json:"pause_timeout,omitempty"`
+}
+
+// ParseConfig reads a config from a policy name ("dismiss"), inline JSON,
+// or the path of a JSON file.
+func ParseConfig(s string) (*Config, error) {
+	s = strings.TrimSpace(s)
+	var c Config
+	switch {
+	case s == "":
+		return nil, nil
+	case !strings.HasPrefix(s, "{") && validPolicy(Policy(s)):
+		c.Policy = Policy(s)
+	default:
+		data := []byte(s)
+		if !strings.HasPrefix(s, "{") {
+			var err error
+			if data, err = os.ReadFile(s); err != nil {
+				return nil, fmt.Errorf("failed to read popup config: %w", err)
+			}
+		}
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("failed to parse popup config: %w", err)
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Validate checks the config's policies and patterns.
+func (c *Config) Validate() error {
+	if c.Policy != "" && !validPolicy(c.Policy) {
+		return fmt.Errorf("unknown popup policy %q (want fail, dismiss, pause or ignore)", c.Policy)
+	}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if !validPolicy(r.Policy) {
+			return fmt.Errorf("popup rule %d: unknown policy %q (want fail, dismiss, pause or ignore)", i, r.Policy)
+		}
+		if r.Title != "" {
+			re, err := regexp.Compile(r.Title)
+			if err != nil {
+				return fmt.Errorf("popup rule %d: invalid title pattern: %w", i, err)
+			}
+			r.title = re
+		}
+	}
+	if c.PauseTimeout != "" {
+		if _, err := time.ParseDuration(c.PauseTimeout); err != nil {
+			return fmt.Errorf("invalid popup pause_timeout: %w", err)
+		}
+	}
+	return nil
+}
+
+func validPolicy(p Policy) bool {
+	switch p {
+	case PolicyFail, PolicyDismiss, PolicyPause, PolicyIgnore:
+		return true
+	}
+	return false
+}
+
+// ruleFor returns the rule that applies to d.
+func (c *Config) ruleFor(d Detection) Rule {
+	for _, r := range c.Rules {
+		if r.Kind != "" && r.Kind != d.Kind {
+			continue
+		}
+		if r.Title != "" {
+			if r.title == nil {
+				r.title = regexp.MustCompile(r.Title)
+			}
+			if !r.title.MatchString(d.Title) {
+				continue
+			}
+		}
+		return r
+	}
+	if c.Policy == "" {
+		return Rule{Policy: PolicyFail}
+	}
+	return Rule{Policy: c.Policy}
+}
+
+func (c *Config) pauseTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.PauseTimeout); err == nil && d > 0 {
+		return d
+	}
+	return 10 * time.Minute
+}
+
+// Detection is what a Detector saw.
+type Detection struct {
+	Present bool   `json:"present"`
+	Kind    string `json:"kind,omitempty"`
+	Title   string `json:"title,omitempty"`
+	// Text is the popup's message.
+	Text string `json:"text,omitempty"`
+	// Dismiss describes the control that closes the popup without
+	// agreeing to anything, e.g. "the Cancel button".
+	Dismiss string `json:"dismiss,omitempty"`
+}
+
+func (d Detection) String() string {
+	s := d.Kind
+	if d.Title != "" {
+		s += fmt.Sprintf(" %q", d.Title)
+	}
+	if d.Text != "" {
+		s += fmt.Sprintf(": %s", d.Text)
+	}
+	return s
+}
+
+// Detector looks for popups on a screenshot.
+type Detector interface {
+	Detect(ctx context.Context, img image.Image) (Detection, error)
+}
+
+// VisionDetector asks a vision model about popups.
+type VisionDetector struct {
+	Client *vision.Client
+}
+
+const detectPrompt = `Is there an unexpected window on top of this screen that blocks the application underneath, such as a modal dialog, a permission prompt (camera, microphone, notifications, screen recording, accessibility) or an update or upgrade nag? Ordinary application windows, menus and tooltips do not count.
+Answer with only a JSON object: {"present": true or false, "kind": "dialog", "permission", "update" or "other", "title": the popup's title, "text": its message in a few words, "dismiss": a description of the button that closes it without agreeing to anything, e.g. "the Not Now button"}.`
+
+// Detect implements Detector.
+func (v VisionDetector) Detect(ctx context.Context, img image.Image) (Detection, error) {
+	text, err := v.Client.Generate(ctx, detectPrompt, img)
+	if err != nil {
+		return Detection{}, err
+	}
+	text = strings.TrimSpace(text)
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+	var d Detection
+	if err := json.Unmarshal([]byte(text), &d); err != nil {
+		return Detection{}, fmt.Errorf("failed to parse popup answer %q: %w", text, err)
+	}
+	switch d.Kind {
+	case KindDialog, KindPermission, KindUpdate:
+	default:
+		d.Kind = KindOther
+	}
+	return d, nil
+}
+
+// Actor presses buttons and keys, as a desktop driver does.
+type Actor interface {
+	Move(normX, normY float64) error
+	Click(button string) error
+	KeyTap(key string) error
+}
+
+// Locator finds the buttons to press, as vision.Client does.
+type Locator interface {
+	Locate(ctx context.Context, img image.Image, target string) (vision.Point, error)
+}
+
+// Watcher checks for popups and applies the config's policies.
+type Watcher struct {
+	Config   *Config
+	Detector Detector
+	Locator  Locator
+	Actor    Actor
+	Capture  func() (image.Image, error)
+	// MinChange is how much of the screen, from 0 to 1, must have changed
+	// since the last check for the detector to be asked again; 0 means
+	// 0.002. It saves a model call for every step while nothing pops up.
+	MinChange float64
+	Logf      func(format string, args ...any)
+
+	last image.Image
+}
+
+// Check looks for a popup and handles it. It returns an error wrapping
+// ErrPopup if the policy is to fail or the popup could not be cleared.
+func (w *Watcher) Check(ctx context.Context) error {
+	img, err := w.Capture()
+	if err != nil {
+		return fmt.Errorf("failed to capture screen: %w", err)
+	}
+	if !w.changed(img) {
+		return nil
+	}
+	d, err := w.Detector.Detect(ctx, img)
+	if err != nil {
+		// A failed check should not fail the run.
+		w.logf("popup check failed: %v", err)
+		return nil
+	}
+	w.last = img
+	if !d.Present {
+		return nil
+	}
+
+	rule := w.Config.ruleFor(d)
+	w.logf("popup: %s; policy %s", d, rule.Policy)
+	switch rule.Policy {
+	case PolicyIgnore:
+		return nil
+	case PolicyDismiss:
+		if err := w.dismiss(ctx, img, d, rule); err != nil {
+			return fmt.Errorf("%w: %s: failed to dismiss: %v", ErrPopup, d, err)
+		}
+	case PolicyPause:
+		if err := w.pause(ctx, d); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrPopup, d)
+	}
+	w.last = nil
+	return nil
+}
+
+func (w *Watcher) changed(img image.Image) bool {
+	if w.last == nil {
+		return true
+	}
+	d, err := vision.CompareImages(w.last, img, 24)
+	if err != nil {
+		return true
+	}
+	minChange := w.MinChange
+	if minChange <= 0 {
+		minChange = 0.002
+	}
+	return d.Fraction >= minChange
+}
+
+// dismiss presses the popup's dismiss button, or escape if there is none,
+// and checks that it went away.
+func (w *Watcher) dismiss(ctx context.Context, img image.Image, d Detection, rule Rule) error {
+	button := rule.Button
+	if button == "" {
+		button = d.Dismiss
+	}
+	if button == "" || w.Locator == nil {
+		if err := w.Actor.KeyTap("escape"); err != nil {
+			return err
+		}
+	} else {
+		p, err := w.Locator.Locate(ctx, img, button)
+		if err != nil {
+			return fmt.Errorf("failed to find %s: %w", button, err)
+		}
+		x, y := p.Normalize(img.Bounds())
+		if err := w.Actor.Move(x, y); err != nil {
+			return err
+		}
+		if err := w.Actor.Click("left"); err != nil {
+			return err
+		}
+	}
+	// Give the window a moment to close before looking again.
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Second):
+	}
+	return w.cleared(ctx)
+}
+
+// pause polls until the popup is gone or the pause times out.
+func (w *Watcher) pause(ctx context.Context, d Detection) error {
+	timeout := w.Config.pauseTimeout()
+	w.logf("paused for up to %v: close the %s to continue", timeout, d.Kind)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s was not closed within %v", ErrPopup, d, timeout)
+		case <-time.After(3 * time.Second):
+		}
+		if w.cleared(ctx) == nil {
+			w.logf("popup closed; resuming")
+			return nil
+		}
+	}
+}
+
+// cleared returns nil if no popup is on the screen.
+func (w *Watcher) cleared(ctx context.Context) error {
+	img, err := w.Capture()
+	if err != nil {
+		return err
+	}
+	d, err := w.Detector.Detect(ctx, img)
+	if err != nil {
+		return err
+	}
+	if d.Present {
+		return fmt.Errorf("still showing %s", d)
+	}
+	return nil
+}
+
+func (w *Watcher) logf(format string, args ...any) {
+	if w.Logf != nil {
+		w.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,73 @@
+// Package preflight checks, before any capture or input happens, that the
+// operating system lets agentGo see and control the desktop. Without these
+// permissions macOS silently returns black screenshots and drops input.
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Permission is an operating system permission agentGo needs.
+type Permission struct {
+	// Name is the permission as shown in system settings.
+	Name string
+	// Purpose says what fails without it.
+	Purpose string
+	// Settings opens the settings pane where it is granted.
+	Settings string
+}
+
+var (
+	// ScreenRecording is required to capture anything but the desktop
+	// background and the menu bar.
+	ScreenRecording = Permission{
+		Name:     "Screen Recording",
+		Purpose:  "screenshots come back black or show only the wallpaper",
+		Settings: "x-apple.systempreferences:com.apple.preference.security?Privacy_ScreenCapture",
+	}
+	// Accessibility is required to move the mouse and send keystrokes.
+	Accessibility = Permission{
+		Name:     "Accessibility",
+		Purpose:  "mouse and keyboard input is silently ignored",
+		Settings: "x-apple.systempreferences:com.apple.preference.security?Privacy_Accessibility",
+	}
+)
+
+// Missing returns the permissions this process lacks. It is always empty on
+// platforms without such permissions.
+func Missing() []Permission {
+	return missing()
+}
+
+// Check returns an error explaining how to grant any missing permissions,
+// or nil if everything needed is granted.
+func Check() error {
+	perms := Missing()
+	if len(perms) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("missing macOS permissions:\n")
+	for _, p := range perms {
+		fmt.Fprintf(&b, "  - %s: without it %s\n", p.Name, p.Purpose)
+	}
+	b.WriteString("Open System Settings > Privacy & Security, enable each permission above for the\n")
+	b.WriteString("app agentGo runs from (Terminal, iTerm, your IDE, or the agentgo binary itself),\n")
+	b.WriteString("then quit and reopen that app. Run \"agentgo preflight -open\" to jump to the panes.")
+	return errors.New(b.String())
+}
+
+// Open opens the system settings pane for p.
+func Open(p Permission) error {
+	if runtime.GOOS != "darwin" {
+		return fmt.Errorf("%s is granted in system settings only on macOS", p.Name)
+	}
+	if err := exec.Command("open", p.Settings).Run(); err != nil {
+		return fmt.Errorf("failed to open settings for %s: %w", p.Name, err)
+	}
+	return nil
+}
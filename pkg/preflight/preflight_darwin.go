@@ -0,0 +1,46 @@
+//go:build darwin && cgo
+
+package preflight
+
+/*
+#cgo LDFLAGS: -framework CoreGraphics -framework ApplicationServices
+#include <CoreGraphics/CoreGraphics.h>
+#include <ApplicationServices/ApplicationServices.h>
+
+static int screenCaptureAllowed(void) {
+	if (__builtin_available(macOS 10.15, *)) {
+		return CGPreflightScreenCaptureAccess();
+	}
+	return 1;
+}
+
+static int requestScreenCapture(void) {
+	if (__builtin_available(macOS 10.15, *)) {
+		return CGRequestScreenCaptureAccess();
+	}
+	return 1;
+}
+
+static int accessibilityAllowed(void) {
+	return AXIsProcessTrusted();
+}
+*/
+import "C"
+
+func missing() []Permission {
+	var perms []Permission
+	if C.screenCaptureAllowed() == 0 {
+		perms = append(perms, ScreenRecording)
+	}
+	if C.accessibilityAllowed() == 0 {
+		perms = append(perms, Accessibility)
+	}
+	return perms
+}
+
+// Request asks macOS to show its Screen Recording prompt, which also adds
+// the app to the list in System Settings. It reports whether access is
+// already granted.
+func Request() bool {
+	return C.requestScreenCapture() != 0
+}
@@ -0,0 +1,14 @@
+//go:build !darwin || !cgo
+
+package preflight
+
+// missing cannot detect permissions here: other platforms have none to
+// grant, and without cgo macOS cannot be asked.
+func missing() []Permission {
+	return nil
+}
+
+// Request reports that access is granted; there is nothing to request.
+func Request() bool {
+	return true
+}
@@ -0,0 +1,160 @@
+// Package profile implements named configuration profiles. A profile
+// bundles the provider, model, prompts, model settings and output locations
+// used together, e.g. "work-vertex" and "home-gemini", in one file:
+//
+//	{
+//	  "home-gemini": {
+//	    "model": "gemini-2.0-flash,gemini-1.5-flash",
+//	    "prompts": "~/agentgo/prompts.json",
+//	    "vision_config": {"temperature": 0, "safety": {"all": "only_high"}},
+//	    "out": "~/agentgo/recordings/session.csv"
+//	  }
+//	}
+//
+// A profile is the lowest configuration layer above the built-in defaults:
+// .env files, the environment and command-line flags all override it.
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"agentGo/pkg/dotenv"
+)
+
+const (
+	// EnvVar selects a profile when no -profile flag is given.
+	EnvVar = "AGENTGO_PROFILE"
+	// PathEnvVar overrides the location of the profiles file.
+	PathEnvVar = "AGENTGO_PROFILES"
+)
+
+// Profile is one named set of settings.
+type Profile struct {
+	// Provider is the model provider. Only "gemini" is supported.
+	Provider string `json:"provider,omitempty"`
+	// Model is the model, or comma-separated fallback chain, to use.
+	Model string `json:"model,omitempty"`
+	// Prompts is the path of a prompts file.
+	Prompts string `json:"prompts,omitempty"`
+	// VisionConfig is the model sampling and safety settings, either
+	// inline or as the path of a config file.
+	VisionConfig json.RawMessage `json:"vision_config,omitempty"`
+	// Out is where the recorder writes recordings. It is applied as
+	// AGENTGO_RECORD_OUT rather than AGENTGO_OUT, which would also set the
+	// -out flags of agentgo's commands, whose outputs are something else.
+	Out string `json:"out,omitempty"`
+	// Flags sets any other flag by name, e.g. {"driver": "xvfb"}.
+	Flags map[string]string `json:"flags,omitempty"`
+	// Env sets environment variables such as GEMINI_API_KEY.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// DefaultPath is the profiles file named by AGENTGO_PROFILES, or else
+// profiles.json in the user's agentgo config directory.
+func DefaultPath() string {
+	if path := os.Getenv(PathEnvVar); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "agentgo", "profiles.json")
+}
+
+// Load reads the named profile from the profiles file at path, or from
+// DefaultPath if path is empty.
+func Load(path, name string) (Profile, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	var profiles map[string]Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return Profile{}, fmt.Errorf("failed to parse profiles %s: %w", path, err)
+	}
+	p, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("no profile %q in %s (have %s)", name, path, strings.Join(names, ", "))
+	}
+	if p.Provider != "" && p.Provider != "gemini" {
+		return Profile{}, fmt.Errorf("profile %q: provider %q is not supported", name, p.Provider)
+	}
+	return p, nil
+}
+
+// Apply puts the profile's settings into the environment, leaving variables
+// that are already set alone, so that dotenv.ApplyFlags picks them up as
+// flag defaults.
+func (p Profile) Apply() error {
+	settings := map[string]string{}
+	for name, value := range p.Flags {
+		settings[dotenv.EnvName(name)] = value
+	}
+	if p.Model != "" {
+		settings[dotenv.EnvName("model")] = p.Model
+	}
+	if p.Prompts != "" {
+		settings[dotenv.EnvName("prompts")] = expandHome(p.Prompts)
+	}
+	if p.Out != "" {
+		settings[dotenv.EnvName("record-out")] = expandHome(p.Out)
+	}
+	if len(p.VisionConfig) > 0 {
+		var path string
+		if err := json.Unmarshal(p.VisionConfig, &path); err == nil {
+			settings[dotenv.EnvName("vision-config")] = expandHome(path)
+		} else {
+			settings[dotenv.EnvName("vision-config")] = string(p.VisionConfig)
+		}
+	}
+	for name, value := range p.Env {
+		settings[name] = value
+	}
+	for name, value := range settings {
+		if _, ok := os.LookupEnv(name); !ok {
+			if err := os.Setenv(name, value); err != nil {
+				return fmt.Errorf("failed to apply profile setting %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Use loads and applies the named profile. An empty name falls back to
+// AGENTGO_PROFILE, and if that is unset too Use does nothing.
+func Use(path, name string) error {
+	if name == "" {
+		name = os.Getenv(EnvVar)
+	}
+	if name == "" {
+		return nil
+	}
+	p, err := Load(path, name)
+	if err != nil {
+		return err
+	}
+	return p.Apply()
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
@@ -0,0 +1,264 @@
+// Package progress shows how far a run has got on a terminal: a bar with
+// the steps done and an estimate of the time left, what the run is doing,
+// and the results of its latest checks. The display is redrawn in place,
+// and log lines written through it scroll above it, so a run's output stays
+// readable instead of one line per move.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// recentChecks is how many check results the display shows.
+const recentChecks = 3
+
+// redrawInterval limits how often progress alone is redrawn, since
+// recordings move the pointer many times a second.
+const redrawInterval = 100 * time.Millisecond
+
+// Display is a progress display on a terminal. A nil Display shows nothing,
+// so callers need not check whether it is on.
+type Display struct {
+	// Remaining, if set, estimates the time left after done steps. By
+	// default it is extrapolated from the time the steps so far took.
+	Remaining func(done int) time.Duration
+
+	out   io.Writer
+	total int
+	width int
+	start time.Time
+
+	mu      sync.Mutex
+	first   int // steps done when the display started, e.g. on resume
+	done    int
+	current string
+	checks  []string
+	lines   int // lines of the display on the terminal
+	drawn   time.Time
+	paused  bool
+	closed  bool
+}
+
+// New returns a display of a run of total steps on out, a terminal that
+// understands ANSI escapes. Lines are cut to the width in COLUMNS, or 80.
+func New(out io.Writer, total int) *Display {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || width < 20 {
+		width = 80
+	}
+	return &Display{out: out, total: total, width: width, start: time.Now(), first: -1}
+}
+
+// Terminal reports whether f is a terminal a Display can draw on.
+func Terminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	return os.Getenv("TERM") != "dumb"
+}
+
+// Step sets how many steps are done and what the run does now.
+func (d *Display) Step(done int, action string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.first < 0 {
+		d.first = done
+	}
+	d.done, d.current = done, action
+	d.redraw()
+}
+
+// Done sets how many steps are done.
+func (d *Display) Done(done int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.first < 0 {
+		d.first = done
+	}
+	d.done = done
+	d.redraw()
+}
+
+// Action sets what the run does now, without finishing a step.
+func (d *Display) Action(action string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.current = action
+	d.redraw()
+}
+
+// Check adds the result of a check, such as an assertion, to those shown.
+func (d *Display) Check(what string, err error) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	line := "  ✓ " + what
+	if err != nil {
+		line = "  ✗ " + what + ": " + err.Error()
+	}
+	d.checks = append(d.checks, line)
+	if len(d.checks) > recentChecks {
+		d.checks = d.checks[len(d.checks)-recentChecks:]
+	}
+	d.draw()
+}
+
+// Write writes p above the display, e.g. as the output of a log.Logger.
+func (d *Display) Write(p []byte) (int, error) {
+	if d == nil {
+		return len(p), nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clear()
+	n, err := d.out.Write(p)
+	d.draw()
+	return n, err
+}
+
+// Pause removes the display until Resume, e.g. while the run asks a
+// question on the terminal.
+func (d *Display) Pause() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clear()
+	d.paused = true
+}
+
+// Resume shows the display again after Pause.
+func (d *Display) Resume() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = false
+	d.draw()
+}
+
+// Close leaves the display as it last was and stops updating it.
+func (d *Display) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = false
+	d.draw()
+	d.closed = true
+	d.lines = 0
+}
+
+// clear erases the display, leaving the cursor where it started.
+func (d *Display) clear() {
+	if d.lines == 0 {
+		return
+	}
+	// To the start of the display's first line, then erase to the end of
+	// the screen.
+	fmt.Fprintf(d.out, "\x1b[%dF\x1b[J", d.lines)
+	d.lines = 0
+}
+
+// draw redraws the display.
+func (d *Display) draw() {
+	if d.paused || d.closed {
+		return
+	}
+	d.clear()
+	lines := []string{d.bar()}
+	if d.current != "" {
+		lines = append(lines, "  "+d.current)
+	}
+	lines = append(lines, d.checks...)
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(d.cut(line))
+		b.WriteByte('\n')
+	}
+	io.WriteString(d.out, b.String())
+	d.lines = len(lines)
+	d.drawn = time.Now()
+}
+
+// redraw redraws the display unless it was drawn moments ago. Close draws
+// what was skipped.
+func (d *Display) redraw() {
+	if time.Since(d.drawn) >= redrawInterval {
+		d.draw()
+	}
+}
+
+// barWidth is the width of the bar itself.
+const barWidth = 24
+
+// bar returns the first line: the bar, the steps done, the time taken and
+// the time left.
+func (d *Display) bar() string {
+	elapsed := time.Since(d.start)
+	line := fmt.Sprintf("%d steps  %s", d.done, formatDuration(elapsed))
+	if d.total > 0 {
+		filled := min(barWidth, barWidth*d.done/d.total)
+		line = fmt.Sprintf("[%s%s] %d/%d  %s", strings.Repeat("█", filled), strings.Repeat("░", barWidth-filled), d.done, d.total, formatDuration(elapsed))
+		if eta, ok := d.eta(elapsed); ok {
+			line += "  ETA " + formatDuration(eta)
+		}
+	}
+	return line
+}
+
+// eta estimates the time left, if it can.
+func (d *Display) eta(elapsed time.Duration) (time.Duration, bool) {
+	if d.done >= d.total {
+		return 0, false
+	}
+	if d.Remaining != nil {
+		return d.Remaining(d.done), true
+	}
+	if ran := d.done - d.first; ran > 0 {
+		return elapsed / time.Duration(ran) * time.Duration(d.total-d.done), true
+	}
+	return 0, false
+}
+
+// cut puts line on one line as wide as the terminal, so that it does not
+// wrap and throw off the count of lines to redraw.
+func (d *Display) cut(line string) string {
+	line = strings.NewReplacer("\r", "", "\n", " ", "\t", " ").Replace(line)
+	if utf8.RuneCountInString(line) <= d.width {
+		return line
+	}
+	runes := []rune(line)
+	return string(runes[:d.width-1]) + "…"
+}
+
+// formatDuration formats d as 1:05 or 1:02:05.
+func formatDuration(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,133 @@
+// Package rdp attaches to a Windows machine over Remote Desktop. It runs
+// FreeRDP inside a private Xvfb display and drives that display, so the
+// remote machine needs nothing installed beyond RDP itself.
+package rdp
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"agentGo/pkg/x11"
+)
+
+// Options describe the RDP session to open.
+type Options struct {
+	// Host is host or host:port; the port defaults to 3389.
+	Host     string
+	Username string
+	Password string
+	Domain   string
+	// Width and Height set the remote desktop size. They default to
+	// 1920x1080.
+	Width, Height int
+	// Timeout bounds how long to wait for the session window. It defaults
+	// to 30 seconds.
+	Timeout time.Duration
+}
+
+// Session is an open RDP session. It implements the desktop driver methods
+// (Move, Click, Type, KeyTap, Capture, Close); coordinates are relative to
+// the remote desktop.
+type Session struct {
+	*x11.Xvfb
+	client *exec.Cmd
+	exited chan error
+}
+
+// Connect opens an RDP session described by opts.
+func Connect(opts Options) (*Session, error) {
+	if opts.Width == 0 || opts.Height == 0 {
+		opts.Width, opts.Height = 1920, 1080
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	host := opts.Host
+	if !strings.Contains(host, ":") {
+		host += ":3389"
+	}
+
+	xvfb, err := x11.StartXvfb(opts.Width, opts.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"/v:" + host,
+		"/size:" + strconv.Itoa(opts.Width) + "x" + strconv.Itoa(opts.Height),
+		"/f",
+		"/cert:ignore",
+		"/from-stdin:force",
+		"-decorations",
+		"+auto-reconnect",
+	}
+	if opts.Username != "" {
+		args = append(args, "/u:"+opts.Username)
+	}
+	if opts.Domain != "" {
+		args = append(args, "/d:"+opts.Domain)
+	}
+	client := exec.Command("xfreerdp", args...)
+	client.Env = append(client.Environ(), "DISPLAY="+xvfb.Name)
+	// The password goes over stdin so it does not show up in ps.
+	client.Stdin = strings.NewReader(opts.Password + "\n")
+	var stderr strings.Builder
+	client.Stderr = &stderr
+	if err := client.Start(); err != nil {
+		xvfb.Close()
+		return nil, fmt.Errorf("failed to start xfreerdp: %w", err)
+	}
+
+	s := &Session{Xvfb: xvfb, client: client, exited: make(chan error, 1)}
+	go func() { s.exited <- client.Wait() }()
+
+	if err := s.waitForWindow(opts.Timeout); err != nil {
+		s.Close()
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("rdp connection to %s failed: %w: %s", host, err, lastLine(msg))
+		}
+		return nil, fmt.Errorf("rdp connection to %s failed: %w", host, err)
+	}
+	return s, nil
+}
+
+// waitForWindow waits for the FreeRDP window to be mapped.
+func (s *Session) waitForWindow(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-s.exited:
+			s.exited <- err
+			return fmt.Errorf("xfreerdp exited: %v", err)
+		default:
+		}
+		cmd := exec.Command("xdotool", "search", "--onlyvisible", "--class", "freerdp")
+		cmd.Env = append(cmd.Environ(), "DISPLAY="+s.Name)
+		if out, err := cmd.Output(); err == nil && len(strings.TrimSpace(string(out))) > 0 {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for the session window")
+}
+
+// Close disconnects the session and stops its display.
+func (s *Session) Close() error {
+	select {
+	case <-s.exited:
+	default:
+		s.client.Process.Kill()
+		<-s.exited
+	}
+	return s.Xvfb.Close()
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
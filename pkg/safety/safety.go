@@ -0,0 +1,325 @@
+// Package safety flags risky actions before they are performed: typing into
+// password fields, pressing Delete or Enter on a confirmation dialog,
+// answering sudo and administrator prompts and the like. Pattern rules
+// catch what the action and the window make plain, and a vision model can
+// be asked about the rest. A flagged action runs only if its rule is
+// granted in advance or a person confirms it.
+package safety
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"strings"
+	"sync"
+
+	"agentGo/pkg/frame"
+	"agentGo/pkg/script"
+)
+
+// Kinds of action.
+const (
+	KindClick = "click"
+	KindType  = "type"
+	KindKey   = "key"
+)
+
+// RuleModel names the risks the model finds, for granting them in Allow.
+const RuleModel = "model"
+
+// Action is an action about to be performed.
+type Action struct {
+	Kind string
+	// Text is what a type action types.
+	Text string
+	// Key is what a key action presses, e.g. "enter" or "ctrl+a".
+	Key string
+	// Target describes what the action is aimed at: the element a click
+	// clicks, or for typing and keys the element last clicked, which has
+	// the focus. It is empty if the action gave coordinates.
+	Target string
+	// Window is the title of the focused window, if it is known.
+	Window string
+}
+
+// String describes the action without the text it types, which may be
+// secret.
+func (a Action) String() string {
+	var b strings.Builder
+	switch a.Kind {
+	case KindType:
+		fmt.Fprintf(&b, "type %d characters", len([]rune(a.Text)))
+	case KindKey:
+		fmt.Fprintf(&b, "press %s", a.Key)
+	default:
+		b.WriteString(a.Kind)
+	}
+	if a.Target != "" {
+		if a.Kind == KindClick {
+			fmt.Fprintf(&b, " %s", a.Target)
+		} else {
+			fmt.Fprintf(&b, " into %s", a.Target)
+		}
+	}
+	if a.Window != "" {
+		fmt.Fprintf(&b, " in window %q", a.Window)
+	}
+	return b.String()
+}
+
+// Rule flags the actions of Kinds whose fields match every pattern set.
+type Rule struct {
+	Name   string
+	Reason string
+	Kinds  []string
+	Text   *regexp.Regexp
+	Key    *regexp.Regexp
+	// Target matches the action's target; Context matches its target or
+	// its window title.
+	Target  *regexp.Regexp
+	Context *regexp.Regexp
+}
+
+func (r Rule) matches(a Action) bool {
+	kind := false
+	for _, k := range r.Kinds {
+		kind = kind || k == a.Kind
+	}
+	return kind &&
+		(r.Text == nil || r.Text.MatchString(a.Text)) &&
+		(r.Key == nil || r.Key.MatchString(a.Key)) &&
+		(r.Target == nil || r.Target.MatchString(a.Target)) &&
+		(r.Context == nil || r.Context.MatchString(a.Target) || r.Context.MatchString(a.Window))
+}
+
+// destructive matches the wording of buttons and dialogs that delete or
+// overwrite something.
+const destructive = `(?i)\b(delete|remove|erase|discard|overwrite|format|uninstall|empty trash|permanently|are you sure|confirm)`
+
+// DefaultRules are the risks flagged unless a Classifier sets its own.
+var DefaultRules = []Rule{
+	{
+		Name:    "password-field",
+		Reason:  "types into a password or secret field",
+		Kinds:   []string{KindType},
+		Context: regexp.MustCompile(`(?i)password|passphrase|passcode|\bpin\b|secret`),
+	},
+	{
+		Name:    "sudo-prompt",
+		Reason:  "answers a sudo or administrator prompt",
+		Kinds:   []string{KindType, KindKey, KindClick},
+		Context: regexp.MustCompile(`(?i)\bsudo\b|authentication required|authenticate|user account control|administrator|polkit|run as admin`),
+	},
+	{
+		Name:    "confirm-dialog",
+		Reason:  "presses Delete or Enter on a confirmation",
+		Kinds:   []string{KindKey},
+		Key:     regexp.MustCompile(`(?i)^(delete|del|enter|return)$`),
+		Context: regexp.MustCompile(destructive),
+	},
+	{
+		Name:   "destructive-click",
+		Reason: "clicks a button that deletes or overwrites something",
+		Kinds:  []string{KindClick},
+		Target: regexp.MustCompile(destructive),
+	},
+	{
+		Name:   "destructive-command",
+		Reason: "types a destructive or elevated command",
+		Kinds:  []string{KindType},
+		Text:   regexp.MustCompile(`(?i)\bsudo\b|\brm\s+-[a-z]*[rf]|\bmkfs\b|\bdd\s+if=|\bformat\s+[a-z]:|\bshutdown\b|\bdel\s+/[sqf]|\bdrop\s+(table|database)\b`),
+	},
+}
+
+// Risk is why an action was flagged.
+type Risk struct {
+	// Rule names the rule that flagged it, or RuleModel.
+	Rule   string
+	Reason string
+}
+
+// Model answers questions about screenshots.
+type Model interface {
+	Generate(ctx context.Context, prompt string, img image.Image) (string, error)
+}
+
+// Classifier decides which actions are risky.
+type Classifier struct {
+	// Rules are checked first; nil means DefaultRules.
+	Rules []Rule
+	// Model, if set, is shown the screen and asked about every action no
+	// rule flags. Capture takes the screenshot.
+	Model   Model
+	Capture func() (image.Image, error)
+}
+
+// Classify returns the risk of a, or nil if it is safe.
+func (c *Classifier) Classify(ctx context.Context, a Action) (*Risk, error) {
+	rules := c.Rules
+	if rules == nil {
+		rules = DefaultRules
+	}
+	for _, r := range rules {
+		if r.matches(a) {
+			return &Risk{Rule: r.Name, Reason: r.Reason}, nil
+		}
+	}
+	if c.Model == nil || c.Capture == nil {
+		return nil, nil
+	}
+	img, err := c.Capture()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture the screen to check the action: %w", err)
+	}
+	defer frame.Put(img)
+	answer, err := c.Model.Generate(ctx, modelPrompt(a), img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the action with the model: %w", err)
+	}
+	answer = strings.TrimSpace(answer)
+	if reason, ok := cutFold(answer, "RISKY"); ok {
+		reason = strings.TrimSpace(strings.TrimLeft(reason, ":- "))
+		if reason == "" {
+			reason = "the model judged it risky"
+		}
+		return &Risk{Rule: RuleModel, Reason: reason}, nil
+	}
+	return nil, nil
+}
+
+func modelPrompt(a Action) string {
+	return fmt.Sprintf(`An automated agent is about to %s on this screen.
+Is that risky enough that a person must confirm it first? Risky actions include typing into a password or other secret field, answering a sudo, administrator or other elevation prompt, confirming a deletion, and any other change that cannot be undone.
+Answer with the single word SAFE, or with RISKY: followed by a short reason.`, a)
+}
+
+// cutFold reports whether s starts with prefix, ignoring case, and returns
+// the rest.
+func cutFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// ErrRefused is returned for risky actions that were neither granted nor
+// confirmed.
+var ErrRefused = errors.New("risky action refused")
+
+// Policy lets risky actions run only if their rule is granted or a person
+// confirms them.
+type Policy struct {
+	Classifier Classifier
+	// Allow names the rules whose actions are granted in advance; "*"
+	// grants every rule and the model's judgement.
+	Allow map[string]bool
+	// Confirm asks a person whether to perform a risky action. Without it,
+	// risky actions that are not granted are refused.
+	Confirm func(ctx context.Context, a Action, r Risk) (bool, error)
+	// Logf receives a line for every risky action and what became of it.
+	Logf func(format string, args ...any)
+}
+
+// ParseAllow parses a comma-separated list of rule names, e.g.
+// "password-field,confirm-dialog", or "*" for all of them.
+func ParseAllow(spec string) (map[string]bool, error) {
+	known := map[string]bool{"*": true, RuleModel: true}
+	for _, r := range DefaultRules {
+		known[r.Name] = true
+	}
+	allow := map[string]bool{}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+		allow[name] = true
+	}
+	return allow, nil
+}
+
+// Check returns nil if a may be performed, or an error wrapping ErrRefused.
+func (p *Policy) Check(ctx context.Context, a Action) error {
+	risk, err := p.Classifier.Classify(ctx, a)
+	if err != nil {
+		return err
+	}
+	if risk == nil {
+		return nil
+	}
+	if p.Allow["*"] || p.Allow[risk.Rule] {
+		p.logf("safety: %s: allowed by policy (%s: %s)", a, risk.Rule, risk.Reason)
+		return nil
+	}
+	if p.Confirm != nil {
+		ok, err := p.Confirm(ctx, a, *risk)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %s (%v)", ErrRefused, a, risk.Reason, err)
+		}
+		if ok {
+			p.logf("safety: %s: confirmed (%s: %s)", a, risk.Rule, risk.Reason)
+			return nil
+		}
+	}
+	p.logf("safety: %s: refused (%s: %s)", a, risk.Rule, risk.Reason)
+	return fmt.Errorf("%w: %s (%s); grant %q in advance or confirm it", ErrRefused, a, risk.Reason, risk.Rule)
+}
+
+func (p *Policy) logf(format string, args ...any) {
+	if p.Logf != nil {
+		p.Logf(format, args...)
+	}
+}
+
+// Guard checks the actions of a run against a policy, taking the element
+// last clicked as the one typing and keys go into.
+type Guard struct {
+	Policy *Policy
+	// Window, if set, returns the title of the focused window.
+	Window func() string
+
+	mu    sync.Mutex
+	focus string
+}
+
+// Check returns nil if a may be performed. A nil Guard allows everything.
+func (g *Guard) Check(ctx context.Context, a Action) error {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	if a.Kind != KindClick && a.Target == "" {
+		a.Target = g.focus
+	}
+	g.mu.Unlock()
+	if a.Window == "" && g.Window != nil {
+		a.Window = g.Window()
+	}
+	if err := g.Policy.Check(ctx, a); err != nil {
+		return err
+	}
+	if a.Kind == KindClick {
+		g.mu.Lock()
+		g.focus = a.Target
+		g.mu.Unlock()
+	}
+	return nil
+}
+
+// Step checks a script step before it runs, for script.Runner.BeforeStep.
+func (g *Guard) Step(ctx context.Context, where string, step script.Step) error {
+	switch step.Action {
+	case script.ActionClick:
+		return g.Check(ctx, Action{Kind: KindClick, Target: step.Target})
+	case script.ActionType:
+		return g.Check(ctx, Action{Kind: KindType, Text: step.Text})
+	case script.ActionKey:
+		return g.Check(ctx, Action{Kind: KindKey, Key: step.Key})
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Run statuses.
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Run is one history entry for a job.
+type Run struct {
+	Job       string    `json:"job"`
+	Scheduled time.Time `json:"scheduled"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	// Report and Screenshot are artifact paths written for the run, if any.
+	Report     string `json:"report,omitempty"`
+	Screenshot string `json:"screenshot,omitempty"`
+}
+
+// Duration returns how long the run took.
+func (r Run) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// History stores run history as one JSONL file per job in a directory.
+type History struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NewHistory returns a history rooted at dir, creating it if needed.
+func NewHistory(dir string) (*History, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &History{Dir: dir}, nil
+}
+
+// Append adds run to its job's history file.
+func (h *History) Append(run Run) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, err := os.OpenFile(h.path(run.Job), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Recent returns up to n of the job's most recent runs, oldest first. A
+// non-positive n returns all runs.
+func (h *History) Recent(job string, n int) ([]Run, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, err := os.Open(h.path(job))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if n > 0 && len(runs) > n {
+		runs = runs[len(runs)-n:]
+	}
+	return runs, scanner.Err()
+}
+
+func (h *History) path(job string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, job)
+	return filepath.Join(h.Dir, safe+".jsonl")
+}
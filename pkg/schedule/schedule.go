@@ -0,0 +1,245 @@
+// Package schedule runs scripts and recordings unattended on cron-style
+// schedules, with overlapping-run protection and per-job run history.
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"agentGo/pkg/notify"
+	"agentGo/pkg/script"
+)
+
+// Job is one scheduled task. Exactly one of Script and Recording is set.
+type Job struct {
+	Name string `json:"name"`
+	// Cron is a five-field cron expression, a macro such as @daily, or
+	// "@every 15m".
+	Cron      string            `json:"cron"`
+	Script    string            `json:"script,omitempty"`
+	Recording string            `json:"recording,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty"`
+	// Timeout bounds a single run. Zero means no limit.
+	Timeout script.Duration `json:"timeout,omitempty"`
+	// Notify overrides the schedule-wide notification settings.
+	Notify *notify.Config `json:"notify,omitempty"`
+
+	spec *Spec
+}
+
+// File is the on-disk schedule format.
+type File struct {
+	// Notify is the default notification config for jobs that set none.
+	Notify *notify.Config `json:"notify,omitempty"`
+	Jobs   []Job          `json:"jobs"`
+}
+
+// LoadFile reads and validates a schedule file.
+func LoadFile(path string) ([]Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule file: %w", err)
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule file %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range f.Jobs {
+		job := &f.Jobs[i]
+		if job.Name == "" {
+			return nil, fmt.Errorf("%s: job %d has no name", path, i)
+		}
+		if seen[job.Name] {
+			return nil, fmt.Errorf("%s: duplicate job name %q", path, job.Name)
+		}
+		seen[job.Name] = true
+		if (job.Script == "") == (job.Recording == "") {
+			return nil, fmt.Errorf("%s: job %q must set exactly one of script or recording", path, job.Name)
+		}
+		if job.spec, err = ParseCron(job.Cron); err != nil {
+			return nil, fmt.Errorf("%s: job %q: %w", path, job.Name, err)
+		}
+		if job.Notify == nil {
+			job.Notify = f.Notify
+		}
+	}
+	return f.Jobs, nil
+}
+
+// RunFunc executes a job once.
+type RunFunc func(ctx context.Context, job Job) error
+
+// Scheduler fires jobs at their scheduled times.
+type Scheduler struct {
+	Jobs    []Job
+	Run     RunFunc
+	History *History
+	// OnFinish, if set, is called after every run (including skipped ones)
+	// before it is recorded, e.g. to attach artifacts and send
+	// notifications. It may modify run.
+	OnFinish func(ctx context.Context, job Job, run *Run)
+	Logf     func(format string, args ...any)
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+// Start runs the scheduling loop until ctx is cancelled, then waits for
+// in-flight runs to finish.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running == nil {
+		s.running = make(map[string]bool)
+	}
+	s.mu.Unlock()
+	next := make([]time.Time, len(s.Jobs))
+	now := time.Now()
+	for i, job := range s.Jobs {
+		if job.spec == nil {
+			spec, err := ParseCron(job.Cron)
+			if err != nil {
+				return fmt.Errorf("job %q: %w", job.Name, err)
+			}
+			s.Jobs[i].spec = spec
+		}
+		next[i] = s.Jobs[i].spec.Next(now)
+		s.logf("job %q: next run at %s", job.Name, next[i].Format(time.RFC3339))
+	}
+
+	defer s.wg.Wait()
+	for {
+		earliest := time.Time{}
+		for _, t := range next {
+			if !t.IsZero() && (earliest.IsZero() || t.Before(earliest)) {
+				earliest = t
+			}
+		}
+		if earliest.IsZero() {
+			s.logf("no jobs left to schedule")
+			<-ctx.Done()
+			return nil
+		}
+
+		timer := time.NewTimer(time.Until(earliest))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case now = <-timer.C:
+		}
+
+		for i, job := range s.Jobs {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			s.fire(ctx, job, next[i])
+			next[i] = job.spec.Next(now)
+		}
+	}
+}
+
+// Trigger starts the job called name now, as if it were scheduled now,
+// unless a previous run of it is still in progress.
+func (s *Scheduler) Trigger(ctx context.Context, name string) error {
+	for _, job := range s.Jobs {
+		if job.Name == name {
+			s.fire(ctx, job, time.Now())
+			return nil
+		}
+	}
+	return fmt.Errorf("no job named %q", name)
+}
+
+// Running returns the names of the jobs running now.
+func (s *Scheduler) Running() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.running))
+	for name := range s.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fire starts job unless a previous run of it is still in progress.
+func (s *Scheduler) fire(ctx context.Context, job Job, scheduled time.Time) {
+	s.mu.Lock()
+	if s.running == nil {
+		s.running = make(map[string]bool)
+	}
+	if s.running[job.Name] {
+		s.mu.Unlock()
+		s.logf("job %q: skipping run scheduled for %s, previous run still in progress", job.Name, scheduled.Format(time.RFC3339))
+		now := time.Now()
+		s.finish(ctx, job, Run{Job: job.Name, Scheduled: scheduled, Start: now, End: now, Status: StatusSkipped,
+			Error: "previous run still in progress"})
+		return
+	}
+	s.running[job.Name] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, job.Name)
+			s.mu.Unlock()
+		}()
+		s.finish(ctx, job, s.RunNow(ctx, job, scheduled))
+	}()
+}
+
+// RunNow executes job synchronously and returns its history entry.
+func (s *Scheduler) RunNow(ctx context.Context, job Job, scheduled time.Time) Run {
+	runCtx := ctx
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, time.Duration(job.Timeout))
+		defer cancel()
+	}
+
+	run := Run{Job: job.Name, Scheduled: scheduled, Start: time.Now()}
+	s.logf("job %q: starting", job.Name)
+	err := s.Run(runCtx, job)
+	run.End = time.Now()
+	run.Status = StatusSuccess
+	if err != nil {
+		run.Status = StatusFailed
+		run.Error = err.Error()
+		s.logf("job %q: failed after %s: %v", job.Name, run.End.Sub(run.Start).Round(time.Millisecond), err)
+	} else {
+		s.logf("job %q: finished in %s", job.Name, run.End.Sub(run.Start).Round(time.Millisecond))
+	}
+	return run
+}
+
+func (s *Scheduler) finish(ctx context.Context, job Job, run Run) {
+	if s.OnFinish != nil {
+		s.OnFinish(ctx, job, &run)
+	}
+	if s.History == nil {
+		return
+	}
+	if err := s.History.Append(run); err != nil {
+		s.logf("failed to record history for job %q: %v", run.Job, err)
+	}
+}
+
+func (s *Scheduler) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
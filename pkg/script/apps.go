@@ -0,0 +1,130 @@
+package script
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"agentGo/pkg/apps"
+	"agentGo/pkg/vars"
+)
+
+// WindowManager is implemented by executors that can launch applications
+// and find, activate and close their windows, as the local desktop can.
+type WindowManager interface {
+	LaunchApp(app string, args []string) error
+	Windows() ([]apps.Window, error)
+	ActivateWindow(w apps.Window) error
+	CloseWindow(w apps.Window) error
+}
+
+// WindowPlacer is implemented by executors that can also move, resize and
+// maximize windows.
+type WindowPlacer interface {
+	PlaceWindow(w apps.Window, g apps.Geometry) error
+	MaximizeWindow(w apps.Window) error
+}
+
+// windowTimeout is how long the window steps wait for the window when the
+// step sets no timeout.
+const windowTimeout = 30 * time.Second
+
+// WindowQuery builds the query for the windows of app whose title matches
+// the regular expression title, if not empty.
+func WindowQuery(app, title string) (apps.Query, error) {
+	q := apps.Query{App: app}
+	if title != "" {
+		re, err := regexp.Compile(title)
+		if err != nil {
+			return q, fmt.Errorf("invalid window regex: %w", err)
+		}
+		q.Title = re
+	}
+	return q, nil
+}
+
+// runApp runs a step that launches an application or acts on a window.
+// Windows that are launched, placed or maximized are also brought to the
+// front, ready for the steps that follow.
+func (r *Runner) runApp(ctx context.Context, step Step, scope *vars.Set) error {
+	wm, ok := r.Exec.(WindowManager)
+	if !ok {
+		return errors.New("this driver cannot manage applications and windows")
+	}
+	placer, _ := r.Exec.(WindowPlacer)
+	var g apps.Geometry
+	switch step.Action {
+	case ActionPlaceWindow, ActionMaximizeWindow:
+		if placer == nil {
+			return errors.New("this driver cannot move or resize windows")
+		}
+		if step.Action == ActionPlaceWindow {
+			geometry, err := scope.Expand(step.Geometry)
+			if err != nil {
+				return err
+			}
+			if g, err = apps.ParseGeometry(geometry); err != nil {
+				return err
+			}
+		}
+	}
+	app, err := scope.Expand(step.App)
+	if err != nil {
+		return err
+	}
+	title, err := scope.Expand(step.Window)
+	if err != nil {
+		return err
+	}
+	q, err := WindowQuery(app, title)
+	if err != nil {
+		return err
+	}
+	switch step.Action {
+	case ActionCloseWindow:
+		windows, err := wm.Windows()
+		if err != nil {
+			return err
+		}
+		found := apps.Find(windows, q)
+		if len(found) == 0 {
+			return fmt.Errorf("no %s is open", q)
+		}
+		r.logf("closing %q", found[0].Title)
+		return wm.CloseWindow(found[0])
+	case ActionLaunchApp:
+		args := make([]string, len(step.Args))
+		for i, a := range step.Args {
+			if args[i], err = scope.Expand(a); err != nil {
+				return err
+			}
+		}
+		r.logf("launching %s", app)
+		if err := wm.LaunchApp(app, args); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(time.Duration(step.Timeout), windowTimeout))
+	defer cancel()
+	w, err := apps.Wait(ctx, wm.Windows, q)
+	if err != nil {
+		return err
+	}
+	switch step.Action {
+	case ActionPlaceWindow:
+		r.logf("placing %q at %s", w.Title, g)
+		if err := placer.PlaceWindow(w, g); err != nil {
+			return err
+		}
+	case ActionMaximizeWindow:
+		r.logf("maximizing %q", w.Title)
+		if err := placer.MaximizeWindow(w); err != nil {
+			return err
+		}
+	}
+	r.logf("activating %q", w.Title)
+	return wm.ActivateWindow(w)
+}
@@ -0,0 +1,286 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"agentGo/pkg/vars"
+	"agentGo/pkg/vision"
+)
+
+// Inspector finds elements on and reads text off screenshots, as
+// vision.Client does.
+type Inspector interface {
+	Locator
+	ReadText(ctx context.Context, img image.Image, target string) (string, error)
+	Visible(ctx context.Context, img image.Image, target string) (bool, error)
+}
+
+// Capturer is implemented by executors that can take screenshots. Failed
+// assertions are only documented with one if the executor is a Capturer.
+type Capturer interface {
+	Capture() (image.Image, error)
+}
+
+// WindowTitler is implemented by executors that can read the title of the
+// focused window.
+type WindowTitler interface {
+	WindowTitle() (string, error)
+}
+
+// ClipboardReader is implemented by executors that can read the clipboard.
+type ClipboardReader interface {
+	Clipboard() (string, error)
+}
+
+// How Expect is compared.
+const (
+	MatchEquals   = "equals"
+	MatchContains = "contains"
+	MatchRegex    = "regex"
+)
+
+var numberOps = map[string]func(a, b float64) bool{
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+}
+
+// AssertionError is a failed assertion step.
+type AssertionError struct {
+	// Message says what was expected and what was found.
+	Message string
+	// Artifacts are the files written to document the failure.
+	Artifacts []string
+}
+
+func (e *AssertionError) Error() string {
+	if len(e.Artifacts) == 0 {
+		return "assertion failed: " + e.Message
+	}
+	return fmt.Sprintf("assertion failed: %s (see %s)", e.Message, strings.Join(e.Artifacts, ", "))
+}
+
+// runAssert checks an assertion step, returning an *AssertionError if it
+// does not hold.
+func (r *Runner) runAssert(ctx context.Context, s *Script, step Step, scope *vars.Set, where string) error {
+	expect, err := scope.Expand(step.Expect)
+	if err != nil {
+		return err
+	}
+	target, err := scope.Expand(step.Target)
+	if err != nil {
+		return err
+	}
+
+	var screen image.Image
+	var message string
+	switch step.Action {
+	case ActionAssertText, ActionAssertNumber:
+		if r.Vision == nil {
+			return errors.New("reading text from the screen needs a vision model; set GEMINI_API_KEY")
+		}
+		if screen, err = r.capture(); err != nil {
+			return err
+		}
+		text, err := r.Vision.ReadText(ctx, screen, target)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", target, err)
+		}
+		if step.Action == ActionAssertText {
+			message = checkText(target, text, expect, step.Match)
+		} else {
+			message = checkNumber(target, text, step.Op, *step.Value)
+		}
+	case ActionAssertImage:
+		if screen, err = r.capture(); err != nil {
+			return err
+		}
+		if message, err = checkImage(s, step, scope, screen); err != nil {
+			return err
+		}
+	case ActionAssertWindowTitle:
+		w, ok := r.Exec.(WindowTitler)
+		if !ok {
+			return errors.New("this driver cannot read window titles")
+		}
+		title, err := w.WindowTitle()
+		if err != nil {
+			return fmt.Errorf("failed to read window title: %w", err)
+		}
+		message = checkText("the window title", title, expect, step.Match)
+	case ActionAssertClipboard:
+		c, ok := r.Exec.(ClipboardReader)
+		if !ok {
+			return errors.New("this driver cannot read the clipboard")
+		}
+		text, err := c.Clipboard()
+		if err != nil {
+			return fmt.Errorf("failed to read clipboard: %w", err)
+		}
+		message = checkText("the clipboard", text, expect, step.Match)
+	}
+	if message == "" {
+		r.logf("%s: %s passed", where, step.Action)
+		return nil
+	}
+	if r.Scrub != nil {
+		message = r.Scrub(message)
+	}
+	return &AssertionError{Message: message, Artifacts: r.saveArtifacts(where, screen, message)}
+}
+
+// checkText returns why got does not match want, or "" if it does.
+func checkText(what, got, want, match string) string {
+	var ok bool
+	switch match {
+	case MatchContains:
+		ok = strings.Contains(got, want)
+	case MatchRegex:
+		ok = regexp.MustCompile(want).MatchString(got)
+	default:
+		ok = strings.TrimSpace(got) == strings.TrimSpace(want)
+		match = MatchEquals
+	}
+	if ok {
+		return ""
+	}
+	return fmt.Sprintf("expected %s to %s %q, got %q", what, verb(match), want, got)
+}
+
+func verb(match string) string {
+	switch match {
+	case MatchContains:
+		return "contain"
+	case MatchRegex:
+		return "match"
+	}
+	return "equal"
+}
+
+var numberPattern = regexp.MustCompile(`-?\d[\d,]*(\.\d+)?|-?\.\d+`)
+
+// ParseNumber extracts the first number from text read off the screen,
+// ignoring currency symbols, units and thousands separators, so "$1,234.50"
+// is 1234.5 and "(12)" accounting style is -12.
+func ParseNumber(text string) (float64, error) {
+	m := numberPattern.FindStringIndex(text)
+	if m == nil {
+		return 0, fmt.Errorf("no number in %q", text)
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(text[m[0]:m[1]], ",", ""), 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 && strings.HasPrefix(strings.TrimSpace(text[:m[0]]), "(") && strings.HasPrefix(strings.TrimSpace(text[m[1]:]), ")") {
+		n = -n
+	}
+	return n, nil
+}
+
+func checkNumber(what, text, op string, want float64) string {
+	got, err := ParseNumber(text)
+	if err != nil {
+		return fmt.Sprintf("expected %s to be a number %s %g, got %q", what, op, want, text)
+	}
+	if numberOps[op](got, want) {
+		return ""
+	}
+	return fmt.Sprintf("expected %s %s %g, got %g (read %q)", what, op, want, got, text)
+}
+
+// checkImage returns why the step's reference image is not on screen, or
+// "" if it is.
+func checkImage(s *Script, step Step, scope *vars.Set, screen image.Image) (string, error) {
+	path, err := scope.Expand(step.Image)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) && s.dir != "" {
+		path = filepath.Join(s.dir, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open reference image: %w", err)
+	}
+	defer f.Close()
+	ref, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	threshold := step.Threshold
+	if threshold == 0 {
+		threshold = 0.9
+	}
+	p, score := vision.MatchTemplate(screen, ref)
+	if score >= threshold {
+		return "", nil
+	}
+	return fmt.Sprintf("expected %s on screen with similarity %.2f, best match %.2f at %.0f,%.0f", filepath.Base(path), threshold, score, p.X, p.Y), nil
+}
+
+func (r *Runner) capture() (image.Image, error) {
+	c, ok := r.Exec.(Capturer)
+	if !ok {
+		return nil, errors.New("this driver cannot capture the screen")
+	}
+	img, err := c.Capture()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture screen: %w", err)
+	}
+	return r.Secure.Redact(img), nil
+}
+
+// saveArtifacts writes the screen and message of a failed assertion to
+// ArtifactDir and returns the files written.
+func (r *Runner) saveArtifacts(where string, screen image.Image, message string) []string {
+	if r.ArtifactDir == "" {
+		return nil
+	}
+	if screen == nil {
+		screen, _ = r.capture()
+	}
+	if err := os.MkdirAll(r.ArtifactDir, 0755); err != nil {
+		r.logf("failed to create artifact directory: %v", err)
+		return nil
+	}
+	base := filepath.Join(r.ArtifactDir, strings.NewReplacer("[", "-", "]", "", ".", "_").Replace(where))
+	var files []string
+	if err := os.WriteFile(base+".txt", []byte(where+": "+message+"\n"), 0644); err != nil {
+		r.logf("failed to write assertion report: %v", err)
+	} else {
+		files = append(files, base+".txt")
+	}
+	if screen != nil {
+		if err := writePNG(base+".png", screen); err != nil {
+			r.logf("failed to write assertion screenshot: %v", err)
+		} else {
+			files = append(files, base+".png")
+		}
+	}
+	return files
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
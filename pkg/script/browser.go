@@ -0,0 +1,67 @@
+package script
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"time"
+
+	"agentGo/pkg/vars"
+)
+
+// Browser manages a browser through its DevTools endpoint, as cdp.Client
+// does.
+type Browser interface {
+	// Launch starts the browser with the user data directory profile and
+	// opens url in it.
+	Launch(ctx context.Context, profile, url string) error
+	// Navigate loads url in the current tab and waits for it to load.
+	Navigate(ctx context.Context, url string) error
+	// NewTab opens a tab, switches to it and loads url in it.
+	NewTab(ctx context.Context, url string) error
+	// SwitchTab switches to the first tab whose title or URL contains
+	// match.
+	SwitchTab(ctx context.Context, match string) error
+	// WaitLoad waits until the current tab has finished loading.
+	WaitLoad(ctx context.Context) error
+}
+
+// browserTimeout is how long the browser steps wait when the step sets no
+// timeout.
+const browserTimeout = 30 * time.Second
+
+// runBrowser runs a step that manages the browser.
+func (r *Runner) runBrowser(ctx context.Context, step Step, scope *vars.Set) error {
+	if r.Browser == nil {
+		return errors.New("browser steps need a browser's DevTools endpoint; set AGENTGO_CDP or use -driver cdp://host:port")
+	}
+	url, err := scope.Expand(step.URL)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(time.Duration(step.Timeout), browserTimeout))
+	defer cancel()
+	switch step.Action {
+	case ActionLaunchBrowser:
+		profile, err := scope.Expand(step.Profile)
+		if err != nil {
+			return err
+		}
+		r.logf("launching browser")
+		return r.Browser.Launch(ctx, profile, url)
+	case ActionOpenURL:
+		r.logf("opening %s", url)
+		if step.NewTab {
+			return r.Browser.NewTab(ctx, url)
+		}
+		return r.Browser.Navigate(ctx, url)
+	case ActionSwitchTab:
+		tab, err := scope.Expand(step.Tab)
+		if err != nil {
+			return err
+		}
+		return r.Browser.SwitchTab(ctx, tab)
+	default:
+		return r.Browser.WaitLoad(ctx)
+	}
+}
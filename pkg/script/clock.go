@@ -0,0 +1,111 @@
+package script
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits. Runs use the system clock unless given
+// another, such as a VirtualClock in tests of replay timing.
+type Clock interface {
+	Now() time.Time
+	// Sleep waits for d, returning early with ctx's error if it is done.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock is the real clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+func (SystemClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// VirtualClock is a Clock whose time moves only when it is slept on, and
+// then at once, so that a run with its waits replays instantly and the same
+// way every time. It is safe for concurrent use.
+type VirtualClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	start time.Time
+}
+
+// NewVirtualClock returns a virtual clock reading start.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start, start: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep moves the clock on by d, unless ctx is done.
+func (c *VirtualClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Advance(d)
+	return nil
+}
+
+// Advance moves the clock on by d, if it is positive.
+func (c *VirtualClock) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Elapsed returns how far the clock has moved since it was created.
+func (c *VirtualClock) Elapsed() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now.Sub(c.start)
+}
+
+type clockKey struct{}
+
+// WithClock returns a context whose waits, in this package and in
+// playback, are timed by c. A nil c leaves ctx as it is.
+func WithClock(ctx context.Context, c Clock) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// ClockFrom returns the clock set on ctx by WithClock, or the system clock.
+func ClockFrom(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return c
+	}
+	return SystemClock{}
+}
+
+// NopExecutor is an Executor that does nothing, for replaying scripts and
+// recordings without a desktop, e.g. to test their timing and branching
+// with a VirtualClock. It can also press and release buttons.
+type NopExecutor struct{}
+
+func (NopExecutor) Move(normX, normY float64) error { return nil }
+func (NopExecutor) Click(button string) error       { return nil }
+func (NopExecutor) Type(text string) error          { return nil }
+func (NopExecutor) KeyTap(key string) error         { return nil }
+func (NopExecutor) MouseDown(button string) error   { return nil }
+func (NopExecutor) MouseUp(button string) error     { return nil }
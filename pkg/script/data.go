@@ -0,0 +1,133 @@
+package script
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Row is one record of a data file, keyed by column name.
+type Row map[string]string
+
+// LoadRows reads the rows of a CSV (with a header row), JSON (an array of
+// objects), or JSONL (one object per line) data file.
+func LoadRows(path string) ([]Row, error) {
+	_, rows, err := LoadTable(path)
+	return rows, err
+}
+
+// LoadTable is like LoadRows but also returns the column names in file
+// order: the CSV header, or the keys of the first JSON object.
+func LoadTable(path string) ([]string, []Row, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read data file: %w", err)
+	}
+
+	var objects []json.RawMessage
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return parseCSVRows(data)
+	case ".json":
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse data file %s: %w", path, err)
+		}
+	case ".jsonl", ".ndjson":
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) > 0 {
+				objects = append(objects, append(json.RawMessage(nil), line...))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to read data file %s: %w", path, err)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported data file type %q (want .csv, .json, or .jsonl)", filepath.Ext(path))
+	}
+
+	rows := make([]Row, len(objects))
+	for i, raw := range objects {
+		var obj map[string]any
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, nil, fmt.Errorf("%s: failed to parse row %d: %w", path, i+1, err)
+		}
+		rows[i] = rowFromObject(obj)
+	}
+	var columns []string
+	if len(objects) > 0 {
+		columns = objectKeys(objects[0])
+	}
+	return columns, rows, nil
+}
+
+func parseCSVRows(data []byte) ([]string, []Row, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read csv records: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+
+	header := records[0]
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	rows := make([]Row, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(Row, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				row[name] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+// objectKeys returns the top-level keys of a JSON object in document order.
+func objectKeys(raw json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return keys
+		}
+		key, _ := tok.(string)
+		keys = append(keys, key)
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
+
+func rowFromObject(obj map[string]any) Row {
+	row := make(Row, len(obj))
+	for key, value := range obj {
+		switch v := value.(type) {
+		case string:
+			row[key] = v
+		case nil:
+			row[key] = ""
+		default:
+			encoded, _ := json.Marshal(v)
+			row[key] = string(encoded)
+		}
+	}
+	return row
+}
@@ -0,0 +1,180 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"agentGo/pkg/vars"
+)
+
+// ButtonPresser is implemented by executors that can hold a mouse button
+// down, which dragging and long presses need.
+type ButtonPresser interface {
+	MouseDown(button string) error
+	MouseUp(button string) error
+}
+
+// Dragger is implemented by executors that drag in one operation instead,
+// such as touch devices.
+type Dragger interface {
+	Drag(fromX, fromY, toX, toY float64, duration time.Duration) error
+}
+
+// Location is an end of a drag: x and y, a target for the vision model to
+// find, or a named anchor on the screen.
+type Location struct {
+	X *float64 `json:"x,omitempty"`
+	Y *float64 `json:"y,omitempty"`
+	// Target describes the element, e.g. "the report.pdf icon".
+	Target string `json:"target,omitempty"`
+	// Anchor is a point of the screen: center, top, bottom, left, right,
+	// top-left, top-right, bottom-left or bottom-right.
+	Anchor string `json:"anchor,omitempty"`
+}
+
+// anchors are the named points of the screen, a margin in from its edges.
+var anchors = map[string][2]float64{
+	"center":       {0.5, 0.5},
+	"top":          {0.5, 0.05},
+	"bottom":       {0.5, 0.95},
+	"left":         {0.05, 0.5},
+	"right":        {0.95, 0.5},
+	"top-left":     {0.05, 0.05},
+	"top-right":    {0.95, 0.05},
+	"bottom-left":  {0.05, 0.95},
+	"bottom-right": {0.95, 0.95},
+}
+
+func (l *Location) validate(where string) error {
+	if l == nil {
+		return fmt.Errorf("%s is required", where)
+	}
+	n := 0
+	if l.X != nil || l.Y != nil {
+		if l.X == nil || l.Y == nil {
+			return fmt.Errorf("%s requires both x and y", where)
+		}
+		n++
+	}
+	if l.Target != "" {
+		n++
+	}
+	if l.Anchor != "" {
+		if _, ok := anchors[strings.ToLower(l.Anchor)]; !ok {
+			return fmt.Errorf("%s: unknown anchor %q", where, l.Anchor)
+		}
+		n++
+	}
+	if n != 1 {
+		return fmt.Errorf("%s requires one of x and y, target or anchor", where)
+	}
+	return nil
+}
+
+// DragOptions says how a drag moves.
+type DragOptions struct {
+	// Button is the mouse button held; empty means left.
+	Button string
+	// Duration is how long the pointer takes from source to destination;
+	// 0 means 500ms. Slower drags suit targets that react to hovering.
+	Duration time.Duration
+	// Hold is how long the button is held still after pressing and before
+	// releasing, for applications that need a moment to start or accept a
+	// drag; 0 means 200ms.
+	Hold time.Duration
+}
+
+// dragFrame is the pause between pointer moves during a drag.
+const dragFrame = 16 * time.Millisecond
+
+// Drag presses the button at the source, moves to the destination in small
+// steps and releases it there. Coordinates are normalized to 0-1.
+func Drag(ctx context.Context, exec Executor, fromX, fromY, toX, toY float64, opts DragOptions) error {
+	if opts.Button == "" {
+		opts.Button = "left"
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = 500 * time.Millisecond
+	}
+	if opts.Hold <= 0 {
+		opts.Hold = 200 * time.Millisecond
+	}
+	p, ok := exec.(ButtonPresser)
+	if !ok {
+		if d, ok := exec.(Dragger); ok {
+			return d.Drag(fromX, fromY, toX, toY, opts.Duration+2*opts.Hold)
+		}
+		return errors.New("this driver cannot drag")
+	}
+
+	if err := exec.Move(fromX, fromY); err != nil {
+		return err
+	}
+	if err := p.MouseDown(opts.Button); err != nil {
+		return err
+	}
+	err := dragMove(ctx, exec, fromX, fromY, toX, toY, opts)
+	// Release even if the move failed, so the button is not left down.
+	if upErr := p.MouseUp(opts.Button); err == nil {
+		err = upErr
+	}
+	return err
+}
+
+func dragMove(ctx context.Context, exec Executor, fromX, fromY, toX, toY float64, opts DragOptions) error {
+	if err := sleep(ctx, opts.Hold); err != nil {
+		return err
+	}
+	steps := max(int(opts.Duration/dragFrame), 1)
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		if err := exec.Move(fromX+(toX-fromX)*t, fromY+(toY-fromY)*t); err != nil {
+			return err
+		}
+		if err := sleep(ctx, opts.Duration/time.Duration(steps)); err != nil {
+			return err
+		}
+	}
+	return sleep(ctx, opts.Hold)
+}
+
+// sleep waits for d on ctx's clock.
+func sleep(ctx context.Context, d time.Duration) error {
+	return ClockFrom(ctx).Sleep(ctx, d)
+}
+
+// drag runs a "drag_from_to" step.
+func (r *Runner) drag(ctx context.Context, step Step, scope *vars.Set) error {
+	fromX, fromY, err := r.resolve(ctx, *step.From, scope)
+	if err != nil {
+		return fmt.Errorf("drag source: %w", err)
+	}
+	toX, toY, err := r.resolve(ctx, *step.To, scope)
+	if err != nil {
+		return fmt.Errorf("drag destination: %w", err)
+	}
+	return Drag(ctx, r.Exec, fromX, fromY, toX, toY, DragOptions{
+		Button:   step.Button,
+		Duration: time.Duration(step.Duration),
+		Hold:     time.Duration(step.Hold),
+	})
+}
+
+// resolve returns the screen position of l, in 0-1 coordinates.
+func (r *Runner) resolve(ctx context.Context, l Location, scope *vars.Set) (float64, float64, error) {
+	switch {
+	case l.Anchor != "":
+		a := anchors[strings.ToLower(l.Anchor)]
+		return a[0], a[1], nil
+	case l.Target == "":
+		return *l.X, *l.Y, nil
+	}
+	target, err := scope.Expand(l.Target)
+	if err != nil {
+		return 0, 0, err
+	}
+	return r.locate(ctx, target)
+}
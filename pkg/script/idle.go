@@ -0,0 +1,99 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"time"
+
+	"agentGo/pkg/frame"
+	"agentGo/pkg/vision"
+)
+
+// CursorShaper is implemented by executors that can tell the shape of the
+// mouse pointer, which shows "busy" or "progress" while an application
+// loads.
+type CursorShaper interface {
+	CursorShape() (string, error)
+}
+
+// idlePoll is the pause between looks at the screen while waiting for it to
+// settle.
+const idlePoll = 250 * time.Millisecond
+
+// idleChange is the share of the screen that may change between looks
+// without counting as activity, enough to ignore a blinking caret.
+const idleChange = 0.0005
+
+// loadingIndicator is what the vision model is asked about once the screen
+// is still, to catch loading states that do not animate.
+const loadingIndicator = "a loading spinner, an unfinished progress bar, or a message saying something is loading or in progress"
+
+// waitUntilIdle waits until the screen has stopped changing for the step's
+// settle time, the pointer is not busy and, with a vision model, no loading
+// indicator is shown.
+func (r *Runner) waitUntilIdle(ctx context.Context, step Step) error {
+	timeout, settle := time.Duration(step.Timeout), time.Duration(step.Settle)
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	if settle == 0 {
+		settle = time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	clock := ClockFrom(ctx)
+	deadline := clock.Now().Add(timeout)
+
+	var last image.Image
+	var stillSince time.Time
+	reason := "the screen kept changing"
+	for {
+		img, err := r.capture()
+		if err != nil {
+			return err
+		}
+		now := clock.Now()
+		if last == nil || screenChanged(last, img) {
+			stillSince = now
+			reason = "the screen kept changing"
+		}
+		if c, ok := r.Exec.(CursorShaper); ok {
+			if shape, err := c.CursorShape(); err == nil && (shape == "busy" || shape == "progress") {
+				stillSince = now
+				reason = "the pointer stayed busy"
+			}
+		}
+		// The previous frame is done with; let the next capture reuse it.
+		frame.Put(last)
+		last = img
+
+		if now.Sub(stillSince) >= settle {
+			if r.Vision == nil {
+				return nil
+			}
+			loading, err := r.Vision.Visible(ctx, img, loadingIndicator)
+			if err == nil && !loading {
+				return nil
+			}
+			if err == nil {
+				reason = "a loading indicator stayed on screen"
+			}
+			// Look again after another settle period.
+			stillSince = now
+		}
+
+		err = sleep(ctx, idlePoll)
+		if err == context.DeadlineExceeded || err == nil && !clock.Now().Before(deadline) {
+			return fmt.Errorf("not idle after %v: %s", timeout, reason)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func screenChanged(a, b image.Image) bool {
+	d, err := vision.CompareImages(a, b, 24)
+	return err != nil || d.Fraction > idleChange
+}
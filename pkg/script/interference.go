@@ -0,0 +1,112 @@
+package script
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrInterference is returned when someone else uses the mouse or keyboard
+// during a run and the run is not resumed.
+var ErrInterference = errors.New("interrupted by manual input")
+
+// InputWatcher is implemented by executors that can tell when a person at
+// the machine uses the mouse or keyboard, as desktop.Local does.
+type InputWatcher interface {
+	// Interference describes input that did not come from the executor
+	// since the last call, or returns "".
+	Interference() string
+}
+
+// InterferenceHandler decides what to do when someone else takes the mouse
+// or keyboard, e.g. by asking them. It blocks while the run is paused and
+// returns nil to resume it or an error to abort it.
+type InterferenceHandler func(ctx context.Context, what string) error
+
+// watchInterval is how often waits check for manual input.
+const watchInterval = 50 * time.Millisecond
+
+// resumeGrace lets the person let go of the keys that resumed a run before
+// input is watched again.
+const resumeGrace = 500 * time.Millisecond
+
+// CheckInterference asks exec, if it is an InputWatcher, whether someone else
+// has used the mouse or keyboard. If so the run pauses in handle; with no
+// handler it fails with ErrInterference. Input during the pause is ignored.
+func CheckInterference(ctx context.Context, exec Executor, handle InterferenceHandler) error {
+	w, ok := exec.(InputWatcher)
+	if !ok {
+		return nil
+	}
+	what := w.Interference()
+	if what == "" {
+		return nil
+	}
+	if handle == nil {
+		return fmt.Errorf("%w: %s", ErrInterference, what)
+	}
+	if err := handle(ctx, what); err != nil {
+		return err
+	}
+	if err := sleep(ctx, resumeGrace); err != nil {
+		return err
+	}
+	w.Interference()
+	return nil
+}
+
+// WatchedSleep waits for d, checking for manual input as it goes.
+func WatchedSleep(ctx context.Context, d time.Duration, exec Executor, handle InterferenceHandler) error {
+	if _, ok := exec.(InputWatcher); !ok {
+		return sleep(ctx, d)
+	}
+	clock := ClockFrom(ctx)
+	deadline := clock.Now().Add(d)
+	for {
+		if err := CheckInterference(ctx, exec, handle); err != nil {
+			return err
+		}
+		left := deadline.Sub(clock.Now())
+		if left <= 0 {
+			return nil
+		}
+		if err := sleep(ctx, min(left, watchInterval)); err != nil {
+			return err
+		}
+	}
+}
+
+// AskToResume returns a handler that pauses the run and asks on out whether
+// to resume: a line reading "abort", or the end of in, aborts it and any
+// other line, such as an empty one, resumes it.
+func AskToResume(in io.Reader, out io.Writer) InterferenceHandler {
+	lines := make(chan string)
+	var scanner *bufio.Scanner
+	return func(ctx context.Context, what string) error {
+		fmt.Fprintf(out, "Paused: %s. Press Enter to resume or type abort: ", what)
+		if scanner == nil {
+			// One reader for the whole run, so no answer is lost to a
+			// read left over from an earlier pause.
+			scanner = bufio.NewScanner(in)
+			go func() {
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+				close(lines)
+			}()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok || strings.EqualFold(strings.TrimSpace(line), "abort") {
+				return fmt.Errorf("%w: %s", ErrInterference, what)
+			}
+			return nil
+		}
+	}
+}
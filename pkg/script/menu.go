@@ -0,0 +1,126 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"agentGo/pkg/vars"
+	"agentGo/pkg/vision"
+)
+
+// Locator finds elements on screenshots, as vision.Client does.
+type Locator interface {
+	Locate(ctx context.Context, img image.Image, target string) (vision.Point, error)
+}
+
+// menuTimeout bounds how long to wait for a menu or submenu to open.
+const menuTimeout = 2 * time.Second
+
+// SplitMenuPath splits a menu path such as "Sort by > Name" into its items.
+func SplitMenuPath(path string) []string {
+	var items []string
+	for _, item := range strings.Split(path, ">") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ContextMenu right-clicks at x, y (0-1 fractions of the screen) and picks
+// the item at path from the menu that opens, hovering over each submenu on
+// the way, e.g. []string{"Sort by", "Name"}.
+func ContextMenu(ctx context.Context, exec Executor, loc Locator, capture func() (image.Image, error), x, y float64, path []string) error {
+	if len(path) == 0 {
+		return errors.New("no menu item to pick")
+	}
+	before, err := capture()
+	if err != nil {
+		return err
+	}
+	if err := exec.Move(x, y); err != nil {
+		return err
+	}
+	if err := exec.Click("right"); err != nil {
+		return err
+	}
+	for i, item := range path {
+		img, err := waitForChange(ctx, capture, before)
+		if err != nil {
+			return fmt.Errorf("waiting for the menu with %q: %w", item, err)
+		}
+		target := fmt.Sprintf("the %q item in the open context menu", item)
+		if i > 0 {
+			target = fmt.Sprintf("the %q item in the submenu that opened from %q", item, path[i-1])
+		}
+		p, err := loc.Locate(ctx, img, target)
+		if err != nil {
+			return fmt.Errorf("failed to find menu item %q: %w", item, err)
+		}
+		ix, iy := p.Normalize(img.Bounds())
+		if err := exec.Move(ix, iy); err != nil {
+			return err
+		}
+		if i < len(path)-1 {
+			// Hovering opens the submenu; clicking could pick the item.
+			before = img
+			continue
+		}
+		if err := exec.Click("left"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForChange captures the screen until it differs from before, as when a
+// menu opens, and returns the new screenshot. If nothing changes in time it
+// returns the last one, since the menu may have opened over the same pixels.
+func waitForChange(ctx context.Context, capture func() (image.Image, error), before image.Image) (image.Image, error) {
+	clock := ClockFrom(ctx)
+	deadline := clock.Now().Add(menuTimeout)
+	for {
+		if err := sleep(ctx, 100*time.Millisecond); err != nil {
+			return nil, err
+		}
+		img, err := capture()
+		if err != nil {
+			return nil, err
+		}
+		if d, err := vision.CompareImages(before, img, 24); err != nil || d.Fraction > 0.001 || clock.Now().After(deadline) {
+			// Let the menu finish animating open.
+			if err := sleep(ctx, 150*time.Millisecond); err != nil {
+				return nil, err
+			}
+			return capture()
+		}
+	}
+}
+
+// contextMenu runs a "context_menu" step.
+func (r *Runner) contextMenu(ctx context.Context, step Step, scope *vars.Set) error {
+	path, err := scope.Expand(step.Item)
+	if err != nil {
+		return err
+	}
+	if r.Vision == nil {
+		return fmt.Errorf("finding menu item %s needs a vision model; set GEMINI_API_KEY", path)
+	}
+	x, y := 0.0, 0.0
+	if step.Target != "" {
+		target, err := scope.Expand(step.Target)
+		if err != nil {
+			return err
+		}
+		if x, y, err = r.locate(ctx, target); err != nil {
+			return err
+		}
+	} else {
+		x, y = *step.X, *step.Y
+	}
+	return ContextMenu(ctx, r.Exec, r.Vision, r.capture, x, y, SplitMenuPath(path))
+}
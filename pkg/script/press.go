@@ -0,0 +1,49 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// clickGap is the pause between the clicks of a double or triple click,
+// well within any system's double-click time.
+const clickGap = 40 * time.Millisecond
+
+// Press clicks button clicks times in quick succession, holding it down for
+// hold on the last press if hold is positive: a press and hold, which
+// drivers without ButtonPresser cannot do.
+func Press(ctx context.Context, exec Executor, button string, clicks int, hold time.Duration) error {
+	if button == "" {
+		button = "left"
+	}
+	clicks = max(clicks, 1)
+	p, ok := exec.(ButtonPresser)
+	if hold > 0 && !ok {
+		return errors.New("this driver cannot hold a button down")
+	}
+	for i := 1; i <= clicks; i++ {
+		if i > 1 {
+			if err := sleep(ctx, clickGap); err != nil {
+				return err
+			}
+		}
+		if i < clicks || hold <= 0 {
+			if err := exec.Click(button); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.MouseDown(button); err != nil {
+			return err
+		}
+		err := sleep(ctx, hold)
+		if upErr := p.MouseUp(button); err == nil {
+			err = upErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,384 @@
+package script
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"agentGo/pkg/budget"
+	"agentGo/pkg/find"
+	"agentGo/pkg/humanize"
+	"agentGo/pkg/secure"
+	"agentGo/pkg/session"
+	"agentGo/pkg/vars"
+	"agentGo/pkg/vision"
+)
+
+// Executor performs the primitive actions a script asks for. Coordinates are
+// normalized to the 0-1 range so scripts replay on any screen size.
+type Executor interface {
+	Move(normX, normY float64) error
+	Click(button string) error
+	Type(text string) error
+	KeyTap(key string) error
+}
+
+// Runner executes scripts against an Executor.
+type Runner struct {
+	Exec Executor
+	// Vars supplies variable values; script defaults are applied beneath it.
+	Vars *vars.Set
+	// Logf receives progress messages. It defaults to log.Printf.
+	Logf func(format string, args ...any)
+	// OnStep, if set, is called before each step runs with its position in
+	// the script, e.g. "steps[2].row1[0]".
+	OnStep func(where string, step Step)
+	// BeforeStep, if set, runs before each step, e.g. to clear popups; an
+	// error fails the step.
+	BeforeStep func(ctx context.Context, where string, step Step) error
+	// Vision finds the targets of moves and clicks and reads text off the
+	// screen for assert_text and assert_number. Scripts without such steps
+	// do not need it.
+	Vision Inspector
+	// ArtifactDir, if set, receives a screenshot and description of every
+	// failed assertion.
+	ArtifactDir string
+	// Human, if set, varies waits and sometimes pauses before a step, as a
+	// person would.
+	Human *humanize.Behavior
+	// OnInterference, if set, pauses the run when someone else uses the
+	// mouse or keyboard of an executor that can tell (see InputWatcher).
+	// Without it such input fails the run with ErrInterference.
+	OnInterference InterferenceHandler
+	// Session, if set, records the run for review: the screen before each
+	// step and at the end, if the executor is a Capturer, and the moves,
+	// clicks, typing and model answers in between. Typing is recorded as
+	// written in the script, before variables are expanded, so values such
+	// as passwords stay out of the recording.
+	Session *session.Writer
+	// Clock, if set, times the run's waits instead of the system clock, as
+	// a VirtualClock does to replay a script instantly in tests.
+	Clock Clock
+	// Browser runs the launch_browser, open_url, switch_tab and
+	// wait_for_page_load steps. Scripts without such steps do not need it.
+	Browser Browser
+	// Resume, if set, is where in the script the last step completed by an
+	// earlier run is, e.g. "steps[2].row4[1]"; it and the steps before it
+	// are skipped.
+	Resume string
+	// AfterStep, if set, is called after each step completes, with its
+	// position in the script, e.g. to checkpoint the run.
+	AfterStep func(where string, step Step)
+	// OnDone, if set, is called after each step has run or failed, with
+	// its position in the script and its error, e.g. to audit the run.
+	OnDone func(where string, step Step, err error)
+	// Budget, if set, counts the steps against the run's budget, and
+	// stops the run before it takes one too many.
+	Budget *budget.Tracker
+	// Secure, if set, recognizes password fields: what is typed into them
+	// is recorded as secure.Placeholder, and screenshots, whether recorded
+	// or shown to the model, have them blacked out.
+	Secure *secure.Detector
+	// Scrub, if set, removes secrets from the reports of failed
+	// assertions, which quote what was read off the screen and the
+	// clipboard.
+	Scrub func(string) string
+}
+
+// Run executes every step of s in order, stopping at the first error.
+func (r *Runner) Run(ctx context.Context, s *Script) error {
+	ctx = WithClock(ctx, r.Clock)
+	langs, _ := languages(s.Languages)
+	ctx = vision.WithLanguages(ctx, langs)
+	scope := vars.New()
+	if r.Vars != nil && r.Vars.Isolated() {
+		scope = vars.NewIsolated()
+	}
+	for name, value := range s.Vars {
+		scope.Set(name, value)
+	}
+	if r.Vars != nil {
+		for _, name := range r.Vars.Names() {
+			value, _ := r.Vars.Get(name)
+			scope.Set(name, value)
+		}
+	}
+	err := r.runSteps(ctx, s, s.Steps, scope, "steps")
+	r.snapshot()
+	if err != nil {
+		r.Session.Error(err.Error())
+	}
+	return err
+}
+
+func (r *Runner) runSteps(ctx context.Context, s *Script, steps []Step, scope *vars.Set, path string) error {
+	for i, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		where := fmt.Sprintf("%s[%d]", path, i)
+		if r.Resume != "" && Completed(where, r.Resume) {
+			continue
+		}
+		if step.Action == ActionForEach {
+			// A foreach only runs its body, whose steps are budgeted, paused
+			// before, checked and reported one by one.
+			if r.OnStep != nil {
+				r.OnStep(where, step)
+			}
+			if err := r.runStep(ctx, s, step, scope, where); err != nil {
+				return fmt.Errorf("%s (%s): %w", where, step.Action, err)
+			}
+			continue
+		}
+		if err := r.Budget.Step(); err != nil {
+			return fmt.Errorf("%s (%s): %w", where, step.Action, err)
+		}
+		if r.OnStep != nil {
+			r.OnStep(where, step)
+		}
+		if pause := r.Human.Pause(); pause > 0 {
+			r.logf("pausing %v before %s", pause.Round(time.Millisecond), where)
+			if err := sleep(ctx, pause); err != nil {
+				return err
+			}
+		}
+		if err := CheckInterference(ctx, r.Exec, r.OnInterference); err != nil {
+			return fmt.Errorf("%s (%s): %w", where, step.Action, err)
+		}
+		r.snapshot()
+		r.Session.Note(fmt.Sprintf("%s: %s", where, step.Action))
+		var err error
+		if r.BeforeStep != nil {
+			err = r.BeforeStep(ctx, where, step)
+		}
+		if err == nil {
+			err = r.runStep(ctx, s, step, scope, where)
+		}
+		if r.OnDone != nil {
+			r.OnDone(where, step, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s (%s): %w", where, step.Action, err)
+		}
+		if r.AfterStep != nil {
+			r.AfterStep(where, step)
+		}
+	}
+	return nil
+}
+
+// Completed reports whether the step at where ran before the step at last,
+// or is it, where both are positions such as "steps[2].row4[1]". A
+// foreach that last is inside has not completed.
+func Completed(where, last string) bool {
+	w, l := position(where), position(last)
+	for i := range w {
+		if i == len(l) {
+			return false
+		}
+		if w[i] != l[i] {
+			return w[i] < l[i]
+		}
+	}
+	return len(w) == len(l)
+}
+
+// position returns the numbers in a step's position, e.g. 2, 4 and 1 for
+// "steps[2].row4[1]", which order steps as they run.
+func position(where string) []int {
+	var nums []int
+	n, in := 0, false
+	for _, c := range where {
+		if c >= '0' && c <= '9' {
+			n, in = n*10+int(c-'0'), true
+			continue
+		}
+		if in {
+			nums = append(nums, n)
+		}
+		n, in = 0, false
+	}
+	if in {
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+func (r *Runner) runStep(ctx context.Context, s *Script, step Step, scope *vars.Set, where string) error {
+	langs, _ := languages(step.Languages)
+	ctx = vision.WithLanguages(ctx, langs)
+	switch step.Action {
+	case ActionMove:
+		return r.moveTo(ctx, step, scope)
+	case ActionClick:
+		if step.X != nil || step.Target != "" {
+			if err := r.moveTo(ctx, step, scope); err != nil {
+				return err
+			}
+		}
+		if err := Press(ctx, r.Exec, step.Button, step.Clicks, time.Duration(step.Hold)); err != nil {
+			return err
+		}
+		r.Session.Click(cmp.Or(step.Button, "left"), step.Clicks)
+		r.Secure.Clicked(step.Target)
+		return nil
+	case ActionType:
+		text, err := scope.Expand(step.Text)
+		if err != nil {
+			return err
+		}
+		if r.Session != nil {
+			r.Session.Type(r.Secure.Text(step.Text))
+		}
+		return r.Exec.Type(text)
+	case ActionKey:
+		key, err := scope.Expand(step.Key)
+		if err != nil {
+			return err
+		}
+		r.Session.Key(key)
+		return r.Exec.KeyTap(key)
+	case ActionWait:
+		return WatchedSleep(ctx, r.Human.Delay(time.Duration(step.Duration)), r.Exec, r.OnInterference)
+	case ActionWaitUntilIdle:
+		return r.waitUntilIdle(ctx, step)
+	case ActionDragFromTo:
+		return r.drag(ctx, step, scope)
+	case ActionContextMenu:
+		return r.contextMenu(ctx, step, scope)
+	case ActionForEach:
+		return r.runForEach(ctx, s, step, scope, where)
+	case ActionAssertText, ActionAssertNumber, ActionAssertImage, ActionAssertWindowTitle, ActionAssertClipboard:
+		return r.runAssert(ctx, s, step, scope, where)
+	case ActionLaunchBrowser, ActionOpenURL, ActionSwitchTab, ActionWaitForPageLoad:
+		return r.runBrowser(ctx, step, scope)
+	case ActionLaunchApp, ActionActivateWindow, ActionCloseWindow, ActionPlaceWindow, ActionMaximizeWindow:
+		return r.runApp(ctx, step, scope)
+	default:
+		return fmt.Errorf("unknown action %q", step.Action)
+	}
+}
+
+// moveTo moves to the step's target, found by the vision model and
+// scrolled into view if the step allows, or to its coordinates.
+func (r *Runner) moveTo(ctx context.Context, step Step, scope *vars.Set) error {
+	if step.Target == "" || (r.Vision == nil && step.X != nil) {
+		return r.move(*step.X, *step.Y)
+	}
+	target, err := scope.Expand(step.Target)
+	if err != nil {
+		return err
+	}
+	if r.Vision == nil {
+		return fmt.Errorf("finding %s needs a vision model; set GEMINI_API_KEY", target)
+	}
+	if step.Scroll > 0 {
+		f := find.Finder{
+			Vision:    r.Vision,
+			Scroller:  r.Exec,
+			Capture:   r.capture,
+			Budget:    step.Scroll,
+			Container: step.ScrollIn,
+			Logf:      r.logf,
+		}
+		x, y, err := f.Find(ctx, target)
+		if err != nil {
+			return err
+		}
+		r.Session.Predict(x, y, target)
+		return r.move(x, y)
+	}
+	x, y, err := r.locate(ctx, target)
+	if err != nil {
+		return err
+	}
+	r.Session.Predict(x, y, target)
+	return r.move(x, y)
+}
+
+// move moves the pointer, recording the move in the session.
+func (r *Runner) move(x, y float64) error {
+	if err := r.Exec.Move(x, y); err != nil {
+		return err
+	}
+	r.Session.Move(x, y)
+	return nil
+}
+
+// snapshot records the screen in the session, if there is one and the
+// executor can capture it.
+func (r *Runner) snapshot() {
+	if r.Session == nil {
+		return
+	}
+	if _, ok := r.Exec.(Capturer); !ok {
+		return
+	}
+	img, err := r.capture()
+	if err != nil {
+		r.logf("session: %v", err)
+		return
+	}
+	r.Session.Frame(img)
+}
+
+// locate finds target on the screen with the vision model, in 0-1
+// coordinates.
+func (r *Runner) locate(ctx context.Context, target string) (float64, float64, error) {
+	if r.Vision == nil {
+		return 0, 0, fmt.Errorf("finding %s needs a vision model; set GEMINI_API_KEY", target)
+	}
+	img, err := r.capture()
+	if err != nil {
+		return 0, 0, err
+	}
+	p, err := r.Vision.Locate(ctx, img, target)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find %s: %w", target, err)
+	}
+	x, y := p.Normalize(img.Bounds())
+	return x, y, nil
+}
+
+// runForEach runs the step's block once per data row, binding each column to
+// a variable of the same name and ROW to the 1-based row number.
+func (r *Runner) runForEach(ctx context.Context, s *Script, step Step, scope *vars.Set, where string) error {
+	dataPath, err := scope.Expand(step.Data)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(dataPath) && s.dir != "" {
+		dataPath = filepath.Join(s.dir, dataPath)
+	}
+	rows, err := LoadRows(dataPath)
+	if err != nil {
+		return err
+	}
+
+	r.logf("foreach: %d rows from %s", len(rows), dataPath)
+	for i, row := range rows {
+		rowScope := scope.Child()
+		for column, value := range row {
+			rowScope.Set(column, value)
+		}
+		rowScope.Set("ROW", strconv.Itoa(i+1))
+
+		if err := r.runSteps(ctx, s, step.Steps, rowScope, fmt.Sprintf("%s.row%d", where, i+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
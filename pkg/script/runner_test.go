@@ -0,0 +1,199 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"agentGo/pkg/vision"
+)
+
+// testExec is an executor whose screen is still unless changing is set, and
+// which reports each entry of input as manual input, one per look.
+type testExec struct {
+	NopExecutor
+	changing bool
+	captures int
+	input    []string
+	clicks   []string
+}
+
+func (e *testExec) Click(button string) error {
+	e.clicks = append(e.clicks, button)
+	return nil
+}
+
+func (e *testExec) Capture() (image.Image, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	if e.changing {
+		shade := uint8(e.captures * 40)
+		for i := range img.Pix {
+			img.Pix[i] = shade
+		}
+	}
+	e.captures++
+	return img, nil
+}
+
+func (e *testExec) Interference() string {
+	if len(e.input) == 0 {
+		return ""
+	}
+	what := e.input[0]
+	e.input = e.input[1:]
+	return what
+}
+
+// testVision answers each question about a loading indicator with the
+// next entry of loading, and false once they run out.
+type testVision struct {
+	loading []bool
+	asked   int
+}
+
+func (v *testVision) Locate(ctx context.Context, img image.Image, target string) (vision.Point, error) {
+	return vision.Point{}, errors.New("not on screen")
+}
+
+func (v *testVision) ReadText(ctx context.Context, img image.Image, target string) (string, error) {
+	return "", errors.New("no text")
+}
+
+func (v *testVision) Visible(ctx context.Context, img image.Image, target string) (bool, error) {
+	v.asked++
+	if len(v.loading) == 0 {
+		return false, nil
+	}
+	loading := v.loading[0]
+	v.loading = v.loading[1:]
+	return loading, nil
+}
+
+func TestRunnerWaits(t *testing.T) {
+	resume := func(ctx context.Context, what string) error { return nil }
+	tests := []struct {
+		name    string
+		script  string
+		exec    *testExec
+		vision  *testVision
+		handler InterferenceHandler
+		elapsed time.Duration
+		err     string
+		clicks  int
+		asked   int
+	}{
+		{
+			name:    "wait",
+			script:  `{"steps": [{"action": "wait", "duration": "1.5s"}, {"action": "wait", "duration": 250}]}`,
+			elapsed: 1750 * time.Millisecond,
+		},
+		{
+			name:    "double click and hold",
+			script:  `{"steps": [{"action": "click", "x": 0.5, "y": 0.5, "clicks": 2, "hold": "200ms"}]}`,
+			elapsed: clickGap + 200*time.Millisecond,
+			clicks:  1,
+		},
+		{
+			name:    "idle screen settles",
+			script:  `{"steps": [{"action": "wait_until_idle", "settle": "1s"}]}`,
+			elapsed: time.Second,
+		},
+		{
+			name:    "busy screen times out",
+			script:  `{"steps": [{"action": "wait_until_idle", "timeout": "2s", "settle": "1s"}]}`,
+			exec:    &testExec{changing: true},
+			elapsed: 2 * time.Second,
+			err:     "not idle after 2s: the screen kept changing",
+		},
+		{
+			name:    "loading indicator is looked for again",
+			script:  `{"steps": [{"action": "wait_until_idle", "settle": "1s"}]}`,
+			vision:  &testVision{loading: []bool{true, true}},
+			elapsed: 3 * time.Second,
+			asked:   3,
+		},
+		{
+			name:    "loading indicator outlasts the timeout",
+			script:  `{"steps": [{"action": "wait_until_idle", "timeout": "2500ms", "settle": "1s"}]}`,
+			vision:  &testVision{loading: []bool{true, true, true}},
+			elapsed: 2500 * time.Millisecond,
+			err:     "a loading indicator stayed on screen",
+			asked:   2,
+		},
+		{
+			name:    "input during a wait resumes it",
+			script:  `{"steps": [{"action": "wait", "duration": "1s"}, {"action": "wait", "duration": "1s"}]}`,
+			exec:    &testExec{input: []string{"", "", "", "mouse moved"}},
+			handler: resume,
+			elapsed: 2 * time.Second,
+		},
+		{
+			name:    "input before a step resumes after a grace period",
+			script:  `{"steps": [{"action": "wait", "duration": "1s"}]}`,
+			exec:    &testExec{input: []string{"key pressed"}},
+			handler: resume,
+			elapsed: resumeGrace + time.Second,
+		},
+		{
+			name:   "input without a handler fails the run",
+			script: `{"steps": [{"action": "wait", "duration": "1s"}]}`,
+			exec:   &testExec{input: []string{"", "", "mouse moved"}},
+			err:    ErrInterference.Error(),
+			// Two looks, 50ms apart, before the input is seen.
+			elapsed: watchInterval,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse([]byte(tt.script))
+			if err != nil {
+				t.Fatal(err)
+			}
+			exec := tt.exec
+			if exec == nil {
+				exec = &testExec{}
+			}
+			clock := NewVirtualClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+			r := &Runner{Exec: exec, Clock: clock, OnInterference: tt.handler, Logf: t.Logf}
+			if tt.vision != nil {
+				r.Vision = tt.vision
+			}
+			err = r.Run(context.Background(), s)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("Run: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("Run returned %v, want an error containing %q", err, tt.err)
+			}
+			if got := clock.Elapsed(); got != tt.elapsed {
+				t.Errorf("run took %v of clock time, want %v", got, tt.elapsed)
+			}
+			if len(exec.clicks) != tt.clicks {
+				t.Errorf("clicked %d times, want %d", len(exec.clicks), tt.clicks)
+			}
+			if tt.vision != nil && tt.vision.asked != tt.asked {
+				t.Errorf("asked about a loading indicator %d times, want %d", tt.vision.asked, tt.asked)
+			}
+		})
+	}
+}
+
+func TestVirtualClockStopsWhenCancelled(t *testing.T) {
+	clock := NewVirtualClock(time.Time{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := &Runner{Exec: &testExec{}, Clock: clock}
+	s, err := Parse([]byte(`{"steps": [{"action": "wait", "duration": "1h"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(ctx, s); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run returned %v, want context.Canceled", err)
+	}
+	if got := clock.Elapsed(); got != 0 {
+		t.Errorf("clock moved %v after the run was cancelled", got)
+	}
+}
@@ -0,0 +1,468 @@
+// Package script defines JSON task scripts: ordered steps such as moving the
+// mouse, clicking, and typing, with ${VAR} substitution, data-driven
+// iteration and assertions that check the outcome.
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"agentGo/pkg/apps"
+	"agentGo/pkg/budget"
+	"agentGo/pkg/popup"
+	"agentGo/pkg/vision"
+)
+
+// Script is a parsed task script.
+type Script struct {
+	// Name is a human-readable label used in logs.
+	Name string `json:"name,omitempty"`
+	// Vars provides default variable values; command-line values override them.
+	Vars map[string]string `json:"vars,omitempty"`
+	// Steps are executed in order.
+	Steps []Step `json:"steps"`
+	// Popups says how to handle dialogs and prompts that appear unasked
+	// while the script runs; nil leaves them unchecked.
+	Popups *popup.Config `json:"popups,omitempty"`
+	// Budget caps what a run may spend on model calls and how many steps
+	// it may take; nil leaves them unlimited.
+	Budget *budget.Budget `json:"budget,omitempty"`
+	// Languages are the languages of the text on the screen, as codes such
+	// as "de" or "jpn" or as names, in which targets are found and text is
+	// read. They override those the vision client was configured with.
+	Languages []string `json:"languages,omitempty"`
+
+	// dir is the directory the script was loaded from, used to resolve
+	// relative data file paths.
+	dir string
+}
+
+// Step is a single script instruction. Which fields are used depends on
+// Action.
+type Step struct {
+	Action string `json:"action"`
+
+	// X and Y are normalized (0-1) screen coordinates for "move" and "click".
+	X *float64 `json:"x,omitempty"`
+	Y *float64 `json:"y,omitempty"`
+	// Button is the mouse button for "click" and "drag_from_to": left
+	// (default), right, or center.
+	Button string `json:"button,omitempty"`
+	// Clicks is how many times "click" clicks: 2 for a double click, 3 for
+	// a triple click; 0 means 1.
+	Clicks int `json:"clicks,omitempty"`
+	// Text is typed by "type".
+	Text string `json:"text,omitempty"`
+	// Key is pressed by "key", e.g. "enter" or "tab".
+	Key string `json:"key,omitempty"`
+	// Duration is how long "wait" pauses, and how long "drag_from_to" takes
+	// to move from source to destination (0 means 500ms).
+	Duration Duration `json:"duration,omitempty"`
+
+	// Data is the CSV, JSON, or JSONL file iterated by "foreach".
+	Data string `json:"data,omitempty"`
+	// Steps is the block run by "foreach" once per row.
+	Steps []Step `json:"steps,omitempty"`
+
+	// Target describes an element for the vision model to find: where
+	// "move" and "click" go, e.g. "the Login button", or whose text
+	// "assert_text" and "assert_number" read, e.g. "the order total". A move
+	// or click with both falls back to x and y when no model is available.
+	Target string `json:"target,omitempty"`
+	// Languages override the script's Languages for the targets and text
+	// of this step, e.g. ["ja"] for a dialog in Japanese.
+	Languages []string `json:"languages,omitempty"`
+	// Scroll is how many times a move or click may scroll to bring a
+	// Target that is not on screen into view; 0 means it does not scroll.
+	Scroll int `json:"scroll,omitempty"`
+	// ScrollIn describes the container to scroll, e.g. "the file list";
+	// empty lets the model pick the one the target should be in.
+	ScrollIn string `json:"scroll_in,omitempty"`
+	// Expect is what "assert_text", "assert_window_title" and
+	// "assert_clipboard" compare against.
+	Expect string `json:"expect,omitempty"`
+	// Match is how Expect is compared: equals (default), contains or regex.
+	Match string `json:"match,omitempty"`
+	// Image is the reference image "assert_image" looks for on the screen.
+	Image string `json:"image,omitempty"`
+	// Threshold is the similarity from 0 to 1 "assert_image" requires;
+	// 0 means 0.9.
+	Threshold float64 `json:"threshold,omitempty"`
+	// Timeout bounds how long "wait_until_idle" waits, how long the browser
+	// steps wait for the browser and page loads, and how long "launch_app"
+	// and the window steps other than "close_window" wait for the window;
+	// 0 means 30s.
+	Timeout Duration `json:"timeout,omitempty"`
+	// Settle is how long the screen must stay still for
+	// "wait_until_idle" to consider it idle; 0 means 1s.
+	Settle Duration `json:"settle,omitempty"`
+	// Op and Value are the comparison "assert_number" makes with the
+	// number it reads, e.g. ">=" and 100.
+	Op    string   `json:"op,omitempty"`
+	Value *float64 `json:"value,omitempty"`
+
+	// From and To are where "drag_from_to" presses and releases the button.
+	From *Location `json:"from,omitempty"`
+	To   *Location `json:"to,omitempty"`
+	// Item is the entry "context_menu" picks from the menu that opens when
+	// Target, or x and y, is right-clicked; "Sort by > Name" goes through a
+	// submenu.
+	Item string `json:"item,omitempty"`
+	// Hold is how long "click" holds the button down, for a press and hold
+	// (0 clicks as usual), and how long "drag_from_to" holds still after
+	// pressing and before releasing (0 means 200ms).
+	Hold Duration `json:"hold,omitempty"`
+
+	// URL is the address "open_url" loads, and "launch_browser" opens once
+	// the browser is up.
+	URL string `json:"url,omitempty"`
+	// NewTab makes "open_url" load URL in a new tab.
+	NewTab bool `json:"new_tab,omitempty"`
+	// Profile is the user data directory "launch_browser" starts the
+	// browser with; empty uses a scratch profile of agentGo's own.
+	Profile string `json:"profile,omitempty"`
+	// Tab is text of the title or URL of the tab "switch_tab" switches to.
+	Tab string `json:"tab,omitempty"`
+
+	// App is the application "launch_app" starts, and whose window
+	// "activate_window", "close_window", "place_window" and
+	// "maximize_window" act on: an executable's path or name, or on macOS
+	// a bundle id such as com.apple.TextEdit.
+	App string `json:"app,omitempty"`
+	// Args are the command-line arguments "launch_app" passes.
+	Args []string `json:"args,omitempty"`
+	// Window is a regular expression the title of the window
+	// "launch_app" and the window steps look for must match.
+	Window string `json:"window,omitempty"`
+	// Geometry is where "place_window" puts the window and how big it
+	// makes it, e.g. "1280x800+0+0" (see apps.ParseGeometry).
+	Geometry string `json:"geometry,omitempty"`
+}
+
+// Step actions.
+const (
+	ActionMove    = "move"
+	ActionClick   = "click"
+	ActionType    = "type"
+	ActionKey     = "key"
+	ActionWait    = "wait"
+	ActionForEach = "foreach"
+
+	ActionWaitUntilIdle     = "wait_until_idle"
+	ActionDragFromTo        = "drag_from_to"
+	ActionContextMenu       = "context_menu"
+	ActionAssertText        = "assert_text"
+	ActionAssertNumber      = "assert_number"
+	ActionAssertImage       = "assert_image"
+	ActionAssertWindowTitle = "assert_window_title"
+	ActionAssertClipboard   = "assert_clipboard"
+
+	ActionLaunchBrowser   = "launch_browser"
+	ActionOpenURL         = "open_url"
+	ActionSwitchTab       = "switch_tab"
+	ActionWaitForPageLoad = "wait_for_page_load"
+
+	ActionLaunchApp      = "launch_app"
+	ActionActivateWindow = "activate_window"
+	ActionCloseWindow    = "close_window"
+	ActionPlaceWindow    = "place_window"
+	ActionMaximizeWindow = "maximize_window"
+)
+
+// Duration is a time.Duration that unmarshals from a Go duration string
+// ("1.5s") or a number of milliseconds.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	ms, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	*d = Duration(ms * float64(time.Millisecond))
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load reads and validates a script file.
+func Load(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.dir = filepath.Dir(path)
+	return s, nil
+}
+
+// Parse decodes and validates a script from JSON.
+func Parse(data []byte) (*Script, error) {
+	var s Script
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks a script decoded other than by Parse, e.g. as part of a
+// larger request, before it is run.
+func (s *Script) Validate() error {
+	if _, err := languages(s.Languages); err != nil {
+		return fmt.Errorf("languages: %w", err)
+	}
+	if err := validateSteps(s.Steps, "steps"); err != nil {
+		return err
+	}
+	if s.Popups != nil {
+		if err := s.Popups.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dir returns the directory the script was loaded from, or "" if it was
+// parsed from memory.
+func (s *Script) Dir() string {
+	return s.dir
+}
+
+// NeedsVision reports whether any step reads the screen with a vision
+// model, so callers only connect to one when the script needs it.
+func (s *Script) NeedsVision() bool {
+	return needsVision(s.Steps)
+}
+
+func needsVision(steps []Step) bool {
+	for _, step := range steps {
+		switch step.Action {
+		case ActionAssertText, ActionAssertNumber, ActionContextMenu:
+			return true
+		case ActionMove, ActionClick:
+			if step.Target != "" {
+				return true
+			}
+		case ActionDragFromTo:
+			if (step.From != nil && step.From.Target != "") || (step.To != nil && step.To.Target != "") {
+				return true
+			}
+		case ActionForEach:
+			if needsVision(step.Steps) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedsBrowser reports whether any step manages a browser through its
+// DevTools endpoint, so callers only connect to one when the script needs
+// it.
+func (s *Script) NeedsBrowser() bool {
+	return needsBrowser(s.Steps)
+}
+
+func needsBrowser(steps []Step) bool {
+	for _, step := range steps {
+		switch step.Action {
+		case ActionLaunchBrowser, ActionOpenURL, ActionSwitchTab, ActionWaitForPageLoad:
+			return true
+		case ActionForEach:
+			if needsBrowser(step.Steps) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// languages returns the names of the languages with codes langs.
+func languages(langs []string) ([]string, error) {
+	names := make([]string, 0, len(langs))
+	for _, lang := range langs {
+		name, err := vision.LanguageName(strings.TrimSpace(lang))
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func validateSteps(steps []Step, path string) error {
+	for i, step := range steps {
+		where := fmt.Sprintf("%s[%d]", path, i)
+		if _, err := languages(step.Languages); err != nil {
+			return fmt.Errorf("%s: languages: %w", where, err)
+		}
+		if err := step.validate(where); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Step) validate(where string) error {
+	switch s.Action {
+	case ActionMove:
+		if (s.X == nil || s.Y == nil) && s.Target == "" {
+			return fmt.Errorf("%s: move requires x and y, or a target", where)
+		}
+		if s.Scroll < 0 {
+			return fmt.Errorf("%s: scroll must not be negative", where)
+		}
+	case ActionClick:
+		if (s.X == nil) != (s.Y == nil) {
+			return fmt.Errorf("%s: click requires both x and y, or neither", where)
+		}
+		if s.Clicks < 0 || s.Clicks > 3 {
+			return fmt.Errorf("%s: clicks must be 1, 2 or 3", where)
+		}
+		if s.Hold < 0 {
+			return fmt.Errorf("%s: hold must not be negative", where)
+		}
+		if s.Scroll < 0 {
+			return fmt.Errorf("%s: scroll must not be negative", where)
+		}
+	case ActionType:
+		if s.Text == "" {
+			return fmt.Errorf("%s: type requires text", where)
+		}
+	case ActionKey:
+		if s.Key == "" {
+			return fmt.Errorf("%s: key requires key", where)
+		}
+	case ActionWait:
+		if s.Duration <= 0 {
+			return fmt.Errorf("%s: wait requires a positive duration", where)
+		}
+	case ActionWaitUntilIdle:
+		if s.Timeout < 0 || s.Settle < 0 {
+			return fmt.Errorf("%s: wait_until_idle timeout and settle must not be negative", where)
+		}
+	case ActionDragFromTo:
+		if err := s.From.validate(where + ": drag_from_to from"); err != nil {
+			return err
+		}
+		if err := s.To.validate(where + ": drag_from_to to"); err != nil {
+			return err
+		}
+		if s.Duration < 0 || s.Hold < 0 {
+			return fmt.Errorf("%s: drag_from_to duration and hold must not be negative", where)
+		}
+	case ActionContextMenu:
+		if (s.X == nil || s.Y == nil) && s.Target == "" {
+			return fmt.Errorf("%s: context_menu requires x and y, or a target", where)
+		}
+		if len(SplitMenuPath(s.Item)) == 0 {
+			return fmt.Errorf("%s: context_menu requires item", where)
+		}
+	case ActionForEach:
+		if s.Data == "" {
+			return fmt.Errorf("%s: foreach requires data", where)
+		}
+		return validateSteps(s.Steps, where+".steps")
+	case ActionAssertText:
+		if s.Target == "" {
+			return fmt.Errorf("%s: assert_text requires target", where)
+		}
+		return s.validateMatch(where)
+	case ActionAssertWindowTitle, ActionAssertClipboard:
+		return s.validateMatch(where)
+	case ActionAssertNumber:
+		if s.Target == "" || s.Op == "" || s.Value == nil {
+			return fmt.Errorf("%s: assert_number requires target, op and value", where)
+		}
+		if _, ok := numberOps[s.Op]; !ok {
+			return fmt.Errorf("%s: unknown op %q (want ==, !=, <, <=, > or >=)", where, s.Op)
+		}
+	case ActionLaunchBrowser, ActionWaitForPageLoad:
+		if s.Timeout < 0 {
+			return fmt.Errorf("%s: %s timeout must not be negative", where, s.Action)
+		}
+	case ActionOpenURL:
+		if s.URL == "" {
+			return fmt.Errorf("%s: open_url requires url", where)
+		}
+		if s.Timeout < 0 {
+			return fmt.Errorf("%s: open_url timeout must not be negative", where)
+		}
+	case ActionSwitchTab:
+		if s.Tab == "" {
+			return fmt.Errorf("%s: switch_tab requires tab", where)
+		}
+	case ActionLaunchApp, ActionActivateWindow, ActionCloseWindow, ActionPlaceWindow, ActionMaximizeWindow:
+		if s.Action == ActionPlaceWindow {
+			if s.Geometry == "" {
+				return fmt.Errorf("%s: place_window requires geometry", where)
+			}
+			if !strings.Contains(s.Geometry, "${") {
+				if _, err := apps.ParseGeometry(s.Geometry); err != nil {
+					return fmt.Errorf("%s: %w", where, err)
+				}
+			}
+		}
+		if s.Action == ActionLaunchApp && s.App == "" {
+			return fmt.Errorf("%s: launch_app requires app", where)
+		}
+		if s.App == "" && s.Window == "" {
+			return fmt.Errorf("%s: %s requires app or window", where, s.Action)
+		}
+		if _, err := regexp.Compile(s.Window); err != nil {
+			return fmt.Errorf("%s: invalid window regex: %w", where, err)
+		}
+		if s.Timeout < 0 {
+			return fmt.Errorf("%s: %s timeout must not be negative", where, s.Action)
+		}
+	case ActionAssertImage:
+		if s.Image == "" {
+			return fmt.Errorf("%s: assert_image requires image", where)
+		}
+		if s.Threshold < 0 || s.Threshold > 1 {
+			return fmt.Errorf("%s: threshold must be between 0 and 1", where)
+		}
+	case "":
+		return fmt.Errorf("%s: missing action", where)
+	default:
+		return fmt.Errorf("%s: unknown action %q", where, s.Action)
+	}
+	return nil
+}
+
+func (s Step) validateMatch(where string) error {
+	switch s.Match {
+	case "", MatchEquals, MatchContains:
+	case MatchRegex:
+		if _, err := regexp.Compile(s.Expect); err != nil {
+			return fmt.Errorf("%s: invalid regex: %w", where, err)
+		}
+	default:
+		return fmt.Errorf("%s: unknown match %q (want equals, contains or regex)", where, s.Match)
+	}
+	return nil
+}
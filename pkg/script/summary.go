@@ -0,0 +1,103 @@
+package script
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Summary says in a few words what the step does, e.g. "clicking the
+// Settings button", for progress reports such as spoken ones. It uses the
+// step as written, so typed text and expanded variables, which may be
+// secret, stay out of it.
+func (s Step) Summary() string {
+	switch s.Action {
+	case ActionMove:
+		return "moving to " + s.where()
+	case ActionClick:
+		verb := "clicking"
+		switch {
+		case s.Clicks == 2:
+			verb = "double-clicking"
+		case s.Button == "right":
+			verb = "right-clicking"
+		}
+		if s.Target == "" && s.X == nil {
+			return verb
+		}
+		return verb + " " + s.where()
+	case ActionType:
+		return "typing"
+	case ActionKey:
+		return "pressing " + s.Key
+	case ActionWait:
+		return "waiting " + time.Duration(s.Duration).String()
+	case ActionWaitUntilIdle:
+		return "waiting for the screen to settle"
+	case ActionDragFromTo:
+		return "dragging"
+	case ActionContextMenu:
+		return "choosing " + s.Item
+	case ActionForEach:
+		return "repeating for each row of " + filepath.Base(s.Data)
+	case ActionAssertText, ActionAssertNumber:
+		return "checking " + s.where()
+	case ActionAssertImage:
+		return "checking the screen shows " + filepath.Base(s.Image)
+	case ActionAssertWindowTitle:
+		return "checking the window title"
+	case ActionAssertClipboard:
+		return "checking the clipboard"
+	case ActionLaunchBrowser:
+		return "launching the browser"
+	case ActionOpenURL:
+		return "opening " + s.URL
+	case ActionSwitchTab:
+		return "switching to the " + s.Tab + " tab"
+	case ActionWaitForPageLoad:
+		return "waiting for the page to load"
+	case ActionLaunchApp:
+		return "launching " + appLabel(s.App)
+	case ActionActivateWindow:
+		return "switching to " + s.window()
+	case ActionCloseWindow:
+		return "closing " + s.window()
+	case ActionPlaceWindow:
+		return "placing " + s.window()
+	case ActionMaximizeWindow:
+		return "maximizing " + s.window()
+	default:
+		return strings.ReplaceAll(s.Action, "_", " ")
+	}
+}
+
+// where names a step's target, or its coordinates without one.
+func (s Step) where() string {
+	if s.Target != "" {
+		return s.Target
+	}
+	if s.X != nil && s.Y != nil {
+		return fmt.Sprintf("%.0f%% across, %.0f%% down", *s.X*100, *s.Y*100)
+	}
+	return "the pointer"
+}
+
+// window names the window a window step acts on.
+func (s Step) window() string {
+	if s.App != "" {
+		return appLabel(s.App) + "'s window"
+	}
+	return "the window"
+}
+
+// appLabel shortens an application's path or bundle id, such as
+// com.apple.TextEdit, to its name.
+func appLabel(app string) string {
+	app = filepath.Base(strings.ReplaceAll(app, `\`, "/"))
+	app = strings.TrimSuffix(strings.TrimSuffix(app, ".exe"), ".app")
+	if strings.Count(app, ".") >= 2 {
+		app = app[strings.LastIndex(app, ".")+1:]
+	}
+	return app
+}
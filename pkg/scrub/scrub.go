@@ -0,0 +1,134 @@
+// Package scrub removes secrets from text before it is written to disk or
+// sent to an API: session logs, the log, assertion reports and the prompts
+// sent to models. Secrets are given as the names of credentials, whose
+// values are looked up with package secrets, or as a pattern; well-known
+// credential formats such as API keys and private keys are always
+// scrubbed.
+package scrub
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/secure"
+)
+
+// Redacted replaces every secret found.
+const Redacted = secure.Placeholder
+
+// minValue is the length below which values are not scrubbed, since they
+// would match ordinary text.
+const minValue = 4
+
+// DefaultSecrets are the credentials agentGo itself uses, whose values are
+// always scrubbed.
+var DefaultSecrets = []string{"GEMINI_API_KEY", "OPENAI_API_KEY", "VNC_PASSWORD", "RDP_PASSWORD", "AGENTGO_TOKEN"}
+
+// DefaultPatterns match well-known credential formats.
+var DefaultPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),                   // Google API keys
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),                 // OpenAI and Anthropic keys
+	regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),             // AWS access keys
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}`),            // GitHub tokens
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),          // Slack tokens
+	regexp.MustCompile(`\bagt_[A-Za-z0-9_-]{43}`),                 // worker API tokens
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`), // bearer tokens
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// Scrubber replaces secrets in text with Redacted. A nil Scrubber leaves
+// text alone.
+type Scrubber struct {
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+// New returns a scrubber of DefaultPatterns, pattern if it is not empty,
+// and the values of DefaultSecrets and names, looked up with
+// secrets.Lookup. Names that are not set are skipped.
+func New(names []string, pattern string) (*Scrubber, error) {
+	s := &Scrubber{patterns: DefaultPatterns}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		s.patterns = append(s.patterns[:len(s.patterns):len(s.patterns)], re)
+	}
+	for _, name := range append(DefaultSecrets, names...) {
+		if name = strings.TrimSpace(name); name != "" {
+			s.Add(secrets.Lookup(name))
+		}
+	}
+	return s, nil
+}
+
+// Add scrubs value too. Values shorter than four characters are ignored.
+func (s *Scrubber) Add(value string) {
+	if len(value) < minValue {
+		return
+	}
+	for _, v := range s.values {
+		if v == value {
+			return
+		}
+	}
+	s.values = append(s.values, value)
+	// The longest first, so that a value containing another is replaced
+	// whole.
+	sort.Slice(s.values, func(i, j int) bool { return len(s.values[i]) > len(s.values[j]) })
+}
+
+// String returns text with every secret replaced by Redacted.
+func (s *Scrubber) String(text string) string {
+	if s == nil || text == "" {
+		return text
+	}
+	for _, v := range s.values {
+		text = strings.ReplaceAll(text, v, Redacted)
+	}
+	for _, re := range s.patterns {
+		text = re.ReplaceAllLiteralString(text, Redacted)
+	}
+	return text
+}
+
+// Logf returns logf with its messages scrubbed.
+func (s *Scrubber) Logf(logf func(format string, args ...any)) func(format string, args ...any) {
+	if s == nil {
+		return logf
+	}
+	return func(format string, args ...any) {
+		logf("%s", s.String(fmt.Sprintf(format, args...)))
+	}
+}
+
+// Writer returns a writer that scrubs what is written to w. Each write is
+// scrubbed on its own, which suits the log package, as it writes a whole
+// line at a time.
+func (s *Scrubber) Writer(w io.Writer) io.Writer {
+	if s == nil {
+		return w
+	}
+	return writer{s: s, w: w}
+}
+
+type writer struct {
+	s *Scrubber
+	w io.Writer
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	scrubbed := w.s.String(string(p))
+	if scrubbed == string(p) {
+		return w.w.Write(p)
+	}
+	if _, err := io.WriteString(w.w, scrubbed); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
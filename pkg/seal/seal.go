@@ -0,0 +1,271 @@
+// Package seal records the checksums of a recording, script or session
+// directory in a manifest, optionally signed with Ed25519, so that files
+// kept as audit evidence or test baselines can be checked for tampering
+// before they are used.
+package seal
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Version is the manifest format this package writes.
+const Version = 1
+
+// Manifest names of a sealed directory and the suffix of a sealed file's.
+const (
+	dirManifest  = "seal.json"
+	fileManifest = ".seal.json"
+)
+
+// Manifest lists the checksums of a sealed file or directory.
+type Manifest struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	// Files maps slash-separated paths, relative to a sealed directory or
+	// the base name of a sealed file, to "sha256:" and the hex digest.
+	Files map[string]string `json:"files"`
+	// Key is the signer's public key and Signature its signature of the
+	// manifest without Signature, both base64. They are empty if the seal
+	// is unsigned.
+	Key       string `json:"key,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// ErrNotSealed is returned by Verify for paths without a manifest.
+var ErrNotSealed = errors.New("not sealed")
+
+// ManifestPath returns where the manifest of path is kept: seal.json in a
+// directory, or beside a file with a .seal.json suffix.
+func ManifestPath(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return filepath.Join(path, dirManifest), nil
+	}
+	return path + fileManifest, nil
+}
+
+// Sealed reports whether path has a manifest.
+func Sealed(path string) bool {
+	manifest, err := ManifestPath(path)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(manifest)
+	return err == nil
+}
+
+// SealedDir returns the nearest directory above path that is sealed, whose
+// manifest therefore covers path, or "" if there is none.
+func SealedDir(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, dirManifest)); err == nil {
+			return dir
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
+
+// Seal writes the manifest of path, a file or a directory, signed with key
+// if it is not nil. An existing manifest is replaced.
+func Seal(path string, key ed25519.PrivateKey) (*Manifest, error) {
+	manifestPath, err := ManifestPath(path)
+	if err != nil {
+		return nil, err
+	}
+	files, err := checksums(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{Version: Version, Created: time.Now().UTC().Truncate(time.Second), Files: files}
+	if key != nil {
+		m.Key = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+		m.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, m.signed()))
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write seal: %w", err)
+	}
+	return m, nil
+}
+
+// Verify checks path against its manifest: every file must be listed and
+// unchanged, and no listed file missing. A signed manifest's signature must
+// hold; if trusted is not nil, the manifest must also be signed by it.
+// Verify returns the manifest, or an error listing every problem found.
+func Verify(path string, trusted ed25519.PublicKey) (*Manifest, error) {
+	manifestPath, err := ManifestPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(manifestPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotSealed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seal: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid seal %s: %w", manifestPath, err)
+	}
+	if m.Version > Version {
+		return nil, fmt.Errorf("seal format v%d is newer than this version of agentgo reads (v%d); upgrade agentgo", m.Version, Version)
+	}
+	if err := m.verifySignature(trusted); err != nil {
+		return &m, err
+	}
+
+	files, err := checksums(path)
+	if err != nil {
+		return &m, err
+	}
+	var problems []error
+	for _, name := range sortedKeys(m.Files) {
+		switch sum, ok := files[name]; {
+		case !ok:
+			problems = append(problems, fmt.Errorf("%s: missing", name))
+		case sum != m.Files[name]:
+			problems = append(problems, fmt.Errorf("%s: modified", name))
+		}
+	}
+	for _, name := range sortedKeys(files) {
+		if _, ok := m.Files[name]; !ok {
+			problems = append(problems, fmt.Errorf("%s: added after sealing", name))
+		}
+	}
+	return &m, errors.Join(problems...)
+}
+
+// Signer returns a short fingerprint of the manifest's signing key, or ""
+// if it is unsigned.
+func (m *Manifest) Signer() string {
+	if m.Key == "" {
+		return ""
+	}
+	key, err := base64.StdEncoding.DecodeString(m.Key)
+	if err != nil {
+		return ""
+	}
+	return Fingerprint(key)
+}
+
+func (m *Manifest) verifySignature(trusted ed25519.PublicKey) error {
+	if m.Signature == "" {
+		if trusted != nil {
+			return errors.New("the seal is not signed")
+		}
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(m.Key)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("the seal has an invalid signing key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil || !ed25519.Verify(key, m.signed(), sig) {
+		return errors.New("the seal's signature does not match its contents")
+	}
+	if trusted != nil && !trusted.Equal(ed25519.PublicKey(key)) {
+		return fmt.Errorf("the seal is signed by %s, not the trusted key %s", Fingerprint(key), Fingerprint(trusted))
+	}
+	return nil
+}
+
+// signed returns the bytes a manifest's signature covers: its JSON without
+// the signature. Map keys marshal in order, so this is stable.
+func (m *Manifest) signed() []byte {
+	unsigned := *m
+	unsigned.Signature = ""
+	data, _ := json.Marshal(unsigned)
+	return data
+}
+
+// checksums returns the checksums of path's files, as Manifest.Files.
+func checksums(path string) (map[string]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	if !fi.IsDir() {
+		sum, err := checksum(path)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Base(path)] = sum
+		return files, nil
+	}
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		if rel == dirManifest {
+			return nil
+		}
+		sum, err := checksum(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return files, nil
+}
+
+func checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Fingerprint returns a short, printable identifier of a public key.
+func Fingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
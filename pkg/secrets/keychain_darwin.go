@@ -0,0 +1,37 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The login keychain is driven through security(1), which also handles the
+// keychain's own access prompts.
+
+func keychainLookup(service, name string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", name, "-w").Output()
+	if err != nil {
+		return "", ErrNotFound
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func keychainStore(service, name, value string) error {
+	return security("add-generic-password", "-U", "-s", service, "-a", name, "-l", service+": "+name, "-w", value)
+}
+
+func keychainDelete(service, name string) error {
+	return security("delete-generic-password", "-s", service, "-a", name)
+}
+
+func security(args ...string) error {
+	cmd := exec.Command("security", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("security %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The Secret Service (GNOME Keyring, KWallet) is driven through
+// secret-tool from libsecret; on Debian it is in the libsecret-tools
+// package.
+
+func keychainLookup(service, name string) (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", ErrNotFound
+	}
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "account", name).Output()
+	if err != nil || len(out) == 0 {
+		return "", ErrNotFound
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func keychainStore(service, name, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", service+": "+name, "service", service, "account", name)
+	cmd.Stdin = strings.NewReader(value)
+	return secretTool(cmd)
+}
+
+func keychainDelete(service, name string) error {
+	return secretTool(exec.Command("secret-tool", "clear", "service", service, "account", name))
+}
+
+func secretTool(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("secret-tool %s failed: %w: %s", cmd.Args[1], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+//go:build !darwin && !linux && !windows
+
+package secrets
+
+import "errors"
+
+var errNoKeychain = errors.New("no supported keychain on this platform")
+
+func keychainLookup(service, name string) (string, error) {
+	return "", ErrNotFound
+}
+
+func keychainStore(service, name, value string) error {
+	return errNoKeychain
+}
+
+func keychainDelete(service, name string) error {
+	return errNoKeychain
+}
@@ -0,0 +1,160 @@
+// Package secrets looks up credentials such as API keys and remote desktop
+// passwords. By default a name is looked up in the environment and then in
+// the OS keychain (macOS Keychain, Windows Credential Manager or the
+// freedesktop Secret Service through libsecret), so a key stored once with
+// "agentgo secret set GEMINI_API_KEY" never has to sit in a shell profile.
+// Other secret managers plug in as Providers, either in code with Register
+// or by setting AGENTGO_SECRET_COMMAND to a command that prints a secret,
+// e.g. "op read op://agentgo/{name}/credential".
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Service is the keychain service name agentGo stores its secrets under.
+const Service = "agentgo"
+
+// ErrNotFound is returned when no provider has the secret.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider is a source of secrets.
+type Provider interface {
+	// Lookup returns the secret called name, or an error wrapping
+	// ErrNotFound if the provider does not have it.
+	Lookup(name string) (string, error)
+}
+
+// Env looks secrets up in the process environment.
+type Env struct{}
+
+// Lookup implements Provider.
+func (Env) Lookup(name string) (string, error) {
+	if v, ok := os.LookupEnv(name); ok && v != "" {
+		return v, nil
+	}
+	return "", ErrNotFound
+}
+
+// Keychain looks secrets up in the OS keychain under a service name.
+type Keychain struct {
+	Service string
+}
+
+// Lookup implements Provider.
+func (k Keychain) Lookup(name string) (string, error) {
+	return keychainLookup(k.service(), name)
+}
+
+// Store saves a secret in the keychain, replacing any previous value.
+func (k Keychain) Store(name, value string) error {
+	return keychainStore(k.service(), name, value)
+}
+
+// Delete removes a secret from the keychain.
+func (k Keychain) Delete(name string) error {
+	return keychainDelete(k.service(), name)
+}
+
+func (k Keychain) service() string {
+	if k.Service == "" {
+		return Service
+	}
+	return k.Service
+}
+
+// Command runs an external program to fetch a secret, which is how password
+// managers and cloud secret managers are plugged in. Every "{name}" in Args
+// is replaced by the secret's name; the trimmed standard output is the
+// secret, and a failing command means it was not found.
+type Command struct {
+	Args []string
+}
+
+// Lookup implements Provider.
+func (c Command) Lookup(name string) (string, error) {
+	if len(c.Args) == 0 {
+		return "", ErrNotFound
+	}
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = strings.ReplaceAll(a, "{name}", name)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v %s", ErrNotFound, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	v := strings.TrimSpace(string(out))
+	if v == "" {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// Chain tries providers in order.
+type Chain []Provider
+
+// Lookup implements Provider. Errors other than ErrNotFound stop the
+// search.
+func (c Chain) Lookup(name string) (string, error) {
+	for _, p := range c {
+		v, err := p.Lookup(name)
+		if err == nil {
+			return v, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+	}
+	return "", ErrNotFound
+}
+
+var (
+	mu       sync.Mutex
+	registry []Provider
+)
+
+// Register adds a provider consulted by Get after the built-in ones.
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry = append(registry, p)
+}
+
+// Default returns the providers Get consults: the environment, the OS
+// keychain, AGENTGO_SECRET_COMMAND if set, then any registered providers.
+func Default() Chain {
+	chain := Chain{Env{}, Keychain{}}
+	if cmd := strings.Fields(os.Getenv("AGENTGO_SECRET_COMMAND")); len(cmd) > 0 {
+		chain = append(chain, Command{Args: cmd})
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return append(chain, registry...)
+}
+
+// Get looks name up with the default providers. The error for a missing
+// secret says where it may be put.
+func Get(name string) (string, error) {
+	v, err := Default().Lookup(name)
+	if errors.Is(err, ErrNotFound) {
+		return "", fmt.Errorf("%s not set: export it, or store it in the keychain with \"agentgo secret set %s\"", name, name)
+	}
+	return v, err
+}
+
+// Lookup is like Get but returns "" for a missing secret, for optional
+// credentials.
+func Lookup(name string) string {
+	v, _ := Default().Lookup(name)
+	return v
+}
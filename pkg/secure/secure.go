@@ -0,0 +1,129 @@
+// Package secure recognizes password and other secure-entry fields, so that
+// what is typed into them stays out of session recordings, logs and the
+// screenshots sent to models. Drivers that can ask the accessibility API
+// which field has the focus implement Prober; for the rest, and for fields
+// the API does not mark, the label of the field last clicked and the title
+// of the window are matched against the words that name secrets.
+package secure
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"regexp"
+	"sync"
+)
+
+// Placeholder replaces what is typed into a secure field.
+const Placeholder = "<secret>"
+
+// Field is what is known about the field with the focus.
+type Field struct {
+	// Secure is set for password and other secure-entry fields.
+	Secure bool
+	// Bounds is the field's box in the pixels of the driver's screenshots,
+	// or empty if it is not known.
+	Bounds image.Rectangle
+}
+
+// ErrUnsupported is returned by probers that cannot tell on this platform.
+var ErrUnsupported = errors.New("the focused field cannot be inspected on this platform")
+
+// Prober is implemented by drivers that can ask the accessibility API
+// about the field with the focus.
+type Prober interface {
+	FocusedField() (Field, error)
+}
+
+// Labels matches the labels and window titles of secure fields, in the
+// languages desktops are most often set to.
+var Labels = regexp.MustCompile(`(?i)password|passphrase|passcode|\bpin\b|\bsecret|\bcvv\b|\bcvc\b|passwort|kennwort|contraseña|mot de passe|senha|wachtwoord|parola d'ordine|пароль|密码|密碼|パスワード|비밀번호`)
+
+// Detector tells whether the focus is in a secure field. A nil Detector
+// finds none, so callers need not check whether masking is on.
+type Detector struct {
+	// Probe, if set, asks the accessibility API.
+	Probe Prober
+	// Window, if set, returns the title of the focused window.
+	Window func() string
+
+	mu    sync.Mutex
+	label string
+}
+
+// Clicked records the label of the element just clicked, which now has
+// the focus; it is empty if the click gave coordinates.
+func (d *Detector) Clicked(label string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.label = label
+	d.mu.Unlock()
+}
+
+// Focused returns what is known about the field with the focus. The
+// accessibility API is believed when it marks a field secure; otherwise
+// the field is secure if its label or the window's title names a secret.
+func (d *Detector) Focused() Field {
+	if d == nil {
+		return Field{}
+	}
+	var f Field
+	if d.Probe != nil {
+		if probed, err := d.Probe.FocusedField(); err == nil {
+			if probed.Secure {
+				return probed
+			}
+			f = probed
+		}
+	}
+	d.mu.Lock()
+	label := d.label
+	d.mu.Unlock()
+	if Labels.MatchString(label) || (d.Window != nil && Labels.MatchString(d.Window())) {
+		f.Secure = true
+	}
+	return f
+}
+
+// Text returns text, or Placeholder if it goes into a secure field.
+func (d *Detector) Text(text string) string {
+	if text != "" && d.Focused().Secure {
+		return Placeholder
+	}
+	return text
+}
+
+// Capture returns capture with the screenshots it takes redacted.
+func (d *Detector) Capture(capture func() (image.Image, error)) func() (image.Image, error) {
+	if d == nil {
+		return capture
+	}
+	return func() (image.Image, error) {
+		img, err := capture()
+		if err != nil {
+			return nil, err
+		}
+		return d.Redact(img), nil
+	}
+}
+
+// Redact blacks out the focused field of img if it is secure and its box
+// is known. An *image.RGBA is changed in place; other images are copied
+// first.
+func (d *Detector) Redact(img image.Image) image.Image {
+	f := d.Focused()
+	box := f.Bounds.Intersect(img.Bounds())
+	if !f.Secure || box.Empty() {
+		return img
+	}
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+	draw.Draw(rgba, box, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	return rgba
+}
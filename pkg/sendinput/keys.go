@@ -0,0 +1,96 @@
+package sendinput
+
+// scanCode is a set 1 keyboard scan code; extended keys are sent with an
+// E0 prefix.
+type scanCode struct {
+	scan     uint16
+	extended bool
+}
+
+const scanLeftShift = 0x2a
+
+// keyCodes maps the key names used by scripts (robotgo's names) to scan
+// codes.
+var keyCodes = map[string]scanCode{
+	"escape":      {0x01, false},
+	"esc":         {0x01, false},
+	"backspace":   {0x0e, false},
+	"tab":         {0x0f, false},
+	"enter":       {0x1c, false},
+	"return":      {0x1c, false},
+	"ctrl":        {0x1d, false},
+	"control":     {0x1d, false},
+	"lctrl":       {0x1d, false},
+	"rctrl":       {0x1d, true},
+	"shift":       {0x2a, false},
+	"lshift":      {0x2a, false},
+	"rshift":      {0x36, false},
+	"alt":         {0x38, false},
+	"lalt":        {0x38, false},
+	"ralt":        {0x38, true},
+	"space":       {0x39, false},
+	"capslock":    {0x3a, false},
+	"f1":          {0x3b, false},
+	"f2":          {0x3c, false},
+	"f3":          {0x3d, false},
+	"f4":          {0x3e, false},
+	"f5":          {0x3f, false},
+	"f6":          {0x40, false},
+	"f7":          {0x41, false},
+	"f8":          {0x42, false},
+	"f9":          {0x43, false},
+	"f10":         {0x44, false},
+	"f11":         {0x57, false},
+	"f12":         {0x58, false},
+	"printscreen": {0x37, true},
+	"home":        {0x47, true},
+	"up":          {0x48, true},
+	"pageup":      {0x49, true},
+	"left":        {0x4b, true},
+	"right":       {0x4d, true},
+	"end":         {0x4f, true},
+	"down":        {0x50, true},
+	"pagedown":    {0x51, true},
+	"insert":      {0x52, true},
+	"delete":      {0x53, true},
+	"cmd":         {0x5b, true},
+	"command":     {0x5b, true},
+	"super":       {0x5b, true},
+	"win":         {0x5b, true},
+	"menu":        {0x5d, true},
+}
+
+// stroke is a key pressed, with or without shift.
+type stroke struct {
+	key   scanCode
+	shift bool
+}
+
+// chars maps the characters Type sends as keys to their key on a US
+// layout.
+var chars = map[rune]stroke{}
+
+func init() {
+	rows := []struct {
+		scan         uint16
+		plain, shift string
+	}{
+		{0x02, "1234567890-=", "!@#$%^&*()_+"},
+		{0x10, "qwertyuiop[]", "QWERTYUIOP{}"},
+		{0x1e, "asdfghjkl;'`", "ASDFGHJKL:\"~"},
+		{0x2b, "\\zxcvbnm,./", "|ZXCVBNM<>?"},
+	}
+	for _, r := range rows {
+		shifted := []rune(r.shift)
+		for i, c := range []rune(r.plain) {
+			k := scanCode{r.scan + uint16(i), false}
+			chars[c] = stroke{k, false}
+			chars[shifted[i]] = stroke{k, true}
+			// Single-letter and digit key names, as in "ctrl+a".
+			keyCodes[string(c)] = k
+		}
+	}
+	for c, name := range map[rune]string{' ': "space", '\n': "enter", '\t': "tab"} {
+		chars[c] = stroke{keyCodes[name], false}
+	}
+}
@@ -0,0 +1,159 @@
+// Package sendinput injects input on Windows with the low-level SendInput
+// API, sending keys as hardware scan codes rather than virtual keys. Games
+// that read raw scan codes and, when agentGo runs elevated, elevated
+// windows accept it where robotgo's events are ignored.
+//
+// Some anti-cheat systems and DirectInput games still ignore injected
+// events. For those, NewInterception sends the same strokes through the
+// Interception kernel driver (github.com/oblitum/Interception), which
+// makes them indistinguishable from a real keyboard and mouse; the driver
+// must be installed and interception.dll be on the DLL search path.
+package sendinput
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"agentGo/pkg/input"
+)
+
+// Driver is a SendInput or Interception input backend. It implements
+// input.Backend.
+type Driver struct {
+	sink sink
+}
+
+// sink is where strokes go: SendInput or the Interception driver.
+type sink interface {
+	key(scan uint16, extended, up bool) error
+	// unicode types a character no key produces.
+	unicode(r rune) error
+	// move places the pointer in physical virtual-desktop pixels.
+	move(x, y int) error
+	position() (int, int, error)
+	button(button string, down bool) error
+	wheel(dx, dy int) error
+	close() error
+}
+
+// New returns a driver that injects input with SendInput.
+func New() (*Driver, error) {
+	s, err := openSendInput()
+	if err != nil {
+		return nil, err
+	}
+	return &Driver{sink: s}, nil
+}
+
+// NewInterception returns a driver that injects input through the
+// Interception driver.
+func NewInterception() (*Driver, error) {
+	s, err := openInterception()
+	if err != nil {
+		return nil, err
+	}
+	return &Driver{sink: s}, nil
+}
+
+// Close releases the Interception context, if any.
+func (d *Driver) Close() error {
+	return d.sink.close()
+}
+
+// Move implements input.Backend.
+func (d *Driver) Move(x, y int) error {
+	return d.sink.move(x, y)
+}
+
+// Position implements input.Backend.
+func (d *Driver) Position() (int, int, error) {
+	return d.sink.position()
+}
+
+// Click implements input.Backend.
+func (d *Driver) Click(button string) error {
+	if dx, dy, ok := input.Wheel(button); ok {
+		return d.Scroll(dx, dy)
+	}
+	if err := d.sink.button(button, true); err != nil {
+		return err
+	}
+	return d.sink.button(button, false)
+}
+
+// Toggle implements input.Backend.
+func (d *Driver) Toggle(button string, down bool) error {
+	return d.sink.button(button, down)
+}
+
+// Type implements input.Backend. Characters on a US keyboard are sent as
+// their scan codes, so the desktop's layout should be US; others are sent
+// as Unicode characters, which the Interception driver cannot do.
+func (d *Driver) Type(text string) error {
+	for _, c := range text {
+		k, ok := chars[c]
+		if !ok {
+			if err := d.sink.unicode(c); err != nil {
+				return err
+			}
+			continue
+		}
+		if k.shift {
+			if err := d.sink.key(scanLeftShift, false, false); err != nil {
+				return err
+			}
+		}
+		if err := d.tap(k.key); err != nil {
+			return err
+		}
+		if k.shift {
+			if err := d.sink.key(scanLeftShift, false, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// KeyTap implements input.Backend.
+func (d *Driver) KeyTap(key string, modifiers ...string) error {
+	keys := make([]scanCode, 0, len(modifiers)+1)
+	for _, name := range append(modifiers, key) {
+		k, ok := keyCodes[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("unknown key %q", name)
+		}
+		keys = append(keys, k)
+	}
+	mods := keys[:len(keys)-1]
+	for _, m := range mods {
+		if err := d.sink.key(m.scan, m.extended, false); err != nil {
+			return err
+		}
+	}
+	err := d.tap(keys[len(keys)-1])
+	for i := len(mods) - 1; i >= 0; i-- {
+		if e := d.sink.key(mods[i].scan, mods[i].extended, true); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Scroll implements input.Backend.
+func (d *Driver) Scroll(dx, dy int) error {
+	return d.sink.wheel(dx, dy)
+}
+
+// Hook implements input.Backend; injected input cannot be watched.
+func (d *Driver) Hook(context.Context) (<-chan input.Event, error) {
+	return nil, input.ErrUnsupported
+}
+
+func (d *Driver) tap(k scanCode) error {
+	if err := d.sink.key(k.scan, k.extended, false); err != nil {
+		return err
+	}
+	return d.sink.key(k.scan, k.extended, true)
+}
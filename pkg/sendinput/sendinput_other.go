@@ -0,0 +1,11 @@
+//go:build !windows
+
+package sendinput
+
+import "errors"
+
+var errNotWindows = errors.New("SendInput is only available on Windows")
+
+func openSendInput() (sink, error) { return nil, errNotWindows }
+
+func openInterception() (sink, error) { return nil, errNotWindows }
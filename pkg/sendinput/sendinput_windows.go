@@ -0,0 +1,323 @@
+package sendinput
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32 = syscall.NewLazyDLL("user32.dll")
+
+	procSendInput        = user32.NewProc("SendInput")
+	procGetCursorPos     = user32.NewProc("GetCursorPos")
+	procGetSystemMetrics = user32.NewProc("GetSystemMetrics")
+)
+
+const (
+	inputMouse    = 0
+	inputKeyboard = 1
+
+	keyeventfExtendedKey = 0x0001
+	keyeventfKeyUp       = 0x0002
+	keyeventfUnicode     = 0x0004
+	keyeventfScanCode    = 0x0008
+
+	mouseeventfMove        = 0x0001
+	mouseeventfLeftDown    = 0x0002
+	mouseeventfLeftUp      = 0x0004
+	mouseeventfRightDown   = 0x0008
+	mouseeventfRightUp     = 0x0010
+	mouseeventfMiddleDown  = 0x0020
+	mouseeventfMiddleUp    = 0x0040
+	mouseeventfWheel       = 0x0800
+	mouseeventfHWheel      = 0x1000
+	mouseeventfVirtualDesk = 0x4000
+	mouseeventfAbsolute    = 0x8000
+
+	wheelDelta = 120
+
+	smXVirtualScreen  = 76
+	smYVirtualScreen  = 77
+	smCXVirtualScreen = 78
+	smCYVirtualScreen = 79
+)
+
+type mouseInput struct {
+	dx, dy    int32
+	mouseData uint32
+	flags     uint32
+	time      uint32
+	extraInfo uintptr
+}
+
+type keybdInput struct {
+	vk, scan  uint16
+	flags     uint32
+	time      uint32
+	extraInfo uintptr
+}
+
+// mouseINPUT and keybdINPUT are the INPUT union as each of its members;
+// keybdINPUT is padded to the union's size.
+type mouseINPUT struct {
+	typ uint32
+	mi  mouseInput
+}
+
+type keybdINPUT struct {
+	typ uint32
+	ki  keybdInput
+	_   [8]byte
+}
+
+type point struct {
+	X, Y int32
+}
+
+type sendInput struct{}
+
+func openSendInput() (sink, error) {
+	if err := procSendInput.Find(); err != nil {
+		return nil, err
+	}
+	return sendInput{}, nil
+}
+
+func send(p unsafe.Pointer) error {
+	n, _, err := procSendInput.Call(1, uintptr(p), unsafe.Sizeof(mouseINPUT{}))
+	if n != 1 {
+		return fmt.Errorf("SendInput failed (blocked by UIPI? run agentGo elevated to drive elevated windows): %w", err)
+	}
+	return nil
+}
+
+func (sendInput) key(scan uint16, extended, up bool) error {
+	in := keybdINPUT{typ: inputKeyboard, ki: keybdInput{scan: scan, flags: keyeventfScanCode}}
+	if extended {
+		in.ki.flags |= keyeventfExtendedKey
+	}
+	if up {
+		in.ki.flags |= keyeventfKeyUp
+	}
+	return send(unsafe.Pointer(&in))
+}
+
+func (sendInput) unicode(r rune) error {
+	units := []uint16{uint16(r)}
+	if r > 0xffff {
+		r -= 0x10000
+		units = []uint16{uint16(0xd800 + r>>10), uint16(0xdc00 + r&0x3ff)}
+	}
+	for _, flags := range []uint32{keyeventfUnicode, keyeventfUnicode | keyeventfKeyUp} {
+		for _, u := range units {
+			in := keybdINPUT{typ: inputKeyboard, ki: keybdInput{scan: u, flags: flags}}
+			if err := send(unsafe.Pointer(&in)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sendInput) move(x, y int) error {
+	nx, ny := normalize(x, y)
+	in := mouseINPUT{typ: inputMouse, mi: mouseInput{dx: nx, dy: ny, flags: mouseeventfMove | mouseeventfAbsolute | mouseeventfVirtualDesk}}
+	return send(unsafe.Pointer(&in))
+}
+
+func (sendInput) position() (int, int, error) {
+	return cursorPos()
+}
+
+func (sendInput) button(button string, down bool) error {
+	flags, err := buttonFlags(button, down)
+	if err != nil {
+		return err
+	}
+	in := mouseINPUT{typ: inputMouse, mi: mouseInput{flags: flags}}
+	return send(unsafe.Pointer(&in))
+}
+
+func (sendInput) wheel(dx, dy int) error {
+	if dy != 0 {
+		// The wheel counts forward (up) as positive.
+		in := mouseINPUT{typ: inputMouse, mi: mouseInput{mouseData: uint32(int32(-dy * wheelDelta)), flags: mouseeventfWheel}}
+		if err := send(unsafe.Pointer(&in)); err != nil {
+			return err
+		}
+	}
+	if dx != 0 {
+		in := mouseINPUT{typ: inputMouse, mi: mouseInput{mouseData: uint32(int32(dx * wheelDelta)), flags: mouseeventfHWheel}}
+		return send(unsafe.Pointer(&in))
+	}
+	return nil
+}
+
+func (sendInput) close() error {
+	return nil
+}
+
+// normalize maps physical virtual-desktop pixels to the 0-65535 range of
+// absolute mouse input.
+func normalize(x, y int) (int32, int32) {
+	metric := func(i uintptr) int {
+		v, _, _ := procGetSystemMetrics.Call(i)
+		return int(int32(v))
+	}
+	vx, vy := metric(smXVirtualScreen), metric(smYVirtualScreen)
+	vw, vh := max(metric(smCXVirtualScreen), 2), max(metric(smCYVirtualScreen), 2)
+	return int32((x - vx) * 65535 / (vw - 1)), int32((y - vy) * 65535 / (vh - 1))
+}
+
+func cursorPos() (int, int, error) {
+	var p point
+	if ok, _, err := procGetCursorPos.Call(uintptr(unsafe.Pointer(&p))); ok == 0 {
+		return 0, 0, err
+	}
+	return int(p.X), int(p.Y), nil
+}
+
+func buttonFlags(button string, down bool) (uint32, error) {
+	var flags [2]uint32
+	switch button {
+	case "", "left":
+		flags = [2]uint32{mouseeventfLeftUp, mouseeventfLeftDown}
+	case "right":
+		flags = [2]uint32{mouseeventfRightUp, mouseeventfRightDown}
+	case "center", "middle":
+		flags = [2]uint32{mouseeventfMiddleUp, mouseeventfMiddleDown}
+	default:
+		return 0, fmt.Errorf("unknown mouse button %q", button)
+	}
+	if down {
+		return flags[1], nil
+	}
+	return flags[0], nil
+}
+
+// Interception devices and stroke states (interception.h).
+const (
+	interceptionKeyboard = 1  // INTERCEPTION_KEYBOARD(0)
+	interceptionMouse    = 11 // INTERCEPTION_MOUSE(0)
+
+	interceptionKeyUp = 0x01
+	interceptionKeyE0 = 0x02
+
+	interceptionMouseLeftDown   = 0x001
+	interceptionMouseLeftUp     = 0x002
+	interceptionMouseRightDown  = 0x004
+	interceptionMouseRightUp    = 0x008
+	interceptionMouseMiddleDown = 0x010
+	interceptionMouseMiddleUp   = 0x020
+	interceptionMouseWheel      = 0x400
+	interceptionMouseHWheel     = 0x800
+
+	interceptionMoveAbsolute       = 0x001
+	interceptionMoveVirtualDesktop = 0x002
+)
+
+// keyStroke and mouseStroke are InterceptionKeyStroke and
+// InterceptionMouseStroke. interception_send takes an array of
+// InterceptionStroke, which is the size of the larger mouse stroke.
+type keyStroke struct {
+	code, state uint16
+	information uint32
+	_           [12]byte
+}
+
+type mouseStroke struct {
+	state, flags uint16
+	rolling      int16
+	x, y         int32
+	information  uint32
+}
+
+type interception struct {
+	ctx     uintptr
+	send    *syscall.LazyProc
+	destroy *syscall.LazyProc
+}
+
+func openInterception() (sink, error) {
+	dll := syscall.NewLazyDLL("interception.dll")
+	if err := dll.Load(); err != nil {
+		return nil, fmt.Errorf("failed to load interception.dll (is the Interception driver installed?): %w", err)
+	}
+	ctx, _, _ := dll.NewProc("interception_create_context").Call()
+	if ctx == 0 {
+		return nil, errors.New("failed to create an Interception context (is the driver installed and the machine rebooted since?)")
+	}
+	return &interception{ctx: ctx, send: dll.NewProc("interception_send"), destroy: dll.NewProc("interception_destroy_context")}, nil
+}
+
+func (ic *interception) stroke(device uintptr, p unsafe.Pointer) error {
+	if n, _, _ := ic.send.Call(ic.ctx, device, uintptr(p), 1); n != 1 {
+		return errors.New("interception_send failed")
+	}
+	return nil
+}
+
+func (ic *interception) key(scan uint16, extended, up bool) error {
+	s := keyStroke{code: scan}
+	if extended {
+		s.state |= interceptionKeyE0
+	}
+	if up {
+		s.state |= interceptionKeyUp
+	}
+	return ic.stroke(interceptionKeyboard, unsafe.Pointer(&s))
+}
+
+func (ic *interception) unicode(r rune) error {
+	return fmt.Errorf("cannot type %q through the Interception driver, which only sends keys", r)
+}
+
+func (ic *interception) move(x, y int) error {
+	nx, ny := normalize(x, y)
+	s := mouseStroke{flags: interceptionMoveAbsolute | interceptionMoveVirtualDesktop, x: nx, y: ny}
+	return ic.stroke(interceptionMouse, unsafe.Pointer(&s))
+}
+
+func (ic *interception) position() (int, int, error) {
+	return cursorPos()
+}
+
+func (ic *interception) button(button string, down bool) error {
+	var states [2]uint16
+	switch button {
+	case "", "left":
+		states = [2]uint16{interceptionMouseLeftUp, interceptionMouseLeftDown}
+	case "right":
+		states = [2]uint16{interceptionMouseRightUp, interceptionMouseRightDown}
+	case "center", "middle":
+		states = [2]uint16{interceptionMouseMiddleUp, interceptionMouseMiddleDown}
+	default:
+		return fmt.Errorf("unknown mouse button %q", button)
+	}
+	s := mouseStroke{state: states[0]}
+	if down {
+		s.state = states[1]
+	}
+	return ic.stroke(interceptionMouse, unsafe.Pointer(&s))
+}
+
+func (ic *interception) wheel(dx, dy int) error {
+	if dy != 0 {
+		s := mouseStroke{state: interceptionMouseWheel, rolling: int16(-dy * wheelDelta)}
+		if err := ic.stroke(interceptionMouse, unsafe.Pointer(&s)); err != nil {
+			return err
+		}
+	}
+	if dx != 0 {
+		s := mouseStroke{state: interceptionMouseHWheel, rolling: int16(dx * wheelDelta)}
+		return ic.stroke(interceptionMouse, unsafe.Pointer(&s))
+	}
+	return nil
+}
+
+func (ic *interception) close() error {
+	ic.destroy.Call(ic.ctx)
+	return nil
+}
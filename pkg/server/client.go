@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client talks to a worker's HTTP API.
+type Client struct {
+	// BaseURL is the worker address, e.g. "http://host:7070". A bare
+	// host:port is accepted and treated as http.
+	BaseURL    string
+	HTTPClient *http.Client
+	// Token, if set, is sent as a bearer token to workers that require
+	// authentication.
+	Token string
+}
+
+// NewClient returns a client for the worker at addr.
+func NewClient(addr string) *Client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &Client{BaseURL: strings.TrimSuffix(addr, "/"), HTTPClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Info fetches the worker's status.
+func (c *Client) Info(ctx context.Context) (Info, error) {
+	var info Info
+	err := c.do(ctx, http.MethodGet, "/v1/info", nil, &info)
+	return info, err
+}
+
+// Submit queues a task on the worker.
+func (c *Client) Submit(ctx context.Context, req TaskRequest) (Task, error) {
+	var t Task
+	err := c.do(ctx, http.MethodPost, "/v1/tasks", req, &t)
+	return t, err
+}
+
+// Get fetches a task's state.
+func (c *Client) Get(ctx context.Context, id string) (Task, error) {
+	var t Task
+	err := c.do(ctx, http.MethodGet, "/v1/tasks/"+id, nil, &t)
+	return t, err
+}
+
+// Cancel stops a task.
+func (c *Client) Cancel(ctx context.Context, id string) (Task, error) {
+	var t Task
+	err := c.do(ctx, http.MethodDelete, "/v1/tasks/"+id, nil, &t)
+	return t, err
+}
+
+// Wait polls a task until it finishes or ctx is cancelled, in which case
+// the remote task is cancelled too.
+func (c *Client) Wait(ctx context.Context, id string, interval time.Duration) (Task, error) {
+	return c.Watch(ctx, id, interval, nil)
+}
+
+// Watch is like Wait but calls onUpdate with every polled state, including
+// the final one.
+func (c *Client) Watch(ctx context.Context, id string, interval time.Duration, onUpdate func(Task)) (Task, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		t, err := c.Get(ctx, id)
+		if err != nil {
+			return t, err
+		}
+		if onUpdate != nil {
+			onUpdate(t)
+		}
+		if t.Done() {
+			return t, nil
+		}
+		select {
+		case <-ctx.Done():
+			c.Cancel(context.Background(), id)
+			return t, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Screenshot fetches the worker's current screen as PNG.
+func (c *Client) Screenshot(ctx context.Context) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/v1/screenshot", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /v1/screenshot: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// StreamScreenshots calls fn with each PNG frame pushed by the worker until
+// ctx is cancelled or fn returns an error.
+func (c *Client) StreamScreenshots(ctx context.Context, interval time.Duration, fn func(png []byte) error) error {
+	req, err := c.newRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/screenshot/stream?interval=%s", interval), nil)
+	if err != nil {
+		return err
+	}
+	// The stream is long-lived, so it must not use the client's timeout.
+	streamClient := *c.HTTPClient
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /v1/screenshot/stream: %s", resp.Status)
+	}
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		return fmt.Errorf("unexpected stream content type %q", resp.Header.Get("Content-Type"))
+	}
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if ctx.Err() != nil || err == io.EOF {
+				return ctx.Err()
+			}
+			return err
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := c.newRequest(ctx, method, path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var e errorResponse
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("%s %s: %s", method, path, e.Error)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// newRequest returns a request for path on the worker, with the client's
+// token.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
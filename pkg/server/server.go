@@ -0,0 +1,487 @@
+// Package server exposes an agentGo worker over an HTTP/JSON API so that
+// scripts and recordings can be run on its desktop from another machine.
+//
+// The API is HTTP/JSON rather than gRPC by choice: tasks are submitted and
+// polled a few times a second at most, so a binary protocol buys nothing,
+// while plain HTTP needs no generated stubs or protoc in the build, can be
+// driven with curl, streams screenshots as multipart/x-mixed-replace that a
+// browser shows as is, and passes through the proxies and load balancers
+// automation labs already run. Coordinators (see package fleet) use the same
+// API through Client.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"agentGo/pkg/auth"
+	"agentGo/pkg/frame"
+	"agentGo/pkg/playback"
+	"agentGo/pkg/script"
+	"agentGo/pkg/throttle"
+)
+
+// Task states.
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateSuccess   = "success"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// TaskRequest asks a worker to run a script or a recording. Exactly one of
+// Script and Recording is set. Content is sent inline because the worker
+// does not share the caller's filesystem. Vars may only set variables the
+// script declares in its vars; a recording takes none.
+type TaskRequest struct {
+	Name      string            `json:"name"`
+	Script    *script.Script    `json:"script,omitempty"`
+	Recording []playback.Sample `json:"recording,omitempty"`
+	Vars      map[string]string `json:"vars,omitempty"`
+	Timeout   script.Duration   `json:"timeout,omitempty"`
+}
+
+// Task is the state of a submitted request.
+type Task struct {
+	ID      string      `json:"id"`
+	Request TaskRequest `json:"request"`
+	State   string      `json:"state"`
+	Error   string      `json:"error,omitempty"`
+	Queued  time.Time   `json:"queued"`
+	Start   time.Time   `json:"start,omitempty"`
+	End     time.Time   `json:"end,omitempty"`
+	// SubmittedBy is the identity that submitted the task.
+	SubmittedBy string `json:"submitted_by,omitempty"`
+	// Log holds the most recent progress messages, oldest first.
+	Log []string `json:"log,omitempty"`
+	// LogOffset is the number of messages dropped from the front of Log.
+	LogOffset int `json:"log_offset,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// maxTaskLog caps the progress messages kept per task.
+const maxTaskLog = 500
+
+// Finished tasks are kept for callers to poll for finishedTTL, and at most
+// maxFinished of them.
+const (
+	finishedTTL = time.Hour
+	maxFinished = 1000
+)
+
+// Done reports whether the task has finished.
+func (t *Task) Done() bool {
+	return t.State == StateSuccess || t.State == StateFailed || t.State == StateCancelled
+}
+
+// Info describes a worker.
+type Info struct {
+	Host   string            `json:"host"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Busy   bool              `json:"busy"`
+	Queued int               `json:"queued"`
+}
+
+// RunFunc executes a task on the worker's desktop, reporting progress
+// through logf.
+type RunFunc func(ctx context.Context, req TaskRequest, logf func(format string, args ...any)) error
+
+// Worker runs submitted tasks one at a time, since they share a single
+// desktop.
+type Worker struct {
+	Labels map[string]string
+	Run    RunFunc
+	// Capture, if set, enables the screenshot endpoints.
+	Capture func() (image.Image, error)
+	// Throttle, if set, sends stream frames less often and smaller on
+	// battery power or when the machine runs hot. Single screenshots are
+	// always taken in full.
+	Throttle *throttle.Governor
+	// Auth, if set, admits only the identities it lists, each to its
+	// scope: observe may read status and screenshots, act may also submit
+	// tasks and cancel its own, and admin may cancel anyone's.
+	Auth *auth.Authenticator
+	Logf func(format string, args ...any)
+
+	mu     sync.Mutex
+	tasks  map[string]*Task
+	queue  chan *Task
+	busy   bool
+	queued int
+	once   sync.Once
+}
+
+func (w *Worker) init() {
+	w.once.Do(func() {
+		w.tasks = make(map[string]*Task)
+		w.queue = make(chan *Task, 256)
+	})
+}
+
+// Serve processes queued tasks until ctx is cancelled.
+func (w *Worker) Serve(ctx context.Context) {
+	w.init()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-w.queue:
+			w.execute(ctx, t)
+		}
+	}
+}
+
+func (w *Worker) execute(ctx context.Context, t *Task) {
+	w.mu.Lock()
+	w.queued--
+	if t.State == StateCancelled {
+		w.mu.Unlock()
+		return
+	}
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if t.Request.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, time.Duration(t.Request.Timeout))
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	t.cancel = cancel
+	t.State = StateRunning
+	t.Start = time.Now()
+	w.busy = true
+	w.mu.Unlock()
+
+	w.logf("task %s (%s) from %s: starting", t.ID, t.Request.Name, t.SubmittedBy)
+	err := w.run(runCtx, t)
+	cancel()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.busy = false
+	t.End = time.Now()
+	switch {
+	case t.State == StateCancelled:
+	case err != nil:
+		t.State = StateFailed
+		t.Error = err.Error()
+	default:
+		t.State = StateSuccess
+	}
+	w.logf("task %s (%s): %s after %s", t.ID, t.Request.Name, t.State, t.End.Sub(t.Start).Round(time.Millisecond))
+}
+
+// run runs t, failing it rather than the worker if it panics.
+func (w *Worker) run(ctx context.Context, t *Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logf("task %s (%s) panicked: %v\n%s", t.ID, t.Request.Name, r, debug.Stack())
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return w.Run(ctx, t.Request, func(format string, args ...any) {
+		w.appendLog(t, fmt.Sprintf(format, args...))
+	})
+}
+
+func (w *Worker) appendLog(t *Task, msg string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t.Log = append(t.Log, msg)
+	if len(t.Log) > maxTaskLog {
+		drop := len(t.Log) - maxTaskLog
+		t.Log = append([]string(nil), t.Log[drop:]...)
+		t.LogOffset += drop
+	}
+}
+
+// Submit queues a task on behalf of the identity by and returns its
+// initial state.
+func (w *Worker) Submit(req TaskRequest, by string) (Task, error) {
+	w.init()
+	if (req.Script == nil) == (len(req.Recording) == 0) {
+		return Task{}, errors.New("task must set exactly one of script or recording")
+	}
+	if req.Script != nil {
+		if err := req.Script.Validate(); err != nil {
+			return Task{}, fmt.Errorf("invalid script: %w", err)
+		}
+	}
+	for name := range req.Vars {
+		if req.Script == nil {
+			return Task{}, errors.New("a recording takes no vars")
+		}
+		if _, ok := req.Script.Vars[name]; !ok {
+			return Task{}, fmt.Errorf("script does not declare var %q", name)
+		}
+	}
+	t := &Task{ID: newID(), Request: req, State: StateQueued, Queued: time.Now(), SubmittedBy: by}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case w.queue <- t:
+	default:
+		return Task{}, errors.New("task queue is full")
+	}
+	w.evict(t.Queued)
+	w.tasks[t.ID] = t
+	w.queued++
+	return *t, nil
+}
+
+// evict forgets tasks that finished more than finishedTTL before now, and
+// the oldest finished tasks beyond maxFinished. w.mu must be held.
+func (w *Worker) evict(now time.Time) {
+	var finished []*Task
+	for id, t := range w.tasks {
+		switch {
+		case !t.Done():
+		case now.Sub(t.End) > finishedTTL:
+			delete(w.tasks, id)
+		default:
+			finished = append(finished, t)
+		}
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+	slices.SortFunc(finished, func(a, b *Task) int { return a.End.Compare(b.End) })
+	for _, t := range finished[:len(finished)-maxFinished] {
+		delete(w.tasks, t.ID)
+	}
+}
+
+// Get returns a snapshot of a task.
+func (w *Worker) Get(id string) (Task, bool) {
+	w.init()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t, ok := w.tasks[id]
+	if !ok {
+		return Task{}, false
+	}
+	snapshot := *t
+	snapshot.Log = append([]string(nil), t.Log...)
+	return snapshot, true
+}
+
+// Cancel stops a queued or running task.
+func (w *Worker) Cancel(id string) (Task, bool) {
+	w.init()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t, ok := w.tasks[id]
+	if !ok {
+		return Task{}, false
+	}
+	if !t.Done() {
+		t.State = StateCancelled
+		t.End = time.Now()
+		if t.cancel != nil {
+			t.cancel()
+		}
+	}
+	return *t, true
+}
+
+// Info returns the worker's current status.
+func (w *Worker) Info() Info {
+	w.init()
+	host, _ := os.Hostname()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return Info{Host: host, Labels: w.Labels, Busy: w.busy, Queued: w.queued}
+}
+
+// Handler returns the worker's HTTP API, with the scope each request
+// takes:
+//
+//	GET    /v1/info         observe  worker status and labels
+//	POST   /v1/tasks        act      submit a TaskRequest
+//	GET    /v1/tasks/{id}   observe  task status
+//	DELETE /v1/tasks/{id}   act      cancel a task (admin for others' tasks)
+//	GET    /v1/screenshot   observe  current screen as PNG
+//	GET    /v1/screenshot/stream?interval=1s
+//	                        observe  multipart/x-mixed-replace stream of PNG frames
+//
+// Every request is logged with the identity that made it.
+func (w *Worker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/info", func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, http.StatusOK, w.Info())
+	})
+	mux.HandleFunc("POST /v1/tasks", func(rw http.ResponseWriter, r *http.Request) {
+		var req TaskRequest
+		if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, 64<<20)).Decode(&req); err != nil {
+			writeError(rw, http.StatusBadRequest, fmt.Errorf("invalid task request: %w", err))
+			return
+		}
+		id, _ := auth.FromContext(r.Context())
+		t, err := w.Submit(req, id.Name)
+		if err != nil {
+			writeError(rw, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(rw, http.StatusAccepted, t)
+	})
+	mux.HandleFunc("GET /v1/tasks/{id}", func(rw http.ResponseWriter, r *http.Request) {
+		t, ok := w.Get(r.PathValue("id"))
+		if !ok {
+			writeError(rw, http.StatusNotFound, errors.New("no such task"))
+			return
+		}
+		writeJSON(rw, http.StatusOK, t)
+	})
+	mux.HandleFunc("DELETE /v1/tasks/{id}", func(rw http.ResponseWriter, r *http.Request) {
+		t, ok := w.Get(r.PathValue("id"))
+		if !ok {
+			writeError(rw, http.StatusNotFound, errors.New("no such task"))
+			return
+		}
+		if id, _ := auth.FromContext(r.Context()); id.Scope < auth.ScopeAdmin && id.Name != t.SubmittedBy {
+			writeError(rw, http.StatusForbidden, fmt.Errorf("%s may only cancel its own tasks", id.Name))
+			return
+		}
+		t, _ = w.Cancel(t.ID)
+		writeJSON(rw, http.StatusOK, t)
+	})
+	mux.HandleFunc("GET /v1/screenshot", w.handleScreenshot)
+	mux.HandleFunc("GET /v1/screenshot/stream", w.handleScreenshotStream)
+	return w.Auth.Handler(mux, scope, w.logf)
+}
+
+// scope returns the scope r takes: reading is observing, and anything
+// else acts on the desktop.
+func scope(r *http.Request) auth.Scope {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return auth.ScopeObserve
+	}
+	return auth.ScopeAct
+}
+
+func (w *Worker) handleScreenshot(rw http.ResponseWriter, r *http.Request) {
+	data, err := w.capturePNG(nil, nil)
+	if err != nil {
+		writeError(rw, http.StatusServiceUnavailable, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "image/png")
+	rw.Write(data)
+}
+
+// handleScreenshotStream pushes a PNG frame every interval until the client
+// disconnects. Browsers render multipart/x-mixed-replace as live video.
+func (w *Worker) handleScreenshotStream(rw http.ResponseWriter, r *http.Request) {
+	interval := time.Second
+	if v := r.URL.Query().Get("interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 100*time.Millisecond {
+			writeError(rw, http.StatusBadRequest, errors.New("interval must be a duration of at least 100ms"))
+			return
+		}
+		interval = d
+	}
+	if w.Capture == nil {
+		writeError(rw, http.StatusServiceUnavailable, errors.New("screen capture is not available on this worker"))
+		return
+	}
+
+	mw := multipart.NewWriter(rw)
+	rw.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mw.Boundary())
+	flusher, _ := rw.(http.Flusher)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// Frames are encoded into the same slice, which is written out before
+	// the next one.
+	var data []byte
+	for {
+		var err error
+		data, err = w.capturePNG(data[:0], w.Throttle)
+		if err != nil {
+			w.logf("screenshot stream: %v", err)
+			return
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":   {"image/png"},
+			"Content-Length": {strconv.Itoa(len(data))},
+		})
+		if err != nil {
+			return
+		}
+		if _, err := part.Write(data); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			ticker.Reset(w.Throttle.Interval(interval))
+		}
+	}
+}
+
+// capturePNG captures the screen, scaled down as gov says, and appends it
+// to dst as PNG.
+func (w *Worker) capturePNG(dst []byte, gov *throttle.Governor) ([]byte, error) {
+	if w.Capture == nil {
+		return nil, errors.New("screen capture is not available on this worker")
+	}
+	img, err := w.Capture()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture screen: %w", err)
+	}
+	defer frame.Put(img)
+	if small := gov.Shrink(img); small != img {
+		defer frame.Put(small)
+		img = small
+	}
+	return frame.AppendPNG(dst, img)
+}
+
+func (w *Worker) logf(format string, args ...any) {
+	if w.Logf != nil {
+		w.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(v)
+}
+
+func writeError(rw http.ResponseWriter, status int, err error) {
+	writeJSON(rw, status, errorResponse{Error: err.Error()})
+}
+
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"agentGo/pkg/script"
+)
+
+func TestSubmitRejectsInvalidScript(t *testing.T) {
+	w := &Worker{Run: func(ctx context.Context, req TaskRequest, logf func(string, ...any)) error {
+		t.Error("an invalid script was run")
+		return nil
+	}, Logf: t.Logf}
+	rec := httptest.NewRecorder()
+	body := `{"name": "bad", "script": {"steps": [{"action": "move"}]}}`
+	w.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/tasks", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "move requires x and y") {
+		t.Errorf("error %s does not say what is wrong", rec.Body)
+	}
+	if info := w.Info(); info.Queued != 0 {
+		t.Errorf("%d tasks queued, want none", info.Queued)
+	}
+}
+
+func TestPanickingTaskFailsAlone(t *testing.T) {
+	w := &Worker{Run: func(ctx context.Context, req TaskRequest, logf func(string, ...any)) error {
+		if req.Name == "panics" {
+			var p *int
+			_ = *p
+		}
+		return nil
+	}, Logf: t.Logf}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Serve(ctx)
+
+	bad, err := w.Submit(TaskRequest{Name: "panics", Script: parseScript(t)}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	good, err := w.Submit(TaskRequest{Name: "works", Script: parseScript(t)}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := waitDone(t, w, bad.ID); got.State != StateFailed || !strings.Contains(got.Error, "panicked") {
+		t.Errorf("panicking task ended %s (%q), want failed", got.State, got.Error)
+	}
+	if got := waitDone(t, w, good.ID); got.State != StateSuccess {
+		t.Errorf("task after a panic ended %s (%q), want success", got.State, got.Error)
+	}
+}
+
+func TestSubmitTakesDeclaredVars(t *testing.T) {
+	w := &Worker{Logf: t.Logf}
+	s := parseScript(t)
+	s.Vars = map[string]string{"USER": "guest"}
+	if _, err := w.Submit(TaskRequest{Script: s, Vars: map[string]string{"USER": "admin"}}, "test"); err != nil {
+		t.Errorf("a declared var was refused: %v", err)
+	}
+	if _, err := w.Submit(TaskRequest{Script: s, Vars: map[string]string{"PATH": "/tmp"}}, "test"); err == nil {
+		t.Error("an undeclared var was taken")
+	}
+}
+
+func TestFinishedTasksAreEvicted(t *testing.T) {
+	w := &Worker{Logf: t.Logf}
+	w.init()
+	now := time.Now()
+	w.tasks["old"] = &Task{ID: "old", State: StateSuccess, End: now.Add(-finishedTTL - time.Minute)}
+	w.tasks["running"] = &Task{ID: "running", State: StateRunning, Start: now.Add(-2 * finishedTTL)}
+	for i := range maxFinished + 5 {
+		id := fmt.Sprintf("done%d", i)
+		w.tasks[id] = &Task{ID: id, State: StateFailed, End: now.Add(time.Duration(i-maxFinished-5) * time.Second)}
+	}
+	w.mu.Lock()
+	w.evict(now)
+	w.mu.Unlock()
+	if _, ok := w.Get("old"); ok {
+		t.Error("a task that finished over an hour ago was kept")
+	}
+	if _, ok := w.Get("running"); !ok {
+		t.Error("a running task was evicted")
+	}
+	for i := range 5 {
+		if _, ok := w.Get(fmt.Sprintf("done%d", i)); ok {
+			t.Errorf("done%d, one of the oldest finished tasks, was kept", i)
+		}
+	}
+	if n := len(w.tasks); n != maxFinished+1 {
+		t.Errorf("%d tasks kept, want %d", n, maxFinished+1)
+	}
+}
+
+func waitDone(t *testing.T, w *Worker, id string) Task {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		task, ok := w.Get(id)
+		if !ok {
+			t.Fatalf("task %s is gone", id)
+		}
+		if task.Done() {
+			return task
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("task %s did not finish", id)
+	return Task{}
+}
+
+func parseScript(t *testing.T) *script.Script {
+	t.Helper()
+	s, err := script.Parse([]byte(`{"steps": [{"action": "wait", "duration": "1ms"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,192 @@
+package session
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Kinds of divergence between two sessions.
+const (
+	DiffMissing = "missing" // a step of the first session not in the second
+	DiffExtra   = "extra"   // a step of the second session not in the first
+	DiffMoved   = "moved"   // a click made somewhere else
+	DiffTiming  = "timing"  // a step made much sooner or later
+)
+
+// DiffOptions sets how far matched steps may differ before it is reported.
+type DiffOptions struct {
+	// Distance is how far, in normalized coordinates, a click may move. The default
+	// is 0.02.
+	Distance float64
+	// Timing is by how much, as a fraction, the time since the step before
+	// may change, and MinTiming the least change reported. The defaults are
+	// 0.5 and one second.
+	Timing    float64
+	MinTiming time.Duration
+}
+
+// Divergence is a difference between two sessions of the same workflow.
+type Divergence struct {
+	Kind string
+	// A and B are the steps that differ, numbered as by Steps; one of them
+	// is 0 for missing and extra steps.
+	A, B           int
+	EventA, EventB Event
+	// DX and DY are how far a click moved, and GapA and GapB the time from
+	// the step before in each session.
+	DX, DY     float64
+	GapA, GapB time.Duration
+}
+
+// Diff aligns the actions of two sessions of the same workflow, their
+// clicks, typing, keys and notes, and reports where they diverge. Pointer
+// moves, frames and model calls vary from run to run and are not compared.
+func Diff(a, b []Event, opts DiffOptions) []Divergence {
+	if opts.Distance <= 0 {
+		opts.Distance = 0.02
+	}
+	if opts.Timing <= 0 {
+		opts.Timing = 0.5
+	}
+	if opts.MinTiming <= 0 {
+		opts.MinTiming = time.Second
+	}
+	as, bs := actions(Steps(a)), actions(Steps(b))
+
+	// Align by edit distance: skipping a step costs 1, and matching two of
+	// the same kind costs more the further apart they were made, so that a
+	// click moved far is a missing and an extra step instead.
+	const gap = 1.0
+	cost := make([][]float64, len(as)+1)
+	for i := range cost {
+		cost[i] = make([]float64, len(bs)+1)
+		cost[i][0] = float64(i) * gap
+	}
+	for j := range cost[0] {
+		cost[0][j] = float64(j) * gap
+	}
+	for i := 1; i <= len(as); i++ {
+		for j := 1; j <= len(bs); j++ {
+			c := min(cost[i-1][j], cost[i][j-1]) + gap
+			if m, ok := matchCost(as[i-1].e, bs[j-1].e, opts.Distance); ok {
+				c = min(c, cost[i-1][j-1]+m)
+			}
+			cost[i][j] = c
+		}
+	}
+
+	// Walk back along the cheapest path, then report in order. Indexes of
+	// -1 mark a step skipped on that side.
+	var path [][2]int
+	for i, j := len(as), len(bs); i > 0 || j > 0; {
+		if i > 0 && j > 0 {
+			if m, ok := matchCost(as[i-1].e, bs[j-1].e, opts.Distance); ok && cost[i][j] == cost[i-1][j-1]+m {
+				i, j = i-1, j-1
+				path = append(path, [2]int{i, j})
+				continue
+			}
+		}
+		if i > 0 && cost[i][j] == cost[i-1][j]+gap {
+			i--
+			path = append(path, [2]int{i, -1})
+		} else {
+			j--
+			path = append(path, [2]int{-1, j})
+		}
+	}
+	var out []Divergence
+	for k := len(path) - 1; k >= 0; k-- {
+		switch i, j := path[k][0], path[k][1]; {
+		case j < 0:
+			out = append(out, Divergence{Kind: DiffMissing, A: as[i].n, EventA: as[i].e})
+		case i < 0:
+			out = append(out, Divergence{Kind: DiffExtra, B: bs[j].n, EventB: bs[j].e})
+		default:
+			out = append(out, compare(as, bs, i, j, opts)...)
+		}
+	}
+	return out
+}
+
+// action is a step compared by Diff, with its number.
+type action struct {
+	n int
+	e Event
+}
+
+func actions(steps []Event) []action {
+	var out []action
+	for i, e := range steps {
+		switch e.Kind {
+		case KindClick, KindType, KindKey, KindNote:
+			out = append(out, action{n: i + 1, e: e})
+		}
+	}
+	return out
+}
+
+// matchCost returns the cost of matching a with b, and false if they are
+// not the same action.
+func matchCost(a, b Event, distance float64) (float64, bool) {
+	if actionKey(a) != actionKey(b) {
+		return 0, false
+	}
+	if a.Kind != KindClick {
+		return 0, true
+	}
+	// Up to about eight times the tolerance still counts as moved.
+	return min(math.Hypot(b.X-a.X, b.Y-a.Y)/(4*distance), 1.9), true
+}
+
+func actionKey(e Event) string {
+	if e.Kind == KindClick {
+		return e.Kind + " " + e.Button + " " + strconv.Itoa(max(e.Clicks, 1))
+	}
+	return e.Kind + " " + e.Text
+}
+
+// compare reports how the matched actions as[i] and bs[j] differ.
+func compare(as, bs []action, i, j int, opts DiffOptions) []Divergence {
+	a, b := as[i], bs[j]
+	d := Divergence{A: a.n, B: b.n, EventA: a.e, EventB: b.e}
+	var out []Divergence
+	if a.e.Kind == KindClick {
+		d.DX, d.DY = b.e.X-a.e.X, b.e.Y-a.e.Y
+		if math.Hypot(d.DX, d.DY) > opts.Distance {
+			moved := d
+			moved.Kind = DiffMoved
+			out = append(out, moved)
+		}
+	}
+	if i > 0 && j > 0 {
+		d.GapA = a.e.Time - as[i-1].e.Time
+		d.GapB = b.e.Time - bs[j-1].e.Time
+		change := d.GapB - d.GapA
+		if change < 0 {
+			change = -change
+		}
+		if change >= opts.MinTiming && float64(change) > opts.Timing*float64(d.GapA) {
+			timing := d
+			timing.Kind = DiffTiming
+			out = append(out, timing)
+		}
+	}
+	return out
+}
+
+// String describes the divergence in a line.
+func (d Divergence) String() string {
+	switch d.Kind {
+	case DiffMissing:
+		return fmt.Sprintf("step %d, %s, is missing from the second", d.A, d.EventA)
+	case DiffExtra:
+		return fmt.Sprintf("step %d, %s, is only in the second", d.B, d.EventB)
+	case DiffMoved:
+		return fmt.Sprintf("steps %d/%d, %s, moved by (%+.4f, %+.4f)", d.A, d.B, clickLabel(d.EventA), d.DX, d.DY)
+	case DiffTiming:
+		return fmt.Sprintf("steps %d/%d, %s, came %v after the step before instead of %v", d.A, d.B, d.EventA, d.GapB.Round(10*time.Millisecond), d.GapA.Round(10*time.Millisecond))
+	}
+	return d.Kind
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// withAlpha returns c at opacity a, from 0 to 1.
+func withAlpha(c color.RGBA, a float64) color.RGBA {
+	c.A = uint8(math.Round(255 * min(max(a, 0), 1)))
+	return c
+}
+
+// blend paints c over the pixel at x, y.
+func blend(img *image.RGBA, x, y int, c color.RGBA) {
+	if !(image.Point{X: x, Y: y}).In(img.Rect) {
+		return
+	}
+	i := img.PixOffset(x, y)
+	a := uint32(c.A)
+	for k, v := range [3]uint8{c.R, c.G, c.B} {
+		img.Pix[i+k] = uint8((uint32(v)*a + uint32(img.Pix[i+k])*(255-a)) / 255)
+	}
+	img.Pix[i+3] = 255
+}
+
+// disc fills a circle of radius r around p.
+func disc(img *image.RGBA, p image.Point, r int, c color.RGBA) {
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			if x*x+y*y <= r*r {
+				blend(img, p.X+x, p.Y+y, c)
+			}
+		}
+	}
+}
+
+// ring draws a circle of radius r around p, width pixels wide.
+func ring(img *image.RGBA, p image.Point, r, width int, c color.RGBA) {
+	outer, inner := r*r, (r-width)*(r-width)
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			if d := x*x + y*y; d <= outer && d > inner {
+				blend(img, p.X+x, p.Y+y, c)
+			}
+		}
+	}
+}
+
+// line draws a line from a to b, width pixels wide.
+func line(img *image.RGBA, a, b image.Point, width int, c color.RGBA) {
+	d := b.Sub(a)
+	steps := max(abs(d.X), abs(d.Y), 1)
+	// Each step paints a span across the line rather than a square, so
+	// that no pixel is blended twice.
+	across := image.Pt(0, 1)
+	if abs(d.X) < abs(d.Y) {
+		across = image.Pt(1, 0)
+	}
+	for i := 0; i <= steps; i++ {
+		p := image.Pt(a.X+d.X*i/steps, a.Y+d.Y*i/steps)
+		for k := -width / 2; k < width-width/2; k++ {
+			q := p.Add(across.Mul(k))
+			blend(img, q.X, q.Y, c)
+		}
+	}
+}
+
+// cross draws a cross of arms r long centred on p.
+func cross(img *image.RGBA, p image.Point, r, width int, c color.RGBA) {
+	line(img, p.Add(image.Pt(-r, -r)), p.Add(image.Pt(r, r)), width, c)
+	line(img, p.Add(image.Pt(-r, r)), p.Add(image.Pt(r, -r)), width, c)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+var face = basicfont.Face7x13
+
+// label writes text on a dark box whose top left corner is at p, moved
+// inside the image if it would leave it.
+func label(img *image.RGBA, p image.Point, text string) {
+	const pad = 3
+	w := font.MeasureString(face, text).Ceil() + 2*pad
+	h := face.Metrics().Height.Ceil() + 2*pad
+	p.X = max(min(p.X, img.Rect.Max.X-w), img.Rect.Min.X)
+	p.Y = max(min(p.Y, img.Rect.Max.Y-h), img.Rect.Min.Y)
+	box := image.Rect(p.X, p.Y, p.X+w, p.Y+h)
+	draw.Draw(img, box, image.NewUniform(color.RGBA{A: 170}), image.Point{}, draw.Over)
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.P(p.X+pad, p.Y+pad+face.Metrics().Ascent.Ceil()),
+	}
+	d.DrawString(text)
+}
+
+// subtitles writes lines centred along the bottom of img, the last lowest.
+func subtitles(img *image.RGBA, lines []string) {
+	h := face.Metrics().Height.Ceil() + 10
+	y := img.Rect.Max.Y - 12 - h*len(lines)
+	for _, text := range lines {
+		// Long typing is shown by its end, which is what was just typed.
+		if fit := (img.Rect.Dx() - 20) / 7; len(text) > fit && fit > 3 {
+			text = "..." + text[len(text)-(fit-3):]
+		}
+		w := font.MeasureString(face, text).Ceil() + 6
+		label(img, image.Pt(img.Rect.Min.X+(img.Rect.Dx()-w)/2, y), text)
+		y += h
+	}
+}
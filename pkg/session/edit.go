@@ -0,0 +1,176 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Edits of a session's events, which are in time order and may start with
+// a KindStart event. Steps are numbered from 1 in that order, leaving out
+// the start event, as Steps lists them.
+
+// Steps returns events without their start event, numbered from 1 by
+// their index plus one.
+func Steps(events []Event) []Event {
+	if len(events) > 0 && events[0].Kind == KindStart {
+		return events[1:]
+	}
+	return events
+}
+
+// DeleteRange removes what happened between from and to, moving later
+// events earlier to close the gap. The screen and pointer position at to
+// carry on from from.
+func DeleteRange(events []Event, from, to time.Duration) ([]Event, error) {
+	if from < 0 || to <= from {
+		return nil, fmt.Errorf("invalid range %v-%v", from, to)
+	}
+	out := make([]Event, 0, len(events))
+	var frame, move *Event
+	carried := false
+	for i, e := range events {
+		switch {
+		case e.Time < from || e.Kind == KindStart:
+			out = append(out, e)
+		case e.Time < to:
+			switch e.Kind {
+			case KindFrame:
+				frame = &events[i]
+			case KindMove:
+				move = &events[i]
+			}
+		default:
+			if !carried {
+				out = carryOver(out, from, frame, move)
+				carried = true
+			}
+			e.Time -= to - from
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// carryOver appends, at time t, the last frame and move removed, if any.
+func carryOver(out []Event, t time.Duration, frame, move *Event) []Event {
+	if frame != nil {
+		out = append(out, Event{Time: t, Kind: KindFrame, Frame: frame.Frame})
+	}
+	if move != nil {
+		out = append(out, Event{Time: t, Kind: KindMove, X: move.X, Y: move.Y, Cursor: move.Cursor})
+	}
+	return out
+}
+
+// DeleteSteps removes steps first to last, inclusive, moving later events
+// earlier so that the step after last happens when first did. The screen
+// at last carries on from first; deleted moves are gone.
+func DeleteSteps(events []Event, first, last int) ([]Event, error) {
+	steps := Steps(events)
+	if first < 1 || last < first || last > len(steps) {
+		return nil, fmt.Errorf("invalid steps %d-%d (there are %d)", first, last, len(steps))
+	}
+	from := steps[first-1].Time
+	to := from
+	if last < len(steps) {
+		to = steps[last].Time
+	}
+	head := len(events) - len(steps)
+	out := append([]Event(nil), events[:head+first-1]...)
+	var frame *Event
+	for i := first - 1; i < last; i++ {
+		if steps[i].Kind == KindFrame {
+			frame = &steps[i]
+		}
+	}
+	if last < len(steps) {
+		out = carryOver(out, from, frame, nil)
+	}
+	for _, e := range steps[last:] {
+		e.Time -= to - from
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// InsertWait delays everything from at onwards by d.
+func InsertWait(events []Event, at, d time.Duration) ([]Event, error) {
+	if at < 0 || d <= 0 {
+		return nil, fmt.Errorf("invalid wait of %v at %v", d, at)
+	}
+	out := append([]Event(nil), events...)
+	for i := range out {
+		if out[i].Time >= at && out[i].Kind != KindStart {
+			out[i].Time += d
+		}
+	}
+	return out, nil
+}
+
+// ReplaceText replaces old with new in typed text, returning how many
+// typing events changed.
+func ReplaceText(events []Event, old, new string) ([]Event, int) {
+	out := append([]Event(nil), events...)
+	n := 0
+	for i := range out {
+		if out[i].Kind == KindType && strings.Contains(out[i].Text, old) {
+			out[i].Text = strings.ReplaceAll(out[i].Text, old, new)
+			n++
+		}
+	}
+	return out, n
+}
+
+// MoveClick sets where click step n was made, in normalized coordinates,
+// or if relative moves it by x, y. The pointer's move to the click, if it
+// is the step before, is moved with it.
+func MoveClick(events []Event, n int, x, y float64, relative bool) ([]Event, error) {
+	out := append([]Event(nil), events...)
+	steps := Steps(out)
+	if n < 1 || n > len(steps) {
+		return nil, fmt.Errorf("invalid step %d (there are %d)", n, len(steps))
+	}
+	click := &steps[n-1]
+	if click.Kind != KindClick {
+		return nil, fmt.Errorf("step %d is a %s, not a click", n, click.Kind)
+	}
+	if relative {
+		x, y = click.X+x, click.Y+y
+	}
+	if x < 0 || x > 1 || y < 0 || y > 1 {
+		return nil, fmt.Errorf("click at (%.4f, %.4f) would be off the screen", x, y)
+	}
+	if n > 1 {
+		if prev := &steps[n-2]; prev.Kind == KindMove && prev.X == click.X && prev.Y == click.Y {
+			prev.X, prev.Y = x, y
+		}
+	}
+	click.X, click.Y = x, y
+	return out, nil
+}
+
+// String describes e in a line, for listings.
+func (e Event) String() string {
+	switch e.Kind {
+	case KindMove:
+		return fmt.Sprintf("move to (%.4f, %.4f)", e.X, e.Y)
+	case KindClick:
+		s := fmt.Sprintf("%s at (%.4f, %.4f)", clickLabel(e), e.X, e.Y)
+		if e.App != "" {
+			s += " in " + e.App
+		}
+		return s
+	case KindPrediction:
+		return fmt.Sprintf("model found %q at (%.4f, %.4f)", e.Text, e.X, e.Y)
+	case KindFrame:
+		return "frame " + e.Frame
+	case KindModel:
+		return fmt.Sprintf("model: %d calls, %d tokens, %d failed", e.Requests, e.Tokens, e.Errors)
+	case KindStart:
+		return fmt.Sprintf("start, format v%d", e.Version)
+	case KindNote:
+		return "note: " + e.Text
+	}
+	return caption(e)
+}
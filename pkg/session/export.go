@@ -0,0 +1,313 @@
+package session
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Options control how a session is rendered.
+type Options struct {
+	// FPS is the video's frame rate. Zero means 5.
+	FPS int
+	// Scale resizes the frames, from 0 to 1. Zero means full size, except
+	// for GIFs, which are scaled to at most 960 pixels wide.
+	Scale float64
+	// Trail is how much of the pointer's path is drawn. Zero means 3
+	// seconds.
+	Trail time.Duration
+}
+
+// How long markers and captions stay on screen.
+const (
+	clickShown      = time.Second
+	predictionShown = 2 * time.Second
+	captionShown    = 3 * time.Second
+)
+
+// Export renders the session in dir as a video at out, with the pointer's
+// path, clicks, typing and the model's answers drawn over the screen. A .gif
+// is encoded directly; other formats, such as .mp4 or .webm, are encoded by
+// ffmpeg, which must be installed.
+func Export(dir, out string, opts Options) error {
+	events, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	fps := opts.FPS
+	if fps <= 0 {
+		fps = 5
+	}
+	r := &renderer{dir: dir, events: events, trail: opts.Trail}
+	if r.trail <= 0 {
+		r.trail = 3 * time.Second
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	isGIF := strings.EqualFold(filepath.Ext(out), ".gif")
+	scale := opts.Scale
+	if scale <= 0 || scale > 1 {
+		scale = 1
+		if isGIF && r.size.X > 960 {
+			scale = 960 / float64(r.size.X)
+		}
+	}
+	size := image.Pt(max(int(float64(r.size.X)*scale), 2), max(int(float64(r.size.Y)*scale), 2))
+	if !isGIF {
+		// Most video codecs need even dimensions.
+		size.X, size.Y = size.X&^1, size.Y&^1
+	}
+	r.canvas = image.NewRGBA(image.Rectangle{Max: size})
+
+	step := time.Second / time.Duration(fps)
+	end := events[len(events)-1].Time + step
+	if isGIF {
+		return r.writeGIF(out, step, end)
+	}
+	return r.writeVideo(out, fps, step, end)
+}
+
+func (r *renderer) writeGIF(out string, step, end time.Duration) error {
+	anim := &gif.GIF{}
+	delay := int(step / (10 * time.Millisecond))
+	for t := time.Duration(0); t < end; t += step {
+		if err := r.render(t); err != nil {
+			return err
+		}
+		anim.Image = append(anim.Image, webSafe(r.canvas))
+		anim.Delay = append(anim.Delay, delay)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", out, err)
+	}
+	w := bufio.NewWriter(f)
+	if err := gif.EncodeAll(w, anim); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode %s: %w", out, err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	return f.Close()
+}
+
+func (r *renderer) writeVideo(out string, fps int, step, end time.Duration) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("exporting %s needs ffmpeg; install it or export a .gif", filepath.Base(out))
+	}
+	size := r.canvas.Rect.Size()
+	cmd := exec.Command("ffmpeg", "-loglevel", "error", "-y",
+		"-f", "rawvideo", "-pix_fmt", "rgba", "-s", fmt.Sprintf("%dx%d", size.X, size.Y), "-r", fmt.Sprint(fps), "-i", "-",
+		"-pix_fmt", "yuv420p", out)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+	for t := time.Duration(0); t < end && err == nil; t += step {
+		if err = r.render(t); err == nil {
+			_, err = stdin.Write(r.canvas.Pix)
+		}
+	}
+	stdin.Close()
+	if werr := cmd.Wait(); werr != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", werr, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// renderer draws the session as it looked at a point in time.
+type renderer struct {
+	dir    string
+	events []Event
+	trail  time.Duration
+	size   image.Point // of the recorded frames
+	canvas *image.RGBA
+
+	shown  string // file name of the decoded frame
+	screen *image.RGBA
+}
+
+// open finds the frame size from the first frame.
+func (r *renderer) open() error {
+	for _, e := range r.events {
+		if e.Kind == KindFrame {
+			img, err := LoadFrame(r.dir, e.Frame)
+			if err != nil {
+				return err
+			}
+			r.size = img.Bounds().Size()
+			return nil
+		}
+	}
+	return errors.New("the session has no frames")
+}
+
+// LoadFrame decodes the frame with the given name from the session in dir.
+func LoadFrame(dir, name string) (image.Image, error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frame: %w", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return img, nil
+}
+
+// render draws the screen at time t onto r.canvas, with the events up to t.
+func (r *renderer) render(t time.Duration) error {
+	var current string
+	for _, e := range r.events {
+		if e.Time > t {
+			break
+		}
+		if e.Kind == KindFrame {
+			current = e.Frame
+		}
+	}
+	if current != r.shown {
+		img, err := LoadFrame(r.dir, current)
+		if err != nil {
+			return err
+		}
+		if r.screen == nil {
+			r.screen = image.NewRGBA(r.canvas.Rect)
+		}
+		xdraw.ApproxBiLinear.Scale(r.screen, r.screen.Rect, img, img.Bounds(), draw.Src, nil)
+		r.shown = current
+	}
+	if r.screen == nil {
+		draw.Draw(r.canvas, r.canvas.Rect, image.Black, image.Point{}, draw.Src)
+	} else {
+		copy(r.canvas.Pix, r.screen.Pix)
+	}
+
+	var path []image.Point
+	var pointer image.Point
+	var captions []string
+	moved := false
+	for _, e := range r.events {
+		if e.Time > t {
+			break
+		}
+		age := t - e.Time
+		p := r.point(e)
+		switch e.Kind {
+		case KindMove:
+			pointer, moved = p, true
+			if age <= r.trail {
+				path = append(path, p)
+			}
+		case KindClick:
+			if age <= clickShown {
+				// The ring widens as the click fades.
+				grow := float64(age) / float64(clickShown)
+				ring(r.canvas, p, 8+int(14*grow), 3, withAlpha(clickColor, 1-grow))
+				label(r.canvas, p.Add(image.Pt(14, -14)), clickLabel(e))
+			}
+		case KindPrediction:
+			if age <= predictionShown {
+				if moved {
+					line(r.canvas, pointer, p, 1, withAlpha(predictionColor, 0.6))
+				}
+				cross(r.canvas, p, 10, 2, predictionColor)
+				if e.Text != "" {
+					label(r.canvas, p.Add(image.Pt(12, 12)), e.Text)
+				}
+			}
+		case KindType, KindKey, KindNote, KindNarration, KindError:
+			if age <= captionShown {
+				captions = append(captions, caption(e))
+			}
+		}
+	}
+	for i := 1; i < len(path); i++ {
+		// Older parts of the path are fainter.
+		line(r.canvas, path[i-1], path[i], 2, withAlpha(pathColor, 0.3+0.7*float64(i)/float64(len(path))))
+	}
+	if moved {
+		disc(r.canvas, pointer, 5, pathColor)
+	}
+	if len(captions) > 3 {
+		captions = captions[len(captions)-3:]
+	}
+	subtitles(r.canvas, captions)
+	label(r.canvas, image.Pt(8, 8), clock(t))
+	return nil
+}
+
+// point converts an event's normalized coordinates to the canvas.
+func (r *renderer) point(e Event) image.Point {
+	size := r.canvas.Rect.Size()
+	return image.Pt(int(e.X*float64(size.X)), int(e.Y*float64(size.Y)))
+}
+
+var (
+	pathColor       = color.RGBA{R: 0, G: 200, B: 255, A: 255}
+	clickColor      = color.RGBA{R: 255, G: 200, B: 0, A: 255}
+	predictionColor = color.RGBA{R: 255, G: 0, B: 200, A: 255}
+)
+
+func clickLabel(e Event) string {
+	switch e.Clicks {
+	case 0, 1:
+		return e.Button + " click"
+	case 2:
+		return e.Button + " double click"
+	}
+	return fmt.Sprintf("%s click x%d", e.Button, e.Clicks)
+}
+
+func caption(e Event) string {
+	switch e.Kind {
+	case KindType:
+		return fmt.Sprintf("typed %q", e.Text)
+	case KindKey:
+		return "pressed " + e.Text
+	case KindNarration:
+		return "said: " + e.Text
+	case KindError:
+		return "failed: " + e.Text
+	}
+	return e.Text
+}
+
+func clock(t time.Duration) string {
+	return fmt.Sprintf("%02d:%04.1f", int(t.Minutes()), (t % time.Minute).Seconds())
+}
+
+// webSafe converts img to the web-safe palette. Mapping each channel
+// straight to its nearest of the palette's six levels is far faster than a
+// nearest-colour search, and screen content survives it well enough to
+// follow what happened.
+func webSafe(img *image.RGBA) *image.Paletted {
+	out := image.NewPaletted(img.Rect, palette.WebSafe)
+	for i, j := 0, 0; i < len(img.Pix); i, j = i+4, j+1 {
+		r, g, b := (int(img.Pix[i])+25)/51, (int(img.Pix[i+1])+25)/51, (int(img.Pix[i+2])+25)/51
+		out.Pix[j] = uint8(36*r + 6*g + b)
+	}
+	return out
+}
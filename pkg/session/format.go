@@ -0,0 +1,200 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Format is how a session log is encoded.
+type Format string
+
+// Session log formats. JSONL is readable and diffable; protobuf, described
+// by session.proto, is several times smaller for long, high-frequency
+// sessions, and zstd shrinks it further.
+const (
+	FormatJSONL     Format = "jsonl"
+	FormatProto     Format = "pb"
+	FormatProtoZstd Format = "pb.zst"
+)
+
+// formats lists the formats in the order a session directory is searched
+// for its log.
+var formats = []Format{FormatJSONL, FormatProto, FormatProtoZstd}
+
+// ParseFormat parses a format name: jsonl, pb (or proto, protobuf) or
+// pb.zst (or zstd). The empty string is JSONL.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "jsonl", "json":
+		return FormatJSONL, nil
+	case "pb", "proto", "protobuf":
+		return FormatProto, nil
+	case "pb.zst", "zst", "zstd":
+		return FormatProtoZstd, nil
+	}
+	return "", fmt.Errorf("unknown session format %q (want jsonl, pb or pb.zst)", s)
+}
+
+// FormatOf returns the format a log's file name implies: protobuf for .pb,
+// compressed protobuf for .pb.zst or .zst, and otherwise JSONL.
+func FormatOf(name string) Format {
+	switch {
+	case strings.HasSuffix(name, ".zst"):
+		return FormatProtoZstd
+	case strings.HasSuffix(name, ".pb"):
+		return FormatProto
+	}
+	return FormatJSONL
+}
+
+// logName returns the name of a session's log in format f.
+func (f Format) logName() string {
+	return "events." + string(f)
+}
+
+// EventEncoder writes a stream of events in one format.
+type EventEncoder interface {
+	Encode(e Event) error
+	// Close finishes the stream. It does not close the underlying writer.
+	Close() error
+}
+
+// NewEncoder returns an encoder writing events to w in format f.
+// Compressed formats run the zstd command.
+func NewEncoder(w io.Writer, f Format) (EventEncoder, error) {
+	switch f {
+	case "", FormatJSONL:
+		return jsonEncoder{json.NewEncoder(w)}, nil
+	case FormatProto:
+		return &protoEncoder{w: w}, nil
+	case FormatProtoZstd:
+		z, err := compress(w)
+		if err != nil {
+			return nil, err
+		}
+		return &protoEncoder{w: z, closer: z}, nil
+	}
+	return nil, fmt.Errorf("unknown session format %q", f)
+}
+
+type jsonEncoder struct{ enc *json.Encoder }
+
+func (e jsonEncoder) Encode(ev Event) error { return e.enc.Encode(ev) }
+func (e jsonEncoder) Close() error          { return nil }
+
+type protoEncoder struct {
+	w      io.Writer
+	closer io.Closer
+	buf    []byte
+}
+
+func (e *protoEncoder) Encode(ev Event) error {
+	e.buf = appendProto(e.buf[:0], ev)
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+func (e *protoEncoder) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
+
+// DecodeEvents reads a session log in format f from r and returns its
+// events in time order and its format version.
+func DecodeEvents(r io.Reader, f Format) ([]Event, int, error) {
+	switch f {
+	case "", FormatJSONL:
+		return ReadEvents(r)
+	case FormatProto:
+		events, err := readProto(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		return sortEvents(events)
+	case FormatProtoZstd:
+		data, err := decompress(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		events, err := readProto(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, err
+		}
+		return sortEvents(events)
+	}
+	return nil, 0, fmt.Errorf("unknown session format %q", f)
+}
+
+// EncodeEvents writes events to w as a session log in format f.
+func EncodeEvents(w io.Writer, events []Event, f Format) error {
+	enc, err := NewEncoder(w, f)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			enc.Close()
+			return fmt.Errorf("failed to write session event: %w", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to write session log: %w", err)
+	}
+	return nil
+}
+
+// errNoZstd explains a missing zstd command.
+var errNoZstd = errors.New("compressed session logs need the zstd command; install zstd or use the pb format")
+
+// zstdWriter compresses what is written to it through the zstd command.
+type zstdWriter struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func compress(w io.Writer) (*zstdWriter, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return nil, errNoZstd
+	}
+	cmd := exec.Command("zstd", "-q", "-c")
+	cmd.Stdout = w
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start zstd: %w", err)
+	}
+	return &zstdWriter{WriteCloser: stdin, cmd: cmd}, nil
+}
+
+func (z *zstdWriter) Close() error {
+	err := z.WriteCloser.Close()
+	if werr := z.cmd.Wait(); werr != nil {
+		return fmt.Errorf("zstd failed: %w", werr)
+	}
+	return err
+}
+
+func decompress(r io.Reader) ([]byte, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return nil, errNoZstd
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("zstd", "-d", "-q", "-c")
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		// What was decompressed before the error is returned with it.
+		return data, fmt.Errorf("failed to decompress session log: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return data, nil
+}
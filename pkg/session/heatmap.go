@@ -0,0 +1,226 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+)
+
+// Layers a heatmap can show.
+const (
+	HeatDwell  = "dwell"
+	HeatClicks = "clicks"
+	HeatBoth   = "both"
+)
+
+// maxDwell caps how long one pointer position counts for, so that a pointer
+// left alone while someone steps away does not drown out everything else.
+const maxDwell = 30 * time.Second
+
+// HeatmapOptions control how a heatmap is drawn.
+type HeatmapOptions struct {
+	// Layer is HeatDwell, where the pointer rested, HeatClicks, where it
+	// clicked, or HeatBoth. Empty means HeatBoth.
+	Layer string
+	// Radius is how far each position spreads, as a fraction of the image's
+	// width. Zero means 0.02.
+	Radius float64
+}
+
+// Heatmap draws where the pointer dwelt and clicked during events over
+// background. Dwell is weighted by how long the pointer stayed at each
+// position, up to maxDwell; with HeatBoth, each layer is scaled to its own
+// peak so that a few clicks still show beside minutes of dwell, and clicks
+// are also marked as dots.
+func Heatmap(events []Event, background image.Image, opts HeatmapOptions) (*image.RGBA, error) {
+	layer := opts.Layer
+	if layer == "" {
+		layer = HeatBoth
+	}
+	if layer != HeatDwell && layer != HeatClicks && layer != HeatBoth {
+		return nil, fmt.Errorf("invalid heatmap layer %q (want dwell, clicks or both)", layer)
+	}
+	radius := opts.Radius
+	if radius <= 0 {
+		radius = 0.02
+	}
+
+	b := background.Bounds()
+	// Heat is gathered on a grid a quarter of the image's size, which is
+	// plenty for blobs a few percent of the screen wide.
+	const cell = 4
+	dwell := newGrid((b.Dx()+cell-1)/cell, (b.Dy()+cell-1)/cell)
+	clicks := newGrid(dwell.w, dwell.h)
+	var clicked []Event
+	for i, e := range events {
+		switch e.Kind {
+		case KindMove:
+			end := events[len(events)-1].Time
+			for _, next := range events[i+1:] {
+				if next.Kind == KindMove {
+					end = next.Time
+					break
+				}
+			}
+			dwell.add(e.X, e.Y, min(end-e.Time, maxDwell).Seconds())
+		case KindClick:
+			clicks.add(e.X, e.Y, 1)
+			clicked = append(clicked, e)
+		}
+	}
+	if dwell.peak() == 0 && clicks.peak() == 0 {
+		return nil, errors.New("the session has no pointer positions")
+	}
+
+	sigma := radius * float64(b.Dx()) / cell
+	var heat *grid
+	switch layer {
+	case HeatDwell:
+		heat = dwell.blur(sigma).normalize()
+	case HeatClicks:
+		heat = clicks.blur(sigma).normalize()
+	default:
+		heat = dwell.blur(sigma).normalize()
+		heat.merge(clicks.blur(sigma).normalize())
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Rect, background, b.Min, draw.Src)
+	for y := range b.Dy() {
+		for x := range b.Dx() {
+			if v := heat.v[(y/cell)*heat.w+x/cell]; v > 0.02 {
+				blend(out, x, y, heatColor(v))
+			}
+		}
+	}
+	if layer == HeatBoth {
+		for _, e := range clicked {
+			p := image.Pt(int(e.X*float64(b.Dx())), int(e.Y*float64(b.Dy())))
+			disc(out, p, 3, color.RGBA{R: 255, G: 255, B: 255, A: 230})
+		}
+	}
+	return out, nil
+}
+
+// heatColor maps heat from 0 to 1 to a colour running from translucent
+// blue through green and yellow to opaque red.
+func heatColor(v float64) color.RGBA {
+	stops := []color.RGBA{{0, 0, 255, 0}, {0, 0, 255, 110}, {0, 255, 0, 150}, {255, 255, 0, 180}, {255, 0, 0, 210}}
+	f := min(v, 1) * float64(len(stops)-1)
+	i := min(int(f), len(stops)-2)
+	t := f - float64(i)
+	mix := func(a, b uint8) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*t) }
+	a, c := stops[i], stops[i+1]
+	return color.RGBA{R: mix(a.R, c.R), G: mix(a.G, c.G), B: mix(a.B, c.B), A: mix(a.A, c.A)}
+}
+
+// grid accumulates heat.
+type grid struct {
+	w, h int
+	v    []float64
+}
+
+func newGrid(w, h int) *grid {
+	return &grid{w: w, h: h, v: make([]float64, w*h)}
+}
+
+// add adds weight at normalized x, y. Positions off the image are ignored.
+func (g *grid) add(x, y, weight float64) {
+	cx, cy := int(x*float64(g.w)), int(y*float64(g.h))
+	if cx < 0 || cy < 0 || cx >= g.w || cy >= g.h {
+		return
+	}
+	g.v[cy*g.w+cx] += weight
+}
+
+func (g *grid) peak() float64 {
+	var m float64
+	for _, v := range g.v {
+		m = max(m, v)
+	}
+	return m
+}
+
+// blur returns g convolved with a Gaussian of the given deviation, in
+// cells, applied along rows and then columns.
+func (g *grid) blur(sigma float64) *grid {
+	sigma = max(sigma, 0.5)
+	r := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*r+1)
+	for i := range kernel {
+		d := float64(i - r)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+	}
+	rows := newGrid(g.w, g.h)
+	for y := range g.h {
+		for x := range g.w {
+			if v := g.v[y*g.w+x]; v != 0 {
+				for k, kv := range kernel {
+					if xx := x + k - r; xx >= 0 && xx < g.w {
+						rows.v[y*g.w+xx] += v * kv
+					}
+				}
+			}
+		}
+	}
+	out := newGrid(g.w, g.h)
+	for y := range g.h {
+		for x := range g.w {
+			if v := rows.v[y*g.w+x]; v != 0 {
+				for k, kv := range kernel {
+					if yy := y + k - r; yy >= 0 && yy < g.h {
+						out.v[yy*g.w+x] += v * kv
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// normalize scales g so its peak is 1.
+func (g *grid) normalize() *grid {
+	if m := g.peak(); m > 0 {
+		for i := range g.v {
+			g.v[i] /= m
+		}
+	}
+	return g
+}
+
+// merge keeps the hotter of g and o in each cell.
+func (g *grid) merge(o *grid) {
+	for i, v := range o.v {
+		g.v[i] = max(g.v[i], v)
+	}
+}
+
+// Representative returns the name of the frame that was on screen longest,
+// as the background for a heatmap, or "" if the session has no frames.
+func Representative(events []Event) string {
+	shown := map[string]time.Duration{}
+	var best, current string
+	var since time.Duration
+	for _, e := range events {
+		if e.Kind != KindFrame {
+			continue
+		}
+		if current != "" {
+			shown[current] += e.Time - since
+		}
+		current, since = e.Frame, e.Time
+	}
+	if current != "" && len(events) > 0 {
+		shown[current] += events[len(events)-1].Time - since
+	}
+	for name, d := range shown {
+		if best == "" || d > shown[best] || d == shown[best] && name < best {
+			best = name
+		}
+	}
+	return best
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of Event in session.proto.
+const (
+	fieldTime protowire.Number = iota + 1
+	fieldKind
+	fieldX
+	fieldY
+	fieldButton
+	fieldClicks
+	fieldApp
+	fieldText
+	fieldFrame
+	fieldVersion
+	fieldRequests
+	fieldTokens
+	fieldErrors
+	fieldCursor
+	fieldHold
+)
+
+// appendProto appends e to b as a length-prefixed Event message. Zero
+// fields are left out, as proto3 does.
+func appendProto(b []byte, e Event) []byte {
+	var m []byte
+	varint := func(n protowire.Number, v int64) {
+		if v != 0 {
+			m = protowire.AppendTag(m, n, protowire.VarintType)
+			m = protowire.AppendVarint(m, uint64(v))
+		}
+	}
+	double := func(n protowire.Number, v float64) {
+		if v != 0 {
+			m = protowire.AppendTag(m, n, protowire.Fixed64Type)
+			m = protowire.AppendFixed64(m, math.Float64bits(v))
+		}
+	}
+	str := func(n protowire.Number, v string) {
+		if v != "" {
+			m = protowire.AppendTag(m, n, protowire.BytesType)
+			m = protowire.AppendString(m, v)
+		}
+	}
+	varint(fieldTime, int64(e.Time))
+	str(fieldKind, e.Kind)
+	double(fieldX, e.X)
+	double(fieldY, e.Y)
+	str(fieldButton, e.Button)
+	varint(fieldClicks, int64(e.Clicks))
+	str(fieldApp, e.App)
+	str(fieldText, e.Text)
+	str(fieldFrame, e.Frame)
+	varint(fieldVersion, int64(e.Version))
+	varint(fieldRequests, int64(e.Requests))
+	varint(fieldTokens, e.Tokens)
+	varint(fieldErrors, int64(e.Errors))
+	str(fieldCursor, e.Cursor)
+	varint(fieldHold, int64(e.Hold))
+	return protowire.AppendBytes(b, m)
+}
+
+// readProto reads length-prefixed Event messages until r is exhausted.
+// Unknown fields are skipped, so that older readers can read logs with
+// fields added later. On an error the events before it are returned with
+// it.
+func readProto(r io.Reader) ([]Event, error) {
+	in := bufio.NewReader(r)
+	var events []Event
+	for i := 1; ; i++ {
+		size, err := binaryUvarint(in)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, fmt.Errorf("invalid session event %d: %w", i, err)
+		}
+		if size > maxEvent {
+			return events, fmt.Errorf("invalid session event %d: %d bytes long, more than %d", i, size, maxEvent)
+		}
+		m := make([]byte, size)
+		if _, err := io.ReadFull(in, m); err != nil {
+			return events, fmt.Errorf("invalid session event %d: %w", i, err)
+		}
+		e, err := parseProto(m)
+		if err != nil {
+			return events, fmt.Errorf("invalid session event %d: %w", i, err)
+		}
+		events = append(events, e)
+	}
+}
+
+func parseProto(m []byte) (Event, error) {
+	var e Event
+	for len(m) > 0 {
+		num, typ, n := protowire.ConsumeTag(m)
+		if n < 0 {
+			return e, protowire.ParseError(n)
+		}
+		m = m[n:]
+		switch {
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(m)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			m = m[n:]
+			switch num {
+			case fieldTime:
+				e.Time = time.Duration(v)
+			case fieldClicks:
+				e.Clicks = int(v)
+			case fieldVersion:
+				e.Version = int(v)
+			case fieldRequests:
+				e.Requests = int(v)
+			case fieldTokens:
+				e.Tokens = int64(v)
+			case fieldErrors:
+				e.Errors = int(v)
+			case fieldHold:
+				e.Hold = time.Duration(v)
+			}
+		case typ == protowire.Fixed64Type && (num == fieldX || num == fieldY):
+			v, n := protowire.ConsumeFixed64(m)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			m = m[n:]
+			if num == fieldX {
+				e.X = math.Float64frombits(v)
+			} else {
+				e.Y = math.Float64frombits(v)
+			}
+		case typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(m)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			m = m[n:]
+			switch num {
+			case fieldKind:
+				e.Kind = v
+			case fieldButton:
+				e.Button = v
+			case fieldApp:
+				e.App = v
+			case fieldText:
+				e.Text = v
+			case fieldFrame:
+				e.Frame = v
+			case fieldCursor:
+				e.Cursor = v
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, m)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			m = m[n:]
+		}
+	}
+	return e, nil
+}
+
+// binaryUvarint reads a varint, returning io.EOF only if r ends before it.
+func binaryUvarint(r io.ByteReader) (uint64, error) {
+	var v uint64
+	for shift := 0; shift < 64; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && shift > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("varint overflows 64 bits")
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestReadProto(t *testing.T) {
+	want := []Event{
+		{Time: time.Second, Kind: "click", X: 0.25, Y: 0.5, Button: "left", Clicks: 2},
+		{Time: 2 * time.Second, Kind: "type", Text: "hello"},
+	}
+	var b []byte
+	for _, e := range want {
+		b = appendProto(b, e)
+	}
+	got, err := readProto(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadProtoRefusesHugeEvents(t *testing.T) {
+	// A corrupt size prefix claims an event of nearly 2^64 bytes.
+	b := appendProto(nil, Event{Kind: "click"})
+	b = protowire.AppendVarint(b, 1<<63)
+	events, err := readProto(bytes.NewReader(b))
+	if err == nil || !strings.Contains(err.Error(), "invalid session event 2") {
+		t.Errorf("readProto returned %v, want an error for event 2", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("read %d events before the error, want 1", len(events))
+	}
+}
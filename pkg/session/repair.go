@@ -0,0 +1,149 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// markerName is the file that marks a session as still being recorded. A
+// Writer touches it every syncInterval and removes it on Close, so one
+// left behind and not touched for a while belongs to a recorder that
+// crashed.
+const markerName = ".recording"
+
+// staleAfter is how long the marker must go untouched before Repair
+// believes the recorder is gone.
+const staleAfter = 10 * syncInterval
+
+// Interrupted reports whether the session in dir was not closed: it is
+// still being recorded, or its recorder crashed.
+func Interrupted(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, markerName))
+	return err == nil
+}
+
+// ErrRecording is returned by Repair for sessions still being recorded.
+var ErrRecording = errors.New("the session is still being recorded")
+
+// Repaired is what Repair did.
+type Repaired struct {
+	// Interrupted is set if the session was not closed.
+	Interrupted bool
+	// Events is the number of events kept.
+	Events int
+	// Dropped is the number of events dropped: a record torn by the crash
+	// and the frames whose image was not completely written.
+	Dropped int
+}
+
+// Repair finalizes the session in dir after its recorder crashed: the
+// events written before the crash are kept, a record torn by it and frames
+// whose image was cut short are dropped, a note marks where the recording
+// stopped, and the marker is removed. The original log is kept alongside
+// with a .bak suffix. A session still being recorded is refused with
+// ErrRecording unless force is set, and one that was closed properly is
+// left alone.
+func Repair(dir string, force bool) (Repaired, error) {
+	var r Repaired
+	marker := filepath.Join(dir, markerName)
+	info, err := os.Stat(marker)
+	if err != nil {
+		if _, _, err := read(dir); err != nil {
+			return r, err
+		}
+		return r, nil
+	}
+	r.Interrupted = true
+	if age := time.Since(info.ModTime()); age < staleAfter && !force {
+		return r, fmt.Errorf("%w (last written %v ago)", ErrRecording, age.Round(time.Second))
+	}
+
+	path, format, err := logPath(dir)
+	if err != nil {
+		return r, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, fmt.Errorf("failed to read session log: %w", err)
+	}
+	events, dropped := salvage(data, format)
+	r.Dropped = dropped
+
+	kept := events[:0]
+	for _, e := range events {
+		if e.Kind == KindFrame && !completePNG(filepath.Join(dir, e.Frame)) {
+			r.Dropped++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	var end time.Duration
+	for _, e := range kept {
+		end = max(end, e.Time)
+	}
+	kept = append(kept, Event{Time: end + 1, Kind: KindNote, Text: "recording interrupted; repaired by agentgo sessions repair"})
+	r.Events = len(kept)
+
+	var buf bytes.Buffer
+	if err := EncodeEvents(&buf, kept, format); err != nil {
+		return r, err
+	}
+	if err := os.Rename(path, path+".bak"); err != nil {
+		return r, fmt.Errorf("failed to back up session log: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return r, fmt.Errorf("failed to write session log: %w", err)
+	}
+	if err := os.Remove(marker); err != nil {
+		return r, fmt.Errorf("failed to finalize session: %w", err)
+	}
+	return r, nil
+}
+
+// salvage decodes the events of a log that may be cut short, returning
+// those that can be read and how many records were lost.
+func salvage(data []byte, format Format) ([]Event, int) {
+	switch format {
+	case FormatProto, FormatProtoZstd:
+		if format == FormatProtoZstd {
+			// Whatever zstd can still decompress.
+			data, _ = decompress(bytes.NewReader(data))
+		}
+		events, err := readProto(bytes.NewReader(data))
+		if err != nil {
+			return events, 1
+		}
+		return events, 0
+	}
+	var events []Event
+	dropped := 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			dropped++
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, dropped
+}
+
+// completePNG reports whether path holds a PNG that decodes to the end.
+func completePNG(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	_, err = png.Decode(f)
+	return err == nil
+}
@@ -0,0 +1,435 @@
+// Package session records what happened on the screen during a run, so that
+// it can be reviewed afterwards: frames of the screen, and between them the
+// pointer's moves, clicks, typing and the model's answers. A session is a
+// directory of PNG frames and an events.jsonl log, or for long sessions a
+// more compact events.pb or events.pb.zst (see Format); Export renders it as
+// a video with the events drawn over the frames.
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"agentGo/pkg/frame"
+)
+
+// Version is the session format this package writes. Sessions start with a
+// KindStart event carrying it; those recorded before it was stamped are
+// version 0, which is otherwise the same.
+const Version = 1
+
+// Kinds of event.
+const (
+	KindStart      = "start"
+	KindFrame      = "frame"
+	KindMove       = "move"
+	KindClick      = "click"
+	KindType       = "type"
+	KindKey        = "key"
+	KindPrediction = "prediction"
+	KindNote       = "note"
+	KindNarration  = "narration"
+	KindModel      = "model"
+	KindError      = "error"
+)
+
+// Event is one entry of a session's log.
+type Event struct {
+	// Time is the offset from the start of the session.
+	Time time.Duration `json:"t"`
+	Kind string        `json:"kind"`
+	// X and Y are normalized (0-1) screen coordinates: where the pointer
+	// moved or clicked, or where the model answered.
+	X float64 `json:"x,omitempty"`
+	Y float64 `json:"y,omitempty"`
+	// Cursor is the pointer's shape after a move, if it was recorded.
+	Cursor string `json:"cursor,omitempty"`
+	// Button, Clicks and Hold describe a click.
+	Button string        `json:"button,omitempty"`
+	Clicks int           `json:"clicks,omitempty"`
+	Hold   time.Duration `json:"hold,omitempty"`
+	// App is the title of the window in front when a click was made.
+	App string `json:"app,omitempty"`
+	// Text is what was typed, the key tapped, what the model was asked to
+	// find, a note, what the person recording said or an error.
+	Text string `json:"text,omitempty"`
+	// Requests, Tokens and Errors total the model calls made.
+	Requests int   `json:"requests,omitempty"`
+	Tokens   int64 `json:"tokens,omitempty"`
+	Errors   int   `json:"errors,omitempty"`
+	// Frame is the file name of a frame, relative to the session directory.
+	Frame string `json:"frame,omitempty"`
+	// Version is the session's format version, on its KindStart event.
+	Version int `json:"version,omitempty"`
+}
+
+// syncInterval is how often a Writer flushes its log to disk, so that a
+// crash loses at most this much of the session.
+const syncInterval = time.Second
+
+// Writer records a session. Recording is best effort: a failure to write
+// does not interrupt the run, but is returned by Close. A nil Writer records
+// nothing, so callers need not check whether recording is on.
+//
+// The log is flushed and synced to disk every second, and the session
+// directory holds a marker until Close, so that the session of a recorder
+// that crashed is readable up to the last sync once Repair has finalized
+// it.
+type Writer struct {
+	// App, if set, returns the title of the window in front, which is
+	// recorded with each click.
+	App func() string
+	// Scrub, if set, removes secrets from the text of every event and the
+	// window titles before they are written.
+	Scrub func(string) string
+
+	dir   string
+	start time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	buf    *bufio.Writer
+	enc    EventEncoder
+	frames int
+	x, y   float64
+	err    error
+	done   chan struct{}
+}
+
+// Create starts a session in dir, creating it if needed, with its log in
+// format f (JSONL if empty). A session already in dir is replaced.
+func Create(dir string, f Format) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if f == "" {
+		f = FormatJSONL
+	}
+	// Remove logs in other formats, which would otherwise be read instead.
+	for _, other := range formats {
+		if other != f {
+			os.Remove(filepath.Join(dir, other.logName()))
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, markerName), []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	file, err := os.Create(filepath.Join(dir, f.logName()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session log: %w", err)
+	}
+	buf := bufio.NewWriter(file)
+	// zstd writes to the file from its own goroutine, so its output
+	// cannot share the buffer the sync loop flushes; it buffers anyway.
+	var out io.Writer = buf
+	if f == FormatProtoZstd {
+		out = file
+	}
+	enc, err := NewEncoder(out, f)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w := &Writer{dir: dir, start: time.Now(), file: file, buf: buf, enc: enc, done: make(chan struct{})}
+	w.record(Event{Kind: KindStart, Version: Version})
+	go w.syncLoop()
+	return w, nil
+}
+
+// syncLoop flushes the log to disk every syncInterval until Close, and
+// touches the marker to show that the session is still being recorded.
+func (w *Writer) syncLoop() {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	marker := filepath.Join(w.dir, markerName)
+	for {
+		select {
+		case <-w.done:
+			return
+		case now := <-ticker.C:
+			w.mu.Lock()
+			w.sync()
+			w.mu.Unlock()
+			os.Chtimes(marker, now, now)
+		}
+	}
+}
+
+// sync writes what is buffered to the log and the log to disk. w.mu must
+// be held.
+func (w *Writer) sync() {
+	if err := w.buf.Flush(); err != nil {
+		w.fail(fmt.Errorf("failed to write session log: %w", err))
+		return
+	}
+	if err := w.file.Sync(); err != nil {
+		w.fail(fmt.Errorf("failed to sync session log: %w", err))
+	}
+}
+
+// Frame saves img as the screen from now on.
+func (w *Writer) Frame(img image.Image) {
+	if w == nil {
+		return
+	}
+	t := time.Since(w.start)
+	data, err := frame.EncodePNG(img)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	name := fmt.Sprintf("frame-%05d.png", w.frames)
+	w.frames++
+	if err := os.WriteFile(filepath.Join(w.dir, name), data, 0o644); err != nil {
+		w.fail(fmt.Errorf("failed to write frame: %w", err))
+		return
+	}
+	w.log(Event{Time: t, Kind: KindFrame, Frame: name})
+}
+
+// Move records the pointer moving to normalized x, y.
+func (w *Writer) Move(x, y float64) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.x, w.y = x, y
+	w.log(Event{Kind: KindMove, X: x, Y: y})
+}
+
+// Click records a click where the pointer last moved.
+func (w *Writer) Click(button string, clicks int) {
+	if w == nil {
+		return
+	}
+	var app string
+	if w.App != nil {
+		app = w.App()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.log(Event{Kind: KindClick, X: w.x, Y: w.y, Button: button, Clicks: max(clicks, 1), App: app})
+}
+
+// Type records typed text.
+func (w *Writer) Type(text string) {
+	w.record(Event{Kind: KindType, Text: text})
+}
+
+// Key records a key tap, such as "enter" or "ctrl+s".
+func (w *Writer) Key(key string) {
+	w.record(Event{Kind: KindKey, Text: key})
+}
+
+// Predict records the model answering that target is at normalized x, y.
+func (w *Writer) Predict(x, y float64, target string) {
+	w.record(Event{Kind: KindPrediction, X: x, Y: y, Text: target})
+}
+
+// Note records a caption, such as the step being run.
+func (w *Writer) Note(text string) {
+	w.record(Event{Kind: KindNote, Text: text})
+}
+
+// Narrate records what the person recording said, transcribed, at the
+// time they started saying it. Transcripts arrive after the events around
+// them, which readers sort back into place.
+func (w *Writer) Narrate(start time.Time, text string) {
+	if w == nil {
+		return
+	}
+	t := max(start.Sub(w.start), 1)
+	w.record(Event{Time: t, Kind: KindNarration, Text: text})
+}
+
+// Model records the model calls made, typically totalled at the end of a
+// run.
+func (w *Writer) Model(requests int, tokens int64, errors int) {
+	w.record(Event{Kind: KindModel, Requests: requests, Tokens: tokens, Errors: errors})
+}
+
+// Error records what made the run fail.
+func (w *Writer) Error(text string) {
+	w.record(Event{Kind: KindError, Text: text})
+}
+
+func (w *Writer) record(e Event) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.log(e)
+}
+
+// log appends e, stamped with the time if it has none. w.mu must be held.
+func (w *Writer) log(e Event) {
+	if e.Time == 0 {
+		e.Time = time.Since(w.start)
+	}
+	if w.Scrub != nil {
+		e.Text, e.App = w.Scrub(e.Text), w.Scrub(e.App)
+	}
+	if err := w.enc.Encode(e); err != nil {
+		w.fail(fmt.Errorf("failed to write session log: %w", err))
+	}
+}
+
+func (w *Writer) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// Close finishes the session, returning the first error recording it.
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	close(w.done)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.enc.Close(); err != nil {
+		w.fail(fmt.Errorf("failed to write session log: %w", err))
+	}
+	w.sync()
+	if err := w.file.Close(); err != nil {
+		w.fail(fmt.Errorf("failed to write session log: %w", err))
+	}
+	if w.err == nil {
+		os.Remove(filepath.Join(w.dir, markerName))
+	}
+	return w.err
+}
+
+// Load reads the events of the session in dir, in time order.
+func Load(dir string) ([]Event, error) {
+	events, _, err := read(dir)
+	return events, err
+}
+
+// read loads the events of the session in dir and its format version.
+func read(dir string) ([]Event, int, error) {
+	path, format, err := logPath(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open session: %w", err)
+	}
+	defer f.Close()
+	events, version, err := DecodeEvents(f, format)
+	if err != nil && Interrupted(dir) {
+		err = fmt.Errorf("%w; the recording was interrupted, finalize it with agentgo sessions repair %s", err, dir)
+	}
+	return events, version, err
+}
+
+// logPath finds the log of the session in dir, in whichever format it was
+// written.
+func logPath(dir string) (string, Format, error) {
+	for _, f := range formats {
+		path := filepath.Join(dir, f.logName())
+		if _, err := os.Stat(path); err == nil {
+			return path, f, nil
+		}
+	}
+	return "", "", fmt.Errorf("failed to open session: no %s in %s", FormatJSONL.logName(), dir)
+}
+
+// maxEvent is the largest encoded event the readers accept, so that a
+// corrupt log cannot make them allocate without bound.
+const maxEvent = 1 << 20
+
+// ReadEvents reads a session log, one JSON event per line, from r and
+// returns its events in time order and its format version.
+func ReadEvents(r io.Reader) ([]Event, int, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxEvent)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, 0, fmt.Errorf("invalid session event on line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read session: %w", err)
+	}
+	return sortEvents(events)
+}
+
+// sortEvents puts events in time order and checks their format version.
+func sortEvents(events []Event) ([]Event, int, error) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time < events[j].Time })
+	version := 0
+	if len(events) > 0 && events[0].Kind == KindStart {
+		version = events[0].Version
+	}
+	if version > Version {
+		return nil, 0, fmt.Errorf("session format v%d is newer than this version of agentgo reads (v%d); upgrade agentgo", version, Version)
+	}
+	return events, version, nil
+}
+
+// FormatVersion returns the format version of the session in dir.
+func FormatVersion(dir string) (int, error) {
+	_, version, err := read(dir)
+	return version, err
+}
+
+// Migrate upgrades the session in dir to the current format, keeping the
+// old log alongside with a .bak suffix, and returns the version it was. The
+// log stays in the encoding it was written in.
+func Migrate(dir string) (int, error) {
+	events, version, err := read(dir)
+	if err != nil || version == Version {
+		return version, err
+	}
+	// Version 0 only lacks the start event.
+	events = append([]Event{{Kind: KindStart, Version: Version}}, events...)
+	path, format, err := logPath(dir)
+	if err != nil {
+		return version, err
+	}
+	if err := os.Rename(path, path+".bak"); err != nil {
+		return version, fmt.Errorf("failed to back up session log: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeEvents(&buf, events, format); err != nil {
+		return version, err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return version, fmt.Errorf("failed to write session log: %w", err)
+	}
+	return version, nil
+}
+
+// WriteEvents writes events to w as a session log, one JSON event per line.
+func WriteEvents(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to write session event: %w", err)
+		}
+	}
+	return nil
+}
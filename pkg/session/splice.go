@@ -0,0 +1,169 @@
+package session
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Open loads the events of the session in dir, as Load does, with frame
+// names resolved to paths, so that events from several sessions can be
+// combined and saved with Save.
+func Open(dir string) ([]Event, error) {
+	events, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		if events[i].Frame != "" {
+			events[i].Frame = filepath.Join(dir, events[i].Frame)
+		}
+	}
+	return events, nil
+}
+
+// LogFormat returns the format of the log of the session in dir.
+func LogFormat(dir string) (Format, error) {
+	_, f, err := logPath(dir)
+	return f, err
+}
+
+// Concat joins sessions end to end: each starts when the one before it
+// ended, and the result has a single start event.
+func Concat(parts ...[]Event) []Event {
+	events := []Event{{Kind: KindStart, Version: Version}}
+	var offset time.Duration
+	for _, part := range parts {
+		var end time.Duration
+		for _, e := range part {
+			if e.Kind == KindStart {
+				continue
+			}
+			end = max(end, e.Time)
+			e.Time += offset
+			events = append(events, e)
+		}
+		offset += end
+	}
+	return events
+}
+
+// Split cuts events, in time order, at the given offsets, which must be
+// increasing, into len(at)+1 sessions, each starting at zero. Each later
+// part begins with the screen and pointer position it was cut at, so that
+// it plays and renders on its own.
+func Split(events []Event, at ...time.Duration) ([][]Event, error) {
+	var end time.Duration
+	if len(events) > 0 {
+		end = events[len(events)-1].Time
+	}
+	for i, t := range at {
+		if t <= 0 || i > 0 && t <= at[i-1] {
+			return nil, errors.New("split points must be positive and increasing")
+		}
+		if t >= end {
+			return nil, fmt.Errorf("split point %v is past the end of the session (%v)", t, end)
+		}
+	}
+
+	parts := make([][]Event, 0, len(at)+1)
+	var frame, move *Event
+	var from time.Duration
+	i := 0
+	for n := 0; n <= len(at); n++ {
+		part := []Event{{Kind: KindStart, Version: Version}}
+		if frame != nil {
+			part = append(part, Event{Kind: KindFrame, Frame: frame.Frame})
+		}
+		if move != nil {
+			part = append(part, Event{Kind: KindMove, X: move.X, Y: move.Y, Cursor: move.Cursor})
+		}
+		for ; i < len(events) && (n == len(at) || events[i].Time < at[n]); i++ {
+			e := events[i]
+			switch e.Kind {
+			case KindStart:
+				continue
+			case KindFrame:
+				frame = &events[i]
+			case KindMove:
+				move = &events[i]
+			}
+			e.Time -= from
+			part = append(part, e)
+		}
+		parts = append(parts, part)
+		if n < len(at) {
+			from = at[n]
+		}
+	}
+	return parts, nil
+}
+
+// Save writes events as a session in dir with its log in format f,
+// copying the frames they name, which are paths as Open returns, and
+// numbering them afresh.
+func Save(dir string, events []Event, f Format) error {
+	if f == "" {
+		f = FormatJSONL
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	out := []Event{{Kind: KindStart, Version: Version}}
+	copied := make(map[string]string)
+	for _, e := range events {
+		if e.Kind == KindStart {
+			continue
+		}
+		if e.Frame != "" {
+			name, ok := copied[e.Frame]
+			if !ok {
+				name = fmt.Sprintf("frame-%05d.png", len(copied))
+				if err := copyFile(filepath.Join(dir, name), e.Frame); err != nil {
+					return err
+				}
+				copied[e.Frame] = name
+			}
+			e.Frame = name
+		}
+		out = append(out, e)
+	}
+	for _, other := range formats {
+		os.Remove(filepath.Join(dir, other.logName()))
+	}
+	file, err := os.Create(filepath.Join(dir, f.logName()))
+	if err != nil {
+		return fmt.Errorf("failed to create session log: %w", err)
+	}
+	buf := bufio.NewWriter(file)
+	if err := EncodeEvents(buf, out, f); err != nil {
+		file.Close()
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write session log: %w", err)
+	}
+	return file.Close()
+}
+
+func copyFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open frame: %w", err)
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to write frame: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write frame: %w", err)
+	}
+	return out.Close()
+}
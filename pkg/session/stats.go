@@ -0,0 +1,112 @@
+package session
+
+import (
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Stats summarizes one or more sessions.
+type Stats struct {
+	Sessions int
+	Duration time.Duration
+	// Events counts events by kind.
+	Events map[string]int
+	// Distance is how far the pointer travelled, in screen widths.
+	Distance float64
+	// ClicksByApp counts clicks by the title of the window in front; ""
+	// collects clicks made where the title was not recorded.
+	ClicksByApp map[string]int
+	// ModelRequests, ModelTokens and ModelErrors total the model calls.
+	ModelRequests int
+	ModelTokens   int64
+	ModelErrors   int
+	// Steps counts the script steps run and Failed the sessions that ended
+	// in an error.
+	Steps  int
+	Failed int
+}
+
+// Summarize returns the statistics of the session in dir.
+func Summarize(dir string) (Stats, error) {
+	events, err := Load(dir)
+	if err != nil {
+		return Stats{}, err
+	}
+	s := Stats{Sessions: 1, Events: map[string]int{}, ClicksByApp: map[string]int{}}
+	if len(events) > 0 {
+		s.Duration = events[len(events)-1].Time
+	}
+
+	// Vertical moves are scaled by the screen's shape, taken from the first
+	// frame, or as 16:9 if the session has none.
+	aspect := 9.0 / 16
+	if name := firstFrame(events); name != "" {
+		if f, err := os.Open(filepath.Join(dir, name)); err == nil {
+			if cfg, err := png.DecodeConfig(f); err == nil && cfg.Width > 0 {
+				aspect = float64(cfg.Height) / float64(cfg.Width)
+			}
+			f.Close()
+		}
+	}
+
+	var last *Event
+	for i, e := range events {
+		if e.Kind == KindStart {
+			continue
+		}
+		s.Events[e.Kind]++
+		switch e.Kind {
+		case KindMove, KindClick:
+			if last != nil {
+				s.Distance += math.Hypot(e.X-last.X, (e.Y-last.Y)*aspect)
+			}
+			last = &events[i]
+			if e.Kind == KindClick {
+				s.ClicksByApp[e.App]++
+			}
+		case KindModel:
+			s.ModelRequests += e.Requests
+			s.ModelTokens += e.Tokens
+			s.ModelErrors += e.Errors
+		case KindNote:
+			// Script runs note each step as it starts.
+			s.Steps++
+		case KindError:
+			s.Failed++
+		}
+	}
+	return s, nil
+}
+
+func firstFrame(events []Event) string {
+	for _, e := range events {
+		if e.Kind == KindFrame {
+			return e.Frame
+		}
+	}
+	return ""
+}
+
+// Add adds o's counts to s.
+func (s *Stats) Add(o Stats) {
+	if s.Events == nil {
+		s.Events, s.ClicksByApp = map[string]int{}, map[string]int{}
+	}
+	s.Sessions += o.Sessions
+	s.Duration += o.Duration
+	for kind, n := range o.Events {
+		s.Events[kind] += n
+	}
+	s.Distance += o.Distance
+	for app, n := range o.ClicksByApp {
+		s.ClicksByApp[app] += n
+	}
+	s.ModelRequests += o.ModelRequests
+	s.ModelTokens += o.ModelTokens
+	s.ModelErrors += o.ModelErrors
+	s.Steps += o.Steps
+	s.Failed += o.Failed
+}
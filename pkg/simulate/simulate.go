@@ -0,0 +1,165 @@
+// Package simulate evaluates an agent's planning offline. The agent, a
+// model calling the desktop tools of package tools, is shown saved
+// screenshots in place of the screen, and the actions it proposes are
+// recorded instead of performed, so the same task can be replayed safely
+// and compared across models and prompts.
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"agentGo/pkg/fake"
+	"agentGo/pkg/frame"
+	"agentGo/pkg/tools"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Chat is a conversation with a function-calling model, as
+// genai.ChatSession is.
+type Chat interface {
+	SendMessage(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
+}
+
+// Instruction is the system instruction that sets the model up as the
+// agent.
+const Instruction = "You operate a computer through the tools you are given, to carry out the user's task. " +
+	"Each message shows the current screen. Take one step at a time with the tools. " +
+	"When the task is done, or cannot be done, stop calling tools and say so in one sentence."
+
+// Step is a tool call the agent proposed.
+type Step struct {
+	// Turn counts the model's responses from 1.
+	Turn int `json:"turn"`
+	// Frame is the index of the saved screenshot on screen when the call
+	// was made.
+	Frame  int             `json:"frame"`
+	Tool   string          `json:"tool"`
+	Args   json.RawMessage `json:"args,omitempty"`
+	Result string          `json:"result,omitempty"`
+	Error  bool            `json:"error,omitempty"`
+}
+
+// Result is the outcome of a simulated run.
+type Result struct {
+	Task  string `json:"task"`
+	Steps []Step `json:"steps"`
+	// Done reports that the agent stopped calling tools by itself, and
+	// Answer is what it said then.
+	Done   bool   `json:"done"`
+	Answer string `json:"answer,omitempty"`
+	Turns  int    `json:"turns"`
+}
+
+// Simulator runs an agent against a fake desktop.
+type Simulator struct {
+	// Chat is the agent, set up with tools.GeminiTool and Instruction, as
+	// Configure does.
+	Chat Chat
+	// Desktop serves the screenshots and records the actions. It should
+	// step on input, so that each action brings up the next screenshot.
+	Desktop *fake.Desktop
+	// Vision, if set, lets the agent find and click elements by
+	// description, on the saved screenshots.
+	Vision tools.Locator
+	// MaxTurns caps the model's responses. The default is 30.
+	MaxTurns int
+	Logf     func(format string, args ...any)
+}
+
+// Configure sets m up as an agent for Simulator: with the desktop tools,
+// Instruction and, for repeatable runs, a temperature of 0.
+func Configure(m *genai.GenerativeModel) {
+	m.Tools = []*genai.Tool{tools.GeminiTool()}
+	m.SystemInstruction = genai.NewUserContent(genai.Text(Instruction))
+	m.SetTemperature(0)
+}
+
+// Run gives the agent task, with the first screenshot, and answers its tool
+// calls until it stops calling them, it runs out of turns, or ctx ends.
+func (s *Simulator) Run(ctx context.Context, task string) (*Result, error) {
+	maxTurns := s.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = 30
+	}
+	// Calls are logged below, with the turn and frame.
+	d := &tools.Dispatcher{Desktop: s.Desktop, Vision: s.Vision, Logf: func(string, ...any) {}}
+	img, err := s.Desktop.Capture()
+	if err != nil {
+		return nil, err
+	}
+	data, err := frame.EncodePNG(img)
+	frame.Put(img)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Task: task}
+	parts := []genai.Part{genai.Text("Task: " + task), genai.ImageData("png", data)}
+	for res.Turns < maxTurns {
+		resp, err := s.Chat.SendMessage(ctx, parts...)
+		if err != nil {
+			return res, fmt.Errorf("turn %d: %w", res.Turns+1, err)
+		}
+		res.Turns++
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			return res, fmt.Errorf("turn %d: the model gave no answer", res.Turns)
+		}
+		calls := resp.Candidates[0].FunctionCalls()
+		if len(calls) == 0 {
+			res.Done = true
+			res.Answer = text(resp.Candidates[0].Content)
+			return res, nil
+		}
+		parts = parts[:0]
+		for _, call := range calls {
+			step := Step{Turn: res.Turns, Frame: s.Desktop.Shown(), Tool: call.Name}
+			step.Args, _ = json.Marshal(call.Args)
+			out := d.DispatchGemini(ctx, call)
+			if r, ok := out[0].(genai.FunctionResponse); ok {
+				if msg, failed := r.Response["error"]; failed {
+					step.Error, step.Result = true, fmt.Sprint(msg)
+				} else {
+					step.Result = fmt.Sprint(r.Response["output"])
+				}
+			}
+			s.logf("turn %d, frame %d: %s %s", step.Turn, step.Frame, step.Tool, step.Args)
+			res.Steps = append(res.Steps, step)
+			parts = append(parts, out...)
+		}
+		// Show the screen as the actions left it.
+		img, err := s.Desktop.Capture()
+		if err != nil {
+			return res, err
+		}
+		data, err := frame.EncodePNG(img)
+		frame.Put(img)
+		if err != nil {
+			return res, err
+		}
+		parts = append(parts, genai.Text("The screen now:"), genai.ImageData("png", data))
+	}
+	return res, errors.New("the agent did not finish within the turn limit")
+}
+
+func text(c *genai.Content) string {
+	var b strings.Builder
+	for _, p := range c.Parts {
+		if t, ok := p.(genai.Text); ok {
+			b.WriteString(string(t))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func (s *Simulator) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,88 @@
+package speech
+
+import (
+	"context"
+	"sync"
+)
+
+// Queue speaks announcements in the background, so that whoever makes
+// them need not wait. When announcements come faster than they can be
+// spoken, it skips to the latest, which is the one that matters.
+type Queue struct {
+	// Logf receives the first failure to speak; the queue stays quiet
+	// after it.
+	Logf func(format string, args ...any)
+
+	mu      sync.Mutex
+	next    string
+	pending bool
+	closed  bool
+	failed  bool
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+// NewQueue starts a queue. Close it to speak what is still pending.
+func NewQueue(logf func(format string, args ...any)) *Queue {
+	q := &Queue{Logf: logf, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go q.loop()
+	return q
+}
+
+// Say speaks text after the announcement being spoken, in place of any
+// that is still waiting.
+func (q *Queue) Say(text string) {
+	q.mu.Lock()
+	if q.closed || q.failed {
+		q.mu.Unlock()
+		return
+	}
+	q.next, q.pending = text, true
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close waits until the pending announcement has been spoken, or ctx is
+// done.
+func (q *Queue) Close(ctx context.Context) {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	select {
+	case <-q.done:
+	case <-ctx.Done():
+	}
+}
+
+func (q *Queue) loop() {
+	defer close(q.done)
+	for range q.wake {
+		for {
+			q.mu.Lock()
+			text, pending, closed := q.next, q.pending, q.closed
+			q.pending = false
+			q.mu.Unlock()
+			if !pending {
+				if closed {
+					return
+				}
+				break
+			}
+			if err := Say(context.Background(), text); err != nil {
+				q.mu.Lock()
+				q.failed = true
+				q.mu.Unlock()
+				if q.Logf != nil {
+					q.Logf("failed to speak: %v", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package speech reads text aloud with the operating system's speech
+// synthesizer: say on macOS, spd-say or espeak on Linux and the .NET
+// SpeechSynthesizer through PowerShell on Windows.
+package speech
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnavailable is returned when no speech synthesizer is installed.
+var ErrUnavailable = errors.New("no speech synthesizer available")
+
+// Say speaks text and returns when it has been spoken or ctx is done.
+func Say(ctx context.Context, text string) error {
+	if text == "" {
+		return nil
+	}
+	return say(ctx, text)
+}
@@ -0,0 +1,14 @@
+package speech
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+func say(ctx context.Context, text string) error {
+	if err := exec.CommandContext(ctx, "say", text).Run(); err != nil {
+		return fmt.Errorf("say failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package speech
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+func say(ctx context.Context, text string) error {
+	// spd-say goes through speech-dispatcher, which desktops usually run;
+	// -w waits until the text has been spoken.
+	if path, err := exec.LookPath("spd-say"); err == nil {
+		if err := exec.CommandContext(ctx, path, "-w", text).Run(); err != nil {
+			return fmt.Errorf("spd-say failed: %w", err)
+		}
+		return nil
+	}
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if path, err := exec.LookPath(name); err == nil {
+			if err := exec.CommandContext(ctx, path, text).Run(); err != nil {
+				return fmt.Errorf("%s failed: %w", name, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: install speech-dispatcher or espeak-ng", ErrUnavailable)
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !linux && !windows
+
+package speech
+
+import "context"
+
+func say(ctx context.Context, text string) error {
+	return ErrUnavailable
+}
@@ -0,0 +1,27 @@
+package speech
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+const script = `Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())`
+
+func say(ctx context.Context, text string) error {
+	// The text goes through standard input so it needs no quoting.
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start powershell: %w", err)
+	}
+	stdin.Write([]byte(text))
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("speech failed: %w", err)
+	}
+	return nil
+}
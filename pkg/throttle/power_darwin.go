@@ -0,0 +1,31 @@
+package throttle
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var speedLimit = regexp.MustCompile(`CPU_Speed_Limit\s*=\s*(\d+)`)
+
+// readState asks pmset for the power source and for the CPU speed limit,
+// which drops below 100 while macOS throttles the CPU to cool it. macOS
+// does not report the CPU temperature without extra privileges.
+func readState() (State, error) {
+	var s State
+	out, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return s, fmt.Errorf("failed to read the power source: %w", err)
+	}
+	s.OnBattery = strings.Contains(string(out), "'Battery Power'")
+	if out, err := exec.Command("pmset", "-g", "therm").Output(); err == nil {
+		if m := speedLimit.FindSubmatch(out); m != nil {
+			if limit, err := strconv.Atoi(string(m[1])); err == nil && limit < 100 {
+				s.Limited = true
+			}
+		}
+	}
+	return s, nil
+}
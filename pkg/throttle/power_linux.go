@@ -0,0 +1,37 @@
+package throttle
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readState reads the power supplies and thermal zones that sysfs exposes.
+// Machines without a battery are never on battery power.
+func readState() (State, error) {
+	var s State
+	supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+	for _, dir := range supplies {
+		if readFile(filepath.Join(dir, "type")) == "Battery" && readFile(filepath.Join(dir, "status")) == "Discharging" {
+			s.OnBattery = true
+		}
+	}
+	zones, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+	for _, dir := range zones {
+		milli, err := strconv.Atoi(readFile(filepath.Join(dir, "temp")))
+		if err != nil {
+			continue
+		}
+		s.Temperature = max(s.Temperature, float64(milli)/1000)
+	}
+	return s, nil
+}
+
+func readFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
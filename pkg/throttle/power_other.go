@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package throttle
+
+func readState() (State, error) {
+	return State{}, errUnsupported
+}
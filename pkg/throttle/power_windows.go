@@ -0,0 +1,29 @@
+package throttle
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var procGetSystemPowerStatus = syscall.NewLazyDLL("kernel32.dll").NewProc("GetSystemPowerStatus")
+
+type systemPowerStatus struct {
+	ACLineStatus        uint8
+	BatteryFlag         uint8
+	BatteryLifePercent  uint8
+	SystemStatusFlag    uint8
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// readState reads the power source. Windows does not report the CPU
+// temperature without WMI and administrator rights.
+func readState() (State, error) {
+	var status systemPowerStatus
+	if ok, _, err := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return State{}, fmt.Errorf("failed to read the power status: %w", err)
+	}
+	// ACLineStatus is 0 offline, 1 online and 255 unknown.
+	return State{OnBattery: status.ACLineStatus == 0}, nil
+}
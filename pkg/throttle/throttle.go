@@ -0,0 +1,240 @@
+// Package throttle eases off periodic screen capture when the machine is on
+// battery power or running hot, so that long recordings and streams do not
+// drain a laptop or drive it into thermal throttling: frames are taken less
+// often and at a lower resolution until the machine is plugged in or cools.
+//
+// Policies are written as "on" for the defaults, "off", or comma-separated
+// settings, e.g. "battery-slowdown=3,battery-scale=0.5,max-temp=80".
+package throttle
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"agentGo/pkg/frame"
+)
+
+// Policy says how much to ease off.
+type Policy struct {
+	// BatterySlowdown stretches capture intervals on battery power: 2
+	// halves the frame rate. BatteryScale scales frames, from 0 to 1.
+	BatterySlowdown float64
+	BatteryScale    float64
+	// HotSlowdown and HotScale apply while the machine is hot: the CPU is
+	// at MaxTemp degrees Celsius or more, or the OS reports it is limiting
+	// the CPU's speed to cool it.
+	HotSlowdown float64
+	HotScale    float64
+	MaxTemp     float64
+}
+
+// Default is the policy "on" stands for, and the one used when none is set.
+var Default = Policy{BatterySlowdown: 2, BatteryScale: 0.75, HotSlowdown: 4, HotScale: 0.5, MaxTemp: 85}
+
+// Parse reads a policy. An empty string or "on" is Default; "off" returns
+// nil.
+func Parse(s string) (*Policy, error) {
+	s = strings.TrimSpace(s)
+	p := Default
+	switch s {
+	case "", "on":
+		return &p, nil
+	case "off":
+		return nil, nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid throttle setting %q: want NAME=VALUE", field)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid throttle %s %q: %w", name, value, err)
+		}
+		switch name {
+		case "battery-slowdown", "hot-slowdown":
+			if v < 1 {
+				return nil, fmt.Errorf("invalid throttle %s %q: must be at least 1", name, value)
+			}
+		case "battery-scale", "hot-scale":
+			if v <= 0 || v > 1 {
+				return nil, fmt.Errorf("invalid throttle %s %q: must be above 0 and at most 1", name, value)
+			}
+		}
+		switch name {
+		case "battery-slowdown":
+			p.BatterySlowdown = v
+		case "battery-scale":
+			p.BatteryScale = v
+		case "hot-slowdown":
+			p.HotSlowdown = v
+		case "hot-scale":
+			p.HotScale = v
+		case "max-temp":
+			p.MaxTemp = v
+		default:
+			return nil, fmt.Errorf("unknown throttle setting %q (want battery-slowdown, battery-scale, hot-slowdown, hot-scale or max-temp)", name)
+		}
+	}
+	return &p, nil
+}
+
+// State is the machine's power and thermal condition.
+type State struct {
+	OnBattery bool
+	// Temperature is the hottest CPU temperature in degrees Celsius, or 0
+	// if it cannot be read.
+	Temperature float64
+	// Limited is set when the OS reports it is slowing the CPU to cool it.
+	Limited bool
+}
+
+var errUnsupported = errors.New("reading the power state is not supported on this platform")
+
+// ReadState returns the machine's current condition.
+func ReadState() (State, error) {
+	return readState()
+}
+
+// recheck is how often the power state is read again.
+const recheck = 30 * time.Second
+
+// Governor applies a policy to a capture loop. A nil Governor never eases
+// off.
+type Governor struct {
+	Policy Policy
+	// Logf is told when throttling starts and stops. It defaults to
+	// log.Printf.
+	Logf func(format string, args ...any)
+
+	mu       sync.Mutex
+	checked  time.Time
+	slowdown float64
+	scale    float64
+	reason   string
+}
+
+// New returns a governor for p, or nil if p is nil.
+func New(p *Policy) *Governor {
+	if p == nil {
+		return nil
+	}
+	return &Governor{Policy: *p}
+}
+
+// adjust returns the current slowdown and scale, reading the power state
+// again if it is stale.
+func (g *Governor) adjust() (slowdown, scale float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if time.Since(g.checked) < recheck {
+		return g.slowdown, g.scale
+	}
+	g.checked = time.Now()
+	g.slowdown, g.scale = 1, 1
+	state, err := ReadState()
+	if err != nil {
+		if g.reason != "unknown" {
+			g.logf("capture will not be throttled: %v", err)
+			g.reason = "unknown"
+		}
+		return g.slowdown, g.scale
+	}
+	var reasons []string
+	if state.OnBattery {
+		reasons = append(reasons, "on battery power")
+		g.slowdown, g.scale = max(g.slowdown, g.Policy.BatterySlowdown), min(g.scale, g.Policy.BatteryScale)
+	}
+	if hot := state.Limited || (g.Policy.MaxTemp > 0 && state.Temperature >= g.Policy.MaxTemp); hot {
+		if state.Temperature > 0 {
+			reasons = append(reasons, fmt.Sprintf("running hot at %.0f°C", state.Temperature))
+		} else {
+			reasons = append(reasons, "running hot")
+		}
+		g.slowdown, g.scale = max(g.slowdown, g.Policy.HotSlowdown), min(g.scale, g.Policy.HotScale)
+	}
+	g.slowdown = max(g.slowdown, 1)
+	g.scale = min(max(g.scale, 0.1), 1)
+
+	reason := strings.Join(reasons, " and ")
+	if reason != g.reason {
+		if reason == "" {
+			g.logf("capture back to full rate and resolution")
+		} else {
+			g.logf("%s: capturing %.3gx less often at %.0f%% resolution", reason, g.slowdown, g.scale*100)
+		}
+		g.reason = reason
+	}
+	return g.slowdown, g.scale
+}
+
+// Interval returns how long to wait between captures instead of base.
+func (g *Governor) Interval(base time.Duration) time.Duration {
+	if g == nil {
+		return base
+	}
+	slowdown, _ := g.adjust()
+	return time.Duration(float64(base) * slowdown)
+}
+
+// Shrink returns img scaled down as the policy asks, or img itself when no
+// scaling is needed.
+func (g *Governor) Shrink(img image.Image) image.Image {
+	if g == nil {
+		return img
+	}
+	_, scale := g.adjust()
+	if scale >= 1 {
+		return img
+	}
+	return Scale(img, scale)
+}
+
+// Scale returns img scaled by f, averaging the pixels each output pixel
+// covers.
+func Scale(img image.Image, f float64) *image.RGBA {
+	b := img.Bounds()
+	w, h := max(int(float64(b.Dx())*f), 1), max(int(float64(b.Dy())*f), 1)
+	out := frame.Get(image.Rect(0, 0, w, h))
+	// Screenshots are RGBA; reading their pixels directly avoids a call and
+	// an allocation per pixel.
+	rgba, _ := img.(*image.RGBA)
+	for y := range h {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/h, y0+1)
+		for x := range w {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(b.Min.X+(x+1)*b.Dx()/w, x0+1)
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					var pr, pg, pb, pa uint32
+					if rgba != nil {
+						p := rgba.Pix[rgba.PixOffset(sx, sy):]
+						pr, pg, pb, pa = uint32(p[0])*0x101, uint32(p[1])*0x101, uint32(p[2])*0x101, uint32(p[3])*0x101
+					} else {
+						pr, pg, pb, pa = img.At(sx, sy).RGBA()
+					}
+					r, g, bl, a, n = r+pr, g+pg, bl+pb, a+pa, n+1
+				}
+			}
+			i := out.PixOffset(x, y)
+			out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = uint8(r/n>>8), uint8(g/n>>8), uint8(bl/n>>8), uint8(a/n>>8)
+		}
+	}
+	return out
+}
+
+func (g *Governor) logf(format string, args ...any) {
+	if g.Logf != nil {
+		g.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
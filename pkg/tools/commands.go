@@ -0,0 +1,238 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Commands lets the run_command tool run the programs in its allowlist,
+// for steps that are far more reliable from the command line than by
+// clicking, such as opening a file or checking a service's state.
+// Programs run directly, without a shell, so arguments cannot chain
+// other commands.
+type Commands struct {
+	// Allow maps each program the tool may run, by the name or path the
+	// call must use, to whether every run must be confirmed. The name "*"
+	// allows any program.
+	Allow map[string]bool
+	// Confirm asks whether to run a command; without it, commands that
+	// must be confirmed are refused.
+	Confirm func(ctx context.Context, command string) (bool, error)
+	// Dir is the working directory of the commands; empty is the
+	// server's.
+	Dir string
+	// Audit, if set, gets a JSON line for every call, allowed or not.
+	// Otherwise the calls are logged.
+	Audit io.Writer
+
+	mu sync.Mutex
+}
+
+// ParseCommands parses an allowlist of comma-separated program names or
+// paths, each followed by ":confirm" if every run must be confirmed, e.g.
+// "ls,xdg-open,git:confirm". "*:confirm" allows any program, and must be
+// confirmed.
+func ParseCommands(spec string) (*Commands, error) {
+	c := &Commands{Allow: map[string]bool{}}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, policy, _ := strings.Cut(entry, ":confirm")
+		if policy != "" || name == "" {
+			return nil, fmt.Errorf("invalid entry %q; want a program, optionally followed by :confirm", entry)
+		}
+		confirm := len(name) < len(entry)
+		if name == "*" && !confirm {
+			return nil, errors.New(`allowing any program needs confirmation; use "*:confirm"`)
+		}
+		c.Allow[name] = confirm
+	}
+	if len(c.Allow) == 0 {
+		return nil, errors.New("no programs given")
+	}
+	return c, nil
+}
+
+// policy reports whether program may run and whether it must be
+// confirmed first.
+func (c *Commands) policy(program string) (allowed, confirm bool) {
+	if confirm, ok := c.Allow[program]; ok {
+		return true, confirm
+	}
+	if _, ok := c.Allow["*"]; ok {
+		return true, true
+	}
+	return false, false
+}
+
+// auditEntry is a line of the audit log.
+type auditEntry struct {
+	Time    time.Time `json:"time"`
+	Program string    `json:"program"`
+	Args    []string  `json:"args"`
+	Dir     string    `json:"dir,omitempty"`
+	// Decision is "allowed", "confirmed", "declined" or "denied".
+	Decision string `json:"decision"`
+	Exit     *int   `json:"exit,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Output   int    `json:"output_bytes,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (c *Commands) audit(d *Dispatcher, e auditEntry) {
+	line, _ := json.Marshal(e)
+	if c.Audit == nil {
+		d.logf("command audit: %s", line)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.Audit.Write(append(line, '\n')); err != nil {
+		d.logf("failed to write the command audit log: %v (%s)", err, line)
+	}
+}
+
+const (
+	// commandTimeout is how long a command may run when the call sets no
+	// timeout.
+	commandTimeout = 30 * time.Second
+	// maxCommandTimeout is the longest timeout a call may set.
+	maxCommandTimeout = 10 * time.Minute
+	// maxOutput is the most output run_command keeps and returns.
+	maxOutput = 64 << 10
+)
+
+// limitedBuffer keeps the first max bytes written to it and counts the
+// rest, so that a command that prints without end cannot fill memory. It
+// takes every write, so that the command is not killed for writing.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	max   int
+	total int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.total += len(p)
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+func (d *Dispatcher) commands() (*Commands, error) {
+	if d.Commands == nil {
+		return nil, errors.New("run_command is not enabled; set AGENTGO_COMMANDS to the programs it may run")
+	}
+	return d.Commands, nil
+}
+
+func runCommand(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Command   string
+		Args      []string
+		TimeoutMS int `json:"timeout_ms"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	c, err := d.commands()
+	if err != nil {
+		return nil, err
+	}
+	if args.Command == "" {
+		return nil, errors.New("command is required")
+	}
+	e := auditEntry{Time: time.Now(), Program: args.Command, Args: args.Args, Dir: c.Dir}
+	allowed, confirm := c.policy(args.Command)
+	if !allowed {
+		e.Decision = "denied"
+		c.audit(d, e)
+		return nil, fmt.Errorf("%s is not in the allowlist of programs run_command may run", args.Command)
+	}
+	e.Decision = "allowed"
+	if confirm {
+		if c.Confirm == nil {
+			e.Decision, e.Error = "declined", "no way to confirm"
+			c.audit(d, e)
+			return nil, fmt.Errorf("%s must be confirmed and there is no way to ask", args.Command)
+		}
+		ok, err := c.Confirm(ctx, commandLine(args.Command, args.Args))
+		if err != nil || !ok {
+			e.Decision = "declined"
+			if err != nil {
+				e.Error = err.Error()
+			}
+			c.audit(d, e)
+			if err != nil {
+				return nil, fmt.Errorf("failed to confirm %s: %w", args.Command, err)
+			}
+			return nil, fmt.Errorf("the user declined to run %s", args.Command)
+		}
+		e.Decision = "confirmed"
+	}
+
+	timeout := commandTimeout
+	if args.TimeoutMS > 0 {
+		timeout = min(time.Duration(args.TimeoutMS)*time.Millisecond, maxCommandTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out := limitedBuffer{max: maxOutput}
+	cmd := exec.CommandContext(ctx, args.Command, args.Args...)
+	cmd.Dir = c.Dir
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	start := time.Now()
+	err = cmd.Run()
+	e.Duration = time.Since(start).Round(time.Millisecond).String()
+	e.Output = out.total
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		e.Exit = new(int)
+	case errors.As(err, &exitErr):
+		code := exitErr.ExitCode()
+		e.Exit = &code
+		if ctx.Err() != nil {
+			e.Error = ctx.Err().Error()
+		}
+	default:
+		e.Error = err.Error()
+		c.audit(d, e)
+		return nil, err
+	}
+	c.audit(d, e)
+
+	text := out.buf.Bytes()
+	more := ""
+	if out.total > len(text) {
+		more = fmt.Sprintf("\n[first %d of %d bytes shown]", len(text), out.total)
+	}
+	status := fmt.Sprintf("exit status %d", *e.Exit)
+	if e.Error != "" {
+		status = fmt.Sprintf("killed after %s", e.Duration)
+	}
+	return []Content{TextContent(fmt.Sprintf("%s\n%s%s", status, text, more))}, nil
+}
+
+// commandLine quotes a command for the user to confirm.
+func commandLine(program string, args []string) string {
+	parts := []string{program}
+	for _, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\$`;&|<>*?") {
+			a = fmt.Sprintf("%q", a)
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
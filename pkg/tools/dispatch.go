@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"strings"
+
+	"agentGo/pkg/memory"
+	"agentGo/pkg/safety"
+	"agentGo/pkg/secure"
+)
+
+// Dispatcher executes tool calls on a desktop.
+type Dispatcher struct {
+	Desktop Desktop
+	// Vision is optional; without it the tools that find elements by
+	// description report an error.
+	Vision Locator
+	// Browser is optional; without it the browser tools report an error.
+	Browser Browser
+	// Files is optional; without it the file tools report an error.
+	Files *Files
+	// Commands is optional; without it run_command reports an error.
+	Commands *Commands
+	// Memory is optional; without it remember and recall report an error.
+	Memory *memory.Store
+	// Safety, if set, checks clicks, typing and keys before they are
+	// performed, refusing risky ones that are not granted or confirmed.
+	Safety *safety.Guard
+	// Secure, if set, recognizes password fields, which are blacked out
+	// of screenshots, and whose typing is logged as secure.Placeholder.
+	Secure *secure.Detector
+	// Scrub, if set, removes secrets from the text the tools return and
+	// from the log, and from what the agent tells its model.
+	Scrub func(string) string
+	// OnCall, if set, is called after every call with the arguments as
+	// LogArgs masks them and the tool's error, e.g. to audit the calls.
+	OnCall func(name string, args json.RawMessage, err error)
+	Logf   func(format string, args ...any)
+}
+
+// ErrUnknownTool is returned for calls to tools that do not exist.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// Call runs the named tool with JSON-encoded arguments. Only a call to a
+// tool that does not exist returns an error; a tool that fails returns a
+// Result with IsError set.
+func (d *Dispatcher) Call(ctx context.Context, name string, args json.RawMessage) (Result, error) {
+	t, ok := Lookup(name)
+	if !ok {
+		return Result{}, fmt.Errorf("%w %q", ErrUnknownTool, name)
+	}
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	logged := d.LogArgs(name, args)
+	d.logf("tool %s %s", name, logged)
+	content, err := t.call(ctx, d, args)
+	if d.OnCall != nil {
+		d.OnCall(name, logged, err)
+	}
+	if err != nil {
+		return Result{Content: []Content{TextContent(d.scrub(err.Error()))}, IsError: true}, nil
+	}
+	for i := range content {
+		content[i].Text = d.scrub(content[i].Text)
+	}
+	return Result{Content: content}, nil
+}
+
+// LogArgs returns the arguments of a call to the named tool as they may be
+// logged: the text of a type call into a password field is replaced by
+// secure.Placeholder.
+func (d *Dispatcher) LogArgs(name string, args json.RawMessage) json.RawMessage {
+	if name != "type" || d.Secure == nil {
+		return args
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(args, &fields); err != nil {
+		return args
+	}
+	for k, v := range fields {
+		var text string
+		if strings.EqualFold(k, "text") && json.Unmarshal(v, &text) == nil && d.Secure.Text(text) != text {
+			fields[k], _ = json.Marshal(secure.Placeholder)
+		}
+	}
+	masked, err := json.Marshal(fields)
+	if err != nil {
+		return args
+	}
+	return masked
+}
+
+// Capture captures the screen, with a focused password field blacked out.
+func (d *Dispatcher) Capture() (image.Image, error) {
+	img, err := d.Desktop.Capture()
+	if err != nil {
+		return nil, err
+	}
+	return d.Secure.Redact(img), nil
+}
+
+// locate captures the screen and finds target on it, in 0-1 coordinates.
+func (d *Dispatcher) locate(ctx context.Context, target string) (float64, float64, error) {
+	if d.Vision == nil {
+		return 0, 0, errors.New("finding elements by description needs a vision model; set GEMINI_API_KEY")
+	}
+	img, err := d.Capture()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to capture screen: %w", err)
+	}
+	p, err := d.Vision.Locate(ctx, img, target)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find %s: %w", target, err)
+	}
+	x, y := p.Normalize(img.Bounds())
+	return x, y, nil
+}
+
+// browser returns the browser the browser tools manage.
+func (d *Dispatcher) browser() (Browser, error) {
+	if d.Browser == nil {
+		return nil, errors.New("browser tools need a browser's DevTools endpoint; set AGENTGO_CDP")
+	}
+	return d.Browser, nil
+}
+
+func (d *Dispatcher) scrub(text string) string {
+	if d.Scrub == nil {
+		return text
+	}
+	return d.Scrub(text)
+}
+
+func (d *Dispatcher) logf(format string, args ...any) {
+	msg := d.scrub(fmt.Sprintf(format, args...))
+	if d.Logf != nil {
+		d.Logf("%s", msg)
+		return
+	}
+	log.Print(msg)
+}
@@ -0,0 +1,313 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Files lets the file tools reach the directories in Dirs and everything
+// under them, and nothing else, so that an agent can check and tidy up
+// the files a task produces without access to the rest of the file system.
+type Files struct {
+	// Dirs are the directories the tools may use. Relative paths in tool
+	// calls are taken relative to the first.
+	Dirs []string
+	// ReadOnly makes write_file fail.
+	ReadOnly bool
+}
+
+// NewFiles returns access to dirs, which must exist. A leading ~ stands
+// for the home directory.
+func NewFiles(dirs ...string) (*Files, error) {
+	f := &Files{}
+	for _, d := range dirs {
+		if d == "" {
+			continue
+		}
+		abs, err := absPath(d)
+		if err != nil {
+			return nil, err
+		}
+		if abs, err = filepath.EvalSymlinks(abs); err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", d)
+		}
+		f.Dirs = append(f.Dirs, abs)
+	}
+	if len(f.Dirs) == 0 {
+		return nil, errors.New("no directories given")
+	}
+	return f, nil
+}
+
+func absPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return filepath.Abs(path)
+}
+
+// errOutside is returned for paths outside the allowed directories.
+var errOutside = errors.New("is outside the directories the file tools may use")
+
+// resolve returns the real path of path, with symbolic links followed so
+// that none leads out of the allowed directories. The path need not exist,
+// so that it can be written.
+func (f *Files) resolve(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	var abs string
+	switch {
+	case path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) || filepath.IsAbs(path):
+		var err error
+		if abs, err = absPath(path); err != nil {
+			return "", err
+		}
+	default:
+		abs = filepath.Join(f.Dirs[0], path)
+	}
+	real, err := realPath(abs)
+	if err != nil {
+		return "", err
+	}
+	if _, _, ok := f.within(real); !ok {
+		return "", fmt.Errorf("%s %w (%s)", path, errOutside, strings.Join(f.Dirs, ", "))
+	}
+	return real, nil
+}
+
+// maxLinks is the most symbolic links realPath follows by hand.
+const maxLinks = 40
+
+// realPath returns abs with the symbolic links in it followed, including
+// one whose target does not exist yet, which is where creating a file
+// through it would write.
+func realPath(abs string) (string, error) {
+	for range maxLinks {
+		real, rest := abs, ""
+		for {
+			r, err := filepath.EvalSymlinks(real)
+			if err == nil {
+				return filepath.Join(r, rest), nil
+			}
+			if target, err := os.Readlink(real); err == nil {
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(filepath.Dir(real), target)
+				}
+				abs = filepath.Join(target, rest)
+				break
+			}
+			parent := filepath.Dir(real)
+			if parent == real {
+				return "", err
+			}
+			rest = filepath.Join(filepath.Base(real), rest)
+			real = parent
+		}
+	}
+	return "", fmt.Errorf("%s: too many levels of symbolic links", abs)
+}
+
+// within returns the allowed directory real is in and its path there.
+func (f *Files) within(real string) (dir, rel string, ok bool) {
+	for _, d := range f.Dirs {
+		if rel, err := filepath.Rel(d, real); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return d, rel, true
+		}
+	}
+	return "", "", false
+}
+
+// open resolves path and opens it with flag. The file is opened through
+// the allowed directory it is in, so that a link made after path was
+// resolved cannot lead out of it either.
+func (f *Files) open(path string, flag int) (*os.File, error) {
+	real, err := f.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, rel, _ := f.within(real)
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	if flag&os.O_CREATE != 0 {
+		if err := os.MkdirAll(filepath.Dir(real), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return root.OpenFile(rel, flag, 0o644)
+}
+
+// maxRead is the most bytes read_file returns at a time.
+const maxRead = 64 << 10
+
+func (d *Dispatcher) files() (*Files, error) {
+	if d.Files == nil {
+		return nil, errors.New("the file tools are not enabled; set AGENTGO_FILES to the directories they may use")
+	}
+	return d.Files, nil
+}
+
+func readFile(_ context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Path   string
+		Offset int64
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+	file, err := files.open(args.Path, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory; use list_files", args.Path)
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	kind := http.DetectContentType(head[:n])
+	if !strings.HasPrefix(kind, "text/") {
+		return []Content{TextContent(fmt.Sprintf("%s is a binary file (%s) of %d bytes, modified %s.",
+			args.Path, kind, info.Size(), info.ModTime().Format(time.RFC3339)))}, nil
+	}
+	if _, err := file.Seek(args.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxRead)
+	n, err = io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	text := string(buf[:n])
+	if end := args.Offset + int64(n); end < info.Size() {
+		text += fmt.Sprintf("\n[%d of %d bytes shown; call again with offset %d for more]", end, info.Size(), end)
+	}
+	return []Content{TextContent(text)}, nil
+}
+
+func writeFile(_ context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Path    string
+		Content string
+		Append  bool
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+	if files.ReadOnly {
+		return nil, errors.New("write_file is disabled in read-only mode")
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if args.Append {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := files.open(args.Path, flags)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.WriteString(args.Content); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Wrote %d bytes to %s.", len(args.Content), args.Path))}, nil
+}
+
+// maxList is the most entries list_files returns.
+const maxList = 500
+
+func listFiles(_ context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Path    string
+		Pattern string
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+	if args.Path == "" {
+		var b strings.Builder
+		b.WriteString("The file tools may use these directories:\n")
+		for _, dir := range files.Dirs {
+			b.WriteString(dir + "\n")
+		}
+		return []Content{TextContent(b.String())}, nil
+	}
+	path, err := files.resolve(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, e := range entries {
+		if args.Pattern != "" {
+			if ok, err := filepath.Match(args.Pattern, e.Name()); err != nil {
+				return nil, fmt.Errorf("invalid pattern: %w", err)
+			} else if !ok {
+				continue
+			}
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if e.IsDir() {
+			lines = append(lines, fmt.Sprintf("%s/\t\t%s", e.Name(), info.ModTime().Format(time.RFC3339)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s\t%d\t%s", e.Name(), info.Size(), info.ModTime().Format(time.RFC3339)))
+		}
+	}
+	sort.Strings(lines)
+	more := ""
+	if len(lines) > maxList {
+		more = fmt.Sprintf("\n[%d more not shown; narrow the pattern]", len(lines)-maxList)
+		lines = lines[:maxList]
+	}
+	if len(lines) == 0 {
+		return []Content{TextContent("No entries.")}, nil
+	}
+	return []Content{TextContent("name\tbytes\tmodified\n" + strings.Join(lines, "\n") + more)}, nil
+}
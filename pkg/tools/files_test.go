@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileThroughLinks(t *testing.T) {
+	sandbox, outside := t.TempDir(), t.TempDir()
+	files, err := NewFiles(sandbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Dispatcher{Files: files}
+	link := func(name, target string) {
+		if err := os.Symlink(target, filepath.Join(sandbox, name)); err != nil {
+			t.Skipf("cannot make symbolic links: %v", err)
+		}
+	}
+	write := func(path string) error {
+		raw, _ := json.Marshal(map[string]string{"path": path, "content": "written"})
+		_, err := writeFile(context.Background(), d, raw)
+		return err
+	}
+
+	// Links whose targets do not exist yet, out of the sandbox directly,
+	// through a relative path and through a directory.
+	link("dangling", filepath.Join(outside, "created"))
+	link("relative", filepath.Join("..", filepath.Base(outside), "created"))
+	link("dir", filepath.Join(outside, "missing"))
+	link("existing", outside)
+	for _, path := range []string{"dangling", "relative", "dir/created", "existing/created"} {
+		if err := write(path); err == nil {
+			t.Errorf("wrote through %s", path)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("files were made outside the sandbox: %v", entries)
+	}
+
+	// A link to a file yet to be made inside the sandbox is followed.
+	link("inside", "made")
+	if err := write("inside"); err != nil {
+		t.Fatalf("writing through a link within the sandbox: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(sandbox, "made")); err != nil || string(data) != "written" {
+		t.Errorf("made holds %q, %v", data, err)
+	}
+
+	link("loop", "loop")
+	if err := write("loop"); err == nil {
+		t.Error("wrote through a link to itself")
+	}
+}
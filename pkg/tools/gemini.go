@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+var geminiTypes = map[string]genai.Type{
+	"string":  genai.TypeString,
+	"number":  genai.TypeNumber,
+	"integer": genai.TypeInteger,
+	"boolean": genai.TypeBoolean,
+	"array":   genai.TypeArray,
+	"object":  genai.TypeObject,
+}
+
+// GeminiTool returns every tool as function declarations for a Gemini
+// model's Tools.
+func GeminiTool() *genai.Tool {
+	tool := &genai.Tool{}
+	for _, t := range all {
+		tool.FunctionDeclarations = append(tool.FunctionDeclarations, &genai.FunctionDeclaration{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  geminiSchema(t.Parameters),
+		})
+	}
+	return tool
+}
+
+func geminiSchema(s *Schema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
+	g := &genai.Schema{
+		Type:        geminiTypes[s.Type],
+		Description: s.Description,
+		Enum:        s.Enum,
+		Required:    s.Required,
+		Items:       geminiSchema(s.Items),
+	}
+	if len(s.Properties) > 0 {
+		g.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, p := range s.Properties {
+			g.Properties[name] = geminiSchema(p)
+		}
+	}
+	return g
+}
+
+// DispatchGemini runs a Gemini function call and returns the parts to send
+// back: the function response, followed by any images the tool produced.
+func (d *Dispatcher) DispatchGemini(ctx context.Context, call genai.FunctionCall) []genai.Part {
+	args, err := json.Marshal(call.Args)
+	if err != nil {
+		args = nil
+	}
+	res, err := d.Call(ctx, call.Name, args)
+	if err != nil {
+		res = Result{Content: []Content{TextContent(err.Error())}, IsError: true}
+	}
+	response := map[string]any{"output": res.Text()}
+	if res.IsError {
+		response = map[string]any{"error": res.Text()}
+	}
+	parts := []genai.Part{genai.FunctionResponse{Name: call.Name, Response: response}}
+	for _, img := range res.Images() {
+		data, err := base64.StdEncoding.DecodeString(img.Data)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, genai.Blob{MIMEType: img.MimeType, Data: data})
+	}
+	return parts
+}
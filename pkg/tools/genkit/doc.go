@@ -0,0 +1,15 @@
+// Package genkit is a Firebase Genkit plugin exposing agentGo's desktop
+// actions, so Genkit applications can orchestrate desktop tasks with
+// Gemini. It registers the tools screenshot, locate and act for models to
+// call, and flows of the same names for direct use:
+//
+//	p := &agentgenkit.Plugin{Dispatcher: &tools.Dispatcher{Desktop: drv, Vision: client}}
+//	g, err := genkit.Init(ctx, genkit.WithPlugins(&googlegenai.GoogleAI{}, p))
+//	...
+//	resp, err := genkit.Generate(ctx, g,
+//		ai.WithPrompt("Open the File menu and click Save"),
+//		ai.WithTools(p.Tools()...))
+//
+// The package is a module of its own, agentGo/pkg/tools/genkit, so that
+// agentGo itself does not depend on github.com/firebase/genkit/go.
+package genkit
@@ -0,0 +1,170 @@
+package genkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/firebase/genkit/go/ai"
+	gk "github.com/firebase/genkit/go/genkit"
+
+	"agentGo/pkg/tools"
+)
+
+// Plugin registers the desktop actions when Genkit initializes.
+type Plugin struct {
+	Dispatcher *tools.Dispatcher
+
+	tools []ai.ToolRef
+}
+
+// Name implements genkit.Plugin.
+func (p *Plugin) Name() string {
+	return "agentgo"
+}
+
+// Init implements genkit.Plugin.
+func (p *Plugin) Init(ctx context.Context, g *gk.Genkit) error {
+	if p.Dispatcher == nil || p.Dispatcher.Desktop == nil {
+		return errors.New("agentgo plugin needs a Dispatcher with a Desktop")
+	}
+	p.tools = register(g, p.Dispatcher)
+	return nil
+}
+
+// Tools returns the tools Init defined, for ai.WithTools.
+func (p *Plugin) Tools() []ai.ToolRef {
+	return p.tools
+}
+
+// Screenshot is the output of screenshot.
+type Screenshot struct {
+	// Image is the screen as a PNG data URL, usable as a media part.
+	Image  string `json:"image"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// LocateInput is the input of locate.
+type LocateInput struct {
+	Description string `json:"description" jsonschema_description:"what to find, e.g. the search box"`
+}
+
+// Point is a screen position as fractions (0-1) of the screen.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// ActInput is the input of act.
+type ActInput struct {
+	Action    string   `json:"action" jsonschema:"enum=click,enum=type,enum=scroll"`
+	X         *float64 `json:"x,omitempty" jsonschema_description:"horizontal position for click or scroll, 0 (left) to 1 (right)"`
+	Y         *float64 `json:"y,omitempty" jsonschema_description:"vertical position for click or scroll, 0 (top) to 1 (bottom)"`
+	Target    string   `json:"target,omitempty" jsonschema_description:"description of the element to click, used instead of x,y"`
+	Button    string   `json:"button,omitempty" jsonschema_description:"mouse button for click: left, right or center"`
+	Text      string   `json:"text,omitempty" jsonschema_description:"text to type"`
+	Key       string   `json:"key,omitempty" jsonschema_description:"key to press after typing, e.g. enter"`
+	Direction string   `json:"direction,omitempty" jsonschema_description:"scroll direction: up or down"`
+	Amount    int      `json:"amount,omitempty" jsonschema_description:"number of wheel steps to scroll"`
+}
+
+// ActResult is the outcome of act. A failed action is reported in Error
+// rather than failing the tool call, so the model can react to it.
+type ActResult struct {
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// register defines the screenshot, locate and act tools and flows on g and
+// returns the tools.
+func register(g *gk.Genkit, d *tools.Dispatcher) []ai.ToolRef {
+	screenshot := func(ctx context.Context, _ struct{}) (Screenshot, error) {
+		return takeScreenshot(ctx, d)
+	}
+	locate := func(ctx context.Context, in LocateInput) (Point, error) {
+		return locateElement(ctx, d, in)
+	}
+	act := func(ctx context.Context, in ActInput) (ActResult, error) {
+		return perform(ctx, d, in)
+	}
+
+	gk.DefineFlow(g, "agentgo/screenshot", screenshot)
+	gk.DefineFlow(g, "agentgo/locate", locate)
+	gk.DefineFlow(g, "agentgo/act", act)
+
+	return []ai.ToolRef{
+		gk.DefineTool(g, "screenshot", "Capture the screen as a PNG image.",
+			func(ctx *ai.ToolContext, in struct{}) (Screenshot, error) { return screenshot(ctx, in) }),
+		gk.DefineTool(g, "locate", "Find an element on the current screen by description and return its center as x,y fractions (0-1) of the screen.",
+			func(ctx *ai.ToolContext, in LocateInput) (Point, error) { return locate(ctx, in) }),
+		gk.DefineTool(g, "act", "Click at a position or on an element by description, type text, or scroll the mouse wheel.",
+			func(ctx *ai.ToolContext, in ActInput) (ActResult, error) { return act(ctx, in) }),
+	}
+}
+
+func takeScreenshot(ctx context.Context, d *tools.Dispatcher) (Screenshot, error) {
+	res, err := call(ctx, d, "screenshot", struct{}{})
+	if err != nil {
+		return Screenshot{}, err
+	}
+	var shot Screenshot
+	for _, c := range res.Content {
+		switch c.Type {
+		case "image":
+			shot.Image = "data:" + c.MimeType + ";base64," + c.Data
+		case "text":
+			fmt.Sscanf(c.Text, "Screenshot is %dx%d pixels.", &shot.Width, &shot.Height)
+		}
+	}
+	return shot, nil
+}
+
+func locateElement(ctx context.Context, d *tools.Dispatcher, in LocateInput) (Point, error) {
+	res, err := call(ctx, d, "find_element", map[string]string{"description": in.Description})
+	if err != nil {
+		return Point{}, err
+	}
+	var p Point
+	if err := json.Unmarshal([]byte(res.Text()), &p); err != nil {
+		return Point{}, fmt.Errorf("failed to parse location: %w", err)
+	}
+	return p, nil
+}
+
+func perform(ctx context.Context, d *tools.Dispatcher, in ActInput) (ActResult, error) {
+	var name string
+	var args any
+	switch in.Action {
+	case "click":
+		name, args = "click", map[string]any{"x": in.X, "y": in.Y, "target": in.Target, "button": in.Button}
+	case "type":
+		name, args = "type", map[string]any{"text": in.Text, "key": in.Key}
+	case "scroll":
+		name, args = "scroll", map[string]any{"direction": in.Direction, "amount": in.Amount, "x": in.X, "y": in.Y}
+	default:
+		return ActResult{Error: fmt.Sprintf("action must be click, type or scroll, not %q", in.Action)}, nil
+	}
+	res, err := call(ctx, d, name, args)
+	if err != nil {
+		return ActResult{Error: err.Error()}, nil
+	}
+	return ActResult{Output: res.Text()}, nil
+}
+
+// call runs a tool, turning a failed result into an error.
+func call(ctx context.Context, d *tools.Dispatcher, name string, args any) (tools.Result, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return tools.Result{}, err
+	}
+	res, err := d.Call(ctx, name, data)
+	if err != nil {
+		return tools.Result{}, err
+	}
+	if res.IsError {
+		return res, errors.New(res.Text())
+	}
+	return res, nil
+}
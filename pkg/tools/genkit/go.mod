@@ -0,0 +1,10 @@
+module agentGo/pkg/tools/genkit
+
+go 1.24.1
+
+require (
+	agentGo v0.0.0
+	github.com/firebase/genkit/go v0.5.1
+)
+
+replace agentGo => ../../..
@@ -0,0 +1,119 @@
+// Package langchain adapts agentGo's desktop actions to LangChainGo tools,
+// so agents built with github.com/tmc/langchaingo get desktop control
+// without their own robotgo glue:
+//
+//	d := &tools.Dispatcher{Desktop: drv, Vision: client}
+//	var agentTools []lctools.Tool
+//	for _, t := range langchain.Tools(d) {
+//		agentTools = append(agentTools, t)
+//	}
+//	agent := agents.NewOneShotAgent(llm, agentTools)
+//
+// Tool satisfies LangChainGo's tools.Tool interface structurally, so this
+// package does not depend on LangChainGo itself.
+package langchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"agentGo/pkg/tools"
+)
+
+// primary is the argument a tool takes when the agent passes plain text
+// instead of a JSON object, as LangChain agents often do.
+var primary = map[string]string{
+	"click":          "target",
+	"type":           "text",
+	"context_menu":   "item",
+	"scroll":         "direction",
+	"find_element":   "description",
+	"scroll_to_find": "description",
+}
+
+// Tool is one desktop action as a LangChainGo tool.
+type Tool struct {
+	d    *tools.Dispatcher
+	tool tools.Tool
+}
+
+// Tools returns every desktop action, executed by d.
+func Tools(d *tools.Dispatcher) []Tool {
+	all := tools.All()
+	list := make([]Tool, len(all))
+	for i, t := range all {
+		list[i] = Tool{d: d, tool: t}
+	}
+	return list
+}
+
+// Name implements tools.Tool.
+func (t Tool) Name() string {
+	return t.tool.Name
+}
+
+// Description implements tools.Tool. It describes the input, since
+// LangChain agents see nothing but this text.
+func (t Tool) Description() string {
+	var b strings.Builder
+	b.WriteString(t.tool.Description)
+	props := t.tool.Parameters.Properties
+	if len(props) == 0 {
+		b.WriteString(" Takes no input.")
+		return b.String()
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.WriteString(" Input is a JSON object with")
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, " %q (%s", name, props[name].Type)
+		if d := props[name].Description; d != "" {
+			b.WriteString(": " + d)
+		}
+		if e := props[name].Enum; len(e) > 0 {
+			b.WriteString(", one of " + strings.Join(e, ", "))
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(".")
+	if p, ok := primary[t.tool.Name]; ok {
+		fmt.Fprintf(&b, " Plain text is taken as %q.", p)
+	}
+	return b.String()
+}
+
+// Call implements tools.Tool. A failed action is reported in the returned
+// text rather than as an error, so the agent can observe it and try again.
+func (t Tool) Call(ctx context.Context, input string) (string, error) {
+	res, err := t.d.Call(ctx, t.tool.Name, t.arguments(input))
+	if err != nil {
+		return "", err
+	}
+	if res.IsError {
+		return "error: " + res.Text(), nil
+	}
+	return res.Text(), nil
+}
+
+// arguments turns the agent's input into the tool's JSON arguments.
+func (t Tool) arguments(input string) json.RawMessage {
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "{") && json.Valid([]byte(input)) {
+		return json.RawMessage(input)
+	}
+	p, ok := primary[t.tool.Name]
+	if !ok || input == "" {
+		return nil
+	}
+	data, _ := json.Marshal(map[string]string{p: strings.Trim(input, `"`)})
+	return data
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"agentGo/pkg/memory"
+)
+
+func (d *Dispatcher) memory() (*memory.Store, error) {
+	if d.Memory == nil {
+		return nil, errors.New("the agent's memory is not enabled; set AGENTGO_MEMORY to the file it is kept in")
+	}
+	return d.Memory, nil
+}
+
+func remember(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Fact string
+		App  string
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	m, err := d.memory()
+	if err != nil {
+		return nil, err
+	}
+	fact, added, err := m.Add(ctx, memory.Fact{Text: args.Fact, App: args.App})
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return []Content{TextContent(fmt.Sprintf("already remembered as fact %d", fact.ID))}, nil
+	}
+	return []Content{TextContent(fmt.Sprintf("remembered as fact %d", fact.ID))}, nil
+}
+
+func recall(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Query string
+		Limit int
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	m, err := d.memory()
+	if err != nil {
+		return nil, err
+	}
+	if args.Limit <= 0 {
+		args.Limit = 5
+	}
+	facts, err := m.Recall(ctx, args.Query, args.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(facts) == 0 {
+		return []Content{TextContent("nothing remembered about that")}, nil
+	}
+	return []Content{TextContent(FormatFacts(facts))}, nil
+}
+
+// FormatFacts lists facts one per line, with the app each is about.
+func FormatFacts(facts []memory.Fact) string {
+	var b strings.Builder
+	for _, f := range facts {
+		b.WriteString("- ")
+		if f.App != "" {
+			fmt.Fprintf(&b, "%s: ", f.App)
+		}
+		b.WriteString(f.Text)
+		b.WriteByte('\n')
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
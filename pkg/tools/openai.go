@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Definition is a tool in the OpenAI chat completions "tools" format, which
+// most function-calling APIs accept.
+type Definition struct {
+	Type     string   `json:"type"`
+	Function Function `json:"function"`
+}
+
+// Function describes a function tool.
+type Function struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Parameters  *Schema `json:"parameters"`
+}
+
+// Definitions returns every tool in OpenAI's format.
+func Definitions() []Definition {
+	defs := make([]Definition, len(all))
+	for i, t := range all {
+		defs[i] = Definition{Type: "function", Function: Function{Name: t.Name, Description: t.Description, Parameters: t.Parameters}}
+	}
+	return defs
+}
+
+// ToolCall is an entry of the "tool_calls" of an OpenAI assistant message.
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+		// Arguments is a JSON object encoded as a string.
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// ToolMessage is the "tool" role message answering a ToolCall.
+type ToolMessage struct {
+	Role       string `json:"role"`
+	ToolCallID string `json:"tool_call_id"`
+	Content    string `json:"content"`
+}
+
+// DispatchOpenAI runs an OpenAI tool call and returns the message to send
+// back along with the full result, whose images the caller may attach in a
+// user message.
+func (d *Dispatcher) DispatchOpenAI(ctx context.Context, call ToolCall) (ToolMessage, Result) {
+	res, err := d.Call(ctx, call.Function.Name, json.RawMessage(call.Function.Arguments))
+	if err != nil {
+		res = Result{Content: []Content{TextContent(err.Error())}, IsError: true}
+	}
+	text := res.Text()
+	if res.IsError {
+		text = "error: " + text
+	}
+	return ToolMessage{Role: "tool", ToolCallID: call.ID, Content: text}, res
+}
@@ -0,0 +1,879 @@
+// Package tools publishes agentGo's desktop actions as tool definitions for
+// function-calling models and executes the tool calls they return. The same
+// action set is offered in OpenAI's format (Definitions, DispatchOpenAI), as
+// a Gemini tool (GeminiTool, DispatchGemini) and over MCP by package mcp,
+// so an agent loop can hand the model structured actions instead of parsing
+// coordinates out of free text.
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"agentGo/pkg/apps"
+	"agentGo/pkg/find"
+	"agentGo/pkg/frame"
+	"agentGo/pkg/safety"
+	"agentGo/pkg/script"
+	"agentGo/pkg/vision"
+)
+
+// Desktop is what the tools act on.
+type Desktop interface {
+	script.Executor
+	Capture() (image.Image, error)
+}
+
+// Locator finds elements on screenshots for find_element and for clicks
+// by description.
+type Locator interface {
+	Locate(ctx context.Context, img image.Image, target string) (vision.Point, error)
+}
+
+// Browser is what the browser tools manage, through its DevTools
+// endpoint.
+type Browser interface {
+	script.Browser
+}
+
+// Schema is the JSON schema of a tool's arguments.
+type Schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+// Tool is one action offered to the model.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  *Schema
+
+	call func(ctx context.Context, d *Dispatcher, args json.RawMessage) ([]Content, error)
+}
+
+// Content is an item of a tool result.
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// TextContent returns a text item.
+func TextContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+// Result is the outcome of a tool call. A failed call is still a result,
+// with IsError set and the error as its text, so the model can react to it.
+type Result struct {
+	Content []Content
+	IsError bool
+}
+
+// Text joins the result's text items.
+func (r Result) Text() string {
+	var texts []string
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			texts = append(texts, c.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Images returns the result's images, which tool messages in some APIs
+// cannot carry and the caller has to send in a following user message.
+func (r Result) Images() []Content {
+	var images []Content
+	for _, c := range r.Content {
+		if c.Type == "image" {
+			images = append(images, c)
+		}
+	}
+	return images
+}
+
+func object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+func prop(typ, description string) *Schema {
+	return &Schema{Type: typ, Description: description}
+}
+
+var all = []Tool{
+	{
+		Name:        "screenshot",
+		Description: "Capture the screen and return it as a PNG image.",
+		Parameters:  object(map[string]*Schema{}),
+		call:        screenshot,
+	},
+	{
+		Name:        "click",
+		Description: "Click at a screen position given as x,y fractions (0-1) of the screen width and height, or on an element given by description. Double-click to open or select a word, triple-click to select a line, or press and hold.",
+		Parameters: object(map[string]*Schema{
+			"x":       prop("number", "horizontal position, 0 (left) to 1 (right)"),
+			"y":       prop("number", "vertical position, 0 (top) to 1 (bottom)"),
+			"target":  prop("string", "description of the element to click, used instead of x,y, e.g. \"the Save button\""),
+			"button":  {Type: "string", Enum: []string{"left", "right", "center"}, Description: "mouse button, default left"},
+			"clicks":  prop("integer", "number of clicks: 1 (default), 2 for a double click or 3 for a triple click"),
+			"hold_ms": prop("integer", "hold the button down this many milliseconds on the last press, for a press and hold"),
+		}),
+		call: click,
+	},
+	{
+		Name:        "context_menu",
+		Description: "Right-click a screen position or an element given by description and pick an item from the context menu that opens, by its text. Use \" > \" to go through submenus, e.g. \"Sort by > Name\".",
+		Parameters: object(map[string]*Schema{
+			"x":      prop("number", "horizontal position, 0 (left) to 1 (right)"),
+			"y":      prop("number", "vertical position, 0 (top) to 1 (bottom)"),
+			"target": prop("string", "description of the element to right-click, used instead of x,y"),
+			"item":   prop("string", "text of the menu item to pick"),
+		}, "item"),
+		call: contextMenu,
+	},
+	{
+		Name:        "drag_from_to",
+		Description: "Press a mouse button on a source, move to a destination and release it there, e.g. to drag a file onto a folder, move a slider or reorder a list. Give each end as x,y fractions (0-1) of the screen or as an element description.",
+		Parameters: object(map[string]*Schema{
+			"from_x":      prop("number", "horizontal position of the source, 0 to 1"),
+			"from_y":      prop("number", "vertical position of the source, 0 to 1"),
+			"from_target": prop("string", "description of the element to drag, used instead of from_x,from_y"),
+			"to_x":        prop("number", "horizontal position of the destination, 0 to 1"),
+			"to_y":        prop("number", "vertical position of the destination, 0 to 1"),
+			"to_target":   prop("string", "description of where to drop, used instead of to_x,to_y"),
+			"duration_ms": prop("integer", "how long the move takes in milliseconds, default 500; slower suits targets that react to hovering"),
+			"hold_ms":     prop("integer", "how long to hold still after pressing and before releasing, in milliseconds, default 200"),
+			"button":      {Type: "string", Enum: []string{"left", "right", "center"}, Description: "mouse button, default left"},
+		}),
+		call: drag,
+	},
+	{
+		Name:        "type",
+		Description: "Type text into the focused element, optionally pressing a key such as enter afterwards.",
+		Parameters: object(map[string]*Schema{
+			"text": prop("string", "text to type"),
+			"key":  prop("string", "key or combination to press after typing, e.g. \"enter\" or \"ctrl+a\""),
+		}, "text"),
+		call: typeText,
+	},
+	{
+		Name:        "scroll",
+		Description: "Scroll the mouse wheel, optionally after moving to an x,y position (0-1 fractions of the screen).",
+		Parameters: object(map[string]*Schema{
+			"direction": {Type: "string", Enum: []string{"up", "down"}},
+			"amount":    prop("integer", "number of wheel steps, default 3"),
+			"x":         prop("number", "horizontal position, 0 to 1"),
+			"y":         prop("number", "vertical position, 0 to 1"),
+		}, "direction"),
+		call: scroll,
+	},
+	{
+		Name:        "find_element",
+		Description: "Find an element on the current screen by description and return its center as x,y fractions (0-1) of the screen.",
+		Parameters: object(map[string]*Schema{
+			"description": prop("string", "what to find, e.g. \"the search box\""),
+		}, "description"),
+		call: findElement,
+	},
+	{
+		Name:        "scroll_to_find",
+		Description: "Find an element that may be off screen, scrolling its list or page until it appears, and return its center as x,y fractions (0-1) of the screen.",
+		Parameters: object(map[string]*Schema{
+			"description": prop("string", "what to find, e.g. \"the row for order 1042\""),
+			"container":   prop("string", "the scrollable area to search, e.g. \"the orders table\"; default is the one the element should be in"),
+			"max_scrolls": prop("integer", "most scrolls to make before giving up, default 10"),
+		}, "description"),
+		call: scrollToFind,
+	},
+	{
+		Name:        "launch_browser",
+		Description: "Start the web browser, optionally with a profile and a page to open, and wait until the page has loaded. Attaches to the browser instead if it is already running.",
+		Parameters: object(map[string]*Schema{
+			"profile": prop("string", "user data directory to start the browser with; default is a scratch profile"),
+			"url":     prop("string", "address to open once the browser is up"),
+		}),
+		call: launchBrowser,
+	},
+	{
+		Name:        "open_url",
+		Description: "Open a web address in the browser and wait until the page has loaded, instead of typing it into the address bar.",
+		Parameters: object(map[string]*Schema{
+			"url":     prop("string", "address to open"),
+			"new_tab": prop("boolean", "open it in a new tab instead of the current one"),
+		}, "url"),
+		call: openURL,
+	},
+	{
+		Name:        "switch_tab",
+		Description: "Switch to the browser tab whose title or address contains the given text.",
+		Parameters: object(map[string]*Schema{
+			"tab": prop("string", "text of the tab's title or address, e.g. \"Inbox\" or \"github.com\""),
+		}, "tab"),
+		call: switchTab,
+	},
+	{
+		Name:        "wait_for_page_load",
+		Description: "Wait until the browser's current page has finished loading, e.g. after clicking a link.",
+		Parameters: object(map[string]*Schema{
+			"timeout_ms": prop("integer", "most milliseconds to wait, default 30000"),
+		}),
+		call: waitForPageLoad,
+	},
+	{
+		Name:        "launch_app",
+		Description: "Start an application and wait until its window appears, then bring it to the front.",
+		Parameters: object(map[string]*Schema{
+			"app":        prop("string", "executable path or name, or on macOS a bundle id, e.g. \"notepad\" or \"com.apple.TextEdit\""),
+			"args":       {Type: "array", Description: "command-line arguments", Items: prop("string", "")},
+			"window":     prop("string", "regular expression the window's title must match, to tell which window to wait for"),
+			"timeout_ms": prop("integer", "most milliseconds to wait for the window, default 30000"),
+		}, "app"),
+		call: launchApp,
+	},
+	{
+		Name:        "activate_window",
+		Description: "Bring a window to the front and focus it, waiting for it to appear if it is not open yet. Pick it by application, title or both.",
+		Parameters: object(map[string]*Schema{
+			"app":        prop("string", "executable path or name, or on macOS a bundle id, of the window's application"),
+			"window":     prop("string", "regular expression the window's title must match"),
+			"timeout_ms": prop("integer", "most milliseconds to wait for the window, default 30000"),
+		}),
+		call: activateWindow,
+	},
+	{
+		Name:        "close_window",
+		Description: "Close a window, as its close button does. Pick it by application, title or both; the frontmost match is closed.",
+		Parameters: object(map[string]*Schema{
+			"app":    prop("string", "executable path or name, or on macOS a bundle id, of the window's application"),
+			"window": prop("string", "regular expression the window's title must match"),
+		}),
+		call: closeWindow,
+	},
+	{
+		Name:        "place_window",
+		Description: "Move and resize a window to a known geometry and bring it to the front, so that positions on screen match what a task expects. Pick it by application, title or both.",
+		Parameters: object(map[string]*Schema{
+			"app":      prop("string", "executable path or name, or on macOS a bundle id, of the window's application"),
+			"window":   prop("string", "regular expression the window's title must match"),
+			"geometry": prop("string", "size and position in screen pixels as WIDTHxHEIGHT+X+Y, e.g. \"1280x800+0+0\"; WIDTHxHEIGHT only resizes and +X+Y only moves"),
+		}, "geometry"),
+		call: placeWindow,
+	},
+	{
+		Name:        "maximize_window",
+		Description: "Maximize a window to fill its screen and bring it to the front. Pick it by application, title or both.",
+		Parameters: object(map[string]*Schema{
+			"app":    prop("string", "executable path or name, or on macOS a bundle id, of the window's application"),
+			"window": prop("string", "regular expression the window's title must match"),
+		}),
+		call: maximizeWindow,
+	},
+	{
+		Name:        "list_files",
+		Description: "List a directory's files with their sizes and modification times, e.g. to check that a task saved its output. Only the directories the file tools are allowed are reachable; with no path, lists those.",
+		Parameters: object(map[string]*Schema{
+			"path":    prop("string", "directory to list, absolute, starting with ~, or relative to the first allowed directory"),
+			"pattern": prop("string", "only list names matching this glob, e.g. \"*.pdf\""),
+		}),
+		call: listFiles,
+	},
+	{
+		Name:        "read_file",
+		Description: "Read a text file, 64 KB at a time; for binary files such as PDFs, report their type and size. Only files in the allowed directories are reachable.",
+		Parameters: object(map[string]*Schema{
+			"path":   prop("string", "file to read, absolute, starting with ~, or relative to the first allowed directory"),
+			"offset": prop("integer", "byte offset to read from, default 0"),
+		}, "path"),
+		call: readFile,
+	},
+	{
+		Name:        "write_file",
+		Description: "Write text to a file, creating it and its directories or replacing its content. Only files in the allowed directories are reachable.",
+		Parameters: object(map[string]*Schema{
+			"path":    prop("string", "file to write, absolute, starting with ~, or relative to the first allowed directory"),
+			"content": prop("string", "text to write"),
+			"append":  prop("boolean", "add to the end of the file instead of replacing it"),
+		}, "path", "content"),
+		call: writeFile,
+	},
+	{
+		Name:        "run_command",
+		Description: "Run a program and return its exit status and output, for steps that are more reliable from the command line than by clicking, such as opening a file or checking a service's state. Only the programs in the allowlist can run; they run without a shell, and some need the user's confirmation first. Every call is audited.",
+		Parameters: object(map[string]*Schema{
+			"command":    prop("string", "program to run, exactly as the allowlist names it"),
+			"args":       {Type: "array", Description: "arguments, each passed as is", Items: prop("string", "")},
+			"timeout_ms": prop("integer", "how long the program may run before it is killed, default 30000, at most 600000"),
+		}, "command"),
+		call: runCommand,
+	},
+	{
+		Name:        "remember",
+		Description: "Remember a fact learned about an application that would save exploring next time, such as where a menu item or setting is, e.g. \"the export button is under File → Export\". Never remember credentials or where they are kept; such facts are refused.",
+		Parameters: object(map[string]*Schema{
+			"fact": prop("string", "the fact, in one sentence"),
+			"app":  prop("string", "the application it is about"),
+		}, "fact"),
+		call: remember,
+	},
+	{
+		Name:        "recall",
+		Description: "Look up facts remembered in earlier runs, e.g. where an application keeps a feature, before exploring for it.",
+		Parameters: object(map[string]*Schema{
+			"query": prop("string", "what to look up, e.g. \"export a report in Acme Books\""),
+			"limit": prop("integer", "most facts to return, default 5"),
+		}, "query"),
+		call: recall,
+	},
+}
+
+// All returns every tool.
+func All() []Tool {
+	return all
+}
+
+// Lookup returns the tool called name.
+func Lookup(name string) (Tool, bool) {
+	for _, t := range all {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Tool{}, false
+}
+
+func screenshot(ctx context.Context, d *Dispatcher, _ json.RawMessage) ([]Content, error) {
+	img, err := d.Capture()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture screen: %w", err)
+	}
+	data, err := frame.EncodePNG(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	size := img.Bounds().Size()
+	frame.Put(img)
+	return []Content{
+		{Type: "image", Data: base64.StdEncoding.EncodeToString(data), MimeType: "image/png"},
+		TextContent(fmt.Sprintf("Screenshot is %dx%d pixels.", size.X, size.Y)),
+	}, nil
+}
+
+func click(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		X, Y   *float64
+		Target string
+		Button string
+		Clicks int
+		HoldMS int `json:"hold_ms"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	var x, y float64
+	switch {
+	case args.Target != "":
+		var err error
+		if x, y, err = d.locate(ctx, args.Target); err != nil {
+			return nil, err
+		}
+	case args.X != nil && args.Y != nil:
+		x, y = *args.X, *args.Y
+	default:
+		return nil, errors.New("click needs either x and y or a target")
+	}
+	if err := checkNorm(x, y); err != nil {
+		return nil, err
+	}
+	if err := d.Safety.Check(ctx, safety.Action{Kind: safety.KindClick, Target: args.Target}); err != nil {
+		return nil, err
+	}
+	if err := d.Desktop.Move(x, y); err != nil {
+		return nil, err
+	}
+	if args.Clicks < 0 || args.Clicks > 3 {
+		return nil, errors.New("clicks must be 1, 2 or 3")
+	}
+	button := args.Button
+	if button == "" {
+		button = "left"
+	}
+	if err := script.Press(ctx, d.Desktop, button, args.Clicks, time.Duration(args.HoldMS)*time.Millisecond); err != nil {
+		return nil, err
+	}
+	d.Secure.Clicked(args.Target)
+	how := "Clicked"
+	switch args.Clicks {
+	case 2:
+		how = "Double-clicked"
+	case 3:
+		how = "Triple-clicked"
+	}
+	if args.HoldMS > 0 {
+		return []Content{TextContent(fmt.Sprintf("%s %s at %.4f,%.4f, holding the last press for %dms.", how, button, x, y, args.HoldMS))}, nil
+	}
+	return []Content{TextContent(fmt.Sprintf("%s %s at %.4f,%.4f.", how, button, x, y))}, nil
+}
+
+func contextMenu(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		X, Y   *float64
+		Target string
+		Item   string
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	path := script.SplitMenuPath(args.Item)
+	if len(path) == 0 {
+		return nil, errors.New("item is required")
+	}
+	if d.Vision == nil {
+		return nil, errors.New("finding menu items needs a vision model; set GEMINI_API_KEY")
+	}
+	var x, y float64
+	switch {
+	case args.Target != "":
+		var err error
+		if x, y, err = d.locate(ctx, args.Target); err != nil {
+			return nil, err
+		}
+	case args.X != nil && args.Y != nil:
+		if err := checkNorm(*args.X, *args.Y); err != nil {
+			return nil, err
+		}
+		x, y = *args.X, *args.Y
+	default:
+		return nil, errors.New("context_menu needs either x and y or a target")
+	}
+	if err := d.Safety.Check(ctx, safety.Action{Kind: safety.KindClick, Target: args.Item}); err != nil {
+		return nil, err
+	}
+	if err := script.ContextMenu(ctx, d.Desktop, d.Vision, d.Capture, x, y, path); err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Right-clicked at %.4f,%.4f and picked %s.", x, y, strings.Join(path, " > ")))}, nil
+}
+
+func drag(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		FromX      *float64 `json:"from_x"`
+		FromY      *float64 `json:"from_y"`
+		FromTarget string   `json:"from_target"`
+		ToX        *float64 `json:"to_x"`
+		ToY        *float64 `json:"to_y"`
+		ToTarget   string   `json:"to_target"`
+		DurationMS int      `json:"duration_ms"`
+		HoldMS     int      `json:"hold_ms"`
+		Button     string
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	fromX, fromY, err := d.position(ctx, "source", args.FromX, args.FromY, args.FromTarget)
+	if err != nil {
+		return nil, err
+	}
+	toX, toY, err := d.position(ctx, "destination", args.ToX, args.ToY, args.ToTarget)
+	if err != nil {
+		return nil, err
+	}
+	err = script.Drag(ctx, d.Desktop, fromX, fromY, toX, toY, script.DragOptions{
+		Button:   args.Button,
+		Duration: time.Duration(args.DurationMS) * time.Millisecond,
+		Hold:     time.Duration(args.HoldMS) * time.Millisecond,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Dragged from %.4f,%.4f to %.4f,%.4f.", fromX, fromY, toX, toY))}, nil
+}
+
+// position returns an end of a drag given as x,y or a target.
+func (d *Dispatcher) position(ctx context.Context, end string, x, y *float64, target string) (float64, float64, error) {
+	if target != "" {
+		return d.locate(ctx, target)
+	}
+	if x == nil || y == nil {
+		return 0, 0, fmt.Errorf("the drag %s needs either x and y or a target", end)
+	}
+	if err := checkNorm(*x, *y); err != nil {
+		return 0, 0, err
+	}
+	return *x, *y, nil
+}
+
+func typeText(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Text string
+		Key  string
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if err := d.Safety.Check(ctx, safety.Action{Kind: safety.KindType, Text: args.Text}); err != nil {
+		return nil, err
+	}
+	if args.Key != "" {
+		if err := d.Safety.Check(ctx, safety.Action{Kind: safety.KindKey, Key: args.Key}); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.Desktop.Type(args.Text); err != nil {
+		return nil, err
+	}
+	if args.Key != "" {
+		if err := d.Desktop.KeyTap(args.Key); err != nil {
+			return nil, err
+		}
+		return []Content{TextContent(fmt.Sprintf("Typed %d characters and pressed %s.", len([]rune(args.Text)), args.Key))}, nil
+	}
+	return []Content{TextContent(fmt.Sprintf("Typed %d characters.", len([]rune(args.Text))))}, nil
+}
+
+func scroll(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Direction string
+		Amount    int
+		X, Y      *float64
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	var button string
+	switch strings.ToLower(args.Direction) {
+	case "up":
+		button = "wheelUp"
+	case "down":
+		button = "wheelDown"
+	default:
+		return nil, fmt.Errorf("direction must be up or down, not %q", args.Direction)
+	}
+	if args.Amount <= 0 {
+		args.Amount = 3
+	}
+	if args.X != nil && args.Y != nil {
+		if err := checkNorm(*args.X, *args.Y); err != nil {
+			return nil, err
+		}
+		if err := d.Desktop.Move(*args.X, *args.Y); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i < args.Amount; i++ {
+		if err := d.Desktop.Click(button); err != nil {
+			return nil, err
+		}
+	}
+	return []Content{TextContent(fmt.Sprintf("Scrolled %s %d steps.", args.Direction, args.Amount))}, nil
+}
+
+func findElement(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Description string
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Description == "" {
+		return nil, errors.New("description is required")
+	}
+	x, y, err := d.locate(ctx, args.Description)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(map[string]float64{"x": x, "y": y})
+	return []Content{TextContent(string(data))}, nil
+}
+
+func scrollToFind(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Description string
+		Container   string
+		MaxScrolls  int `json:"max_scrolls"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Description == "" {
+		return nil, errors.New("description is required")
+	}
+	v, ok := d.Vision.(find.Vision)
+	if !ok {
+		return nil, errors.New("scrolling to find elements needs a vision model; set GEMINI_API_KEY")
+	}
+	f := find.Finder{
+		Vision:    v,
+		Scroller:  d.Desktop,
+		Capture:   d.Capture,
+		Budget:    args.MaxScrolls,
+		Container: args.Container,
+		Logf:      d.logf,
+	}
+	x, y, err := f.Find(ctx, args.Description)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(map[string]float64{"x": x, "y": y})
+	return []Content{TextContent(string(data))}, nil
+}
+
+// browserTimeout bounds the browser tools' waits.
+const browserTimeout = 30 * time.Second
+
+func launchBrowser(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Profile string
+		URL     string
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	b, err := d.browser()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, browserTimeout)
+	defer cancel()
+	if err := b.Launch(ctx, args.Profile, args.URL); err != nil {
+		return nil, err
+	}
+	if args.URL != "" {
+		return []Content{TextContent("Browser is up with " + args.URL + " loaded.")}, nil
+	}
+	return []Content{TextContent("Browser is up.")}, nil
+}
+
+func openURL(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		URL    string
+		NewTab bool `json:"new_tab"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	b, err := d.browser()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, browserTimeout)
+	defer cancel()
+	if args.NewTab {
+		err = b.NewTab(ctx, args.URL)
+	} else {
+		err = b.Navigate(ctx, args.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []Content{TextContent("Loaded " + args.URL + ".")}, nil
+}
+
+func switchTab(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		Tab string
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if args.Tab == "" {
+		return nil, errors.New("tab is required")
+	}
+	b, err := d.browser()
+	if err != nil {
+		return nil, err
+	}
+	if err := b.SwitchTab(ctx, args.Tab); err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Switched to the tab matching %q.", args.Tab))}, nil
+}
+
+func waitForPageLoad(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	var args struct {
+		TimeoutMS int `json:"timeout_ms"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	b, err := d.browser()
+	if err != nil {
+		return nil, err
+	}
+	timeout := browserTimeout
+	if args.TimeoutMS > 0 {
+		timeout = time.Duration(args.TimeoutMS) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := b.WaitLoad(ctx); err != nil {
+		return nil, err
+	}
+	return []Content{TextContent("Page has loaded.")}, nil
+}
+
+// windowTimeout bounds the window tools' waits for a window to appear.
+const windowTimeout = 30 * time.Second
+
+// windowArgs are the arguments of the window tools.
+type windowArgs struct {
+	App       string
+	Args      []string
+	Window    string
+	Geometry  string
+	TimeoutMS int `json:"timeout_ms"`
+}
+
+// windows parses the window tools' arguments into the executor's window
+// manager and a query.
+func (d *Dispatcher) windows(raw json.RawMessage) (script.WindowManager, windowArgs, apps.Query, error) {
+	var args windowArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, args, apps.Query{}, err
+	}
+	if args.App == "" && args.Window == "" {
+		return nil, args, apps.Query{}, errors.New("app or window is required")
+	}
+	wm, ok := d.Desktop.(script.WindowManager)
+	if !ok {
+		return nil, args, apps.Query{}, errors.New("this desktop cannot manage applications and windows")
+	}
+	q, err := script.WindowQuery(args.App, args.Window)
+	return wm, args, q, err
+}
+
+// activate waits for a window q picks and brings it to the front.
+func activate(ctx context.Context, wm script.WindowManager, q apps.Query, timeoutMS int) (apps.Window, error) {
+	timeout := windowTimeout
+	if timeoutMS > 0 {
+		timeout = time.Duration(timeoutMS) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	w, err := apps.Wait(ctx, wm.Windows, q)
+	if err != nil {
+		return w, err
+	}
+	return w, wm.ActivateWindow(w)
+}
+
+func launchApp(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	wm, args, q, err := d.windows(raw)
+	if err != nil {
+		return nil, err
+	}
+	if args.App == "" {
+		return nil, errors.New("app is required")
+	}
+	if err := wm.LaunchApp(args.App, args.Args); err != nil {
+		return nil, err
+	}
+	w, err := activate(ctx, wm, q, args.TimeoutMS)
+	if err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Launched %s; its window %q is in front.", args.App, w.Title))}, nil
+}
+
+func activateWindow(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	wm, args, q, err := d.windows(raw)
+	if err != nil {
+		return nil, err
+	}
+	w, err := activate(ctx, wm, q, args.TimeoutMS)
+	if err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Window %q is in front.", w.Title))}, nil
+}
+
+func closeWindow(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	wm, _, q, err := d.windows(raw)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := wm.Windows()
+	if err != nil {
+		return nil, err
+	}
+	found := apps.Find(windows, q)
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no %s is open", q)
+	}
+	if err := wm.CloseWindow(found[0]); err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Asked window %q to close.", found[0].Title))}, nil
+}
+
+func placeWindow(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	wm, args, q, err := d.windows(raw)
+	if err != nil {
+		return nil, err
+	}
+	g, err := apps.ParseGeometry(args.Geometry)
+	if err != nil {
+		return nil, err
+	}
+	placer, ok := wm.(script.WindowPlacer)
+	if !ok {
+		return nil, errors.New("this desktop cannot move or resize windows")
+	}
+	w, err := rearrange(ctx, wm, q, func(w apps.Window) error { return placer.PlaceWindow(w, g) })
+	if err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Placed window %q at %s.", w.Title, g))}, nil
+}
+
+func maximizeWindow(ctx context.Context, d *Dispatcher, raw json.RawMessage) ([]Content, error) {
+	wm, _, q, err := d.windows(raw)
+	if err != nil {
+		return nil, err
+	}
+	placer, ok := wm.(script.WindowPlacer)
+	if !ok {
+		return nil, errors.New("this desktop cannot move or resize windows")
+	}
+	w, err := rearrange(ctx, wm, q, placer.MaximizeWindow)
+	if err != nil {
+		return nil, err
+	}
+	return []Content{TextContent(fmt.Sprintf("Maximized window %q.", w.Title))}, nil
+}
+
+// rearrange waits for a window q picks, applies change to it and brings it
+// to the front.
+func rearrange(ctx context.Context, wm script.WindowManager, q apps.Query, change func(apps.Window) error) (apps.Window, error) {
+	ctx, cancel := context.WithTimeout(ctx, windowTimeout)
+	defer cancel()
+	w, err := apps.Wait(ctx, wm.Windows, q)
+	if err != nil {
+		return w, err
+	}
+	if err := change(w); err != nil {
+		return w, err
+	}
+	return w, wm.ActivateWindow(w)
+}
+
+func checkNorm(x, y float64) error {
+	if x < 0 || x > 1 || y < 0 || y > 1 {
+		return fmt.Errorf("position %.4f,%.4f is outside the screen; use fractions from 0 to 1", x, y)
+	}
+	return nil
+}
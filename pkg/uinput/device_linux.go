@@ -0,0 +1,121 @@
+package uinput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"os"
+	"syscall"
+	"time"
+)
+
+// uinput ioctls (linux/uinput.h).
+const (
+	uiDevCreate  = 0x5501
+	uiDevDestroy = 0x5502
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+	uiSetRelBit  = 0x40045566
+	uiSetAbsBit  = 0x40045567
+)
+
+// userDev is struct uinput_user_dev, the legacy setup every kernel accepts.
+type userDev struct {
+	Name         [80]byte
+	Bustype      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	FFEffectsMax uint32
+	Absmax       [64]int32
+	Absmin       [64]int32
+	Absfuzz      [64]int32
+	Absflat      [64]int32
+}
+
+// inputEvent is struct input_event.
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+type device struct {
+	f *os.File
+}
+
+// create sets up a device with the mouse buttons, wheels, every key in
+// keyCodes, and absolute axes of the given size.
+func create(size image.Point) (device, error) {
+	f, err := os.OpenFile("/dev/uinput", os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return device{}, fmt.Errorf("failed to open uinput (is the uinput module loaded and /dev/uinput writable?): %w", err)
+	}
+	bits := []struct {
+		req uintptr
+		vs  []uint16
+	}{
+		{uiSetEvBit, []uint16{evSyn, evKey, evRel, evAbs}},
+		{uiSetKeyBit, []uint16{btnLeft, btnRight, btnMiddle}},
+		{uiSetRelBit, []uint16{relWheel, relHWheel}},
+		{uiSetAbsBit, []uint16{absX, absY}},
+	}
+	seen := map[uint16]bool{}
+	for _, code := range keyCodes {
+		if !seen[code] {
+			seen[code] = true
+			bits[1].vs = append(bits[1].vs, code)
+		}
+	}
+	for _, b := range bits {
+		for _, v := range b.vs {
+			if err := ioctl(f, b.req, uintptr(v)); err != nil {
+				f.Close()
+				return device{}, fmt.Errorf("failed to set up uinput device: %w", err)
+			}
+		}
+	}
+	dev := userDev{Bustype: 0x06, Vendor: 0x1, Product: 0x1, Version: 1} // BUS_VIRTUAL
+	copy(dev.Name[:], "agentGo virtual input")
+	dev.Absmax[absX], dev.Absmax[absY] = int32(size.X-1), int32(size.Y-1)
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.NativeEndian, &dev)
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return device{}, fmt.Errorf("failed to set up uinput device: %w", err)
+	}
+	if err := ioctl(f, uiDevCreate, 0); err != nil {
+		f.Close()
+		return device{}, fmt.Errorf("failed to create uinput device: %w", err)
+	}
+	// Give the display server time to notice the new device, or the first
+	// events are lost.
+	time.Sleep(200 * time.Millisecond)
+	return device{f}, nil
+}
+
+func (d device) send(evs ...event) error {
+	var buf bytes.Buffer
+	var now syscall.Timeval
+	for _, e := range append(evs, event{evSyn, synReport, 0}) {
+		binary.Write(&buf, binary.NativeEndian, &inputEvent{Time: now, Type: e.typ, Code: e.code, Value: e.value})
+	}
+	if _, err := d.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write uinput events: %w", err)
+	}
+	return nil
+}
+
+func (d device) close() error {
+	ioctl(d.f, uiDevDestroy, 0)
+	return d.f.Close()
+}
+
+func ioctl(f *os.File, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
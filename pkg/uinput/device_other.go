@@ -0,0 +1,18 @@
+//go:build !linux
+
+package uinput
+
+import (
+	"errors"
+	"image"
+)
+
+var errNotLinux = errors.New("uinput is only available on Linux")
+
+type device struct{}
+
+func create(image.Point) (device, error) { return device{}, errNotLinux }
+
+func (device) send(...event) error { return errNotLinux }
+
+func (device) close() error { return nil }
@@ -0,0 +1,114 @@
+package uinput
+
+// Linux input event codes (linux/input-event-codes.h).
+const (
+	evSyn = 0x00
+	evKey = 0x01
+	evRel = 0x02
+	evAbs = 0x03
+
+	synReport = 0
+
+	relHWheel = 0x06
+	relWheel  = 0x08
+
+	absX = 0x00
+	absY = 0x01
+
+	btnLeft   = 0x110
+	btnRight  = 0x111
+	btnMiddle = 0x112
+
+	keyLeftShift = 42
+)
+
+// keyCodes maps the key names used by scripts (robotgo's names) to Linux
+// key codes.
+var keyCodes = map[string]uint16{
+	"escape":      1,
+	"esc":         1,
+	"backspace":   14,
+	"tab":         15,
+	"enter":       28,
+	"return":      28,
+	"ctrl":        29,
+	"control":     29,
+	"lctrl":       29,
+	"shift":       42,
+	"lshift":      42,
+	"rshift":      54,
+	"alt":         56,
+	"lalt":        56,
+	"space":       57,
+	"capslock":    58,
+	"f1":          59,
+	"f2":          60,
+	"f3":          61,
+	"f4":          62,
+	"f5":          63,
+	"f6":          64,
+	"f7":          65,
+	"f8":          66,
+	"f9":          67,
+	"f10":         68,
+	"f11":         87,
+	"f12":         88,
+	"rctrl":       97,
+	"printscreen": 99,
+	"ralt":        100,
+	"home":        102,
+	"up":          103,
+	"pageup":      104,
+	"left":        105,
+	"right":       106,
+	"end":         107,
+	"down":        108,
+	"pagedown":    109,
+	"insert":      110,
+	"delete":      111,
+	"pause":       119,
+	"cmd":         125,
+	"command":     125,
+	"super":       125,
+	"win":         125,
+	"menu":        139,
+}
+
+// chars maps the characters Type can enter to the key that produces them on
+// a US layout, and whether shift is held for it.
+var chars = map[rune]stroke{}
+
+// stroke is a key pressed, with or without shift.
+type stroke struct {
+	code  uint16
+	shift bool
+}
+
+func init() {
+	rows := []struct {
+		code         uint16
+		plain, shift string
+	}{
+		{2, "1234567890-=", "!@#$%^&*()_+"},
+		{16, "qwertyuiop[]", "QWERTYUIOP{}"},
+		{30, "asdfghjkl;'`", "ASDFGHJKL:\"~"},
+		{43, "\\zxcvbnm,./", "|ZXCVBNM<>?"},
+	}
+	for _, r := range rows {
+		shifted := []rune(r.shift)
+		for i, c := range []rune(r.plain) {
+			code := r.code + uint16(i)
+			chars[c] = stroke{code, false}
+			chars[shifted[i]] = stroke{code, true}
+		}
+	}
+	for c, name := range map[rune]string{' ': "space", '\n': "enter", '\t': "tab"} {
+		chars[c] = stroke{keyCodes[name], false}
+	}
+	// Single-letter and digit key names, as in "ctrl+a".
+	for c, k := range chars {
+		if !k.shift && c > ' ' {
+			keyCodes[string(c)] = k.code
+		}
+	}
+}
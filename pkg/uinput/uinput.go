@@ -0,0 +1,193 @@
+// Package uinput injects input through a virtual mouse and keyboard created
+// with the Linux kernel's uinput module. The events come from the kernel,
+// so they work under X11, Wayland and on the console alike, without cgo or
+// a display connection; the process needs write access to /dev/uinput
+// (usually root or membership of the input group).
+package uinput
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+
+	"agentGo/pkg/input"
+)
+
+// Device is a virtual input device. It implements input.Backend.
+type Device struct {
+	dev    device
+	bounds image.Rectangle
+
+	mu   sync.Mutex
+	x, y int
+}
+
+// Open creates a virtual device whose pointer positions span bounds, the
+// virtual desktop in physical pixels.
+func Open(bounds image.Rectangle) (*Device, error) {
+	if bounds.Empty() {
+		return nil, fmt.Errorf("invalid screen bounds %v", bounds)
+	}
+	dev, err := create(bounds.Size())
+	if err != nil {
+		return nil, err
+	}
+	return &Device{dev: dev, bounds: bounds}, nil
+}
+
+// Close destroys the virtual device.
+func (d *Device) Close() error {
+	return d.dev.close()
+}
+
+// Move implements input.Backend.
+func (d *Device) Move(x, y int) error {
+	p := image.Pt(x, y).Sub(d.bounds.Min)
+	p.X = min(max(p.X, 0), d.bounds.Dx()-1)
+	p.Y = min(max(p.Y, 0), d.bounds.Dy()-1)
+	if err := d.dev.send(event{evAbs, absX, int32(p.X)}, event{evAbs, absY, int32(p.Y)}); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.x, d.y = p.X+d.bounds.Min.X, p.Y+d.bounds.Min.Y
+	d.mu.Unlock()
+	return nil
+}
+
+// Position implements input.Backend. uinput only writes events, so this is
+// where Move last put the pointer, not where it is now.
+func (d *Device) Position() (int, int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.x, d.y, nil
+}
+
+// Click implements input.Backend.
+func (d *Device) Click(button string) error {
+	if dx, dy, ok := input.Wheel(button); ok {
+		return d.Scroll(dx, dy)
+	}
+	b, err := buttonCode(button)
+	if err != nil {
+		return err
+	}
+	if err := d.dev.send(event{evKey, b, 1}); err != nil {
+		return err
+	}
+	return d.dev.send(event{evKey, b, 0})
+}
+
+// Toggle implements input.Backend.
+func (d *Device) Toggle(button string, down bool) error {
+	b, err := buttonCode(button)
+	if err != nil {
+		return err
+	}
+	return d.dev.send(event{evKey, b, value(down)})
+}
+
+// Type implements input.Backend. Text is typed as keys of a US layout, so
+// the desktop's layout should be US; characters it has no key for are an
+// error.
+func (d *Device) Type(text string) error {
+	for _, c := range text {
+		if _, ok := chars[c]; !ok {
+			return fmt.Errorf("cannot type %q with uinput", c)
+		}
+	}
+	for _, c := range text {
+		k := chars[c]
+		if k.shift {
+			if err := d.dev.send(event{evKey, keyLeftShift, 1}); err != nil {
+				return err
+			}
+		}
+		if err := d.tap(k.code); err != nil {
+			return err
+		}
+		if k.shift {
+			if err := d.dev.send(event{evKey, keyLeftShift, 0}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// KeyTap implements input.Backend.
+func (d *Device) KeyTap(key string, modifiers ...string) error {
+	codes := make([]uint16, 0, len(modifiers)+1)
+	for _, name := range append(modifiers, key) {
+		code, ok := keyCodes[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("unknown key %q", name)
+		}
+		codes = append(codes, code)
+	}
+	mods := codes[:len(codes)-1]
+	for _, m := range mods {
+		if err := d.dev.send(event{evKey, m, 1}); err != nil {
+			return err
+		}
+	}
+	err := d.tap(codes[len(codes)-1])
+	for i := len(mods) - 1; i >= 0; i-- {
+		if e := d.dev.send(event{evKey, mods[i], 0}); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Scroll implements input.Backend.
+func (d *Device) Scroll(dx, dy int) error {
+	// The wheel axis counts up as positive.
+	if dy != 0 {
+		if err := d.dev.send(event{evRel, relWheel, int32(-dy)}); err != nil {
+			return err
+		}
+	}
+	if dx != 0 {
+		return d.dev.send(event{evRel, relHWheel, int32(dx)})
+	}
+	return nil
+}
+
+// Hook implements input.Backend; uinput devices only write events.
+func (d *Device) Hook(context.Context) (<-chan input.Event, error) {
+	return nil, input.ErrUnsupported
+}
+
+func (d *Device) tap(code uint16) error {
+	if err := d.dev.send(event{evKey, code, 1}); err != nil {
+		return err
+	}
+	return d.dev.send(event{evKey, code, 0})
+}
+
+func buttonCode(button string) (uint16, error) {
+	switch button {
+	case "", "left":
+		return btnLeft, nil
+	case "right":
+		return btnRight, nil
+	case "center", "middle":
+		return btnMiddle, nil
+	}
+	return 0, fmt.Errorf("unknown mouse button %q", button)
+}
+
+func value(down bool) int32 {
+	if down {
+		return 1
+	}
+	return 0
+}
+
+// event is one input event; send follows each batch with a sync report.
+type event struct {
+	typ, code uint16
+	value     int32
+}
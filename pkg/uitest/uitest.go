@@ -0,0 +1,367 @@
+// Package uitest writes desktop end-to-end tests as ordinary Go tests.
+// Elements are named by description and found by the vision model, and a
+// screenshot of the screen is saved when a test fails:
+//
+//	func TestSave(t *testing.T) {
+//		uitest.Click(t, "the Save button")
+//		uitest.AssertVisible(t, "a \"Saved\" confirmation")
+//	}
+//
+// The package-level helpers share a session opened on first use from the
+// environment (and a .env file): AGENTGO_DRIVER selects the desktop driver
+// (default local), AGENTGO_MODEL the model and GEMINI_API_KEY the key.
+// Failure screenshots go to AGENTGO_UITEST_ARTIFACTS, default
+// uitest-failures in the test's working directory.
+package uitest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/motion"
+	"agentGo/pkg/preflight"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/vision"
+)
+
+// Session drives one desktop for tests.
+type Session struct {
+	Driver desktop.Driver
+	Vision *vision.Client
+	// Timeout bounds how long Click, AssertVisible and the Wait helpers
+	// keep looking for an element that is not there yet.
+	Timeout time.Duration
+	// Poll is the pause between looks.
+	Poll time.Duration
+	// ArtifactDir is where failure screenshots are written.
+	ArtifactDir string
+
+	watched sync.Map
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultSession *Session
+	defaultErr     error
+)
+
+// Default returns the shared session, opening it on first use. It fails t
+// if the desktop or the model cannot be opened.
+func Default(t testing.TB) *Session {
+	t.Helper()
+	defaultOnce.Do(func() {
+		defaultSession, defaultErr = open()
+	})
+	if defaultErr != nil {
+		t.Fatalf("uitest: %v", defaultErr)
+	}
+	return defaultSession
+}
+
+func open() (*Session, error) {
+	if err := dotenv.LoadDefault(); err != nil {
+		return nil, err
+	}
+	drv, err := desktop.Open(os.Getenv(dotenv.EnvName("driver")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open driver: %w", err)
+	}
+	pointer, err := motion.Parse(os.Getenv(dotenv.EnvName("motion")))
+	if err != nil {
+		drv.Close()
+		return nil, err
+	}
+	desktop.UseMotion(drv, pointer)
+	if err := desktop.UseCapture(os.Getenv(dotenv.EnvName("capture"))); err != nil {
+		drv.Close()
+		return nil, err
+	}
+	if _, ok := drv.(*desktop.Local); ok {
+		if err := desktop.UseInputDriver(os.Getenv(dotenv.EnvName("input"))); err != nil {
+			drv.Close()
+			return nil, err
+		}
+		if err := preflight.Check(); err != nil {
+			drv.Close()
+			return nil, err
+		}
+	}
+	apiKey, err := secrets.Get("GEMINI_API_KEY")
+	if err != nil {
+		drv.Close()
+		return nil, err
+	}
+	model := os.Getenv(dotenv.EnvName("model"))
+	if model == "" {
+		model = vision.DefaultModel
+	}
+	client, err := vision.Connect(context.Background(), apiKey, model)
+	if err != nil {
+		drv.Close()
+		return nil, err
+	}
+	dir := os.Getenv("AGENTGO_UITEST_ARTIFACTS")
+	if dir == "" {
+		dir = "uitest-failures"
+	}
+	return &Session{Driver: drv, Vision: client, ArtifactDir: dir}, nil
+}
+
+// Close releases the session's driver and model client.
+func (s *Session) Close() error {
+	s.Vision.Close()
+	return s.Driver.Close()
+}
+
+// Click finds target and clicks it, failing t if it does not appear
+// within the timeout.
+func (s *Session) Click(t testing.TB, target string) {
+	t.Helper()
+	s.watch(t)
+	x, y, err := s.find(t, target)
+	if err != nil {
+		t.Fatalf("click %s: %v", target, err)
+	}
+	if err := s.Driver.Move(x, y); err != nil {
+		t.Fatalf("click %s: %v", target, err)
+	}
+	if err := s.Driver.Click("left"); err != nil {
+		t.Fatalf("click %s: %v", target, err)
+	}
+}
+
+// Type types text into the focused element.
+func (s *Session) Type(t testing.TB, text string) {
+	t.Helper()
+	s.watch(t)
+	if err := s.Driver.Type(text); err != nil {
+		t.Fatalf("type: %v", err)
+	}
+}
+
+// Press taps a key or combination such as "enter" or "ctrl+s".
+func (s *Session) Press(t testing.TB, key string) {
+	t.Helper()
+	s.watch(t)
+	if err := s.Driver.KeyTap(key); err != nil {
+		t.Fatalf("press %s: %v", key, err)
+	}
+}
+
+// AssertVisible reports an error if target does not appear within the
+// timeout. The test goes on either way.
+func (s *Session) AssertVisible(t testing.TB, target string) bool {
+	t.Helper()
+	s.watch(t)
+	if err := s.waitFor(t, target, true); err != nil {
+		t.Errorf("expected %s to be visible: %v", target, err)
+		return false
+	}
+	return true
+}
+
+// AssertNotVisible reports an error if target is still shown at the end
+// of the timeout.
+func (s *Session) AssertNotVisible(t testing.TB, target string) bool {
+	t.Helper()
+	s.watch(t)
+	if err := s.waitFor(t, target, false); err != nil {
+		t.Errorf("expected %s not to be visible: %v", target, err)
+		return false
+	}
+	return true
+}
+
+// WaitVisible waits for target to appear and fails t if it does not.
+func (s *Session) WaitVisible(t testing.TB, target string) {
+	t.Helper()
+	s.watch(t)
+	if err := s.waitFor(t, target, true); err != nil {
+		t.Fatalf("waiting for %s: %v", target, err)
+	}
+}
+
+// ReadText returns the text shown in target.
+func (s *Session) ReadText(t testing.TB, target string) string {
+	t.Helper()
+	s.watch(t)
+	img := s.capture(t)
+	text, err := s.Vision.ReadText(context.Background(), img, target)
+	if err != nil {
+		t.Fatalf("read %s: %v", target, err)
+	}
+	return text
+}
+
+// Screenshot captures the screen.
+func (s *Session) Screenshot(t testing.TB) image.Image {
+	t.Helper()
+	return s.capture(t)
+}
+
+func (s *Session) capture(t testing.TB) image.Image {
+	t.Helper()
+	img, err := s.Driver.Capture()
+	if err != nil {
+		t.Fatalf("failed to capture screen: %v", err)
+	}
+	return img
+}
+
+// find locates target, retrying until the timeout, and returns its 0-1
+// coordinates.
+func (s *Session) find(t testing.TB, target string) (float64, float64, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	for {
+		img := s.capture(t)
+		visible, err := s.Vision.Visible(ctx, img, target)
+		if err == nil && visible {
+			p, err := s.Vision.Locate(ctx, img, target)
+			if err == nil {
+				x, y := p.Normalize(img.Bounds())
+				return x, y, nil
+			}
+		}
+		if werr := s.pause(ctx); werr != nil {
+			if err != nil {
+				return 0, 0, err
+			}
+			return 0, 0, errors.New("not found")
+		}
+	}
+}
+
+// waitFor waits until target's visibility is want.
+func (s *Session) waitFor(t testing.TB, target string, want bool) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout())
+	defer cancel()
+	for {
+		visible, err := s.Vision.Visible(ctx, s.capture(t), target)
+		if err == nil && visible == want {
+			return nil
+		}
+		if werr := s.pause(ctx); werr != nil {
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("still %s after %v", map[bool]string{true: "not shown", false: "shown"}[want], s.timeout())
+		}
+	}
+}
+
+func (s *Session) pause(ctx context.Context) error {
+	poll := s.Poll
+	if poll <= 0 {
+		poll = 500 * time.Millisecond
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(poll):
+		return nil
+	}
+}
+
+func (s *Session) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return s.Timeout
+}
+
+// watch arranges, once per test, for a screenshot to be saved if the test
+// fails.
+func (s *Session) watch(t testing.TB) {
+	if _, loaded := s.watched.LoadOrStore(t, true); loaded {
+		return
+	}
+	t.Cleanup(func() {
+		s.watched.Delete(t)
+		if !t.Failed() {
+			return
+		}
+		path, err := s.saveFailure(t.Name())
+		if err != nil {
+			t.Logf("failed to save failure screenshot: %v", err)
+			return
+		}
+		t.Logf("screenshot at failure: %s", path)
+	})
+}
+
+func (s *Session) saveFailure(name string) (string, error) {
+	img, err := s.Driver.Capture()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(s.ArtifactDir, 0755); err != nil {
+		return "", err
+	}
+	name = strings.NewReplacer("/", "_", "\\", "_", " ", "_", ":", "_").Replace(name)
+	path := filepath.Join(s.ArtifactDir, name+".png")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Click clicks target on the shared session.
+func Click(t testing.TB, target string) {
+	t.Helper()
+	Default(t).Click(t, target)
+}
+
+// Type types text on the shared session.
+func Type(t testing.TB, text string) {
+	t.Helper()
+	Default(t).Type(t, text)
+}
+
+// Press taps key on the shared session.
+func Press(t testing.TB, key string) {
+	t.Helper()
+	Default(t).Press(t, key)
+}
+
+// AssertVisible checks that target is shown on the shared session.
+func AssertVisible(t testing.TB, target string) bool {
+	t.Helper()
+	return Default(t).AssertVisible(t, target)
+}
+
+// AssertNotVisible checks that target is not shown on the shared session.
+func AssertNotVisible(t testing.TB, target string) bool {
+	t.Helper()
+	return Default(t).AssertNotVisible(t, target)
+}
+
+// WaitVisible waits for target on the shared session.
+func WaitVisible(t testing.TB, target string) {
+	t.Helper()
+	Default(t).WaitVisible(t, target)
+}
+
+// ReadText reads target's text on the shared session.
+func ReadText(t testing.TB, target string) string {
+	t.Helper()
+	return Default(t).ReadText(t, target)
+}
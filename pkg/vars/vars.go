@@ -0,0 +1,243 @@
+// Package vars implements ${VAR} substitution for typed text, window titles,
+// and file paths so a single recording or script can be reused across
+// accounts, documents, and environments.
+package vars
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Set holds variable values. Lookups check the set's own values, then its
+// parent (if any), and finally the process environment, unless the set is
+// isolated from it.
+type Set struct {
+	values   map[string]string
+	parent   *Set
+	isolated bool
+}
+
+// New returns an empty variable set that falls back to the environment.
+func New() *Set {
+	return &Set{values: make(map[string]string)}
+}
+
+// NewIsolated returns an empty variable set that does not fall back to the
+// environment, for values that come from elsewhere, such as a task sent by
+// another machine, which must not read this one's environment.
+func NewIsolated() *Set {
+	return &Set{values: make(map[string]string), isolated: true}
+}
+
+// Isolated reports whether s does not fall back to the environment.
+func (s *Set) Isolated() bool {
+	return s.isolated
+}
+
+// Child returns a new scope whose lookups fall back to s. Values set on the
+// child never leak into s.
+func (s *Set) Child() *Set {
+	return &Set{values: make(map[string]string), parent: s, isolated: s.isolated}
+}
+
+// Set assigns a value to name.
+func (s *Set) Set(name, value string) {
+	s.values[name] = value
+}
+
+// Get returns the value of name and whether it was defined.
+func (s *Set) Get(name string) (string, bool) {
+	for scope := s; scope != nil; scope = scope.parent {
+		if v, ok := scope.values[name]; ok {
+			return v, true
+		}
+	}
+	if s.isolated {
+		return "", false
+	}
+	return os.LookupEnv(name)
+}
+
+// Names returns the names defined directly on this set and its parents,
+// sorted. Environment variables are not included.
+func (s *Set) Names() []string {
+	seen := make(map[string]bool)
+	for scope := s; scope != nil; scope = scope.parent {
+		for name := range scope.values {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadFile reads variables from a values file. Files ending in .json must
+// contain a single object of string values; anything else is read as
+// NAME=VALUE lines, with blank lines and lines starting with # ignored.
+// Values already set are overwritten.
+func (s *Set) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var values map[string]string
+		if err := json.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("failed to parse values file %s: %w", path, err)
+		}
+		for name, value := range values {
+			s.Set(name, value)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, err := ParseAssignment(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		s.Set(name, value)
+	}
+	return scanner.Err()
+}
+
+// ParseAssignment splits a NAME=VALUE string. Surrounding quotes on the value
+// are removed.
+func ParseAssignment(assignment string) (string, string, error) {
+	name, value, ok := strings.Cut(assignment, "=")
+	name = strings.TrimSpace(name)
+	if !ok || !validName(name) {
+		return "", "", fmt.Errorf("invalid variable assignment %q, expected NAME=VALUE", assignment)
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return name, value, nil
+}
+
+// Expand replaces ${NAME} and ${NAME:-default} references in str. A
+// reference to an undefined variable without a default is an error, since
+// silently typing an empty string is rarely what a recording intended. Use
+// $$ to produce a literal dollar sign.
+func (s *Set) Expand(str string) (string, error) {
+	if !strings.Contains(str, "$") {
+		return str, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if c != '$' || i+1 >= len(str) {
+			b.WriteByte(c)
+			continue
+		}
+		switch str[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(str[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference in %q", str)
+			}
+			ref := str[i+2 : i+2+end]
+			name, def, hasDefault := strings.Cut(ref, ":-")
+			if !validName(name) {
+				return "", fmt.Errorf("invalid variable name %q", name)
+			}
+			value, ok := s.Get(name)
+			if !ok || (hasDefault && value == "") {
+				if !hasDefault {
+					return "", fmt.Errorf("undefined variable %q", name)
+				}
+				value = def
+			}
+			b.WriteString(value)
+			i += end + 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// MustExpand is like Expand but returns str unchanged when expansion fails.
+// It is intended for log and display strings only.
+func (s *Set) MustExpand(str string) string {
+	out, err := s.Expand(str)
+	if err != nil {
+		return str
+	}
+	return out
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Flag collects repeated -var NAME=VALUE command-line flags.
+type Flag []string
+
+// String implements flag.Value.
+func (f *Flag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set implements flag.Value.
+func (f *Flag) Set(value string) error {
+	if _, _, err := ParseAssignment(value); err != nil {
+		return err
+	}
+	*f = append(*f, value)
+	return nil
+}
+
+// Apply assigns every collected flag to s.
+func (f Flag) Apply(s *Set) {
+	for _, assignment := range f {
+		name, value, _ := ParseAssignment(assignment)
+		s.Set(name, value)
+	}
+}
+
+// Load builds a set from an optional values file and -var flags. Flags take
+// precedence over the file, and both take precedence over the environment.
+func Load(valuesFile string, flags Flag) (*Set, error) {
+	s := New()
+	if valuesFile != "" {
+		if err := s.LoadFile(valuesFile); err != nil {
+			return nil, err
+		}
+	}
+	flags.Apply(s)
+	return s, nil
+}
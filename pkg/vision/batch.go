@@ -0,0 +1,83 @@
+package vision
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Question is one frame and the element to find on it.
+type Question struct {
+	Image  image.Image
+	Target string
+}
+
+// Answer is the result of one Question in a batch.
+type Answer struct {
+	Point Point
+	Err   error
+}
+
+var indexedPattern = regexp.MustCompile(`(?im)^\W*(?:question|image)?\s*(\d+)\s*[:.)=-]\s*(.+)$`)
+
+// LocateBatch asks all questions in a single request, numbering them and
+// asking for one numbered answer per line. This amortizes the per-request
+// overhead over many frames, which matters for offline analysis of large
+// evaluation sets; interactive callers should use Locate. The returned
+// slice has one answer per question; the error is set only if the request
+// as a whole failed.
+func (c *Client) LocateBatch(ctx context.Context, questions []Question) ([]Answer, error) {
+	if len(questions) == 0 {
+		return nil, nil
+	}
+	parts := c.exampleParts(false)
+	parts = append(parts, genai.Text(fmt.Sprintf("There are %d numbered questions, each followed by its screenshot. %s", len(questions), c.languageHint(ctx))))
+	for i, q := range questions {
+		data, err := encodePNG(q.Image)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts,
+			genai.Text(fmt.Sprintf("Question %d: %s", i+1, c.pointPrompt(q.Target, q.Image.Bounds().Size(), "", false))),
+			genai.ImageData("png", data),
+		)
+	}
+	parts = append(parts, genai.Text("Answer every question on its own line as the question number, a colon and the coordinates, for example \"1: x,y\". If you cannot find the element, write \"none\" after the colon."))
+
+	text, err := c.generate(ctx, parts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.parseIndexed(text, questions), nil
+}
+
+// parseIndexed matches numbered answer lines to questions.
+func (c *Client) parseIndexed(text string, questions []Question) []Answer {
+	answers := make([]Answer, len(questions))
+	seen := make([]bool, len(questions))
+	for _, m := range indexedPattern.FindAllStringSubmatch(text, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(questions) || seen[n-1] {
+			continue
+		}
+		seen[n-1] = true
+		answer := strings.Trim(m[2], " *_`")
+		if strings.EqualFold(answer, "none") {
+			answers[n-1].Err = fmt.Errorf("question %d: not found", n)
+			continue
+		}
+		p, err := c.ParsePoint(answer, questions[n-1].Image.Bounds().Size())
+		answers[n-1] = Answer{Point: p, Err: err}
+	}
+	for i := range answers {
+		if !seen[i] {
+			answers[i].Err = fmt.Errorf("question %d: no answer in response", i+1)
+		}
+	}
+	return answers
+}
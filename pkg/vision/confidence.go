@@ -0,0 +1,115 @@
+package vision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrLowConfidence is returned by Locate when the model was not confident
+// enough of its answer and the low-confidence policy found nothing better.
+var ErrLowConfidence = errors.New("low confidence")
+
+// Policy says what Locate does with an answer below MinConfidence.
+type Policy string
+
+const (
+	// PolicyUnknown gives up with ErrLowConfidence, so callers record the
+	// target as unknown instead of using a guess.
+	PolicyUnknown Policy = "unknown"
+	// PolicyRetry asks once more with a prompt urging the model to look
+	// carefully, then gives up.
+	PolicyRetry Policy = "retry"
+	// PolicyTemplate falls back to matching a template image of the target
+	// (see LoadTemplates), then gives up.
+	PolicyTemplate Policy = "template"
+)
+
+// ParsePolicy parses a low-confidence policy name; empty means unknown.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return PolicyUnknown, nil
+	case PolicyUnknown, PolicyRetry, PolicyTemplate:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown low-confidence policy %q (want unknown, retry or template)", s)
+}
+
+// ConfidenceInstruction is appended to a point prompt to ask for a
+// confidence score; ParseScored reads the answer.
+const ConfidenceInstruction = "After the coordinates add your confidence from 0 to 1 that you found the right thing, in the format x,y,confidence. If it is not visible at all, answer none."
+
+var scoredPattern = regexp.MustCompile(`(-?\d+(?:\.\d+)?)\s*,\s*(-?\d+(?:\.\d+)?)\s*,\s*(\d+(?:\.\d+)?)`)
+
+// ParseScored extracts a point and confidence from a response to a prompt
+// ending in ConfidenceInstruction. An answer of "none" has confidence 0; an
+// answer without a score is taken at face value with confidence 1.
+func (c *Client) ParseScored(text string, size image.Point) (Point, float64, error) {
+	if m := scoredPattern.FindStringSubmatch(text); m != nil {
+		x, _ := strconv.ParseFloat(m[1], 64)
+		y, _ := strconv.ParseFloat(m[2], 64)
+		conf, _ := strconv.ParseFloat(m[3], 64)
+		if conf > 1 {
+			// Some models answer in percent.
+			conf /= 100
+		}
+		return c.Convention.ToPixels(x, y, size), conf, nil
+	}
+	if strings.Contains(strings.ToLower(text), "none") && !coordinatePattern.MatchString(text) {
+		return Point{}, 0, nil
+	}
+	p, err := c.ParsePoint(text, size)
+	return p, 1, err
+}
+
+// LocateScored is like Locate but also returns the model's confidence in
+// its answer, from 0 to 1, without applying any policy.
+func (c *Client) LocateScored(ctx context.Context, img image.Image, target string) (Point, float64, error) {
+	return c.locateScored(ctx, img, target, "")
+}
+
+func (c *Client) locateScored(ctx context.Context, img image.Image, target, hint string) (Point, float64, error) {
+	text, err := c.askPoint(ctx, img, target, hint, true)
+	if err != nil {
+		return Point{}, 0, err
+	}
+	return c.ParseScored(text, img.Bounds().Size())
+}
+
+// locateConfident implements Locate when MinConfidence is set.
+func (c *Client) locateConfident(ctx context.Context, img image.Image, target string) (Point, error) {
+	p, conf, err := c.LocateScored(ctx, img, target)
+	if err != nil {
+		return Point{}, err
+	}
+	if conf >= c.MinConfidence {
+		return p, nil
+	}
+
+	switch c.LowConfidence {
+	case PolicyRetry:
+		hint := "Look carefully: the first attempt was unsure. Check every part of the screen and make sure the element matches the description exactly. "
+		p, conf2, err := c.locateScored(ctx, img, target, hint)
+		if err != nil {
+			return Point{}, err
+		}
+		if conf2 >= c.MinConfidence {
+			return p, nil
+		}
+		conf = conf2
+	case PolicyTemplate:
+		if tmpl, ok := templateFor(c.Templates, target); ok {
+			p, score := MatchTemplate(img, tmpl)
+			if score >= c.MinConfidence {
+				return p, nil
+			}
+			return Point{}, fmt.Errorf("%w: model %.2f, template match %.2f", ErrLowConfidence, conf, score)
+		}
+	}
+	return Point{}, fmt.Errorf("%w: %.2f is below %.2f", ErrLowConfidence, conf, c.MinConfidence)
+}
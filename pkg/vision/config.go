@@ -0,0 +1,131 @@
+package vision
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Config tunes how the model samples and what it refuses. Unset fields keep
+// the API defaults.
+type Config struct {
+	// Temperature 0 makes answers repeatable, which is what coordinate
+	// questions want.
+	Temperature     *float32 `json:"temperature,omitempty"`
+	TopK            *int32   `json:"top_k,omitempty"`
+	TopP            *float32 `json:"top_p,omitempty"`
+	MaxOutputTokens *int32   `json:"max_output_tokens,omitempty"`
+	// Safety maps a harm category (harassment, hate_speech,
+	// sexually_explicit, dangerous_content or all) to the level at which
+	// responses are blocked (none, only_high, medium_and_above or
+	// low_and_above). Screenshots of ordinary desktops are sometimes
+	// blocked at the default levels.
+	Safety map[string]string `json:"safety,omitempty"`
+}
+
+// LoadConfig reads a JSON config file. A path that starts with "{" is
+// taken as the JSON itself.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data := []byte(path)
+	if !strings.HasPrefix(strings.TrimSpace(path), "{") {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return cfg, fmt.Errorf("failed to read vision config: %w", err)
+		}
+		path = "file " + path
+	} else {
+		path = "inline config"
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse vision %s: %w", path, err)
+	}
+	if _, err := cfg.safetySettings(); err != nil {
+		return cfg, fmt.Errorf("invalid vision %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Configure applies cfg to each of the client's models.
+func (c *Client) Configure(cfg Config) error {
+	for _, k := range c.keys {
+		for _, m := range k.models {
+			if err := cfg.apply(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (cfg Config) apply(m *genai.GenerativeModel) error {
+	if cfg.Temperature != nil {
+		m.SetTemperature(*cfg.Temperature)
+	}
+	if cfg.TopK != nil {
+		m.SetTopK(*cfg.TopK)
+	}
+	if cfg.TopP != nil {
+		m.SetTopP(*cfg.TopP)
+	}
+	if cfg.MaxOutputTokens != nil {
+		m.SetMaxOutputTokens(*cfg.MaxOutputTokens)
+	}
+	settings, err := cfg.safetySettings()
+	if err != nil {
+		return err
+	}
+	if len(settings) > 0 {
+		m.SafetySettings = settings
+	}
+	return nil
+}
+
+var harmCategories = map[string]genai.HarmCategory{
+	"harassment":        genai.HarmCategoryHarassment,
+	"hate_speech":       genai.HarmCategoryHateSpeech,
+	"sexually_explicit": genai.HarmCategorySexuallyExplicit,
+	"dangerous_content": genai.HarmCategoryDangerousContent,
+}
+
+var blockThresholds = map[string]genai.HarmBlockThreshold{
+	"none":             genai.HarmBlockNone,
+	"only_high":        genai.HarmBlockOnlyHigh,
+	"medium_and_above": genai.HarmBlockMediumAndAbove,
+	"low_and_above":    genai.HarmBlockLowAndAbove,
+}
+
+func (cfg Config) safetySettings() ([]*genai.SafetySetting, error) {
+	levels := map[genai.HarmCategory]genai.HarmBlockThreshold{}
+	if name, ok := cfg.Safety["all"]; ok {
+		t, ok := blockThresholds[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown safety threshold %q", name)
+		}
+		for _, cat := range harmCategories {
+			levels[cat] = t
+		}
+	}
+	for key, name := range cfg.Safety {
+		if key == "all" {
+			continue
+		}
+		cat, ok := harmCategories[strings.ToLower(key)]
+		if !ok {
+			return nil, fmt.Errorf("unknown harm category %q", key)
+		}
+		t, ok := blockThresholds[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown safety threshold %q", name)
+		}
+		levels[cat] = t
+	}
+	settings := make([]*genai.SafetySetting, 0, len(levels))
+	for cat, t := range levels {
+		settings = append(settings, &genai.SafetySetting{Category: cat, Threshold: t})
+	}
+	return settings, nil
+}
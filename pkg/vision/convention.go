@@ -0,0 +1,130 @@
+package vision
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Convention is the coordinate space a model answers in.
+type Convention string
+
+const (
+	// ConventionAuto asks for the space that can be told apart from the
+	// other on an image of the size sent (see Asked) and guesses the space
+	// from the answer.
+	ConventionAuto Convention = "auto"
+	// ConventionPixels is the image's own pixel space.
+	ConventionPixels Convention = "pixels"
+	// ConventionNorm1000 is 0-1000 on both axes, the space Gemini 2 models
+	// are trained to point in.
+	ConventionNorm1000 Convention = "norm1000"
+	// ConventionNorm1 is 0-1 on both axes.
+	ConventionNorm1 Convention = "norm1"
+)
+
+// ParseConvention parses a convention name. The empty string is accepted and
+// returned as is, meaning "use the model's default".
+func ParseConvention(s string) (Convention, error) {
+	switch c := Convention(strings.ToLower(strings.TrimSpace(s))); c {
+	case "", ConventionAuto, ConventionPixels, ConventionNorm1000, ConventionNorm1:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown coordinate convention %q (want auto, pixels, norm1000 or norm1)", s)
+}
+
+// DefaultConvention returns the convention a model is most accurate in.
+// Gemini 2 and later answer in 0-1000 natively; older models are asked for
+// pixels and checked.
+func DefaultConvention(model string) Convention {
+	model = strings.TrimPrefix(model, "models/")
+	if strings.HasPrefix(model, "gemini-1") {
+		return ConventionAuto
+	}
+	if strings.HasPrefix(model, "gemini-") {
+		return ConventionNorm1000
+	}
+	return ConventionAuto
+}
+
+// Asked returns the convention points on an image of the given size are
+// requested in. Auto asks for pixels on images smaller than 1000x1000, where
+// a 0-1000 answer often falls outside the image and gives itself away, and
+// for 0-1000 on larger ones, where any 0-1000 answer also fits the image but
+// a pixel answer beyond 1000 does not fit 0-1000.
+func (c Convention) Asked(size image.Point) Convention {
+	if c != ConventionAuto && c != "" {
+		return c
+	}
+	if size.X >= 1000 && size.Y >= 1000 {
+		return ConventionNorm1000
+	}
+	return ConventionPixels
+}
+
+// Instruction is the prompt sentence telling the model how to format a
+// point on an image of the given size.
+func (c Convention) Instruction(size image.Point) string {
+	switch c.Asked(size) {
+	case ConventionNorm1000:
+		return "Return only the x,y coordinates normalized to 0-1000 (0,0 is the top-left corner, 1000,1000 the bottom-right) in the format x,y."
+	case ConventionNorm1:
+		return "Return only the x,y coordinates as fractions from 0 to 1 of the image width and height in the format x,y."
+	}
+	return fmt.Sprintf("The image is %dx%d pixels. Return only the x,y pixel coordinates in the format x,y.", size.X, size.Y)
+}
+
+// ToPixels converts x,y answered in convention c to pixels of an image with
+// the given size. ConventionAuto uses DetectConvention on the values.
+func (c Convention) ToPixels(x, y float64, size image.Point) Point {
+	if c == ConventionAuto || c == "" {
+		c = DetectConvention(x, y, size)
+	}
+	switch c {
+	case ConventionNorm1000:
+		return Point{X: x / 1000 * float64(size.X), Y: y / 1000 * float64(size.Y)}
+	case ConventionNorm1:
+		return Point{X: x * float64(size.X), Y: y * float64(size.Y)}
+	}
+	return Point{X: x, Y: y}
+}
+
+// Format renders a pixel point on an image of the given size the way a
+// model answering in convention c would.
+func (c Convention) Format(p Point, size image.Point) string {
+	switch c.Asked(size) {
+	case ConventionNorm1000:
+		return fmt.Sprintf("%d,%d", int(math.Round(p.X/float64(size.X)*1000)), int(math.Round(p.Y/float64(size.Y)*1000)))
+	case ConventionNorm1:
+		return fmt.Sprintf("%.3f,%.3f", p.X/float64(size.X), p.Y/float64(size.Y))
+	}
+	return fmt.Sprintf("%d,%d", int(math.Round(p.X)), int(math.Round(p.Y)))
+}
+
+// DetectConvention guesses which convention an answer for an image of the
+// given size was given in, when it was asked for as ConventionAuto.Asked
+// says. Values in 0-1 with a fraction are taken as normalized to 1. On
+// images smaller than 1000x1000, values that fall outside the image but
+// within 0-1000 are taken as normalized to 1000 and anything else as
+// pixels; on larger images, values beyond 1000 that fit the image are taken
+// as pixels and anything else as normalized to 1000. An answer that fits
+// both spaces is taken to be in the one asked for, which is why models
+// known to ignore the instruction should be configured explicitly.
+func DetectConvention(x, y float64, size image.Point) Convention {
+	if x >= 0 && y >= 0 && x <= 1 && y <= 1 && (x != float64(int(x)) || y != float64(int(y))) {
+		return ConventionNorm1
+	}
+	inImage := x >= 0 && y >= 0 && x <= float64(size.X) && y <= float64(size.Y)
+	inNorm := x >= 0 && y >= 0 && x <= 1000 && y <= 1000
+	if ConventionAuto.Asked(size) == ConventionNorm1000 {
+		if inImage && !inNorm {
+			return ConventionPixels
+		}
+		return ConventionNorm1000
+	}
+	if inNorm && !inImage {
+		return ConventionNorm1000
+	}
+	return ConventionPixels
+}
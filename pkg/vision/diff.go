@@ -0,0 +1,98 @@
+package vision
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+)
+
+// Diff is where two screenshots differ pixel by pixel.
+type Diff struct {
+	// Changed bounds the changed pixels, in the coordinates of the
+	// second image; it is empty if the images match.
+	Changed image.Rectangle
+	// Fraction is the share of pixels that changed, from 0 to 1.
+	Fraction float64
+}
+
+// CompareImages diffs two equally sized images, ignoring per-pixel changes
+// below tolerance (0-255 in gray level) such as compression noise.
+func CompareImages(a, b image.Image, tolerance uint8) (Diff, error) {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return Diff{}, fmt.Errorf("images differ in size: %v and %v", a.Bounds().Size(), b.Bounds().Size())
+	}
+	if a.Bounds().Empty() {
+		return Diff{}, nil
+	}
+	ga, gb := newGray(a), newGray(b)
+	minX, minY, maxX, maxY := gb.w, gb.h, -1, -1
+	var changed int
+	for y := 0; y < gb.h; y++ {
+		for x := 0; x < gb.w; x++ {
+			i := y*gb.w + x
+			if math.Abs(ga.pix[i]-gb.pix[i]) <= float64(tolerance) {
+				continue
+			}
+			changed++
+			minX, maxX = min(minX, x), max(maxX, x)
+			minY, maxY = min(minY, y), max(maxY, y)
+		}
+	}
+	var d Diff
+	if changed > 0 {
+		d.Changed = image.Rect(minX, minY, maxX+1, maxY+1).Add(b.Bounds().Min)
+	}
+	d.Fraction = float64(changed) / float64(gb.w*gb.h)
+	return d, nil
+}
+
+// Highlight returns a copy of img with rect outlined in red.
+func Highlight(img image.Image, rect image.Rectangle) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+	if rect.Empty() {
+		return out
+	}
+	red := &image.Uniform{C: color.RGBA{R: 255, A: 255}}
+	const t = 3
+	r := rect.Inset(-t)
+	for _, edge := range []image.Rectangle{
+		image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+t),
+		image.Rect(r.Min.X, r.Max.Y-t, r.Max.X, r.Max.Y),
+		image.Rect(r.Min.X, r.Min.Y, r.Min.X+t, r.Max.Y),
+		image.Rect(r.Max.X-t, r.Min.Y, r.Max.X, r.Max.Y),
+	} {
+		draw.Draw(out, edge.Intersect(out.Bounds()), red, image.Point{}, draw.Src)
+	}
+	return out
+}
+
+// DescribeDiff asks the model for the meaningful differences between a
+// before and an after screenshot. The pixel diff, if the images are the
+// same size, points the model at the changed area. focus optionally says
+// what matters, e.g. "layout and text, not timestamps".
+func (c *Client) DescribeDiff(ctx context.Context, before, after image.Image, focus string) (string, error) {
+	var b strings.Builder
+	b.WriteString("The first image is a screenshot before, the second after. Describe the meaningful differences between them: windows, dialogs, text, values, layout, states of controls and errors. Ignore blinking cursors, clocks and anti-aliasing noise. Use a short bulleted list; if there is no meaningful difference, say so in one sentence.")
+	if d, err := CompareImages(before, after, 24); err == nil {
+		if d.Changed.Empty() {
+			b.WriteString(" The images are identical pixel for pixel.")
+		} else {
+			size := after.Bounds().Size()
+			fmt.Fprintf(&b, " Pixels changed within x %d-%d, y %d-%d of the %dx%d image (%.1f%% of the screen).",
+				d.Changed.Min.X, d.Changed.Max.X, d.Changed.Min.Y, d.Changed.Max.Y, size.X, size.Y, d.Fraction*100)
+		}
+	}
+	if focus != "" {
+		b.WriteString(" Focus on " + focus + ".")
+	}
+	text, err := c.GenerateImages(ctx, b.String(), []image.Image{before, after}, []string{"Before:", "After:"})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
@@ -0,0 +1,27 @@
+package vision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// EmbeddingModel is the model Embed uses.
+const EmbeddingModel = "text-embedding-004"
+
+// Embed returns the embedding of text, to compare its meaning with that of
+// other text.
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	k := c.pickKey()
+	res, err := k.client.EmbeddingModel(EmbeddingModel).EmbedContent(ctx, genai.Text(text))
+	c.record(k, 0, err)
+	if err != nil {
+		return nil, fmt.Errorf("gemini embedding failed: %w", err)
+	}
+	if res.Embedding == nil || len(res.Embedding.Values) == 0 {
+		return nil, errors.New("gemini returned no embedding")
+	}
+	return res.Embedding.Values, nil
+}
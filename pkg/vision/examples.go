@@ -0,0 +1,90 @@
+package vision
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Example is a solved localization question shown to the model before the
+// real one, so it sees exactly what a correct answer looks like.
+type Example struct {
+	// Target describes the element, as it would be passed to Locate.
+	Target string `json:"target"`
+	// X and Y are the correct answer in the image's pixels.
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// Image names the screenshot file, relative to the example file. It
+	// defaults to the example file's name with a .png extension.
+	Image string `json:"image,omitempty"`
+
+	png  []byte
+	size image.Point
+}
+
+// LoadExamples reads every *.json file in dir as an Example together with
+// the screenshot it refers to. A directory might hold login.png and
+// login.json containing
+//
+//	{"target": "the Sign in button", "x": 812, "y": 440}
+func LoadExamples(dir string) ([]Example, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	examples := make([]Example, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read example: %w", err)
+		}
+		var ex Example
+		if err := json.Unmarshal(data, &ex); err != nil {
+			return nil, fmt.Errorf("failed to parse example %s: %w", path, err)
+		}
+		if ex.Target == "" {
+			return nil, fmt.Errorf("example %s has no target", path)
+		}
+		imgPath := strings.TrimSuffix(path, ".json") + ".png"
+		if ex.Image != "" {
+			imgPath = filepath.Join(dir, ex.Image)
+		}
+		if ex.png, err = os.ReadFile(imgPath); err != nil {
+			return nil, fmt.Errorf("failed to read example image: %w", err)
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(ex.png))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode example image %s: %w", imgPath, err)
+		}
+		ex.size = image.Pt(cfg.Width, cfg.Height)
+		examples = append(examples, ex)
+	}
+	return examples, nil
+}
+
+// exampleParts renders the client's examples as request parts: for each,
+// the same question Locate asks, the screenshot and the correct answer.
+func (c *Client) exampleParts(scored bool) []genai.Part {
+	if len(c.Examples) == 0 {
+		return nil
+	}
+	parts := []genai.Part{genai.Text("Here are solved examples of the task, followed by the real question.")}
+	for i, ex := range c.Examples {
+		answer := c.Convention.Format(Point{X: ex.X, Y: ex.Y}, ex.size)
+		if scored {
+			answer += ",0.95"
+		}
+		parts = append(parts,
+			genai.Text(fmt.Sprintf("Example %d: %s", i+1, c.pointPrompt(ex.Target, ex.size, "", scored))),
+			genai.ImageData("png", ex.png),
+			genai.Text("Answer: "+answer),
+		)
+	}
+	return append(parts, genai.Text("Now the real question:"))
+}
@@ -0,0 +1,109 @@
+package vision
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Rotation is how a Client with several API keys picks one per call.
+type Rotation string
+
+const (
+	// RotateOnLimit keeps using one key until it is rate limited (HTTP 429),
+	// then moves on to the next.
+	RotateOnLimit Rotation = "on-429"
+	// RotateRoundRobin uses the keys in turn, one call each, and also moves
+	// on when a key is rate limited.
+	RotateRoundRobin Rotation = "round-robin"
+)
+
+// ParseRotation parses a rotation name; empty means RotateOnLimit.
+func ParseRotation(s string) (Rotation, error) {
+	switch r := Rotation(strings.ToLower(strings.TrimSpace(s))); r {
+	case "":
+		return RotateOnLimit, nil
+	case RotateOnLimit, RotateRoundRobin:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown key rotation %q (want on-429 or round-robin)", s)
+}
+
+// KeyUsage counts the calls made with one API key.
+type KeyUsage struct {
+	// Key identifies the key by its last characters.
+	Key         string
+	Requests    int
+	RateLimited int
+	Errors      int
+	Tokens      int64
+}
+
+// keyClient is one API key with its own connection. Its models are in the
+// same order as Client.names.
+type keyClient struct {
+	client *genai.Client
+	models []*genai.GenerativeModel
+	usage  KeyUsage
+}
+
+// Usage returns the per-key usage so far, in the order the keys were given.
+func (c *Client) Usage() []KeyUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	usage := make([]KeyUsage, len(c.keys))
+	for i, k := range c.keys {
+		usage[i] = k.usage
+	}
+	return usage
+}
+
+// pickKey returns the key to use for the next call.
+func (c *Client) pickKey() *keyClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := c.keys[c.next%len(c.keys)]
+	if c.Rotation == RotateRoundRobin {
+		c.next = (c.next + 1) % len(c.keys)
+	}
+	return k
+}
+
+// record accounts for a call made with k and moves off k if it was rate
+// limited.
+func (c *Client) record(k *keyClient, tokens int32, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k.usage.Requests++
+	k.usage.Tokens += int64(tokens)
+	if err == nil {
+		return
+	}
+	k.usage.Errors++
+	if isRateLimited(err) {
+		k.usage.RateLimited++
+		if c.keys[c.next%len(c.keys)] == k {
+			c.next = (c.next + 1) % len(c.keys)
+		}
+	}
+}
+
+// isRateLimited reports whether err is the API refusing a call for quota
+// reasons. The client library surfaces these as HTTP 429 or gRPC
+// RESOURCE_EXHAUSTED depending on transport, so the message is checked.
+func isRateLimited(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "429") ||
+		strings.Contains(msg, "RESOURCE_EXHAUSTED") ||
+		strings.Contains(msg, "ResourceExhausted") ||
+		strings.Contains(strings.ToLower(msg), "quota")
+}
+
+// maskKey shortens a key to something safe to log.
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "..."
+	}
+	return "..." + key[len(key)-4:]
+}
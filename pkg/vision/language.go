@@ -0,0 +1,112 @@
+package vision
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// languageNames maps ISO 639-1 codes, the ISO 639-2 codes Tesseract names
+// its language data by, and a few script variants to the names prompts use.
+var languageNames = map[string]string{
+	"ar": "Arabic", "ara": "Arabic",
+	"cs": "Czech", "ces": "Czech",
+	"da": "Danish", "dan": "Danish",
+	"de": "German", "deu": "German",
+	"el": "Greek", "ell": "Greek",
+	"en": "English", "eng": "English",
+	"es": "Spanish", "spa": "Spanish",
+	"fi": "Finnish", "fin": "Finnish",
+	"fr": "French", "fra": "French",
+	"he": "Hebrew", "heb": "Hebrew",
+	"hi": "Hindi", "hin": "Hindi",
+	"hu": "Hungarian", "hun": "Hungarian",
+	"id": "Indonesian", "ind": "Indonesian",
+	"it": "Italian", "ita": "Italian",
+	"ja": "Japanese", "jpn": "Japanese",
+	"ko": "Korean", "kor": "Korean",
+	"nl": "Dutch", "nld": "Dutch",
+	"no": "Norwegian", "nor": "Norwegian",
+	"pl": "Polish", "pol": "Polish",
+	"pt": "Portuguese", "por": "Portuguese",
+	"ro": "Romanian", "ron": "Romanian",
+	"ru": "Russian", "rus": "Russian",
+	"sv": "Swedish", "swe": "Swedish",
+	"th": "Thai", "tha": "Thai",
+	"tr": "Turkish", "tur": "Turkish",
+	"uk": "Ukrainian", "ukr": "Ukrainian",
+	"vi": "Vietnamese", "vie": "Vietnamese",
+	"zh":      "Chinese",
+	"zh-hans": "Simplified Chinese", "chi_sim": "Simplified Chinese",
+	"zh-hant": "Traditional Chinese", "chi_tra": "Traditional Chinese",
+}
+
+// ParseLanguages parses a comma-separated list of the languages text on the
+// screen is in, given as codes such as de, jpn or chi_sim, or as names.
+func ParseLanguages(list string) ([]string, error) {
+	var langs []string
+	for _, lang := range strings.Split(list, ",") {
+		if lang = strings.TrimSpace(lang); lang == "" {
+			continue
+		}
+		name, err := LanguageName(lang)
+		if err != nil {
+			return nil, err
+		}
+		langs = append(langs, name)
+	}
+	return langs, nil
+}
+
+// LanguageName returns the name of the language with code lang, or lang
+// itself if it is already a name such as "Catalan".
+func LanguageName(lang string) (string, error) {
+	key := strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	if name, ok := languageNames[key]; ok {
+		return name, nil
+	}
+	if name, ok := languageNames[strings.ToLower(lang)]; ok {
+		return name, nil
+	}
+	// Anything longer than a code is taken as a name; unknown codes are
+	// refused rather than passed to the model.
+	if len(lang) <= 3 || strings.ContainsAny(lang, "_-") {
+		return "", fmt.Errorf("unknown language %q (use a code such as de, jpn or chi_sim, or the language's name)", lang)
+	}
+	return lang, nil
+}
+
+type languagesKey struct{}
+
+// WithLanguages returns a context in which the client reads and finds
+// text in langs, as ParseLanguages returns them, instead of its
+// Languages, e.g. for one script step.
+func WithLanguages(ctx context.Context, langs []string) context.Context {
+	if len(langs) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, languagesKey{}, langs)
+}
+
+// languages returns the languages of the text on screen for a call made
+// with ctx.
+func (c *Client) languages(ctx context.Context) []string {
+	if langs, ok := ctx.Value(languagesKey{}).([]string); ok {
+		return langs
+	}
+	return c.Languages
+}
+
+// languageHint tells the model which languages the screen's text is in, so
+// that targets and text are matched in them rather than translated.
+func (c *Client) languageHint(ctx context.Context) string {
+	langs := c.languages(ctx)
+	if len(langs) == 0 {
+		return ""
+	}
+	list := langs[0]
+	if n := len(langs); n > 1 {
+		list = strings.Join(langs[:n-1], ", ") + " or " + langs[n-1]
+	}
+	return fmt.Sprintf("The text on the screen is in %s. Match descriptions against it in that language, even when they are written in another, and give any text exactly as shown, without translating it. ", list)
+}
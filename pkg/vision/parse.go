@@ -0,0 +1,36 @@
+package vision
+
+import (
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+)
+
+// Point is a location in an image's pixel space.
+type Point struct {
+	X, Y float64
+}
+
+// Normalize converts p to 0-1 coordinates relative to bounds.
+func (p Point) Normalize(bounds image.Rectangle) (float64, float64) {
+	return (p.X - float64(bounds.Min.X)) / float64(bounds.Dx()),
+		(p.Y - float64(bounds.Min.Y)) / float64(bounds.Dy())
+}
+
+var coordinatePattern = regexp.MustCompile(`(-?\d+(?:\.\d+)?)\s*,\s*(-?\d+(?:\.\d+)?)`)
+
+// ParseCoordinates extracts the first "x,y" pair from a model response,
+// tolerating surrounding text, parentheses, and whitespace.
+func ParseCoordinates(text string) (float64, float64, error) {
+	m := coordinatePattern.FindStringSubmatch(text)
+	if m == nil {
+		return 0, 0, fmt.Errorf("no x,y coordinates in response %q", text)
+	}
+	x, errX := strconv.ParseFloat(m[1], 64)
+	y, errY := strconv.ParseFloat(m[2], 64)
+	if errX != nil || errY != nil {
+		return 0, 0, fmt.Errorf("invalid coordinates in response %q", text)
+	}
+	return x, y, nil
+}
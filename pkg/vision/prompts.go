@@ -0,0 +1,83 @@
+package vision
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// PromptSet holds the prompts used with one provider or model.
+type PromptSet struct {
+	// System is a standing instruction sent with every call, separate from
+	// the per-frame prompt: the model's role, the coordinate format it
+	// should use, what to do when it cannot find something.
+	System string `json:"system,omitempty"`
+}
+
+// Prompts maps a provider or model name to its prompts. Keys are matched as
+// prefixes of the model name, so "gemini" covers every Gemini model and
+// "gemini-2.0-flash" just that one; the longest match wins and "*" applies
+// when nothing else does. For example:
+//
+//	{
+//	  "*": {"system": "You locate user interface elements on screenshots."},
+//	  "gemini-2": {"system": "You locate user interface elements on screenshots. Answer points as x,y in 0-1000."}
+//	}
+type Prompts map[string]PromptSet
+
+// LoadPrompts reads a JSON prompts file.
+func LoadPrompts(path string) (Prompts, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts: %w", err)
+	}
+	var p Prompts
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse prompts %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// For returns the prompts for a model.
+func (p Prompts) For(model string) PromptSet {
+	model = strings.TrimPrefix(model, "models/")
+	best, found := "", false
+	for key := range p {
+		if key != "*" && strings.HasPrefix(model, key) && len(key) >= len(best) {
+			best, found = key, true
+		}
+	}
+	if found {
+		return p[best]
+	}
+	return p["*"]
+}
+
+// SetSystemInstruction sets the standing instruction sent with every call.
+// An empty string removes it.
+func (c *Client) SetSystemInstruction(text string) {
+	for _, k := range c.keys {
+		for _, m := range k.models {
+			m.SystemInstruction = systemInstruction(text)
+		}
+	}
+}
+
+// UsePrompts applies the prompts p holds for each of the client's models.
+func (c *Client) UsePrompts(p Prompts) {
+	for _, k := range c.keys {
+		for i, m := range k.models {
+			m.SystemInstruction = systemInstruction(p.For(c.names[i]).System)
+		}
+	}
+}
+
+func systemInstruction(text string) *genai.Content {
+	if text == "" {
+		return nil
+	}
+	return &genai.Content{Parts: []genai.Part{genai.Text(text)}}
+}
@@ -0,0 +1,108 @@
+package vision
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"log"
+	"math"
+)
+
+// Refiner locates elements in two passes. The first pass asks for the target
+// on the whole image; the second crops a window around that answer, enlarges
+// it and asks again. Models downsample large screenshots, so the second pass
+// sees the target at a much higher effective resolution and is usually
+// several times more accurate.
+type Refiner struct {
+	*Client
+	// Window is the side of the square crop as a fraction of the image's
+	// longer side. Zero means 0.25.
+	Window float64
+	// Zoom is the factor the crop is enlarged by before the second pass.
+	// Zero means 2.
+	Zoom int
+	Logf func(format string, args ...any)
+}
+
+// NewRefiner returns a Refiner with the default window and zoom.
+func NewRefiner(c *Client) *Refiner {
+	return &Refiner{Client: c}
+}
+
+// Locate finds target on img and refines the answer on a zoomed crop. If the
+// second pass fails or lands outside the crop, the first answer is returned.
+func (r *Refiner) Locate(ctx context.Context, img image.Image, target string) (Point, error) {
+	first, err := r.Client.Locate(ctx, img, target)
+	if err != nil {
+		return Point{}, err
+	}
+	crop := r.window(img.Bounds(), first)
+	if crop.Empty() {
+		return first, nil
+	}
+	zoom := r.Zoom
+	if zoom <= 0 {
+		zoom = 2
+	}
+	zoomed := enlarge(img, crop, zoom)
+	second, err := r.Client.Locate(ctx, zoomed, target)
+	if err != nil {
+		r.logf("refinement failed, keeping first answer: %v", err)
+		return first, nil
+	}
+	if !(image.Point{X: int(second.X), Y: int(second.Y)}).In(zoomed.Bounds()) {
+		r.logf("refined answer %.0f,%.0f is outside the crop, keeping first answer", second.X, second.Y)
+		return first, nil
+	}
+	refined := Point{
+		X: float64(crop.Min.X) + second.X/float64(zoom),
+		Y: float64(crop.Min.Y) + second.Y/float64(zoom),
+	}
+	r.logf("refined %.0f,%.0f to %.1f,%.1f", first.X, first.Y, refined.X, refined.Y)
+	return refined, nil
+}
+
+// window returns the crop centred on p, shifted to stay inside bounds.
+func (r *Refiner) window(bounds image.Rectangle, p Point) image.Rectangle {
+	frac := r.Window
+	if frac <= 0 {
+		frac = 0.25
+	}
+	return around(bounds, p, int(math.Round(frac*float64(max(bounds.Dx(), bounds.Dy())))))
+}
+
+// around returns the square of the given side centred on p, shifted to stay
+// inside bounds, or an empty rectangle if there is no room for one.
+func around(bounds image.Rectangle, p Point, side int) image.Rectangle {
+	side = min(side, bounds.Dx(), bounds.Dy())
+	if side <= 0 {
+		return image.Rectangle{}
+	}
+	x := min(max(int(p.X)-side/2, bounds.Min.X), bounds.Max.X-side)
+	y := min(max(int(p.Y)-side/2, bounds.Min.Y), bounds.Max.Y-side)
+	return image.Rect(x, y, x+side, y+side)
+}
+
+func (r *Refiner) logf(format string, args ...any) {
+	if r.Logf != nil {
+		r.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// enlarge copies the crop of img into a new image zoom times larger, using
+// nearest-neighbour sampling so edges stay sharp.
+func enlarge(img image.Image, crop image.Rectangle, zoom int) *image.RGBA {
+	src := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(src, src.Bounds(), img, crop.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, crop.Dx()*zoom, crop.Dy()*zoom))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		srow := src.Pix[(y/zoom)*src.Stride:]
+		drow := dst.Pix[y*dst.Stride:]
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			copy(drow[x*4:x*4+4], srow[(x/zoom)*4:])
+		}
+	}
+	return dst
+}
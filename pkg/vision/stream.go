@@ -0,0 +1,92 @@
+package vision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// generateUntil streams a response and stops as soon as complete reports
+// that the text received so far holds the whole answer, cancelling the rest
+// of the generation. It returns the text received.
+func (c *Client) generateUntil(ctx context.Context, complete func(text string) bool, parts ...genai.Part) (string, error) {
+	parts = c.scrub(parts)
+	return c.withFallback(ctx, func(m *genai.GenerativeModel) (string, int32, error) {
+		return streamUntil(ctx, m, complete, parts...)
+	})
+}
+
+func streamUntil(ctx context.Context, m *genai.GenerativeModel, complete func(text string) bool, parts ...genai.Part) (string, int32, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	it := m.GenerateContentStream(streamCtx, parts...)
+	var b strings.Builder
+	var used, prompt int32
+	for {
+		res, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			if b.Len() > 0 {
+				used = billed(ctx, m, parts, used, prompt, b.Len())
+			}
+			return "", used, fmt.Errorf("gemini call failed: %w", err)
+		}
+		if text, err := responseText(res); err == nil {
+			b.WriteString(text)
+		}
+		// Usage is cumulative, so the last chunk seen has the total.
+		if n := tokens(res); n > 0 {
+			used = n
+		}
+		if res.UsageMetadata != nil && res.UsageMetadata.PromptTokenCount > prompt {
+			prompt = res.UsageMetadata.PromptTokenCount
+		}
+		if complete(b.String()) {
+			used = billed(ctx, m, parts, used, prompt, b.Len())
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "", used, fmt.Errorf("gemini returned no candidates")
+	}
+	return b.String(), used, nil
+}
+
+// billed returns the tokens a stream cut short after received bytes of
+// text is billed at least: the prompt's, counted if no chunk said, and
+// about a token for every four bytes received. The chunks that would have
+// reported the total never arrive, and a budget that charged what they
+// reported would let cancelled calls through for free.
+func billed(ctx context.Context, m *genai.GenerativeModel, parts []genai.Part, used, prompt int32, received int) int32 {
+	if prompt == 0 {
+		if res, err := m.CountTokens(ctx, parts...); err == nil {
+			prompt = res.TotalTokens
+		}
+	}
+	return max(used, prompt+int32(received/4))
+}
+
+// pointComplete reports whether text already holds a whole point answer: a
+// pair (or, if scored, a triple) of numbers followed by something that is
+// not part of a number, or a "none" answer.
+func pointComplete(text string, scored bool) bool {
+	pattern := coordinatePattern
+	if scored {
+		pattern = scoredPattern
+		if strings.Contains(strings.ToLower(text), "none") {
+			return true
+		}
+	}
+	loc := pattern.FindStringIndex(text)
+	if loc == nil || loc[1] >= len(text) {
+		return false
+	}
+	next := text[loc[1]]
+	return next != '.' && (next < '0' || next > '9')
+}
@@ -0,0 +1,152 @@
+package vision
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadTemplates reads every PNG in dir as a template image, keyed by file
+// name without extension.
+func LoadTemplates(dir string) (map[string]image.Image, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	if err != nil {
+		return nil, err
+	}
+	templates := make(map[string]image.Image, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open template: %w", err)
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode template %s: %w", path, err)
+		}
+		templates[strings.TrimSuffix(filepath.Base(path), ".png")] = img
+	}
+	return templates, nil
+}
+
+// templateFor returns the template for target: the one named exactly
+// target, or else the one with the longest name that target mentions.
+func templateFor(templates map[string]image.Image, target string) (image.Image, bool) {
+	if t, ok := templates[target]; ok {
+		return t, true
+	}
+	var best string
+	for name := range templates {
+		if len(name) > len(best) && strings.Contains(strings.ToLower(target), strings.ToLower(name)) {
+			best = name
+		}
+	}
+	if best == "" {
+		return nil, false
+	}
+	return templates[best], true
+}
+
+// MatchTemplate finds where tmpl best matches img and returns the center of
+// that position in img's coordinates together with a similarity score from
+// 0 (no match) to 1 (identical pixels). The search runs on a downscaled copy
+// first and is then refined at full resolution around the best candidate.
+func MatchTemplate(img, tmpl image.Image) (Point, float64) {
+	src := newGray(img)
+	t := newGray(tmpl)
+	if t.w == 0 || t.h == 0 || t.w > src.w || t.h > src.h {
+		return Point{}, 0
+	}
+
+	f := max(1, min(t.w, t.h)/8)
+	cs, ct := src.shrink(f), t.shrink(f)
+	bx, by := 0, 0
+	best := math.Inf(1)
+	for y := 0; y+ct.h <= cs.h; y++ {
+		for x := 0; x+ct.w <= cs.w; x++ {
+			if d := cs.ssd(ct, x, y, best); d < best {
+				best, bx, by = d, x, y
+			}
+		}
+	}
+
+	best = math.Inf(1)
+	fx, fy := bx*f, by*f
+	for y := max(0, by*f-2*f); y <= min(src.h-t.h, by*f+2*f); y++ {
+		for x := max(0, bx*f-2*f); x <= min(src.w-t.w, bx*f+2*f); x++ {
+			if d := src.ssd(t, x, y, best); d < best {
+				best, fx, fy = d, x, y
+			}
+		}
+	}
+
+	origin := img.Bounds().Min
+	center := Point{
+		X: float64(origin.X+fx) + float64(t.w)/2,
+		Y: float64(origin.Y+fy) + float64(t.h)/2,
+	}
+	score := 1 - math.Sqrt(best/float64(t.w*t.h))/255
+	return center, score
+}
+
+// gray is a luminance image with its own origin at 0,0.
+type gray struct {
+	w, h int
+	pix  []float64
+}
+
+func newGray(img image.Image) gray {
+	b := img.Bounds()
+	g := gray{w: b.Dx(), h: b.Dy(), pix: make([]float64, b.Dx()*b.Dy())}
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			r, gr, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			g.pix[y*g.w+x] = (0.299*float64(r) + 0.587*float64(gr) + 0.114*float64(bl)) / 257
+		}
+	}
+	return g
+}
+
+// shrink box-averages g by factor f.
+func (g gray) shrink(f int) gray {
+	if f == 1 {
+		return g
+	}
+	s := gray{w: g.w / f, h: g.h / f}
+	s.pix = make([]float64, s.w*s.h)
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			var sum float64
+			for dy := 0; dy < f; dy++ {
+				row := g.pix[(y*f+dy)*g.w+x*f:]
+				for dx := 0; dx < f; dx++ {
+					sum += row[dx]
+				}
+			}
+			s.pix[y*s.w+x] = sum / float64(f*f)
+		}
+	}
+	return s
+}
+
+// ssd is the sum of squared differences of t placed at x,y in g. It stops
+// early once the sum exceeds limit.
+func (g gray) ssd(t gray, x, y int, limit float64) float64 {
+	var sum float64
+	for ty := 0; ty < t.h; ty++ {
+		row := g.pix[(y+ty)*g.w+x:]
+		trow := t.pix[ty*t.w:]
+		for tx := 0; tx < t.w; tx++ {
+			d := row[tx] - trow[tx]
+			sum += d * d
+		}
+		if sum > limit {
+			return sum
+		}
+	}
+	return sum
+}
@@ -0,0 +1,61 @@
+package vision
+
+import (
+	"image"
+	"math"
+)
+
+// Tracker follows something that moves a little between frames, such as the
+// pointer, so that each frame only a crop around where it was last seen
+// needs to go to the model. A crop a fifth the screen's width is 10-20
+// times smaller to upload than the whole frame, and the model sees it at a
+// higher effective resolution, so it also answers more precisely. When the
+// target is lost, the next frame is sent whole.
+type Tracker struct {
+	// Window is the side of the square crop as a fraction of the image's
+	// longer side. Zero means 0.2.
+	Window float64
+
+	last  Point
+	found bool
+}
+
+// Region returns the part of an image with the given bounds to send: a
+// window around the last answer, or the whole image when there is none.
+func (t *Tracker) Region(bounds image.Rectangle) image.Rectangle {
+	if !t.found {
+		return bounds
+	}
+	frac := t.Window
+	if frac <= 0 {
+		frac = 0.2
+	}
+	side := int(math.Round(frac * float64(max(bounds.Dx(), bounds.Dy()))))
+	if crop := around(bounds, t.last, side); !crop.Empty() {
+		return crop
+	}
+	return bounds
+}
+
+// Found records an answer for region, in the region's own pixels, and
+// returns it in the image's. An answer outside the region loses the target.
+func (t *Tracker) Found(region image.Rectangle, p Point) (Point, bool) {
+	abs := Point{X: p.X + float64(region.Min.X), Y: p.Y + float64(region.Min.Y)}
+	if !(image.Point{X: int(abs.X), Y: int(abs.Y)}).In(region) {
+		t.Lost()
+		return Point{}, false
+	}
+	t.last, t.found = abs, true
+	return abs, true
+}
+
+// Lost forgets the target, e.g. when the model could not find it, so the
+// next region is the whole image.
+func (t *Tracker) Lost() {
+	t.found = false
+}
+
+// Tracking reports whether the target's position is known.
+func (t *Tracker) Tracking() bool {
+	return t.found
+}
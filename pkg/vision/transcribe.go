@@ -0,0 +1,22 @@
+package vision
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// transcribePrompt asks for the words alone, so that they can be parsed as
+// commands.
+const transcribePrompt = "Transcribe the speech in this recording exactly, as plain text on one line, with no commentary. If there is no speech, answer with nothing."
+
+// Transcribe has the model write down the speech in audio, a recording of
+// type mimeType such as "audio/wav".
+func (c *Client) Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error) {
+	text, err := c.generate(ctx, genai.Text(transcribePrompt), genai.Blob{MIMEType: mimeType, Data: audio})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
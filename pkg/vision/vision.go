@@ -0,0 +1,372 @@
+// Package vision asks a Gemini model questions about screenshots: where an
+// element is, and what text it shows.
+package vision
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"strings"
+	"sync"
+
+	"agentGo/pkg/frame"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// DefaultModel is the model used when none is configured.
+const DefaultModel = "gemini-1.5-flash"
+
+// Client sends screenshots and prompts to a Gemini model. It may hold an
+// ordered list of models: when one fails, for example because it is
+// overloaded, the call is retried on the next. It may also hold several API
+// keys, which it rotates across according to Rotation.
+type Client struct {
+	keys  []*keyClient
+	names []string
+	// Convention is the coordinate space points are requested in. Connect
+	// sets it to the primary model's default.
+	Convention Convention
+	// MinConfidence, when above zero, makes Locate ask the model how sure
+	// it is and apply LowConfidence to answers scoring below it.
+	MinConfidence float64
+	LowConfidence Policy
+	// Templates are images of targets used by PolicyTemplate.
+	Templates map[string]image.Image
+	// Examples are solved questions sent ahead of every Locate call.
+	Examples []Example
+	// Languages are the languages of the text on the screen, as
+	// ParseLanguages returns them, which Locate, ReadText and Visible match
+	// and read text in. WithLanguages overrides them for a call; empty
+	// leaves the model to recognize the language.
+	Languages []string
+	// Stream makes Locate stream the response and stop reading as soon as
+	// the coordinates have arrived, instead of waiting for the model to
+	// finish.
+	Stream bool
+	// Rotation is how calls are spread over several API keys.
+	Rotation Rotation
+	// Meter, if set, is asked before every call whether it may be made and
+	// told what each call used, to hold a task to a budget.
+	Meter Meter
+	// Scrub, if set, removes secrets from the text of every request before
+	// it is sent.
+	Scrub func(string) string
+	Logf  func(format string, args ...any)
+
+	mu       sync.Mutex
+	answered string
+	next     int
+}
+
+// Connect creates a client for the named model using an API key. modelName
+// may be a comma-separated list such as "gemini-2.0-flash,gemini-1.5-flash"
+// to fall back on the later models when the first fails, and apiKey a
+// comma-separated list of keys to rotate across.
+func Connect(ctx context.Context, apiKey, modelName string) (*Client, error) {
+	names := ParseModels(modelName)
+	c := &Client{names: names, Convention: DefaultConvention(names[0])}
+	for _, key := range strings.Split(apiKey, ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		client, err := genai.NewClient(ctx, option.WithAPIKey(key))
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to create gemini client: %w", err)
+		}
+		k := &keyClient{client: client, usage: KeyUsage{Key: maskKey(key)}}
+		for _, name := range names {
+			k.models = append(k.models, client.GenerativeModel(name))
+		}
+		c.keys = append(c.keys, k)
+	}
+	if len(c.keys) == 0 {
+		return nil, fmt.Errorf("no gemini API key given")
+	}
+	return c, nil
+}
+
+// ParseModels splits a comma-separated model list, defaulting to
+// DefaultModel.
+func ParseModels(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		names = []string{DefaultModel}
+	}
+	return names
+}
+
+// Close releases the underlying connections.
+func (c *Client) Close() error {
+	var err error
+	for _, k := range c.keys {
+		if cerr := k.client.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// ModelName returns the name of the primary model.
+func (c *Client) ModelName() string {
+	return c.names[0]
+}
+
+// Answered returns the name of the model that answered the most recent
+// successful call.
+func (c *Client) Answered() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.answered
+}
+
+// Model exposes the primary generative model for callers that need to tune
+// it directly.
+func (c *Client) Model() *genai.GenerativeModel {
+	return c.keys[0].models[0]
+}
+
+// NewModel returns a fresh handle on the primary model, for callers that
+// configure it for other uses, such as function calling, without affecting
+// the client's own calls.
+func (c *Client) NewModel() *genai.GenerativeModel {
+	return c.keys[0].client.GenerativeModel(c.names[0])
+}
+
+// Ping checks that the primary model can be reached with the first key,
+// by fetching its description, which costs no tokens.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Model().Info(ctx)
+	return err
+}
+
+// Generate sends prompt together with img (PNG encoded) and returns the text
+// of the first candidate.
+func (c *Client) Generate(ctx context.Context, prompt string, img image.Image) (string, error) {
+	data, err := encodePNG(img)
+	if err != nil {
+		return "", err
+	}
+	return c.GeneratePNG(ctx, prompt, data)
+}
+
+// GeneratePNG is like Generate for an already encoded PNG.
+func (c *Client) GeneratePNG(ctx context.Context, prompt string, pngData []byte) (string, error) {
+	return c.generate(ctx, genai.Text(prompt), genai.ImageData("png", pngData))
+}
+
+// GenerateImages sends prompt followed by several images, each preceded by
+// its caption when captions has one, and returns the response text.
+func (c *Client) GenerateImages(ctx context.Context, prompt string, imgs []image.Image, captions []string) (string, error) {
+	parts := []genai.Part{genai.Text(prompt)}
+	for i, img := range imgs {
+		if i < len(captions) && captions[i] != "" {
+			parts = append(parts, genai.Text(captions[i]))
+		}
+		data, err := encodePNG(img)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, genai.ImageData("png", data))
+	}
+	return c.generate(ctx, parts...)
+}
+
+// generate sends a request built from parts and returns the response text.
+func (c *Client) generate(ctx context.Context, parts ...genai.Part) (string, error) {
+	parts = c.scrub(parts)
+	return c.withFallback(ctx, func(m *genai.GenerativeModel) (string, int32, error) {
+		res, err := m.GenerateContent(ctx, parts...)
+		if err != nil {
+			return "", 0, fmt.Errorf("gemini call failed: %w", err)
+		}
+		text, err := responseText(res)
+		return text, tokens(res), err
+	})
+}
+
+// Meter meters a client's calls, as budget.Tracker does.
+type Meter interface {
+	// Allow returns an error if a call to model may not be made.
+	Allow(model string) error
+	// Charge records a call to model that used tokens.
+	Charge(model string, tokens int64)
+}
+
+// withFallback runs call on each model in turn until one succeeds. Within a
+// model, a rate-limited key is swapped for the next one before giving up on
+// the model. A call the Meter does not allow is not made, and not retried
+// on another model.
+func (c *Client) withFallback(ctx context.Context, call func(m *genai.GenerativeModel) (string, int32, error)) (string, error) {
+	var err error
+	for i, name := range c.names {
+		for try := 0; try < len(c.keys); try++ {
+			if c.Meter != nil {
+				if err := c.Meter.Allow(name); err != nil {
+					return "", err
+				}
+			}
+			k := c.pickKey()
+			var text string
+			var used int32
+			text, used, err = call(k.models[i])
+			c.record(k, used, err)
+			if c.Meter != nil {
+				c.Meter.Charge(name, int64(used))
+			}
+			if err == nil {
+				c.mu.Lock()
+				c.answered = name
+				c.mu.Unlock()
+				return text, nil
+			}
+			if ctx.Err() != nil {
+				return "", err
+			}
+			if !isRateLimited(err) || len(c.keys) == 1 {
+				break
+			}
+			c.logf("key %s is rate limited, switching keys", k.usage.Key)
+		}
+		if i < len(c.names)-1 {
+			c.logf("model %s failed, falling back to %s: %v", name, c.names[i+1], err)
+		}
+	}
+	return "", err
+}
+
+// scrub returns parts with the secrets removed from their text.
+func (c *Client) scrub(parts []genai.Part) []genai.Part {
+	if c.Scrub == nil {
+		return parts
+	}
+	out := make([]genai.Part, len(parts))
+	for i, p := range parts {
+		if text, ok := p.(genai.Text); ok {
+			p = genai.Text(c.Scrub(string(text)))
+		}
+		out[i] = p
+	}
+	return out
+}
+
+func (c *Client) logf(format string, args ...any) {
+	if c.Logf != nil {
+		c.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Locate asks the model for the center of the element matching target and
+// returns it in the image's pixel coordinates. With MinConfidence set, an
+// unsure answer is handled by the LowConfidence policy and may fail with
+// ErrLowConfidence.
+func (c *Client) Locate(ctx context.Context, img image.Image, target string) (Point, error) {
+	if c.MinConfidence > 0 {
+		return c.locateConfident(ctx, img, target)
+	}
+	text, err := c.askPoint(ctx, img, target, "", false)
+	if err != nil {
+		return Point{}, err
+	}
+	return c.ParsePoint(text, img.Bounds().Size())
+}
+
+// askPoint asks where target is on img, preceded by the client's examples.
+// hint is extra guidance placed before the format instruction; scored asks
+// for a confidence as well.
+func (c *Client) askPoint(ctx context.Context, img image.Image, target, hint string, scored bool) (string, error) {
+	data, err := encodePNG(img)
+	if err != nil {
+		return "", err
+	}
+	parts := c.exampleParts(scored)
+	parts = append(parts, genai.Text(c.pointPrompt(target, img.Bounds().Size(), c.languageHint(ctx)+hint, scored)), genai.ImageData("png", data))
+	if c.Stream {
+		return c.generateUntil(ctx, func(text string) bool { return pointComplete(text, scored) }, parts...)
+	}
+	return c.generate(ctx, parts...)
+}
+
+func (c *Client) pointPrompt(target string, size image.Point, hint string, scored bool) string {
+	prompt := fmt.Sprintf("Find %s and give the center of it. %s%s", target, hint, c.Convention.Instruction(size))
+	if scored {
+		prompt += " " + ConfidenceInstruction
+	}
+	return prompt
+}
+
+// ParsePoint extracts a point from a response to a prompt that used
+// c.Convention.Instruction and converts it to pixels of an image of the
+// given size.
+func (c *Client) ParsePoint(text string, size image.Point) (Point, error) {
+	x, y, err := ParseCoordinates(text)
+	if err != nil {
+		return Point{}, err
+	}
+	return c.Convention.ToPixels(x, y, size), nil
+}
+
+// ReadText asks the model for the text currently shown in the element
+// matching target.
+func (c *Client) ReadText(ctx context.Context, img image.Image, target string) (string, error) {
+	prompt := fmt.Sprintf(
+		"%sRead the text currently shown in %s. Return only that text with no quotes or explanation. If it is empty, return an empty response.",
+		c.languageHint(ctx), target,
+	)
+	text, err := c.Generate(ctx, prompt, img)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// Visible asks the model whether target is shown on img.
+func (c *Client) Visible(ctx context.Context, img image.Image, target string) (bool, error) {
+	prompt := fmt.Sprintf("%sIs %s visible on this screenshot? Answer only yes or no.", c.languageHint(ctx), target)
+	text, err := c.Generate(ctx, prompt, img)
+	if err != nil {
+		return false, err
+	}
+	answer := strings.ToLower(strings.Trim(strings.TrimSpace(text), " .*_`\""))
+	switch {
+	case strings.HasPrefix(answer, "yes"):
+		return true, nil
+	case strings.HasPrefix(answer, "no"):
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected answer %q to visibility question", text)
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	return frame.EncodePNG(img)
+}
+
+func tokens(res *genai.GenerateContentResponse) int32 {
+	if res == nil || res.UsageMetadata == nil {
+		return 0
+	}
+	return res.UsageMetadata.TotalTokenCount
+}
+
+func responseText(res *genai.GenerateContentResponse) (string, error) {
+	if res == nil || len(res.Candidates) == 0 || res.Candidates[0].Content == nil {
+		return "", fmt.Errorf("gemini returned no candidates")
+	}
+	var b strings.Builder
+	for _, part := range res.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			b.WriteString(string(text))
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,122 @@
+package vnc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"agentGo/pkg/motion"
+)
+
+// Move moves the pointer to normalized (0-1) screen coordinates, gliding
+// there if a motion profile is set.
+func (c *Client) Move(normX, normY float64) error {
+	w, h := c.Size()
+	return c.pointer.Move(normX, normY, func(x, y float64) error {
+		return c.pointerEvent(func() {
+			c.x = clamp(int(x*float64(w)), 0, w-1)
+			c.y = clamp(int(y*float64(h)), 0, h-1)
+		})
+	})
+}
+
+// SetMotion sets how the pointer travels between positions.
+func (c *Client) SetMotion(p motion.Profile) {
+	c.pointer.SetProfile(p)
+}
+
+// Click presses and releases a mouse button at the current position.
+func (c *Client) Click(button string) error {
+	mask, err := buttonMask(button)
+	if err != nil {
+		return err
+	}
+	if err := c.pointerEvent(func() { c.buttons |= mask }); err != nil {
+		return err
+	}
+	time.Sleep(20 * time.Millisecond)
+	return c.pointerEvent(func() { c.buttons &^= mask })
+}
+
+// MouseDown presses and holds a mouse button at the current position.
+func (c *Client) MouseDown(button string) error {
+	mask, err := buttonMask(button)
+	if err != nil {
+		return err
+	}
+	return c.pointerEvent(func() { c.buttons |= mask })
+}
+
+// MouseUp releases a mouse button held by MouseDown.
+func (c *Client) MouseUp(button string) error {
+	mask, err := buttonMask(button)
+	if err != nil {
+		return err
+	}
+	return c.pointerEvent(func() { c.buttons &^= mask })
+}
+
+// Type sends key presses for each character of text.
+func (c *Client) Type(text string) error {
+	for _, r := range text {
+		sym := runeKeysym(r)
+		if err := c.keyEvent(sym, true); err != nil {
+			return err
+		}
+		if err := c.keyEvent(sym, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KeyTap presses a key or combination such as "enter" or "ctrl+a".
+// Modifiers are held while the final key is tapped.
+func (c *Client) KeyTap(key string) error {
+	parts := strings.Split(key, "+")
+	syms := make([]uint32, len(parts))
+	for i, p := range parts {
+		sym, ok := Keysym(strings.TrimSpace(p))
+		if !ok {
+			return fmt.Errorf("unknown key %q", p)
+		}
+		syms[i] = sym
+	}
+	for _, sym := range syms {
+		if err := c.keyEvent(sym, true); err != nil {
+			return err
+		}
+	}
+	for i := len(syms) - 1; i >= 0; i-- {
+		if err := c.keyEvent(syms[i], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buttonMask(button string) (uint8, error) {
+	switch button {
+	case "", "left":
+		return 1, nil
+	case "center", "middle":
+		return 2, nil
+	case "right":
+		return 4, nil
+	case "wheelUp":
+		return 8, nil
+	case "wheelDown":
+		return 16, nil
+	}
+	return 0, fmt.Errorf("unknown mouse button %q", button)
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
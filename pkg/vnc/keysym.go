@@ -0,0 +1,82 @@
+package vnc
+
+import "strings"
+
+// keysyms maps the key names used by scripts (robotgo's names) to X11
+// keysyms.
+var keysyms = map[string]uint32{
+	"backspace":   0xff08,
+	"tab":         0xff09,
+	"enter":       0xff0d,
+	"return":      0xff0d,
+	"escape":      0xff1b,
+	"esc":         0xff1b,
+	"delete":      0xffff,
+	"insert":      0xff63,
+	"home":        0xff50,
+	"end":         0xff57,
+	"pageup":      0xff55,
+	"pagedown":    0xff56,
+	"left":        0xff51,
+	"up":          0xff52,
+	"right":       0xff53,
+	"down":        0xff54,
+	"space":       0x0020,
+	"shift":       0xffe1,
+	"lshift":      0xffe1,
+	"rshift":      0xffe2,
+	"ctrl":        0xffe3,
+	"control":     0xffe3,
+	"lctrl":       0xffe3,
+	"rctrl":       0xffe4,
+	"alt":         0xffe9,
+	"lalt":        0xffe9,
+	"ralt":        0xffea,
+	"cmd":         0xffeb,
+	"command":     0xffeb,
+	"super":       0xffeb,
+	"win":         0xffeb,
+	"capslock":    0xffe5,
+	"printscreen": 0xff61,
+	"f1":          0xffbe,
+	"f2":          0xffbf,
+	"f3":          0xffc0,
+	"f4":          0xffc1,
+	"f5":          0xffc2,
+	"f6":          0xffc3,
+	"f7":          0xffc4,
+	"f8":          0xffc5,
+	"f9":          0xffc6,
+	"f10":         0xffc7,
+	"f11":         0xffc8,
+	"f12":         0xffc9,
+}
+
+// Keysym returns the X11 keysym for a named key or a single character.
+func Keysym(name string) (uint32, bool) {
+	if sym, ok := keysyms[strings.ToLower(name)]; ok {
+		return sym, true
+	}
+	runes := []rune(name)
+	if len(runes) == 1 {
+		return runeKeysym(runes[0]), true
+	}
+	return 0, false
+}
+
+// runeKeysym maps a character to its keysym: Latin-1 characters map
+// directly, and other Unicode characters use the 0x01000000 range.
+func runeKeysym(r rune) uint32 {
+	switch r {
+	case '\n':
+		return keysyms["enter"]
+	case '\t':
+		return keysyms["tab"]
+	case '\b':
+		return keysyms["backspace"]
+	}
+	if r < 0x100 {
+		return uint32(r)
+	}
+	return 0x01000000 | uint32(r)
+}
@@ -0,0 +1,422 @@
+// Package vnc is a minimal RFB (VNC) client that captures the remote frame
+// buffer and injects pointer and keyboard input, so agentGo can drive
+// machines and headless VMs it is not running on.
+package vnc
+
+import (
+	"bufio"
+	"crypto/des"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"agentGo/pkg/frame"
+	"agentGo/pkg/motion"
+)
+
+// Client is a connection to a VNC server. It implements the desktop
+// driver methods (Move, Click, Type, KeyTap, Capture, Close).
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	// Name is the desktop name announced by the server.
+	Name string
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+	fb      *image.RGBA
+
+	pointerMu sync.Mutex
+	x, y      int
+	buttons   uint8
+	pointer   motion.Pointer
+}
+
+const (
+	securityNone    = 1
+	securityVNCAuth = 2
+
+	encodingRaw         = 0
+	encodingCopyRect    = 1
+	encodingDesktopSize = -223
+)
+
+// Dial connects to a VNC server at addr (host:port) and completes the RFB
+// handshake. password is used only if the server requires VNC
+// authentication.
+func Dial(addr, password string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to vnc server: %w", err)
+	}
+	c := &Client{conn: conn, r: bufio.NewReaderSize(conn, 64*1024)}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if err := c.handshake(password); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("vnc handshake with %s failed: %w", addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Size returns the frame buffer dimensions.
+func (c *Client) Size() (int, int) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	b := c.fb.Bounds()
+	return b.Dx(), b.Dy()
+}
+
+func (c *Client) handshake(password string) error {
+	var version [12]byte
+	if _, err := io.ReadFull(c.r, version[:]); err != nil {
+		return err
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(string(version[:]), "RFB %03d.%03d\n", &major, &minor); err != nil {
+		return fmt.Errorf("unexpected protocol version %q", version)
+	}
+	if major != 3 {
+		return fmt.Errorf("unsupported protocol version %d.%d", major, minor)
+	}
+	switch {
+	case minor >= 8:
+		minor = 8
+	case minor == 7:
+	default:
+		minor = 3
+	}
+	if _, err := fmt.Fprintf(c.conn, "RFB 003.%03d\n", minor); err != nil {
+		return err
+	}
+
+	security, err := c.negotiateSecurity(minor)
+	if err != nil {
+		return err
+	}
+	if security == securityVNCAuth {
+		if err := c.vncAuth(password); err != nil {
+			return err
+		}
+	}
+	if security == securityVNCAuth || minor >= 8 {
+		var result uint32
+		if err := binary.Read(c.r, binary.BigEndian, &result); err != nil {
+			return err
+		}
+		if result != 0 {
+			reason := "authentication failed"
+			if minor >= 8 {
+				if r, err := c.readString(); err == nil {
+					reason = r
+				}
+			}
+			return errors.New(reason)
+		}
+	}
+
+	// ClientInit: request a shared session so other viewers stay connected.
+	if _, err := c.conn.Write([]byte{1}); err != nil {
+		return err
+	}
+	var init struct {
+		Width, Height uint16
+		PixelFormat   [16]byte
+	}
+	if err := binary.Read(c.r, binary.BigEndian, &init); err != nil {
+		return err
+	}
+	if c.Name, err = c.readString(); err != nil {
+		return err
+	}
+	c.fb = image.NewRGBA(image.Rect(0, 0, int(init.Width), int(init.Height)))
+
+	// Ask for 32-bit little-endian true colour so raw rectangles decode as
+	// B, G, R, X bytes regardless of the server's native format.
+	setPixelFormat := []byte{
+		0, 0, 0, 0,
+		32, 24, 0, 1, // bits-per-pixel, depth, big-endian, true-colour
+		0, 255, 0, 255, 0, 255, // red, green, blue max
+		16, 8, 0, // red, green, blue shift
+		0, 0, 0,
+	}
+	if _, err := c.conn.Write(setPixelFormat); err != nil {
+		return err
+	}
+	encodings := []int32{encodingCopyRect, encodingRaw, encodingDesktopSize}
+	msg := make([]byte, 4+4*len(encodings))
+	msg[0] = 2
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(encodings)))
+	for i, e := range encodings {
+		binary.BigEndian.PutUint32(msg[4+4*i:], uint32(e))
+	}
+	_, err = c.conn.Write(msg)
+	return err
+}
+
+func (c *Client) negotiateSecurity(minor int) (uint8, error) {
+	if minor == 3 {
+		var security uint32
+		if err := binary.Read(c.r, binary.BigEndian, &security); err != nil {
+			return 0, err
+		}
+		if security == 0 {
+			reason, _ := c.readString()
+			return 0, fmt.Errorf("server refused connection: %s", reason)
+		}
+		if security != securityNone && security != securityVNCAuth {
+			return 0, fmt.Errorf("unsupported security type %d", security)
+		}
+		return uint8(security), nil
+	}
+
+	n, err := c.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		reason, _ := c.readString()
+		return 0, fmt.Errorf("server refused connection: %s", reason)
+	}
+	types := make([]byte, n)
+	if _, err := io.ReadFull(c.r, types); err != nil {
+		return 0, err
+	}
+	chosen := uint8(0)
+	for _, t := range types {
+		if t == securityNone {
+			chosen = securityNone
+			break
+		}
+		if t == securityVNCAuth {
+			chosen = securityVNCAuth
+		}
+	}
+	if chosen == 0 {
+		return 0, fmt.Errorf("no supported security type among %v", types)
+	}
+	_, err = c.conn.Write([]byte{chosen})
+	return chosen, err
+}
+
+// vncAuth answers the DES challenge. VNC uses the password (truncated or
+// zero-padded to 8 bytes) as the key with each byte's bits reversed.
+func (c *Client) vncAuth(password string) error {
+	var challenge [16]byte
+	if _, err := io.ReadFull(c.r, challenge[:]); err != nil {
+		return err
+	}
+	var key [8]byte
+	copy(key[:], password)
+	for i, b := range key {
+		var r byte
+		for bit := 0; bit < 8; bit++ {
+			if b&(1<<bit) != 0 {
+				r |= 0x80 >> bit
+			}
+		}
+		key[i] = r
+	}
+	block, err := des.NewCipher(key[:])
+	if err != nil {
+		return err
+	}
+	var response [16]byte
+	block.Encrypt(response[:8], challenge[:8])
+	block.Encrypt(response[8:], challenge[8:])
+	_, err = c.conn.Write(response[:])
+	return err
+}
+
+func (c *Client) readString() (string, error) {
+	var n uint32
+	if err := binary.Read(c.r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	if n > 1<<20 {
+		return "", fmt.Errorf("string of %d bytes is too long", n)
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(c.r, buf)
+	return string(buf), err
+}
+
+// Capture requests a full frame buffer update and returns a copy of the
+// resulting screen.
+func (c *Client) Capture() (image.Image, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	if err := c.requestUpdate(); err != nil {
+		return nil, err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		msgType, err := c.r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vnc message: %w", err)
+		}
+		switch msgType {
+		case 0:
+			resized, err := c.readFramebufferUpdate()
+			if err != nil {
+				return nil, err
+			}
+			if resized {
+				// The new frame buffer is empty until the next update.
+				if err := c.requestUpdate(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			out := frame.Get(c.fb.Bounds())
+			copy(out.Pix, c.fb.Pix)
+			return out, nil
+		case 1:
+			if err := c.skipColourMap(); err != nil {
+				return nil, err
+			}
+		case 2:
+			// Bell: nothing to read.
+		case 3:
+			if _, err := c.r.Discard(3); err != nil {
+				return nil, err
+			}
+			if _, err := c.readString(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported vnc server message type %d", msgType)
+		}
+	}
+}
+
+// requestUpdate asks for a full, non-incremental frame buffer update.
+func (c *Client) requestUpdate() error {
+	b := c.fb.Bounds()
+	req := make([]byte, 10)
+	req[0] = 3
+	binary.BigEndian.PutUint16(req[6:], uint16(b.Dx()))
+	binary.BigEndian.PutUint16(req[8:], uint16(b.Dy()))
+	return c.write(req)
+}
+
+// readFramebufferUpdate applies one FramebufferUpdate message and reports
+// whether the desktop was resized.
+func (c *Client) readFramebufferUpdate() (bool, error) {
+	var hdr struct {
+		Pad   uint8
+		Rects uint16
+	}
+	if err := binary.Read(c.r, binary.BigEndian, &hdr); err != nil {
+		return false, err
+	}
+	resized := false
+	for i := 0; i < int(hdr.Rects); i++ {
+		var rect struct {
+			X, Y, W, H uint16
+			Encoding   int32
+		}
+		if err := binary.Read(c.r, binary.BigEndian, &rect); err != nil {
+			return false, err
+		}
+		x, y, w, h := int(rect.X), int(rect.Y), int(rect.W), int(rect.H)
+		switch rect.Encoding {
+		case encodingRaw:
+			row := make([]byte, w*4)
+			for j := 0; j < h; j++ {
+				if _, err := io.ReadFull(c.r, row); err != nil {
+					return false, err
+				}
+				if y+j >= c.fb.Rect.Dy() || x >= c.fb.Rect.Dx() {
+					continue
+				}
+				dst := c.fb.Pix[c.fb.PixOffset(x, y+j):]
+				for k := 0; k < w && x+k < c.fb.Rect.Dx(); k++ {
+					dst[4*k] = row[4*k+2]
+					dst[4*k+1] = row[4*k+1]
+					dst[4*k+2] = row[4*k]
+					dst[4*k+3] = 255
+				}
+			}
+		case encodingCopyRect:
+			var src struct{ X, Y uint16 }
+			if err := binary.Read(c.r, binary.BigEndian, &src); err != nil {
+				return false, err
+			}
+			srcRect := image.Rect(int(src.X), int(src.Y), int(src.X)+w, int(src.Y)+h)
+			if !srcRect.In(c.fb.Rect) || !image.Rect(x, y, x+w, y+h).In(c.fb.Rect) {
+				return false, fmt.Errorf("copyrect outside frame buffer")
+			}
+			tmp := image.NewRGBA(image.Rect(0, 0, w, h))
+			for j := 0; j < h; j++ {
+				copy(tmp.Pix[tmp.PixOffset(0, j):tmp.PixOffset(w, j)],
+					c.fb.Pix[c.fb.PixOffset(int(src.X), int(src.Y)+j):c.fb.PixOffset(int(src.X)+w, int(src.Y)+j)])
+			}
+			for j := 0; j < h; j++ {
+				copy(c.fb.Pix[c.fb.PixOffset(x, y+j):c.fb.PixOffset(x+w, y+j)], tmp.Pix[tmp.PixOffset(0, j):tmp.PixOffset(w, j)])
+			}
+		case encodingDesktopSize:
+			c.fb = image.NewRGBA(image.Rect(0, 0, w, h))
+			resized = true
+		default:
+			return false, fmt.Errorf("unsupported vnc encoding %d", rect.Encoding)
+		}
+	}
+	return resized, nil
+}
+
+func (c *Client) skipColourMap() error {
+	var hdr struct {
+		Pad          uint8
+		First, Count uint16
+	}
+	if err := binary.Read(c.r, binary.BigEndian, &hdr); err != nil {
+		return err
+	}
+	_, err := c.r.Discard(int(hdr.Count) * 6)
+	return err
+}
+
+func (c *Client) write(msg []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.conn.Write(msg)
+	return err
+}
+
+// pointerEvent applies update to the pointer state and sends the result.
+func (c *Client) pointerEvent(update func()) error {
+	c.pointerMu.Lock()
+	update()
+	msg := make([]byte, 6)
+	msg[0] = 5
+	msg[1] = c.buttons
+	binary.BigEndian.PutUint16(msg[2:], uint16(c.x))
+	binary.BigEndian.PutUint16(msg[4:], uint16(c.y))
+	c.pointerMu.Unlock()
+	return c.write(msg)
+}
+
+func (c *Client) keyEvent(keysym uint32, down bool) error {
+	msg := make([]byte, 8)
+	msg[0] = 4
+	if down {
+		msg[1] = 1
+	}
+	binary.BigEndian.PutUint32(msg[4:], keysym)
+	return c.write(msg)
+}
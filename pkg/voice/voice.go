@@ -0,0 +1,265 @@
+// Package voice records what is said into the microphone with SoX, which
+// must be installed, and has a speech-to-text backend, such as Gemini or
+// Whisper, transcribe it: to narrate recordings, or to pick out spoken
+// commands such as "agent, stop" or "agent, take over and file this
+// expense report".
+package voice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Transcriber turns recorded speech into text, as vision.Client does.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error)
+}
+
+// maxUtterance is the longest a single recording runs.
+const maxUtterance = 30 * time.Second
+
+// Utterance is something said into the microphone.
+type Utterance struct {
+	// Audio is the recording as WAV.
+	Audio []byte
+	// Start and End are when the speech started and ended.
+	Start, End time.Time
+}
+
+// sampleRate is the rate utterances are recorded at, in 16-bit mono.
+const sampleRate = 16000
+
+// Record waits for speech on the default microphone and records it until a
+// second of silence, as 16 kHz mono WAV.
+func Record(ctx context.Context) (Utterance, error) {
+	if _, err := exec.LookPath("sox"); err != nil {
+		return Utterance{}, errors.New("recording from the microphone needs SoX; install sox")
+	}
+	dir, err := os.MkdirTemp("", "agentgo-voice")
+	if err != nil {
+		return Utterance{}, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "utterance.wav")
+	// silence starts recording once 0.1s is louder than 3% and stops after
+	// 1s quieter than that.
+	cmd := exec.CommandContext(ctx, "sox", "-q", "-d", "-c", "1", "-r", strconv.Itoa(sampleRate), "-b", "16", path,
+		"silence", "1", "0.1", "3%", "1", "1.0", "3%",
+		"trim", "0", strconv.Itoa(int(maxUtterance.Seconds())))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return Utterance{}, ctx.Err()
+		}
+		return Utterance{}, fmt.Errorf("sox failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	u := Utterance{End: time.Now()}
+	if u.Audio, err = os.ReadFile(path); err != nil {
+		return Utterance{}, err
+	}
+	// Silence is not recorded, so the speech started as long before the
+	// end as the recording lasts, less its 44-byte header.
+	samples := max(len(u.Audio)-44, 0) / 2
+	u.Start = u.End.Add(-time.Duration(samples) * time.Second / sampleRate)
+	return u, nil
+}
+
+// Kind is what a command asks for.
+type Kind string
+
+const (
+	// Stop stops whatever the agent is doing.
+	Stop Kind = "stop"
+	// RunJob runs a scheduled job now.
+	RunJob Kind = "run"
+	// Task gives the agent a task in words.
+	Task Kind = "task"
+)
+
+// Command is a spoken command.
+type Command struct {
+	Kind Kind
+	// Job is the job RunJob runs.
+	Job string
+	// Task is the task for the agent.
+	Task string
+	// Heard is the transcript the command came from.
+	Heard string
+}
+
+var (
+	stopWords = []string{"stop", "abort", "cancel", "halt", "kill"}
+	runWords  = []string{"run", "start"}
+	// taskPrefixes are ways of asking that are not part of the task.
+	taskPrefixes = []string{"take over and", "take over", "please", "go ahead and", "can you", "could you"}
+)
+
+// Parse picks the command out of a transcript. Commands start with wake,
+// e.g. "agent", unless wake is empty, so that talk around the microphone
+// is not taken for one; stopping needs no wake word. "run" or "start" and
+// the name of one of jobs runs the job; anything else after the wake word
+// is a task.
+func Parse(text, wake string, jobs []string) (Command, bool) {
+	words := strings.Fields(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || r == '\'' {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, text))
+	if len(words) == 0 {
+		return Command{}, false
+	}
+	if wake = strings.ToLower(strings.TrimSpace(wake)); wake != "" {
+		for len(words) > 0 && (words[0] == "hey" || words[0] == "ok" || words[0] == "okay") {
+			words = words[1:]
+		}
+		if len(words) > 0 && words[0] == wake {
+			words = words[1:]
+		} else if !isStop(words) {
+			return Command{}, false
+		}
+	}
+	if len(words) == 0 {
+		return Command{}, false
+	}
+	cmd := Command{Heard: text}
+	if isStop(words) {
+		cmd.Kind = Stop
+		return cmd, true
+	}
+	rest := strings.Join(words, " ")
+	if contains(runWords, words[0]) {
+		name := strings.TrimPrefix(strings.Join(words[1:], " "), "the ")
+		for _, job := range jobs {
+			if normalize(job) == name || normalize(job)+" job" == name {
+				cmd.Kind, cmd.Job = RunJob, job
+				return cmd, true
+			}
+		}
+	}
+	for _, p := range taskPrefixes {
+		if r, ok := strings.CutPrefix(rest, p+" "); ok {
+			rest = r
+			break
+		}
+	}
+	cmd.Kind, cmd.Task = Task, rest
+	return cmd, true
+}
+
+// isStop reports whether words ask to stop: a stop word, alone or followed
+// by a few words such as "everything" or "the run".
+func isStop(words []string) bool {
+	return len(words) <= 3 && contains(stopWords, words[0])
+}
+
+// normalize spells a job name as it would be transcribed, e.g.
+// "expense-report" as "expense report".
+func normalize(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Listener listens to the microphone for commands.
+type Listener struct {
+	Transcriber Transcriber
+	// Wake is the word commands start with; see Parse.
+	Wake string
+	// Jobs are the names of the jobs commands may run.
+	Jobs []string
+	Logf func(format string, args ...any)
+}
+
+// Listen records, transcribes and parses utterances, passing each command
+// to handle, until ctx is done. Failed transcriptions are logged and
+// skipped; a failure to record ends it.
+func (l *Listener) Listen(ctx context.Context, handle func(Command)) error {
+	for {
+		u, err := Record(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		text, err := l.Transcriber.Transcribe(ctx, u.Audio, "audio/wav")
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			l.logf("voice: failed to transcribe: %v", err)
+			continue
+		}
+		if cmd, ok := Parse(text, l.Wake, l.Jobs); ok {
+			l.logf("voice: heard %q", text)
+			handle(cmd)
+		}
+	}
+}
+
+func (l *Listener) logf(format string, args ...any) {
+	if l.Logf != nil {
+		l.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// Narrate transcribes everything said until ctx is done, passing each
+// utterance's text and when it started to add. Utterances are transcribed
+// in the background so that none is missed, and Narrate waits for those
+// under way before it returns. Failed transcriptions are logged and
+// skipped; a failure to record ends it.
+func Narrate(ctx context.Context, t Transcriber, add func(start time.Time, text string), logf func(format string, args ...any)) error {
+	if logf == nil {
+		logf = log.Printf
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		u, err := Record(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Transcription outlives ctx, which ends the recording.
+			tctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), transcribeTimeout)
+			defer cancel()
+			text, err := t.Transcribe(tctx, u.Audio, "audio/wav")
+			if err != nil {
+				logf("voice: failed to transcribe: %v", err)
+				return
+			}
+			if text != "" {
+				add(u.Start, text)
+			}
+		}()
+	}
+}
+
+// transcribeTimeout bounds the transcription of an utterance.
+const transcribeTimeout = time.Minute
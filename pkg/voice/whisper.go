@@ -0,0 +1,92 @@
+package voice
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// Whisper transcribes with an OpenAI-compatible transcription API: OpenAI's
+// Whisper, or a local server that speaks the same API, such as
+// faster-whisper-server or whisper.cpp's server with
+// --inference-path /v1/audio/transcriptions.
+type Whisper struct {
+	// URL is the API's base URL, e.g. https://api.openai.com/v1 or
+	// http://localhost:8000/v1.
+	URL string
+	// APIKey authorizes the requests; local servers may not need one.
+	APIKey string
+	// Model is the model to use; empty is whisper-1.
+	Model string
+	// Client makes the requests; nil is http.DefaultClient.
+	Client *http.Client
+}
+
+// Transcribe sends audio to the API and returns its text.
+func (w *Whisper) Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error) {
+	ext := "wav"
+	if _, sub, ok := strings.Cut(mimeType, "/"); ok && sub != "" {
+		ext = sub
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", cmp.Or(w.Model, "whisper-1"))
+	form.WriteField("response_format", "json")
+	part, err := form.CreateFormFile("file", "speech."+ext)
+	if err != nil {
+		return "", err
+	}
+	part.Write(audio)
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(w.URL, "/")+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if w.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+w.APIKey)
+	}
+	resp, err := cmp.Or(w.Client, http.DefaultClient).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("transcription failed: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("invalid transcription response: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// TranscriberUsage describes the values ParseTranscriber takes, for flags.
+const TranscriberUsage = "speech-to-text backend: gemini, or the base URL of an OpenAI-compatible transcription API such as https://api.openai.com/v1 or a local Whisper server, authorized by OPENAI_API_KEY if set"
+
+// ParseTranscriber returns the backend spec names: gemini, or empty, for
+// the Gemini client, or an OpenAI-compatible API's base URL for Whisper
+// authorized by apiKey.
+func ParseTranscriber(spec, apiKey string, gemini Transcriber) (Transcriber, error) {
+	switch {
+	case spec == "" || spec == "gemini":
+		return gemini, nil
+	case strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://"):
+		return &Whisper{URL: spec, APIKey: apiKey}, nil
+	}
+	return nil, fmt.Errorf("unknown transcriber %q; want gemini or an http(s) URL", spec)
+}
@@ -0,0 +1,268 @@
+package wayland
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	portalDest = "org.freedesktop.portal.Desktop"
+	portalPath = "/org/freedesktop/portal/desktop"
+	screenCast = "org.freedesktop.portal.ScreenCast"
+
+	sourceMonitor       = 1
+	cursorModeEmbedded  = 2
+	persistUntilRevoked = 2
+)
+
+// ScreenCast is a portal screen-cast session of one monitor. The user is
+// asked to approve it once when it starts; on portals that support it, the
+// approval is remembered for later sessions too.
+type ScreenCast struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+	node    uint32
+	remote  *os.File
+	// Width and Height are the stream size reported by the portal.
+	Width, Height int
+}
+
+var tokenCounter atomic.Uint32
+
+// Start opens a screen-cast session, prompting the user to pick and approve
+// a monitor if no saved approval applies.
+func Start() (*ScreenCast, error) {
+	// A connection of its own, since Close closes it.
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	s := &ScreenCast{conn: conn}
+	if err := s.start(); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to start screen cast: %w", err)
+	}
+	return s, nil
+}
+
+func (s *ScreenCast) start() error {
+	version, _ := s.property("version")
+	cursorModes, _ := s.property("AvailableCursorModes")
+
+	results, err := s.request("CreateSession", nil, map[string]dbus.Variant{
+		"session_handle_token": dbus.MakeVariant(token()),
+	})
+	if err != nil {
+		return err
+	}
+	switch h := results["session_handle"].Value().(type) {
+	case string:
+		s.session = dbus.ObjectPath(h)
+	case dbus.ObjectPath:
+		s.session = h
+	default:
+		return errors.New("portal returned no session handle")
+	}
+
+	options := map[string]dbus.Variant{
+		"types":    dbus.MakeVariant(uint32(sourceMonitor)),
+		"multiple": dbus.MakeVariant(false),
+	}
+	if cursorModes&cursorModeEmbedded != 0 {
+		options["cursor_mode"] = dbus.MakeVariant(uint32(cursorModeEmbedded))
+	}
+	if version >= 4 {
+		options["persist_mode"] = dbus.MakeVariant(uint32(persistUntilRevoked))
+		if restore := loadRestoreToken(); restore != "" {
+			options["restore_token"] = dbus.MakeVariant(restore)
+		}
+	}
+	if _, err := s.request("SelectSources", []any{s.session}, options); err != nil {
+		return err
+	}
+
+	results, err = s.request("Start", []any{s.session, ""}, map[string]dbus.Variant{})
+	if err != nil {
+		return err
+	}
+	if restore, ok := results["restore_token"].Value().(string); ok {
+		saveRestoreToken(restore)
+	}
+	var streams []struct {
+		Node  uint32
+		Props map[string]dbus.Variant
+	}
+	if err := results["streams"].Store(&streams); err != nil || len(streams) == 0 {
+		return errors.New("portal returned no streams")
+	}
+	s.node = streams[0].Node
+	var size struct{ W, H int32 }
+	if v, ok := streams[0].Props["size"]; ok && v.Store(&size) == nil {
+		s.Width, s.Height = int(size.W), int(size.H)
+	}
+
+	var fd dbus.UnixFD
+	err = s.portal().Call(screenCast+".OpenPipeWireRemote", 0, s.session, map[string]dbus.Variant{}).Store(&fd)
+	if err != nil {
+		return fmt.Errorf("OpenPipeWireRemote: %w", err)
+	}
+	s.remote = os.NewFile(uintptr(fd), "pipewire-remote")
+	return nil
+}
+
+// Capture grabs one frame from the stream. It runs a short GStreamer
+// pipeline on the PipeWire remote, so gst-launch-1.0 and the pipewire
+// GStreamer plugin must be installed.
+func (s *ScreenCast) Capture() (image.Image, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "gst-launch-1.0", "-q",
+		"pipewiresrc", "fd=3", "path="+strconv.FormatUint(uint64(s.node), 10), "num-buffers=1", "always-copy=true",
+		"!", "videoconvert", "!", "pngenc", "snapshot=true", "!", "fdsink", "fd=1")
+	cmd.ExtraFiles = []*os.File{s.remote}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to read screen cast frame: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("failed to read screen cast frame: %w", err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screen cast frame: %w", err)
+	}
+	return img, nil
+}
+
+// Close ends the session.
+func (s *ScreenCast) Close() error {
+	if s.session != "" {
+		s.conn.Object(portalDest, s.session).Call("org.freedesktop.portal.Session.Close", 0)
+	}
+	if s.remote != nil {
+		s.remote.Close()
+	}
+	return s.conn.Close()
+}
+
+func (s *ScreenCast) portal() dbus.BusObject {
+	return s.conn.Object(portalDest, portalPath)
+}
+
+// property reads a uint32 property of the ScreenCast portal.
+func (s *ScreenCast) property(name string) (uint32, error) {
+	v, err := s.portal().GetProperty(screenCast + "." + name)
+	if err != nil {
+		return 0, err
+	}
+	if n, ok := v.Value().(uint32); ok {
+		return n, nil
+	}
+	return 0, fmt.Errorf("unexpected value for %s", name)
+}
+
+// request calls a portal method that answers through a Request object and
+// waits for its Response signal, which may take a while if the user is
+// being asked for permission.
+func (s *ScreenCast) request(method string, args []any, options map[string]dbus.Variant) (map[string]dbus.Variant, error) {
+	handleToken := token()
+	options["handle_token"] = dbus.MakeVariant(handleToken)
+
+	sender := strings.ReplaceAll(strings.TrimPrefix(s.conn.Names()[0], ":"), ".", "_")
+	expected := dbus.ObjectPath(portalPath + "/request/" + sender + "/" + handleToken)
+	match := []dbus.MatchOption{
+		dbus.WithMatchInterface("org.freedesktop.portal.Request"),
+		dbus.WithMatchMember("Response"),
+		dbus.WithMatchObjectPath(expected),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return nil, err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+	signals := make(chan *dbus.Signal, 16)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	handle := expected
+	if err := s.portal().Call(screenCast+"."+method, 0, append(args, options)...).Store(&handle); err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+
+	timeout := time.After(2 * time.Minute)
+	for {
+		select {
+		case sig, ok := <-signals:
+			if !ok {
+				return nil, fmt.Errorf("%s: d-bus connection closed", method)
+			}
+			if sig.Name != "org.freedesktop.portal.Request.Response" || sig.Path != handle {
+				continue
+			}
+			var code uint32
+			var results map[string]dbus.Variant
+			if err := dbus.Store(sig.Body, &code, &results); err != nil {
+				continue
+			}
+			switch code {
+			case 0:
+				return results, nil
+			case 1:
+				return nil, fmt.Errorf("%s: screen sharing was cancelled", method)
+			default:
+				return nil, fmt.Errorf("%s: portal request failed", method)
+			}
+		case <-timeout:
+			return nil, fmt.Errorf("%s: timed out waiting for the portal", method)
+		}
+	}
+}
+
+func token() string {
+	return fmt.Sprintf("agentgo%d_%d", os.Getpid(), tokenCounter.Add(1))
+}
+
+// restoreTokenPath is where the portal's restore token is kept so later runs
+// can skip the permission prompt.
+func restoreTokenPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "agentgo", "screencast-restore-token")
+}
+
+func loadRestoreToken() string {
+	path := restoreTokenPath()
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func saveRestoreToken(restore string) {
+	path := restoreTokenPath()
+	if path == "" {
+		return
+	}
+	os.MkdirAll(filepath.Dir(path), 0o700)
+	os.WriteFile(path, []byte(restore+"\n"), 0o600)
+}
@@ -0,0 +1,47 @@
+//go:build !linux
+
+package wayland
+
+import (
+	"errors"
+	"image"
+)
+
+// ScreenCast is a portal screen-cast session. Portals exist only on Linux.
+type ScreenCast struct {
+	Width, Height int
+}
+
+// Start reports that screen casting is unsupported on this platform.
+func Start() (*ScreenCast, error) {
+	return nil, errors.New("wayland screen casting is only supported on linux")
+}
+
+// Capture implements the capture interface.
+func (s *ScreenCast) Capture() (image.Image, error) {
+	return nil, errors.New("wayland screen casting is only supported on linux")
+}
+
+// Close implements the capture interface.
+func (s *ScreenCast) Close() error {
+	return nil
+}
+
+// Stream is a continuous capture of a screen cast. Portals exist only on
+// Linux.
+type Stream struct{}
+
+// Stream reports that screen casting is unsupported on this platform.
+func (s *ScreenCast) Stream() (*Stream, error) {
+	return nil, errors.New("wayland screen casting is only supported on linux")
+}
+
+// Capture implements the capture interface.
+func (st *Stream) Capture() (*image.RGBA, error) {
+	return nil, errors.New("wayland screen casting is only supported on linux")
+}
+
+// Close implements the capture interface.
+func (st *Stream) Close() error {
+	return nil
+}
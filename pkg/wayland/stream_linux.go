@@ -0,0 +1,122 @@
+package wayland
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"agentGo/pkg/frame"
+)
+
+// Stream is a continuous capture of a screen cast for video frame rates:
+// one long-running GStreamer pipeline delivers raw RGBA frames, instead of
+// a pipeline started for every frame as Capture does.
+type Stream struct {
+	cmd           *exec.Cmd
+	stderr        bytes.Buffer
+	width, height int
+
+	mu     sync.Mutex
+	latest []byte
+	err    error
+	ready  chan struct{}
+	once   sync.Once
+	done   chan struct{}
+}
+
+// Stream starts streaming the cast. It needs gst-launch-1.0 and the pipewire
+// GStreamer plugin, as Capture does.
+func (s *ScreenCast) Stream() (*Stream, error) {
+	if s.Width <= 0 || s.Height <= 0 {
+		return nil, errors.New("the portal did not report the screen cast size")
+	}
+	st := &Stream{width: s.Width, height: s.Height, ready: make(chan struct{}), done: make(chan struct{})}
+	// Scaling to the size the portal reported fixes the frame size, so
+	// frames can be read back to back without any framing.
+	st.cmd = exec.Command("gst-launch-1.0", "-q",
+		"pipewiresrc", "fd=3", "path="+strconv.FormatUint(uint64(s.node), 10), "always-copy=true",
+		"!", "videoconvert", "!", "videoscale",
+		"!", fmt.Sprintf("video/x-raw,format=RGBA,width=%d,height=%d", s.Width, s.Height),
+		"!", "fdsink", "fd=1", "sync=false")
+	st.cmd.ExtraFiles = []*os.File{s.remote}
+	st.cmd.Stderr = &st.stderr
+	out, err := st.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := st.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start screen cast stream: %w", err)
+	}
+	go st.read(out)
+
+	select {
+	case <-st.ready:
+	case <-time.After(10 * time.Second):
+		st.Close()
+		return nil, errors.New("timed out waiting for the first screen cast frame")
+	}
+	st.mu.Lock()
+	err = st.err
+	st.mu.Unlock()
+	if err != nil {
+		st.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// read keeps the latest frame until the pipeline ends, reading into a spare
+// buffer so that Capture never sees a half-written frame.
+func (st *Stream) read(r io.Reader) {
+	defer close(st.done)
+	spare := make([]byte, 4*st.width*st.height)
+	for {
+		if _, err := io.ReadFull(r, spare); err != nil {
+			// Waiting for the pipeline also finishes collecting its
+			// stderr.
+			st.cmd.Wait()
+			st.mu.Lock()
+			st.err = fmt.Errorf("screen cast stream ended: %w", err)
+			if msg := strings.TrimSpace(st.stderr.String()); msg != "" {
+				st.err = fmt.Errorf("%w: %s", st.err, msg)
+			}
+			st.mu.Unlock()
+			st.once.Do(func() { close(st.ready) })
+			return
+		}
+		st.mu.Lock()
+		st.latest, spare = spare, st.latest
+		st.mu.Unlock()
+		if spare == nil {
+			spare = make([]byte, 4*st.width*st.height)
+		}
+		st.once.Do(func() { close(st.ready) })
+	}
+}
+
+// Capture returns a copy of the latest frame.
+func (st *Stream) Capture() (*image.RGBA, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.err != nil {
+		return nil, st.err
+	}
+	img := frame.Get(image.Rect(0, 0, st.width, st.height))
+	copy(img.Pix, st.latest)
+	return img, nil
+}
+
+// Close stops the stream.
+func (st *Stream) Close() error {
+	st.cmd.Process.Kill()
+	<-st.done
+	return nil
+}
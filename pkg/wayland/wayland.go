@@ -0,0 +1,11 @@
+// Package wayland captures the screen on Wayland sessions, where X11 capture
+// returns black frames, through the xdg-desktop-portal ScreenCast API and
+// PipeWire.
+package wayland
+
+import "os"
+
+// Session reports whether the current desktop session is Wayland.
+func Session() bool {
+	return os.Getenv("XDG_SESSION_TYPE") == "wayland" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
@@ -0,0 +1,214 @@
+// Package x11 drives an X display other than the one agentGo runs on, such
+// as a private Xvfb server. Input goes through xdotool and capture through
+// xwd, so no cgo or X libraries are needed at build time.
+//
+// This is how recordings replay in CI containers without a desktop. With
+// Xvfb, xdotool and xwd installed (on Debian: xvfb xdotool x11-apps):
+//
+//	agentgo play -driver xvfb://1280x800 task.json
+//
+// starts a private Xvfb display of that size, points DISPLAY at it for any
+// programs agentgo starts, runs the task and stops the server afterwards.
+// When DISPLAY is unset, the default local driver does the same at
+// 1920x1080. To drive a display started elsewhere,
+// for example "Xvfb :99 &" in a CI step or a nested Xephyr server, use
+// -driver x11://:99; the application under test must be started on the
+// same display.
+package x11
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"agentGo/pkg/motion"
+)
+
+// Display is a desktop driver for an X display. It implements the desktop
+// driver methods (Move, Click, Type, KeyTap, Capture, Close).
+type Display struct {
+	// Name is the X display name, e.g. ":99".
+	Name string
+
+	pointer motion.Pointer
+}
+
+// NewDisplay returns a driver for the named X display.
+func NewDisplay(name string) *Display {
+	return &Display{Name: name}
+}
+
+// Size returns the display dimensions in pixels.
+func (d *Display) Size() (int, int, error) {
+	out, err := d.run("xdotool", "getdisplaygeometry")
+	if err != nil {
+		return 0, 0, err
+	}
+	var w, h int
+	if _, err := fmt.Sscan(string(out), &w, &h); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse display geometry %q: %w", out, err)
+	}
+	return w, h, nil
+}
+
+// Move moves the pointer to normalized (0-1) screen coordinates, gliding
+// there if a motion profile is set.
+func (d *Display) Move(normX, normY float64) error {
+	w, h, err := d.Size()
+	if err != nil {
+		return err
+	}
+	return d.pointer.Move(normX, normY, func(nx, ny float64) error {
+		x := clamp(int(nx*float64(w)), 0, w-1)
+		y := clamp(int(ny*float64(h)), 0, h-1)
+		_, err := d.run("xdotool", "mousemove", strconv.Itoa(x), strconv.Itoa(y))
+		return err
+	})
+}
+
+// SetMotion sets how the pointer travels between positions.
+func (d *Display) SetMotion(p motion.Profile) {
+	d.pointer.SetProfile(p)
+}
+
+// Click presses and releases a mouse button at the current position.
+func (d *Display) Click(button string) error {
+	b, err := xButton(button)
+	if err != nil {
+		return err
+	}
+	_, err = d.run("xdotool", "click", b)
+	return err
+}
+
+// MouseDown presses and holds a mouse button at the current position.
+func (d *Display) MouseDown(button string) error {
+	b, err := xButton(button)
+	if err != nil {
+		return err
+	}
+	_, err = d.run("xdotool", "mousedown", b)
+	return err
+}
+
+// MouseUp releases a mouse button held by MouseDown.
+func (d *Display) MouseUp(button string) error {
+	b, err := xButton(button)
+	if err != nil {
+		return err
+	}
+	_, err = d.run("xdotool", "mouseup", b)
+	return err
+}
+
+// xButton returns the X button number of a mouse button name.
+func xButton(button string) (string, error) {
+	switch button {
+	case "", "left":
+		return "1", nil
+	case "center", "middle":
+		return "2", nil
+	case "right":
+		return "3", nil
+	case "wheelUp":
+		return "4", nil
+	case "wheelDown":
+		return "5", nil
+	}
+	return "", fmt.Errorf("unknown mouse button %q", button)
+}
+
+// Type types text into the focused window.
+func (d *Display) Type(text string) error {
+	_, err := d.run("xdotool", "type", "--delay", "12", "--", text)
+	return err
+}
+
+// KeyTap presses a key or combination such as "enter" or "ctrl+a".
+func (d *Display) KeyTap(key string) error {
+	parts := strings.Split(key, "+")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if name, ok := keyNames[strings.ToLower(p)]; ok {
+			p = name
+		}
+		parts[i] = p
+	}
+	_, err := d.run("xdotool", "key", "--", strings.Join(parts, "+"))
+	return err
+}
+
+// Capture grabs the whole display.
+func (d *Display) Capture() (image.Image, error) {
+	out, err := d.run("xwd", "-root", "-silent")
+	if err != nil {
+		return nil, err
+	}
+	return DecodeXWD(bytes.NewReader(out))
+}
+
+// Close implements the driver interface; a Display owns no resources.
+func (d *Display) Close() error {
+	return nil
+}
+
+func (d *Display) run(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), "DISPLAY="+d.Name)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s %s failed: %w: %s", name, args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s %s failed: %w", name, args[0], err)
+	}
+	return out, nil
+}
+
+// keyNames maps the key names used by scripts (robotgo's names) to X keysym
+// names understood by xdotool.
+var keyNames = map[string]string{
+	"backspace":   "BackSpace",
+	"tab":         "Tab",
+	"enter":       "Return",
+	"return":      "Return",
+	"escape":      "Escape",
+	"esc":         "Escape",
+	"delete":      "Delete",
+	"insert":      "Insert",
+	"home":        "Home",
+	"end":         "End",
+	"pageup":      "Prior",
+	"pagedown":    "Next",
+	"left":        "Left",
+	"up":          "Up",
+	"right":       "Right",
+	"down":        "Down",
+	"space":       "space",
+	"shift":       "shift",
+	"ctrl":        "ctrl",
+	"control":     "ctrl",
+	"alt":         "alt",
+	"cmd":         "super",
+	"command":     "super",
+	"super":       "super",
+	"win":         "super",
+	"capslock":    "Caps_Lock",
+	"printscreen": "Print",
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,108 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"agentGo/pkg/input"
+)
+
+// Input returns an input backend that drives the display with xdotool, for
+// the local desktop where robotgo's cgo build is unavailable (for example
+// in containers): desktop.UseInput(x11.NewDisplay(os.Getenv("DISPLAY")).Input()).
+// Coordinates are the display's pixels. It cannot hook input events.
+func (d *Display) Input() input.Backend {
+	return xdotool{d}
+}
+
+type xdotool struct{ d *Display }
+
+func (x xdotool) Move(px, py int) error {
+	_, err := x.d.run("xdotool", "mousemove", strconv.Itoa(px), strconv.Itoa(py))
+	return err
+}
+
+func (x xdotool) Position() (int, int, error) {
+	out, err := x.d.run("xdotool", "getmouselocation", "--shell")
+	if err != nil {
+		return 0, 0, err
+	}
+	var px, py int
+	var seen int
+	for _, line := range strings.Split(string(out), "\n") {
+		k, v, _ := strings.Cut(strings.TrimSpace(line), "=")
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		switch k {
+		case "X":
+			px, seen = n, seen|1
+		case "Y":
+			py, seen = n, seen|2
+		}
+	}
+	if seen != 3 {
+		return 0, 0, fmt.Errorf("failed to parse pointer location %q", out)
+	}
+	return px, py, nil
+}
+
+func (x xdotool) Click(button string) error {
+	return x.d.Click(button)
+}
+
+func (x xdotool) Toggle(button string, down bool) error {
+	if down {
+		return x.d.MouseDown(button)
+	}
+	return x.d.MouseUp(button)
+}
+
+func (x xdotool) Type(text string) error {
+	return x.d.Type(text)
+}
+
+func (x xdotool) KeyTap(key string, modifiers ...string) error {
+	return x.d.KeyTap(strings.Join(append(modifiers, key), "+"))
+}
+
+// ReleaseKeys implements input.KeyReleaser.
+func (x xdotool) ReleaseKeys(keys ...string) error {
+	args := []string{"keyup", "--"}
+	for _, k := range keys {
+		if name, ok := keyNames[strings.ToLower(k)]; ok {
+			k = name
+		}
+		args = append(args, k)
+	}
+	_, err := x.d.run("xdotool", args...)
+	return err
+}
+
+// Scroll clicks the X wheel buttons: 4 and 5 scroll up and down, 6 and 7
+// left and right.
+func (x xdotool) Scroll(dx, dy int) error {
+	for _, s := range []struct {
+		n             int
+		less, greater string
+	}{{dy, "4", "5"}, {dx, "6", "7"}} {
+		b := s.greater
+		if s.n < 0 {
+			b, s.n = s.less, -s.n
+		}
+		if s.n == 0 {
+			continue
+		}
+		if _, err := x.d.run("xdotool", "click", "--repeat", strconv.Itoa(s.n), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (xdotool) Hook(context.Context) (<-chan input.Event, error) {
+	return nil, input.ErrUnsupported
+}
@@ -0,0 +1,70 @@
+package x11
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Xvfb is a private virtual X server started by StartXvfb.
+type Xvfb struct {
+	*Display
+	cmd *exec.Cmd
+}
+
+// StartXvfb starts an Xvfb server with a single screen of the given size on
+// the first free display number and returns a driver for it.
+func StartXvfb(width, height int) (*Xvfb, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// -displayfd makes Xvfb pick a free display and report it on fd 3 once
+	// it is ready to accept connections.
+	cmd := exec.Command("Xvfb", "-displayfd", "3", "-nolisten", "tcp",
+		"-screen", "0", fmt.Sprintf("%dx%dx24", width, height))
+	cmd.ExtraFiles = []*os.File{w}
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to start Xvfb: %w", err)
+	}
+	w.Close()
+
+	type result struct {
+		line string
+		err  error
+	}
+	ready := make(chan result, 1)
+	go func() {
+		line, err := bufio.NewReader(r).ReadString('\n')
+		ready <- result{line, err}
+	}()
+
+	select {
+	case res := <-ready:
+		n, err := strconv.Atoi(strings.TrimSpace(res.line))
+		if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, fmt.Errorf("Xvfb did not report a display number: %v", res.err)
+		}
+		return &Xvfb{Display: NewDisplay(":" + strconv.Itoa(n)), cmd: cmd}, nil
+	case <-time.After(15 * time.Second):
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("timed out waiting for Xvfb to start")
+	}
+}
+
+// Close stops the X server.
+func (x *Xvfb) Close() error {
+	x.cmd.Process.Kill()
+	x.cmd.Wait()
+	return nil
+}
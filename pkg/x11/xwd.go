@@ -0,0 +1,129 @@
+package x11
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math/bits"
+
+	"agentGo/pkg/frame"
+)
+
+// xwdHeader is the fixed part of an XWD (X Window Dump) file. xwd always
+// writes it most-significant byte first.
+type xwdHeader struct {
+	HeaderSize      uint32
+	FileVersion     uint32
+	PixmapFormat    uint32
+	PixmapDepth     uint32
+	PixmapWidth     uint32
+	PixmapHeight    uint32
+	XOffset         uint32
+	ByteOrder       uint32
+	BitmapUnit      uint32
+	BitmapBitOrder  uint32
+	BitmapPad       uint32
+	BitsPerPixel    uint32
+	BytesPerLine    uint32
+	VisualClass     uint32
+	RedMask         uint32
+	GreenMask       uint32
+	BlueMask        uint32
+	BitsPerRGB      uint32
+	ColormapEntries uint32
+	NColors         uint32
+	WindowWidth     uint32
+	WindowHeight    uint32
+	WindowX         uint32
+	WindowY         uint32
+	WindowBdrWidth  uint32
+}
+
+const xwdZPixmap = 2
+
+// DecodeXWD decodes a true-colour ZPixmap XWD image as produced by
+// "xwd -root".
+func DecodeXWD(r io.Reader) (*image.RGBA, error) {
+	br := bufio.NewReader(r)
+	var h xwdHeader
+	if err := binary.Read(br, binary.BigEndian, &h); err != nil {
+		return nil, fmt.Errorf("failed to read xwd header: %w", err)
+	}
+	if h.FileVersion != 7 {
+		return nil, fmt.Errorf("unsupported xwd version %d", h.FileVersion)
+	}
+	if h.PixmapFormat != xwdZPixmap {
+		return nil, fmt.Errorf("unsupported xwd pixmap format %d", h.PixmapFormat)
+	}
+	if h.BitsPerPixel != 16 && h.BitsPerPixel != 24 && h.BitsPerPixel != 32 {
+		return nil, fmt.Errorf("unsupported xwd depth of %d bits per pixel", h.BitsPerPixel)
+	}
+	const headerLen = 100
+	skip := int(h.HeaderSize) - headerLen + int(h.NColors)*12
+	if skip < 0 {
+		return nil, fmt.Errorf("invalid xwd header size %d", h.HeaderSize)
+	}
+	if _, err := br.Discard(skip); err != nil {
+		return nil, fmt.Errorf("failed to read xwd header: %w", err)
+	}
+
+	w, hgt := int(h.PixmapWidth), int(h.PixmapHeight)
+	// Every pixel is written below, so a recycled frame will do.
+	img := frame.Get(image.Rect(0, 0, w, hgt))
+	bytesPerPixel := int(h.BitsPerPixel) / 8
+	if int(h.BytesPerLine) < w*bytesPerPixel {
+		return nil, fmt.Errorf("invalid xwd line length %d", h.BytesPerLine)
+	}
+	line := make([]byte, h.BytesPerLine)
+	red := newChannel(h.RedMask)
+	green := newChannel(h.GreenMask)
+	blue := newChannel(h.BlueMask)
+	msbFirst := h.ByteOrder == 1
+
+	for y := 0; y < hgt; y++ {
+		if _, err := io.ReadFull(br, line); err != nil {
+			return nil, fmt.Errorf("failed to read xwd pixels: %w", err)
+		}
+		dst := img.Pix[img.PixOffset(0, y):]
+		for x := 0; x < w; x++ {
+			p := line[x*bytesPerPixel : (x+1)*bytesPerPixel]
+			var v uint32
+			for i := range p {
+				if msbFirst {
+					v = v<<8 | uint32(p[i])
+				} else {
+					v |= uint32(p[i]) << (8 * i)
+				}
+			}
+			dst[4*x] = red.value(v)
+			dst[4*x+1] = green.value(v)
+			dst[4*x+2] = blue.value(v)
+			dst[4*x+3] = 255
+		}
+	}
+	return img, nil
+}
+
+// channel extracts an 8-bit colour component from a pixel using its mask.
+type channel struct {
+	mask  uint32
+	shift int
+	max   uint32
+}
+
+func newChannel(mask uint32) channel {
+	shift := bits.TrailingZeros32(mask)
+	if mask == 0 {
+		return channel{}
+	}
+	return channel{mask: mask, shift: shift, max: mask >> shift}
+}
+
+func (c channel) value(p uint32) uint8 {
+	if c.max == 0 {
+		return 0
+	}
+	return uint8((p & c.mask) >> c.shift * 255 / c.max)
+}
@@ -1,80 +1,132 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
-	"time"
 
-	"github.com/go-vgo/robotgo"
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/humanize"
+	"agentGo/pkg/motion"
+	"agentGo/pkg/playback"
+	"agentGo/pkg/preflight"
+	"agentGo/pkg/profile"
+	"agentGo/pkg/script"
+	"agentGo/pkg/vars"
 )
 
 func main() {
-	// Open the CSV file
-	file, err := os.Open("mouse_movements.csv")
-	if err != nil {
-		log.Fatalf("failed to open csv file: %v", err)
+	scriptPath := flag.String("script", "", "run a JSON task script instead of playing back a CSV recording")
+	inPath := flag.String("in", "mouse_movements.csv", "path of the CSV file to play back; may contain ${VAR} references")
+	driverSpec := flag.String("driver", "local", "desktop to drive: local, xvfb[://WxH], x11://:N, vnc://host:port, rdp://user@host or adb://[serial]")
+	displayIndex := flag.Int("display", 0, "index of the local display to play back on (see agentgo displays)")
+	valuesFile := flag.String("values", "", "file of NAME=VALUE lines (or a JSON object) used for ${VAR} substitution")
+	var varFlags vars.Flag
+	flag.Var(&varFlags, "var", "set a substitution variable as NAME=VALUE (repeatable)")
+	envFile := flag.String("env-file", "", "file of NAME=VALUE environment settings, read in addition to ./.env")
+	profileName := flag.String("profile", "", "named profile of settings to use (default: $AGENTGO_PROFILE)")
+	flag.Parse()
+
+	// Flags given on the command line win over the environment, which wins
+	// over .env files, which win over the profile.
+	if *envFile != "" {
+		if err := dotenv.Load(*envFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := dotenv.LoadDefault(); err != nil {
+		log.Fatal(err)
+	}
+	if err := profile.Use("", *profileName); err != nil {
+		log.Fatal(err)
+	}
+	if err := dotenv.ApplyFlags(flag.CommandLine); err != nil {
+		log.Fatal(err)
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	variables, err := vars.Load(*valuesFile, varFlags)
 	if err != nil {
-		log.Fatalf("failed to read csv records: %v", err)
+		log.Fatalf("failed to load variables: %v", err)
 	}
-
-	// Remove header row
-	if len(records) > 0 {
-		records = records[1:]
+	spec := *driverSpec
+	if *displayIndex != 0 {
+		if spec != "local" {
+			log.Fatalf("-display applies only to the local driver, not %q", spec)
+		}
+		spec = fmt.Sprintf("local:%d", *displayIndex)
 	}
-
-	// Get logical screen dimensions for playback
-	logicalWidth, logicalHeight := robotgo.GetScreenSize()
-	log.Printf("Playing back on logical screen size: %d x %d", logicalWidth, logicalHeight)
-
-	log.Println("Starting mouse playback...")
-
-	var lastTimestamp int64
-
-	for i, record := range records {
-		if len(record) != 3 {
-			log.Printf("skipping malformed record: %v", record)
-			continue
+	drv, err := desktop.Open(spec)
+	if err != nil {
+		log.Fatalf("failed to open driver: %v", err)
+	}
+	defer drv.Close()
+	pointer, err := motion.Parse(os.Getenv(dotenv.EnvName("motion")))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("motion"), err)
+	}
+	// A humanized run varies its timing and its moves, from one seed.
+	config, err := humanize.Parse(os.Getenv(dotenv.EnvName("humanize")))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("humanize"), err)
+	}
+	human := humanize.New(config)
+	if human != nil {
+		log.Printf("Humanizing input with seed %d", config.Seed)
+		if pointer.Easing == "" {
+			pointer.Easing = motion.Human
 		}
-
-		// Parse the record
-		timestamp, err := strconv.ParseInt(record[0], 10, 64)
-		if err != nil {
-			log.Printf("failed to parse timestamp: %v", err)
-			continue
+		pointer.Overshoot = human.OvershootTo
+	}
+	desktop.UseMotion(drv, pointer)
+	if err := desktop.UseCapture(os.Getenv(dotenv.EnvName("capture"))); err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("capture"), err)
+	}
+	if _, ok := drv.(*desktop.Local); ok {
+		if err := desktop.UseInputDriver(os.Getenv(dotenv.EnvName("input"))); err != nil {
+			log.Fatalf("invalid %s: %v", dotenv.EnvName("input"), err)
 		}
-		normX, err := strconv.ParseFloat(record[1], 64)
-		if err != nil {
-			log.Printf("failed to parse normalized x coordinate: %v", err)
-			continue
+		if err := preflight.Check(); err != nil {
+			log.Fatal(err)
 		}
-		normY, err := strconv.ParseFloat(record[2], 64)
+	}
+
+	if *scriptPath != "" {
+		scriptFile, err := variables.Expand(*scriptPath)
 		if err != nil {
-			log.Printf("failed to parse normalized y coordinate: %v", err)
-			continue
+			log.Fatalf("failed to expand script path: %v", err)
 		}
+		runScript(drv, scriptFile, variables, human)
+		return
+	}
 
-		// Wait for the correct amount of time
-		if i > 0 {
-			delay := time.Duration(timestamp-lastTimestamp) * time.Millisecond
-			time.Sleep(delay)
-		}
-		lastTimestamp = timestamp
+	csvPath, err := variables.Expand(*inPath)
+	if err != nil {
+		log.Fatalf("failed to expand input path: %v", err)
+	}
+
+	if local, ok := drv.(*desktop.Local); ok {
+		// Get logical screen dimensions for playback
+		logicalWidth, logicalHeight := local.ScreenSize()
+		log.Printf("Playing back on logical screen size: %d x %d", logicalWidth, logicalHeight)
+	}
+
+	log.Println("Starting mouse playback...")
 
-		// De-normalize the coordinates for the current screen and move the mouse
-		finalX := int(normX * float64(logicalWidth))
-		finalY := int(normY * float64(logicalHeight))
-		
-		fmt.Printf("Moving mouse to (%d, %d) (Normalized: %.4f, %.4f)\n", finalX, finalY, normX, normY)
-		robotgo.Move(finalX, finalY)
+	player := &playback.Player{
+		Mover: drv,
+		Human: human,
+		// Ask whether to go on if someone takes the mouse or keyboard.
+		OnInterference: script.AskToResume(os.Stdin, os.Stderr),
+		Logf: func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		},
+	}
+	if err := player.PlayFile(context.Background(), csvPath); err != nil {
+		log.Fatalf("playback failed: %v", err)
 	}
 
 	log.Println("Playback finished.")
-}
\ No newline at end of file
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/humanize"
+	"agentGo/pkg/script"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/vars"
+	"agentGo/pkg/vision"
+)
+
+// runScript loads and executes a task script on drv, humanized by human if
+// it is non-nil.
+func runScript(drv desktop.Driver, path string, variables *vars.Set, human *humanize.Behavior) {
+	s, err := script.Load(path)
+	if err != nil {
+		log.Fatalf("failed to load script: %v", err)
+	}
+
+	log.Printf("Running script %s...", path)
+	runner := &script.Runner{Exec: drv, Vars: variables, ArtifactDir: "agentgo-artifacts", Human: human}
+	runner.OnInterference = script.AskToResume(os.Stdin, os.Stderr)
+	if dir := os.Getenv(dotenv.EnvName("artifacts")); dir != "" {
+		runner.ArtifactDir = dir
+	}
+	if s.NeedsVision() {
+		apiKey, err := secrets.Get("GEMINI_API_KEY")
+		if err != nil {
+			log.Fatal(err)
+		}
+		model := os.Getenv(dotenv.EnvName("model"))
+		if model == "" {
+			model = vision.DefaultModel
+		}
+		client, err := vision.Connect(context.Background(), apiKey, model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer client.Close()
+		runner.Vision = client
+	}
+	if err := runner.Run(context.Background(), s); err != nil {
+		log.Fatalf("script failed: %v", err)
+	}
+	log.Println("Script finished.")
+}
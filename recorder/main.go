@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
@@ -15,36 +17,151 @@ import (
 	"strings"
 	"time"
 
-	"github.com/go-vgo/robotgo"
-	"github.com/google/generative-ai-go/genai"
-	"github.com/kbinani/screenshot"
-	"google.golang.org/api/option"
+	"agentGo/pkg/desktop"
+	"agentGo/pkg/dotenv"
+	"agentGo/pkg/playback"
+	"agentGo/pkg/preflight"
+	"agentGo/pkg/profile"
+	"agentGo/pkg/scrub"
+	"agentGo/pkg/secrets"
+	"agentGo/pkg/secure"
+	"agentGo/pkg/session"
+	"agentGo/pkg/throttle"
+	"agentGo/pkg/vars"
+	"agentGo/pkg/vision"
+	"agentGo/pkg/voice"
 )
 
 const recordingTime = 10 * time.Second
 
 func main() {
-	// Get API key from environment variable
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("GEMINI_API_KEY environment variable not set")
+	outPath := flag.String("out", "mouse_movements.csv", "path of the CSV file to write; may contain ${VAR} references")
+	cursorMode := flag.String("cursor", "crosshair", "how the pointer appears in screenshots: crosshair (a synthetic red marker) or real (the actual cursor image)")
+	displayIndex := flag.Int("display", 0, "index of the display to record (see agentgo displays)")
+	minConfidence := flag.Float64("min-confidence", 0, "ask the model for a confidence score and log answers below this (0-1) as unknown")
+	visionConfig := flag.String("vision-config", "", "JSON file, or inline JSON object, of model sampling and safety settings (temperature, top_k, top_p, max_output_tokens, safety)")
+	promptsFile := flag.String("prompts", "", "JSON file of per-provider prompts, such as the system instruction")
+	modelName := flag.String("model", vision.DefaultModel, "Gemini model to ask; a comma-separated list falls back to later models when one fails")
+	sessionDir := flag.String("session", "", "also record frames, clicks and the model's answers in this directory, for agentgo export")
+	sessionFormat := flag.String("session-format", "jsonl", "log format of -session: jsonl, pb (protobuf) or pb.zst (protobuf compressed with the zstd command)")
+	narrate := flag.Bool("narrate", false, "also record what you say into the microphone, transcribed, as narration on the -session timeline (needs SoX)")
+	transcriber := flag.String("transcriber", "gemini", voice.TranscriberUsage)
+	crop := flag.Float64("crop", 0, "send the model only a square around where it last found the pointer, this fraction of the screen's longer side (e.g. 0.2), and the whole screen when it loses track; 0 always sends the whole screen")
+	coords := flag.String("coords", "", "coordinate convention to ask the model for: auto, pixels, norm1000 or norm1 (default: the model's own)")
+	valuesFile := flag.String("values", "", "file of NAME=VALUE lines (or a JSON object) used for ${VAR} substitution")
+	var varFlags vars.Flag
+	flag.Var(&varFlags, "var", "set a substitution variable as NAME=VALUE (repeatable)")
+	envFile := flag.String("env-file", "", "file of NAME=VALUE environment settings, read in addition to ./.env")
+	profileName := flag.String("profile", "", "named profile of settings to use (default: $AGENTGO_PROFILE)")
+	flag.Parse()
+
+	// Flags given on the command line win over the environment, which wins
+	// over .env files, which win over the profile.
+	if *envFile != "" {
+		if err := dotenv.Load(*envFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := dotenv.LoadDefault(); err != nil {
+		log.Fatal(err)
+	}
+	if err := profile.Use("", *profileName); err != nil {
+		log.Fatal(err)
+	}
+	if err := dotenv.ApplyFlags(flag.CommandLine); err != nil {
+		log.Fatal(err)
+	}
+	// A profile's "out" comes as AGENTGO_RECORD_OUT, meant for the recorder
+	// alone, and yields to -out and AGENTGO_OUT.
+	outGiven := false
+	flag.Visit(func(f *flag.Flag) { outGiven = outGiven || f.Name == "out" })
+	if out, ok := os.LookupEnv(dotenv.EnvName("record-out")); ok && !outGiven && os.Getenv(dotenv.EnvName("out")) == "" {
+		*outPath = out
+	}
+
+	// Secrets are scrubbed from the log, the session and the prompts, as
+	// for agentgo (see AGENTGO_SCRUB_SECRETS and AGENTGO_SCRUB_PATTERN).
+	var scrubNames []string
+	if list := os.Getenv(dotenv.EnvName("scrub-secrets")); list != "" {
+		scrubNames = strings.Split(list, ",")
+	}
+	scrubber, err := scrub.New(scrubNames, os.Getenv(dotenv.EnvName("scrub-pattern")))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("scrub-pattern"), err)
+	}
+	log.SetOutput(scrubber.Writer(os.Stderr))
+
+	if err := preflight.Check(); err != nil {
+		log.Fatal(err)
+	}
+	if err := desktop.UseCapture(os.Getenv(dotenv.EnvName("capture"))); err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("capture"), err)
+	}
+	policy, err := throttle.Parse(os.Getenv(dotenv.EnvName("throttle")))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", dotenv.EnvName("throttle"), err)
+	}
+	gov := throttle.New(policy)
+
+	if *crop < 0 || *crop > 1 {
+		log.Fatalf("invalid -crop %v (want a fraction from 0 to 1)", *crop)
+	}
+	if *cursorMode != "crosshair" && *cursorMode != "real" {
+		log.Fatalf("invalid -cursor %q (want crosshair or real)", *cursorMode)
+	}
+
+	convention, err := vision.ParseConvention(*coords)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	variables, err := vars.Load(*valuesFile, varFlags)
+	if err != nil {
+		log.Fatalf("failed to load variables: %v", err)
+	}
+	csvPath, err := variables.Expand(*outPath)
+	if err != nil {
+		log.Fatalf("failed to expand output path: %v", err)
+	}
+
+	// Get API key from the environment or the keychain
+	apiKey, err := secrets.Get("GEMINI_API_KEY")
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Create a new Gemini client
 	ctx, cancel := context.WithTimeout(context.Background(), recordingTime)
 	defer cancel()
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	client, err := vision.Connect(ctx, apiKey, *modelName)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer client.Close()
-
-	// Initialize the generative model
-	model := client.GenerativeModel("gemini-1.5-flash")
+	client.Scrub = scrubber.String
+	if convention != "" {
+		client.Convention = convention
+	}
+	if *promptsFile != "" {
+		prompts, err := vision.LoadPrompts(*promptsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.UsePrompts(prompts)
+	}
+	if *visionConfig != "" {
+		cfg, err := vision.LoadConfig(*visionConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := client.Configure(cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// Create and open the CSV file for the player
-	file, err := os.Create("mouse_movements.csv")
+	file, err := os.Create(csvPath)
 	if err != nil {
 		log.Fatalf("failed to create csv file: %v", err)
 	}
@@ -53,8 +170,62 @@ func main() {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	// Write CSV header
-	if err := writer.Write([]string{"timestamp", "norm_x", "norm_y"}); err != nil {
+	// A nil session records nothing.
+	var rec *session.Writer
+	if *sessionDir != "" {
+		format, err := session.ParseFormat(*sessionFormat)
+		if err != nil {
+			log.Fatalf("invalid -session-format: %v", err)
+		}
+		if rec, err = session.Create(*sessionDir, format); err != nil {
+			log.Fatal(err)
+		}
+		rec.Scrub = scrubber.String
+		rec.App = func() string {
+			title, _ := (&desktop.Executor{}).WindowTitle()
+			return title
+		}
+		defer func() {
+			var requests, failed int
+			var tokens int64
+			for _, u := range client.Usage() {
+				requests, tokens, failed = requests+u.Requests, tokens+u.Tokens, failed+u.Errors
+			}
+			rec.Model(requests, tokens, failed)
+			if err := rec.Close(); err != nil {
+				log.Printf("session: %v", err)
+			}
+		}()
+	}
+
+	// Narration is transcribed into the session as it is spoken; the
+	// transcriptions still under way when recording ends are waited for.
+	if *narrate {
+		if rec == nil {
+			log.Fatal("-narrate needs -session to record the narration in")
+		}
+		openAIKey := secrets.Lookup("OPENAI_API_KEY")
+		t, err := voice.ParseTranscriber(*transcriber, openAIKey, client)
+		if err != nil {
+			log.Fatal(err)
+		}
+		narrated := make(chan struct{})
+		go func() {
+			defer close(narrated)
+			err := voice.Narrate(ctx, t, func(start time.Time, text string) {
+				log.Printf("Narration: %s", text)
+				rec.Narrate(start, text)
+			}, log.Printf)
+			if err != nil {
+				log.Printf("narration stopped: %v", err)
+			}
+		}()
+		defer func() { <-narrated }()
+	}
+
+	// Write the format version and CSV header. Nothing has gone through the
+	// CSV writer's buffer yet, so they land first.
+	if err := playback.WriteHeader(file); err != nil {
 		log.Fatalf("failed to write header to csv: %v", err)
 	}
 
@@ -63,11 +234,35 @@ func main() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
+	// With -crop, the model is shown a window around its last answer.
+	tracker := &vision.Tracker{Window: *crop}
+
+	// A focused password field is blacked out of the frames recorded and
+	// sent to the model, unless AGENTGO_MASK_SECRETS is false.
+	var masker *secure.Detector
+	if mask, err := strconv.ParseBool(os.Getenv(dotenv.EnvName("mask-secrets"))); err != nil || mask {
+		masker = &secure.Detector{Window: func() string {
+			title, _ := (&desktop.Executor{}).WindowTitle()
+			return title
+		}}
+		if local, err := desktop.NewLocalDisplay(*displayIndex); err == nil {
+			masker.Probe = local
+		}
+	}
+
 	startTime := time.Now()
-	bounds := screenshot.GetDisplayBounds(0)
-	
+	display, err := desktop.LookupDisplay(*displayIndex)
+	if err != nil {
+		log.Fatalf("failed to find display: %v", err)
+	}
+	bounds := display.Bounds
+	origin := bounds.Min
+
 	// Get screen dimensions
-	logicalWidth, logicalHeight := robotgo.GetScreenSize()
+	logicalWidth, logicalHeight := desktop.NewExecutor().ScreenSize()
+	if *displayIndex != 0 {
+		logicalWidth, logicalHeight = bounds.Dx(), bounds.Dy()
+	}
 	physicalWidth := float64(bounds.Dx())
 	physicalHeight := float64(bounds.Dy())
 
@@ -75,50 +270,123 @@ func main() {
 	xScale := float64(bounds.Dx()) / float64(logicalWidth)
 	yScale := float64(bounds.Dy()) / float64(logicalHeight)
 
+	// Clicks are polled far more often than positions are sampled, so that
+	// double clicks and press durations are caught.
+	clicks, err := desktop.WatchClicks(ctx, 10*time.Millisecond)
+	if err != nil {
+		log.Printf("clicks will not be recorded: %v", err)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Recording finished.")
 			return
+		case c, ok := <-clicks:
+			if !ok {
+				clicks = nil
+				continue
+			}
+			record := []string{
+				fmt.Sprintf("%d", c.Time.Sub(startTime).Milliseconds()),
+				fmt.Sprintf("%.8f", float64(c.X-origin.X)/float64(logicalWidth)),
+				fmt.Sprintf("%.8f", float64(c.Y-origin.Y)/float64(logicalHeight)),
+				"",
+				c.Button,
+				fmt.Sprintf("%d", c.Count),
+				fmt.Sprintf("%d", c.Hold.Milliseconds()),
+			}
+			if err := writer.Write(record); err != nil {
+				log.Printf("failed to write click to csv: %v", err)
+			}
+			rec.Move(float64(c.X-origin.X)/float64(logicalWidth), float64(c.Y-origin.Y)/float64(logicalHeight))
+			rec.Click(c.Button, c.Count)
+			log.Printf("Recorded %s click x%d held %v", c.Button, c.Count, c.Hold)
 		case t := <-ticker.C:
+			// On battery power or when the machine runs hot, sample less
+			// often and at a lower resolution.
+			ticker.Reset(gov.Interval(time.Second))
+
 			// --- Step 1: Get GROUND TRUTH mouse position and normalize it ---
-			mouseX, mouseY := robotgo.GetMousePos()
+			mouseX, mouseY := desktop.CursorPos()
+			mouseX, mouseY = mouseX-origin.X, mouseY-origin.Y
 			groundTruthNormX := float64(mouseX) / float64(logicalWidth)
 			groundTruthNormY := float64(mouseY) / float64(logicalHeight)
 
+			// The cursor shape hints at what is under the pointer
+			shape, err := desktop.CurrentCursorShape()
+			if err != nil {
+				shape = desktop.ShapeUnknown
+			}
+
 			// --- Step 2: Write the ground truth coordinates to the CSV for the player ---
 			timestamp := t.Sub(startTime).Milliseconds()
 			record := []string{
 				fmt.Sprintf("%d", timestamp),
 				fmt.Sprintf("%.8f", groundTruthNormX),
 				fmt.Sprintf("%.8f", groundTruthNormY),
+				string(shape),
+				"", "", "",
 			}
 			if err := writer.Write(record); err != nil {
 				log.Printf("failed to write record to csv: %v", err)
 			}
 
 			// --- Step 3: Perform visual analysis to get Gemini's coordinates ---
-			img, err := screenshot.CaptureRect(bounds)
+			img, err := desktop.CaptureDisplay(*displayIndex)
 			if err != nil {
 				log.Printf("failed to capture screen: %v", err)
 				continue
 			}
+			masker.Redact(img)
+			if small, ok := gov.Shrink(img).(*image.RGBA); ok {
+				img = small
+			}
+			// Wayland screen casts and throttled frames may not match the X11
+			// display bounds, so scale from the frame actually captured.
+			if img.Bounds().Size() != bounds.Size() {
+				bounds = img.Bounds()
+				physicalWidth, physicalHeight = float64(bounds.Dx()), float64(bounds.Dy())
+				xScale = physicalWidth / float64(logicalWidth)
+				yScale = physicalHeight / float64(logicalHeight)
+			}
+
+			// Record the screen before the marker is drawn on it.
+			rec.Move(groundTruthNormX, groundTruthNormY)
+			rec.Frame(img)
 
 			// The image from screenshot is already an *image.RGBA, so we can draw on it directly.
 			drawX := int(float64(mouseX) * xScale)
 			drawY := int(float64(mouseY) * yScale)
 
-			// Draw a red crosshair to represent the cursor
-			cursorColor := color.RGBA{R: 255, G: 0, B: 0, A: 255}
-			armLength := 15 // 15px out from the center
-			thickness := 5  // 5px thick lines
-			// Horizontal line
-			draw.Draw(img, image.Rect(drawX-armLength, drawY-thickness/2, drawX+armLength, drawY+thickness/2), &image.Uniform{C: cursorColor}, image.Point{}, draw.Src)
-			// Vertical line
-			draw.Draw(img, image.Rect(drawX-thickness/2, drawY-armLength, drawX+thickness/2, drawY+armLength), &image.Uniform{C: cursorColor}, image.Point{}, draw.Src)
+			prompt := "This screenshot has an artificial red crosshair marker drawn on it. Your task is to ignore all other UI elements and find the center of this red crosshair."
+			if *cursorMode == "real" {
+				// Composite the actual cursor so the model has to find the
+				// pointer as it really looks.
+				c, err := desktop.Cursor()
+				if err != nil {
+					log.Printf("failed to read cursor: %v", err)
+					continue
+				}
+				desktop.DrawCursor(img, c, origin, xScale)
+				prompt = "Find the mouse pointer in this screenshot. Ignore all other UI elements and give the position of the pointer's tip (its hotspot)."
+			} else {
+				// Draw a red crosshair to represent the cursor
+				cursorColor := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+				armLength := 15 // 15px out from the center
+				thickness := 5  // 5px thick lines
+				// Horizontal line
+				draw.Draw(img, image.Rect(drawX-armLength, drawY-thickness/2, drawX+armLength, drawY+thickness/2), &image.Uniform{C: cursorColor}, image.Point{}, draw.Src)
+				// Vertical line
+				draw.Draw(img, image.Rect(drawX-thickness/2, drawY-armLength, drawX+thickness/2, drawY+armLength), &image.Uniform{C: cursorColor}, image.Point{}, draw.Src)
+			}
 
+			region := img.Bounds()
+			if *crop > 0 {
+				region = tracker.Region(region)
+			}
 			var buf bytes.Buffer
-			if err := png.Encode(&buf, img); err != nil {
+			if err := png.Encode(&buf, img.SubImage(region)); err != nil {
 				log.Printf("failed to encode image: %v", err)
 				continue
 			}
@@ -128,39 +396,50 @@ func main() {
 			if err := os.WriteFile(debugFilename, buf.Bytes(), 0644); err != nil {
 				log.Printf("failed to create debug file: %v", err)
 			}
-			
-			// Send the image to Gemini with the improved prompt
-			prompt := "This screenshot has an artificial red crosshair marker drawn on it. Your task is to ignore all other UI elements and find this red crosshair. Return only the center x,y coordinates of the crosshair in the format x,y."
-			res, err := model.GenerateContent(ctx, genai.Text(prompt), genai.ImageData("png", buf.Bytes()))
+
+			// Send the image to Gemini with the improved prompt, asking for
+			// coordinates in the client's convention
+			prompt += " " + client.Convention.Instruction(region.Size())
+			if *minConfidence > 0 {
+				prompt += " " + vision.ConfidenceInstruction
+			}
+			text, err := client.GeneratePNG(ctx, prompt, buf.Bytes())
 			if err != nil {
 				log.Printf("Gemini call failed: %v", err)
+				tracker.Lost()
 				continue
 			}
 
 			// --- Step 4: Compare Gemini's response to the ground truth ---
 			var geminiNormX, geminiNormY float64
-			geminiCoordsStr := "N/A"
-			if len(res.Candidates) > 0 && len(res.Candidates[0].Content.Parts) > 0 {
-				if coordsText, ok := res.Candidates[0].Content.Parts[0].(genai.Text); ok {
-					coords := strings.Split(strings.TrimSpace(string(coordsText)), ",")
-					geminiCoordsStr = string(coordsText)
-					if len(coords) == 2 {
-						geminiX, errX := strconv.ParseFloat(coords[0], 64)
-						geminiY, errY := strconv.ParseFloat(coords[1], 64)
-
-						if errX == nil && errY == nil {
-							geminiNormX = geminiX / physicalWidth
-							geminiNormY = geminiY / physicalHeight
-						}
-					}
+			geminiCoordsStr := strings.TrimSpace(text)
+			p, confidence, err := client.ParseScored(text, region.Size())
+			if err == nil && *crop > 0 {
+				var ok bool
+				if p, ok = tracker.Found(region, p); !ok {
+					err = errors.New("answer outside the crop")
 				}
 			}
-			
+			if err != nil || confidence < *minConfidence {
+				tracker.Lost()
+			}
+			if err == nil && confidence < *minConfidence {
+				// Better no answer than a wrong one
+				log.Printf("Ground Truth: (%.4f, %.4f) cursor=%s vs Gemini: unknown (confidence %.2f) [Raw Gemini: %s, model %s]",
+					groundTruthNormX, groundTruthNormY, shape, confidence, geminiCoordsStr, client.Answered())
+				continue
+			}
+			if err == nil {
+				geminiNormX = p.X / physicalWidth
+				geminiNormY = p.Y / physicalHeight
+				rec.Predict(geminiNormX, geminiNormY, "pointer")
+			}
+
 			log.Printf(
-				"Ground Truth: (%.4f, %.4f) vs Gemini: (%.4f, %.4f) [Raw Gemini: %s]",
-				groundTruthNormX, groundTruthNormY,
+				"Ground Truth: (%.4f, %.4f) cursor=%s vs Gemini: (%.4f, %.4f) [Raw Gemini: %s, model %s]",
+				groundTruthNormX, groundTruthNormY, shape,
 				geminiNormX, geminiNormY,
-				geminiCoordsStr,
+				geminiCoordsStr, client.Answered(),
 			)
 		}
 	}